@@ -6,36 +6,108 @@ import (
 
 	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning"
 )
 
 func (hs *HTTPServer) AdminProvisioningReloadDashboards(c *models.ReqContext) response.Response {
-	err := hs.ProvisioningService.ProvisionDashboards()
+	result, err := hs.ProvisioningService.ReloadProvisioning(c.Req.Context(), "dashboards")
 	if err != nil && !errors.Is(err, context.Canceled) {
-		return response.Error(500, "", err)
+		return provisioningReloadError("", err)
 	}
-	return response.Success("Dashboards config reloaded")
+	return provisioningReloaded("Dashboards config reloaded", result)
 }
 
 func (hs *HTTPServer) AdminProvisioningReloadDatasources(c *models.ReqContext) response.Response {
-	err := hs.ProvisioningService.ProvisionDatasources()
+	result, err := hs.ProvisioningService.ReloadProvisioning(c.Req.Context(), "datasources")
 	if err != nil {
-		return response.Error(500, "", err)
+		return provisioningReloadError("", err)
 	}
-	return response.Success("Datasources config reloaded")
+	return provisioningReloaded("Datasources config reloaded", result)
 }
 
 func (hs *HTTPServer) AdminProvisioningReloadPlugins(c *models.ReqContext) response.Response {
-	err := hs.ProvisioningService.ProvisionPlugins()
+	result, err := hs.ProvisioningService.ReloadProvisioning(c.Req.Context(), "plugins")
 	if err != nil {
-		return response.Error(500, "Failed to reload plugins config", err)
+		return provisioningReloadError("Failed to reload plugins config", err)
 	}
-	return response.Success("Plugins config reloaded")
+	return provisioningReloaded("Plugins config reloaded", result)
 }
 
 func (hs *HTTPServer) AdminProvisioningReloadNotifications(c *models.ReqContext) response.Response {
-	err := hs.ProvisioningService.ProvisionNotifications()
+	result, err := hs.ProvisioningService.ReloadProvisioning(c.Req.Context(), "notifiers")
 	if err != nil {
-		return response.Error(500, "", err)
+		return provisioningReloadError("", err)
 	}
-	return response.Success("Notifications config reloaded")
+	return provisioningReloaded("Notifications config reloaded", result)
+}
+
+// AdminProvisioningGetInventory lists the objects applied by provisioning and the files they came from.
+func (hs *HTTPServer) AdminProvisioningGetInventory(c *models.ReqContext) response.Response {
+	inventory := hs.ProvisioningService.GetProvisionedInventory()
+	if inventory == nil {
+		inventory = []provisioning.ProvisionedObject{}
+	}
+	return response.JSON(200, inventory)
+}
+
+// AdminProvisioningGetDiff previews what reloading the provisioning files of a kind would change, without changing
+// anything.
+func (hs *HTTPServer) AdminProvisioningGetDiff(c *models.ReqContext) response.Response {
+	diff, err := hs.ProvisioningService.DiffProvisioning(c.Req.Context(), c.Params(":kind"))
+	if err != nil {
+		if errors.Is(err, provisioning.ErrUnsupportedDiffKind) {
+			return response.Error(404, err.Error(), err)
+		}
+		return provisioningReloadError("Failed to diff provisioning config", err)
+	}
+	return response.JSON(200, diff)
+}
+
+// provisioningReloadResponse is the body of a successful reload, with the counts of what it applied.
+type provisioningReloadResponse struct {
+	Message string `json:"message"`
+	provisioning.ProvisionCounts
+}
+
+// provisioningValidationResponse is the body of a reload that failed because of invalid provisioning files. Files
+// has an entry for every invalid file, it's empty when the error doesn't belong to a file.
+type provisioningValidationResponse struct {
+	Message string                     `json:"message"`
+	Files   []provisioningFileErrorDTO `json:"files"`
+}
+
+type provisioningFileErrorDTO struct {
+	Subsystem string `json:"subsystem"`
+	Path      string `json:"path"`
+	Line      int    `json:"line,omitempty"`
+	Column    int    `json:"column,omitempty"`
+	Message   string `json:"message"`
+}
+
+func provisioningReloaded(message string, result *provisioning.ProvisionResult) response.Response {
+	body := provisioningReloadResponse{Message: message}
+	if result != nil {
+		body.ProvisionCounts = result.Counts()
+	}
+	return response.JSON(200, body)
+}
+
+// provisioningReloadError returns 422 with every invalid file when provisioning files are invalid, and 500 for the
+// other errors, like database errors, which retrying the same files may fix.
+func provisioningReloadError(message string, err error) response.Response {
+	if !provisioning.IsInvalidConfig(err) {
+		return response.Error(500, message, err)
+	}
+
+	body := provisioningValidationResponse{Message: err.Error(), Files: []provisioningFileErrorDTO{}}
+	for _, fileErr := range provisioning.FileErrorsOf(err) {
+		body.Files = append(body.Files, provisioningFileErrorDTO{
+			Subsystem: fileErr.Subsystem,
+			Path:      fileErr.Path,
+			Line:      fileErr.Line,
+			Column:    fileErr.Column,
+			Message:   fileErr.Err.Error(),
+		})
+	}
+	return response.JSON(422, body)
 }
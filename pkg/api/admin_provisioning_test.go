@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning"
+	"github.com/grafana/grafana/pkg/services/provisioning/provisioningtest"
+	"github.com/grafana/grafana/pkg/util/errutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminProvisioningReload(t *testing.T) {
+	reloadScenario := func(t *testing.T, desc string, fake *provisioningtest.FakeProvisioningService, fn scenarioFunc) {
+		loggedInUserScenarioWithRole(t, desc, "GET", "/api/admin/provisioning/datasources/reload",
+			"/api/admin/provisioning/datasources/reload", models.ROLE_ADMIN, func(sc *scenarioContext) {
+				hs := &HTTPServer{ProvisioningService: fake}
+				sc.handlerFunc = func(c *models.ReqContext) response.Response {
+					return hs.AdminProvisioningReloadDatasources(c)
+				}
+				fn(sc)
+			})
+	}
+
+	reloadScenario(t, "Successful reload returns the applied counts", &provisioningtest.FakeProvisioningService{
+		Results: map[string]*provisioning.ProvisionResult{"datasources": {
+			Objects: []provisioning.ProvisionedObject{
+				{Kind: "datasource", Name: "Prometheus", Action: "created"},
+				{Kind: "datasource", Name: "Loki", Action: "skipped"},
+			},
+			Deleted: []provisioning.ProvisionedObject{{Kind: "datasource", Name: "Graphite"}},
+		}},
+	}, func(sc *scenarioContext) {
+		sc.fakeReqWithParams("GET", sc.url, map[string]string{}).exec()
+
+		assert.Equal(t, 200, sc.resp.Code)
+		assert.JSONEq(t, `{"message": "Datasources config reloaded", "applied": 2, "created": 1, "updated": 0,
+			"skipped": 1, "deleted": 1}`, sc.resp.Body.String())
+	})
+
+	invalidFiles := provisioning.FileErrors{
+		{Subsystem: "datasources", Path: "/etc/grafana/provisioning/datasources/a.yaml", Line: 3, Err: errors.New("unknown field \"urll\"")},
+		{Subsystem: "datasources", Path: "/etc/grafana/provisioning/datasources/b.yaml", Err: errors.New("invalid")},
+	}
+	reloadScenario(t, "Invalid files fail with 422 and every file", &provisioningtest.FakeProvisioningService{
+		ProvisionDatasourcesError: errutil.Wrap("Datasource provisioning error", invalidFiles.Err()),
+	}, func(sc *scenarioContext) {
+		sc.fakeReqWithParams("GET", sc.url, map[string]string{}).exec()
+
+		assert.Equal(t, 422, sc.resp.Code)
+		var body provisioningValidationResponse
+		require.NoError(t, json.Unmarshal(sc.resp.Body.Bytes(), &body))
+		assert.Contains(t, body.Message, "Datasource provisioning error")
+		require.Len(t, body.Files, 2)
+		assert.Equal(t, provisioningFileErrorDTO{Subsystem: "datasources", Path: "/etc/grafana/provisioning/datasources/a.yaml",
+			Line: 3, Message: `unknown field "urll"`}, body.Files[0])
+		assert.Equal(t, "/etc/grafana/provisioning/datasources/b.yaml", body.Files[1].Path)
+	})
+
+	reloadScenario(t, "Org that doesn't exist fails with 422 without files", &provisioningtest.FakeProvisioningService{
+		ProvisionDatasourcesError: fmt.Errorf("failed to provision \"Prometheus\" data source: %w", models.ErrOrgNotFound),
+	}, func(sc *scenarioContext) {
+		sc.fakeReqWithParams("GET", sc.url, map[string]string{}).exec()
+
+		assert.Equal(t, 422, sc.resp.Code)
+		assert.JSONEq(t, `{"message": "failed to provision \"Prometheus\" data source: organization not found", "files": []}`,
+			sc.resp.Body.String())
+	})
+
+	reloadScenario(t, "Other errors fail with 500", &provisioningtest.FakeProvisioningService{
+		ProvisionDatasourcesError: errutil.Wrap("Datasource provisioning error", errors.New("database is locked")),
+	}, func(sc *scenarioContext) {
+		sc.fakeReqWithParams("GET", sc.url, map[string]string{}).exec()
+
+		assert.Equal(t, 500, sc.resp.Code)
+		assert.NotContains(t, sc.resp.Body.String(), "files")
+	})
+}
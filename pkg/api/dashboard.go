@@ -50,6 +50,9 @@ func (hs *HTTPServer) GetDashboard(c *models.ReqContext) response.Response {
 	slug := c.Params(":slug")
 	uid := c.Params(":uid")
 	dash, rsp := getDashboardHelper(c.OrgId, slug, 0, uid)
+	if rsp != nil && uid != "" {
+		dash, rsp = hs.getDeferredDashboard(c, uid, rsp)
+	}
 	if rsp != nil {
 		return rsp
 	}
@@ -189,6 +192,19 @@ func getDashboardHelper(orgID int64, slug string, id int64, uid string) (*models
 	return query.Result, nil
 }
 
+// getDeferredDashboard returns the dashboard with uid once it's provisioned, if a lazy dashboard provider deferred it.
+// Otherwise it returns notFound, the response of the lookup that didn't find the dashboard.
+func (hs *HTTPServer) getDeferredDashboard(c *models.ReqContext, uid string, notFound response.Response) (*models.Dashboard, response.Response) {
+	provisioned, err := hs.ProvisioningService.ProvisionDeferredDashboard(c.Req.Context(), c.OrgId, uid)
+	if err != nil {
+		return nil, response.Error(500, "Failed to provision dashboard", err)
+	}
+	if !provisioned {
+		return nil, notFound
+	}
+	return getDashboardHelper(c.OrgId, "", 0, uid)
+}
+
 func (hs *HTTPServer) DeleteDashboardBySlug(c *models.ReqContext) response.Response {
 	query := models.GetDashboardsBySlugQuery{OrgId: c.OrgId, Slug: c.Params(":slug")}
 
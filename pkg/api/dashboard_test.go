@@ -18,6 +18,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/dashboards"
 	"github.com/grafana/grafana/pkg/services/live"
 	"github.com/grafana/grafana/pkg/services/provisioning"
+	"github.com/grafana/grafana/pkg/services/provisioning/provisioningtest"
 	"github.com/grafana/grafana/pkg/services/quota"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/stretchr/testify/assert"
@@ -1007,7 +1008,7 @@ func TestDashboardAPIEndpoint(t *testing.T) {
 				dashboards.GetProvisionedData = origGetProvisionedData
 			})
 			dashboards.GetProvisionedData = func(dboards.Store, int64) (*models.DashboardProvisioning, error) {
-				return &models.DashboardProvisioning{ExternalId: "/tmp/grafana/dashboards/test/dashboard1.json"}, nil
+				return &models.DashboardProvisioning{Name: "default", ExternalId: "/tmp/grafana/dashboards/test/dashboard1.json"}, nil
 			}
 
 			bus.AddHandler("test", func(query *models.GetDashboardAclInfoListQuery) error {
@@ -1042,30 +1043,49 @@ func TestDashboardAPIEndpoint(t *testing.T) {
 		loggedInUserScenarioWithRole(t, "When calling GET on", "GET", "/api/dashboards/uid/dash", "/api/dashboards/uid/:uid", models.ROLE_EDITOR, func(sc *scenarioContext) {
 			setUp()
 
-			mock := provisioning.NewProvisioningServiceMock()
-			mock.GetDashboardProvisionerResolvedPathFunc = func(name string) string {
-				return "/tmp/grafana/dashboards"
+			fake := &provisioningtest.FakeProvisioningService{
+				ResolvedPaths: map[string]string{"default": "/tmp/grafana/dashboards"},
 			}
 
-			dash := getDashboardShouldReturn200WithConfig(sc, mock)
+			dash := getDashboardShouldReturn200WithConfig(sc, fake)
 
 			assert.Equal(t, filepath.Join("test", "dashboard1.json"), dash.Meta.ProvisionedExternalId)
 		})
 
-		loggedInUserScenarioWithRole(t, "When allowUiUpdates is true and calling GET on", "GET", "/api/dashboards/uid/dash", "/api/dashboards/uid/:uid", models.ROLE_EDITOR, func(sc *scenarioContext) {
+		loggedInUserScenarioWithRole(t, "When a lazy provider deferred the dashboard and calling GET on", "GET", "/api/dashboards/uid/dash", "/api/dashboards/uid/:uid", models.ROLE_EDITOR, func(sc *scenarioContext) {
 			setUp()
 
-			mock := provisioning.NewProvisioningServiceMock()
-			mock.GetDashboardProvisionerResolvedPathFunc = func(name string) string {
-				return "/tmp/grafana/dashboards"
+			fake := &provisioningtest.FakeProvisioningService{
+				ResolvedPaths:      map[string]string{"default": "/tmp/grafana/dashboards"},
+				DeferredDashboards: map[int64][]string{testOrgID: {"dash"}},
 			}
-			mock.GetAllowUIUpdatesFromConfigFunc = func(name string) bool {
-				return true
+			bus.AddHandler("test", func(query *models.GetDashboardQuery) error {
+				if fake.CallCount("ProvisionDeferredDashboard") == 0 {
+					return models.ErrDashboardNotFound
+				}
+				query.Result = &models.Dashboard{Id: 1, Uid: "dash", Data: simplejson.NewFromAny(map[string]interface{}{
+					"uid": "dash", "title": "Deferred",
+				})}
+				return nil
+			})
+
+			dash := getDashboardShouldReturn200WithConfig(sc, fake)
+
+			assert.Equal(t, "Deferred", dash.Dashboard.Get("title").MustString())
+			assert.Equal(t, 1, fake.CallCount("ProvisionDeferredDashboard"))
+		})
+
+		loggedInUserScenarioWithRole(t, "When allowUiUpdates is true and calling GET on", "GET", "/api/dashboards/uid/dash", "/api/dashboards/uid/:uid", models.ROLE_EDITOR, func(sc *scenarioContext) {
+			setUp()
+
+			fake := &provisioningtest.FakeProvisioningService{
+				ResolvedPaths:  map[string]string{"default": "/tmp/grafana/dashboards"},
+				AllowUIUpdates: map[string]bool{"default": true},
 			}
 
 			hs := &HTTPServer{
 				Cfg:                 setting.NewCfg(),
-				ProvisioningService: mock,
+				ProvisioningService: fake,
 			}
 			callGetDashboard(sc, hs)
 
@@ -1083,7 +1103,7 @@ func TestDashboardAPIEndpoint(t *testing.T) {
 func getDashboardShouldReturn200WithConfig(sc *scenarioContext, provisioningService provisioning.ProvisioningService) dtos.
 	DashboardFullWithMeta {
 	if provisioningService == nil {
-		provisioningService = provisioning.NewProvisioningServiceMock()
+		provisioningService = &provisioningtest.FakeProvisioningService{}
 	}
 
 	hs := &HTTPServer{
@@ -1171,7 +1191,7 @@ func postDashboardScenario(t *testing.T, desc string, url string, routePattern s
 		hs := HTTPServer{
 			Bus:                 bus.GetBus(),
 			Cfg:                 cfg,
-			ProvisioningService: provisioning.NewProvisioningServiceMock(),
+			ProvisioningService: &provisioningtest.FakeProvisioningService{},
 			Live:                &live.GrafanaLive{Cfg: setting.NewCfg()},
 			QuotaService: &quota.QuotaService{
 				Cfg: cfg,
@@ -1235,7 +1255,7 @@ func restoreDashboardVersionScenario(t *testing.T, desc string, url string, rout
 		hs := HTTPServer{
 			Cfg:                 cfg,
 			Bus:                 bus.GetBus(),
-			ProvisioningService: provisioning.NewProvisioningServiceMock(),
+			ProvisioningService: &provisioningtest.FakeProvisioningService{},
 			Live:                &live.GrafanaLive{Cfg: cfg},
 			QuotaService:        &quota.QuotaService{Cfg: cfg},
 		}
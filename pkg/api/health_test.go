@@ -9,7 +9,9 @@ import (
 
 	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/infra/localcache"
+	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/provisioningtest"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/stretchr/testify/require"
 	macaron "gopkg.in/macaron.v1"
@@ -167,6 +169,71 @@ func TestHealthAPI_DatabaseHealthCached(t *testing.T) {
 	require.True(t, healthy.(bool))
 }
 
+func TestHealthAPI_ProvisioningNotReady(t *testing.T) {
+	m, hs := setupHealthAPITestEnvironment(t, func(cfg *setting.Cfg) {
+		cfg.ProvisioningAPIHealthCheck = true
+	})
+	hs.Cfg.AnonymousHideVersion = true
+	provisioningService := &provisioningtest.FakeProvisioningService{
+		HealthError: errors.New("dashboards haven't been provisioned yet"),
+	}
+	hs.ProvisioningService = provisioningService
+
+	bus.AddHandler("test", func(query *models.GetDBHealthQuery) error {
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+
+	require.Equal(t, 503, rec.Code)
+	expectedBody := `
+		{
+			"database": "ok",
+			"provisioning": "failing"
+		}
+	`
+	require.JSONEq(t, expectedBody, rec.Body.String())
+
+	provisioningService.HealthError = nil
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+
+	require.Equal(t, 200, rec.Code)
+	expectedBody = `
+		{
+			"database": "ok",
+			"provisioning": "ok"
+		}
+	`
+	require.JSONEq(t, expectedBody, rec.Body.String())
+}
+
+func TestHealthAPI_ProvisioningNotChecked(t *testing.T) {
+	m, hs := setupHealthAPITestEnvironment(t)
+	hs.Cfg.AnonymousHideVersion = true
+	hs.ProvisioningService = &provisioningtest.FakeProvisioningService{
+		HealthError: errors.New("dashboards haven't been provisioned yet"),
+	}
+
+	bus.AddHandler("test", func(query *models.GetDBHealthQuery) error {
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+
+	require.Equal(t, 200, rec.Code)
+	expectedBody := `
+		{
+			"database": "ok"
+		}
+	`
+	require.JSONEq(t, expectedBody, rec.Body.String())
+}
+
 func setupHealthAPITestEnvironment(t *testing.T, cbs ...func(*setting.Cfg)) (*macaron.Macaron, *HTTPServer) {
 	t.Helper()
 
@@ -181,6 +248,7 @@ func setupHealthAPITestEnvironment(t *testing.T, cbs ...func(*setting.Cfg)) (*ma
 	hs := &HTTPServer{
 		CacheService: localcache.New(5*time.Minute, 10*time.Minute),
 		Cfg:          cfg,
+		log:          log.New("http.server"),
 	}
 
 	m.Get("/api/health", hs.apiHealthHandler)
@@ -406,7 +406,8 @@ func (hs *HTTPServer) healthzHandler(ctx *macaron.Context) {
 
 // apiHealthHandler will return ok if Grafana's web server is running and it
 // can access the database. If the database cannot be accessed it will return
-// http status code 503.
+// http status code 503. With the provisioning api_health_check setting it
+// also returns 503 while provisioning isn't healthy.
 func (hs *HTTPServer) apiHealthHandler(ctx *macaron.Context) {
 	notHeadOrGet := ctx.Req.Method != http.MethodGet && ctx.Req.Method != http.MethodHead
 	if notHeadOrGet || ctx.Req.URL.Path != "/api/health" {
@@ -420,8 +421,21 @@ func (hs *HTTPServer) apiHealthHandler(ctx *macaron.Context) {
 		data.Set("commit", hs.Cfg.BuildCommit)
 	}
 
+	healthy := true
 	if !hs.databaseHealthy() {
 		data.Set("database", "failing")
+		healthy = false
+	}
+	if hs.Cfg.ProvisioningAPIHealthCheck && hs.ProvisioningService != nil {
+		data.Set("provisioning", "ok")
+		if err := hs.ProvisioningService.Health(); err != nil {
+			hs.log.Warn("Provisioning isn't healthy", "err", err)
+			data.Set("provisioning", "failing")
+			healthy = false
+		}
+	}
+
+	if !healthy {
 		ctx.Resp.Header().Set("Content-Type", "application/json; charset=UTF-8")
 		ctx.Resp.WriteHeader(503)
 	} else {
@@ -91,13 +91,22 @@ func (b *InProcBus) DispatchCtx(ctx context.Context, msg Msg) error {
 
 	span.SetTag("msg", msgName)
 
-	var handler = b.handlersWithCtx[msgName]
+	withCtx := true
+	handler := b.handlersWithCtx[msgName]
 	if handler == nil {
-		return ErrHandlerNotFound
+		// fall back to handlers without context so callers can pass their context
+		// before every handler has been migrated
+		withCtx = false
+		handler = b.handlers[msgName]
+		if handler == nil {
+			return ErrHandlerNotFound
+		}
 	}
 
 	var params = []reflect.Value{}
-	params = append(params, reflect.ValueOf(ctx))
+	if withCtx {
+		params = append(params, reflect.ValueOf(ctx))
+	}
 	params = append(params, reflect.ValueOf(msg))
 
 	ret := reflect.ValueOf(handler).Call(params)
@@ -132,6 +132,23 @@ var adminCommands = []*cli.Command{
 	},
 }
 
+var provisioningCommands = []*cli.Command{
+	{
+		Name:  "lint",
+		Usage: "lint <provisioning path>, validates the provisioning files without a database",
+		Action: func(context *cli.Context) error {
+			return provisioningLintCommand(&utils.ContextCommandLine{Context: context})
+		},
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "strict",
+				Usage: "Fail on unknown fields",
+				Value: false,
+			},
+		},
+	},
+}
+
 var Commands = []*cli.Command{
 	{
 		Name:        "plugins",
@@ -143,4 +160,9 @@ var Commands = []*cli.Command{
 		Usage:       "Grafana admin commands",
 		Subcommands: adminCommands,
 	},
+	{
+		Name:        "provisioning",
+		Usage:       "Validate provisioning files",
+		Subcommands: provisioningCommands,
+	},
 }
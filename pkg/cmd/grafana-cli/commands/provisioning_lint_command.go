@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/logger"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
+
+	// Registers the alert notifiers, so notifier configs can be validated.
+	_ "github.com/grafana/grafana/pkg/services/alerting/notifiers"
+	"github.com/grafana/grafana/pkg/services/provisioning/dashboards"
+	"github.com/grafana/grafana/pkg/services/provisioning/datasources"
+	"github.com/grafana/grafana/pkg/services/provisioning/notifiers"
+	"github.com/grafana/grafana/pkg/services/provisioning/plugins"
+	provisioningutils "github.com/grafana/grafana/pkg/services/provisioning/utils"
+)
+
+var errMissingProvisioningPath = errors.New("missing provisioning path, usage: provisioning lint <path>")
+
+// provisioningLinters lint the subdirectories of a provisioning path, and the per-org directories like
+// orgs/<orgID>/datasources of the org-scoped ones. Plugins can't be checked against the installed apps without a
+// running Grafana.
+var provisioningLinters = []struct {
+	dir       string
+	orgScoped bool
+	lint      func(path string, strict bool) ([]provisioningutils.LintError, error)
+}{
+	{dir: "datasources", orgScoped: true, lint: datasources.Lint},
+	{dir: "notifiers", lint: notifiers.Lint},
+	{dir: "plugins", lint: func(path string, strict bool) ([]provisioningutils.LintError, error) {
+		return plugins.Lint(path, nil, strict)
+	}},
+	{dir: "dashboards", orgScoped: true, lint: dashboards.Lint},
+}
+
+func provisioningLintCommand(c utils.CommandLine) error {
+	path := c.Args().First()
+	if path == "" {
+		return errMissingProvisioningPath
+	}
+
+	invalid, err := lintProvisioning(path, c.Bool("strict"))
+	if err != nil {
+		return err
+	}
+	if invalid > 0 {
+		return fmt.Errorf("found %d invalid provisioning files", invalid)
+	}
+
+	logger.Info("All provisioning files are valid\n")
+	return nil
+}
+
+// lintProvisioning lints the provisioning files of every subsystem directory of path and prints the problems it
+// finds. Unknown fields are problems when strict is set. It returns how many files are invalid.
+func lintProvisioning(path string, strict bool) (int, error) {
+	linted := false
+	invalid := 0
+	for _, linter := range provisioningLinters {
+		dirs := []string{filepath.Join(path, linter.dir)}
+		if linter.orgScoped {
+			dirs = append(dirs, provisioningutils.OrgDirectories(path, linter.dir)...)
+		}
+
+		for _, dir := range dirs {
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				logger.Debugf("Skipping %s, it isn't a directory\n", dir)
+				continue
+			}
+			linted = true
+
+			lintErrors, err := linter.lint(dir, strict)
+			if err != nil {
+				return 0, fmt.Errorf("failed to lint %s: %w", dir, err)
+			}
+
+			files := map[string]struct{}{}
+			for _, lintErr := range lintErrors {
+				logger.Errorf("%s %s\n", color.RedString("✗"), lintErr.Error())
+				files[lintErr.File] = struct{}{}
+			}
+			invalid += len(files)
+		}
+	}
+
+	if !linted {
+		return 0, fmt.Errorf("no provisioning directories found in %s", path)
+	}
+	return invalid, nil
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/commands/commandstest"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
+)
+
+func TestProvisioningLintCommand(t *testing.T) {
+	t.Run("Requires a path", func(t *testing.T) {
+		c, err := commandstest.NewCliContext(map[string]string{})
+		require.NoError(t, err)
+		assert.Equal(t, errMissingProvisioningPath, provisioningLintCommand(c))
+	})
+
+	t.Run("Valid provisioning files", func(t *testing.T) {
+		require.NoError(t, provisioningLintCommand(newArgsCliContext(t, "testdata/provisioning/valid")))
+	})
+
+	t.Run("Fails with the number of invalid files", func(t *testing.T) {
+		err := provisioningLintCommand(newArgsCliContext(t, "testdata/provisioning/invalid"))
+		require.EqualError(t, err, "found 4 invalid provisioning files")
+	})
+
+	t.Run("Fails on unknown fields in strict mode", func(t *testing.T) {
+		invalid, err := lintProvisioning("testdata/provisioning/unknown-fields", false)
+		require.NoError(t, err)
+		assert.Equal(t, 0, invalid)
+
+		invalid, err = lintProvisioning("testdata/provisioning/unknown-fields", true)
+		require.NoError(t, err)
+		assert.Equal(t, 1, invalid)
+	})
+
+	t.Run("Fails without provisioning directories", func(t *testing.T) {
+		_, err := lintProvisioning("testdata", false)
+		require.Error(t, err)
+	})
+}
+
+func newArgsCliContext(t *testing.T, args ...string) *utils.ContextCommandLine {
+	t.Helper()
+	flagSet := flag.NewFlagSet("Test", 0)
+	require.NoError(t, flagSet.Parse(args))
+	return &utils.ContextCommandLine{Context: cli.NewContext(&cli.App{Name: "Test"}, flagSet, nil)}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"runtime"
 	"runtime/trace"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -72,6 +74,12 @@ func main() {
 		os.Exit(0)
 	}
 
+	provisioningSelfTest, err := parseProvisioningCommand(flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
 	profileDiagnostics := newProfilingDiagnostics(*profile, *profilePort)
 	if err := profileDiagnostics.overrideWithEnv(); err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
@@ -95,7 +103,12 @@ func main() {
 		}()
 	}
 
-	if err := executeServer(*configFile, *homePath, *pidFile, *packaging, traceDiagnostics); err != nil {
+	if provisioningSelfTest {
+		err = executeProvisioningSelfTest(*configFile, *homePath, *packaging)
+	} else {
+		err = executeServer(*configFile, *homePath, *pidFile, *packaging, traceDiagnostics)
+	}
+	if err != nil {
 		code := 1
 		var ewc exitWithCode
 		if errors.As(err, &ewc) {
@@ -134,18 +147,7 @@ func executeServer(configFile, homePath, pidFile, packaging string, traceDiagnos
 		defer trace.Stop()
 	}
 
-	buildstampInt64, err := strconv.ParseInt(buildstamp, 10, 64)
-	if err != nil || buildstampInt64 == 0 {
-		buildstampInt64 = time.Now().Unix()
-	}
-
-	setting.BuildVersion = version
-	setting.BuildCommit = commit
-	setting.BuildStamp = buildstampInt64
-	setting.BuildBranch = buildBranch
-	setting.IsEnterprise = extensions.IsEnterprise
-	setting.Packaging = validPackaging(packaging)
-
+	setBuildInformation(packaging)
 	metrics.SetBuildInformation(version, commit, buildBranch)
 
 	s, err := server.New(server.Config{
@@ -169,6 +171,53 @@ func executeServer(configFile, homePath, pidFile, packaging string, traceDiagnos
 	return nil
 }
 
+// executeProvisioningSelfTest runs the provisioning self-test on its own, without starting the server.
+func executeProvisioningSelfTest(configFile, homePath, packaging string) error {
+	defer func() {
+		if err := log.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to close log: %s\n", err)
+		}
+	}()
+
+	setBuildInformation(packaging)
+	return server.ProvisioningSelfTest(context.Background(), server.Config{ConfigFile: configFile, HomePath: homePath},
+		flag.Args(), os.Stdout)
+}
+
+func setBuildInformation(packaging string) {
+	buildstampInt64, err := strconv.ParseInt(buildstamp, 10, 64)
+	if err != nil || buildstampInt64 == 0 {
+		buildstampInt64 = time.Now().Unix()
+	}
+
+	setting.BuildVersion = version
+	setting.BuildCommit = commit
+	setting.BuildStamp = buildstampInt64
+	setting.BuildBranch = buildBranch
+	setting.IsEnterprise = extensions.IsEnterprise
+	setting.Packaging = validPackaging(packaging)
+}
+
+// parseProvisioningCommand returns true when the arguments after the flags are the provisioning self-test command,
+// grafana-server provisioning self-test. The cfg: arguments that override the settings can come before or after it,
+// and other arguments are ignored like before.
+func parseProvisioningCommand(args []string) (bool, error) {
+	var command []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "cfg:") {
+			command = append(command, arg)
+		}
+	}
+	if len(command) == 0 || command[0] != "provisioning" {
+		return false, nil
+	}
+	if len(command) == 2 && command[1] == "self-test" {
+		return true, nil
+	}
+	return false, fmt.Errorf("unknown provisioning command %q, the only one is self-test",
+		strings.Join(command[1:], " "))
+}
+
 func validPackaging(packaging string) string {
 	validTypes := []string{"dev", "deb", "rpm", "docker", "brew", "hosted", "unknown"}
 	for _, vt := range validTypes {
@@ -192,6 +241,7 @@ func listenToSystemSignals(s *server.Server) {
 			if err := log.Reload(); err != nil {
 				fmt.Fprintf(os.Stderr, "Failed to reload loggers: %s\n", err)
 			}
+			s.Reload()
 		case sig := <-signalChan:
 			s.Shutdown(fmt.Sprintf("System signal: %s", sig))
 			return
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProvisioningCommand(t *testing.T) {
+	selfTest, err := parseProvisioningCommand([]string{"cfg:default.paths.data=/tmp", "provisioning", "self-test"})
+	require.NoError(t, err)
+	assert.True(t, selfTest)
+
+	selfTest, err = parseProvisioningCommand([]string{"cfg:default.paths.data=/tmp"})
+	require.NoError(t, err)
+	assert.False(t, selfTest, "Flags and settings alone start the server")
+
+	_, err = parseProvisioningCommand([]string{"provisioning", "apply"})
+	require.EqualError(t, err, `unknown provisioning command "apply", the only one is self-test`)
+}
@@ -31,6 +31,7 @@ func init() {
 	registry.RegisterService(&licensing.OSSLicensingService{})
 	registry.RegisterService(&validations.OSSPluginRequestValidator{})
 	registry.RegisterService(&ossaccesscontrol.OSSAccessControlService{})
+	registry.RegisterService(&ossaccesscontrol.OSSDatasourcePermissionsService{})
 }
 
 var IsEnterprise bool = false
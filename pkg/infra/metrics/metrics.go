@@ -104,6 +104,9 @@ var (
 
 	// MRenderingQueue is a metric gauge for image rendering queue size
 	MRenderingQueue prometheus.Gauge
+
+	// MProvisioningParseCache is a metric counter for provisioned dashboard files found in, or missing from, the parse cache
+	MProvisioningParseCache *prometheus.CounterVec
 )
 
 // Timers
@@ -373,6 +376,15 @@ func init() {
 		[]string{"status"},
 	)
 
+	MProvisioningParseCache = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:      "provisioning_dashboards_parse_cache_total",
+			Help:      "counter for provisioned dashboard files that didn't have to be parsed again (hit) or did (miss)",
+			Namespace: ExporterName,
+		},
+		[]string{"provider", "result"},
+	)
+
 	MRenderingSummary = prometheus.NewSummaryVec(
 		prometheus.SummaryOpts{
 			Name:       "rendering_request_duration_milliseconds",
@@ -582,6 +594,7 @@ func initMetricVars() {
 		MRenderingRequestTotal,
 		MRenderingSummary,
 		MRenderingQueue,
+		MProvisioningParseCache,
 		MAlertingActiveAlerts,
 		MStatTotalDashboards,
 		MStatTotalFolders,
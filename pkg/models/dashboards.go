@@ -353,6 +353,30 @@ type DashboardProvisioning struct {
 	ExternalId  string
 	CheckSum    string
 	Updated     int64
+	// DashboardCheckSum is the checksum of the dashboard JSON as provisioning saved it, so changes made to the
+	// dashboard afterwards can be detected.
+	DashboardCheckSum string
+}
+
+// DashboardCheckSum returns a checksum of the JSON model of a dashboard, leaving out the id and version fields that
+// change on every save.
+func DashboardCheckSum(data *simplejson.Json) (string, error) {
+	fields, err := data.Map()
+	if err != nil {
+		return "", err
+	}
+	withoutSaveFields := make(map[string]interface{}, len(fields))
+	for name, value := range fields {
+		if name != "id" && name != "version" {
+			withoutSaveFields[name] = value
+		}
+	}
+
+	content, err := simplejson.NewFromAny(withoutSaveFields).MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+	return util.Md5SumString(string(content))
 }
 
 type DeleteDashboardCommand struct {
@@ -362,6 +386,22 @@ type DeleteDashboardCommand struct {
 
 type DeleteOrphanedProvisionedDashboardsCommand struct {
 	ReaderNames []string
+	// OrgId limits the deletion to the dashboards of one org. Zero deletes the orphans of every org.
+	OrgId int64
+}
+
+// CountOrphanedProvisionedDashboardsQuery counts, per org, the provisioned dashboards and how many of them
+// DeleteOrphanedProvisionedDashboardsCommand would delete for the same ReaderNames and org.
+type CountOrphanedProvisionedDashboardsQuery struct {
+	ReaderNames []string
+
+	Result []*OrphanedProvisionedDashboardsCount
+}
+
+type OrphanedProvisionedDashboardsCount struct {
+	OrgId       int64
+	Orphaned    int64
+	Provisioned int64
 }
 
 //
@@ -68,6 +68,22 @@ func TestSaveDashboardCommand_GetDashboardModel(t *testing.T) {
 	})
 }
 
+func TestDashboardCheckSum(t *testing.T) {
+	saved := simplejson.NewFromAny(map[string]interface{}{"id": 1, "version": 2, "title": "test dash"})
+	checkSum, err := DashboardCheckSum(saved)
+	require.NoError(t, err)
+
+	resaved := simplejson.NewFromAny(map[string]interface{}{"id": 1, "version": 3, "title": "test dash"})
+	resavedCheckSum, err := DashboardCheckSum(resaved)
+	require.NoError(t, err)
+	assert.Equal(t, checkSum, resavedCheckSum, "saving again doesn't change the checksum")
+
+	saved.Set("title", "edited dash")
+	editedCheckSum, err := DashboardCheckSum(saved)
+	require.NoError(t, err)
+	assert.NotEqual(t, checkSum, editedCheckSum)
+}
+
 func TestSlugifyTitle(t *testing.T) {
 	testCases := map[string]string{
 		"Grafana Play Home": "grafana-play-home",
@@ -79,6 +79,19 @@ func (ds *DataSource) decryptedValue(field string, fallback string) string {
 	return fallback
 }
 
+// DatasourceProvisioning marks a datasource as created or updated by provisioning, as opposed to the UI or API.
+type DatasourceProvisioning struct {
+	Id           int64
+	DatasourceId int64
+	OrgId        int64
+	Updated      int64
+	// Name and CheckSum are the name and the checksum of the config the datasource was last provisioned from. Deleted
+	// is set once the datasource was deleted other than by provisioning, like through the UI.
+	Name     string
+	CheckSum string
+	Deleted  bool
+}
+
 // ----------------------
 // COMMANDS
 
@@ -140,13 +153,38 @@ type DeleteDataSourceCommand struct {
 	Name string
 
 	OrgID int64
+	// Provisioned is set when provisioning deletes the datasource. Datasources provisioning created that are deleted
+	// otherwise are remembered as deleted, see GetDeletedProvisionedDatasourceQuery.
+	Provisioned bool
 
 	DeletedDatasourcesCount int64
 }
 
+// SaveProvisionedDatasourceCommand marks a datasource as managed by provisioning.
+type SaveProvisionedDatasourceCommand struct {
+	DatasourceId int64
+	OrgId        int64
+	Name         string
+	CheckSum     string
+}
+
 // ---------------------
 // QUERIES
 
+// GetProvisionedDatasourcesQuery returns the datasources marked as managed by provisioning.
+type GetProvisionedDatasourcesQuery struct {
+	Result []*DataSource
+}
+
+// GetDeletedProvisionedDatasourceQuery returns the mark of the named datasource of an org that provisioning created
+// and that was then deleted other than by provisioning, or nil when there's none.
+type GetDeletedProvisionedDatasourceQuery struct {
+	OrgId int64
+	Name  string
+
+	Result *DatasourceProvisioning
+}
+
 type GetDataSourcesQuery struct {
 	OrgId           int64
 	DataSourceLimit int
@@ -27,6 +27,14 @@ type Org struct {
 	Updated time.Time
 }
 
+// OrgProvisioning links an organization to the external ID it was provisioned with.
+type OrgProvisioning struct {
+	Id         int64
+	OrgId      int64
+	ExternalId string
+	Updated    int64
+}
+
 // ---------------------
 // COMMANDS
 
@@ -52,6 +60,11 @@ type UpdateOrgAddressCommand struct {
 	Address
 }
 
+type SaveProvisionedOrgCommand struct {
+	OrgId      int64
+	ExternalId string
+}
+
 type GetOrgByIdQuery struct {
 	Id     int64
 	Result *Org
@@ -62,6 +75,10 @@ type GetOrgByNameQuery struct {
 	Result *Org
 }
 
+type GetProvisionedOrgsQuery struct {
+	Result []*OrgProvisioning
+}
+
 type SearchOrgsQuery struct {
 	Query string
 	Name  string
@@ -25,6 +25,15 @@ type PluginSetting struct {
 	Updated time.Time
 }
 
+// PluginSettingProvisioning marks the settings of an app in an org as applied by provisioning, as opposed to the
+// UI or API.
+type PluginSettingProvisioning struct {
+	Id       int64
+	OrgId    int64
+	PluginId string
+	Updated  int64
+}
+
 // ----------------------
 // COMMANDS
 
@@ -47,6 +56,19 @@ type UpdatePluginSettingVersionCmd struct {
 	OrgId         int64  `json:"-"`
 }
 
+// SaveProvisionedPluginSettingCommand marks the settings of an app in an org as managed by provisioning.
+type SaveProvisionedPluginSettingCommand struct {
+	OrgId    int64
+	PluginId string
+}
+
+// DeleteProvisionedPluginSettingCommand removes the provisioning mark of the settings of an app in an org, which
+// leaves the settings themselves as they are.
+type DeleteProvisionedPluginSettingCommand struct {
+	OrgId    int64
+	PluginId string
+}
+
 func (cmd *UpdatePluginSettingCmd) GetEncryptedJsonData() securejsondata.SecureJsonData {
 	return securejsondata.GetEncryptedJsonData(cmd.SecureJsonData)
 }
@@ -68,6 +90,11 @@ type GetPluginSettingByIdQuery struct {
 	Result   *PluginSetting
 }
 
+// GetProvisionedPluginSettingsQuery returns the marks of the app settings managed by provisioning.
+type GetProvisionedPluginSettingsQuery struct {
+	Result []*PluginSettingProvisioning
+}
+
 type PluginStateChangedEvent struct {
 	PluginId string
 	OrgId    int64
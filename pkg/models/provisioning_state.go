@@ -0,0 +1,33 @@
+package models
+
+// ProvisioningState is the state provisioning keeps in the database to recognize what it provisioned before.
+type ProvisioningState struct {
+	Orgs       []*OrgProvisioning
+	Dashboards []*DashboardProvisioningState
+}
+
+// DashboardProvisioningState is the provisioning record of a dashboard. The dashboard is referenced by org and UID
+// rather than by ID, so the record can be restored on another instance.
+type DashboardProvisioningState struct {
+	OrgId        int64
+	DashboardUid string
+	Name         string
+	ExternalId   string
+	CheckSum     string
+	Updated      int64
+}
+
+// ---------------------
+// COMMANDS
+
+// RestoreProvisioningStateCommand replaces the provisioning records of the orgs and dashboards in State.
+type RestoreProvisioningStateCommand struct {
+	State *ProvisioningState
+}
+
+// ---------------------
+// QUERIES
+
+type GetProvisioningStateQuery struct {
+	Result *ProvisioningState
+}
@@ -2,6 +2,7 @@ package registry
 
 import (
 	"context"
+	"errors"
 	"reflect"
 	"sort"
 
@@ -129,6 +130,18 @@ type BackgroundService interface {
 	Run(ctx context.Context) error
 }
 
+// ErrStopServer is returned, possibly wrapped, by the Run method of a background service that is done and wants
+// the server to shut down, like one applying provisioning once. Unlike other errors, the server stops without one.
+var ErrStopServer = errors.New("service stopped the server")
+
+// ReloadableService should be implemented by services with settings
+// that can be changed without restarting Grafana.
+type ReloadableService interface {
+	// Reload is called when Grafana is asked to reload its configuration,
+	// e.g. on SIGHUP, and should apply the settings that changed.
+	Reload() error
+}
+
 // DatabaseMigrator allows the caller to add migrations to
 // the migrator passed as argument
 type DatabaseMigrator interface {
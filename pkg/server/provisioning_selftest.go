@@ -0,0 +1,27 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/grafana/grafana/pkg/services/provisioning"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// ProvisioningSelfTest runs the provisioning self-test of the configuration in place of the server, and prints its
+// results to out. Only the settings are loaded: the services aren't initialized and the migrations aren't run, and
+// the database is only pinged, so nothing is written to it. args are the cfg: arguments that override the settings.
+func ProvisioningSelfTest(ctx context.Context, cfg Config, args []string, out io.Writer) error {
+	settings := setting.NewCfg()
+	if err := settings.Load(&setting.CommandLineArgs{Config: cfg.ConfigFile, HomePath: cfg.HomePath, Args: args}); err != nil {
+		return fmt.Errorf("failed to load the settings: %w", err)
+	}
+
+	results := provisioning.RunSelfTest(ctx, settings, func(ctx context.Context) error {
+		return sqlstore.Ping(ctx, settings)
+	})
+	fmt.Fprint(out, results.String())
+	return results.Err()
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvisioningSelfTest(t *testing.T) {
+	setupSelfTest := func(t *testing.T) (string, []string) {
+		t.Helper()
+		dir := t.TempDir()
+		provisioning := filepath.Join(dir, "provisioning")
+		require.NoError(t, os.MkdirAll(filepath.Join(provisioning, "datasources"), 0750))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(provisioning, "datasources", "prometheus.yaml"),
+			[]byte("apiVersion: 1\ndatasources:\n  - name: Prometheus\n"), 0600))
+		return dir, []string{
+			"cfg:default.paths.data=" + dir,
+			"cfg:default.paths.logs=" + filepath.Join(dir, "log"),
+			"cfg:default.paths.provisioning=" + provisioning,
+			"cfg:default.log.mode=console",
+		}
+	}
+	cfg := Config{HomePath: "../../"}
+
+	t.Run("Nothing is written to the database", func(t *testing.T) {
+		dir, args := setupSelfTest(t)
+		db := filepath.Join(dir, "grafana.db")
+		require.NoError(t, ioutil.WriteFile(db, nil, 0600))
+
+		var out bytes.Buffer
+		require.NoError(t, ProvisioningSelfTest(context.Background(), cfg, args, &out))
+		assert.Contains(t, out.String(), "PASS  database: reachable\n")
+		assert.Contains(t, out.String(), "PASS  datasources: 1 directory, parsed ")
+
+		written, err := ioutil.ReadFile(db)
+		require.NoError(t, err)
+		assert.Empty(t, written, "The migrations ran or the main org was created")
+	})
+
+	t.Run("A SQLite database that doesn't exist fails without being created", func(t *testing.T) {
+		dir, args := setupSelfTest(t)
+
+		var out bytes.Buffer
+		err := ProvisioningSelfTest(context.Background(), cfg, args, &out)
+		require.EqualError(t, err, "provisioning self-test failed: database")
+		assert.Contains(t, out.String(), "FAIL  database: can't open SQLite database file: ")
+		assert.NoFileExists(t, filepath.Join(dir, "grafana.db"))
+	})
+}
@@ -192,6 +192,12 @@ func (s *Server) Run() error {
 			continue
 		}
 
+		// With apply_once the server stops once provisioning was applied, so it doesn't serve HTTP in between.
+		if _, isHTTPServer := svc.Instance.(*api.HTTPServer); isHTTPServer && s.applyOnce() {
+			s.log.Info("Provisioning is applied once, not starting the HTTP server")
+			continue
+		}
+
 		// Variable is needed for accessing loop variable in callback
 		descriptor := svc
 		s.childRoutines.Go(func() error {
@@ -201,6 +207,11 @@ func (s *Server) Run() error {
 			default:
 			}
 			err := service.Run(s.context)
+			if errors.Is(err, registry.ErrStopServer) {
+				// Returning the error cancels the context of the other services.
+				s.log.Info("Stopped "+descriptor.Name+", shutting down", "reason", err)
+				return err
+			}
 			// Do not return context.Canceled error since errgroup.Group only
 			// returns the first error to the caller - thus we can miss a more
 			// interesting error.
@@ -216,7 +227,16 @@ func (s *Server) Run() error {
 	s.notifySystemd("READY=1")
 
 	s.log.Debug("Waiting on services...")
-	return s.childRoutines.Wait()
+	err := s.childRoutines.Wait()
+	if errors.Is(err, registry.ErrStopServer) {
+		return nil
+	}
+	return err
+}
+
+// applyOnce reports whether the provisioning service stops the server once it was applied.
+func (s *Server) applyOnce() bool {
+	return s.cfg != nil && s.cfg.ProvisioningApplyOnce
 }
 
 // Shutdown initiates Grafana graceful shutdown. This shuts down all
@@ -235,6 +255,20 @@ func (s *Server) Shutdown(reason string) {
 	})
 }
 
+// Reload asks the services supporting it to reload their settings, e.g. on SIGHUP.
+func (s *Server) Reload() {
+	for _, svc := range s.serviceRegistry.GetServices() {
+		service, ok := svc.Instance.(registry.ReloadableService)
+		if !ok || s.serviceRegistry.IsDisabled(svc.Instance) {
+			continue
+		}
+
+		if err := service.Reload(); err != nil {
+			s.log.Error("Failed to reload "+svc.Name, "error", err)
+		}
+	}
+}
+
 // ExitCode returns an exit code for a given error.
 func (s *Server) ExitCode(runError error) int {
 	if runError != nil {
@@ -3,9 +3,13 @@ package server
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/grafana/grafana/pkg/api"
 	"github.com/grafana/grafana/pkg/registry"
+	"github.com/grafana/grafana/pkg/setting"
 
 	"github.com/stretchr/testify/require"
 )
@@ -82,6 +86,60 @@ func TestServer_Run_Error(t *testing.T) {
 	require.NotZero(t, s.ExitCode(err))
 }
 
+func TestServer_Run_StopServer(t *testing.T) {
+	s := testServer()
+
+	s.serviceRegistry = &testServiceRegistry{
+		services: []*registry.Descriptor{
+			{
+				Name:         "TestService1",
+				Instance:     newTestService(nil, nil),
+				InitPriority: registry.High,
+			},
+			{
+				Name:         "TestService2",
+				Instance:     newTestService(nil, fmt.Errorf("%w: done", registry.ErrStopServer)),
+				InitPriority: registry.High,
+			},
+		},
+	}
+
+	err := s.Run()
+	require.NoError(t, err)
+	require.Zero(t, s.ExitCode(err))
+}
+
+func TestServer_Run_ApplyOnceSkipsHTTPServer(t *testing.T) {
+	s := testServer()
+	s.cfg = &setting.Cfg{ProvisioningApplyOnce: true}
+
+	service := newTestService(nil, nil)
+	s.serviceRegistry = &testServiceRegistry{
+		services: []*registry.Descriptor{
+			{
+				// Running it panics, since none of its dependencies are set.
+				Name:         "HTTPServer",
+				Instance:     &api.HTTPServer{},
+				InitPriority: registry.High,
+			},
+			{
+				Name:         "TestService",
+				Instance:     service,
+				InitPriority: registry.High,
+			},
+		},
+	}
+
+	go func() {
+		<-service.started
+		// Give the HTTP server the time to start, if it wasn't skipped.
+		time.Sleep(100 * time.Millisecond)
+		s.Shutdown("test interrupt")
+	}()
+	err := s.Run()
+	require.NoError(t, err)
+}
+
 func TestServer_Shutdown(t *testing.T) {
 	s := testServer()
 	services := []*registry.Descriptor{
@@ -0,0 +1,32 @@
+package accesscontrol
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrDatasourcePermissionsUnavailable is returned when data source permissions are set while the service is disabled.
+var ErrDatasourcePermissionsUnavailable = errors.New("data source permissions are not available")
+
+// Data source permissions
+const (
+	DatasourcePermissionQuery = "query"
+	DatasourcePermissionEdit  = "edit"
+)
+
+// DatasourcePermission grants a team or a built-in role like Viewer a permission on a data source.
+type DatasourcePermission struct {
+	TeamID      int64
+	BuiltInRole string
+	Permission  string
+}
+
+// DatasourcePermissionsService manages who can query and edit data sources. Data source permissions are a Grafana
+// Enterprise feature, so the OSS service is always disabled.
+type DatasourcePermissionsService interface {
+	// IsDisabled returns true when data source permissions aren't available.
+	IsDisabled() bool
+
+	// SetDatasourcePermissions replaces the permissions of a data source, revoking the ones that aren't listed.
+	SetDatasourcePermissions(ctx context.Context, orgID int64, datasourceID int64, permissions []DatasourcePermission) error
+}
@@ -0,0 +1,26 @@
+package ossaccesscontrol
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+)
+
+// OSSDatasourcePermissionsService is the data source permissions service of OSS builds, which don't have data
+// source permissions.
+type OSSDatasourcePermissionsService struct{}
+
+// Init initializes the OSSDatasourcePermissionsService.
+func (s *OSSDatasourcePermissionsService) Init() error {
+	return nil
+}
+
+// IsDisabled returns true since OSS builds don't have data source permissions.
+func (s *OSSDatasourcePermissionsService) IsDisabled() bool {
+	return true
+}
+
+// SetDatasourcePermissions always fails with accesscontrol.ErrDatasourcePermissionsUnavailable.
+func (s *OSSDatasourcePermissionsService) SetDatasourcePermissions(context.Context, int64, int64, []accesscontrol.DatasourcePermission) error {
+	return accesscontrol.ErrDatasourcePermissionsUnavailable
+}
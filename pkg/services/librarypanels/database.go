@@ -193,6 +193,9 @@ func (lps *LibraryPanelService) deleteLibraryPanel(c *models.ReqContext, uid str
 			return errLibraryPanelHasConnectedDashboards
 		}
 
+		if _, err := session.Exec("DELETE FROM library_panel_provenance WHERE librarypanel_id=?", panel.ID); err != nil {
+			return err
+		}
 		result, err := session.Exec("DELETE FROM library_panel WHERE id=?", panel.ID)
 		if err != nil {
 			return err
@@ -294,6 +297,10 @@ func (lps *LibraryPanelService) deleteLibraryPanelsInFolder(c *models.ReqContext
 			if err != nil {
 				return err
 			}
+			_, err = session.Exec("DELETE FROM library_panel_provenance WHERE librarypanel_id=?", panelID.ID)
+			if err != nil {
+				return err
+			}
 		}
 		if _, err := session.Exec("DELETE FROM library_panel WHERE folder_id=? AND org_id=?", folderID, c.SignedInUser.OrgId); err != nil {
 			return err
@@ -277,4 +277,19 @@ func (lps *LibraryPanelService) AddMigration(mg *migrator.Migrator) {
 
 	mg.AddMigration("create library_panel_dashboard table v1", migrator.NewAddTableMigration(libraryPanelDashboardV1))
 	mg.AddMigration("add index library_panel_dashboard librarypanel_id & dashboard_id", migrator.NewAddIndexMigration(libraryPanelDashboardV1, libraryPanelDashboardV1.Indices[0]))
+
+	libraryPanelProvenanceV1 := migrator.Table{
+		Name: "library_panel_provenance",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "librarypanel_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "provenance", Type: migrator.DB_NVarchar, Length: 40, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"librarypanel_id"}, Type: migrator.UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create library_panel_provenance table v1", migrator.NewAddTableMigration(libraryPanelProvenanceV1))
+	mg.AddMigration("add unique index library_panel_provenance librarypanel_id", migrator.NewAddIndexMigration(libraryPanelProvenanceV1, libraryPanelProvenanceV1.Indices[0]))
 }
@@ -0,0 +1,109 @@
+package librarypanels
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvisioningStore(t *testing.T) {
+	provisioned := func(sc scenarioContext) ProvisionedLibraryPanel {
+		return ProvisionedLibraryPanel{
+			OrgID:      sc.user.OrgId,
+			FolderID:   sc.folder.Id,
+			UID:        "provisioned",
+			Name:       "Provisioned",
+			Model:      json.RawMessage(`{"type": "text", "options": {"content": "provisioned"}}`),
+			Provenance: ProvenanceFile,
+		}
+	}
+
+	testScenario(t, "Upserting a library panel creates it and then updates it", func(t *testing.T, sc scenarioContext) {
+		store := ProvisioningStore{SQLStore: sc.sqlStore}
+		panel := provisioned(sc)
+
+		created, err := store.UpsertProvisionedLibraryPanel(context.Background(), panel)
+		require.NoError(t, err)
+		assert.True(t, created)
+
+		panel.Name = "Renamed"
+		created, err = store.UpsertProvisionedLibraryPanel(context.Background(), panel)
+		require.NoError(t, err)
+		assert.False(t, created)
+
+		stored, err := store.GetProvisionedLibraryPanel(context.Background(), sc.user.OrgId, "provisioned")
+		require.NoError(t, err)
+		assert.Equal(t, "Renamed", stored.Name)
+		assert.Equal(t, sc.folder.Id, stored.FolderID)
+		assert.Equal(t, ProvenanceFile, stored.Provenance)
+		model, err := simplejson.NewJson(stored.Model)
+		require.NoError(t, err)
+		assert.Equal(t, "Renamed", model.Get("title").MustString())
+		assert.Equal(t, "provisioned", model.GetPath("options", "content").MustString())
+
+		dto, err := sc.service.getLibraryPanel(sc.reqContext, "provisioned")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), dto.Version)
+	})
+
+	scenarioWithLibraryPanel(t, "Library panels created in the UI have no provenance", func(t *testing.T, sc scenarioContext) {
+		store := ProvisioningStore{SQLStore: sc.sqlStore}
+
+		stored, err := store.GetProvisionedLibraryPanel(context.Background(), sc.user.OrgId, sc.initialResult.Result.UID)
+		require.NoError(t, err)
+		assert.Empty(t, stored.Provenance)
+
+		_, err = store.GetProvisionedLibraryPanel(context.Background(), sc.user.OrgId, "unknown")
+		assert.ErrorIs(t, err, ErrProvisionedLibraryPanelNotFound)
+	})
+
+	testScenario(t, "Deleting a library panel deletes it with its provenance", func(t *testing.T, sc scenarioContext) {
+		store := ProvisioningStore{SQLStore: sc.sqlStore}
+		_, err := store.UpsertProvisionedLibraryPanel(context.Background(), provisioned(sc))
+		require.NoError(t, err)
+
+		require.NoError(t, store.DeleteProvisionedLibraryPanel(context.Background(), sc.user.OrgId, "provisioned"))
+		_, err = store.GetProvisionedLibraryPanel(context.Background(), sc.user.OrgId, "provisioned")
+		assert.ErrorIs(t, err, ErrProvisionedLibraryPanelNotFound)
+		count, err := sc.sqlStore.NewSession().Count(&libraryPanelProvenance{})
+		require.NoError(t, err)
+		assert.Zero(t, count)
+
+		err = store.DeleteProvisionedLibraryPanel(context.Background(), sc.user.OrgId, "provisioned")
+		assert.ErrorIs(t, err, ErrProvisionedLibraryPanelNotFound)
+	})
+
+	scenarioWithLibraryPanel(t, "Dashboards using a library panel are listed", func(t *testing.T, sc scenarioContext) {
+		store := ProvisioningStore{SQLStore: sc.sqlStore}
+		uid := sc.initialResult.Result.UID
+
+		connected := createDashboard(t, sc.sqlStore, sc.user, "Connected", sc.folder.Id)
+		require.NoError(t, sc.service.connectDashboard(sc.reqContext, uid, connected.Id))
+		createDashboard(t, sc.sqlStore, sc.user, "Unrelated", sc.folder.Id)
+
+		dashboard := models.NewDashboard("Provisioned")
+		dashboard.Data.Set("panels", []interface{}{
+			map[string]interface{}{"type": "row", "collapsed": true, "panels": []interface{}{
+				map[string]interface{}{"libraryPanel": map[string]interface{}{"uid": uid}},
+			}},
+		})
+		_, err := sc.sqlStore.SaveProvisionedDashboard(models.SaveDashboardCommand{
+			Dashboard: dashboard.Data,
+			OrgId:     sc.user.OrgId,
+		}, &models.DashboardProvisioning{Name: "default", ExternalId: "provisioned.json"})
+		require.NoError(t, err)
+
+		dashboards, err := store.GetLibraryPanelDashboards(context.Background(), sc.user.OrgId, uid)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Connected", "Provisioned"}, dashboards)
+
+		dashboards, err = store.GetLibraryPanelDashboards(context.Background(), sc.user.OrgId, "unknown")
+		require.NoError(t, err)
+		assert.Empty(t, dashboards)
+	})
+}
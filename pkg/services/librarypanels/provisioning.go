@@ -0,0 +1,211 @@
+package librarypanels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// ProvenanceFile is the provenance of library panels provisioned from files.
+const ProvenanceFile = "file"
+
+// ErrProvisionedLibraryPanelNotFound is returned by ProvisioningStore when a library panel doesn't exist.
+var ErrProvisionedLibraryPanelNotFound = errLibraryPanelNotFound
+
+// libraryPanelProvenance is the model for the provenance of library panels that aren't managed in the UI.
+type libraryPanelProvenance struct {
+	ID             int64 `xorm:"pk autoincr 'id'"`
+	LibraryPanelID int64 `xorm:"librarypanel_id"`
+	Provenance     string
+}
+
+// ProvisionedLibraryPanel is a library panel as provisioning reads and writes it.
+type ProvisionedLibraryPanel struct {
+	OrgID    int64
+	FolderID int64
+	UID      string
+	Name     string
+	Model    json.RawMessage
+	// Provenance is empty for library panels created in the UI.
+	Provenance string
+}
+
+// ProvisioningStore reads and writes library panels for provisioning, which doesn't have a signed in user to check
+// the folder permissions of.
+type ProvisioningStore struct {
+	SQLStore *sqlstore.SQLStore
+}
+
+// GetProvisionedLibraryPanel returns the library panel with uid in an org, or ErrProvisionedLibraryPanelNotFound.
+func (s ProvisioningStore) GetProvisionedLibraryPanel(ctx context.Context, orgID int64, uid string) (*ProvisionedLibraryPanel, error) {
+	var panel *ProvisionedLibraryPanel
+	err := s.SQLStore.WithDbSession(ctx, func(session *sqlstore.DBSession) error {
+		libraryPanel, err := getLibraryPanel(session, uid, orgID)
+		if err != nil {
+			return err
+		}
+		var provenance libraryPanelProvenance
+		if _, err := session.Where("librarypanel_id=?", libraryPanel.ID).Get(&provenance); err != nil {
+			return err
+		}
+		panel = &ProvisionedLibraryPanel{
+			OrgID:      libraryPanel.OrgID,
+			FolderID:   libraryPanel.FolderID,
+			UID:        libraryPanel.UID,
+			Name:       libraryPanel.Name,
+			Model:      libraryPanel.Model,
+			Provenance: provenance.Provenance,
+		}
+		return nil
+	})
+	return panel, err
+}
+
+// UpsertProvisionedLibraryPanel creates the library panel, or updates the one with the same uid in the org, and
+// records its provenance. It returns whether the panel was created.
+func (s ProvisioningStore) UpsertProvisionedLibraryPanel(ctx context.Context, panel ProvisionedLibraryPanel) (bool, error) {
+	created := false
+	err := s.SQLStore.WithTransactionalDbSession(ctx, func(session *sqlstore.DBSession) error {
+		existing, err := getLibraryPanel(session, panel.UID, panel.OrgID)
+		if err != nil && !errors.Is(err, errLibraryPanelNotFound) {
+			return err
+		}
+		created = errors.Is(err, errLibraryPanelNotFound)
+
+		libraryPanel := LibraryPanel{
+			OrgID:    panel.OrgID,
+			FolderID: panel.FolderID,
+			UID:      panel.UID,
+			Name:     panel.Name,
+			Model:    panel.Model,
+			Version:  1,
+			Created:  time.Now(),
+			Updated:  time.Now(),
+		}
+		if libraryPanel.UID == "" {
+			libraryPanel.UID = util.GenerateShortUID()
+		}
+		if err := syncFieldsWithModel(&libraryPanel); err != nil {
+			return err
+		}
+
+		if created {
+			if _, err := session.Insert(&libraryPanel); err != nil {
+				if s.SQLStore.Dialect.IsUniqueConstraintViolation(err) {
+					return errLibraryPanelAlreadyExists
+				}
+				return err
+			}
+		} else {
+			libraryPanel.ID = existing.ID
+			libraryPanel.Created = existing.Created
+			libraryPanel.CreatedBy = existing.CreatedBy
+			libraryPanel.UpdatedBy = existing.UpdatedBy
+			libraryPanel.Version = existing.Version + 1
+			if _, err := session.ID(existing.ID).AllCols().Update(&libraryPanel); err != nil {
+				if s.SQLStore.Dialect.IsUniqueConstraintViolation(err) {
+					return errLibraryPanelAlreadyExists
+				}
+				return err
+			}
+		}
+
+		if _, err := session.Exec("DELETE FROM library_panel_provenance WHERE librarypanel_id=?", libraryPanel.ID); err != nil {
+			return err
+		}
+		_, err = session.Insert(&libraryPanelProvenance{LibraryPanelID: libraryPanel.ID, Provenance: panel.Provenance})
+		return err
+	})
+	return created, err
+}
+
+// DeleteProvisionedLibraryPanel deletes the library panel with uid in an org, along with its connections and its
+// provenance. It returns ErrProvisionedLibraryPanelNotFound when the panel doesn't exist.
+func (s ProvisioningStore) DeleteProvisionedLibraryPanel(ctx context.Context, orgID int64, uid string) error {
+	return s.SQLStore.WithTransactionalDbSession(ctx, func(session *sqlstore.DBSession) error {
+		panel, err := getLibraryPanel(session, uid, orgID)
+		if err != nil {
+			return err
+		}
+		for _, sql := range []string{
+			"DELETE FROM library_panel_dashboard WHERE librarypanel_id=?",
+			"DELETE FROM library_panel_provenance WHERE librarypanel_id=?",
+			"DELETE FROM library_panel WHERE id=?",
+		} {
+			if _, err := session.Exec(sql, panel.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetLibraryPanelDashboards returns the titles of the dashboards of an org that use the library panel with uid:
+// the ones connected to it when they were saved in the UI, and the provisioned ones whose JSON references it, since
+// provisioning doesn't connect them.
+func (s ProvisioningStore) GetLibraryPanelDashboards(ctx context.Context, orgID int64, uid string) ([]string, error) {
+	titles := map[string]bool{}
+	err := s.SQLStore.WithDbSession(ctx, func(session *sqlstore.DBSession) error {
+		var connected []struct {
+			Title string
+		}
+		sql := "SELECT dashboard.title FROM library_panel_dashboard AS lpd"
+		sql += " INNER JOIN library_panel AS lp ON lp.id = lpd.librarypanel_id"
+		sql += " INNER JOIN dashboard ON dashboard.id = lpd.dashboard_id"
+		sql += " WHERE lp.uid=? AND lp.org_id=?"
+		if err := session.SQL(sql, uid, orgID).Find(&connected); err != nil {
+			return err
+		}
+		for _, dashboard := range connected {
+			titles[dashboard.Title] = true
+		}
+
+		var provisioned []struct {
+			Title string
+			Data  *simplejson.Json
+		}
+		sql = "SELECT dashboard.title, dashboard.data FROM dashboard"
+		sql += " INNER JOIN dashboard_provisioning ON dashboard_provisioning.dashboard_id = dashboard.id"
+		sql += " WHERE dashboard.org_id=?"
+		if err := session.SQL(sql, orgID).Find(&provisioned); err != nil {
+			return err
+		}
+		for _, dashboard := range provisioned {
+			if dashboard.Data != nil && usesLibraryPanel(dashboard.Data, uid) {
+				titles[dashboard.Title] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dashboards := make([]string, 0, len(titles))
+	for title := range titles {
+		dashboards = append(dashboards, title)
+	}
+	sort.Strings(dashboards)
+	return dashboards, nil
+}
+
+// usesLibraryPanel reports whether a panel of the dashboard JSON, including the panels of collapsed rows, is the
+// library panel with uid.
+func usesLibraryPanel(data *simplejson.Json, uid string) bool {
+	panels := data.Get("panels").MustArray()
+	for len(panels) > 0 {
+		panel := simplejson.NewFromAny(panels[0])
+		panels = panels[1:]
+		if panel.GetPath("libraryPanel", "uid").MustString() == uid {
+			return true
+		}
+		panels = append(panels, panel.Get("panels").MustArray()...)
+	}
+	return false
+}
@@ -175,6 +175,14 @@ func (srv AlertmanagerSrv) RouteGetSilences(c *models.ReqContext) response.Respo
 }
 
 func (srv AlertmanagerSrv) RoutePostAlertingConfig(c *models.ReqContext, body apimodels.PostableUserConfig) response.Response {
+	if err := srv.checkProvisionedConfiguration(&body); err != nil {
+		var provisionedErr errProvisionedChange
+		if errors.As(err, &provisionedErr) {
+			return response.Error(http.StatusBadRequest, err.Error(), nil)
+		}
+		return response.Error(http.StatusInternalServerError, "failed to check provisioned Alertmanager configuration", err)
+	}
+
 	if err := srv.am.SaveAndApplyConfig(&body); err != nil {
 		return response.Error(http.StatusInternalServerError, "failed to save and apply Alertmanager configuration", err)
 	}
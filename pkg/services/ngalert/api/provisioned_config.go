@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/notifier"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+)
+
+// checkProvisionedConfiguration returns an error if the configuration posted through the API changes a provisioned
+// contact point, notification template or notification policy tree that doesn't allow updates from the UI.
+func (srv AlertmanagerSrv) checkProvisionedConfiguration(updated *apimodels.PostableUserConfig) error {
+	provenanceQuery := ngmodels.GetAlertConfigurationProvenancesQuery{}
+	if err := srv.store.GetAlertConfigurationProvenances(&provenanceQuery); err != nil {
+		return err
+	}
+	if len(provenanceQuery.Result) == 0 {
+		return nil
+	}
+
+	query := ngmodels.GetLatestAlertmanagerConfigurationQuery{}
+	if err := srv.store.GetLatestAlertmanagerConfiguration(&query); err != nil {
+		if errors.Is(err, store.ErrNoAlertmanagerConfiguration) {
+			return nil
+		}
+		return err
+	}
+	current, err := notifier.Load([]byte(query.Result.AlertmanagerConfiguration))
+	if err != nil {
+		return err
+	}
+
+	return checkProvisionedChanges(current, updated, provenanceQuery.Result)
+}
+
+// errProvisionedChange is returned when a configuration changes a provisioned part that doesn't allow updates
+// from the UI.
+type errProvisionedChange struct {
+	what string
+}
+
+func (e errProvisionedChange) Error() string {
+	return fmt.Sprintf("%s is provisioned and can't be changed through the API", e.what)
+}
+
+func checkProvisionedChanges(current, updated *apimodels.PostableUserConfig, provenances []*ngmodels.AlertConfigurationProvenance) error {
+	for _, provenance := range provenances {
+		if provenance.Provenance == ngmodels.ProvenanceNone || provenance.AllowUIUpdates {
+			continue
+		}
+
+		switch provenance.RecordType {
+		case ngmodels.ContactPointRecordType:
+			currentReceiver := findReceiver(current, provenance.RecordKey)
+			if currentReceiver == nil {
+				continue
+			}
+			if !receiversEqual(currentReceiver, findReceiver(updated, provenance.RecordKey)) {
+				return errProvisionedChange{what: fmt.Sprintf("contact point %q", provenance.RecordKey)}
+			}
+		case ngmodels.TemplateRecordType:
+			currentTemplate, exists := current.TemplateFiles[provenance.RecordKey]
+			if !exists {
+				continue
+			}
+			if updatedTemplate, exists := updated.TemplateFiles[provenance.RecordKey]; !exists || updatedTemplate != currentTemplate {
+				return errProvisionedChange{what: fmt.Sprintf("template %q", provenance.RecordKey)}
+			}
+		case ngmodels.NotificationPolicyRecordType:
+			if !jsonEqual(current.AlertmanagerConfig.Route, updated.AlertmanagerConfig.Route) {
+				return errProvisionedChange{what: "the notification policy tree"}
+			}
+		}
+	}
+	return nil
+}
+
+func findReceiver(cfg *apimodels.PostableUserConfig, name string) *apimodels.PostableApiReceiver {
+	for _, receiver := range cfg.AlertmanagerConfig.Receivers {
+		if receiver.Name == name {
+			return receiver
+		}
+	}
+	return nil
+}
+
+// receiversEqual compares two receivers. Secure settings are only compared when the updated receiver sets them,
+// since they aren't returned to the UI.
+func receiversEqual(current, updated *apimodels.PostableApiReceiver) bool {
+	if updated == nil || len(current.GrafanaManagedReceivers) != len(updated.GrafanaManagedReceivers) {
+		return false
+	}
+
+	for i, currentReceiver := range current.GrafanaManagedReceivers {
+		updatedReceiver := updated.GrafanaManagedReceivers[i]
+		for key, value := range updatedReceiver.SecureSettings {
+			if currentReceiver.SecureSettings[key] != value {
+				return false
+			}
+		}
+
+		c, u := *currentReceiver, *updatedReceiver
+		c.SecureSettings, u.SecureSettings = nil, nil
+		if !jsonEqual(c, u) {
+			return false
+		}
+	}
+	return true
+}
+
+func jsonEqual(a, b interface{}) bool {
+	aJSON, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bJSON, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+
+	var aValue, bValue interface{}
+	if err := json.Unmarshal(aJSON, &aValue); err != nil {
+		return false
+	}
+	if err := json.Unmarshal(bJSON, &bValue); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(aValue, bValue)
+}
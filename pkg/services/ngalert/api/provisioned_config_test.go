@@ -0,0 +1,96 @@
+package api
+
+import (
+	"testing"
+
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/notifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const provisionedTestConfig = `{
+	"template_files": {"ops.tmpl": "{{ define \"ops.title\" }}Ops{{ end }}"},
+	"alertmanager_config": {
+		"route": {"receiver": "ops"},
+		"receivers": [
+			{"name": "ops", "grafana_managed_receiver_configs": [{"uid": "ops", "name": "ops", "type": "webhook", "settings": {"url": "http://localhost"}, "secureSettings": {"password": "secret"}}]},
+			{"name": "ui", "grafana_managed_receiver_configs": [{"uid": "ui", "name": "ui", "type": "email", "settings": {"addresses": "ui@example.com"}}]}
+		]
+	}
+}`
+
+func TestCheckProvisionedChanges(t *testing.T) {
+	load := func(t *testing.T, raw string) *apimodels.PostableUserConfig {
+		t.Helper()
+		cfg, err := notifier.Load([]byte(raw))
+		require.NoError(t, err)
+		return cfg
+	}
+	provisioned := []*ngmodels.AlertConfigurationProvenance{
+		{RecordType: ngmodels.ContactPointRecordType, RecordKey: "ops", Provenance: ngmodels.ProvenanceFile},
+		{RecordType: ngmodels.NotificationPolicyRecordType, RecordKey: ngmodels.NotificationPolicyRecordKey, Provenance: ngmodels.ProvenanceFile},
+		{RecordType: ngmodels.TemplateRecordType, RecordKey: "ops.tmpl", Provenance: ngmodels.ProvenanceFile},
+	}
+
+	t.Run("Allows changes to contact points that aren't provisioned", func(t *testing.T) {
+		updated := load(t, provisionedTestConfig)
+		updated.AlertmanagerConfig.Receivers = updated.AlertmanagerConfig.Receivers[:1]
+
+		require.NoError(t, checkProvisionedChanges(load(t, provisionedTestConfig), updated, provisioned))
+	})
+
+	t.Run("Allows posting provisioned contact points without their secure settings", func(t *testing.T) {
+		updated := load(t, provisionedTestConfig)
+		updated.AlertmanagerConfig.Receivers[0].GrafanaManagedReceivers[0].SecureSettings = nil
+
+		require.NoError(t, checkProvisionedChanges(load(t, provisionedTestConfig), updated, provisioned))
+	})
+
+	t.Run("Rejects changes to provisioned contact points", func(t *testing.T) {
+		updated := load(t, provisionedTestConfig)
+		updated.AlertmanagerConfig.Receivers[0].GrafanaManagedReceivers[0].Settings.Set("url", "http://example.com")
+
+		err := checkProvisionedChanges(load(t, provisionedTestConfig), updated, provisioned)
+		require.EqualError(t, err, `contact point "ops" is provisioned and can't be changed through the API`)
+	})
+
+	t.Run("Rejects deleting provisioned contact points", func(t *testing.T) {
+		updated := load(t, provisionedTestConfig)
+		updated.AlertmanagerConfig.Receivers = updated.AlertmanagerConfig.Receivers[1:]
+
+		require.Error(t, checkProvisionedChanges(load(t, provisionedTestConfig), updated, provisioned))
+	})
+
+	t.Run("Rejects changes to a provisioned notification policy tree", func(t *testing.T) {
+		updated := load(t, provisionedTestConfig)
+		updated.AlertmanagerConfig.Route.Receiver = "ui"
+
+		err := checkProvisionedChanges(load(t, provisionedTestConfig), updated, provisioned)
+		require.EqualError(t, err, "the notification policy tree is provisioned and can't be changed through the API")
+	})
+
+	t.Run("Rejects changes to provisioned templates", func(t *testing.T) {
+		updated := load(t, provisionedTestConfig)
+		updated.TemplateFiles["ops.tmpl"] = `{{ define "ops.title" }}Changed{{ end }}`
+
+		err := checkProvisionedChanges(load(t, provisionedTestConfig), updated, provisioned)
+		require.EqualError(t, err, `template "ops.tmpl" is provisioned and can't be changed through the API`)
+
+		delete(updated.TemplateFiles, "ops.tmpl")
+		require.Error(t, checkProvisionedChanges(load(t, provisionedTestConfig), updated, provisioned))
+	})
+
+	t.Run("Allows changes to provisioned parts that allow UI updates", func(t *testing.T) {
+		updated := load(t, provisionedTestConfig)
+		updated.AlertmanagerConfig.Route.Receiver = "ui"
+		updated.AlertmanagerConfig.Receivers = updated.AlertmanagerConfig.Receivers[1:]
+
+		editable := []*ngmodels.AlertConfigurationProvenance{
+			{RecordType: ngmodels.ContactPointRecordType, RecordKey: "ops", Provenance: ngmodels.ProvenanceFile, AllowUIUpdates: true},
+			{RecordType: ngmodels.NotificationPolicyRecordType, RecordKey: ngmodels.NotificationPolicyRecordKey, Provenance: ngmodels.ProvenanceFile, AllowUIUpdates: true},
+		}
+		assert.NoError(t, checkProvisionedChanges(load(t, provisionedTestConfig), updated, editable))
+	})
+}
@@ -354,6 +354,8 @@ type Config struct {
 	InhibitRules []*config.InhibitRule `yaml:"inhibit_rules,omitempty" json:"inhibit_rules,omitempty"`
 	Receivers    []*config.Receiver    `yaml:"-" json:"receivers,omitempty"`
 	Templates    []string              `yaml:"templates" json:"templates"`
+	// MuteTimeIntervals are the named time intervals that routes reference to be muted during.
+	MuteTimeIntervals []config.MuteTimeInterval `yaml:"mute_time_intervals,omitempty" json:"mute_time_intervals,omitempty"`
 }
 
 type PostableApiAlertingConfig struct {
@@ -43,6 +43,15 @@ const (
 	KeepLastStateErrState ExecutionErrorState = "KeepLastState"
 )
 
+const (
+	// BaseIntervalSeconds is the scheduler interval, alert rule intervals have to be a multiple of it.
+	// Changing this value is discouraged, since existing rules with intervals that aren't exactly divided
+	// by the new value wouldn't be evaluated.
+	BaseIntervalSeconds = 10
+	// DefaultIntervalSeconds is the interval of alert rules that don't set one.
+	DefaultIntervalSeconds int64 = 6 * BaseIntervalSeconds
+)
+
 const (
 	UIDLabel          = "__alert_rule_uid__"
 	NamespaceUIDLabel = "__alert_rule_namespace_uid__"
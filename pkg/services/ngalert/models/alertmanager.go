@@ -34,3 +34,51 @@ type SaveAlertmanagerConfigurationCmd struct {
 type DeleteAlertmanagerConfigurationCmd struct {
 	ID int64
 }
+
+// Provenance tells where a part of the Alertmanager configuration is managed.
+type Provenance string
+
+const (
+	// ProvenanceNone is the provenance of configuration managed in the UI or through the API.
+	ProvenanceNone Provenance = ""
+	// ProvenanceFile is the provenance of configuration provisioned from files.
+	ProvenanceFile Provenance = "file"
+)
+
+const (
+	// ContactPointRecordType is the record type of the provenance of a contact point, keyed by its name.
+	ContactPointRecordType = "contact_point"
+	// NotificationPolicyRecordType is the record type of the provenance of the notification policy tree, keyed by
+	// NotificationPolicyRecordKey.
+	NotificationPolicyRecordType = "notification_policy"
+	// NotificationPolicyRecordKey is the key of the provenance of the notification policy tree.
+	NotificationPolicyRecordKey = "root"
+	// TemplateRecordType is the record type of the provenance of a notification template, keyed by its file name.
+	TemplateRecordType = "template"
+	// MuteTimingRecordType is the record type of the provenance of a mute timing, keyed by its name.
+	MuteTimingRecordType = "mute_timing"
+)
+
+// AlertConfigurationProvenance is the provenance of a contact point, a notification template, a mute timing or of
+// the notification policy tree of the Alertmanager configuration.
+type AlertConfigurationProvenance struct {
+	ID             int64 `xorm:"pk autoincr 'id'"`
+	RecordType     string
+	RecordKey      string
+	Provenance     Provenance
+	AllowUIUpdates bool `xorm:"allow_ui_updates"`
+}
+
+// GetAlertConfigurationProvenancesQuery is the query to get the provenance of the parts of the Alertmanager
+// configuration that aren't managed in the UI.
+type GetAlertConfigurationProvenancesQuery struct {
+	Result []*AlertConfigurationProvenance
+}
+
+// SaveProvisionedAlertmanagerConfigurationCmd is the command to save an Alertmanager configuration together with
+// the provenance of its provisioned parts, replacing the provenance of the previously provisioned parts.
+type SaveProvisionedAlertmanagerConfigurationCmd struct {
+	AlertmanagerConfiguration string
+	ConfigurationVersion      string
+	Provenances               []*AlertConfigurationProvenance
+}
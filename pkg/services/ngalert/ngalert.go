@@ -15,6 +15,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/services/ngalert/api"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
 	"github.com/grafana/grafana/pkg/services/ngalert/notifier"
 	"github.com/grafana/grafana/pkg/services/ngalert/schedule"
 	"github.com/grafana/grafana/pkg/services/ngalert/store"
@@ -27,13 +28,9 @@ import (
 const (
 	maxAttempts int64 = 3
 	// scheduler interval
-	// changing this value is discouraged
-	// because this could cause existing alert definition
-	// with intervals that are not exactly divided by this number
-	// not to be evaluated
-	baseIntervalSeconds = 10
+	baseIntervalSeconds = ngmodels.BaseIntervalSeconds
 	// default alert definiiton interval
-	defaultIntervalSeconds int64 = 6 * baseIntervalSeconds
+	defaultIntervalSeconds = ngmodels.DefaultIntervalSeconds
 )
 
 // AlertNG is the service for evaluating the condition of an alert definition.
@@ -21,6 +21,7 @@ import (
 	"github.com/prometheus/alertmanager/provider/mem"
 	"github.com/prometheus/alertmanager/silence"
 	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/timeinterval"
 	"github.com/prometheus/alertmanager/types"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
@@ -180,6 +181,7 @@ func (am *Alertmanager) Run(ctx context.Context) error {
 // AddMigration runs the database migrations as the service starts.
 func (am *Alertmanager) AddMigration(mg *migrator.Migrator) {
 	alertmanagerConfigurationMigration(mg)
+	alertConfigurationProvenanceMigration(mg)
 }
 
 func (am *Alertmanager) StopAndWait() error {
@@ -319,11 +321,17 @@ func (am *Alertmanager) applyConfig(cfg *apimodels.PostableUserConfig) error {
 	am.inhibitor = inhibit.NewInhibitor(am.alerts, cfg.AlertmanagerConfig.InhibitRules, am.marker, gokit_log.NewNopLogger())
 	am.silencer = silence.NewSilencer(am.silences, am.marker, gokit_log.NewNopLogger())
 
+	muteTimes := make(map[string][]timeinterval.TimeInterval, len(cfg.AlertmanagerConfig.MuteTimeIntervals))
+	for _, ti := range cfg.AlertmanagerConfig.MuteTimeIntervals {
+		muteTimes[ti.Name] = ti.TimeIntervals
+	}
+
 	inhibitionStage := notify.NewMuteStage(am.inhibitor)
+	timeMuteStage := notify.NewTimeMuteStage(muteTimes)
 	silencingStage := notify.NewMuteStage(am.silencer)
 	for name := range integrationsMap {
 		stage := am.createReceiverStage(name, integrationsMap[name], waitFunc, am.notificationLog)
-		routingStage[name] = notify.MultiStage{silencingStage, inhibitionStage, stage}
+		routingStage[name] = notify.MultiStage{silencingStage, inhibitionStage, timeMuteStage, stage}
 	}
 
 	am.route = dispatch.NewRoute(cfg.AlertmanagerConfig.Route, nil)
@@ -373,36 +381,7 @@ func (am *Alertmanager) buildReceiverIntegrations(receiver *apimodels.PostableAp
 	var integrations []notify.Integration
 
 	for i, r := range receiver.GrafanaManagedReceivers {
-		var (
-			cfg = &models.AlertNotification{
-				Uid:                   r.Uid,
-				Name:                  r.Name,
-				Type:                  r.Type,
-				IsDefault:             r.IsDefault,
-				SendReminder:          r.SendReminder,
-				DisableResolveMessage: r.DisableResolveMessage,
-				Settings:              r.Settings,
-				SecureSettings:        securejsondata.GetEncryptedJsonData(r.SecureSettings),
-			}
-			n   NotificationChannel
-			err error
-		)
-		switch r.Type {
-		case "email":
-			n, err = channels.NewEmailNotifier(cfg, tmpl.ExternalURL) // Email notifier already has a default template.
-		case "pagerduty":
-			n, err = channels.NewPagerdutyNotifier(cfg, tmpl)
-		case "slack":
-			n, err = channels.NewSlackNotifier(cfg, tmpl)
-		case "telegram":
-			n, err = channels.NewTelegramNotifier(cfg, tmpl)
-		case "teams":
-			n, err = channels.NewTeamsNotifier(cfg, tmpl)
-		case "dingding":
-			n, err = channels.NewDingDingNotifier(cfg, tmpl)
-		case "webhook":
-			n, err = channels.NewWebHookNotifier(cfg, tmpl)
-		}
+		n, err := newNotificationChannel(r, tmpl)
 		if err != nil {
 			return nil, err
 		}
@@ -412,6 +391,63 @@ func (am *Alertmanager) buildReceiverIntegrations(receiver *apimodels.PostableAp
 	return integrations, nil
 }
 
+// newNotificationChannel builds the notification channel of a Grafana managed receiver. It returns nil for
+// receivers of an unsupported type.
+func newNotificationChannel(r *apimodels.PostableGrafanaReceiver, tmpl *template.Template) (NotificationChannel, error) {
+	var (
+		cfg = &models.AlertNotification{
+			Uid:                   r.Uid,
+			Name:                  r.Name,
+			Type:                  r.Type,
+			IsDefault:             r.IsDefault,
+			SendReminder:          r.SendReminder,
+			DisableResolveMessage: r.DisableResolveMessage,
+			Settings:              r.Settings,
+			SecureSettings:        securejsondata.GetEncryptedJsonData(r.SecureSettings),
+		}
+		n   NotificationChannel
+		err error
+	)
+	switch r.Type {
+	case "email":
+		n, err = channels.NewEmailNotifier(cfg, tmpl.ExternalURL) // Email notifier already has a default template.
+	case "pagerduty":
+		n, err = channels.NewPagerdutyNotifier(cfg, tmpl)
+	case "slack":
+		n, err = channels.NewSlackNotifier(cfg, tmpl)
+	case "telegram":
+		n, err = channels.NewTelegramNotifier(cfg, tmpl)
+	case "teams":
+		n, err = channels.NewTeamsNotifier(cfg, tmpl)
+	case "dingding":
+		n, err = channels.NewDingDingNotifier(cfg, tmpl)
+	case "webhook":
+		n, err = channels.NewWebHookNotifier(cfg, tmpl)
+	}
+	return n, err
+}
+
+// ValidateReceiver checks that every Grafana managed receiver of receiver has a supported type and valid settings,
+// without building the receiver's integrations.
+func ValidateReceiver(receiver *apimodels.PostableApiReceiver) error {
+	tmpl := &template.Template{ExternalURL: &url.URL{}}
+	for _, r := range receiver.GrafanaManagedReceivers {
+		n, err := newNotificationChannel(r, tmpl)
+		if err != nil {
+			return fmt.Errorf("invalid %s receiver %q: %w", r.Type, r.Name, err)
+		}
+		if n == nil {
+			return fmt.Errorf("receiver %q has unsupported type %q", r.Name, r.Type)
+		}
+	}
+	return nil
+}
+
+// DefaultConfiguration returns the configuration the Alertmanager uses when there's none in the database.
+func DefaultConfiguration() (*apimodels.PostableUserConfig, error) {
+	return Load([]byte(alertmanagerDefaultConfiguration))
+}
+
 // PutAlerts receives the alerts and then sends them through the corresponding route based on whenever the alert has a receiver embedded or not
 func (am *Alertmanager) PutAlerts(postableAlerts apimodels.PostableAlerts) error {
 	now := time.Now()
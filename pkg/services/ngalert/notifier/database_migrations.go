@@ -15,3 +15,23 @@ func alertmanagerConfigurationMigration(mg *migrator.Migrator) {
 
 	mg.AddMigration("create_alert_configuration_table", migrator.NewAddTableMigration(alertConfiguration))
 }
+
+func alertConfigurationProvenanceMigration(mg *migrator.Migrator) {
+	alertConfigurationProvenance := migrator.Table{
+		Name: "alert_configuration_provenance",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "record_type", Type: migrator.DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "record_key", Type: migrator.DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "provenance", Type: migrator.DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "allow_ui_updates", Type: migrator.DB_Bool, Nullable: false, Default: "0"},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"record_type", "record_key"}, Type: migrator.UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create alert_configuration_provenance table", migrator.NewAddTableMigration(alertConfigurationProvenance))
+	mg.AddMigration("add unique index in alert_configuration_provenance on record_type and record_key columns",
+		migrator.NewAddIndexMigration(alertConfigurationProvenance, alertConfigurationProvenance.Indices[0]))
+}
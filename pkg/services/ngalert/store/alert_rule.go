@@ -33,6 +33,9 @@ type UpdateRuleGroupCmd struct {
 type UpsertRule struct {
 	Existing *ngmodels.AlertRule
 	New      ngmodels.AlertRule
+	// CreateWithUID creates the rule with the UID of New if it doesn't exist yet, instead of failing.
+	// Used by provisioning, which derives stable rule UIDs from its config.
+	CreateWithUID bool
 }
 
 // Store is the interface for persisting alert rules and instances
@@ -163,23 +166,26 @@ func (st DBstore) UpsertAlertRules(rules []UpsertRule) error {
 			if r.Existing == nil && r.New.UID != "" {
 				// check by UID
 				existingAlertRule, err := getAlertRuleByUID(sess, r.New.UID, r.New.OrgID)
-				if err != nil {
-					if errors.Is(err, ngmodels.ErrAlertRuleNotFound) {
-						return fmt.Errorf("failed to get alert rule %s: %w", r.New.UID, err)
-					}
+				switch {
+				case err == nil:
+					r.Existing = existingAlertRule
+				case !errors.Is(err, ngmodels.ErrAlertRuleNotFound):
 					return err
+				case !r.CreateWithUID:
+					return fmt.Errorf("failed to get alert rule %s: %w", r.New.UID, err)
 				}
-				r.Existing = existingAlertRule
 			}
 
 			var parentVersion int64
 			switch r.Existing {
 			case nil: // new rule
-				uid, err := generateNewAlertRuleUID(sess, r.New.OrgID)
-				if err != nil {
-					return fmt.Errorf("failed to generate UID for alert rule %q: %w", r.New.Title, err)
+				if !r.CreateWithUID || r.New.UID == "" {
+					uid, err := generateNewAlertRuleUID(sess, r.New.OrgID)
+					if err != nil {
+						return fmt.Errorf("failed to generate UID for alert rule %q: %w", r.New.Title, err)
+					}
+					r.New.UID = uid
 				}
-				r.New.UID = uid
 
 				if r.New.IntervalSeconds == 0 {
 					r.New.IntervalSeconds = st.DefaultIntervalSeconds
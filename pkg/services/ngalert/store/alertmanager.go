@@ -81,3 +81,41 @@ func (st DBstore) SaveAlertmanagerConfiguration(cmd *models.SaveAlertmanagerConf
 		return nil
 	})
 }
+
+// GetAlertConfigurationProvenances returns the provenance of the parts of the Alertmanager configuration that aren't
+// managed in the UI.
+func (st DBstore) GetAlertConfigurationProvenances(query *models.GetAlertConfigurationProvenancesQuery) error {
+	return st.SQLStore.WithDbSession(context.Background(), func(sess *sqlstore.DBSession) error {
+		provenances := make([]*models.AlertConfigurationProvenance, 0)
+		if err := sess.Find(&provenances); err != nil {
+			return err
+		}
+		query.Result = provenances
+		return nil
+	})
+}
+
+// SaveProvisionedAlertmanagerConfiguration creates an alertmanager configuration and replaces the provenance of the
+// provisioned parts of the configuration in a single transaction.
+func (st DBstore) SaveProvisionedAlertmanagerConfiguration(cmd *models.SaveProvisionedAlertmanagerConfigurationCmd) error {
+	return st.SQLStore.WithTransactionalDbSession(context.Background(), func(sess *sqlstore.DBSession) error {
+		config := models.AlertConfiguration{
+			AlertmanagerConfiguration: cmd.AlertmanagerConfiguration,
+			ConfigurationVersion:      cmd.ConfigurationVersion,
+		}
+		if _, err := sess.Insert(config); err != nil {
+			return err
+		}
+
+		if _, err := sess.Where("provenance = ?", models.ProvenanceFile).Delete(&models.AlertConfigurationProvenance{}); err != nil {
+			return err
+		}
+		for _, provenance := range cmd.Provenances {
+			provenance.ID = 0
+			if _, err := sess.Insert(provenance); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
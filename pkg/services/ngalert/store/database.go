@@ -40,6 +40,8 @@ type AlertingStore interface {
 	GetLatestAlertmanagerConfiguration(*models.GetLatestAlertmanagerConfigurationQuery) error
 	GetAlertmanagerConfiguration(*models.GetAlertmanagerConfigurationQuery) error
 	SaveAlertmanagerConfiguration(*models.SaveAlertmanagerConfigurationCmd) error
+	GetAlertConfigurationProvenances(*models.GetAlertConfigurationProvenancesQuery) error
+	SaveProvisionedAlertmanagerConfiguration(*models.SaveProvisionedAlertmanagerConfigurationCmd) error
 }
 
 // DBstore stores the alert definitions and instances in the database.
@@ -0,0 +1,71 @@
+// +build integration
+
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/registry"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpsertAlertRulesWithUID(t *testing.T) {
+	dbstore := setupTestEnv(t, baseIntervalSeconds)
+	t.Cleanup(registry.ClearOverrides)
+
+	newRule := func(title string) models.AlertRule {
+		return models.AlertRule{
+			OrgID:           1,
+			UID:             "provisioned-rule",
+			Title:           title,
+			Condition:       "A",
+			NamespaceUID:    "folder",
+			RuleGroup:       "group",
+			IntervalSeconds: 60,
+			Data: []models.AlertQuery{
+				{
+					Model: json.RawMessage(`{
+						"datasourceUid": "-100",
+						"type":"math",
+						"expression":"2 + 2 > 1"
+					}`),
+					RefID: "A",
+					RelativeTimeRange: models.RelativeTimeRange{
+						From: models.Duration(time.Duration(5) * time.Hour),
+						To:   models.Duration(time.Duration(3) * time.Hour),
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("Unknown UIDs fail without CreateWithUID", func(t *testing.T) {
+		err := dbstore.UpsertAlertRules([]store.UpsertRule{{New: newRule("rule")}})
+		require.ErrorIs(t, err, models.ErrAlertRuleNotFound)
+	})
+
+	t.Run("CreateWithUID creates the rule with the given UID", func(t *testing.T) {
+		err := dbstore.UpsertAlertRules([]store.UpsertRule{{New: newRule("rule"), CreateWithUID: true}})
+		require.NoError(t, err)
+
+		query := models.GetAlertRuleByUIDQuery{UID: "provisioned-rule", OrgID: 1}
+		require.NoError(t, dbstore.GetAlertRuleByUID(&query))
+		assert.Equal(t, "rule", query.Result.Title)
+		assert.Equal(t, int64(1), query.Result.Version)
+	})
+
+	t.Run("CreateWithUID updates an existing rule", func(t *testing.T) {
+		err := dbstore.UpsertAlertRules([]store.UpsertRule{{New: newRule("renamed rule"), CreateWithUID: true}})
+		require.NoError(t, err)
+
+		query := models.GetAlertRuleByUIDQuery{UID: "provisioned-rule", OrgID: 1}
+		require.NoError(t, dbstore.GetAlertRuleByUID(&query))
+		assert.Equal(t, "renamed rule", query.Result.Title)
+		assert.Equal(t, int64(2), query.Result.Version)
+	})
+}
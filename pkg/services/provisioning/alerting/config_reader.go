@@ -0,0 +1,186 @@
+package alerting
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+type configReader struct {
+	log        log.Logger
+	fileFilter setting.ProvisioningFileFilter
+	// strict rejects unknown fields instead of ignoring them.
+	strict bool
+	// env is what the guards of the files are checked against.
+	env utils.Environment
+}
+
+func (cr *configReader) readConfig(path string) ([]*rulesAsConfig, error) {
+	var rules []*rulesAsConfig
+	cr.log.Debug("Looking for alert rule provisioning files", "path", path)
+
+	files, err := utils.ReadDir(path)
+	if err != nil {
+		cr.log.Error("Can't read alert rule provisioning files from directory", "path", path, "error", err)
+		return rules, nil
+	}
+
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".yaml") || strings.HasSuffix(file.Name(), ".yml") {
+			if !cr.fileFilter.Includes(file.Name()) {
+				cr.log.Debug("Skipping excluded alert rule provisioning file", "path", path, "file.Name", file.Name())
+				continue
+			}
+
+			cr.log.Debug("Parsing alert rule provisioning file", "path", path, "file.Name", file.Name())
+			rule, err := cr.parseRuleConfig(path, file)
+			if err != nil {
+				return nil, err
+			}
+
+			if rule != nil {
+				rules = append(rules, rule)
+			}
+		}
+	}
+
+	cr.log.Debug("Validating alert rules")
+	if err := validateRules(rules); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func (cr *configReader) parseRuleConfig(path string, file os.FileInfo) (*rulesAsConfig, error) {
+	filename, err := filepath.Abs(filepath.Join(path, file.Name()))
+	if err != nil {
+		return nil, err
+	}
+
+	// nolint:gosec
+	// We can ignore the gosec G304 warning on this one because `filename` comes from ps.Cfg.ProvisioningPath
+	yamlFile, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg *rulesAsConfigV1
+	decoder := utils.YAMLDecoder{Subsystem: "alerting", Kind: "AlertRule", Strict: cr.strict, Environment: cr.env,
+		Log: cr.log}
+	if err := decoder.Decode(filename, yamlFile, &cfg); err != nil {
+		if errors.Is(err, utils.ErrFileSkipped) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	r := &rulesAsConfig{Filename: file.Name()}
+	if cfg == nil {
+		return r, nil
+	}
+
+	for index, ruleV1 := range cfg.Rules {
+		source := fmt.Sprintf("rule item %d in %s", index+1, file.Name())
+		rule, err := ruleV1.mapToAlertRule()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		r.Rules = append(r.Rules, &ruleFromConfig{Source: source, Rule: rule})
+	}
+
+	for _, tmpl := range cfg.Templates {
+		generated, err := tmpl.expand()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file.Name(), err)
+		}
+		r.Rules = append(r.Rules, generated...)
+	}
+
+	return r, nil
+}
+
+// validateRules checks every rule, including the generated ones, and makes sure no two rules of an org
+// share a UID since they would overwrite each other.
+func validateRules(configs []*rulesAsConfig) error {
+	seen := map[ngmodels.AlertRuleKey]string{}
+	for _, cfg := range configs {
+		for _, r := range cfg.Rules {
+			if err := validateRule(&r.Rule); err != nil {
+				return fmt.Errorf("%s: %w", r.Source, err)
+			}
+
+			key := r.Rule.GetKey()
+			if other, exists := seen[key]; exists {
+				return fmt.Errorf("%s: uid %q is already used by %s", r.Source, key.UID, other)
+			}
+			seen[key] = r.Source
+		}
+	}
+
+	return nil
+}
+
+func validateRule(rule *ngmodels.AlertRule) error {
+	requiredFields := []struct {
+		name  string
+		value string
+	}{
+		{"uid", rule.UID},
+		{"title", rule.Title},
+		{"folderUid", rule.NamespaceUID},
+		{"group", rule.RuleGroup},
+		{"condition", rule.Condition},
+	}
+
+	var errStrings []string
+	for _, field := range requiredFields {
+		if field.value == "" {
+			errStrings = append(errStrings, fmt.Sprintf("missing required field %s", field.name))
+		}
+	}
+	if len(errStrings) != 0 {
+		return fmt.Errorf(strings.Join(errStrings, ", "))
+	}
+
+	if len(rule.UID) > maxRuleUIDLength || !util.IsValidShortUID(rule.UID) {
+		return fmt.Errorf("invalid uid %q, it can only contain letters, numbers, - and _ and be at most %d characters long", rule.UID, maxRuleUIDLength)
+	}
+
+	if rule.IntervalSeconds%ngmodels.BaseIntervalSeconds != 0 {
+		return fmt.Errorf("intervalSeconds has to be a multiple of %d", ngmodels.BaseIntervalSeconds)
+	}
+
+	conditionFound := false
+	for _, query := range rule.Data {
+		if query.RefID == rule.Condition {
+			conditionFound = true
+		}
+	}
+	if !conditionFound {
+		return fmt.Errorf("condition %q doesn't match the refId of any query", rule.Condition)
+	}
+
+	switch rule.NoDataState {
+	case "", ngmodels.Alerting, ngmodels.NoData, ngmodels.KeepLastState, ngmodels.OK:
+	default:
+		return fmt.Errorf("invalid noDataState %q", rule.NoDataState)
+	}
+
+	switch rule.ExecErrState {
+	case "", ngmodels.AlertingErrState, ngmodels.KeepLastStateErrState:
+	default:
+		return fmt.Errorf("invalid execErrState %q", rule.ExecErrState)
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package alerting
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/prometheus/alertmanager/config"
+)
+
+// muteTimingsDirectory is the directory of the mute timings, next to the contact point provisioning directory.
+const muteTimingsDirectory = "mute-timings"
+
+// ErrMuteTimingInUse is returned, wrapped with the mute timing and the policy using it, when a provisioned mute
+// timing that was removed from the files is still used by a notification policy.
+var ErrMuteTimingInUse = errors.New("mute timing is used by a notification policy")
+
+// readMuteTimings reads the mute timings of the files in path. It reports whether path was read, so a missing
+// directory doesn't delete the mute timings provisioned before.
+func (cr *configReader) readMuteTimings(path string) ([]*muteTimingFromConfig, bool, error) {
+	if path == "" {
+		return nil, false, nil
+	}
+	files, err := utils.ReadDir(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, false, err
+		}
+		cr.log.Debug("No mute timings to provision", "path", path)
+		return nil, false, nil
+	}
+
+	var muteTimings []*muteTimingFromConfig
+	provisioned := map[string]string{}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".yaml") && !strings.HasSuffix(file.Name(), ".yml") {
+			continue
+		}
+		if !cr.fileFilter.Includes(file.Name()) {
+			cr.log.Debug("Skipping excluded mute timing provisioning file", "path", path, "file.Name", file.Name())
+			continue
+		}
+
+		filename, err := filepath.Abs(filepath.Join(path, file.Name()))
+		if err != nil {
+			return nil, false, err
+		}
+		// nolint:gosec
+		// We can ignore the gosec G304 warning on this one because `filename` comes from ps.Cfg.ProvisioningPath
+		content, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, false, err
+		}
+
+		var cfg *muteTimingsAsConfigV1
+		decoder := utils.YAMLDecoder{Subsystem: "alerting", Kind: "AlertNotification", Strict: cr.strict, Environment: cr.env,
+			Log: cr.log}
+		if err := decoder.Decode(filename, content, &cfg); err != nil {
+			if errors.Is(err, utils.ErrFileSkipped) {
+				continue
+			}
+			return nil, false, err
+		}
+		if cfg == nil {
+			continue
+		}
+
+		for index, muteTiming := range cfg.MuteTimings {
+			if muteTiming == nil {
+				return nil, false, fmt.Errorf("mute timing item %d in %s is empty", index+1, file.Name())
+			}
+			if other, exists := provisioned[muteTiming.Name]; exists {
+				return nil, false, fmt.Errorf("%s: mute timing %q is already provisioned by %s", file.Name(),
+					muteTiming.Name, other)
+			}
+			provisioned[muteTiming.Name] = file.Name()
+			muteTimings = append(muteTimings, &muteTimingFromConfig{MuteTiming: muteTiming, Filename: file.Name()})
+		}
+	}
+	return muteTimings, true, nil
+}
+
+// mergeMuteTimings applies the provisioned mute timings to updated and returns their provenance. Mute timings that
+// were provisioned before but are no longer in any file are deleted, unless a notification policy still uses them.
+func (np *NotificationProvisioner) mergeMuteTimings(updated *apimodels.PostableUserConfig,
+	muteTimings []*muteTimingFromConfig, previous []*ngmodels.AlertConfigurationProvenance) ([]*ngmodels.AlertConfigurationProvenance, error) {
+	intervals := append([]config.MuteTimeInterval{}, updated.AlertmanagerConfig.MuteTimeIntervals...)
+
+	var provenances []*ngmodels.AlertConfigurationProvenance
+	provisioned := map[string]bool{}
+	for _, muteTiming := range muteTimings {
+		name := muteTiming.MuteTiming.Name
+		provisioned[name] = true
+		provenances = append(provenances, &ngmodels.AlertConfigurationProvenance{
+			RecordType: ngmodels.MuteTimingRecordType,
+			RecordKey:  name,
+			Provenance: ngmodels.ProvenanceFile,
+		})
+
+		if index := muteTimingIndex(intervals, name); index >= 0 {
+			np.log.Debug("updating mute timing from configuration", "name", name)
+			intervals[index] = *muteTiming.MuteTiming
+			continue
+		}
+		np.log.Info("inserting mute timing from configuration", "name", name)
+		intervals = append(intervals, *muteTiming.MuteTiming)
+	}
+
+	for _, provenance := range previous {
+		if provenance.Provenance != ngmodels.ProvenanceFile || provenance.RecordType != ngmodels.MuteTimingRecordType ||
+			provisioned[provenance.RecordKey] {
+			continue
+		}
+		index := muteTimingIndex(intervals, provenance.RecordKey)
+		if index < 0 {
+			continue
+		}
+		if policy := policyUsingMuteTiming(updated.AlertmanagerConfig.Route, "", provenance.RecordKey); policy != "" {
+			return nil, fmt.Errorf("can't delete mute timing %q: %w: %s", provenance.RecordKey, ErrMuteTimingInUse, policy)
+		}
+		np.log.Info("deleting mute timing missing from configuration", "name", provenance.RecordKey)
+		intervals = append(intervals[:index:index], intervals[index+1:]...)
+	}
+
+	updated.AlertmanagerConfig.MuteTimeIntervals = intervals
+	if len(intervals) == 0 {
+		updated.AlertmanagerConfig.MuteTimeIntervals = nil
+	}
+	return provenances, nil
+}
+
+// validateMuteTimingReferences makes sure every mute timing the provisioned notification policies use is in the
+// configuration.
+func validateMuteTimingReferences(cfg *apimodels.PostableUserConfig, configs []*notificationsAsConfig) error {
+	defined := map[string]bool{}
+	for _, muteTiming := range cfg.AlertmanagerConfig.MuteTimeIntervals {
+		defined[muteTiming.Name] = true
+	}
+
+	var check func(route *config.Route, path string) error
+	check = func(route *config.Route, path string) error {
+		if path == "" && len(route.MuteTimeIntervals) > 0 {
+			return errors.New("the root policy can't have mute timings")
+		}
+		for _, name := range route.MuteTimeIntervals {
+			if !defined[name] {
+				return fmt.Errorf("%s references missing mute timing %q", describePolicy(path), name)
+			}
+		}
+		for _, child := range route.Routes {
+			if err := check(child, childPolicyPath(path, child)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, c := range configs {
+		if c.Policy == nil {
+			continue
+		}
+		if err := check(c.Policy.Route, ""); err != nil {
+			return fmt.Errorf("%s: %w", c.Filename, err)
+		}
+	}
+	return nil
+}
+
+// policyUsingMuteTiming returns the description of the first policy of the tree of route that uses the mute timing,
+// or an empty string when none does.
+func policyUsingMuteTiming(route *config.Route, path string, name string) string {
+	if route == nil {
+		return ""
+	}
+	for _, muteTiming := range route.MuteTimeIntervals {
+		if muteTiming == name {
+			return describePolicy(path)
+		}
+	}
+	for _, child := range route.Routes {
+		if policy := policyUsingMuteTiming(child, childPolicyPath(path, child), name); policy != "" {
+			return policy
+		}
+	}
+	return ""
+}
+
+func muteTimingIndex(intervals []config.MuteTimeInterval, name string) int {
+	for i, interval := range intervals {
+		if interval.Name == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,199 @@
+package alerting
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/common/model"
+)
+
+// routeSetting is a setting of a notification policy that two files can't set to different values.
+type routeSetting struct {
+	name string
+	// value returns the setting of the route in a comparable form, or an empty string when it isn't set.
+	value func(route *config.Route) string
+	copy  func(dst, src *config.Route)
+}
+
+var routeSettings = []routeSetting{
+	{
+		name:  "receiver",
+		value: func(route *config.Route) string { return route.Receiver },
+		copy:  func(dst, src *config.Route) { dst.Receiver = src.Receiver },
+	},
+	{
+		name:  "group_by",
+		value: func(route *config.Route) string { return sortedList(route.GroupByStr) },
+		copy: func(dst, src *config.Route) {
+			dst.GroupByStr, dst.GroupBy, dst.GroupByAll = src.GroupByStr, src.GroupBy, src.GroupByAll
+		},
+	},
+	{
+		name:  "group_wait",
+		value: func(route *config.Route) string { return durationValue(route.GroupWait) },
+		copy:  func(dst, src *config.Route) { dst.GroupWait = src.GroupWait },
+	},
+	{
+		name:  "group_interval",
+		value: func(route *config.Route) string { return durationValue(route.GroupInterval) },
+		copy:  func(dst, src *config.Route) { dst.GroupInterval = src.GroupInterval },
+	},
+	{
+		name:  "repeat_interval",
+		value: func(route *config.Route) string { return durationValue(route.RepeatInterval) },
+		copy:  func(dst, src *config.Route) { dst.RepeatInterval = src.RepeatInterval },
+	},
+	{
+		name:  "mute_time_intervals",
+		value: func(route *config.Route) string { return sortedList(route.MuteTimeIntervals) },
+		copy:  func(dst, src *config.Route) { dst.MuteTimeIntervals = src.MuteTimeIntervals },
+	},
+}
+
+// mergeNotificationPolicies merges the notification policy trees of configs into one, in order of file name. Child
+// policies with the same matchers are merged into one policy, the others are added after the policies of the
+// earlier files. Two files setting a policy to different values, like different default receivers, are an error.
+// It returns nil when no file has a notification policy tree.
+func mergeNotificationPolicies(configs []*notificationsAsConfig, logger log.Logger) (*notificationPolicyFromConfig, error) {
+	var withPolicy []*notificationsAsConfig
+	for _, cfg := range configs {
+		if cfg.Policy != nil {
+			withPolicy = append(withPolicy, cfg)
+		}
+	}
+	if len(withPolicy) == 0 {
+		return nil, nil
+	}
+	if len(withPolicy) == 1 {
+		return withPolicy[0].Policy, nil
+	}
+
+	sort.SliceStable(withPolicy, func(i, j int) bool { return withPolicy[i].Filename < withPolicy[j].Filename })
+	filenames := make([]string, 0, len(withPolicy))
+	for _, cfg := range withPolicy {
+		filenames = append(filenames, cfg.Filename)
+	}
+	logger.Info("Merging notification policy trees", "files", strings.Join(filenames, ", "))
+
+	merged := &notificationPolicyFromConfig{Route: &config.Route{}, AllowUIUpdates: withPolicy[0].Policy.AllowUIUpdates}
+	m := policyMerge{origins: map[*config.Route]map[string]string{}}
+	for _, cfg := range withPolicy {
+		if cfg.Policy.AllowUIUpdates != merged.AllowUIUpdates {
+			return nil, fmt.Errorf("conflicting notification policies: allowUiUpdates is %t in %s and %t in %s",
+				merged.AllowUIUpdates, withPolicy[0].Filename, cfg.Policy.AllowUIUpdates, cfg.Filename)
+		}
+		if err := m.mergeRoute(merged.Route, cfg.Policy.Route, cfg.Filename, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	if merged.Route.Receiver == "" {
+		return nil, fmt.Errorf("the notification policy tree merged from %s has no default receiver",
+			strings.Join(filenames, ", "))
+	}
+	return merged, nil
+}
+
+type policyMerge struct {
+	// origins are the files that set the settings of the merged routes, by route and setting name.
+	origins map[*config.Route]map[string]string
+}
+
+// mergeRoute merges the settings and the child policies of from, which is read from filename, into into. The
+// routes of the files aren't changed. path describes into in errors, it's empty for the root policy.
+func (m policyMerge) mergeRoute(into, from *config.Route, filename, path string) error {
+	if m.origins[into] == nil {
+		m.origins[into] = map[string]string{}
+	}
+
+	for _, setting := range routeSettings {
+		value := setting.value(from)
+		if value == "" {
+			continue
+		}
+		current := setting.value(into)
+		if current == "" {
+			setting.copy(into, from)
+			m.origins[into][setting.name] = filename
+			continue
+		}
+		if current != value {
+			return fmt.Errorf("conflicting notification policies: %s of the %s is %q in %s and %q in %s",
+				setting.name, describePolicy(path), current, m.origins[into][setting.name], value, filename)
+		}
+	}
+	// Continue can't be told apart from being left out, so a policy continues when any file says so.
+	into.Continue = into.Continue || from.Continue
+
+	for _, child := range from.Routes {
+		key := matchersKey(child)
+		var existing *config.Route
+		for _, route := range into.Routes {
+			if matchersKey(route) == key {
+				existing = route
+				break
+			}
+		}
+		if existing == nil {
+			existing = &config.Route{Match: child.Match, MatchRE: child.MatchRE, Matchers: child.Matchers}
+			into.Routes = append(into.Routes, existing)
+		}
+
+		if err := m.mergeRoute(existing, child, filename, childPolicyPath(path, child)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// childPolicyPath returns the path of a child policy of the policy at path, which describes it in errors. The path
+// of the root policy is empty.
+func childPolicyPath(path string, child *config.Route) string {
+	childPath := fmt.Sprintf("policy matching {%s}", matchersKey(child))
+	if path != "" {
+		childPath = path + " > " + childPath
+	}
+	return childPath
+}
+
+// describePolicy describes the policy at path in errors.
+func describePolicy(path string) string {
+	if path == "" {
+		return "root policy"
+	}
+	return path
+}
+
+// matchersKey returns the matchers of a route in a normalized form, so the deprecated match and match_re fields
+// compare equal to the matchers they're written as.
+func matchersKey(route *config.Route) string {
+	var matchers []string
+	for name, value := range route.Match {
+		matchers = append(matchers, fmt.Sprintf("%s=%q", name, value))
+	}
+	for name, re := range route.MatchRE {
+		original, _ := re.MarshalYAML()
+		matchers = append(matchers, fmt.Sprintf("%s=~%q", name, fmt.Sprint(original)))
+	}
+	for _, matcher := range route.Matchers {
+		matchers = append(matchers, matcher.String())
+	}
+	sort.Strings(matchers)
+	return strings.Join(matchers, ", ")
+}
+
+func sortedList(list []string) string {
+	sorted := append([]string{}, list...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+func durationValue(d *model.Duration) string {
+	if d == nil {
+		return ""
+	}
+	return d.String()
+}
@@ -0,0 +1,136 @@
+package alerting
+
+import (
+	"testing"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestMergeNotificationPolicies(t *testing.T) {
+	policyFile := func(t *testing.T, filename, route string) *notificationsAsConfig {
+		t.Helper()
+		parsed := &config.Route{}
+		require.NoError(t, yaml.Unmarshal([]byte(route), parsed))
+		return &notificationsAsConfig{Filename: filename, Policy: &notificationPolicyFromConfig{Route: parsed}}
+	}
+	logger := log.New("test logger")
+
+	t.Run("Without policies there's nothing to merge", func(t *testing.T) {
+		policy, err := mergeNotificationPolicies([]*notificationsAsConfig{{Filename: "contact-points.yaml"}}, logger)
+		require.NoError(t, err)
+		assert.Nil(t, policy)
+	})
+
+	t.Run("A single policy is used as is", func(t *testing.T) {
+		single := policyFile(t, "policy.yaml", "receiver: ops-email")
+		policy, err := mergeNotificationPolicies([]*notificationsAsConfig{single}, logger)
+		require.NoError(t, err)
+		assert.Same(t, single.Policy, policy)
+	})
+
+	t.Run("Child policies are merged under matching parents in order of file name", func(t *testing.T) {
+		configs := []*notificationsAsConfig{
+			policyFile(t, "c.yaml", `
+routes:
+  - matchers: ['team="a"']
+    routes:
+      - receiver: team-a-pager
+        match:
+          severity: critical
+  - receiver: team-c
+    matchers: ['team="c"']
+`),
+			policyFile(t, "a.yaml", `
+receiver: ops-email
+group_by: [alertname]
+routes:
+  - receiver: team-a
+    match:
+      team: a
+`),
+			policyFile(t, "b.yaml", `
+group_by: [alertname]
+routes:
+  - receiver: team-b
+    match:
+      team: b
+  - matchers: ['team="a"']
+    group_wait: 10s
+`),
+		}
+
+		policy, err := mergeNotificationPolicies(configs, logger)
+		require.NoError(t, err)
+		root := policy.Route
+		assert.Equal(t, "ops-email", root.Receiver)
+		assert.Equal(t, []string{"alertname"}, root.GroupByStr)
+
+		require.Len(t, root.Routes, 3)
+		assert.Equal(t, "team-a", root.Routes[0].Receiver)
+		assert.Equal(t, "10s", root.Routes[0].GroupWait.String())
+		require.Len(t, root.Routes[0].Routes, 1)
+		assert.Equal(t, "team-a-pager", root.Routes[0].Routes[0].Receiver)
+		assert.Equal(t, "team-b", root.Routes[1].Receiver)
+		assert.Equal(t, "team-c", root.Routes[2].Receiver)
+
+		assert.Empty(t, configs[1].Policy.Route.Routes[0].Routes, "The policies of the files aren't changed")
+	})
+
+	t.Run("Merging is deterministic", func(t *testing.T) {
+		first := func() []*notificationsAsConfig {
+			return []*notificationsAsConfig{
+				policyFile(t, "b.yaml", "routes: [{receiver: team-b, match: {team: b}}]"),
+				policyFile(t, "a.yaml", "receiver: ops-email\nroutes: [{receiver: team-a, match: {team: a}}]"),
+			}
+		}
+		policy, err := mergeNotificationPolicies(first(), logger)
+		require.NoError(t, err)
+		reversed := first()
+		reversed[0], reversed[1] = reversed[1], reversed[0]
+		again, err := mergeNotificationPolicies(reversed, logger)
+		require.NoError(t, err)
+
+		out, err := yaml.Marshal(policy.Route)
+		require.NoError(t, err)
+		outAgain, err := yaml.Marshal(again.Route)
+		require.NoError(t, err)
+		assert.Equal(t, string(out), string(outAgain))
+	})
+
+	t.Run("Conflicting default receivers are an error", func(t *testing.T) {
+		_, err := mergeNotificationPolicies([]*notificationsAsConfig{
+			policyFile(t, "a.yaml", "receiver: ops-email"),
+			policyFile(t, "b.yaml", "receiver: ops-webhook"),
+		}, logger)
+		assert.EqualError(t, err,
+			`conflicting notification policies: receiver of the root policy is "ops-email" in a.yaml and "ops-webhook" in b.yaml`)
+	})
+
+	t.Run("Conflicting settings of a child policy are an error", func(t *testing.T) {
+		_, err := mergeNotificationPolicies([]*notificationsAsConfig{
+			policyFile(t, "a.yaml", "receiver: ops-email\nroutes: [{match: {team: a}, repeat_interval: 1h}]"),
+			policyFile(t, "b.yaml", "routes: [{matchers: ['team=\"a\"'], repeat_interval: 4h}]"),
+		}, logger)
+		assert.EqualError(t, err,
+			`conflicting notification policies: repeat_interval of the policy matching {team="a"} is "1h" in a.yaml and "4h" in b.yaml`)
+	})
+
+	t.Run("Conflicting allowUiUpdates are an error", func(t *testing.T) {
+		editable := policyFile(t, "b.yaml", "receiver: ops-email")
+		editable.Policy.AllowUIUpdates = true
+		_, err := mergeNotificationPolicies([]*notificationsAsConfig{policyFile(t, "a.yaml", "receiver: ops-email"), editable}, logger)
+		assert.EqualError(t, err, "conflicting notification policies: allowUiUpdates is false in a.yaml and true in b.yaml")
+	})
+
+	t.Run("The merged tree needs a default receiver", func(t *testing.T) {
+		_, err := mergeNotificationPolicies([]*notificationsAsConfig{
+			policyFile(t, "a.yaml", "routes: [{receiver: team-a, match: {team: a}}]"),
+			policyFile(t, "b.yaml", "group_by: [alertname]"),
+		}, logger)
+		assert.EqualError(t, err, "the notification policy tree merged from a.yaml, b.yaml has no default receiver")
+	})
+}
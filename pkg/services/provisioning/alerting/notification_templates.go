@@ -0,0 +1,100 @@
+package alerting
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	tmpltext "text/template"
+
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	amtemplate "github.com/prometheus/alertmanager/template"
+)
+
+// templatesDirectory is the subdirectory of the contact point provisioning directory with the notification
+// templates.
+const templatesDirectory = "templates"
+
+var templateReference = regexp.MustCompile(`{{-?\s*template\s+"([^"]+)"`)
+
+// parseTemplate returns the names of the templates defined in the content of a template file.
+func parseTemplate(name, content string) ([]string, error) {
+	tmpl, err := tmpltext.New(name).Funcs(tmpltext.FuncMap(amtemplate.DefaultFuncs)).Parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var defined []string
+	for _, t := range tmpl.Templates() {
+		if t.Name() != name {
+			defined = append(defined, t.Name())
+		}
+	}
+	return defined, nil
+}
+
+// isDefaultTemplate tells whether a template is defined by the default template of the Alertmanager, like
+// default.title, slack.default.text or __subject.
+func isDefaultTemplate(name string) bool {
+	return strings.HasPrefix(name, "__") || strings.HasPrefix(name, "default.") || strings.Contains(name, ".default.")
+}
+
+// validateTemplateReferences makes sure every template the provisioned contact points use is defined by one of
+// the template files of the configuration or by the default template.
+func validateTemplateReferences(cfg *apimodels.PostableUserConfig, configs []*notificationsAsConfig) error {
+	defined := map[string]bool{}
+	for name, content := range cfg.TemplateFiles {
+		names, err := parseTemplate(name, content)
+		if err != nil {
+			return fmt.Errorf("invalid template %q: %w", name, err)
+		}
+		for _, n := range names {
+			defined[n] = true
+		}
+	}
+
+	for _, c := range configs {
+		for _, contactPoint := range c.ContactPoints {
+			for _, receiver := range contactPoint.Receiver.GrafanaManagedReceivers {
+				if receiver.Settings == nil {
+					continue
+				}
+				for _, value := range settingStrings(receiver.Settings.Interface()) {
+					for _, match := range templateReference.FindAllStringSubmatch(value, -1) {
+						if !defined[match[1]] && !isDefaultTemplate(match[1]) {
+							return fmt.Errorf("%s: contact point %q references missing template %q", c.Filename,
+								contactPoint.Receiver.Name, match[1])
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// settingStrings returns the string values of the settings of a receiver, in a stable order.
+func settingStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var strs []string
+		for _, key := range keys {
+			strs = append(strs, settingStrings(v[key])...)
+		}
+		return strs
+	case []interface{}:
+		var strs []string
+		for _, item := range v {
+			strs = append(strs, settingStrings(item)...)
+		}
+		return strs
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package alerting
+
+import (
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/provisioning/values"
+	"github.com/prometheus/alertmanager/config"
+)
+
+// notificationsAsConfig is a normalized data object for contact point and notification policy config data.
+type notificationsAsConfig struct {
+	Filename      string
+	ContactPoints []*contactPointFromConfig
+	Policy        *notificationPolicyFromConfig
+}
+
+type contactPointFromConfig struct {
+	Receiver       *apimodels.PostableApiReceiver
+	AllowUIUpdates bool
+}
+
+// templateFromConfig is a notification template read from the templates subdirectory.
+type templateFromConfig struct {
+	// Name is the file name of the template, which is its key in the template files of the Alertmanager
+	// configuration.
+	Name    string
+	Content string
+	// Filename is the absolute path of the file the template was read from.
+	Filename string
+}
+
+// muteTimingFromConfig is a mute timing read from the mute timings directory.
+type muteTimingFromConfig struct {
+	MuteTiming *config.MuteTimeInterval
+	// Filename is the name of the file the mute timing was read from.
+	Filename string
+}
+
+type notificationPolicyFromConfig struct {
+	Route          *config.Route
+	AllowUIUpdates bool
+}
+
+type notificationsAsConfigV1 struct {
+	configVersion `yaml:",inline"`
+
+	ContactPoints []*contactPointV1     `json:"contactPoints" yaml:"contactPoints"`
+	Policy        *notificationPolicyV1 `json:"notificationPolicy" yaml:"notificationPolicy"`
+}
+
+// muteTimingsAsConfigV1 is a file of the mute timings directory. The mute timings are written like the
+// mute_time_intervals of an Alertmanager configuration file, which validates their time intervals.
+type muteTimingsAsConfigV1 struct {
+	configVersion `yaml:",inline"`
+
+	MuteTimings []*config.MuteTimeInterval `json:"muteTimings" yaml:"muteTimings"`
+}
+
+// contactPointV1 is a receiver of the Alertmanager, made of one or more Grafana managed receivers.
+type contactPointV1 struct {
+	Name           values.StringValue `json:"name" yaml:"name"`
+	AllowUIUpdates values.BoolValue   `json:"allowUiUpdates" yaml:"allowUiUpdates"`
+	Receivers      []*receiverV1      `json:"receivers" yaml:"receivers"`
+}
+
+type receiverV1 struct {
+	UID                   values.StringValue    `json:"uid" yaml:"uid"`
+	Type                  values.StringValue    `json:"type" yaml:"type"`
+	DisableResolveMessage values.BoolValue      `json:"disableResolveMessage" yaml:"disableResolveMessage"`
+	Settings              values.JSONValue      `json:"settings" yaml:"settings"`
+	SecureSettings        values.StringMapValue `json:"secureSettings" yaml:"secureSettings"`
+}
+
+// notificationPolicyV1 is the root of the notification policy tree, written like the route of an Alertmanager
+// configuration file.
+type notificationPolicyV1 struct {
+	AllowUIUpdates values.BoolValue `json:"allowUiUpdates" yaml:"allowUiUpdates"`
+	Route          *config.Route    `json:"route" yaml:"route"`
+}
+
+func (cp *contactPointV1) mapToContactPoint() *contactPointFromConfig {
+	receiver := &apimodels.PostableApiReceiver{}
+	receiver.Name = cp.Name.Value()
+	for _, r := range cp.Receivers {
+		settings := r.Settings.Value()
+		if settings == nil {
+			settings = map[string]interface{}{}
+		}
+		receiver.GrafanaManagedReceivers = append(receiver.GrafanaManagedReceivers, &apimodels.PostableGrafanaReceiver{
+			Uid:                   r.UID.Value(),
+			Name:                  cp.Name.Value(),
+			Type:                  r.Type.Value(),
+			DisableResolveMessage: r.DisableResolveMessage.Value(),
+			Settings:              simplejson.NewFromAny(settings),
+			SecureSettings:        r.SecureSettings.Value(),
+		})
+	}
+
+	return &contactPointFromConfig{Receiver: receiver, AllowUIUpdates: cp.AllowUIUpdates.Value()}
+}
@@ -0,0 +1,335 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/notifier"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// NotificationStore is the part of the unified alerting store used to provision contact points and notification
+// policies.
+type NotificationStore interface {
+	GetLatestAlertmanagerConfiguration(*ngmodels.GetLatestAlertmanagerConfigurationQuery) error
+	GetAlertConfigurationProvenances(*ngmodels.GetAlertConfigurationProvenancesQuery) error
+	SaveProvisionedAlertmanagerConfiguration(*ngmodels.SaveProvisionedAlertmanagerConfigurationCmd) error
+}
+
+// ProvisionNotifications scans a directory for provisioning config files
+// and provisions the contact points and notification policies in those files. What was applied is recorded in
+// inventory. The cleanup guard limits how many of the provisioned contact points and templates missing from the
+// files are deleted.
+func ProvisionNotifications(ctx context.Context, configDirectory string, notificationStore NotificationStore, fileFilter setting.ProvisioningFileFilter,
+	strict bool, cleanupGuard setting.ProvisioningCleanupGuard, inventory *utils.Inventory) error {
+	logger := log.New("provisioning.alerting")
+	np := NotificationProvisioner{
+		log:          logger,
+		cfgProvider:  &configReader{log: logger, fileFilter: fileFilter, strict: strict, env: utils.EnvironmentFromContext(ctx)},
+		store:        notificationStore,
+		inventory:    inventory,
+		cleanupGuard: cleanupGuard,
+		// The mute timings are next to the contact points, like alerting/mute-timings.
+		muteTimingsPath: filepath.Join(filepath.Dir(configDirectory), muteTimingsDirectory),
+	}
+	return np.applyChanges(ctx, configDirectory)
+}
+
+// NotificationProvisioner is responsible for provisioning contact points and notification policies based on
+// configuration read by the `configReader`. They're merged into the latest Alertmanager configuration, so contact
+// points created in the UI are kept.
+type NotificationProvisioner struct {
+	log         log.Logger
+	cfgProvider *configReader
+	store       NotificationStore
+	inventory   *utils.Inventory
+	// muteTimingsPath is the directory the mute timings are read from, none are provisioned when it's empty.
+	muteTimingsPath string
+	// cleanupGuard limits how many provisioned contact points and templates a run may delete.
+	cleanupGuard setting.ProvisioningCleanupGuard
+}
+
+func (np *NotificationProvisioner) applyChanges(ctx context.Context, configPath string) error {
+	// Without the directory every provisioned contact point would be deleted, which is more likely caused by a
+	// missing volume than by files that were removed on purpose.
+	if _, err := os.Stat(configPath); err != nil {
+		np.log.Debug("Skipping contact point provisioning, directory can't be read", "path", configPath, "error", err)
+		return nil
+	}
+
+	configs, err := np.cfgProvider.readNotificationConfig(configPath)
+	if err != nil {
+		return err
+	}
+	templates, err := np.cfgProvider.readTemplates(configPath)
+	if err != nil {
+		return err
+	}
+	policy, err := mergeNotificationPolicies(configs, np.log)
+	if err != nil {
+		return err
+	}
+	muteTimings, muteTimingsRead, err := np.cfgProvider.readMuteTimings(np.muteTimingsPath)
+	if err != nil {
+		return err
+	}
+
+	provenanceQuery := &ngmodels.GetAlertConfigurationProvenancesQuery{}
+	if err := np.store.GetAlertConfigurationProvenances(provenanceQuery); err != nil {
+		return err
+	}
+
+	current, err := np.latestConfiguration()
+	if err != nil {
+		return err
+	}
+
+	updated, provenances, err := np.merge(current, configs, policy, templates, provenanceQuery.Result)
+	if err != nil {
+		return err
+	}
+	if muteTimingsRead {
+		muteTimingProvenances, err := np.mergeMuteTimings(updated, muteTimings, provenanceQuery.Result)
+		if err != nil {
+			return err
+		}
+		provenances = append(provenances, muteTimingProvenances...)
+	} else {
+		provenances = append(provenances, keptProvenances(provenanceQuery.Result, ngmodels.MuteTimingRecordType)...)
+	}
+	if err := validateTemplateReferences(updated, configs); err != nil {
+		return err
+	}
+	if err := validateMuteTimingReferences(updated, configs); err != nil {
+		return err
+	}
+
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	updatedJSON, err := json.Marshal(updated)
+	if err != nil {
+		return err
+	}
+	if string(currentJSON) == string(updatedJSON) && provenancesEqual(provenanceQuery.Result, provenances) {
+		np.log.Debug("Provisioned contact points and notification policies are up to date")
+		np.recordApplied(configPath, configs, templates, muteTimings)
+		return nil
+	}
+
+	// Loading the serialized configuration validates it, e.g. that every receiver used by the notification
+	// policies still exists after deleting contact points.
+	if _, err := notifier.Load(updatedJSON); err != nil {
+		return fmt.Errorf("invalid Alertmanager configuration after provisioning contact points: %w", err)
+	}
+
+	// The notification store doesn't take a context, so a canceled run stops before the configuration is saved.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := np.store.SaveProvisionedAlertmanagerConfiguration(&ngmodels.SaveProvisionedAlertmanagerConfigurationCmd{
+		AlertmanagerConfiguration: string(updatedJSON),
+		ConfigurationVersion:      fmt.Sprintf("v%d", ngmodels.AlertConfigurationVersion),
+		Provenances:               provenances,
+	}); err != nil {
+		return err
+	}
+	np.recordApplied(configPath, configs, templates, muteTimings)
+	return nil
+}
+
+// recordApplied adds the contact points, the notification templates, the mute timings and the notification policy
+// tree to the inventory. They're saved as a single configuration, so they're only recorded once it's saved.
+func (np *NotificationProvisioner) recordApplied(configPath string, configs []*notificationsAsConfig,
+	templates []*templateFromConfig, muteTimings []*muteTimingFromConfig) {
+	for _, tmpl := range templates {
+		np.inventory.Record(utils.ProvisionedObject{Kind: "notification_template", Name: tmpl.Name, File: tmpl.Filename})
+	}
+	for _, muteTiming := range muteTimings {
+		np.inventory.Record(utils.ProvisionedObject{Kind: "mute_timing", Name: muteTiming.MuteTiming.Name,
+			File: provisioningFilePath(np.muteTimingsPath, muteTiming.Filename)})
+	}
+	for _, cfg := range configs {
+		filename := provisioningFilePath(configPath, cfg.Filename)
+		for _, contactPoint := range cfg.ContactPoints {
+			np.inventory.Record(utils.ProvisionedObject{Kind: "contact_point", Name: contactPoint.Receiver.Name, File: filename})
+		}
+		if cfg.Policy != nil {
+			np.inventory.Record(utils.ProvisionedObject{Kind: "notification_policy", Name: ngmodels.NotificationPolicyRecordKey,
+				File: filename})
+		}
+	}
+}
+
+func (np *NotificationProvisioner) latestConfiguration() (*apimodels.PostableUserConfig, error) {
+	query := &ngmodels.GetLatestAlertmanagerConfigurationQuery{}
+	if err := np.store.GetLatestAlertmanagerConfiguration(query); err != nil {
+		if errors.Is(err, store.ErrNoAlertmanagerConfiguration) {
+			return notifier.DefaultConfiguration()
+		}
+		return nil, err
+	}
+	return notifier.Load([]byte(query.Result.AlertmanagerConfiguration))
+}
+
+// merge returns a copy of current with the provisioned contact points, notification templates and the merged
+// notification policy tree applied, and the provenance of everything that's provisioned. Contact points and templates that were
+// provisioned before but are no longer in any file are deleted, unless that's more of them than the cleanup guard allows.
+func (np *NotificationProvisioner) merge(current *apimodels.PostableUserConfig, configs []*notificationsAsConfig,
+	policy *notificationPolicyFromConfig, templates []*templateFromConfig, previous []*ngmodels.AlertConfigurationProvenance) (*apimodels.PostableUserConfig,
+	[]*ngmodels.AlertConfigurationProvenance, error) {
+	updated := *current
+	updated.AlertmanagerConfig.Receivers = append([]*apimodels.PostableApiReceiver{}, current.AlertmanagerConfig.Receivers...)
+
+	var provenances []*ngmodels.AlertConfigurationProvenance
+	provisioned := map[string]bool{}
+	for _, cfg := range configs {
+		for _, contactPoint := range cfg.ContactPoints {
+			name := contactPoint.Receiver.Name
+			provisioned[name] = true
+			provenances = append(provenances, &ngmodels.AlertConfigurationProvenance{
+				RecordType:     ngmodels.ContactPointRecordType,
+				RecordKey:      name,
+				Provenance:     ngmodels.ProvenanceFile,
+				AllowUIUpdates: contactPoint.AllowUIUpdates,
+			})
+
+			if index := receiverIndex(updated.AlertmanagerConfig.Receivers, name); index >= 0 {
+				np.log.Debug("updating contact point from configuration", "name", name)
+				updated.AlertmanagerConfig.Receivers[index] = contactPoint.Receiver
+				continue
+			}
+			np.log.Info("inserting contact point from configuration", "name", name)
+			updated.AlertmanagerConfig.Receivers = append(updated.AlertmanagerConfig.Receivers, contactPoint.Receiver)
+		}
+	}
+
+	if policy != nil {
+		np.log.Debug("updating notification policy tree from configuration")
+		updated.AlertmanagerConfig.Route = policy.Route
+		provenances = append(provenances, &ngmodels.AlertConfigurationProvenance{
+			RecordType:     ngmodels.NotificationPolicyRecordType,
+			RecordKey:      ngmodels.NotificationPolicyRecordKey,
+			Provenance:     ngmodels.ProvenanceFile,
+			AllowUIUpdates: policy.AllowUIUpdates,
+		})
+	}
+
+	if len(current.TemplateFiles) > 0 || len(templates) > 0 {
+		updated.TemplateFiles = make(map[string]string, len(current.TemplateFiles)+len(templates))
+		for name, content := range current.TemplateFiles {
+			updated.TemplateFiles[name] = content
+		}
+	}
+	provisionedTemplates := map[string]bool{}
+	for _, tmpl := range templates {
+		provisionedTemplates[tmpl.Name] = true
+		provenances = append(provenances, &ngmodels.AlertConfigurationProvenance{
+			RecordType: ngmodels.TemplateRecordType,
+			RecordKey:  tmpl.Name,
+			Provenance: ngmodels.ProvenanceFile,
+		})
+		if updated.TemplateFiles[tmpl.Name] != tmpl.Content {
+			np.log.Debug("updating notification template from configuration", "name", tmpl.Name)
+			updated.TemplateFiles[tmpl.Name] = tmpl.Content
+		}
+	}
+
+	var orphans []*ngmodels.AlertConfigurationProvenance
+	previouslyProvisioned := 0
+	for _, provenance := range previous {
+		if provenance.Provenance != ngmodels.ProvenanceFile {
+			continue
+		}
+		switch provenance.RecordType {
+		case ngmodels.TemplateRecordType:
+			previouslyProvisioned++
+			if !provisionedTemplates[provenance.RecordKey] {
+				orphans = append(orphans, provenance)
+			}
+		case ngmodels.ContactPointRecordType:
+			previouslyProvisioned++
+			if !provisioned[provenance.RecordKey] {
+				orphans = append(orphans, provenance)
+			}
+		}
+	}
+	if !np.cleanupGuard.Allows(len(orphans), previouslyProvisioned) {
+		np.log.Error("Not deleting contact points and templates missing from configuration, the cleanup would delete "+
+			"more of them than allowed. Check that the provisioning directory is complete, or set orphan_cleanup_force "+
+			"to delete them", "orphaned", len(orphans), "provisioned", previouslyProvisioned)
+		// They stay provisioned, so they're deleted once they're missing from configuration on purpose.
+		return &updated, append(provenances, orphans...), nil
+	}
+
+	for _, provenance := range orphans {
+		if provenance.RecordType == ngmodels.TemplateRecordType {
+			if _, exists := updated.TemplateFiles[provenance.RecordKey]; exists {
+				np.log.Info("deleting notification template missing from configuration", "name", provenance.RecordKey)
+				delete(updated.TemplateFiles, provenance.RecordKey)
+			}
+			continue
+		}
+		if index := receiverIndex(updated.AlertmanagerConfig.Receivers, provenance.RecordKey); index >= 0 {
+			np.log.Info("deleting contact point missing from configuration", "name", provenance.RecordKey)
+			receivers := updated.AlertmanagerConfig.Receivers
+			updated.AlertmanagerConfig.Receivers = append(receivers[:index:index], receivers[index+1:]...)
+		}
+	}
+
+	return &updated, provenances, nil
+}
+
+// keptProvenances returns the provenance of the provisioned records of a type, for the parts of the configuration a
+// run doesn't change.
+func keptProvenances(previous []*ngmodels.AlertConfigurationProvenance, recordType string) []*ngmodels.AlertConfigurationProvenance {
+	var kept []*ngmodels.AlertConfigurationProvenance
+	for _, provenance := range previous {
+		if provenance.Provenance == ngmodels.ProvenanceFile && provenance.RecordType == recordType {
+			kept = append(kept, provenance)
+		}
+	}
+	return kept
+}
+
+func receiverIndex(receivers []*apimodels.PostableApiReceiver, name string) int {
+	for i, receiver := range receivers {
+		if receiver.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// provenancesEqual compares the provenance of the provisioned parts of the configuration, ignoring their order.
+func provenancesEqual(previous, provenances []*ngmodels.AlertConfigurationProvenance) bool {
+	normalize := func(list []*ngmodels.AlertConfigurationProvenance) []ngmodels.AlertConfigurationProvenance {
+		var normalized []ngmodels.AlertConfigurationProvenance
+		for _, p := range list {
+			if p.Provenance == ngmodels.ProvenanceFile {
+				normalized = append(normalized, ngmodels.AlertConfigurationProvenance{RecordType: p.RecordType,
+					RecordKey: p.RecordKey, Provenance: p.Provenance, AllowUIUpdates: p.AllowUIUpdates})
+			}
+		}
+		sort.Slice(normalized, func(i, j int) bool {
+			if normalized[i].RecordType != normalized[j].RecordType {
+				return normalized[i].RecordType < normalized[j].RecordType
+			}
+			return normalized[i].RecordKey < normalized[j].RecordKey
+		})
+		return normalized
+	}
+	return reflect.DeepEqual(normalize(previous), normalize(provenances))
+}
@@ -0,0 +1,161 @@
+package alerting
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/notifier"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+)
+
+func (cr *configReader) readNotificationConfig(path string) ([]*notificationsAsConfig, error) {
+	var notifications []*notificationsAsConfig
+	cr.log.Debug("Looking for contact point provisioning files", "path", path)
+
+	files, err := utils.ReadDir(path)
+	if err != nil {
+		cr.log.Error("Can't read contact point provisioning files from directory", "path", path, "error", err)
+		return notifications, nil
+	}
+
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".yaml") || strings.HasSuffix(file.Name(), ".yml") {
+			if !cr.fileFilter.Includes(file.Name()) {
+				cr.log.Debug("Skipping excluded contact point provisioning file", "path", path, "file.Name", file.Name())
+				continue
+			}
+
+			cr.log.Debug("Parsing contact point provisioning file", "path", path, "file.Name", file.Name())
+			n, err := cr.parseNotificationConfig(path, file)
+			if err != nil {
+				return nil, err
+			}
+
+			if n != nil {
+				notifications = append(notifications, n)
+			}
+		}
+	}
+
+	cr.log.Debug("Validating contact points and notification policies")
+	if err := validateNotifications(notifications); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// readTemplates reads the notification templates in the templates subdirectory of path. A missing subdirectory
+// means there are no templates.
+func (cr *configReader) readTemplates(path string) ([]*templateFromConfig, error) {
+	templatesPath := filepath.Join(path, templatesDirectory)
+	files, err := utils.ReadDir(templatesPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		cr.log.Debug("No notification templates to provision", "path", templatesPath)
+		return nil, nil
+	}
+
+	var templates []*templateFromConfig
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".tmpl") {
+			continue
+		}
+		if !cr.fileFilter.Includes(file.Name()) {
+			cr.log.Debug("Skipping excluded notification template", "path", templatesPath, "file.Name", file.Name())
+			continue
+		}
+
+		filename, err := filepath.Abs(filepath.Join(templatesPath, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		// nolint:gosec
+		// We can ignore the gosec G304 warning on this one because `filename` comes from ps.Cfg.ProvisioningPath
+		content, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := parseTemplate(file.Name(), string(content)); err != nil {
+			return nil, fmt.Errorf("invalid notification template %s: %w", filename, err)
+		}
+
+		templates = append(templates, &templateFromConfig{Name: file.Name(), Content: string(content), Filename: filename})
+	}
+	return templates, nil
+}
+
+func (cr *configReader) parseNotificationConfig(path string, file os.FileInfo) (*notificationsAsConfig, error) {
+	filename, err := filepath.Abs(filepath.Join(path, file.Name()))
+	if err != nil {
+		return nil, err
+	}
+
+	// nolint:gosec
+	// We can ignore the gosec G304 warning on this one because `filename` comes from ps.Cfg.ProvisioningPath
+	yamlFile, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg *notificationsAsConfigV1
+	decoder := utils.YAMLDecoder{Subsystem: "alerting", Kind: "AlertNotification", Strict: cr.strict, Environment: cr.env,
+		Log: cr.log}
+	if err := decoder.Decode(filename, yamlFile, &cfg); err != nil {
+		if errors.Is(err, utils.ErrFileSkipped) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	n := &notificationsAsConfig{Filename: file.Name()}
+	if cfg == nil {
+		return n, nil
+	}
+
+	for _, contactPoint := range cfg.ContactPoints {
+		n.ContactPoints = append(n.ContactPoints, contactPoint.mapToContactPoint())
+	}
+
+	if cfg.Policy != nil {
+		if cfg.Policy.Route == nil {
+			return nil, fmt.Errorf("%s: notification policy is missing its route", file.Name())
+		}
+		n.Policy = &notificationPolicyFromConfig{Route: cfg.Policy.Route, AllowUIUpdates: cfg.Policy.AllowUIUpdates.Value()}
+	}
+
+	return n, nil
+}
+
+// validateNotifications checks the receivers of every contact point and makes sure contact point names are unique.
+// The notification policy trees are validated when they're merged.
+func validateNotifications(configs []*notificationsAsConfig) error {
+	contactPoints := map[string]string{}
+	for _, cfg := range configs {
+		for index, contactPoint := range cfg.ContactPoints {
+			name := contactPoint.Receiver.Name
+			if name == "" {
+				return fmt.Errorf("contact point item %d in %s: missing required field name", index+1, cfg.Filename)
+			}
+			if other, exists := contactPoints[name]; exists {
+				return fmt.Errorf("%s: contact point %q is already provisioned by %s", cfg.Filename, name, other)
+			}
+			contactPoints[name] = cfg.Filename
+
+			if len(contactPoint.Receiver.GrafanaManagedReceivers) == 0 {
+				return fmt.Errorf("%s: contact point %q has no receivers", cfg.Filename, name)
+			}
+			if err := notifier.ValidateReceiver(contactPoint.Receiver); err != nil {
+				return fmt.Errorf("%s: %w", cfg.Filename, err)
+			}
+		}
+	}
+
+	return nil
+}
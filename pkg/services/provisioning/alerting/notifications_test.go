@@ -0,0 +1,356 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/notifier"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	notificationsConfig                = "testdata/notifications"
+	notificationsUnsupportedTypeConfig = "testdata/notifications-unsupported-type"
+	notificationsWithoutPolicyConfig   = "testdata/notifications-without-policy"
+	notificationTemplatesConfig        = "testdata/notification-templates"
+	missingNotificationTemplateConfig  = "testdata/notification-templates-missing"
+	splitNotificationPolicyConfig      = "testdata/notifications-split-policy"
+	notificationsMuteTimingsConfig     = "testdata/notifications-mute-timings"
+	muteTimingsConfig                  = "testdata/mute-timings"
+	invalidMuteTimingsConfig           = "testdata/mute-timings-invalid"
+)
+
+func TestNotificationProvisioner(t *testing.T) {
+	setup := func() (*NotificationProvisioner, *fakeNotificationStore) {
+		notificationStore := &fakeNotificationStore{}
+		logger := log.New("test logger")
+		return &NotificationProvisioner{log: logger, cfgProvider: &configReader{log: logger}, store: notificationStore,
+			cleanupGuard: setting.ProvisioningCleanupGuard{MaxPercent: 100}}, notificationStore
+	}
+
+	t.Run("Adds contact points and the notification policy tree to the default configuration", func(t *testing.T) {
+		np, notificationStore := setup()
+
+		require.NoError(t, np.applyChanges(context.Background(), notificationsConfig))
+
+		require.Len(t, notificationStore.saved, 1)
+		cfg := notificationStore.latestConfig(t)
+		assert.Equal(t, []string{"grafana-default-email", "ops-email", "ops-webhook"}, receiverNames(cfg))
+		assert.Equal(t, "ops-email", cfg.AlertmanagerConfig.Route.Receiver)
+		require.Len(t, cfg.AlertmanagerConfig.Route.Routes, 1)
+		assert.Equal(t, "ops-webhook", cfg.AlertmanagerConfig.Route.Routes[0].Receiver)
+
+		webhook := cfg.AlertmanagerConfig.Receivers[2].GrafanaManagedReceivers[0]
+		assert.Equal(t, "webhook", webhook.Type)
+		assert.Equal(t, "secret", webhook.SecureSettings["password"])
+
+		assert.ElementsMatch(t, []ngmodels.AlertConfigurationProvenance{
+			{RecordType: ngmodels.ContactPointRecordType, RecordKey: "ops-email", Provenance: ngmodels.ProvenanceFile},
+			{RecordType: ngmodels.ContactPointRecordType, RecordKey: "ops-webhook", Provenance: ngmodels.ProvenanceFile, AllowUIUpdates: true},
+			{RecordType: ngmodels.NotificationPolicyRecordType, RecordKey: ngmodels.NotificationPolicyRecordKey, Provenance: ngmodels.ProvenanceFile},
+		}, notificationStore.provenanceValues())
+	})
+
+	t.Run("Merges the notification policy trees of all files", func(t *testing.T) {
+		np, notificationStore := setup()
+
+		require.NoError(t, np.applyChanges(context.Background(), splitNotificationPolicyConfig))
+
+		route := notificationStore.latestConfig(t).AlertmanagerConfig.Route
+		assert.Equal(t, "ops-email", route.Receiver)
+		require.Len(t, route.Routes, 2)
+		assert.Equal(t, "ops-webhook", route.Routes[0].Receiver)
+		assert.Equal(t, "10s", route.Routes[0].GroupWait.String())
+		assert.Equal(t, "ops-email", route.Routes[1].Receiver)
+	})
+
+	t.Run("Doesn't save a new configuration when nothing changed", func(t *testing.T) {
+		np, notificationStore := setup()
+		require.NoError(t, np.applyChanges(context.Background(), notificationsConfig))
+
+		require.NoError(t, np.applyChanges(context.Background(), notificationsConfig))
+
+		require.Len(t, notificationStore.saved, 1)
+	})
+
+	t.Run("Deletes provisioned contact points missing from the files and keeps the others", func(t *testing.T) {
+		np, notificationStore := setup()
+		notificationStore.saveConfig(t, `{
+			"alertmanager_config": {
+				"route": {"receiver": "ui-created"},
+				"receivers": [
+					{"name": "ui-created", "grafana_managed_receiver_configs": [{"name": "ui-created", "type": "email", "settings": {"addresses": "ui@example.com"}}]},
+					{"name": "removed", "grafana_managed_receiver_configs": [{"name": "removed", "type": "email", "settings": {"addresses": "removed@example.com"}}]}
+				]
+			}
+		}`)
+		notificationStore.provenances = []*ngmodels.AlertConfigurationProvenance{
+			{RecordType: ngmodels.ContactPointRecordType, RecordKey: "removed", Provenance: ngmodels.ProvenanceFile},
+		}
+
+		require.NoError(t, np.applyChanges(context.Background(), notificationsConfig))
+
+		cfg := notificationStore.latestConfig(t)
+		assert.Equal(t, []string{"ui-created", "ops-email", "ops-webhook"}, receiverNames(cfg))
+	})
+
+	t.Run("Fails when a deleted contact point is still used by the notification policies", func(t *testing.T) {
+		np, notificationStore := setup()
+		notificationStore.saveConfig(t, `{
+			"alertmanager_config": {
+				"route": {"receiver": "removed"},
+				"receivers": [
+					{"name": "removed", "grafana_managed_receiver_configs": [{"name": "removed", "type": "email", "settings": {"addresses": "removed@example.com"}}]}
+				]
+			}
+		}`)
+		notificationStore.provenances = []*ngmodels.AlertConfigurationProvenance{
+			{RecordType: ngmodels.ContactPointRecordType, RecordKey: "removed", Provenance: ngmodels.ProvenanceFile},
+		}
+
+		err := np.applyChanges(context.Background(), notificationsWithoutPolicyConfig)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unexpected receiver (removed) is undefined")
+		require.Len(t, notificationStore.saved, 1)
+	})
+
+	t.Run("Fails for receivers of an unsupported type", func(t *testing.T) {
+		np, notificationStore := setup()
+
+		err := np.applyChanges(context.Background(), notificationsUnsupportedTypeConfig)
+		require.EqualError(t, err, `contact-points.yaml: receiver "ops-pager" has unsupported type "pager"`)
+		assert.Empty(t, notificationStore.saved)
+	})
+
+	t.Run("Adds the notification templates in the templates subdirectory", func(t *testing.T) {
+		np, notificationStore := setup()
+
+		require.NoError(t, np.applyChanges(context.Background(), notificationTemplatesConfig))
+
+		cfg := notificationStore.latestConfig(t)
+		require.Len(t, cfg.TemplateFiles, 1)
+		assert.Contains(t, cfg.TemplateFiles["ops.tmpl"], `{{ define "ops.text" }}`)
+		assert.Contains(t, notificationStore.provenanceValues(), ngmodels.AlertConfigurationProvenance{
+			RecordType: ngmodels.TemplateRecordType, RecordKey: "ops.tmpl", Provenance: ngmodels.ProvenanceFile,
+		})
+
+		require.NoError(t, np.applyChanges(context.Background(), notificationTemplatesConfig))
+		require.Len(t, notificationStore.saved, 1, "Unchanged templates should not save a new configuration")
+	})
+
+	t.Run("Deletes provisioned templates missing from the files and keeps the others", func(t *testing.T) {
+		np, notificationStore := setup()
+		notificationStore.saveConfig(t, `{
+			"template_files": {"ui.tmpl": "{{ define \"ui\" }}UI{{ end }}", "removed.tmpl": "{{ define \"removed\" }}Removed{{ end }}"},
+			"alertmanager_config": {
+				"route": {"receiver": "ui-created"},
+				"receivers": [
+					{"name": "ui-created", "grafana_managed_receiver_configs": [{"name": "ui-created", "type": "email", "settings": {"addresses": "ui@example.com"}}]}
+				]
+			}
+		}`)
+		notificationStore.provenances = []*ngmodels.AlertConfigurationProvenance{
+			{RecordType: ngmodels.TemplateRecordType, RecordKey: "removed.tmpl", Provenance: ngmodels.ProvenanceFile},
+		}
+
+		require.NoError(t, np.applyChanges(context.Background(), notificationTemplatesConfig))
+
+		cfg := notificationStore.latestConfig(t)
+		assert.Contains(t, cfg.TemplateFiles, "ui.tmpl")
+		assert.Contains(t, cfg.TemplateFiles, "ops.tmpl")
+		assert.NotContains(t, cfg.TemplateFiles, "removed.tmpl")
+	})
+
+	t.Run("Fails for contact points that reference a missing template", func(t *testing.T) {
+		np, notificationStore := setup()
+
+		err := np.applyChanges(context.Background(), missingNotificationTemplateConfig)
+		require.EqualError(t, err, `contact-points.yaml: contact point "ops-slack" references missing template "ops.missing"`)
+		assert.Empty(t, notificationStore.saved)
+	})
+
+	t.Run("Adds the mute timings the notification policies use", func(t *testing.T) {
+		np, notificationStore := setup()
+		np.muteTimingsPath = muteTimingsConfig
+
+		require.NoError(t, np.applyChanges(context.Background(), notificationsMuteTimingsConfig))
+
+		cfg := notificationStore.latestConfig(t)
+		require.Len(t, cfg.AlertmanagerConfig.MuteTimeIntervals, 2)
+		assert.Equal(t, "weekends", cfg.AlertmanagerConfig.MuteTimeIntervals[0].Name)
+		maintenance := cfg.AlertmanagerConfig.MuteTimeIntervals[1]
+		assert.Equal(t, "maintenance", maintenance.Name)
+		require.Len(t, maintenance.TimeIntervals, 1)
+		assert.Equal(t, 22*60, maintenance.TimeIntervals[0].Times[0].StartMinute)
+		assert.Equal(t, []string{"weekends", "maintenance"}, cfg.AlertmanagerConfig.Route.Routes[0].MuteTimeIntervals)
+		assert.Contains(t, notificationStore.provenanceValues(), ngmodels.AlertConfigurationProvenance{
+			RecordType: ngmodels.MuteTimingRecordType, RecordKey: "maintenance", Provenance: ngmodels.ProvenanceFile,
+		})
+
+		require.NoError(t, np.applyChanges(context.Background(), notificationsMuteTimingsConfig))
+		require.Len(t, notificationStore.saved, 1, "Unchanged mute timings should not save a new configuration")
+	})
+
+	t.Run("Fails for notification policies that use a missing mute timing", func(t *testing.T) {
+		np, notificationStore := setup()
+
+		err := np.applyChanges(context.Background(), notificationsMuteTimingsConfig)
+		require.EqualError(t, err,
+			`contact-points.yaml: policy matching {severity="warning"} references missing mute timing "weekends"`)
+		assert.Empty(t, notificationStore.saved)
+	})
+
+	t.Run("Fails for mute timings with invalid time intervals", func(t *testing.T) {
+		np, notificationStore := setup()
+		np.muteTimingsPath = invalidMuteTimingsConfig
+
+		err := np.applyChanges(context.Background(), notificationsWithoutPolicyConfig)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "25:00")
+		assert.Empty(t, notificationStore.saved)
+	})
+
+	t.Run("Refuses to delete a provisioned mute timing that a notification policy uses", func(t *testing.T) {
+		np, notificationStore := setup()
+		np.muteTimingsPath = muteTimingsConfig
+		notificationStore.saveConfig(t, `{
+			"alertmanager_config": {
+				"route": {"receiver": "ops-email", "routes": [{"receiver": "ops-email", "matchers": ["team=\"a\""], "mute_time_intervals": ["retired"]}]},
+				"mute_time_intervals": [{"name": "retired", "time_intervals": [{"weekdays": ["monday"]}]}],
+				"receivers": [
+					{"name": "ops-email", "grafana_managed_receiver_configs": [{"name": "ops-email", "type": "email", "settings": {"addresses": "ops@example.com"}}]}
+				]
+			}
+		}`)
+		notificationStore.provenances = []*ngmodels.AlertConfigurationProvenance{
+			{RecordType: ngmodels.MuteTimingRecordType, RecordKey: "retired", Provenance: ngmodels.ProvenanceFile},
+		}
+
+		err := np.applyChanges(context.Background(), notificationsWithoutPolicyConfig)
+		require.True(t, errors.Is(err, ErrMuteTimingInUse))
+		assert.EqualError(t, err, `can't delete mute timing "retired": mute timing is used by a notification policy: `+
+			`policy matching {team="a"}`)
+		require.Len(t, notificationStore.saved, 1)
+	})
+
+	t.Run("Keeps provisioned mute timings when their directory is missing", func(t *testing.T) {
+		np, notificationStore := setup()
+		np.muteTimingsPath = "testdata/missing"
+		notificationStore.saveConfig(t, `{
+			"alertmanager_config": {
+				"route": {"receiver": "ops-email"},
+				"mute_time_intervals": [{"name": "retired", "time_intervals": [{"weekdays": ["monday"]}]}],
+				"receivers": [
+					{"name": "ops-email", "grafana_managed_receiver_configs": [{"name": "ops-email", "type": "email", "settings": {"addresses": "ops@example.com"}}]}
+				]
+			}
+		}`)
+		retired := &ngmodels.AlertConfigurationProvenance{RecordType: ngmodels.MuteTimingRecordType, RecordKey: "retired",
+			Provenance: ngmodels.ProvenanceFile}
+		notificationStore.provenances = []*ngmodels.AlertConfigurationProvenance{retired}
+
+		require.NoError(t, np.applyChanges(context.Background(), notificationsWithoutPolicyConfig))
+
+		cfg := notificationStore.latestConfig(t)
+		require.Len(t, cfg.AlertmanagerConfig.MuteTimeIntervals, 1)
+		assert.Contains(t, notificationStore.provenanceValues(), *retired)
+	})
+
+	t.Run("An empty directory triggers the cleanup guard", func(t *testing.T) {
+		np, notificationStore := setup()
+		np.cleanupGuard = setting.ProvisioningCleanupGuard{MaxPercent: 50}
+		notificationStore.saveConfig(t, `{
+			"template_files": {"ops.tmpl": "{{ define \"ops\" }}Ops{{ end }}"},
+			"alertmanager_config": {
+				"route": {"receiver": "ops-email"},
+				"receivers": [
+					{"name": "ops-email", "grafana_managed_receiver_configs": [{"name": "ops-email", "type": "email", "settings": {"addresses": "ops@example.com"}}]}
+				]
+			}
+		}`)
+		provenances := []*ngmodels.AlertConfigurationProvenance{
+			{RecordType: ngmodels.ContactPointRecordType, RecordKey: "ops-email", Provenance: ngmodels.ProvenanceFile},
+			{RecordType: ngmodels.TemplateRecordType, RecordKey: "ops.tmpl", Provenance: ngmodels.ProvenanceFile},
+		}
+		notificationStore.provenances = provenances
+
+		require.NoError(t, np.applyChanges(context.Background(), t.TempDir()))
+		cfg := notificationStore.latestConfig(t)
+		assert.Equal(t, []string{"ops-email"}, receiverNames(cfg))
+		assert.Contains(t, cfg.TemplateFiles, "ops.tmpl")
+		assert.ElementsMatch(t, []ngmodels.AlertConfigurationProvenance{*provenances[0], *provenances[1]},
+			notificationStore.provenanceValues(), "The contact points and templates should stay provisioned")
+	})
+
+	t.Run("Keeps provisioned contact points when the directory is missing", func(t *testing.T) {
+		np, notificationStore := setup()
+		notificationStore.provenances = []*ngmodels.AlertConfigurationProvenance{
+			{RecordType: ngmodels.ContactPointRecordType, RecordKey: "ops-email", Provenance: ngmodels.ProvenanceFile},
+		}
+
+		require.NoError(t, np.applyChanges(context.Background(), "testdata/missing"))
+		assert.Empty(t, notificationStore.saved)
+	})
+}
+
+func receiverNames(cfg *apimodels.PostableUserConfig) []string {
+	var names []string
+	for _, receiver := range cfg.AlertmanagerConfig.Receivers {
+		names = append(names, receiver.Name)
+	}
+	return names
+}
+
+type fakeNotificationStore struct {
+	saved       []string
+	provenances []*ngmodels.AlertConfigurationProvenance
+}
+
+func (s *fakeNotificationStore) GetLatestAlertmanagerConfiguration(query *ngmodels.GetLatestAlertmanagerConfigurationQuery) error {
+	if len(s.saved) == 0 {
+		return store.ErrNoAlertmanagerConfiguration
+	}
+	query.Result = &ngmodels.AlertConfiguration{AlertmanagerConfiguration: s.saved[len(s.saved)-1]}
+	return nil
+}
+
+func (s *fakeNotificationStore) GetAlertConfigurationProvenances(query *ngmodels.GetAlertConfigurationProvenancesQuery) error {
+	query.Result = s.provenances
+	return nil
+}
+
+func (s *fakeNotificationStore) SaveProvisionedAlertmanagerConfiguration(cmd *ngmodels.SaveProvisionedAlertmanagerConfigurationCmd) error {
+	s.saved = append(s.saved, cmd.AlertmanagerConfiguration)
+	s.provenances = cmd.Provenances
+	return nil
+}
+
+func (s *fakeNotificationStore) saveConfig(t *testing.T, cfg string) {
+	t.Helper()
+	require.True(t, json.Valid([]byte(cfg)))
+	s.saved = append(s.saved, cfg)
+}
+
+func (s *fakeNotificationStore) latestConfig(t *testing.T) *apimodels.PostableUserConfig {
+	t.Helper()
+	require.NotEmpty(t, s.saved)
+	cfg, err := notifier.Load([]byte(s.saved[len(s.saved)-1]))
+	require.NoError(t, err)
+	return cfg
+}
+
+func (s *fakeNotificationStore) provenanceValues() []ngmodels.AlertConfigurationProvenance {
+	var values []ngmodels.AlertConfigurationProvenance
+	for _, p := range s.provenances {
+		values = append(values, *p)
+	}
+	return values
+}
@@ -0,0 +1,178 @@
+package alerting
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// maxRuleUIDLength is the column size of alert rule UIDs.
+	maxRuleUIDLength = 40
+	// ruleUIDHashLength is the number of hex characters of the parameter hash appended to template UIDs.
+	ruleUIDHashLength = 10
+	// maxTemplateUIDLength leaves room for the separator and the hash in generated UIDs.
+	maxTemplateUIDLength = maxRuleUIDLength - ruleUIDHashLength - 1
+)
+
+// expand generates one rule per combination of the matrix values, in a stable order.
+func (tmpl *ruleTemplateV1) expand() ([]*ruleFromConfig, error) {
+	templateUID := tmpl.UID.Value()
+	if templateUID == "" {
+		return nil, fmt.Errorf("rule template is missing required field uid")
+	}
+	if len(templateUID) > maxTemplateUIDLength {
+		return nil, fmt.Errorf("rule template %q: uid can't be longer than %d characters", templateUID, maxTemplateUIDLength)
+	}
+	if _, ok := tmpl.Rule["uid"]; ok {
+		return nil, fmt.Errorf("rule template %q: generated rules can't set a uid, it's derived from the template uid", templateUID)
+	}
+
+	combinations, err := matrixCombinations(tmpl.Matrix)
+	if err != nil {
+		return nil, fmt.Errorf("rule template %q: %w", templateUID, err)
+	}
+
+	rules := make([]*ruleFromConfig, 0, len(combinations))
+	for _, params := range combinations {
+		source := fmt.Sprintf("rule template %q with %s", templateUID, formatParams(params))
+
+		rendered, err := renderTemplate(tmpl.Rule, params)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+
+		// Going through YAML again decodes the rendered rule exactly like a rule written out in full,
+		// including the interpolation of environment variables.
+		raw, err := yaml.Marshal(rendered)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+
+		var ruleV1 ruleFromConfigV1
+		if err := yaml.Unmarshal(raw, &ruleV1); err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+
+		rule, err := ruleV1.mapToAlertRule()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		rule.UID = generatedRuleUID(templateUID, params)
+
+		rules = append(rules, &ruleFromConfig{Source: source, Rule: rule})
+	}
+
+	return rules, nil
+}
+
+// matrixCombinations returns the cartesian product of the matrix values. Parameters are combined in the
+// order of their names and values in the order they are listed, so the result doesn't depend on map order.
+func matrixCombinations(matrix map[string][]string) ([]map[string]string, error) {
+	if len(matrix) == 0 {
+		return nil, fmt.Errorf("matrix needs at least one parameter")
+	}
+
+	names := make([]string, 0, len(matrix))
+	for name := range matrix {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	combinations := []map[string]string{{}}
+	for _, name := range names {
+		if len(matrix[name]) == 0 {
+			return nil, fmt.Errorf("matrix parameter %q has no values", name)
+		}
+
+		next := make([]map[string]string, 0, len(combinations)*len(matrix[name]))
+		for _, combination := range combinations {
+			for _, value := range matrix[name] {
+				params := make(map[string]string, len(combination)+1)
+				for k, v := range combination {
+					params[k] = v
+				}
+				params[name] = value
+				next = append(next, params)
+			}
+		}
+		combinations = next
+	}
+
+	return combinations, nil
+}
+
+// renderTemplate executes every string in the raw rule as a Go template with the parameters as data.
+func renderTemplate(raw interface{}, params map[string]string) (interface{}, error) {
+	switch value := raw.(type) {
+	case string:
+		tmpl, err := template.New("rule").Option("missingkey=error").Parse(value)
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, params); err != nil {
+			return nil, err
+		}
+		return buf.String(), nil
+	case map[interface{}]interface{}:
+		rendered := make(map[interface{}]interface{}, len(value))
+		for k, v := range value {
+			r, err := renderTemplate(v, params)
+			if err != nil {
+				return nil, err
+			}
+			rendered[k] = r
+		}
+		return rendered, nil
+	case map[string]interface{}:
+		rendered := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			r, err := renderTemplate(v, params)
+			if err != nil {
+				return nil, err
+			}
+			rendered[k] = r
+		}
+		return rendered, nil
+	case []interface{}:
+		rendered := make([]interface{}, len(value))
+		for i, v := range value {
+			r, err := renderTemplate(v, params)
+			if err != nil {
+				return nil, err
+			}
+			rendered[i] = r
+		}
+		return rendered, nil
+	default:
+		return raw, nil
+	}
+}
+
+// generatedRuleUID derives the UID of a generated rule from the template UID and its parameters, so the
+// same parameters always end up updating the same rule.
+func generatedRuleUID(templateUID string, params map[string]string) string {
+	// Maps are encoded with sorted keys, which makes the encoding stable.
+	encoded, _ := json.Marshal(params)
+	hash := sha256.Sum256(encoded)
+	return templateUID + "-" + hex.EncodeToString(hash[:])[:ruleUIDHashLength]
+}
+
+// formatParams formats parameters as name=value pairs sorted by name.
+func formatParams(params map[string]string) string {
+	pairs := make([]string, 0, len(params))
+	for name, value := range params {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,92 @@
+package alerting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	templatesConfig            = "testdata/templates"
+	duplicateUIDConfig         = "testdata/duplicate-uid"
+	missingTemplateParamConfig = "testdata/missing-template-param"
+	invalidConditionConfig     = "testdata/invalid-condition"
+)
+
+func TestRuleTemplates(t *testing.T) {
+	reader := &configReader{log: log.New("test logger")}
+
+	t.Run("Generates one rule per matrix combination", func(t *testing.T) {
+		configs, err := reader.readConfig(templatesConfig)
+		require.NoError(t, err)
+		require.Len(t, configs, 1)
+
+		rules := configs[0].Rules
+		require.Len(t, rules, 5)
+		assert.Equal(t, "disk-usage", rules[0].Rule.UID)
+
+		var titles []string
+		for _, r := range rules[1:] {
+			titles = append(titles, r.Rule.Title)
+		}
+		assert.Equal(t, []string{
+			"Errors in api (prod)",
+			"Errors in web (prod)",
+			"Errors in api (staging)",
+			"Errors in web (staging)",
+		}, titles)
+
+		generated := rules[1].Rule
+		assert.Equal(t, int64(1), generated.OrgID)
+		assert.Equal(t, "services", generated.NamespaceUID)
+		assert.Equal(t, "api", generated.RuleGroup)
+		assert.Equal(t, 5*time.Minute, generated.For)
+		assert.Equal(t, map[string]string{"service": "api", "env": "prod"}, generated.Labels)
+		require.Len(t, generated.Data, 1)
+		assert.JSONEq(t, `{"expr": "rate(errors_total{service=\"api\", env=\"prod\"}[5m]) > 0"}`, string(generated.Data[0].Model))
+	})
+
+	t.Run("Generated UIDs are deterministic across runs", func(t *testing.T) {
+		first, err := reader.readConfig(templatesConfig)
+		require.NoError(t, err)
+		second, err := reader.readConfig(templatesConfig)
+		require.NoError(t, err)
+
+		seen := map[string]bool{}
+		for i, r := range first[0].Rules {
+			assert.Equal(t, r.Rule.UID, second[0].Rules[i].Rule.UID)
+			assert.False(t, seen[r.Rule.UID], "UIDs should be unique")
+			seen[r.Rule.UID] = true
+		}
+
+		// Pinned so a change to the derivation, which would recreate every generated rule, doesn't go unnoticed.
+		assert.Equal(t, "service-errors-62749ef10b", first[0].Rules[1].Rule.UID)
+	})
+
+	t.Run("UIDs don't depend on the order of the parameters", func(t *testing.T) {
+		params := map[string]string{"service": "api", "env": "prod"}
+		reordered := map[string]string{"env": "prod", "service": "api"}
+		assert.Equal(t, generatedRuleUID("service-errors", params), generatedRuleUID("service-errors", reordered))
+	})
+
+	t.Run("Fails when generated rules share a UID", func(t *testing.T) {
+		_, err := reader.readConfig(duplicateUIDConfig)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is already used by rule template \"service-errors\" with service=api")
+	})
+
+	t.Run("Fails when a template uses an unknown parameter", func(t *testing.T) {
+		_, err := reader.readConfig(missingTemplateParamConfig)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `rule template "service-errors" with service=api`)
+		assert.Contains(t, err.Error(), "servce")
+	})
+
+	t.Run("Validates generated rules", func(t *testing.T) {
+		_, err := reader.readConfig(invalidConditionConfig)
+		assert.EqualError(t, err, `rule template "service-errors" with service=api: condition "B" doesn't match the refId of any query`)
+	})
+}
@@ -0,0 +1,162 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// RuleStore is the part of the unified alerting store used to provision alert rules.
+type RuleStore interface {
+	GetAlertRuleByUID(*ngmodels.GetAlertRuleByUIDQuery) error
+	UpsertAlertRules([]store.UpsertRule) error
+}
+
+// ProvisionRules scans a directory for provisioning config files
+// and provisions the alert rules in those files. The rules that were applied are recorded in inventory.
+func ProvisionRules(ctx context.Context, configDirectory string, ruleStore RuleStore, fileFilter setting.ProvisioningFileFilter,
+	strict bool, inventory *utils.Inventory) error {
+	logger := log.New("provisioning.alerting")
+	rp := RuleProvisioner{
+		log:         logger,
+		cfgProvider: &configReader{log: logger, fileFilter: fileFilter, strict: strict, env: utils.EnvironmentFromContext(ctx)},
+		store:       ruleStore,
+		inventory:   inventory,
+	}
+	return rp.applyChanges(ctx, configDirectory)
+}
+
+// RuleProvisioner is responsible for provisioning alert rules based on
+// configuration read by the `configReader`
+type RuleProvisioner struct {
+	log         log.Logger
+	cfgProvider *configReader
+	store       RuleStore
+	inventory   *utils.Inventory
+}
+
+func (rp *RuleProvisioner) applyChanges(ctx context.Context, configPath string) error {
+	configs, err := rp.cfgProvider.readConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	var upserts []store.UpsertRule
+	var applied []utils.ProvisionedObject
+	var dangling []utils.DanglingReference
+	refCheck := utils.ReferenceCheckFromContext(ctx)
+	datasourceExists := utils.DatasourceExists(ctx)
+	for _, cfg := range configs {
+		filename := provisioningFilePath(configPath, cfg.Filename)
+		for _, r := range cfg.Rules {
+			upsert, err := rp.upsertRule(ctx, r)
+			if err != nil {
+				return fmt.Errorf("%s: %w", r.Source, err)
+			}
+			upserts = append(upserts, upsert)
+			action := utils.ActionCreated
+			if upsert.Existing != nil {
+				action = utils.ActionUpdated
+			}
+			applied = append(applied, utils.ProvisionedObject{Kind: "alert_rule", Name: r.Rule.Title, UID: r.Rule.UID,
+				OrgID: r.Rule.OrgID, File: filename, Action: action})
+			if refCheck != utils.ReferenceCheckOff {
+				dangling = append(dangling, danglingReferences(r, filename, datasourceExists)...)
+			}
+		}
+	}
+
+	// The rules are checked once all of them are read, so a failing check doesn't apply any of them.
+	if err := utils.CheckDanglingReferences(rp.log, refCheck, dangling); err != nil {
+		return err
+	}
+
+	if len(upserts) == 0 {
+		return nil
+	}
+
+	// The rule store doesn't take a context, so a canceled run stops before the rules are upserted.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// The rules are upserted in a single transaction, so either all of them are applied or none.
+	if err := rp.store.UpsertAlertRules(upserts); err != nil {
+		return err
+	}
+	for _, object := range applied {
+		rp.inventory.Record(object)
+	}
+	return nil
+}
+
+// danglingReferences returns the references of the queries of a rule to datasources that don't exist in its org.
+func danglingReferences(r *ruleFromConfig, filename string, datasourceExists func(orgID int64, uid string) bool) []utils.DanglingReference {
+	var dangling []utils.DanglingReference
+	for _, query := range r.Rule.Data {
+		if utils.IsDatasourceReference(query.DatasourceUID) && !datasourceExists(r.Rule.OrgID, query.DatasourceUID) {
+			dangling = append(dangling, utils.DanglingReference{File: filename, Kind: "alert_rule", UID: r.Rule.UID,
+				Location: fmt.Sprintf("query %s", query.RefID), DatasourceUID: query.DatasourceUID})
+		}
+	}
+	return dangling
+}
+
+// provisioningFilePath returns the absolute path of a file read from the config directory.
+func provisioningFilePath(configPath string, filename string) string {
+	path, err := filepath.Abs(filepath.Join(configPath, filename))
+	if err != nil {
+		return filepath.Join(configPath, filename)
+	}
+	return path
+}
+
+func (rp *RuleProvisioner) upsertRule(ctx context.Context, r *ruleFromConfig) (store.UpsertRule, error) {
+	upsert := store.UpsertRule{New: r.Rule, CreateWithUID: true}
+
+	query := &ngmodels.GetAlertRuleByUIDQuery{UID: r.Rule.UID, OrgID: r.Rule.OrgID}
+	err := rp.store.GetAlertRuleByUID(query)
+	if err != nil && !errors.Is(err, ngmodels.ErrAlertRuleNotFound) {
+		return upsert, err
+	}
+
+	if err == nil {
+		existing := query.Result
+		if existing.NamespaceUID != r.Rule.NamespaceUID || existing.RuleGroup != r.Rule.RuleGroup {
+			rp.log.Warn("provisioned alert rule can't be moved to another folder or group, keeping it where it is",
+				"uid", existing.UID, "folderUid", existing.NamespaceUID, "group", existing.RuleGroup)
+		}
+		rp.log.Debug("updating alert rule from configuration", "uid", existing.UID, "title", r.Rule.Title)
+		upsert.Existing = existing
+		return upsert, nil
+	}
+
+	if err := checkFolderExists(ctx, r.Rule.OrgID, r.Rule.NamespaceUID); err != nil {
+		return upsert, err
+	}
+	rp.log.Info("inserting alert rule from configuration", "uid", r.Rule.UID, "title", r.Rule.Title)
+	return upsert, nil
+}
+
+func checkFolderExists(ctx context.Context, orgID int64, folderUID string) error {
+	query := &models.GetDashboardQuery{OrgId: orgID, Uid: folderUID}
+	if err := bus.DispatchCtx(ctx, query); err != nil {
+		if errors.Is(err, models.ErrDashboardNotFound) {
+			return fmt.Errorf("folder %q not found", folderUID)
+		}
+		return err
+	}
+
+	if !query.Result.IsFolder {
+		return fmt.Errorf("%q is a dashboard, not a folder", folderUID)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleProvisioner(t *testing.T) {
+	setup := func(t *testing.T, folders ...string) (*RuleProvisioner, *fakeRuleStore) {
+		bus.ClearBusHandlers()
+		t.Cleanup(bus.ClearBusHandlers)
+		bus.AddHandler("test", func(query *models.GetDashboardQuery) error {
+			for _, uid := range folders {
+				if uid == query.Uid {
+					query.Result = &models.Dashboard{Uid: uid, IsFolder: true}
+					return nil
+				}
+			}
+			return models.ErrDashboardNotFound
+		})
+
+		ruleStore := &fakeRuleStore{rules: map[string]*ngmodels.AlertRule{}}
+		logger := log.New("test logger")
+		return &RuleProvisioner{log: logger, cfgProvider: &configReader{log: logger}, store: ruleStore}, ruleStore
+	}
+
+	t.Run("Creates generated rules with their UIDs", func(t *testing.T) {
+		rp, ruleStore := setup(t, "infra", "services")
+
+		require.NoError(t, rp.applyChanges(context.Background(), templatesConfig))
+
+		require.Len(t, ruleStore.upserted, 5)
+		for _, upsert := range ruleStore.upserted {
+			assert.Nil(t, upsert.Existing)
+			assert.True(t, upsert.CreateWithUID)
+			assert.NotEmpty(t, upsert.New.UID)
+		}
+	})
+
+	t.Run("Running again updates the same rules", func(t *testing.T) {
+		rp, ruleStore := setup(t, "infra", "services")
+		require.NoError(t, rp.applyChanges(context.Background(), templatesConfig))
+		created := ruleStore.upserted
+
+		ruleStore.upserted = nil
+		require.NoError(t, rp.applyChanges(context.Background(), templatesConfig))
+
+		require.Len(t, ruleStore.upserted, len(created))
+		for i, upsert := range ruleStore.upserted {
+			require.NotNil(t, upsert.Existing)
+			assert.Equal(t, created[i].New.UID, upsert.Existing.UID)
+		}
+	})
+
+	t.Run("Fails when the folder of a new rule doesn't exist", func(t *testing.T) {
+		rp, ruleStore := setup(t, "infra")
+
+		err := rp.applyChanges(context.Background(), templatesConfig)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `folder "services" not found`)
+		assert.Empty(t, ruleStore.upserted)
+	})
+
+	t.Run("Rules querying datasources that don't exist", func(t *testing.T) {
+		setupMissingDatasource := func(t *testing.T) (*RuleProvisioner, *fakeRuleStore) {
+			rp, ruleStore := setup(t, "infra", "services")
+			bus.AddHandler("test", func(query *models.GetDataSourceQuery) error {
+				return models.ErrDataSourceNotFound
+			})
+			return rp, ruleStore
+		}
+
+		t.Run("are applied with a warning by default", func(t *testing.T) {
+			rp, ruleStore := setupMissingDatasource(t)
+			require.NoError(t, rp.applyChanges(context.Background(), templatesConfig))
+			assert.Len(t, ruleStore.upserted, 5)
+		})
+
+		t.Run("fail all the rules in fail mode", func(t *testing.T) {
+			rp, ruleStore := setupMissingDatasource(t)
+			ctx := utils.WithReferenceCheck(context.Background(), utils.ReferenceCheckFail)
+
+			err := rp.applyChanges(ctx, templatesConfig)
+			require.True(t, errors.Is(err, utils.ErrDanglingReferences))
+			assert.Contains(t, err.Error(), `references datasource "prometheus" in query A`)
+			assert.Contains(t, err.Error(), "rules.yaml")
+			assert.Empty(t, ruleStore.upserted)
+		})
+	})
+}
+
+type fakeRuleStore struct {
+	rules    map[string]*ngmodels.AlertRule
+	upserted []store.UpsertRule
+}
+
+func (s *fakeRuleStore) GetAlertRuleByUID(query *ngmodels.GetAlertRuleByUIDQuery) error {
+	rule, ok := s.rules[query.UID]
+	if !ok {
+		return ngmodels.ErrAlertRuleNotFound
+	}
+	query.Result = rule
+	return nil
+}
+
+func (s *fakeRuleStore) UpsertAlertRules(rules []store.UpsertRule) error {
+	s.upserted = append(s.upserted, rules...)
+	for _, r := range rules {
+		rule := r.New
+		s.rules[rule.UID] = &rule
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package alerting
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/values"
+)
+
+// configVersion is used to figure out which API version a config uses.
+type configVersion struct {
+	APIVersion int64 `json:"apiVersion" yaml:"apiVersion"`
+}
+
+// rulesAsConfig is a normalized data object for alert rules config data. Any config version should be
+// mappable to this type. Rule templates are already expanded into the rules they generate.
+type rulesAsConfig struct {
+	Filename string
+	Rules    []*ruleFromConfig
+}
+
+type ruleFromConfig struct {
+	// Source describes where the rule comes from in error messages, e.g. the template and parameters
+	// that generated it.
+	Source string
+	Rule   ngmodels.AlertRule
+}
+
+type rulesAsConfigV1 struct {
+	configVersion `yaml:",inline"`
+
+	Rules     []*ruleFromConfigV1 `json:"rules" yaml:"rules"`
+	Templates []*ruleTemplateV1   `json:"templates" yaml:"templates"`
+}
+
+// ruleTemplateV1 generates one rule per combination of the values in the matrix. The rule is kept in its
+// raw form, since its strings are rendered with the parameters of each combination before being decoded.
+type ruleTemplateV1 struct {
+	UID    values.StringValue     `json:"uid" yaml:"uid"`
+	Matrix map[string][]string    `json:"matrix" yaml:"matrix"`
+	Rule   map[string]interface{} `json:"rule" yaml:"rule"`
+}
+
+type ruleFromConfigV1 struct {
+	UID             values.StringValue    `json:"uid" yaml:"uid"`
+	OrgID           values.Int64Value     `json:"orgId" yaml:"orgId"`
+	FolderUID       values.StringValue    `json:"folderUid" yaml:"folderUid"`
+	Group           values.StringValue    `json:"group" yaml:"group"`
+	Title           values.StringValue    `json:"title" yaml:"title"`
+	Condition       values.StringValue    `json:"condition" yaml:"condition"`
+	Data            []*alertQueryV1       `json:"data" yaml:"data"`
+	IntervalSeconds values.Int64Value     `json:"intervalSeconds" yaml:"intervalSeconds"`
+	For             values.StringValue    `json:"for" yaml:"for"`
+	NoDataState     values.StringValue    `json:"noDataState" yaml:"noDataState"`
+	ExecErrState    values.StringValue    `json:"execErrState" yaml:"execErrState"`
+	Labels          values.StringMapValue `json:"labels" yaml:"labels"`
+	Annotations     values.StringMapValue `json:"annotations" yaml:"annotations"`
+}
+
+type alertQueryV1 struct {
+	RefID             values.StringValue  `json:"refId" yaml:"refId"`
+	QueryType         values.StringValue  `json:"queryType" yaml:"queryType"`
+	DatasourceUID     values.StringValue  `json:"datasourceUid" yaml:"datasourceUid"`
+	RelativeTimeRange relativeTimeRangeV1 `json:"relativeTimeRange" yaml:"relativeTimeRange"`
+	Model             values.JSONValue    `json:"model" yaml:"model"`
+}
+
+// relativeTimeRangeV1 holds the start and end of a query in seconds before now.
+type relativeTimeRangeV1 struct {
+	From values.Int64Value `json:"from" yaml:"from"`
+	To   values.Int64Value `json:"to" yaml:"to"`
+}
+
+func (rule *ruleFromConfigV1) mapToAlertRule() (ngmodels.AlertRule, error) {
+	r := ngmodels.AlertRule{
+		UID:             rule.UID.Value(),
+		OrgID:           rule.OrgID.Value(),
+		NamespaceUID:    rule.FolderUID.Value(),
+		RuleGroup:       rule.Group.Value(),
+		Title:           rule.Title.Value(),
+		Condition:       rule.Condition.Value(),
+		IntervalSeconds: rule.IntervalSeconds.Value(),
+		NoDataState:     ngmodels.NoDataState(rule.NoDataState.Value()),
+		ExecErrState:    ngmodels.ExecutionErrorState(rule.ExecErrState.Value()),
+		Labels:          rule.Labels.Value(),
+		Annotations:     rule.Annotations.Value(),
+	}
+
+	if r.OrgID == 0 {
+		r.OrgID = 1
+	}
+
+	if rule.For.Value() != "" {
+		forDuration, err := time.ParseDuration(rule.For.Value())
+		if err != nil {
+			return r, fmt.Errorf("invalid for duration %q: %w", rule.For.Value(), err)
+		}
+		r.For = forDuration
+	}
+
+	for _, query := range rule.Data {
+		model, err := json.Marshal(query.Model.Value())
+		if err != nil {
+			return r, fmt.Errorf("invalid model of query %q: %w", query.RefID.Value(), err)
+		}
+
+		r.Data = append(r.Data, ngmodels.AlertQuery{
+			RefID:         query.RefID.Value(),
+			QueryType:     query.QueryType.Value(),
+			DatasourceUID: query.DatasourceUID.Value(),
+			RelativeTimeRange: ngmodels.RelativeTimeRange{
+				From: ngmodels.Duration(time.Duration(query.RelativeTimeRange.From.Value()) * time.Second),
+				To:   ngmodels.Duration(time.Duration(query.RelativeTimeRange.To.Value()) * time.Second),
+			},
+			Model: model,
+		})
+	}
+
+	return r, nil
+}
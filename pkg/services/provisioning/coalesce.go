@@ -0,0 +1,138 @@
+package provisioning
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/opentracing/opentracing-go"
+)
+
+// coalescer runs a provisioning function at most once at a time. A trigger that arrives while it's running queues
+// a single extra run that starts when the current one finishes, and triggers that arrive while a run is already
+// queued are coalesced into it. Every trigger returns the result of a run that started after it, so a reload
+// request never reports a run that missed its changes.
+type coalescer struct {
+	name    string
+	log     log.Logger
+	mutex   sync.Mutex
+	running bool
+	queued  *queuedRun
+}
+
+type queuedRun struct {
+	fn   func() error
+	done chan struct{}
+	err  error
+	// triggers is the number of triggers waiting for the run.
+	triggers int
+}
+
+func newCoalescer(name string, log log.Logger) *coalescer {
+	return &coalescer{name: name, log: log}
+}
+
+// run runs fn, or waits for the run queued after the current one when a run is in progress.
+func (c *coalescer) run(fn func() error) error {
+	c.mutex.Lock()
+	if !c.running {
+		c.running = true
+		c.mutex.Unlock()
+
+		err := fn()
+		c.next()
+		return err
+	}
+
+	if c.queued == nil {
+		c.log.Debug("Provisioning is already running, queueing another run", "provisioner", c.name)
+		c.queued = &queuedRun{fn: fn, done: make(chan struct{})}
+	} else {
+		c.log.Info("Provisioning run is already queued, coalescing trigger", "provisioner", c.name)
+	}
+	queued := c.queued
+	queued.triggers++
+	c.mutex.Unlock()
+
+	<-queued.done
+	return queued.err
+}
+
+// next starts the queued run if there is one, so the caller that triggered the finished run doesn't wait for it.
+func (c *coalescer) next() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	queued := c.queued
+	c.queued = nil
+	if queued == nil {
+		c.running = false
+		return
+	}
+
+	c.log.Debug("Running queued provisioning", "provisioner", c.name, "triggers", queued.triggers)
+	go func() {
+		queued.err = queued.fn()
+		close(queued.done)
+		c.next()
+	}()
+}
+
+// coalesce runs fn through the coalescer of the named provisioner.
+func (ps *provisioningServiceImpl) coalesce(name string, fn func() error) error {
+	ps.mutex.Lock()
+	if ps.coalescers == nil {
+		ps.coalescers = map[string]*coalescer{}
+	}
+	c, exists := ps.coalescers[name]
+	if !exists {
+		c = newCoalescer(name, ps.log)
+		ps.coalescers[name] = c
+	}
+	ps.mutex.Unlock()
+
+	return c.run(fn)
+}
+
+// runProvisioner coalesces the runs of a provisioner and traces each run in its own span, a child of the span in ctx
+// when there is one. The objects provision recorded in its inventory are counted on the span and passed to the
+// observers, along with dirs, the directories it reads. A nil inventory reports no objects. Otherwise, the objects
+// listed by GetProvisionedInventory are replaced by the inventory once provision returned, so the objects of a run
+// aren't listed while it's applying them.
+func (ps *provisioningServiceImpl) runProvisioner(ctx context.Context, name string, dirs []string,
+	provision func(ctx context.Context) (*utils.Inventory, error)) error {
+	return ps.runProvisionerWithResult(ctx, name, func(ctx context.Context) (ProvisionResult, error) {
+		run := ps.startRun(name)
+		inventory, err := provision(ctx)
+		if inventory != nil {
+			ps.setInventory(name, run, inventory)
+		}
+		return ProvisionResult{Objects: inventory.Objects(), Deleted: inventory.Deleted(), Directories: dirs}, err
+	})
+}
+
+// runProvisionerWithResult is runProvisioner for provisioners that report more than the applied objects. The
+// Duration of the result is set by the run, which calls the post-apply hooks when provision succeeded.
+func (ps *provisioningServiceImpl) runProvisionerWithResult(ctx context.Context, name string,
+	provision func(ctx context.Context) (ProvisionResult, error)) error {
+	return ps.coalesce(name, func() error {
+		span, ctx := opentracing.StartSpanFromContext(ps.withEnvironment(ctx), "provisioning "+name)
+		start := time.Now()
+		result, err := ps.provisionWithTimeout(ctx, name, provision)
+		result.Duration = time.Since(start)
+		if err == nil {
+			err = ps.runPostApplyHooks(ctx, name, result)
+		}
+		span.SetTag("objects", len(result.Objects))
+		utils.FinishSpan(span, err)
+
+		ps.notifyObservers(name, result, err)
+		ps.recordReport(name, start, result, err)
+		if err == nil {
+			ps.recordResult(name, result)
+		}
+		return err
+	})
+}
@@ -0,0 +1,126 @@
+package provisioning
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoalescer(t *testing.T) {
+	t.Run("Runs sequential triggers one after the other", func(t *testing.T) {
+		c := newCoalescer("test", log.New("test"))
+		var runs int32
+		for i := 0; i < 3; i++ {
+			require.NoError(t, c.run(func() error {
+				atomic.AddInt32(&runs, 1)
+				return nil
+			}))
+		}
+		assert.Equal(t, int32(3), atomic.LoadInt32(&runs))
+	})
+
+	t.Run("Coalesces triggers made during a run into a single extra run", func(t *testing.T) {
+		c := newCoalescer("test", log.New("test"))
+		started := make(chan struct{})
+		release := make(chan struct{})
+		var runs int32
+		errSecondRun := errors.New("second run")
+
+		firstDone := make(chan error)
+		go func() {
+			firstDone <- c.run(func() error {
+				atomic.AddInt32(&runs, 1)
+				close(started)
+				<-release
+				return nil
+			})
+		}()
+		<-started
+
+		const triggers = 10
+		results := make(chan error, triggers)
+		var wg sync.WaitGroup
+		for i := 0; i < triggers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results <- c.run(func() error {
+					atomic.AddInt32(&runs, 1)
+					return errSecondRun
+				})
+			}()
+		}
+		waitForQueued(t, c, triggers)
+
+		close(release)
+		require.NoError(t, <-firstDone)
+		wg.Wait()
+		close(results)
+
+		assert.Equal(t, int32(2), atomic.LoadInt32(&runs), "Triggers during a run should cause exactly one more run")
+		for err := range results {
+			assert.Equal(t, errSecondRun, err, "Coalesced triggers should get the result of the queued run")
+		}
+
+		require.NoError(t, c.run(func() error { return nil }), "The coalescer should be idle again")
+	})
+}
+
+func TestProvisioningServiceCoalescesReloads(t *testing.T) {
+	var running, maxRunning, runs int32
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	provisionOrgs := func() error {
+		atomic.AddInt32(&runs, 1)
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+				break
+			}
+		}
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		return nil
+	}
+	ps := &provisioningServiceImpl{log: log.New("test")}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, ps.coalesce("orgs", provisionOrgs))
+		}()
+		if i == 0 {
+			<-started
+		}
+	}
+	waitForQueued(t, ps.coalescers["orgs"], 4)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&maxRunning), "Runs should never overlap")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+}
+
+// waitForQueued waits until the given number of triggers wait for the queued run, since the triggers are made from
+// other goroutines.
+func waitForQueued(t *testing.T, c *coalescer, triggers int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		return c.queued != nil && c.queued.triggers == triggers
+	}, time.Second, time.Millisecond)
+}
@@ -1,6 +1,8 @@
 package dashboards
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -9,14 +11,33 @@ import (
 
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/provisioning/utils"
-	"gopkg.in/yaml.v2"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 type configReader struct {
-	path string
-	log  log.Logger
+	path       string
+	log        log.Logger
+	fileFilter setting.ProvisioningFileFilter
+	// offline skips the validation that needs a database, like checking that orgs exist.
+	offline bool
+	// strict rejects unknown fields instead of ignoring them.
+	strict bool
+	// env is what the guards of the files are checked against.
+	env utils.Environment
 }
 
+// deprecatedFields are the fields of provider config files that have a replacement, for both versions.
+var deprecatedFields = []utils.DeprecatedField{
+	{Path: "providers[].options.folder", Replacement: "providers[].options.path"},
+	{Path: "[].options.folder", Replacement: "[].options.path"},
+}
+
+// schemas read the provider config files by their apiVersion. Version 0 files are a list of providers.
+var schemas = utils.NewSchemaRegistry(
+	utils.Schema{APIVersion: 0, New: func() interface{} { return &[]*configV0{} }, Deprecated: deprecatedFields},
+	utils.Schema{APIVersion: 1, New: func() interface{} { return &configV1{} }, Deprecated: deprecatedFields},
+)
+
 func (cr *configReader) parseConfigs(file os.FileInfo) ([]*config, error) {
 	filename, _ := filepath.Abs(filepath.Join(cr.path, file.Name()))
 
@@ -27,45 +48,33 @@ func (cr *configReader) parseConfigs(file os.FileInfo) ([]*config, error) {
 		return nil, err
 	}
 
-	apiVersion := &configVersion{APIVersion: 0}
-
-	// We ignore the error here because it errors out for version 0 which does not have apiVersion
-	// specified (so 0 is default). This can also error in case the apiVersion is not an integer but at the moment
-	// this does not handle that case and would still go on as if version = 0.
-	// TODO: return appropriate error in case the apiVersion is specified but isn't integer (or even if it is
-	//  integer > max version?).
-	_ = yaml.Unmarshal(yamlFile, &apiVersion)
-
-	if apiVersion.APIVersion > 0 {
-		v1 := &configV1{}
-		err := yaml.Unmarshal(yamlFile, &v1)
-		if err != nil {
-			return nil, err
-		}
-
-		if v1 != nil {
-			return v1.mapToDashboardsAsConfig()
-		}
-	} else {
-		var v0 []*configV0
-		err := yaml.Unmarshal(yamlFile, &v0)
-		if err != nil {
-			return nil, err
+	decoder := utils.YAMLDecoder{Subsystem: "dashboards", Kind: "Dashboard", Strict: cr.strict, Environment: cr.env,
+		Log: cr.log}
+	cfg, _, err := schemas.Decode(decoder, filename, yamlFile)
+	if err != nil {
+		if errors.Is(err, utils.ErrFileSkipped) {
+			return nil, nil
 		}
+		return nil, err
+	}
 
-		if v0 != nil {
+	switch cfg := cfg.(type) {
+	case *configV1:
+		return cfg.mapToDashboardsAsConfig()
+	case *[]*configV0:
+		if *cfg != nil {
 			cr.log.Warn("[Deprecated] the dashboard provisioning config is outdated. please upgrade", "filename", filename)
-			return mapV0ToDashboardsAsConfig(v0)
+			return mapV0ToDashboardsAsConfig(*cfg)
 		}
 	}
 
 	return []*config{}, nil
 }
 
-func (cr *configReader) readConfig() ([]*config, error) {
+func (cr *configReader) readConfig(ctx context.Context) ([]*config, error) {
 	var dashboards []*config
 
-	files, err := ioutil.ReadDir(cr.path)
+	files, err := utils.ReadDir(cr.path)
 	if err != nil {
 		cr.log.Error("can't read dashboard provisioning files from directory", "path", cr.path, "error", err)
 		return dashboards, nil
@@ -76,9 +85,18 @@ func (cr *configReader) readConfig() ([]*config, error) {
 			continue
 		}
 
+		if !cr.fileFilter.Includes(file.Name()) {
+			cr.log.Debug("skipping excluded dashboard provisioning file", "path", cr.path, "file.Name", file.Name())
+			continue
+		}
+
 		parsedDashboards, err := cr.parseConfigs(file)
 		if err != nil {
-			return nil, fmt.Errorf("could not parse provisioning config file: %s error: %v", file.Name(), err)
+			return nil, fmt.Errorf("could not parse provisioning config file: %s error: %w", file.Name(), err)
+		}
+
+		if err := cr.applyPathOrg(parsedDashboards); err != nil {
+			return nil, fmt.Errorf("invalid provisioning config file: %s error: %w", file.Name(), err)
 		}
 
 		if len(parsedDashboards) > 0 {
@@ -86,23 +104,46 @@ func (cr *configReader) readConfig() ([]*config, error) {
 		}
 	}
 
-	uidUsage := map[string]uint8{}
+	if err := cr.validateConfigs(ctx, dashboards); err != nil {
+		return nil, err
+	}
+
+	return dashboards, nil
+}
+
+// applyPathOrg sets the org of the providers of a per-org directory like orgs/<orgID>/dashboards.
+func (cr *configReader) applyPathOrg(dashboards []*config) error {
+	pathOrgID := utils.OrgFromPath(cr.path)
 	for _, dashboard := range dashboards {
-		if dashboard.OrgID == 0 {
-			dashboard.OrgID = 1
+		if err := utils.ApplyPathOrg(&dashboard.OrgID, pathOrgID); err != nil {
+			return fmt.Errorf("dashboard provider %q: %w", dashboard.Name, err)
 		}
+	}
+	return nil
+}
 
-		if err := utils.CheckOrgExists(dashboard.OrgID); err != nil {
-			return nil, fmt.Errorf("failed to provision dashboards with %q reader: %w", dashboard.Name, err)
+// validateConfigs validates the provider configs read from the directory, resolving their org and defaulting their
+// type. Offline, org names aren't resolved and providers without an orgId are in org 1.
+func (cr *configReader) validateConfigs(ctx context.Context, dashboards []*config) error {
+	uidUsage := map[string]uint8{}
+	for _, dashboard := range dashboards {
+		if cr.offline {
+			if dashboard.OrgID == 0 {
+				dashboard.OrgID = 1
+			}
+		} else {
+			if err := utils.ResolveOrg(ctx, &dashboard.OrgID, dashboard.OrgName); err != nil {
+				return fmt.Errorf("failed to provision dashboards with %q reader: %w", dashboard.Name, err)
+			}
+			if err := utils.CheckOrgExists(ctx, dashboard.OrgID); err != nil {
+				return fmt.Errorf("failed to provision dashboards with %q reader: %w", dashboard.Name, err)
+			}
 		}
 
 		if dashboard.Type == "" {
 			dashboard.Type = "file"
 		}
 
-		if dashboard.UpdateIntervalSeconds == 0 {
-			dashboard.UpdateIntervalSeconds = 10
-		}
 		if len(dashboard.FolderUID) > 0 {
 			uidUsage[dashboard.FolderUID]++
 		}
@@ -114,5 +155,47 @@ func (cr *configReader) readConfig() ([]*config, error) {
 		}
 	}
 
-	return dashboards, nil
+	return nil
+}
+
+// readLayeredConfigs reads the provider configs of each directory in order. Providers of later directories replace
+// the ones of earlier directories with the same name. Providers of per-org directories can only replace providers
+// of the same org.
+func readLayeredConfigs(ctx context.Context, paths []string, log log.Logger, fileFilter setting.ProvisioningFileFilter, strict bool) ([]*config, error) {
+	var configs []*config
+	configPaths := map[string]string{}
+	earlier := map[string]int{}
+
+	for _, path := range paths {
+		cfgReader := &configReader{path: path, log: log, fileFilter: fileFilter, strict: strict,
+			env: utils.EnvironmentFromContext(ctx)}
+		read, err := cfgReader.readConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cfg := range read {
+			if i, ok := earlier[cfg.Name]; ok {
+				// Provisioned dashboards are tracked by provider name, so providers of different orgs can't share one.
+				fromOrgDirectory := utils.OrgFromPath(path) != 0 || utils.OrgFromPath(configPaths[cfg.Name]) != 0
+				if fromOrgDirectory && configs[i].OrgID != cfg.OrgID {
+					return nil, fmt.Errorf("dashboard provider %q of org %d in %s has the same name as the provider of org %d in %s",
+						cfg.Name, cfg.OrgID, path, configs[i].OrgID, configPaths[cfg.Name])
+				}
+				log.Info("dashboard provider overridden by a later provisioning path", "name", cfg.Name,
+					"overridden", configPaths[cfg.Name], "winner", path)
+				configs[i] = cfg
+				delete(earlier, cfg.Name)
+			} else {
+				configs = append(configs, cfg)
+			}
+			configPaths[cfg.Name] = path
+		}
+
+		for i, cfg := range configs {
+			earlier[cfg.Name] = i
+		}
+	}
+
+	return configs, nil
 }
@@ -1,6 +1,7 @@
 package dashboards
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -11,7 +12,9 @@ import (
 
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 var (
@@ -19,6 +22,13 @@ var (
 	oldVersion            = "./testdata/test-configs/version-0"
 	brokenConfigs         = "./testdata/test-configs/broken-configs"
 	appliedDefaults       = "./testdata/test-configs/applied-defaults"
+	overlayConfig         = "./testdata/test-configs/overlay"
+	folderMappingConfig   = "./testdata/test-configs/folder-mapping"
+
+	orgScopedConfig         = "./testdata/test-configs/org-scoped"
+	orgScopedConflictConfig = "./testdata/test-configs/org-scoped-conflict"
+	orgNamesConfig          = "./testdata/test-configs/org-names"
+	orgNameConflictConfig   = "./testdata/test-configs/org-name-conflict"
 )
 
 func TestDashboardsAsConfig(t *testing.T) {
@@ -28,7 +38,7 @@ func TestDashboardsAsConfig(t *testing.T) {
 
 		t.Run("Should fail if orgs don't exist in the database", func(t *testing.T) {
 			cfgProvider := configReader{path: appliedDefaults, log: logger}
-			_, err := cfgProvider.readConfig()
+			_, err := cfgProvider.readConfig(context.Background())
 			require.Error(t, err)
 			assert.True(t, errors.Is(err, models.ErrOrgNotFound))
 		})
@@ -41,35 +51,112 @@ func TestDashboardsAsConfig(t *testing.T) {
 
 		t.Run("default values should be applied", func(t *testing.T) {
 			cfgProvider := configReader{path: appliedDefaults, log: logger}
-			cfg, err := cfgProvider.readConfig()
+			cfg, err := cfgProvider.readConfig(context.Background())
 			require.NoError(t, err)
 
 			require.Equal(t, "file", cfg[0].Type)
 			require.Equal(t, int64(1), cfg[0].OrgID)
-			require.Equal(t, int64(10), cfg[0].UpdateIntervalSeconds)
+			// Providers without an update interval use the dashboards_poll_interval setting.
+			require.Equal(t, int64(0), cfg[0].UpdateIntervalSeconds)
 		})
 
 		t.Run("Can read config file version 1 format", func(t *testing.T) {
 			_ = os.Setenv("TEST_VAR", "general")
 			cfgProvider := configReader{path: simpleDashboardConfig, log: logger}
-			cfg, err := cfgProvider.readConfig()
+			cfg, err := cfgProvider.readConfig(context.Background())
 			_ = os.Unsetenv("TEST_VAR")
 			require.NoError(t, err)
 
 			validateDashboardAsConfig(t, cfg)
+			assert.False(t, cfg[0].Lazy)
+			assert.True(t, cfg[1].Lazy)
 		})
 
 		t.Run("Can read config file in version 0 format", func(t *testing.T) {
 			cfgProvider := configReader{path: oldVersion, log: logger}
-			cfg, err := cfgProvider.readConfig()
+			cfg, err := cfgProvider.readConfig(context.Background())
 			require.NoError(t, err)
 
 			validateDashboardAsConfig(t, cfg)
 		})
 
+		t.Run("Later provisioning paths override providers by name", func(t *testing.T) {
+			_ = os.Setenv("TEST_VAR", "general")
+			cfg, err := readLayeredConfigs(context.Background(), []string{simpleDashboardConfig, overlayConfig}, logger, setting.ProvisioningFileFilter{}, false)
+			_ = os.Unsetenv("TEST_VAR")
+			require.NoError(t, err)
+
+			require.Len(t, cfg, 3)
+			assert.Equal(t, "general dashboards", cfg[0].Name)
+			assert.Equal(t, "default", cfg[1].Name)
+			assert.Equal(t, "/var/lib/grafana/team-dashboards", cfg[1].Options["path"])
+			assert.Equal(t, "team", cfg[2].Name)
+		})
+
+		t.Run("Providers of per-org directories get the org of their directory", func(t *testing.T) {
+			dirs := utils.OrgDirectories(orgScopedConfig, "dashboards")
+			require.Len(t, dirs, 2)
+
+			cfg, err := readLayeredConfigs(context.Background(), dirs, logger, setting.ProvisioningFileFilter{}, false)
+			require.NoError(t, err)
+			require.Len(t, cfg, 2)
+			assert.Equal(t, "team-a", cfg[0].Name)
+			assert.Equal(t, int64(1), cfg[0].OrgID)
+			assert.Equal(t, "team-b", cfg[1].Name)
+			assert.Equal(t, int64(2), cfg[1].OrgID)
+		})
+
+		t.Run("Providers of different orgs can't share a name", func(t *testing.T) {
+			dirs := append(utils.OrgDirectories(orgScopedConfig, "dashboards"),
+				utils.OrgDirectories(orgScopedConflictConfig, "dashboards")...)
+			_, err := readLayeredConfigs(context.Background(), dirs, logger, setting.ProvisioningFileFilter{}, false)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "dashboard provider \"team-a\" of org 2")
+		})
+
+		t.Run("Providers get the org of their orgName or the default org", func(t *testing.T) {
+			cfgProvider := configReader{path: orgNamesConfig, log: logger}
+			cfg, err := cfgProvider.readConfig(context.Background())
+			require.NoError(t, err)
+			require.Len(t, cfg, 2)
+			assert.Equal(t, int64(2), cfg[0].OrgID)
+			assert.Equal(t, int64(1), cfg[1].OrgID)
+
+			cfg, err = cfgProvider.readConfig(utils.WithDefaultOrg(context.Background(), "Main Org. 2"))
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), cfg[1].OrgID)
+		})
+
+		t.Run("Providers whose orgName is another org than their orgId fail", func(t *testing.T) {
+			cfgProvider := configReader{path: orgNameConflictConfig, log: logger}
+			_, err := cfgProvider.readConfig(context.Background())
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, utils.ErrOrgNameConflict))
+		})
+
+		t.Run("A default org that doesn't exist fails", func(t *testing.T) {
+			cfgProvider := configReader{path: appliedDefaults, log: logger}
+			_, err := cfgProvider.readConfig(utils.WithDefaultOrg(context.Background(), "Production"))
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, models.ErrOrgNotFound))
+		})
+
+		t.Run("Can read folderMapping rules of providers", func(t *testing.T) {
+			cfgProvider := configReader{path: folderMappingConfig, log: logger}
+			cfg, err := cfgProvider.readConfig(context.Background())
+			require.NoError(t, err)
+
+			require.Len(t, cfg, 1)
+			assert.Equal(t, "Shared", cfg[0].Folder)
+			assert.Equal(t, []folderMapping{
+				{Pattern: "team-a-*.json", Folder: "Team A", FolderUID: "team-a"},
+				{Pattern: "legacy/*.json", Folder: "Legacy"},
+			}, cfg[0].FolderMapping)
+		})
+
 		t.Run("Should skip invalid path", func(t *testing.T) {
 			cfgProvider := configReader{path: "/invalid-directory", log: logger}
-			cfg, err := cfgProvider.readConfig()
+			cfg, err := cfgProvider.readConfig(context.Background())
 			if err != nil {
 				t.Fatalf("readConfig return an error %v", err)
 			}
@@ -79,7 +166,7 @@ func TestDashboardsAsConfig(t *testing.T) {
 
 		t.Run("Should skip broken config files", func(t *testing.T) {
 			cfgProvider := configReader{path: brokenConfigs, log: logger}
-			cfg, err := cfgProvider.readConfig()
+			cfg, err := cfgProvider.readConfig(context.Background())
 			if err != nil {
 				t.Fatalf("readConfig return an error %v", err)
 			}
@@ -116,5 +203,5 @@ func validateDashboardAsConfig(t *testing.T, cfg []*config) {
 	require.Equal(t, len(ds2.Options), 1)
 	require.Equal(t, ds2.Options["path"], "/var/lib/grafana/dashboards")
 	require.False(t, ds2.DisableDeletion)
-	require.Equal(t, ds2.UpdateIntervalSeconds, int64(10))
+	require.Equal(t, ds2.UpdateIntervalSeconds, int64(0))
 }
@@ -2,54 +2,74 @@ package dashboards
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/dashboards"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util/errutil"
 )
 
 // DashboardProvisioner is responsible for syncing dashboard from disk to
 // Grafana's database.
 type DashboardProvisioner interface {
-	Provision() error
+	Provision(ctx context.Context) error
+	ProvisionProvider(ctx context.Context, name string) error
 	PollChanges(ctx context.Context)
-	GetProvisionerResolvedPath(name string) string
+	GetProvisionerRootPath(name string) string
 	GetAllowUIUpdatesFromConfig(name string) bool
-	CleanUpOrphanedDashboards()
+	GetAllowUIUpdatesMap() map[string]bool
+	GetProvisioningInfo(dashboardUID string) (*ProvisioningInfo, bool)
+	GetProvisionedDashboards() []utils.ProvisionedObject
+	GetDeletedDashboards() []utils.ProvisionedObject
+	CleanUpOrphanedDashboards(ctx context.Context)
+	PollingStalled(threshold time.Duration) bool
+	PollingFailed() error
+	ResumedFiles() int
+	SetDriftHandler(handler DriftHandler)
+	Diff(ctx context.Context) (*utils.Diff, error)
+	ProvisionDeferredDashboard(ctx context.Context, orgID int64, uid string) (bool, error)
 }
 
+// ErrProviderNotFound is returned when there is no dashboard provider with the requested name.
+var ErrProviderNotFound = errors.New("dashboard provider not found")
+
 // DashboardProvisionerFactory creates DashboardProvisioners based on input
-type DashboardProvisionerFactory func(string, dashboards.Store) (DashboardProvisioner, error)
+type DashboardProvisionerFactory func(context.Context, []string, dashboards.Store, *setting.Cfg) (DashboardProvisioner, error)
 
 // Provisioner is responsible for syncing dashboard from disk to Grafana's database.
 type Provisioner struct {
-	log         log.Logger
-	fileReaders []*FileReader
-	configs     []*config
+	log          log.Logger
+	fileReaders  []*FileReader
+	configs      []*config
+	cleanupGuard setting.ProvisioningCleanupGuard
 }
 
-// New returns a new DashboardProvisioner
-func New(configDirectory string, store dashboards.Store) (DashboardProvisioner, error) {
+// New returns a new DashboardProvisioner for the providers configured in the directories, merged in order.
+func New(ctx context.Context, configDirectories []string, store dashboards.Store, settings *setting.Cfg) (DashboardProvisioner, error) {
 	logger := log.New("provisioning.dashboard")
-	cfgReader := &configReader{path: configDirectory, log: logger}
-	configs, err := cfgReader.readConfig()
+	configs, err := readLayeredConfigs(ctx, configDirectories, logger, settings.ProvisioningFileFilters["dashboards"],
+		settings.ProvisioningStrictFields["dashboards"])
 	if err != nil {
 		return nil, errutil.Wrap("Failed to read dashboards config", err)
 	}
 
-	fileReaders, err := getFileReaders(configs, logger, store)
+	fileReaders, err := getFileReaders(configs, logger, store, settings, parseCachesFromContext(ctx))
 	if err != nil {
 		return nil, errutil.Wrap("Failed to initialize file readers", err)
 	}
 
 	d := &Provisioner{
-		log:         logger,
-		fileReaders: fileReaders,
-		configs:     configs,
+		log:          logger,
+		fileReaders:  fileReaders,
+		configs:      configs,
+		cleanupGuard: settings.ProvisioningCleanupGuard,
 	}
 
 	return d, nil
@@ -57,9 +77,9 @@ func New(configDirectory string, store dashboards.Store) (DashboardProvisioner,
 
 // Provision scans the disk for dashboards and updates
 // the database with the latest versions of those dashboards.
-func (provider *Provisioner) Provision() error {
+func (provider *Provisioner) Provision(ctx context.Context) error {
 	for _, reader := range provider.fileReaders {
-		if err := reader.walkDisk(); err != nil {
+		if err := reader.walkDisk(ctx); err != nil {
 			if os.IsNotExist(err) {
 				// don't stop the provisioning service in case the folder is missing. The folder can appear after the startup
 				provider.log.Warn("Failed to provision config", "name", reader.Cfg.Name, "error", err)
@@ -73,16 +93,67 @@ func (provider *Provisioner) Provision() error {
 	return nil
 }
 
-// CleanUpOrphanedDashboards deletes provisioned dashboards missing a linked reader.
-func (provider *Provisioner) CleanUpOrphanedDashboards() {
+// ProvisionProvider scans the disk for the dashboards of the named provider only and updates the database, which
+// also removes its dashboards that are gone from disk. ErrProviderNotFound is returned, wrapped, for an unknown
+// provider name.
+func (provider *Provisioner) ProvisionProvider(ctx context.Context, name string) error {
+	for _, reader := range provider.fileReaders {
+		if reader.Cfg.Name != name {
+			continue
+		}
+
+		if err := reader.walkDisk(ctx); err != nil {
+			if os.IsNotExist(err) {
+				provider.log.Warn("Failed to provision config", "name", name, "error", err)
+				return nil
+			}
+
+			return errutil.Wrapf(err, "Failed to provision config %v", name)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%w: %q", ErrProviderNotFound, name)
+}
+
+// SetDriftHandler sets the handler called before provisioning overwrites manual changes of a dashboard. It must be
+// set before the dashboards are provisioned.
+func (provider *Provisioner) SetDriftHandler(handler DriftHandler) {
+	for _, reader := range provider.fileReaders {
+		reader.onDrift = handler
+	}
+}
+
+// CleanUpOrphanedDashboards deletes provisioned dashboards missing a linked reader. The cleanup guard is checked per
+// org, and nothing is deleted in an org when that's more of its provisioned dashboards than the guard allows.
+func (provider *Provisioner) CleanUpOrphanedDashboards(ctx context.Context) {
 	currentReaders := make([]string, len(provider.fileReaders))
 
 	for index, reader := range provider.fileReaders {
 		currentReaders[index] = reader.Cfg.Name
 	}
 
-	if err := bus.Dispatch(&models.DeleteOrphanedProvisionedDashboardsCommand{ReaderNames: currentReaders}); err != nil {
-		provider.log.Warn("Failed to delete orphaned provisioned dashboards", "err", err)
+	count := &models.CountOrphanedProvisionedDashboardsQuery{ReaderNames: currentReaders}
+	if err := bus.DispatchCtx(ctx, count); err != nil {
+		provider.log.Warn("Failed to count orphaned provisioned dashboards", "err", err)
+		return
+	}
+	for _, orgCount := range count.Result {
+		if orgCount.Orphaned == 0 {
+			continue
+		}
+		if !provider.cleanupGuard.Allows(int(orgCount.Orphaned), int(orgCount.Provisioned)) {
+			provider.log.Error("Not deleting orphaned provisioned dashboards, the cleanup would delete more of them "+
+				"than allowed. Check that the provisioning directories are complete, or set orphan_cleanup_force to "+
+				"delete them", "orgId", orgCount.OrgId, "orphaned", orgCount.Orphaned,
+				"provisioned", orgCount.Provisioned, "readers", currentReaders)
+			continue
+		}
+
+		cmd := &models.DeleteOrphanedProvisionedDashboardsCommand{ReaderNames: currentReaders, OrgId: orgCount.OrgId}
+		if err := bus.DispatchCtx(ctx, cmd); err != nil {
+			provider.log.Warn("Failed to delete orphaned provisioned dashboards", "orgId", orgCount.OrgId, "err", err)
+		}
 	}
 }
 
@@ -94,29 +165,127 @@ func (provider *Provisioner) PollChanges(ctx context.Context) {
 	}
 }
 
-// GetProvisionerResolvedPath returns resolved path for the specified provisioner name. Can be used to generate
-// relative path to provisioning file from it's external_id.
-func (provider *Provisioner) GetProvisionerResolvedPath(name string) string {
+// PollingStalled returns true if any provider hasn't finished a polling cycle within its update interval plus
+// threshold, which means its polling loop hangs.
+func (provider *Provisioner) PollingStalled(threshold time.Duration) bool {
+	for _, reader := range provider.fileReaders {
+		if reader.pollingStalled(threshold) {
+			return true
+		}
+	}
+	return false
+}
+
+// PollingFailed returns the error of the first provider whose polling failed more cycles in a row than the
+// poll failure threshold, or nil when polling works.
+func (provider *Provisioner) PollingFailed() error {
+	for _, reader := range provider.fileReaders {
+		if err := reader.pollingFailed(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResumedFiles returns the number of dashboard files the last provisioning of the providers skipped, because a
+// failed provisioning before it already applied them and they haven't changed since.
+func (provider *Provisioner) ResumedFiles() int {
+	resumed := 0
+	for _, reader := range provider.fileReaders {
+		resumed += reader.ResumedFiles()
+	}
+	return resumed
+}
+
+// GetProvisionerRootPath returns the absolute path of the specified provisioner name, without resolving symlinks,
+// since the dashboards are tracked by their path under it. Can be used to generate relative path to provisioning
+// file from it's external_id.
+func (provider *Provisioner) GetProvisionerRootPath(name string) string {
 	for _, reader := range provider.fileReaders {
 		if reader.Cfg.Name == name {
-			return reader.resolvedPath()
+			return reader.rootPath()
 		}
 	}
 	return ""
 }
 
-// GetAllowUIUpdatesFromConfig return if a dashboard provisioner allows updates from the UI
+// ProvisioningInfo tells which provider and file a provisioned dashboard comes from, and whether it can be
+// changed in the UI.
+type ProvisioningInfo struct {
+	Provider string `json:"provider"`
+	// File is the absolute path of the file the dashboard was provisioned from.
+	File           string `json:"file"`
+	AllowUIUpdates bool   `json:"allowUiUpdates"`
+	// AppliedAt is when provisioning last saved the dashboard.
+	AppliedAt time.Time `json:"appliedAt"`
+}
+
+// GetAllowUIUpdatesFromConfig return if a dashboard provisioner allows updates from the UI, like the AllowUIUpdates
+// that GetProvisioningInfo returns for its dashboards. It returns false for a name no provisioner has.
 func (provider *Provisioner) GetAllowUIUpdatesFromConfig(name string) bool {
-	for _, config := range provider.configs {
-		if config.Name == name {
-			return config.AllowUIUpdates
+	info, ok := provider.providerInfo(name)
+	return ok && info.AllowUIUpdates
+}
+
+// GetProvisioningInfo returns where the dashboard with the uid was provisioned from by the last walk of the disk of
+// its provider, and false when no provider provisioned it.
+func (provider *Provisioner) GetProvisioningInfo(dashboardUID string) (*ProvisioningInfo, bool) {
+	for _, reader := range provider.fileReaders {
+		object, ok := reader.provisionedDashboard(dashboardUID)
+		if !ok {
+			continue
 		}
+		info, _ := provider.providerInfo(reader.Cfg.Name)
+		info.File = object.File
+		info.AppliedAt = object.AppliedAt
+		return info, true
 	}
-	return false
+	return nil, false
+}
+
+// providerInfo returns the provisioning info the dashboards of the named provider share, and false when no provider
+// has the name.
+func (provider *Provisioner) providerInfo(name string) (*ProvisioningInfo, bool) {
+	for _, reader := range provider.fileReaders {
+		if reader.Cfg.Name == name {
+			return &ProvisioningInfo{Provider: name, AllowUIUpdates: reader.Cfg.AllowUIUpdates}, true
+		}
+	}
+	return nil, false
+}
+
+// GetAllowUIUpdatesMap returns whether each dashboard provisioner allows updates from the UI, by provisioner name
+func (provider *Provisioner) GetAllowUIUpdatesMap() map[string]bool {
+	allowUIUpdates := make(map[string]bool, len(provider.configs))
+	for _, config := range provider.configs {
+		allowUIUpdates[config.Name] = config.AllowUIUpdates
+	}
+	return allowUIUpdates
+}
+
+// GetProvisionedDashboards returns the dashboards every provider provisioned from the files it found on disk.
+func (provider *Provisioner) GetProvisionedDashboards() []utils.ProvisionedObject {
+	var objects []utils.ProvisionedObject
+	for _, reader := range provider.fileReaders {
+		objects = append(objects, reader.ProvisionedDashboards()...)
+	}
+	return objects
+}
+
+// GetDeletedDashboards returns the dashboards the last walk of the disk of every provider deleted, since their files
+// were gone.
+func (provider *Provisioner) GetDeletedDashboards() []utils.ProvisionedObject {
+	var objects []utils.ProvisionedObject
+	for _, reader := range provider.fileReaders {
+		objects = append(objects, reader.DeletedDashboards()...)
+	}
+	return objects
 }
 
-func getFileReaders(configs []*config, logger log.Logger, store dashboards.Store) ([]*FileReader, error) {
+func getFileReaders(configs []*config, logger log.Logger, store dashboards.Store, settings *setting.Cfg,
+	parseCaches *ParseCaches) ([]*FileReader, error) {
 	var readers []*FileReader
+	retainResumeStates(configs)
 
 	for _, config := range configs {
 		switch config.Type {
@@ -126,11 +295,23 @@ func getFileReaders(configs []*config, logger log.Logger, store dashboards.Store
 			if err != nil {
 				return nil, errutil.Wrapf(err, "Failed to create file reader for config %v", config.Name)
 			}
+			fileReader.Locale = settings.ProvisioningLocale
+			fileReader.MaxConcurrency = settings.ProvisioningDashboardsMaxConcurrency
+			fileReader.PollInterval = settings.ProvisioningDashboardsPoll.Interval
+			fileReader.PollJitter = settings.ProvisioningDashboardsPoll.Jitter
+			fileReader.PollFailureThreshold = settings.ProvisioningPollFailureThreshold
+			fileReader.IncrementalPolling = settings.ProvisioningDashboardsPoll.Incremental
+			fileReader.PollWindow = settings.ProvisioningDashboardsPoll.Window
+			fileReader.CleanupGuard = settings.ProvisioningCleanupGuard
+			fileReader.ReferenceCheck = utils.ReferenceCheckMode(settings.ProvisioningDanglingReferences)
+			fileReader.parseCache = parseCaches.get(fileReader)
+			fileReader.resume = providerResumeState(config, settings.ProvisioningLocale)
 			readers = append(readers, fileReader)
 		default:
 			return nil, fmt.Errorf("type %s is not supported", config.Type)
 		}
 	}
 
+	parseCaches.retain(readers)
 	return readers, nil
 }
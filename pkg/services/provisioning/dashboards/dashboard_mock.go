@@ -1,22 +1,49 @@
 package dashboards
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+)
 
 // Calls is a mock implementation of the provisioner interface
 type calls struct {
 	Provision                   []interface{}
+	ProvisionProvider           []interface{}
 	PollChanges                 []interface{}
-	GetProvisionerResolvedPath  []interface{}
+	GetProvisionerRootPath      []interface{}
 	GetAllowUIUpdatesFromConfig []interface{}
+	GetAllowUIUpdatesMap        []interface{}
+	GetProvisioningInfo         []interface{}
+	GetProvisionedDashboards    []interface{}
+	GetDeletedDashboards        []interface{}
+	PollingStalled              []interface{}
+	PollingFailed               []interface{}
+	SetDriftHandler             []interface{}
+	ResumedFiles                []interface{}
+	Diff                        []interface{}
+	ProvisionDeferredDashboard  []interface{}
 }
 
 // ProvisionerMock is a mock implementation of `Provisioner`
 type ProvisionerMock struct {
 	Calls                           *calls
-	ProvisionFunc                   func() error
+	ProvisionFunc                   func(ctx context.Context) error
+	ProvisionProviderFunc           func(ctx context.Context, name string) error
 	PollChangesFunc                 func(ctx context.Context)
-	GetProvisionerResolvedPathFunc  func(name string) string
+	GetProvisionerRootPathFunc      func(name string) string
 	GetAllowUIUpdatesFromConfigFunc func(name string) bool
+	GetAllowUIUpdatesMapFunc        func() map[string]bool
+	GetProvisioningInfoFunc         func(dashboardUID string) (*ProvisioningInfo, bool)
+	GetProvisionedDashboardsFunc    func() []utils.ProvisionedObject
+	GetDeletedDashboardsFunc        func() []utils.ProvisionedObject
+	PollingStalledFunc              func(threshold time.Duration) bool
+	PollingFailedFunc               func() error
+	SetDriftHandlerFunc             func(handler DriftHandler)
+	ResumedFilesFunc                func() int
+	DiffFunc                        func(ctx context.Context) (*utils.Diff, error)
+	ProvisionDeferredDashboardFunc  func(ctx context.Context, orgID int64, uid string) (bool, error)
 }
 
 // NewDashboardProvisionerMock returns a new dashboardprovisionermock
@@ -27,10 +54,19 @@ func NewDashboardProvisionerMock() *ProvisionerMock {
 }
 
 // Provision is a mock implementation of `Provisioner.Provision`
-func (dpm *ProvisionerMock) Provision() error {
+func (dpm *ProvisionerMock) Provision(ctx context.Context) error {
 	dpm.Calls.Provision = append(dpm.Calls.Provision, nil)
 	if dpm.ProvisionFunc != nil {
-		return dpm.ProvisionFunc()
+		return dpm.ProvisionFunc(ctx)
+	}
+	return nil
+}
+
+// ProvisionProvider is a mock implementation of `Provisioner.ProvisionProvider`
+func (dpm *ProvisionerMock) ProvisionProvider(ctx context.Context, name string) error {
+	dpm.Calls.ProvisionProvider = append(dpm.Calls.ProvisionProvider, name)
+	if dpm.ProvisionProviderFunc != nil {
+		return dpm.ProvisionProviderFunc(ctx, name)
 	}
 	return nil
 }
@@ -43,11 +79,11 @@ func (dpm *ProvisionerMock) PollChanges(ctx context.Context) {
 	}
 }
 
-// GetProvisionerResolvedPath is a mock implementation of `Provisioner.GetProvisionerResolvedPath`
-func (dpm *ProvisionerMock) GetProvisionerResolvedPath(name string) string {
-	dpm.Calls.GetProvisionerResolvedPath = append(dpm.Calls.GetProvisionerResolvedPath, name)
-	if dpm.GetProvisionerResolvedPathFunc != nil {
-		return dpm.GetProvisionerResolvedPathFunc(name)
+// GetProvisionerRootPath is a mock implementation of `Provisioner.GetProvisionerRootPath`
+func (dpm *ProvisionerMock) GetProvisionerRootPath(name string) string {
+	dpm.Calls.GetProvisionerRootPath = append(dpm.Calls.GetProvisionerRootPath, name)
+	if dpm.GetProvisionerRootPathFunc != nil {
+		return dpm.GetProvisionerRootPathFunc(name)
 	}
 	return ""
 }
@@ -61,5 +97,94 @@ func (dpm *ProvisionerMock) GetAllowUIUpdatesFromConfig(name string) bool {
 	return false
 }
 
+// GetAllowUIUpdatesMap is a mock implementation of `Provisioner.GetAllowUIUpdatesMap`
+func (dpm *ProvisionerMock) GetAllowUIUpdatesMap() map[string]bool {
+	dpm.Calls.GetAllowUIUpdatesMap = append(dpm.Calls.GetAllowUIUpdatesMap, nil)
+	if dpm.GetAllowUIUpdatesMapFunc != nil {
+		return dpm.GetAllowUIUpdatesMapFunc()
+	}
+	return map[string]bool{}
+}
+
+// GetProvisioningInfo is a mock implementation of `Provisioner.GetProvisioningInfo`
+func (dpm *ProvisionerMock) GetProvisioningInfo(dashboardUID string) (*ProvisioningInfo, bool) {
+	dpm.Calls.GetProvisioningInfo = append(dpm.Calls.GetProvisioningInfo, dashboardUID)
+	if dpm.GetProvisioningInfoFunc != nil {
+		return dpm.GetProvisioningInfoFunc(dashboardUID)
+	}
+	return nil, false
+}
+
+// GetProvisionedDashboards is a mock implementation of `Provisioner.GetProvisionedDashboards`
+func (dpm *ProvisionerMock) GetProvisionedDashboards() []utils.ProvisionedObject {
+	dpm.Calls.GetProvisionedDashboards = append(dpm.Calls.GetProvisionedDashboards, nil)
+	if dpm.GetProvisionedDashboardsFunc != nil {
+		return dpm.GetProvisionedDashboardsFunc()
+	}
+	return nil
+}
+
+// GetDeletedDashboards is a mock implementation of `Provisioner.GetDeletedDashboards`
+func (dpm *ProvisionerMock) GetDeletedDashboards() []utils.ProvisionedObject {
+	dpm.Calls.GetDeletedDashboards = append(dpm.Calls.GetDeletedDashboards, nil)
+	if dpm.GetDeletedDashboardsFunc != nil {
+		return dpm.GetDeletedDashboardsFunc()
+	}
+	return nil
+}
+
 // CleanUpOrphanedDashboards not implemented for mocks
-func (dpm *ProvisionerMock) CleanUpOrphanedDashboards() {}
+func (dpm *ProvisionerMock) CleanUpOrphanedDashboards(ctx context.Context) {}
+
+// PollingStalled is a mock implementation of `Provisioner.PollingStalled`
+func (dpm *ProvisionerMock) PollingStalled(threshold time.Duration) bool {
+	dpm.Calls.PollingStalled = append(dpm.Calls.PollingStalled, threshold)
+	if dpm.PollingStalledFunc != nil {
+		return dpm.PollingStalledFunc(threshold)
+	}
+	return false
+}
+
+// PollingFailed is a mock implementation of `Provisioner.PollingFailed`
+func (dpm *ProvisionerMock) PollingFailed() error {
+	dpm.Calls.PollingFailed = append(dpm.Calls.PollingFailed, nil)
+	if dpm.PollingFailedFunc != nil {
+		return dpm.PollingFailedFunc()
+	}
+	return nil
+}
+
+// SetDriftHandler is a mock implementation of `Provisioner.SetDriftHandler`
+func (dpm *ProvisionerMock) SetDriftHandler(handler DriftHandler) {
+	dpm.Calls.SetDriftHandler = append(dpm.Calls.SetDriftHandler, handler)
+	if dpm.SetDriftHandlerFunc != nil {
+		dpm.SetDriftHandlerFunc(handler)
+	}
+}
+
+// ResumedFiles is a mock implementation of `Provisioner.ResumedFiles`
+func (dpm *ProvisionerMock) ResumedFiles() int {
+	dpm.Calls.ResumedFiles = append(dpm.Calls.ResumedFiles, nil)
+	if dpm.ResumedFilesFunc != nil {
+		return dpm.ResumedFilesFunc()
+	}
+	return 0
+}
+
+// Diff is a mock implementation of `Provisioner.Diff`
+func (dpm *ProvisionerMock) Diff(ctx context.Context) (*utils.Diff, error) {
+	dpm.Calls.Diff = append(dpm.Calls.Diff, nil)
+	if dpm.DiffFunc != nil {
+		return dpm.DiffFunc(ctx)
+	}
+	return utils.NewDiff("dashboards"), nil
+}
+
+// ProvisionDeferredDashboard is a mock implementation of `Provisioner.ProvisionDeferredDashboard`
+func (dpm *ProvisionerMock) ProvisionDeferredDashboard(ctx context.Context, orgID int64, uid string) (bool, error) {
+	dpm.Calls.ProvisionDeferredDashboard = append(dpm.Calls.ProvisionDeferredDashboard, uid)
+	if dpm.ProvisionDeferredDashboardFunc != nil {
+		return dpm.ProvisionDeferredDashboardFunc(ctx, orgID, uid)
+	}
+	return false, nil
+}
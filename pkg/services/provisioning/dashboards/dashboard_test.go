@@ -0,0 +1,226 @@
+package dashboards
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvisionProvider(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+	})
+	fakeService = mockDashboardProvisioningService()
+	bus.AddHandler("test", mockGetDashboardQuery)
+	t.Cleanup(bus.ClearBusHandlers)
+
+	logger := log.New("test.logger")
+	newReader := func(name, path string) *FileReader {
+		reader, err := NewDashboardFileReader(&config{
+			Name:    name,
+			Type:    "file",
+			OrgID:   1,
+			Options: map[string]interface{}{"path": path},
+		}, logger, nil)
+		require.NoError(t, err)
+		return reader
+	}
+	provisioner := &Provisioner{
+		log:         logger,
+		fileReaders: []*FileReader{newReader("one", oneDashboard), newReader("folder-one", defaultDashboards)},
+	}
+
+	t.Run("Only the named provider is provisioned", func(t *testing.T) {
+		require.NoError(t, provisioner.ProvisionProvider(context.Background(), "one"))
+
+		require.Len(t, fakeService.inserted, 1)
+		require.Len(t, fakeService.provisioned["one"], 1)
+		require.Empty(t, fakeService.provisioned["folder-one"])
+	})
+
+	t.Run("Unknown provider returns ErrProviderNotFound", func(t *testing.T) {
+		err := provisioner.ProvisionProvider(context.Background(), "unknown")
+		require.True(t, errors.Is(err, ErrProviderNotFound))
+		require.EqualError(t, err, `dashboard provider not found: "unknown"`)
+	})
+
+	t.Run("Missing provider folder is not an error", func(t *testing.T) {
+		provisioner.fileReaders = append(provisioner.fileReaders, newReader("missing", "/invalid-directory"))
+		require.NoError(t, provisioner.ProvisionProvider(context.Background(), "missing"))
+	})
+}
+
+func TestCleanUpOrphanedDashboards(t *testing.T) {
+	t.Cleanup(bus.ClearBusHandlers)
+	bus.ClearBusHandlers()
+	var deleted []string
+	var deletedOrgs []int64
+	counts := func(readerNames []string) []*models.OrphanedProvisionedDashboardsCount {
+		return []*models.OrphanedProvisionedDashboardsCount{
+			{OrgId: 1, Provisioned: 4, Orphaned: 4 - int64(len(readerNames))},
+		}
+	}
+	bus.AddHandler("test", func(query *models.CountOrphanedProvisionedDashboardsQuery) error {
+		query.Result = counts(query.ReaderNames)
+		return nil
+	})
+	bus.AddHandler("test", func(cmd *models.DeleteOrphanedProvisionedDashboardsCommand) error {
+		deleted = cmd.ReaderNames
+		deletedOrgs = append(deletedOrgs, cmd.OrgId)
+		return nil
+	})
+
+	// An empty directory reads as no providers, which makes every provisioned dashboard an orphan.
+	newProvisioner := func(t *testing.T, guard setting.ProvisioningCleanupGuard) DashboardProvisioner {
+		provisioner, err := New(context.Background(), []string{t.TempDir()}, nil,
+			&setting.Cfg{ProvisioningCleanupGuard: guard})
+		require.NoError(t, err)
+		return provisioner
+	}
+
+	t.Run("An empty tree triggers the guard", func(t *testing.T) {
+		deleted = nil
+		newProvisioner(t, setting.ProvisioningCleanupGuard{MaxPercent: 50}).CleanUpOrphanedDashboards(context.Background())
+		require.Nil(t, deleted, "Nothing should be deleted")
+	})
+
+	t.Run("Forcing the cleanup deletes the orphans", func(t *testing.T) {
+		deleted = nil
+		newProvisioner(t, setting.ProvisioningCleanupGuard{MaxPercent: 50, Force: true}).
+			CleanUpOrphanedDashboards(context.Background())
+		require.NotNil(t, deleted)
+		require.Empty(t, deleted)
+	})
+
+	t.Run("Orphans within the limits are deleted", func(t *testing.T) {
+		deleted = nil
+		reader, err := NewDashboardFileReader(&config{Name: "default", Type: "file", OrgID: 1,
+			Options: map[string]interface{}{"path": t.TempDir()}}, log.New("test.logger"), nil)
+		require.NoError(t, err)
+		provisioner := &Provisioner{log: log.New("test.logger"), fileReaders: []*FileReader{reader},
+			cleanupGuard: setting.ProvisioningCleanupGuard{MaxPercent: 100, MaxCount: 3}}
+
+		provisioner.CleanUpOrphanedDashboards(context.Background())
+		require.Equal(t, []string{"default"}, deleted)
+	})
+
+	t.Run("The guard is checked per org", func(t *testing.T) {
+		deleted, deletedOrgs = nil, nil
+		origCounts := counts
+		t.Cleanup(func() { counts = origCounts })
+		// Every dashboard of org 2 is gone, which is only a third of all the provisioned dashboards.
+		counts = func([]string) []*models.OrphanedProvisionedDashboardsCount {
+			return []*models.OrphanedProvisionedDashboardsCount{
+				{OrgId: 1, Provisioned: 4, Orphaned: 0},
+				{OrgId: 2, Provisioned: 2, Orphaned: 2},
+				{OrgId: 3, Provisioned: 4, Orphaned: 1},
+			}
+		}
+
+		newProvisioner(t, setting.ProvisioningCleanupGuard{MaxPercent: 50}).CleanUpOrphanedDashboards(context.Background())
+		require.Equal(t, []int64{3}, deletedOrgs)
+	})
+}
+
+func TestGetProvisionedDashboards(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+	})
+	fakeService = mockDashboardProvisioningService()
+	bus.AddHandler("test", mockGetDashboardQuery)
+	t.Cleanup(bus.ClearBusHandlers)
+
+	dir := t.TempDir()
+	dashboardPath := filepath.Join(dir, "dashboard.json")
+	require.NoError(t, ioutil.WriteFile(dashboardPath, []byte(`{"title": "Home", "uid": "home"}`), 0600))
+
+	reader, err := NewDashboardFileReader(&config{
+		Name:    "inventory",
+		Type:    "file",
+		OrgID:   2,
+		Options: map[string]interface{}{"path": dir},
+	}, log.New("test.logger"), nil)
+	require.NoError(t, err)
+	provisioner := &Provisioner{log: log.New("test.logger"), fileReaders: []*FileReader{reader}}
+
+	require.NoError(t, reader.walkDisk(context.Background()))
+	provisioned := provisioner.GetProvisionedDashboards()
+	require.Len(t, provisioned, 1)
+	require.Equal(t, "dashboard", provisioned[0].Kind)
+	require.Equal(t, "Home", provisioned[0].Name)
+	require.Equal(t, "home", provisioned[0].UID)
+	require.Equal(t, int64(2), provisioned[0].OrgID)
+	require.Equal(t, dashboardPath, provisioned[0].File)
+	require.Equal(t, utils.ActionCreated, provisioned[0].Action)
+	appliedAt := provisioned[0].AppliedAt
+	require.False(t, appliedAt.IsZero())
+
+	require.NoError(t, reader.walkDisk(context.Background()))
+	require.Equal(t, appliedAt, provisioner.GetProvisionedDashboards()[0].AppliedAt,
+		"Up to date dashboards should keep the time they were applied at")
+
+	require.NoError(t, os.Remove(dashboardPath))
+	require.NoError(t, reader.walkDisk(context.Background()))
+	require.Empty(t, provisioner.GetProvisionedDashboards(), "Deleted dashboards should be dropped")
+	deleted := provisioner.GetDeletedDashboards()
+	require.Len(t, deleted, 1)
+	require.Equal(t, "Home", deleted[0].Name)
+	require.Equal(t, utils.ActionDeleted, deleted[0].Action)
+
+	require.NoError(t, reader.walkDisk(context.Background()))
+	require.Empty(t, provisioner.GetDeletedDashboards(), "Only the deletions of the last walk should be listed")
+}
+
+func TestGetProvisioningInfo(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+	})
+	fakeService = mockDashboardProvisioningService()
+	bus.AddHandler("test", mockGetDashboardQuery)
+	t.Cleanup(bus.ClearBusHandlers)
+
+	dir := t.TempDir()
+	dashboardPath := filepath.Join(dir, "dashboard.json")
+	require.NoError(t, ioutil.WriteFile(dashboardPath, []byte(`{"title": "Home", "uid": "home"}`), 0600))
+
+	cfg := &config{
+		Name:           "locked",
+		Type:           "file",
+		OrgID:          1,
+		AllowUIUpdates: true,
+		Options:        map[string]interface{}{"path": dir},
+	}
+	reader, err := NewDashboardFileReader(cfg, log.New("test.logger"), nil)
+	require.NoError(t, err)
+	provisioner := &Provisioner{log: log.New("test.logger"), fileReaders: []*FileReader{reader}, configs: []*config{cfg}}
+
+	_, ok := provisioner.GetProvisioningInfo("home")
+	require.False(t, ok, "Nothing is provisioned before the first walk")
+
+	require.NoError(t, reader.walkDisk(context.Background()))
+	info, ok := provisioner.GetProvisioningInfo("home")
+	require.True(t, ok)
+	require.Equal(t, "locked", info.Provider)
+	require.Equal(t, dashboardPath, info.File)
+	require.True(t, info.AllowUIUpdates)
+	require.Equal(t, provisioner.GetProvisionedDashboards()[0].AppliedAt, info.AppliedAt)
+	require.Equal(t, info.AllowUIUpdates, provisioner.GetAllowUIUpdatesFromConfig("locked"))
+	require.False(t, provisioner.GetAllowUIUpdatesFromConfig("unknown"))
+
+	_, ok = provisioner.GetProvisioningInfo("other")
+	require.False(t, ok)
+}
@@ -0,0 +1,69 @@
+package dashboards
+
+import (
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+)
+
+// datasourceRef is a reference of a dashboard to a datasource by uid. Datasources referenced by name, as older
+// dashboards do, aren't checked.
+type datasourceRef struct {
+	location string
+	uid      string
+}
+
+// findDatasourceRefs returns the datasource references of the panels, queries, template variables and annotations
+// of a dashboard.
+func findDatasourceRefs(data *simplejson.Json) []datasourceRef {
+	var refs []datasourceRef
+	appendRef := func(location string, item *simplejson.Json) {
+		if uid := item.GetPath("datasource", "uid").MustString(); utils.IsDatasourceReference(uid) {
+			refs = append(refs, datasourceRef{location: location, uid: uid})
+		}
+	}
+
+	for _, variable := range data.GetPath("templating", "list").MustArray() {
+		variable := simplejson.NewFromAny(variable)
+		appendRef(fmt.Sprintf("template variable %q", variable.Get("name").MustString()), variable)
+	}
+	for _, annotation := range data.GetPath("annotations", "list").MustArray() {
+		annotation := simplejson.NewFromAny(annotation)
+		appendRef(fmt.Sprintf("annotation %q", annotation.Get("name").MustString()), annotation)
+	}
+
+	var panels []interface{}
+	panels = append(panels, data.Get("panels").MustArray()...)
+	for _, row := range data.Get("rows").MustArray() {
+		panels = append(panels, simplejson.NewFromAny(row).Get("panels").MustArray()...)
+	}
+	for len(panels) > 0 {
+		panel := simplejson.NewFromAny(panels[0])
+		panels = panels[1:]
+
+		location := fmt.Sprintf("panel %q", panel.Get("title").MustString())
+		appendRef(location, panel)
+		for _, target := range panel.Get("targets").MustArray() {
+			target := simplejson.NewFromAny(target)
+			appendRef(fmt.Sprintf("query %s of %s", target.Get("refId").MustString(), location), target)
+		}
+		// Collapsed rows keep their panels inside the row panel.
+		panels = append(panels, panel.Get("panels").MustArray()...)
+	}
+
+	return refs
+}
+
+// danglingReferences returns the references of a dashboard to datasources that don't exist in the org of the reader.
+func (fr *FileReader) danglingReferences(file string, uid string, refs []datasourceRef,
+	datasourceExists func(orgID int64, uid string) bool) []utils.DanglingReference {
+	var dangling []utils.DanglingReference
+	for _, ref := range refs {
+		if !datasourceExists(fr.Cfg.OrgID, ref.uid) {
+			dangling = append(dangling, utils.DanglingReference{File: file, Kind: "dashboard", UID: uid,
+				Location: ref.location, DatasourceUID: ref.uid})
+		}
+	}
+	return dangling
+}
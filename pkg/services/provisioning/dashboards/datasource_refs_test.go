@@ -0,0 +1,95 @@
+package dashboards
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const datasourceRefsDashboards = "testdata/test-dashboards/datasource-refs"
+
+func TestDanglingDatasourceReferences(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+		bus.ClearBusHandlers()
+	})
+
+	existingUIDs := map[string]bool{"prometheus": true}
+	bus.ClearBusHandlers()
+	bus.AddHandler("test", func(query *models.GetDataSourceQuery) error {
+		if query.OrgId == 1 && existingUIDs[query.Uid] {
+			query.Result = &models.DataSource{Uid: query.Uid, OrgId: query.OrgId}
+			return nil
+		}
+		return models.ErrDataSourceNotFound
+	})
+
+	newReader := func(t *testing.T, mode utils.ReferenceCheckMode) *FileReader {
+		t.Helper()
+
+		fakeService = mockDashboardProvisioningService()
+		cfg := &config{
+			Name:    "Default",
+			Type:    "file",
+			OrgID:   1,
+			Options: map[string]interface{}{"path": datasourceRefsDashboards},
+		}
+		reader, err := NewDashboardFileReader(cfg, log.New("test-logger"), nil)
+		require.NoError(t, err)
+		reader.ReferenceCheck = mode
+		return reader
+	}
+
+	t.Run("Dangling references are reported with the dashboard file", func(t *testing.T) {
+		reader := newReader(t, utils.ReferenceCheckWarn)
+		require.NoError(t, reader.walkDisk(context.Background()))
+		assert.Len(t, fakeService.inserted, 1)
+
+		dangling := reader.DanglingReferences()
+		var locations []string
+		for _, ref := range dangling {
+			assert.Equal(t, "services", ref.UID)
+			assert.Contains(t, ref.File, "services.json")
+			locations = append(locations, ref.Location+" -> "+ref.DatasourceUID)
+		}
+		assert.ElementsMatch(t, []string{
+			`annotation "Deploys" -> loki`,
+			`query B of panel "Requests" -> prometheus-old`,
+			`panel "Errors" -> loki`,
+		}, locations)
+	})
+
+	t.Run("Dashboards with dangling references aren't saved in fail mode", func(t *testing.T) {
+		reader := newReader(t, utils.ReferenceCheckFail)
+		err := reader.walkDisk(context.Background())
+		require.True(t, errors.Is(err, utils.ErrDanglingReferences))
+		assert.Contains(t, err.Error(), `references datasource "prometheus-old" in query B of panel "Requests"`)
+		assert.Empty(t, fakeService.inserted)
+	})
+
+	t.Run("References aren't checked when the check is off", func(t *testing.T) {
+		reader := newReader(t, utils.ReferenceCheckOff)
+		require.NoError(t, reader.walkDisk(context.Background()))
+		assert.Len(t, fakeService.inserted, 1)
+		assert.Empty(t, reader.DanglingReferences())
+	})
+
+	t.Run("Dashboards are saved once their datasources exist", func(t *testing.T) {
+		existingUIDs["loki"], existingUIDs["prometheus-old"] = true, true
+		t.Cleanup(func() { delete(existingUIDs, "loki"); delete(existingUIDs, "prometheus-old") })
+
+		reader := newReader(t, utils.ReferenceCheckFail)
+		require.NoError(t, reader.walkDisk(context.Background()))
+		assert.Len(t, fakeService.inserted, 1)
+		assert.Empty(t, reader.DanglingReferences())
+	})
+}
@@ -0,0 +1,145 @@
+package dashboards
+
+import (
+	"context"
+	"os"
+	"sort"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+)
+
+// ignoredDiffFields are the fields of the dashboard JSON that saving a dashboard sets, so they differ between the
+// file and the database without a change.
+var ignoredDiffFields = map[string]bool{"id": true, "version": true}
+
+// Diff compares the dashboard files of every provider with the dashboards they provisioned, without changing
+// anything. Providers whose path doesn't exist are skipped.
+func (provider *Provisioner) Diff(ctx context.Context) (*utils.Diff, error) {
+	diff := utils.NewDiff("dashboards")
+	for _, reader := range provider.fileReaders {
+		if err := reader.diff(ctx, diff); err != nil {
+			if os.IsNotExist(err) {
+				provider.log.Warn("Failed to diff config", "name", reader.Cfg.Name, "error", err)
+				continue
+			}
+			return nil, err
+		}
+	}
+	return diff, nil
+}
+
+// diff adds the dashboard files of the provider that aren't provisioned yet, the provisioned dashboards whose file
+// is gone and the ones whose JSON differs from their file to diff. Like walkDisk, it compares the locale variant
+// of a file when there is one.
+func (fr *FileReader) diff(ctx context.Context, diff *utils.Diff) error {
+	rootPath := fr.rootPath()
+	resolvedPath := fr.resolvedPath()
+	if _, err := os.Stat(resolvedPath); err != nil {
+		return err
+	}
+
+	provisionedDashboardRefs, err := getProvisionedDashboardsByPath(fr.dashboardProvisioningService, fr.Cfg.Name)
+	if err != nil {
+		return err
+	}
+	provisionedDashboardRefs = rebaseProvisionedDashboards(provisionedDashboardRefs, resolvedPath, rootPath)
+
+	filesFoundOnDisk := map[string]os.FileInfo{}
+	if err := fr.walkDashboardFiles(rootPath, resolvedPath, filesFoundOnDisk); err != nil {
+		return err
+	}
+	localizedFiles := fr.localizeDashboardFiles(rootPath, filesFoundOnDisk)
+
+	paths := make([]string, 0, len(filesFoundOnDisk))
+	for path := range filesFoundOnDisk {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		sourcePath := path
+		if localizedPath, ok := localizedFiles[path]; ok {
+			sourcePath = localizedPath
+		}
+		content, _, err := fr.readDashboardFile(sourcePath)
+		if err != nil {
+			return err
+		}
+		data, err := simplejson.NewJson(content)
+		if err != nil {
+			return utils.NewJSONFileError("dashboards", sourcePath, content, err)
+		}
+		object := utils.DiffObject{Kind: "dashboard", Name: data.Get("title").MustString(), UID: data.Get("uid").MustString(),
+			OrgID: fr.Cfg.OrgID, File: sourcePath}
+
+		provisionedData, ok := provisionedDashboardRefs[path]
+		if !ok {
+			diff.Added = append(diff.Added, object)
+			continue
+		}
+
+		query := &models.GetDashboardQuery{Id: provisionedData.DashboardId, OrgId: fr.Cfg.OrgID}
+		if err := bus.DispatchCtx(ctx, query); err != nil {
+			return err
+		}
+		object.UID = query.Result.Uid
+
+		var fields utils.FieldChanges
+		if provisionedData.ExternalId != path {
+			fields.Compare("path", provisionedData.ExternalId, path)
+		}
+		fields = append(fields, dashboardChanges(query.Result.Data, data)...)
+		if len(fields) > 0 {
+			diff.Changed = append(diff.Changed, utils.ChangedObject{DiffObject: object, Fields: fields})
+		}
+	}
+
+	removedPaths := make([]string, 0, len(provisionedDashboardRefs))
+	for path := range provisionedDashboardRefs {
+		if _, ok := filesFoundOnDisk[path]; !ok {
+			removedPaths = append(removedPaths, path)
+		}
+	}
+	sort.Strings(removedPaths)
+	for _, path := range removedPaths {
+		object := utils.DiffObject{Kind: "dashboard", Name: path, OrgID: fr.Cfg.OrgID, File: path}
+		query := &models.GetDashboardQuery{Id: provisionedDashboardRefs[path].DashboardId, OrgId: fr.Cfg.OrgID}
+		if err := bus.DispatchCtx(ctx, query); err == nil {
+			object.Name, object.UID = query.Result.Title, query.Result.Uid
+		}
+		diff.Removed = append(diff.Removed, object)
+	}
+
+	return nil
+}
+
+// dashboardChanges returns the top level fields of the stored dashboard JSON that differ from the file.
+func dashboardChanges(current *simplejson.Json, desired *simplejson.Json) utils.FieldChanges {
+	currentFields := map[string]interface{}{}
+	for field, value := range current.MustMap() {
+		currentFields[field] = value
+	}
+	desiredFields := map[string]interface{}{}
+	for field, value := range desired.MustMap() {
+		desiredFields[field] = value
+	}
+	for field := range ignoredDiffFields {
+		delete(currentFields, field)
+		delete(desiredFields, field)
+	}
+	// Dashboards without a uid in their file get one when they're saved.
+	if _, ok := desiredFields["uid"]; !ok {
+		delete(currentFields, "uid")
+	}
+
+	var fields utils.FieldChanges
+	fields.CompareMap("", currentFields, desiredFields)
+	return fields
+}
@@ -0,0 +1,81 @@
+package dashboards
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+	})
+	fakeService = mockDashboardProvisioningService()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"unchanged.json": `{"title": "Unchanged", "uid": "unchanged", "panels": []}`,
+		"changed.json":   `{"title": "Changed in the file", "uid": "changed"}`,
+		"added.json":     `{"title": "Added", "uid": "added"}`,
+	}
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+
+	cfg := &config{Name: "test-diff", Type: "file", OrgID: 1, Options: map[string]interface{}{"path": dir}}
+	fakeService.provisioned[cfg.Name] = []*models.DashboardProvisioning{
+		{DashboardId: 1, Name: cfg.Name, ExternalId: filepath.Join(dir, "unchanged.json")},
+		{DashboardId: 2, Name: cfg.Name, ExternalId: filepath.Join(dir, "changed.json")},
+		{DashboardId: 3, Name: cfg.Name, ExternalId: filepath.Join(dir, "removed.json")},
+	}
+	stored := map[int64]*models.Dashboard{
+		1: {Id: 1, Uid: "unchanged", Title: "Unchanged", Data: simplejson.NewFromAny(map[string]interface{}{
+			"id": 1, "title": "Unchanged", "uid": "unchanged", "panels": []interface{}{}, "version": 3,
+		})},
+		2: {Id: 2, Uid: "changed", Title: "Changed", Data: simplejson.NewFromAny(map[string]interface{}{
+			"id": 2, "title": "Changed", "uid": "changed", "version": 1,
+		})},
+		3: {Id: 3, Uid: "removed", Title: "Removed"},
+	}
+	t.Cleanup(bus.ClearBusHandlers)
+	bus.AddHandler("test", func(query *models.GetDashboardQuery) error {
+		dash, ok := stored[query.Id]
+		if !ok {
+			return models.ErrDashboardNotFound
+		}
+		query.Result = dash
+		return nil
+	})
+
+	reader, err := NewDashboardFileReader(cfg, log.New("test.logger"), nil)
+	require.NoError(t, err)
+	provisioner := &Provisioner{log: log.New("test.logger"), fileReaders: []*FileReader{reader}, configs: []*config{cfg}}
+
+	diff, err := provisioner.Diff(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []utils.DiffObject{
+		{Kind: "dashboard", Name: "Added", UID: "added", OrgID: 1, File: filepath.Join(dir, "added.json")},
+	}, diff.Added)
+	assert.Equal(t, []utils.DiffObject{
+		{Kind: "dashboard", Name: "Removed", UID: "removed", OrgID: 1, File: filepath.Join(dir, "removed.json")},
+	}, diff.Removed)
+	require.Len(t, diff.Changed, 1)
+	assert.Equal(t, "changed", diff.Changed[0].UID)
+	assert.Equal(t, utils.FieldChanges{
+		{Field: "title", Current: "Changed", Desired: "Changed in the file"},
+	}, diff.Changed[0].Fields, "Fields set by saving the dashboard aren't changes")
+
+	assert.Empty(t, fakeService.inserted, "Diffing doesn't save dashboards")
+	assert.Len(t, fakeService.provisioned[cfg.Name], 3)
+}
@@ -0,0 +1,73 @@
+package dashboards
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// DashboardDrift is a provisioned dashboard that was changed after provisioning saved it, and whose changes are
+// about to be overwritten because its file changed.
+type DashboardDrift struct {
+	UID      string
+	Title    string
+	OrgID    int64
+	Provider string
+	// File is the provisioning file the dashboard is saved from.
+	File string
+	// Version is the version of the dashboard that is overwritten. It can still be restored from the version
+	// history of the dashboard.
+	Version int
+	// UpdatedBy is the login of the user that changed the dashboard last, or empty when it's unknown.
+	UpdatedBy   string
+	UpdatedByID int64
+	Updated     time.Time
+}
+
+// DriftHandler is called for every provisioned dashboard whose manual changes are overwritten.
+type DriftHandler func(drift DashboardDrift)
+
+// checkDrift compares the dashboard saved by provisioning with the one in the database, and logs and reports the
+// dashboard when it was changed since. Dashboards provisioned before their checksum was stored aren't checked.
+func (fr *FileReader) checkDrift(ctx context.Context, path string, provisionedData *models.DashboardProvisioning) {
+	if provisionedData == nil || provisionedData.DashboardCheckSum == "" {
+		return
+	}
+
+	query := &models.GetDashboardQuery{Id: provisionedData.DashboardId, OrgId: fr.Cfg.OrgID}
+	if err := bus.DispatchCtx(ctx, query); err != nil {
+		fr.log.Debug("Failed to load provisioned dashboard to check for manual changes", "file", path, "error", err)
+		return
+	}
+	current := query.Result
+	checkSum, err := models.DashboardCheckSum(current.Data)
+	if err != nil || checkSum == provisionedData.DashboardCheckSum {
+		return
+	}
+
+	drift := DashboardDrift{
+		UID:         current.Uid,
+		Title:       current.Title,
+		OrgID:       current.OrgId,
+		Provider:    fr.Cfg.Name,
+		File:        path,
+		Version:     current.Version,
+		UpdatedByID: current.UpdatedBy,
+		Updated:     current.Updated,
+	}
+	if current.UpdatedBy > 0 {
+		userQuery := &models.GetUserByIdQuery{Id: current.UpdatedBy}
+		if err := bus.DispatchCtx(ctx, userQuery); err == nil {
+			drift.UpdatedBy = userQuery.Result.Login
+		}
+	}
+
+	fr.log.Warn("Overwriting manual changes of a provisioned dashboard", "uid", drift.UID, "title", drift.Title,
+		"file", path, "version", drift.Version, "updatedBy", drift.UpdatedBy, "updatedById", drift.UpdatedByID,
+		"updated", drift.Updated)
+	if fr.onDrift != nil {
+		fr.onDrift(drift)
+	}
+}
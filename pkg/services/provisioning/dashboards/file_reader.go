@@ -2,12 +2,18 @@ package dashboards
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/grafana/grafana/pkg/bus"
@@ -16,23 +22,93 @@ import (
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/services/search"
+	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util"
+	"golang.org/x/sync/errgroup"
 )
 
 var (
 	// ErrFolderNameMissing is returned when folder name is missing.
 	ErrFolderNameMissing = errors.New("folder name missing")
+	// ErrFolderUIDNotFound is returned, wrapped with the UID, when no folder has the folderUid of a provider that
+	// can't create it.
+	ErrFolderUIDNotFound = errors.New("folder uid not found")
 )
 
+// defaultPollInterval is used by providers without an update interval when the poll settings aren't set.
+const defaultPollInterval = 10 * time.Second
+
+// pathFolderUIDHashLength is the number of hex characters of the hash in the UIDs of foldersFromFilesPath folders
+// and of dashboards without a uid.
+const pathFolderUIDHashLength = 20
+
+// localesFolderName is the subfolder of a provider's path holding locale specific dashboard variants.
+const localesFolderName = "locales"
+
 // FileReader is responsible for reading dashboards from disk and
 // insert/update dashboards to the Grafana database using
 // `dashboards.DashboardProvisioningService`.
 type FileReader struct {
+	// lastPoll is the unix time in nanoseconds of the last finished polling cycle. It is accessed atomically and
+	// kept first in the struct for 64-bit alignment.
+	lastPoll int64
+	// resumedFiles is the number of files the current or last walk skipped since a failed walk before applied them.
+	// It is accessed atomically.
+	resumedFiles int64
+	// pollFailures is the number of polling cycles in a row that failed. It is accessed atomically.
+	pollFailures int64
+
 	Cfg                          *config
 	Path                         string
 	log                          log.Logger
 	dashboardProvisioningService dashboards.DashboardProvisioningService
 	FoldersFromFilesStructure    bool
+	// FoldersFromFilesPath puts dashboards into a folder named after their directory path below the provider path.
+	FoldersFromFilesPath bool
+	// Locale selects dashboard variants from the `locales/<locale>` subfolder of the path.
+	Locale string
+	// MaxConcurrency is the number of dashboard files read and saved in parallel.
+	MaxConcurrency int
+	// PollInterval is used when the provider has no updateIntervalSeconds of its own. Zero disables polling.
+	PollInterval time.Duration
+	// PollJitter randomizes every polling interval by up to plus or minus this percentage, so replicas
+	// provisioning the same files don't poll in lockstep.
+	PollJitter int
+	// PollFailureThreshold is the number of polling cycles in a row that have to fail for polling to be reported
+	// as failing. Zero never reports it.
+	PollFailureThreshold int
+	// IncrementalPolling makes polls only save the files modified since the last walk of the disk. Lazy providers
+	// are always walked in full.
+	IncrementalPolling bool
+	// ReferenceCheck is what happens to dashboards referencing datasources that don't exist, warn when it's empty.
+	ReferenceCheck utils.ReferenceCheckMode
+	// PollWindow is the time of the day polling is active in, the polls that fall outside of it are skipped. It's
+	// checked on every poll, so the zero value, which is always open, only costs a comparison.
+	PollWindow setting.ProvisioningPollWindow
+	// CleanupGuard limits how many of the dashboards of the provider a walk may delete because their files are gone.
+	CleanupGuard setting.ProvisioningCleanupGuard
+
+	mutex                  sync.Mutex
+	lastBrokenLinks        []BrokenLink
+	lastPollErr            error
+	lastDanglingReferences []utils.DanglingReference
+	// applied holds the dashboards provisioned from the files found by the last walk of the disk, by path.
+	applied map[string]utils.ProvisionedObject
+	// deleted holds the dashboards the last walk of the disk deleted since their files were gone.
+	deleted []utils.ProvisionedObject
+	// walkMutex serializes walkDisk, since a provider can be provisioned again while it's polling.
+	walkMutex  sync.Mutex
+	parseCache *parseCache
+	resume     *resumeState
+	// onDrift is called before manual changes of a provisioned dashboard are overwritten.
+	onDrift DriftHandler
+	// deferred holds the dashboards of a lazy provider that the last walk of the disk didn't provision, by uid.
+	deferred map[string]deferredDashboard
+	// poll is the state of the last successful walk of the disk that incremental polls compare to. It's guarded by
+	// walkMutex.
+	poll *pollState
 }
 
 // NewDashboardFileReader returns a new filereader based on `config`
@@ -44,14 +120,25 @@ func NewDashboardFileReader(cfg *config, log log.Logger, store dboards.Store) (*
 		if !ok {
 			return nil, fmt.Errorf("failed to load dashboards, path param is not a string")
 		}
-
-		log.Warn("[Deprecated] The folder property is deprecated. Please use path instead.")
 	}
 
 	foldersFromFilesStructure, _ := cfg.Options["foldersFromFilesStructure"].(bool)
-	if foldersFromFilesStructure && cfg.Folder != "" && cfg.FolderUID != "" {
+	if foldersFromFilesStructure && (cfg.Folder != "" || cfg.FolderUID != "") {
 		return nil, fmt.Errorf("'folder' and 'folderUID' should be empty using 'foldersFromFilesStructure' option")
 	}
+	foldersFromFilesPath, _ := cfg.Options["foldersFromFilesPath"].(bool)
+	if foldersFromFilesPath && foldersFromFilesStructure {
+		return nil, fmt.Errorf("'foldersFromFilesStructure' and 'foldersFromFilesPath' options can't be used together")
+	}
+	if foldersFromFilesPath && (cfg.Folder != "" || cfg.FolderUID != "") {
+		return nil, fmt.Errorf("'folder' and 'folderUID' should be empty using 'foldersFromFilesPath' option")
+	}
+	if len(cfg.FolderMapping) > 0 && (foldersFromFilesStructure || foldersFromFilesPath) {
+		return nil, fmt.Errorf("'folderMapping' can't be used together with the 'foldersFromFilesStructure' or 'foldersFromFilesPath' options")
+	}
+	if err := validateFolderMapping(cfg.FolderMapping); err != nil {
+		return nil, err
+	}
 
 	return &FileReader{
 		Cfg:                          cfg,
@@ -59,107 +146,446 @@ func NewDashboardFileReader(cfg *config, log log.Logger, store dboards.Store) (*
 		log:                          log,
 		dashboardProvisioningService: dashboards.NewProvisioningService(store),
 		FoldersFromFilesStructure:    foldersFromFilesStructure,
+		FoldersFromFilesPath:         foldersFromFilesPath,
+		PollInterval:                 defaultPollInterval,
+		CleanupGuard:                 setting.ProvisioningCleanupGuard{MaxPercent: 100},
+		parseCache:                   newParseCache(cfg.Name),
+		resume:                       newResumeState(""),
 	}, nil
 }
 
-// pollChanges periodically runs walkDisk based on interval specified in the config.
+// pollChanges periodically runs walkDisk based on interval specified in the config, skipping the polls outside of
+// the poll window.
 func (fr *FileReader) pollChanges(ctx context.Context) {
-	ticker := time.NewTicker(time.Duration(int64(time.Second) * fr.Cfg.UpdateIntervalSeconds))
+	interval := fr.updateInterval()
+	if interval == 0 {
+		fr.log.Debug("Polling for dashboard changes is disabled")
+		return
+	}
+
+	// Seeded per reader since the global source isn't seeded randomly, which would make every replica wait
+	// for the same intervals.
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	atomic.StoreInt64(&fr.lastPoll, time.Now().UnixNano())
+	timer := time.NewTimer(jitterInterval(interval, fr.PollJitter, rnd))
+	defer timer.Stop()
+	windowOpen := true
 	for {
 		select {
-		case <-ticker.C:
-			if err := fr.walkDisk(); err != nil {
-				fr.log.Error("failed to search for dashboards", "error", err)
+		case <-timer.C:
+			if open := fr.PollWindow.Contains(time.Now()); open != windowOpen {
+				windowOpen = open
+				fr.log.Info("Dashboard poll window changed", "open", open)
+			}
+			// A skipped poll still counts as a polling cycle, so the watchdog doesn't take the closed window for a
+			// hanging polling loop.
+			if windowOpen {
+				fr.recordPoll(fr.walk(ctx, fr.IncrementalPolling && !fr.Cfg.Lazy))
 			}
+			atomic.StoreInt64(&fr.lastPoll, time.Now().UnixNano())
+			timer.Reset(jitterInterval(interval, fr.PollJitter, rnd))
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+// recordPoll counts the failed polling cycles in a row. A failed cycle only logs a warning until the
+// PollFailureThreshold is reached, which is logged as an error once, since an error that persists, like an
+// unmounted directory, isn't fixed by retrying. Polling keeps retrying anyway so it recovers on its own.
+func (fr *FileReader) recordPoll(err error) {
+	if err == nil {
+		failures := atomic.SwapInt64(&fr.pollFailures, 0)
+		if fr.PollFailureThreshold > 0 && failures >= int64(fr.PollFailureThreshold) {
+			fr.log.Info("Polling for dashboards recovered", "failedCycles", failures)
+		}
+		return
+	}
+
+	fr.mutex.Lock()
+	fr.lastPollErr = err
+	fr.mutex.Unlock()
+	failures := atomic.AddInt64(&fr.pollFailures, 1)
+	if fr.PollFailureThreshold > 0 && failures == int64(fr.PollFailureThreshold) {
+		fr.log.Error("Polling for dashboards keeps failing, retrying", "failedCycles", failures, "error", err)
+		return
+	}
+	fr.log.Warn("failed to search for dashboards", "error", err, "failedCycles", failures)
+}
+
+// pollingFailed returns an error with the last error of polling when at least PollFailureThreshold polling cycles
+// in a row failed, or nil.
+func (fr *FileReader) pollingFailed() error {
+	failures := atomic.LoadInt64(&fr.pollFailures)
+	if fr.PollFailureThreshold <= 0 || failures < int64(fr.PollFailureThreshold) {
+		return nil
+	}
+
+	fr.mutex.Lock()
+	defer fr.mutex.Unlock()
+	return fmt.Errorf("polling dashboard provider %q failed %d times in a row: %w", fr.Cfg.Name, failures, fr.lastPollErr)
+}
+
+// pollingStalled returns true if polling was started and no polling cycle finished within the longest
+// update interval plus threshold. A cycle that failed still counts as finished since restarting the loop won't
+// fix it.
+func (fr *FileReader) pollingStalled(threshold time.Duration) bool {
+	lastPoll := atomic.LoadInt64(&fr.lastPoll)
+	if lastPoll == 0 {
+		return false
+	}
+	maxInterval := fr.updateInterval() * time.Duration(100+fr.PollJitter) / 100
+	return time.Since(time.Unix(0, lastPoll)) > maxInterval+threshold
+}
+
+// updateInterval returns the interval between polling cycles before jitter, or zero if polling is disabled.
+func (fr *FileReader) updateInterval() time.Duration {
+	if fr.PollInterval <= 0 {
+		return 0
+	}
+	if fr.Cfg.UpdateIntervalSeconds > 0 {
+		return time.Duration(int64(time.Second) * fr.Cfg.UpdateIntervalSeconds)
+	}
+	return fr.PollInterval
+}
+
+// jitterInterval returns interval randomly shortened or lengthened by up to jitter percent.
+func jitterInterval(interval time.Duration, jitter int, rnd *rand.Rand) time.Duration {
+	if jitter <= 0 {
+		return interval
+	}
+	maxJitter := int64(interval) * int64(jitter) / 100
+	return interval + time.Duration(rnd.Int63n(2*maxJitter+1)-maxJitter)
+}
+
 // walkDisk traverses the file system for the defined path, reading dashboard definition files,
 // and applies any change to the database.
-func (fr *FileReader) walkDisk() error {
+func (fr *FileReader) walkDisk(ctx context.Context) error {
+	return fr.walk(ctx, false)
+}
+
+// walk walks the disk like walkDisk. An incremental walk only saves the files that are new or were modified since
+// the last walk, and does nothing else when no file changed or went missing.
+func (fr *FileReader) walk(ctx context.Context, incremental bool) (err error) {
+	span, ctx := utils.StartChildSpan(ctx, "provisioning dashboards walk")
+	span.SetTag("provider", fr.Cfg.Name)
+	span.SetTag("incremental", incremental)
+	defer func() { utils.FinishSpan(span, err) }()
+
+	fr.walkMutex.Lock()
+	defer fr.walkMutex.Unlock()
+	atomic.StoreInt64(&fr.resumedFiles, 0)
+	defer func() { fr.resume.finishWalk(err) }()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	fr.log.Debug("Start walking disk", "path", fr.Path)
+	rootPath := fr.rootPath()
 	resolvedPath := fr.resolvedPath()
 	if _, err := os.Stat(resolvedPath); err != nil {
 		return err
 	}
 
-	provisionedDashboardRefs, err := getProvisionedDashboardsByPath(fr.dashboardProvisioningService, fr.Cfg.Name)
-	if err != nil {
+	// Find relevant files
+	filesFoundOnDisk := map[string]os.FileInfo{}
+	if err := fr.walkDashboardFiles(rootPath, resolvedPath, filesFoundOnDisk); err != nil {
 		return err
 	}
+	span.SetTag("files", len(filesFoundOnDisk))
+
+	nextPoll := newPollState(resolvedPath, filesFoundOnDisk)
+	var changed map[string]bool
+	if incremental {
+		var missing bool
+		changed, missing, incremental = fr.poll.changes(resolvedPath, filesFoundOnDisk)
+		if incremental && len(changed) == 0 && !missing {
+			fr.poll.polls++
+			span.SetTag("changed", 0)
+			fr.log.Debug("No dashboard files changed since the last walk", "path", fr.Path)
+			return nil
+		}
+	}
+	if incremental {
+		nextPoll.polls = fr.poll.polls + 1
+		span.SetTag("changed", len(changed))
+	}
+	defer func() {
+		if err == nil {
+			fr.poll = nextPoll
+		} else {
+			// The next poll walks in full, so the files this walk didn't get to are saved even if they're older.
+			fr.poll = nil
+		}
+	}()
 
-	// Find relevant files
-	filesFoundOnDisk := map[string]os.FileInfo{}
-	if err := filepath.Walk(resolvedPath, createWalkFn(filesFoundOnDisk)); err != nil {
+	provisionedDashboardRefs, err := getProvisionedDashboardsByPath(fr.dashboardProvisioningService, fr.Cfg.Name)
+	if err != nil {
 		return err
 	}
+	provisionedDashboardRefs = rebaseProvisionedDashboards(provisionedDashboardRefs, resolvedPath, rootPath)
+
+	localizedFiles := fr.localizeDashboardFiles(rootPath, filesFoundOnDisk)
 
 	fr.handleMissingDashboardFiles(provisionedDashboardRefs, filesFoundOnDisk)
 
+	filesToSave := filesFoundOnDisk
+	if fr.Cfg.Lazy {
+		filesToSave = fr.deferDashboards(filesFoundOnDisk, localizedFiles, provisionedDashboardRefs)
+		span.SetTag("deferred", len(filesFoundOnDisk)-len(filesToSave))
+	}
+
 	sanityChecker := newProvisioningSanityChecker(fr.Cfg.Name)
+	if incremental {
+		unchanged := filesToSave
+		filesToSave = changedFiles(filesToSave, localizedFiles, changed)
+		fr.trackUnchanged(unchanged, filesToSave, localizedFiles, sanityChecker)
+	}
 
-	if fr.FoldersFromFilesStructure {
-		err = fr.storeDashboardsInFoldersFromFileStructure(filesFoundOnDisk, provisionedDashboardRefs, resolvedPath, &sanityChecker)
-	} else {
-		err = fr.storeDashboardsInFolder(filesFoundOnDisk, provisionedDashboardRefs, &sanityChecker)
+	switch {
+	case fr.FoldersFromFilesStructure || fr.FoldersFromFilesPath:
+		err = fr.storeDashboardsInFoldersFromFileStructure(ctx, filesToSave, localizedFiles, provisionedDashboardRefs, rootPath, sanityChecker)
+	case len(fr.Cfg.FolderMapping) > 0:
+		err = fr.storeDashboardsInMappedFolders(ctx, filesToSave, localizedFiles, provisionedDashboardRefs, rootPath, sanityChecker)
+	default:
+		err = fr.storeDashboardsInFolder(ctx, filesToSave, localizedFiles, provisionedDashboardRefs, sanityChecker)
 	}
 	if err != nil {
 		return err
 	}
+	if failed := fr.resume.failedFiles(); len(failed) > 0 {
+		// The files that failed are retried by the next walk, even an incremental one.
+		for _, path := range failed {
+			delete(nextPoll.files, path)
+		}
+		fr.log.Warn("Some dashboard files failed to save, the next walk retries them", "failed", len(failed),
+			"files", len(filesFoundOnDisk))
+	}
+
+	if fr.FoldersFromFilesPath && !fr.Cfg.DisableDeletion {
+		fr.deleteOrphanedPathFolders(ctx, provisionedDashboardRefs, filesFoundOnDisk, rootPath)
+	}
 
+	fr.parseCache.finishWalk()
+	fr.retainApplied(filesFoundOnDisk)
+	if resumed := fr.ResumedFiles(); resumed > 0 {
+		span.SetTag("resumed", resumed)
+		fr.log.Info("Resumed dashboard provisioning after a failed run", "skipped", resumed, "files", len(filesFoundOnDisk))
+	}
 	sanityChecker.logWarnings(fr.log)
 
-	return nil
+	brokenLinks := sanityChecker.brokenLinks(dashboardExistsInOrg(ctx, fr.Cfg.OrgID, sanityChecker.uidUsage))
+	for _, link := range brokenLinks {
+		fr.log.Warn("provisioned dashboard has a broken link", "file", link.File, "uid", link.DashboardUID,
+			"link", link.Location, "url", link.URL, "reason", link.Reason)
+	}
+	dangling := sanityChecker.danglingReferences()
+	fr.mutex.Lock()
+	fr.lastBrokenLinks = brokenLinks
+	fr.lastDanglingReferences = dangling
+	fr.mutex.Unlock()
+
+	return utils.CheckDanglingReferences(fr.log, fr.referenceCheck(), dangling)
+}
+
+// ResumedFiles returns the number of files the last walk of the disk skipped, because the failed walk before it
+// already applied them and they haven't changed since.
+func (fr *FileReader) ResumedFiles() int {
+	return int(atomic.LoadInt64(&fr.resumedFiles))
+}
+
+// BrokenLinks returns the broken links found in the dashboards by the last walk of the disk.
+func (fr *FileReader) BrokenLinks() []BrokenLink {
+	fr.mutex.Lock()
+	defer fr.mutex.Unlock()
+	return fr.lastBrokenLinks
+}
+
+// DanglingReferences returns the references to datasources that don't exist found in the dashboards saved by the
+// last walk of the disk.
+func (fr *FileReader) DanglingReferences() []utils.DanglingReference {
+	fr.mutex.Lock()
+	defer fr.mutex.Unlock()
+	return fr.lastDanglingReferences
+}
+
+func (fr *FileReader) referenceCheck() utils.ReferenceCheckMode {
+	if fr.ReferenceCheck == "" {
+		return utils.ReferenceCheckWarn
+	}
+	return fr.ReferenceCheck
+}
+
+// localizeDashboardFiles removes the locale variants from filesFoundOnDisk and returns, for every dashboard
+// that has a variant for the configured locale, the path of that variant keyed by the default file path.
+func (fr *FileReader) localizeDashboardFiles(rootPath string, filesFoundOnDisk map[string]os.FileInfo) map[string]string {
+	localesPath := filepath.Join(rootPath, localesFolderName) + string(filepath.Separator)
+	for path := range filesFoundOnDisk {
+		if strings.HasPrefix(path, localesPath) {
+			delete(filesFoundOnDisk, path)
+		}
+	}
+
+	localizedFiles := map[string]string{}
+	if fr.Locale == "" {
+		return localizedFiles
+	}
+
+	for path := range filesFoundOnDisk {
+		relPath, err := filepath.Rel(rootPath, path)
+		if err != nil {
+			continue
+		}
+
+		localizedPath := filepath.Join(localesPath, fr.Locale, relPath)
+		if _, err := os.Stat(localizedPath); err == nil {
+			fr.log.Debug("using localized dashboard", "file", path, "locale", fr.Locale, "variant", localizedPath)
+			localizedFiles[path] = localizedPath
+		}
+	}
+
+	return localizedFiles
 }
 
 // storeDashboardsInFolder saves dashboards from the filesystem on disk to the folder from config
-func (fr *FileReader) storeDashboardsInFolder(filesFoundOnDisk map[string]os.FileInfo,
+func (fr *FileReader) storeDashboardsInFolder(ctx context.Context, filesFoundOnDisk map[string]os.FileInfo, localizedFiles map[string]string,
 	dashboardRefs map[string]*models.DashboardProvisioning, sanityChecker *provisioningSanityChecker) error {
-	folderID, err := getOrCreateFolderID(fr.Cfg, fr.dashboardProvisioningService, fr.Cfg.Folder)
+	folderID, err := getOrCreateFolderID(ctx, fr.Cfg, fr.dashboardProvisioningService, fr.Cfg.Folder)
 	if err != nil && !errors.Is(err, ErrFolderNameMissing) {
 		return err
 	}
 
 	// save dashboards based on json files
-	for path, fileInfo := range filesFoundOnDisk {
-		provisioningMetadata, err := fr.saveDashboard(path, folderID, fileInfo, dashboardRefs)
+	return fr.processFiles(ctx, filesFoundOnDisk, func(ctx context.Context, path string, fileInfo os.FileInfo) error {
+		provisioningMetadata, err := fr.saveDashboard(ctx, path, localizedFiles[path], folderID, fileInfo, dashboardRefs)
 		if err != nil {
-			fr.log.Error("failed to save dashboard", "error", err)
-			continue
+			fr.log.Error("failed to save dashboard", "file", path, "error", err)
+			fr.resume.fail(path)
+			return nil
 		}
 
 		sanityChecker.track(provisioningMetadata)
-	}
-	return nil
+		return nil
+	})
 }
 
 // storeDashboardsInFoldersFromFilesystemStructure saves dashboards from the filesystem on disk to the same folder
 // in Grafana as they are in on the filesystem.
-func (fr *FileReader) storeDashboardsInFoldersFromFileStructure(filesFoundOnDisk map[string]os.FileInfo,
-	dashboardRefs map[string]*models.DashboardProvisioning, resolvedPath string, sanityChecker *provisioningSanityChecker) error {
-	for path, fileInfo := range filesFoundOnDisk {
-		folderName := ""
-
-		dashboardsFolder := filepath.Dir(path)
-		if dashboardsFolder != resolvedPath {
-			folderName = filepath.Base(dashboardsFolder)
-		}
+func (fr *FileReader) storeDashboardsInFoldersFromFileStructure(ctx context.Context, filesFoundOnDisk map[string]os.FileInfo, localizedFiles map[string]string,
+	dashboardRefs map[string]*models.DashboardProvisioning, rootPath string, sanityChecker *provisioningSanityChecker) error {
+	folderIDs := newFolderIDCache()
+	getFolderID := func(folderName string) (int64, error) {
+		return folderIDs.get(folderName, func() (int64, error) {
+			var folderID int64
+			var err error
+			if fr.FoldersFromFilesPath && folderName != "" {
+				folderID, err = getOrCreatePathFolderID(ctx, fr.Cfg, fr.dashboardProvisioningService, folderName)
+			} else {
+				folderID, err = getOrCreateFolderID(ctx, fr.Cfg, fr.dashboardProvisioningService, folderName)
+			}
+			if err != nil && !errors.Is(err, ErrFolderNameMissing) {
+				return 0, err
+			}
+			return folderID, nil
+		})
+	}
 
-		folderID, err := getOrCreateFolderID(fr.Cfg, fr.dashboardProvisioningService, folderName)
-		if err != nil && !errors.Is(err, ErrFolderNameMissing) {
+	return fr.processFiles(ctx, filesFoundOnDisk, func(ctx context.Context, path string, fileInfo os.FileInfo) error {
+		folderName := fr.fileFolderName(path, rootPath)
+		folderID, err := getFolderID(folderName)
+		if err != nil {
 			return fmt.Errorf("can't provision folder %q from file system structure: %w", folderName, err)
 		}
 
-		provisioningMetadata, err := fr.saveDashboard(path, folderID, fileInfo, dashboardRefs)
+		provisioningMetadata, err := fr.saveDashboard(ctx, path, localizedFiles[path], folderID, fileInfo, dashboardRefs)
 		sanityChecker.track(provisioningMetadata)
 		if err != nil {
-			fr.log.Error("failed to save dashboard", "error", err)
+			fr.log.Error("failed to save dashboard", "file", path, "error", err)
+			fr.resume.fail(path)
 		}
+		return nil
+	})
+}
+
+// folderIDCache resolves every folder of a walk once. Folders are resolved under a lock so that concurrent workers
+// don't create the same folder twice.
+type folderIDCache struct {
+	mutex sync.Mutex
+	ids   map[string]int64
+}
+
+func newFolderIDCache() *folderIDCache {
+	return &folderIDCache{ids: map[string]int64{}}
+}
+
+// get returns the ID of the folder with the given key, like its name, calling resolve the first time it's asked for.
+// Failures aren't cached.
+func (c *folderIDCache) get(key string, resolve func() (int64, error)) (int64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if folderID, ok := c.ids[key]; ok {
+		return folderID, nil
+	}
+	folderID, err := resolve()
+	if err != nil {
+		return 0, err
 	}
-	return nil
+	c.ids[key] = folderID
+	return folderID, nil
+}
+
+// processFiles calls process for every file in filesFoundOnDisk using a pool of at most MaxConcurrency workers.
+// The files are picked up sorted by path, so a single worker saves them in the same order on every machine. The
+// first error returned by process, or canceling ctx, cancels the files that haven't been picked up yet and is
+// returned once all workers have stopped.
+func (fr *FileReader) processFiles(ctx context.Context, filesFoundOnDisk map[string]os.FileInfo, process func(ctx context.Context, path string, fileInfo os.FileInfo) error) error {
+	workers := fr.MaxConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(filesFoundOnDisk) {
+		workers = len(filesFoundOnDisk)
+	}
+
+	g, groupCtx := errgroup.WithContext(ctx)
+	paths := make(chan string)
+
+	sortedPaths := make([]string, 0, len(filesFoundOnDisk))
+	for path := range filesFoundOnDisk {
+		sortedPaths = append(sortedPaths, path)
+	}
+	sort.Strings(sortedPaths)
+
+	g.Go(func() error {
+		defer close(paths)
+		for _, path := range sortedPaths {
+			select {
+			case paths <- path:
+			case <-groupCtx.Done():
+				return nil
+			}
+		}
+		return nil
+	})
+
+	for i := 0; i < workers; i++ {
+		g.Go(func() error {
+			for path := range paths {
+				if err := process(groupCtx, path, filesFoundOnDisk[path]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	// The files that weren't picked up when ctx was canceled haven't been provisioned.
+	return ctx.Err()
 }
 
 // handleMissingDashboardFiles will unprovision or delete dashboards which are missing on disk.
@@ -167,13 +593,25 @@ func (fr *FileReader) handleMissingDashboardFiles(provisionedDashboardRefs map[s
 	filesFoundOnDisk map[string]os.FileInfo) {
 	// find dashboards to delete since json file is missing
 	var dashboardsToDelete []int64
+	deletedPaths := map[int64]string{}
 	for path, provisioningData := range provisionedDashboardRefs {
 		_, existsOnDisk := filesFoundOnDisk[path]
 		if !existsOnDisk {
 			dashboardsToDelete = append(dashboardsToDelete, provisioningData.DashboardId)
+			deletedPaths[provisioningData.DashboardId] = path
 		}
 	}
 
+	var deleted []utils.ProvisionedObject
+
+	if !fr.CleanupGuard.Allows(len(dashboardsToDelete), len(provisionedDashboardRefs)) {
+		fr.log.Error("Not deleting the provisioned dashboards whose files are missing, that would delete more of the "+
+			"dashboards of the provider than allowed. Check that the dashboards path is mounted and complete, or set "+
+			"orphan_cleanup_force to delete them", "missing", len(dashboardsToDelete),
+			"provisioned", len(provisionedDashboardRefs), "path", fr.resolvedPath())
+		dashboardsToDelete = nil
+	}
+
 	if fr.Cfg.DisableDeletion {
 		// If deletion is disabled for the provisioner we just remove provisioning metadata about the dashboard
 		// so afterwards the dashboard is considered unprovisioned.
@@ -191,49 +629,129 @@ func (fr *FileReader) handleMissingDashboardFiles(provisionedDashboardRefs map[s
 			err := fr.dashboardProvisioningService.DeleteProvisionedDashboard(dashboardID, fr.Cfg.OrgID)
 			if err != nil {
 				fr.log.Error("failed to delete dashboard", "id", dashboardID, "error", err)
+				continue
 			}
+			deleted = append(deleted, fr.deletedDashboard(deletedPaths[dashboardID]))
 		}
 	}
+
+	fr.mutex.Lock()
+	fr.deleted = deleted
+	fr.mutex.Unlock()
+}
+
+// deletedDashboard returns the dashboard of the file at path for the list of deleted dashboards, with the title and
+// uid it was applied with when this reader applied it.
+func (fr *FileReader) deletedDashboard(path string) utils.ProvisionedObject {
+	fr.mutex.Lock()
+	object, ok := fr.applied[path]
+	fr.mutex.Unlock()
+	if !ok {
+		object = utils.ProvisionedObject{Kind: "dashboard", OrgID: fr.Cfg.OrgID, File: path}
+	}
+	object.AppliedAt = time.Now()
+	object.Action = utils.ActionDeleted
+	return object
 }
 
-// saveDashboard saves or updates the dashboard provisioning file at path.
-func (fr *FileReader) saveDashboard(path string, folderID int64, fileInfo os.FileInfo,
-	provisionedDashboardRefs map[string]*models.DashboardProvisioning) (provisioningMetadata, error) {
+// saveDashboard saves or updates the dashboard provisioning file at path. If localizedPath is set the dashboard
+// is read from that locale variant instead, while still being tracked by path.
+func (fr *FileReader) saveDashboard(ctx context.Context, path string, localizedPath string, folderID int64, fileInfo os.FileInfo,
+	provisionedDashboardRefs map[string]*models.DashboardProvisioning) (_ provisioningMetadata, err error) {
+	span, _ := utils.StartChildSpan(ctx, "provisioning dashboards file")
+	span.SetTag("file", path)
+	defer func() { utils.FinishSpan(span, err) }()
+
 	provisioningMetadata := provisioningMetadata{}
-	resolvedFileInfo, err := resolveSymlink(fileInfo, path)
+
+	sourcePath := path
+	if localizedPath != "" {
+		localizedFileInfo, err := os.Stat(localizedPath)
+		if err != nil {
+			return provisioningMetadata, err
+		}
+		sourcePath, fileInfo = localizedPath, localizedFileInfo
+	}
+
+	resolvedFileInfo, err := resolveSymlink(fileInfo, sourcePath)
 	if err != nil {
 		return provisioningMetadata, err
 	}
 
 	provisionedData, alreadyProvisioned := provisionedDashboardRefs[path]
+	if alreadyProvisioned && provisionedData.ExternalId == path {
+		if resumed, ok := fr.resume.lookup(path, resolvedFileInfo, folderID); ok {
+			atomic.AddInt64(&fr.resumedFiles, 1)
+			fr.parseCache.keep(sourcePath)
+			fr.recordUpToDate(path, resumed, provisionedData)
+			return resumed, nil
+		}
+	}
 
-	jsonFile, err := fr.readDashboardFromFile(path, resolvedFileInfo.ModTime(), folderID)
+	content, checkSum, err := fr.readDashboardFile(sourcePath)
 	if err != nil {
-		fr.log.Error("failed to load dashboard from ", "file", path, "error", err)
+		fr.log.Error("failed to load dashboard from ", "file", sourcePath, "error", err)
 		return provisioningMetadata, nil
 	}
 
 	upToDate := alreadyProvisioned
 	if provisionedData != nil {
-		upToDate = jsonFile.checkSum == provisionedData.CheckSum
+		// Dashboards tracked by a path that was rebased are saved again to store the path they're found at now.
+		upToDate = checkSum == provisionedData.CheckSum && provisionedData.ExternalId == path
+	}
+
+	if cached, ok := fr.parseCache.lookup(sourcePath, checkSum, folderID, upToDate); ok {
+		fr.recordUpToDate(path, cached, provisionedData)
+		fr.resume.done(path, resolvedFileInfo, folderID, cached)
+		return cached, nil
+	}
+
+	jsonFile, err := fr.parseDashboardFile(sourcePath, content, checkSum, resolvedFileInfo.ModTime(), folderID)
+	if err != nil {
+		fr.log.Error("failed to load dashboard from ", "file", sourcePath, "error", err)
+		return provisioningMetadata, nil
 	}
 
 	// keeps track of which UIDs and titles we have already provisioned
 	dash := jsonFile.dashboard
 	provisioningMetadata.uid = dash.Dashboard.Uid
 	provisioningMetadata.identity = dashboardIdentity{title: dash.Dashboard.Title, folderID: dash.Dashboard.FolderId}
+	provisioningMetadata.file = sourcePath
+	provisioningMetadata.links = findDashboardLinks(dash.Dashboard.Data)
 
 	if upToDate {
+		fr.parseCache.put(sourcePath, checkSum, folderID, provisioningMetadata)
+		fr.recordUpToDate(path, provisioningMetadata, provisionedData)
+		fr.resume.done(path, resolvedFileInfo, folderID, provisioningMetadata)
 		return provisioningMetadata, nil
 	}
 
+	// The id of an exported dashboard is the one of the instance it was exported from, dashboards are saved by uid.
 	if dash.Dashboard.Id != 0 {
 		dash.Dashboard.Data.Set("id", nil)
 		dash.Dashboard.Id = 0
 	}
+	// Dashboards that were saved before keep their uid, new ones without a uid get one derived from their file, so
+	// they're updated instead of created again when their provisioning data is lost.
+	if dash.Dashboard.Uid == "" && !alreadyProvisioned {
+		dash.Dashboard.SetUid(fr.fileDashboardUID(path))
+		provisioningMetadata.uid = dash.Dashboard.Uid
+	}
+
+	// Only the dashboards that are saved are checked, so polling unchanged files doesn't look up their datasources.
+	var dangling []utils.DanglingReference
+	if fr.referenceCheck() != utils.ReferenceCheckOff {
+		dangling = fr.danglingReferences(sourcePath, dash.Dashboard.Uid, findDatasourceRefs(dash.Dashboard.Data),
+			utils.DatasourceExists(ctx))
+		if len(dangling) > 0 && fr.referenceCheck() == utils.ReferenceCheckFail {
+			provisioningMetadata.danglingRefs = dangling
+			return provisioningMetadata, nil
+		}
+	}
 
 	if alreadyProvisioned {
 		dash.Dashboard.SetId(provisionedData.DashboardId)
+		fr.checkDrift(ctx, path, provisionedData)
 	}
 
 	fr.log.Debug("saving new dashboard", "provisioner", fr.Cfg.Name, "file", path, "folderId", dash.Dashboard.FolderId)
@@ -244,8 +762,122 @@ func (fr *FileReader) saveDashboard(path string, folderID int64, fileInfo os.Fil
 		CheckSum:   jsonFile.checkSum,
 	}
 
-	_, err = fr.dashboardProvisioningService.SaveProvisionedDashboard(dash, dp)
-	return provisioningMetadata, err
+	saved, err := fr.dashboardProvisioningService.SaveProvisionedDashboard(dash, dp)
+	if err != nil {
+		return provisioningMetadata, err
+	}
+	fr.parseCache.put(sourcePath, checkSum, folderID, provisioningMetadata)
+	action := utils.ActionCreated
+	if alreadyProvisioned {
+		action = utils.ActionUpdated
+	}
+	fr.recordApplied(path, utils.ProvisionedObject{Kind: "dashboard", Name: saved.Title, UID: saved.Uid, OrgID: fr.Cfg.OrgID,
+		File: sourcePath, Action: action})
+	fr.resume.done(path, resolvedFileInfo, folderID, provisioningMetadata)
+	// The dangling references aren't cached, they're only reported by the walk that saves the dashboard.
+	provisioningMetadata.danglingRefs = dangling
+	return provisioningMetadata, nil
+}
+
+func (fr *FileReader) recordApplied(path string, object utils.ProvisionedObject) {
+	if object.AppliedAt.IsZero() {
+		object.AppliedAt = time.Now()
+	}
+
+	fr.mutex.Lock()
+	defer fr.mutex.Unlock()
+	if fr.applied == nil {
+		fr.applied = map[string]utils.ProvisionedObject{}
+	}
+	fr.applied[path] = object
+}
+
+// recordUpToDate keeps the dashboard at path in the inventory. Dashboards that were applied before this reader
+// was created are listed with the modification time of the file they were saved from.
+func (fr *FileReader) recordUpToDate(path string, metadata provisioningMetadata, provisionedData *models.DashboardProvisioning) {
+	fr.mutex.Lock()
+	_, recorded := fr.applied[path]
+	fr.mutex.Unlock()
+	if recorded {
+		return
+	}
+
+	object := utils.ProvisionedObject{Kind: "dashboard", Name: metadata.identity.title, UID: metadata.uid,
+		OrgID: fr.Cfg.OrgID, File: metadata.file, Action: utils.ActionSkipped}
+	if provisionedData != nil {
+		object.AppliedAt = time.Unix(provisionedData.Updated, 0)
+	}
+	fr.recordApplied(path, object)
+}
+
+// retainApplied drops the dashboards of files that are gone from the inventory.
+func (fr *FileReader) retainApplied(filesFoundOnDisk map[string]os.FileInfo) {
+	fr.mutex.Lock()
+	defer fr.mutex.Unlock()
+	for path := range fr.applied {
+		if _, ok := filesFoundOnDisk[path]; !ok {
+			delete(fr.applied, path)
+		}
+	}
+}
+
+// ProvisionedDashboards returns the dashboards provisioned from the files found by the last walk of the disk.
+func (fr *FileReader) ProvisionedDashboards() []utils.ProvisionedObject {
+	fr.mutex.Lock()
+	defer fr.mutex.Unlock()
+
+	objects := make([]utils.ProvisionedObject, 0, len(fr.applied))
+	for _, object := range fr.applied {
+		objects = append(objects, object)
+	}
+	return objects
+}
+
+// provisionedDashboard returns the dashboard with the uid that the last walk of the disk provisioned.
+func (fr *FileReader) provisionedDashboard(uid string) (utils.ProvisionedObject, bool) {
+	fr.mutex.Lock()
+	defer fr.mutex.Unlock()
+
+	for _, object := range fr.applied {
+		if object.UID == uid {
+			return object, true
+		}
+	}
+	return utils.ProvisionedObject{}, false
+}
+
+// DeletedDashboards returns the dashboards the last walk of the disk deleted.
+func (fr *FileReader) DeletedDashboards() []utils.ProvisionedObject {
+	fr.mutex.Lock()
+	defer fr.mutex.Unlock()
+	return append([]utils.ProvisionedObject{}, fr.deleted...)
+}
+
+// rebaseProvisionedDashboards moves the dashboards tracked by a path below resolvedPath to the same path below rootPath,
+// so dashboards provisioned before the walk kept the paths of a symlinked root aren't deleted and created again.
+func rebaseProvisionedDashboards(byPath map[string]*models.DashboardProvisioning, resolvedPath string,
+	rootPath string) map[string]*models.DashboardProvisioning {
+	if resolvedPath == rootPath {
+		return byPath
+	}
+
+	rebased := make(map[string]*models.DashboardProvisioning, len(byPath))
+	for path, provisioningData := range byPath {
+		if relPath, ok := relativePath(resolvedPath, path); ok {
+			path = filepath.Join(rootPath, relPath)
+		}
+		rebased[path] = provisioningData
+	}
+	return rebased
+}
+
+// relativePath returns the path of target relative to base if target is below base.
+func relativePath(base string, target string) (string, bool) {
+	relPath, err := filepath.Rel(base, target)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return relPath, true
 }
 
 func getProvisionedDashboardsByPath(service dashboards.DashboardProvisioningService, name string) (
@@ -263,13 +895,19 @@ func getProvisionedDashboardsByPath(service dashboards.DashboardProvisioningServ
 	return byPath, nil
 }
 
-func getOrCreateFolderID(cfg *config, service dashboards.DashboardProvisioningService, folderName string) (int64, error) {
+// getOrCreateFolderID returns the ID of the folder of a provider, creating the folder when it doesn't exist. With a
+// folderUid, the folder is looked up by that UID only, since folder names aren't unique; folderName is then just the
+// title of the folder created for it.
+func getOrCreateFolderID(ctx context.Context, cfg *config, service dashboards.DashboardProvisioningService, folderName string) (int64, error) {
+	if cfg.FolderUID != "" {
+		return getOrCreateFolderIDByUID(ctx, cfg, service, folderName)
+	}
 	if folderName == "" {
 		return 0, ErrFolderNameMissing
 	}
 
 	cmd := &models.GetDashboardQuery{Slug: models.SlugifyTitle(folderName), OrgId: cfg.OrgID}
-	err := bus.Dispatch(cmd)
+	err := bus.DispatchCtx(ctx, cmd)
 
 	if err != nil && !errors.Is(err, models.ErrDashboardNotFound) {
 		return 0, err
@@ -277,19 +915,7 @@ func getOrCreateFolderID(cfg *config, service dashboards.DashboardProvisioningSe
 
 	// dashboard folder not found. create one.
 	if errors.Is(err, models.ErrDashboardNotFound) {
-		dash := &dashboards.SaveDashboardDTO{}
-		dash.Dashboard = models.NewDashboardFolder(folderName)
-		dash.Dashboard.IsFolder = true
-		dash.Overwrite = true
-		dash.OrgId = cfg.OrgID
-		// set dashboard folderUid if given
-		dash.Dashboard.SetUid(cfg.FolderUID)
-		dbDash, err := service.SaveFolderForProvisionedDashboards(dash)
-		if err != nil {
-			return 0, err
-		}
-
-		return dbDash.Id, nil
+		return createFolder(cfg, service, folderName)
 	}
 
 	if !cmd.Result.IsFolder {
@@ -299,6 +925,150 @@ func getOrCreateFolderID(cfg *config, service dashboards.DashboardProvisioningSe
 	return cmd.Result.Id, nil
 }
 
+// getOrCreateFolderIDByUID returns the ID of the folder with the folderUid of cfg. A missing folder is created with
+// folderName as its title, unless the provider disables folder creation or has no folder name.
+func getOrCreateFolderIDByUID(ctx context.Context, cfg *config, service dashboards.DashboardProvisioningService, folderName string) (int64, error) {
+	cmd := &models.GetDashboardQuery{Uid: cfg.FolderUID, OrgId: cfg.OrgID}
+	err := bus.DispatchCtx(ctx, cmd)
+	if err != nil && !errors.Is(err, models.ErrDashboardNotFound) {
+		return 0, err
+	}
+	if err == nil {
+		if !cmd.Result.IsFolder {
+			return 0, utils.InvalidConfig(fmt.Errorf("folderUid %q of provider %q belongs to a dashboard, not a folder",
+				cfg.FolderUID, cfg.Name))
+		}
+		return cmd.Result.Id, nil
+	}
+
+	switch {
+	case cfg.DisableFolderCreation:
+		return 0, utils.InvalidConfig(fmt.Errorf("%w: no folder of org %d has the folderUid %q of provider %q",
+			ErrFolderUIDNotFound, cfg.OrgID, cfg.FolderUID, cfg.Name))
+	case folderName == "":
+		return 0, utils.InvalidConfig(fmt.Errorf("%w: no folder of org %d has the folderUid %q of provider %q, set folder to create it",
+			ErrFolderUIDNotFound, cfg.OrgID, cfg.FolderUID, cfg.Name))
+	}
+	return createFolder(cfg, service, folderName)
+}
+
+// createFolder creates the folder of a provider, with its folderUid if it has one.
+func createFolder(cfg *config, service dashboards.DashboardProvisioningService, folderName string) (int64, error) {
+	dash := &dashboards.SaveDashboardDTO{}
+	dash.Dashboard = models.NewDashboardFolder(folderName)
+	dash.Dashboard.IsFolder = true
+	dash.Overwrite = true
+	dash.OrgId = cfg.OrgID
+	// set dashboard folderUid if given
+	dash.Dashboard.SetUid(cfg.FolderUID)
+	dbDash, err := service.SaveFolderForProvisionedDashboards(dash)
+	if err != nil {
+		return 0, err
+	}
+
+	return dbDash.Id, nil
+}
+
+// fileFolderName returns the name of the folder the dashboard file at path is provisioned to when folders are taken
+// from the file system, or an empty name for files in rootPath or outside of it. Grafana folders can't be nested, so with
+// foldersFromFilesPath the directories below rootPath make up the name, like team-a/service-x.
+func (fr *FileReader) fileFolderName(path string, rootPath string) string {
+	dashboardsFolder := filepath.Dir(path)
+	if dashboardsFolder == rootPath {
+		return ""
+	}
+	if !fr.FoldersFromFilesPath {
+		return filepath.Base(dashboardsFolder)
+	}
+
+	relPath, ok := relativePath(rootPath, dashboardsFolder)
+	if !ok {
+		return ""
+	}
+	return filepath.ToSlash(relPath)
+}
+
+// pathFolderUID derives the UID of a folder created for foldersFromFilesPath from the provider and the folder name,
+// so every run finds the folder it created before.
+func pathFolderUID(providerName string, folderName string) string {
+	hash := sha256.Sum256([]byte(providerName + "\x00" + folderName))
+	return "path-" + hex.EncodeToString(hash[:])[:pathFolderUIDHashLength]
+}
+
+// fileDashboardUID derives the uid of a dashboard without one from the provider and the path of its file relative to
+// the path of the provider. Files are tracked by their path below the unresolved path, so a symlinked path keeps the
+// uids when its target is swapped.
+func (fr *FileReader) fileDashboardUID(path string) string {
+	relPath, ok := relativePath(fr.rootPath(), path)
+	if !ok {
+		relPath = path
+	}
+	hash := sha256.Sum256([]byte(fr.Cfg.Name + "\x00" + filepath.ToSlash(relPath)))
+	return "file-" + hex.EncodeToString(hash[:])[:pathFolderUIDHashLength]
+}
+
+// getOrCreatePathFolderID returns the ID of a folder of foldersFromFilesPath, creating it when needed. The folder
+// is looked up by its UID first, so a folder renamed in the UI keeps getting the dashboards of its directory
+// instead of being created again.
+func getOrCreatePathFolderID(ctx context.Context, cfg *config, service dashboards.DashboardProvisioningService, folderName string) (int64, error) {
+	folderCfg := *cfg
+	folderCfg.FolderUID = pathFolderUID(cfg.Name, folderName)
+	folderCfg.DisableFolderCreation = false
+	return getOrCreateFolderID(ctx, &folderCfg, service, folderName)
+}
+
+// deleteOrphanedPathFolders deletes the folders of foldersFromFilesPath whose directories no longer have any
+// dashboard files. Only folders that are empty once their provisioned dashboards are gone are deleted, so
+// dashboards saved to them from the UI keep them around.
+func (fr *FileReader) deleteOrphanedPathFolders(ctx context.Context, provisionedDashboardRefs map[string]*models.DashboardProvisioning,
+	filesFoundOnDisk map[string]os.FileInfo, rootPath string) {
+	current := map[string]bool{}
+	for path := range filesFoundOnDisk {
+		current[fr.fileFolderName(path, rootPath)] = true
+	}
+
+	orphans := map[string]bool{}
+	for path := range provisionedDashboardRefs {
+		if folderName := fr.fileFolderName(path, rootPath); folderName != "" && !current[folderName] {
+			orphans[folderName] = true
+		}
+	}
+
+	for folderName := range orphans {
+		if err := deletePathFolderIfEmpty(ctx, fr.Cfg, folderName); err != nil {
+			fr.log.Warn("Failed to delete orphaned folder", "folder", folderName, "error", err)
+		}
+	}
+}
+
+func deletePathFolderIfEmpty(ctx context.Context, cfg *config, folderName string) error {
+	query := &models.GetDashboardQuery{Uid: pathFolderUID(cfg.Name, folderName), OrgId: cfg.OrgID}
+	if err := bus.DispatchCtx(ctx, query); err != nil {
+		if errors.Is(err, models.ErrDashboardNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !query.Result.IsFolder {
+		return nil
+	}
+
+	dashboardsInFolder := &search.FindPersistedDashboardsQuery{
+		OrgId:        cfg.OrgID,
+		SignedInUser: &models.SignedInUser{OrgId: cfg.OrgID, OrgRole: models.ROLE_ADMIN},
+		FolderIds:    []int64{query.Result.Id},
+		Limit:        1,
+	}
+	if err := bus.DispatchCtx(ctx, dashboardsInFolder); err != nil {
+		return err
+	}
+	if len(dashboardsInFolder.Result) > 0 {
+		return nil
+	}
+
+	return bus.DispatchCtx(ctx, &models.DeleteDashboardCommand{Id: query.Result.Id, OrgId: cfg.OrgID})
+}
+
 func resolveSymlink(fileinfo os.FileInfo, path string) (os.FileInfo, error) {
 	checkFilepath, err := filepath.EvalSymlinks(path)
 	if path != checkFilepath {
@@ -313,6 +1083,55 @@ func resolveSymlink(fileinfo os.FileInfo, path string) (os.FileInfo, error) {
 	return fileinfo, err
 }
 
+// walkDashboardFiles stores the dashboard files found in resolvedPath in filesOnDisk by their path below rootPath.
+// Since the root is resolved again on every walk, a repointed symlink, like the `..data` directory of a Kubernetes
+// ConfigMap, is picked up without changing the paths the dashboards are tracked by. Symlinked directories below the
+// root are followed one level deep, so symlinks pointing at each other or at a parent can't make the walk loop.
+func (fr *FileReader) walkDashboardFiles(rootPath string, resolvedPath string, filesOnDisk map[string]os.FileInfo) error {
+	return fr.walkDashboardDir(rootPath, resolvedPath, true, filesOnDisk)
+}
+
+func (fr *FileReader) walkDashboardDir(logicalDir string, dir string, followSymlinks bool, filesOnDisk map[string]os.FileInfo) error {
+	walkFn := createWalkFn(filesOnDisk)
+	return filepath.Walk(dir, func(path string, fileInfo os.FileInfo, err error) error {
+		logicalPath := logicalDir
+		if relPath, ok := relativePath(dir, path); ok && relPath != "." {
+			logicalPath = filepath.Join(logicalDir, relPath)
+		}
+
+		if err == nil && fileInfo.Mode()&os.ModeSymlink != 0 {
+			if targetInfo, statErr := os.Stat(path); statErr == nil && targetInfo.IsDir() {
+				return fr.walkSymlinkedDir(logicalPath, path, followSymlinks, filesOnDisk)
+			}
+		}
+
+		return walkFn(logicalPath, fileInfo, err)
+	})
+}
+
+// walkSymlinkedDir walks the directory path links to, unless it's hidden, nested in another symlinked directory or
+// one of the directories containing the symlink.
+func (fr *FileReader) walkSymlinkedDir(logicalPath string, path string, followSymlinks bool, filesOnDisk map[string]os.FileInfo) error {
+	if strings.HasPrefix(filepath.Base(path), ".") {
+		return nil
+	}
+	if !followSymlinks {
+		fr.log.Debug("skipping nested symlinked directory", "path", logicalPath)
+		return nil
+	}
+
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	if _, ok := relativePath(target, filepath.Dir(path)); ok {
+		fr.log.Warn("skipping symlinked directory pointing at a parent directory", "path", logicalPath, "target", target)
+		return nil
+	}
+
+	return fr.walkDashboardDir(logicalPath, target, false, filesOnDisk)
+}
+
 func createWalkFn(filesOnDisk map[string]os.FileInfo) filepath.WalkFunc {
 	return func(path string, fileInfo os.FileInfo, err error) error {
 		if err != nil {
@@ -350,12 +1169,13 @@ type dashboardJSONFile struct {
 	lastModified time.Time
 }
 
-func (fr *FileReader) readDashboardFromFile(path string, lastModified time.Time, folderID int64) (*dashboardJSONFile, error) {
+// readDashboardFile returns the content of the dashboard file at path and its checksum.
+func (fr *FileReader) readDashboardFile(path string) ([]byte, string, error) {
 	// nolint:gosec
 	// We can ignore the gosec G304 warning on this one because `path` comes from the provisioning configuration file.
 	reader, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer func() {
 		if err := reader.Close(); err != nil {
@@ -365,17 +1185,21 @@ func (fr *FileReader) readDashboardFromFile(path string, lastModified time.Time,
 
 	all, err := ioutil.ReadAll(reader)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	checkSum, err := util.Md5SumString(string(all))
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
+	return all, checkSum, nil
+}
 
+func (fr *FileReader) parseDashboardFile(path string, all []byte, checkSum string, lastModified time.Time,
+	folderID int64) (*dashboardJSONFile, error) {
 	data, err := simplejson.NewJson(all)
 	if err != nil {
-		return nil, err
+		return nil, utils.NewJSONFileError("dashboards", path, all, err)
 	}
 
 	dash, err := createDashboardJSON(data, lastModified, fr.Cfg, folderID)
@@ -390,6 +1214,16 @@ func (fr *FileReader) readDashboardFromFile(path string, lastModified time.Time,
 	}, nil
 }
 
+// rootPath returns the absolute path of the provider without resolving symlinks. Dashboards are tracked by their
+// path below it, so they keep their identity when a symlink in the path is repointed.
+func (fr *FileReader) rootPath() string {
+	path, err := filepath.Abs(fr.Path)
+	if err != nil {
+		return fr.Path
+	}
+	return path
+}
+
 func (fr *FileReader) resolvedPath() string {
 	if _, err := os.Stat(fr.Path); os.IsNotExist(err) {
 		fr.log.Error("Cannot read directory", "error", err)
@@ -415,6 +1249,11 @@ func (fr *FileReader) resolvedPath() string {
 type provisioningMetadata struct {
 	uid      string
 	identity dashboardIdentity
+	file     string
+	links    []dashboardLink
+	// danglingRefs are the references to datasources that don't exist of a dashboard that was saved, or that wasn't
+	// because of them.
+	danglingRefs []utils.DanglingReference
 }
 
 type dashboardIdentity struct {
@@ -426,8 +1265,8 @@ func (d *dashboardIdentity) Exists() bool {
 	return len(d.title) > 0 && d.folderID > 0
 }
 
-func newProvisioningSanityChecker(provisioningProvider string) provisioningSanityChecker {
-	return provisioningSanityChecker{
+func newProvisioningSanityChecker(provisioningProvider string) *provisioningSanityChecker {
+	return &provisioningSanityChecker{
 		provisioningProvider: provisioningProvider,
 		uidUsage:             map[string]uint8{},
 		titleUsage:           map[dashboardIdentity]uint8{},
@@ -435,21 +1274,63 @@ func newProvisioningSanityChecker(provisioningProvider string) provisioningSanit
 }
 
 type provisioningSanityChecker struct {
+	mutex                sync.Mutex
 	provisioningProvider string
 	uidUsage             map[string]uint8
 	titleUsage           map[dashboardIdentity]uint8
+	dashboards           []provisioningMetadata
+	dangling             []utils.DanglingReference
 }
 
-func (checker provisioningSanityChecker) track(pm provisioningMetadata) {
+func (checker *provisioningSanityChecker) track(pm provisioningMetadata) {
+	checker.mutex.Lock()
+	defer checker.mutex.Unlock()
+
 	if len(pm.uid) > 0 {
 		checker.uidUsage[pm.uid]++
 	}
 	if pm.identity.Exists() {
 		checker.titleUsage[pm.identity]++
 	}
+	if len(pm.links) > 0 {
+		checker.dashboards = append(checker.dashboards, pm)
+	}
+	checker.dangling = append(checker.dangling, pm.danglingRefs...)
+}
+
+// danglingReferences returns the dangling datasource references of the tracked dashboards.
+func (checker *provisioningSanityChecker) danglingReferences() []utils.DanglingReference {
+	checker.mutex.Lock()
+	defer checker.mutex.Unlock()
+	return checker.dangling
 }
 
-func (checker provisioningSanityChecker) logWarnings(log log.Logger) {
+// brokenLinks returns the links of the tracked dashboards whose target doesn't resolve.
+func (checker *provisioningSanityChecker) brokenLinks(dashboardExists func(uid string) bool) []BrokenLink {
+	checker.mutex.Lock()
+	defer checker.mutex.Unlock()
+
+	var broken []BrokenLink
+	for _, pm := range checker.dashboards {
+		for _, link := range pm.links {
+			if reason := checkLink(link, dashboardExists); reason != "" {
+				broken = append(broken, BrokenLink{
+					File:         pm.file,
+					DashboardUID: pm.uid,
+					Location:     link.location,
+					URL:          link.url,
+					Reason:       reason,
+				})
+			}
+		}
+	}
+	return broken
+}
+
+func (checker *provisioningSanityChecker) logWarnings(log log.Logger) {
+	checker.mutex.Lock()
+	defer checker.mutex.Unlock()
+
 	for uid, times := range checker.uidUsage {
 		if times > 1 {
 			log.Error("the same 'uid' is used more than once", "uid", uid, "provider", checker.provisioningProvider)
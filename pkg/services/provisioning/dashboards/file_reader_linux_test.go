@@ -3,10 +3,15 @@
 package dashboards
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/dashboards"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -33,3 +38,131 @@ func TestProvisionedSymlinkedFolder(t *testing.T) {
 	resolvedPath := reader.resolvedPath()
 	assert.Equal(t, want, resolvedPath)
 }
+
+func TestProvisionerRootPathOfSymlinkedFolder(t *testing.T) {
+	reader, err := NewDashboardFileReader(&config{Name: "Default", Type: "file", OrgID: 1,
+		Options: map[string]interface{}{"path": symlinkedFolder}}, log.New("test-logger"), nil)
+	require.NoError(t, err)
+	provisioner := &Provisioner{log: log.New("test-logger"), fileReaders: []*FileReader{reader}}
+
+	// The external ids are under the symlink, so relative paths have to be computed from it.
+	want, err := filepath.Abs(symlinkedFolder)
+	require.NoError(t, err)
+	assert.Equal(t, want, provisioner.GetProvisionerRootPath("Default"))
+	assert.Empty(t, provisioner.GetProvisionerRootPath("unknown"))
+}
+
+func TestProvisionedConfigMapSwap(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+	})
+	fakeService = mockDashboardProvisioningService()
+
+	// Mimic the layout of a mounted Kubernetes ConfigMap, where `..data` points at the current version of the
+	// files and is swapped atomically on every update.
+	dir := t.TempDir()
+	writeConfigMapVersion := func(version string, title string) {
+		t.Helper()
+		teamDir := filepath.Join(dir, version, "team")
+		require.NoError(t, os.MkdirAll(teamDir, 0750))
+		dashboard := []byte(`{"uid": "configmap", "title": "` + title + `"}`)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(teamDir, "dashboard.json"), dashboard, 0600))
+	}
+	swapConfigMapData := func(version string) {
+		t.Helper()
+		require.NoError(t, os.Symlink(version, filepath.Join(dir, "..data_tmp")))
+		require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+	}
+
+	writeConfigMapVersion("..v1", "First")
+	swapConfigMapData("..v1")
+	require.NoError(t, os.Symlink(filepath.Join("..data", "team"), filepath.Join(dir, "team")))
+	// A symlink to the directory itself must not make the walk loop.
+	require.NoError(t, os.Symlink(".", filepath.Join(dir, "loop")))
+
+	cfg := &config{
+		Name:    "Default",
+		Type:    "file",
+		OrgID:   1,
+		Options: map[string]interface{}{"path": dir},
+	}
+	reader, err := NewDashboardFileReader(cfg, log.New("test-logger"), nil)
+	require.NoError(t, err)
+
+	require.NoError(t, reader.walkDisk(context.Background()))
+	require.Len(t, fakeService.inserted, 1)
+	assert.Equal(t, "First", fakeService.inserted[0].Dashboard.Title)
+
+	externalID := filepath.Join(dir, "team", "dashboard.json")
+	require.Len(t, fakeService.provisioned["Default"], 1)
+	assert.Equal(t, externalID, fakeService.provisioned["Default"][0].ExternalId)
+
+	writeConfigMapVersion("..v2", "Second")
+	swapConfigMapData("..v2")
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "..v1")))
+
+	require.NoError(t, reader.walkDisk(context.Background()))
+	require.Len(t, fakeService.inserted, 1)
+	assert.Equal(t, "Second", fakeService.inserted[0].Dashboard.Title)
+	require.Len(t, fakeService.provisioned["Default"], 1)
+	assert.Equal(t, externalID, fakeService.provisioned["Default"][0].ExternalId)
+}
+
+func TestProvisionedSymlinkedFolderKeepsDashboardsTrackedByResolvedPath(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+	})
+	fakeService = mockDashboardProvisioningService()
+
+	resolvedPath, err := filepath.Abs(filepath.Join(containingID, "dashboard1.json"))
+	require.NoError(t, err)
+	fakeService.provisioned["Default"] = []*models.DashboardProvisioning{
+		{DashboardId: 1, Name: "Default", ExternalId: resolvedPath},
+	}
+
+	cfg := &config{
+		Name:    "Default",
+		Type:    "file",
+		OrgID:   1,
+		Options: map[string]interface{}{"path": symlinkedFolder},
+	}
+	reader, err := NewDashboardFileReader(cfg, log.New("test-logger"), nil)
+	require.NoError(t, err)
+
+	require.NoError(t, reader.walkDisk(context.Background()))
+
+	want, err := filepath.Abs(filepath.Join(symlinkedFolder, "dashboard1.json"))
+	require.NoError(t, err)
+	require.Len(t, fakeService.provisioned["Default"], 1)
+	assert.Equal(t, int64(1), fakeService.inserted[0].Dashboard.Id)
+	assert.Equal(t, want, fakeService.provisioned["Default"][0].ExternalId)
+}
+
+func TestDashboardsWithoutUIDInSymlinkedFolder(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+	})
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "v1"), 0750))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "v1", "dashboard.json"), []byte(`{"title": "No uid"}`), 0600))
+	require.NoError(t, os.Symlink("v1", filepath.Join(dir, "current")))
+	walkUID := func(path string) string {
+		t.Helper()
+		fakeService = mockDashboardProvisioningService()
+		cfg := &config{Name: "Default", Type: "file", OrgID: 1, Options: map[string]interface{}{"path": path}}
+		reader, err := NewDashboardFileReader(cfg, log.New("test-logger"), nil)
+		require.NoError(t, err)
+		require.NoError(t, reader.walkDisk(context.Background()))
+		require.Len(t, fakeService.inserted, 1)
+		return fakeService.inserted[0].Dashboard.Uid
+	}
+
+	uid := walkUID(filepath.Join(dir, "v1"))
+	require.NotEmpty(t, uid)
+	assert.Equal(t, uid, walkUID(filepath.Join(dir, "current")),
+		"The uid should be derived from the path of the file below the provider's path, not the resolved one")
+}
@@ -1,22 +1,32 @@
 package dashboards
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/components/simplejson"
 	dboards "github.com/grafana/grafana/pkg/dashboards"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/services/search"
+	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util"
 
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
 	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -26,6 +36,8 @@ const (
 	containingID              = "testdata/test-dashboards/containing-id"
 	unprovision               = "testdata/test-dashboards/unprovision"
 	foldersFromFilesStructure = "testdata/test-dashboards/folders-from-files-structure"
+	foldersFromFilesPath      = "testdata/test-dashboards/folders-from-files-path"
+	localizedDashboards       = "testdata/test-dashboards/localized"
 )
 
 var fakeService *fakeDashboardProvisioningService
@@ -115,7 +127,7 @@ func TestDashboardFileReader(t *testing.T) {
 				reader, err := NewDashboardFileReader(cfg, logger, nil)
 				So(err, ShouldBeNil)
 
-				err = reader.walkDisk()
+				err = reader.walkDisk(context.Background())
 				So(err, ShouldBeNil)
 
 				folders := 0
@@ -146,7 +158,7 @@ func TestDashboardFileReader(t *testing.T) {
 				reader, err := NewDashboardFileReader(cfg, logger, nil)
 				So(err, ShouldBeNil)
 
-				err = reader.walkDisk()
+				err = reader.walkDisk(context.Background())
 				So(err, ShouldBeNil)
 
 				So(len(fakeService.inserted), ShouldEqual, 1)
@@ -181,7 +193,7 @@ func TestDashboardFileReader(t *testing.T) {
 				reader, err := NewDashboardFileReader(cfg, logger, nil)
 				So(err, ShouldBeNil)
 
-				err = reader.walkDisk()
+				err = reader.walkDisk(context.Background())
 				So(err, ShouldBeNil)
 				So(len(fakeService.inserted), ShouldEqual, 0)
 			})
@@ -207,7 +219,7 @@ func TestDashboardFileReader(t *testing.T) {
 				reader, err := NewDashboardFileReader(cfg, logger, nil)
 				So(err, ShouldBeNil)
 
-				err = reader.walkDisk()
+				err = reader.walkDisk(context.Background())
 				So(err, ShouldBeNil)
 				So(len(fakeService.inserted), ShouldEqual, 1)
 			})
@@ -241,7 +253,7 @@ func TestDashboardFileReader(t *testing.T) {
 				reader, err := NewDashboardFileReader(cfg, logger, nil)
 				So(err, ShouldBeNil)
 
-				err = reader.walkDisk()
+				err = reader.walkDisk(context.Background())
 				So(err, ShouldBeNil)
 				So(len(fakeService.inserted), ShouldEqual, 0)
 			})
@@ -267,18 +279,76 @@ func TestDashboardFileReader(t *testing.T) {
 				reader, err := NewDashboardFileReader(cfg, logger, nil)
 				So(err, ShouldBeNil)
 
-				err = reader.walkDisk()
+				err = reader.walkDisk(context.Background())
 				So(err, ShouldBeNil)
 				So(len(fakeService.inserted), ShouldEqual, 1)
 			})
 
+			Convey("Manual changes of a provisioned dashboard are reported before they're overwritten", func() {
+				cfg.Options["path"] = oneDashboard
+				absPath, err := filepath.Abs(oneDashboard + "/dashboard1.json")
+				So(err, ShouldBeNil)
+
+				saved := simplejson.NewFromAny(map[string]interface{}{"id": 42, "version": 3, "title": "Grafana"})
+				savedCheckSum, err := models.DashboardCheckSum(saved)
+				So(err, ShouldBeNil)
+				fakeService.provisioned = map[string][]*models.DashboardProvisioning{
+					"Default": {
+						{
+							Name:              "Default",
+							DashboardId:       42,
+							ExternalId:        absPath,
+							CheckSum:          "fakechecksum",
+							DashboardCheckSum: savedCheckSum,
+						},
+					},
+				}
+
+				current := models.NewDashboardFromJson(saved)
+				current.Id, current.Uid, current.OrgId, current.Version, current.UpdatedBy = 42, "grafana", 1, 4, 7
+				bus.AddHandler("test", func(query *models.GetDashboardQuery) error {
+					query.Result = current
+					return nil
+				})
+				bus.AddHandler("test", func(query *models.GetUserByIdQuery) error {
+					query.Result = &models.User{Id: query.Id, Login: "editor"}
+					return nil
+				})
+
+				var drifts []DashboardDrift
+				newReader := func() *FileReader {
+					reader, err := NewDashboardFileReader(cfg, logger, nil)
+					So(err, ShouldBeNil)
+					reader.onDrift = func(drift DashboardDrift) { drifts = append(drifts, drift) }
+					return reader
+				}
+
+				Convey("Unchanged dashboards aren't reported", func() {
+					So(newReader().walkDisk(context.Background()), ShouldBeNil)
+					So(len(fakeService.inserted), ShouldEqual, 1)
+					So(drifts, ShouldBeEmpty)
+				})
+
+				Convey("Changed dashboards are reported", func() {
+					current.Data = simplejson.NewFromAny(map[string]interface{}{"id": 42, "version": 4, "title": "Edited"})
+					So(newReader().walkDisk(context.Background()), ShouldBeNil)
+					So(len(fakeService.inserted), ShouldEqual, 1)
+					So(drifts, ShouldHaveLength, 1)
+					So(drifts[0].UID, ShouldEqual, "grafana")
+					So(drifts[0].Provider, ShouldEqual, "Default")
+					So(drifts[0].File, ShouldEqual, absPath)
+					So(drifts[0].Version, ShouldEqual, 4)
+					So(drifts[0].UpdatedBy, ShouldEqual, "editor")
+				})
+			})
+
 			Convey("Overrides id from dashboard.json files", func() {
 				cfg.Options["path"] = containingID
 
 				reader, err := NewDashboardFileReader(cfg, logger, nil)
 				So(err, ShouldBeNil)
 
-				err = reader.walkDisk()
+				err = reader.walkDisk(context.Background())
 				So(err, ShouldBeNil)
 
 				So(len(fakeService.inserted), ShouldEqual, 1)
@@ -291,7 +361,7 @@ func TestDashboardFileReader(t *testing.T) {
 				reader, err := NewDashboardFileReader(cfg, logger, nil)
 				So(err, ShouldBeNil)
 
-				err = reader.walkDisk()
+				err = reader.walkDisk(context.Background())
 				So(err, ShouldBeNil)
 
 				So(len(fakeService.inserted), ShouldEqual, 5)
@@ -350,13 +420,13 @@ func TestDashboardFileReader(t *testing.T) {
 				reader1, err := NewDashboardFileReader(cfg1, logger, nil)
 				So(err, ShouldBeNil)
 
-				err = reader1.walkDisk()
+				err = reader1.walkDisk(context.Background())
 				So(err, ShouldBeNil)
 
 				reader2, err := NewDashboardFileReader(cfg2, logger, nil)
 				So(err, ShouldBeNil)
 
-				err = reader2.walkDisk()
+				err = reader2.walkDisk(context.Background())
 				So(err, ShouldBeNil)
 
 				var folderCount int
@@ -385,7 +455,7 @@ func TestDashboardFileReader(t *testing.T) {
 				},
 			}
 
-			_, err := getOrCreateFolderID(cfg, fakeService, cfg.Folder)
+			_, err := getOrCreateFolderID(context.Background(), cfg, fakeService, cfg.Folder)
 			So(err, ShouldEqual, ErrFolderNameMissing)
 		})
 
@@ -400,7 +470,7 @@ func TestDashboardFileReader(t *testing.T) {
 				},
 			}
 
-			folderID, err := getOrCreateFolderID(cfg, fakeService, cfg.Folder)
+			folderID, err := getOrCreateFolderID(context.Background(), cfg, fakeService, cfg.Folder)
 			So(err, ShouldBeNil)
 			inserted := false
 			for _, d := range fakeService.inserted {
@@ -460,7 +530,7 @@ func TestDashboardFileReader(t *testing.T) {
 				reader, err := NewDashboardFileReader(cfg, logger, nil)
 				So(err, ShouldBeNil)
 
-				err = reader.walkDisk()
+				err = reader.walkDisk(context.Background())
 				So(err, ShouldBeNil)
 
 				So(len(fakeService.provisioned["Default"]), ShouldEqual, 1)
@@ -471,7 +541,7 @@ func TestDashboardFileReader(t *testing.T) {
 				reader, err := NewDashboardFileReader(cfg, logger, nil)
 				So(err, ShouldBeNil)
 
-				err = reader.walkDisk()
+				err = reader.walkDisk(context.Background())
 				So(err, ShouldBeNil)
 
 				So(len(fakeService.provisioned["Default"]), ShouldEqual, 1)
@@ -483,6 +553,668 @@ func TestDashboardFileReader(t *testing.T) {
 	})
 }
 
+func TestLocalizedDashboards(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+	})
+
+	provisionWithLocale := func(t *testing.T, locale string) map[string]string {
+		t.Helper()
+
+		fakeService = mockDashboardProvisioningService()
+		cfg := &config{
+			Name:    "Default",
+			Type:    "file",
+			OrgID:   1,
+			Options: map[string]interface{}{"path": localizedDashboards},
+		}
+
+		reader, err := NewDashboardFileReader(cfg, log.New("test-logger"), nil)
+		require.NoError(t, err)
+		reader.Locale = locale
+
+		err = reader.walkDisk(context.Background())
+		require.NoError(t, err)
+
+		titles := map[string]string{}
+		for _, d := range fakeService.inserted {
+			titles[d.Dashboard.Uid] = d.Dashboard.Title
+		}
+		return titles
+	}
+
+	t.Run("Should provision the variant for the configured locale", func(t *testing.T) {
+		titles := provisionWithLocale(t, "de")
+		require.Equal(t, map[string]string{"overview": "Übersicht", "details": "Details"}, titles)
+	})
+
+	t.Run("Should fall back to the default dashboards for a locale without variants", func(t *testing.T) {
+		titles := provisionWithLocale(t, "fr")
+		require.Equal(t, map[string]string{"overview": "Overview", "details": "Details"}, titles)
+	})
+
+	t.Run("Should not provision the variants as separate dashboards without a locale", func(t *testing.T) {
+		titles := provisionWithLocale(t, "")
+		require.Len(t, fakeService.inserted, 2)
+		require.Equal(t, map[string]string{"overview": "Overview", "details": "Details"}, titles)
+	})
+
+	t.Run("Should track the variant by the default file path", func(t *testing.T) {
+		provisionWithLocale(t, "de")
+
+		absPath, err := filepath.Abs(filepath.Join(localizedDashboards, "overview.json"))
+		require.NoError(t, err)
+
+		var externalIDs []string
+		for _, p := range fakeService.provisioned["Default"] {
+			externalIDs = append(externalIDs, p.ExternalId)
+		}
+		require.Contains(t, externalIDs, absPath)
+	})
+}
+
+func TestFoldersFromFilesPath(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+		bus.ClearBusHandlers()
+	})
+
+	var deleted []int64
+	var folderContents map[int64]int
+	setup := func(t *testing.T) *FileReader {
+		t.Helper()
+
+		fakeService = mockDashboardProvisioningService()
+		deleted = nil
+		folderContents = map[int64]int{}
+		bus.ClearBusHandlers()
+		bus.AddHandler("test", func(query *models.GetDashboardQuery) error {
+			for _, d := range fakeService.getDashboard {
+				if (query.Uid != "" && d.Uid == query.Uid) || (query.Slug != "" && d.Slug == query.Slug) {
+					query.Result = d
+					return nil
+				}
+			}
+			return models.ErrDashboardNotFound
+		})
+		bus.AddHandler("test", func(query *search.FindPersistedDashboardsQuery) error {
+			query.Result = search.HitList{}
+			for i := 0; i < folderContents[query.FolderIds[0]]; i++ {
+				query.Result = append(query.Result, &search.Hit{})
+			}
+			return nil
+		})
+		bus.AddHandler("test", func(cmd *models.DeleteDashboardCommand) error {
+			deleted = append(deleted, cmd.Id)
+			return nil
+		})
+
+		cfg := &config{
+			Name:    "Default",
+			Type:    "file",
+			OrgID:   1,
+			Options: map[string]interface{}{"path": foldersFromFilesPath, "foldersFromFilesPath": true},
+		}
+		reader, err := NewDashboardFileReader(cfg, log.New("test-logger"), nil)
+		require.NoError(t, err)
+		return reader
+	}
+
+	dashboardFolders := func() (map[string]*models.Dashboard, map[string]int64) {
+		folders := map[string]*models.Dashboard{}
+		folderIDs := map[string]int64{}
+		for _, d := range fakeService.inserted {
+			if d.Dashboard.IsFolder {
+				folders[d.Dashboard.Title] = d.Dashboard
+			} else {
+				folderIDs[d.Dashboard.Title] = d.Dashboard.FolderId
+			}
+		}
+		return folders, folderIDs
+	}
+
+	t.Run("Should create a folder for every directory path", func(t *testing.T) {
+		reader := setup(t)
+		require.NoError(t, reader.walkDisk(context.Background()))
+
+		folders, folderIDs := dashboardFolders()
+		require.Len(t, folders, 3)
+		for _, name := range []string{"team-a", "team-a/service-x", "team-b/service-x"} {
+			require.Contains(t, folders, name)
+			require.Equal(t, pathFolderUID("Default", name), folders[name].Uid)
+		}
+		require.Equal(t, map[string]int64{
+			"Root":             0,
+			"Team A Overview":  folders["team-a"].Id,
+			"Team A Service X": folders["team-a/service-x"].Id,
+			"Team B Service X": folders["team-b/service-x"].Id,
+		}, folderIDs)
+	})
+
+	t.Run("Should keep using a folder renamed in the UI", func(t *testing.T) {
+		reader := setup(t)
+		renamed := &models.Dashboard{Id: 42, Uid: pathFolderUID("Default", "team-a"), Title: "Renamed", Slug: "renamed",
+			IsFolder: true}
+		fakeService.getDashboard = []*models.Dashboard{renamed}
+		require.NoError(t, reader.walkDisk(context.Background()))
+
+		folders, folderIDs := dashboardFolders()
+		require.NotContains(t, folders, "team-a")
+		require.Equal(t, int64(42), folderIDs["Team A Overview"])
+	})
+
+	t.Run("Should delete the empty folders of removed directories", func(t *testing.T) {
+		reader := setup(t)
+		absPath, err := filepath.Abs(foldersFromFilesPath)
+		require.NoError(t, err)
+		fakeService.provisioned = map[string][]*models.DashboardProvisioning{
+			"Default": {
+				{Name: "Default", DashboardId: 1, ExternalId: filepath.Join(absPath, "team-c", "gone.json")},
+				{Name: "Default", DashboardId: 2, ExternalId: filepath.Join(absPath, "team-d", "gone.json")},
+				{Name: "Default", DashboardId: 3, ExternalId: filepath.Join(absPath, "team-e", "gone.json")},
+			},
+		}
+		fakeService.getDashboard = []*models.Dashboard{
+			{Id: 43, Uid: pathFolderUID("Default", "team-c"), Title: "team-c", Slug: "team-c", IsFolder: true},
+			{Id: 44, Uid: pathFolderUID("Default", "team-d"), Title: "team-d", Slug: "team-d", IsFolder: true},
+		}
+		folderContents[44] = 1
+
+		require.NoError(t, reader.walkDisk(context.Background()))
+		require.Equal(t, []int64{43}, deleted, "Folders that still hold dashboards should be kept")
+	})
+
+	t.Run("Should keep the folders of removed directories when deletion is disabled", func(t *testing.T) {
+		reader := setup(t)
+		reader.Cfg.DisableDeletion = true
+		absPath, err := filepath.Abs(foldersFromFilesPath)
+		require.NoError(t, err)
+		fakeService.provisioned = map[string][]*models.DashboardProvisioning{
+			"Default": {{Name: "Default", DashboardId: 1, ExternalId: filepath.Join(absPath, "team-c", "gone.json")}},
+		}
+		fakeService.getDashboard = []*models.Dashboard{
+			{Id: 43, Uid: pathFolderUID("Default", "team-c"), Title: "team-c", Slug: "team-c", IsFolder: true},
+		}
+
+		require.NoError(t, reader.walkDisk(context.Background()))
+		require.Empty(t, deleted)
+	})
+
+	t.Run("Should not be combined with other folder options", func(t *testing.T) {
+		_, err := NewDashboardFileReader(&config{
+			Name:    "Default",
+			Folder:  "Team",
+			Options: map[string]interface{}{"path": foldersFromFilesPath, "foldersFromFilesPath": true},
+		}, log.New("test-logger"), nil)
+		require.EqualError(t, err, "'folder' and 'folderUID' should be empty using 'foldersFromFilesPath' option")
+
+		_, err = NewDashboardFileReader(&config{
+			Name: "Default",
+			Options: map[string]interface{}{"path": foldersFromFilesPath, "foldersFromFilesPath": true,
+				"foldersFromFilesStructure": true},
+		}, log.New("test-logger"), nil)
+		require.EqualError(t, err, "'foldersFromFilesStructure' and 'foldersFromFilesPath' options can't be used together")
+	})
+}
+
+func TestFolderUID(t *testing.T) {
+	t.Cleanup(bus.ClearBusHandlers)
+	setup := func(t *testing.T, folderUID string) *config {
+		t.Helper()
+
+		fakeService = mockDashboardProvisioningService()
+		fakeService.getDashboard = []*models.Dashboard{
+			{Id: 10, Uid: "team-a", Title: "Shared", Slug: "shared", IsFolder: true},
+			{Id: 20, Uid: "team-b", Title: "Shared", Slug: "shared", IsFolder: true},
+			{Id: 30, Uid: "overview", Title: "Overview", Slug: "overview"},
+		}
+		bus.ClearBusHandlers()
+		bus.AddHandler("test", mockGetDashboardQuery)
+		return &config{Name: "Default", Type: "file", OrgID: 1, Folder: "Shared", FolderUID: folderUID}
+	}
+
+	t.Run("Should take the folder with the folderUid over the one with the folder name", func(t *testing.T) {
+		cfg := setup(t, "team-b")
+
+		folderID, err := getOrCreateFolderID(context.Background(), cfg, fakeService, cfg.Folder)
+		require.NoError(t, err)
+		require.Equal(t, int64(20), folderID)
+		require.Empty(t, fakeService.inserted)
+	})
+
+	t.Run("Should find the folder by folderUid without a folder name", func(t *testing.T) {
+		cfg := setup(t, "team-b")
+		cfg.Folder = ""
+
+		folderID, err := getOrCreateFolderID(context.Background(), cfg, fakeService, cfg.Folder)
+		require.NoError(t, err)
+		require.Equal(t, int64(20), folderID)
+	})
+
+	t.Run("Should create a missing folder with the folderUid and the folder name", func(t *testing.T) {
+		cfg := setup(t, "team-c")
+
+		folderID, err := getOrCreateFolderID(context.Background(), cfg, fakeService, cfg.Folder)
+		require.NoError(t, err)
+		require.Len(t, fakeService.inserted, 1)
+		created := fakeService.inserted[0].Dashboard
+		require.Equal(t, folderID, created.Id)
+		require.Equal(t, "team-c", created.Uid)
+		require.Equal(t, "Shared", created.Title)
+		require.True(t, created.IsFolder)
+	})
+
+	t.Run("Should fail on a missing folder when folder creation is disabled", func(t *testing.T) {
+		cfg := setup(t, "team-c")
+		cfg.DisableFolderCreation = true
+
+		_, err := getOrCreateFolderID(context.Background(), cfg, fakeService, cfg.Folder)
+		require.True(t, errors.Is(err, ErrFolderUIDNotFound))
+		require.True(t, utils.IsInvalidConfig(err))
+		require.EqualError(t, err, `folder uid not found: no folder of org 1 has the folderUid "team-c" of provider "Default"`)
+		require.Empty(t, fakeService.inserted)
+	})
+
+	t.Run("Should fail on a missing folder without a folder name to create it with", func(t *testing.T) {
+		cfg := setup(t, "team-c")
+		cfg.Folder = ""
+
+		_, err := getOrCreateFolderID(context.Background(), cfg, fakeService, cfg.Folder)
+		require.True(t, errors.Is(err, ErrFolderUIDNotFound))
+		require.Contains(t, err.Error(), "set folder to create it")
+		require.Empty(t, fakeService.inserted)
+	})
+
+	t.Run("Should fail when the folderUid belongs to a dashboard", func(t *testing.T) {
+		cfg := setup(t, "overview")
+
+		_, err := getOrCreateFolderID(context.Background(), cfg, fakeService, cfg.Folder)
+		require.EqualError(t, err, `folderUid "overview" of provider "Default" belongs to a dashboard, not a folder`)
+		require.Empty(t, fakeService.inserted)
+	})
+}
+
+func TestDashboardsWithoutUID(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+	})
+	newReader := func(t *testing.T, name string) *FileReader {
+		t.Helper()
+
+		cfg := &config{Name: name, Type: "file", OrgID: 1, Options: map[string]interface{}{"path": containingID}}
+		reader, err := NewDashboardFileReader(cfg, log.New("test-logger"), nil)
+		require.NoError(t, err)
+		return reader
+	}
+
+	t.Run("Should update the same dashboard when provisioning a file twice", func(t *testing.T) {
+		fakeService = mockDashboardProvisioningService()
+
+		require.NoError(t, newReader(t, "Default").walkDisk(context.Background()))
+		require.Len(t, fakeService.inserted, 1)
+		first := fakeService.inserted[0].Dashboard
+		require.NotEmpty(t, first.Uid)
+		require.Nil(t, first.Data.Get("id").Interface())
+
+		// Make the file look changed, so it's saved again instead of being skipped as up to date.
+		fakeService.provisioned["Default"][0].CheckSum = "outdated"
+		require.NoError(t, newReader(t, "Default").walkDisk(context.Background()))
+		require.Len(t, fakeService.inserted, 1)
+		require.Len(t, fakeService.provisioned["Default"], 1)
+		require.Equal(t, first.Id, fakeService.inserted[0].Dashboard.Id)
+	})
+
+	t.Run("Should derive the same uid when the provisioning data is lost", func(t *testing.T) {
+		fakeService = mockDashboardProvisioningService()
+		require.NoError(t, newReader(t, "Default").walkDisk(context.Background()))
+		uid := fakeService.inserted[0].Dashboard.Uid
+
+		fakeService = mockDashboardProvisioningService()
+		require.NoError(t, newReader(t, "Default").walkDisk(context.Background()))
+		require.Equal(t, uid, fakeService.inserted[0].Dashboard.Uid)
+
+		fakeService = mockDashboardProvisioningService()
+		require.NoError(t, newReader(t, "Other").walkDisk(context.Background()))
+		require.NotEqual(t, uid, fakeService.inserted[0].Dashboard.Uid)
+	})
+
+	t.Run("Should keep the uid of a dashboard that was provisioned before", func(t *testing.T) {
+		fakeService = mockDashboardProvisioningService()
+		require.NoError(t, newReader(t, "Default").walkDisk(context.Background()))
+		fakeService.provisioned["Default"][0].CheckSum = "outdated"
+
+		require.NoError(t, newReader(t, "Default").walkDisk(context.Background()))
+		// The store keeps the uid the dashboard already has when it's saved without one.
+		require.Len(t, fakeService.inserted, 1)
+		require.Empty(t, fakeService.inserted[0].Dashboard.Uid)
+	})
+}
+
+func TestWalkDiskTracing(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+	})
+	tracer := mocktracer.New()
+	origTracer := opentracing.GlobalTracer()
+	opentracing.SetGlobalTracer(tracer)
+	t.Cleanup(func() { opentracing.SetGlobalTracer(origTracer) })
+
+	walk := func(t *testing.T, ctx context.Context) {
+		t.Helper()
+
+		fakeService = mockDashboardProvisioningService()
+		cfg := &config{
+			Name:    "Default",
+			Type:    "file",
+			OrgID:   1,
+			Options: map[string]interface{}{"path": defaultDashboards},
+		}
+		reader, err := NewDashboardFileReader(cfg, log.New("test-logger"), nil)
+		require.NoError(t, err)
+		require.NoError(t, reader.walkDisk(ctx))
+	}
+
+	t.Run("Should trace every file of a traced run", func(t *testing.T) {
+		tracer.Reset()
+		run := tracer.StartSpan("run")
+		walk(t, opentracing.ContextWithSpan(context.Background(), run))
+		run.Finish()
+
+		var walkSpan *mocktracer.MockSpan
+		var files []*mocktracer.MockSpan
+		for _, span := range tracer.FinishedSpans() {
+			switch span.OperationName {
+			case "provisioning dashboards walk":
+				walkSpan = span
+			case "provisioning dashboards file":
+				files = append(files, span)
+			}
+		}
+		require.NotNil(t, walkSpan)
+		require.Equal(t, run.Context().(mocktracer.MockSpanContext).SpanID, walkSpan.ParentID)
+		require.Equal(t, "Default", walkSpan.Tag("provider"))
+		require.Equal(t, 2, walkSpan.Tag("files"))
+		require.Len(t, files, 2)
+		for _, file := range files {
+			require.Equal(t, walkSpan.SpanContext.SpanID, file.ParentID)
+		}
+	})
+
+	t.Run("Should not start traces when polling", func(t *testing.T) {
+		tracer.Reset()
+		walk(t, context.Background())
+		require.Empty(t, tracer.FinishedSpans())
+	})
+}
+
+func TestProcessFiles(t *testing.T) {
+	files := map[string]os.FileInfo{}
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("dashboard%d.json", i)
+		files[name] = &FakeFileInfo{name: name}
+	}
+
+	t.Run("Should never exceed the configured number of workers", func(t *testing.T) {
+		reader := &FileReader{MaxConcurrency: 3}
+
+		var running, maxRunning, processed int32
+		err := reader.processFiles(context.Background(), files, func(_ context.Context, path string, fileInfo os.FileInfo) error {
+			current := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&processed, 1)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, int32(len(files)), atomic.LoadInt32(&processed))
+		require.LessOrEqual(t, atomic.LoadInt32(&maxRunning), int32(3))
+	})
+
+	t.Run("Should cancel the remaining files after the first error", func(t *testing.T) {
+		reader := &FileReader{MaxConcurrency: 1}
+		expectedErr := errors.New("test error")
+
+		var processed int32
+		err := reader.processFiles(context.Background(), files, func(_ context.Context, path string, fileInfo os.FileInfo) error {
+			atomic.AddInt32(&processed, 1)
+			return expectedErr
+		})
+		require.Equal(t, expectedErr, err)
+		require.Equal(t, int32(1), atomic.LoadInt32(&processed))
+	})
+
+	t.Run("Should stop picking up files when the context is canceled", func(t *testing.T) {
+		reader := &FileReader{MaxConcurrency: 1}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var processed int32
+		err := reader.processFiles(ctx, files, func(_ context.Context, path string, fileInfo os.FileInfo) error {
+			atomic.AddInt32(&processed, 1)
+			cancel()
+			return nil
+		})
+		require.ErrorIs(t, err, context.Canceled)
+		require.Less(t, atomic.LoadInt32(&processed), int32(len(files)))
+	})
+
+	t.Run("Should process files serially when concurrency isn't configured", func(t *testing.T) {
+		reader := &FileReader{}
+
+		var running, maxRunning int32
+		err := reader.processFiles(context.Background(), files, func(_ context.Context, path string, fileInfo os.FileInfo) error {
+			current := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+					break
+				}
+			}
+			atomic.AddInt32(&running, -1)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, int32(1), atomic.LoadInt32(&maxRunning))
+	})
+}
+
+func TestPollingStalled(t *testing.T) {
+	reader := &FileReader{Cfg: &config{UpdateIntervalSeconds: 1}, PollInterval: defaultPollInterval}
+
+	t.Run("Should not be stalled before polling started", func(t *testing.T) {
+		require.False(t, reader.pollingStalled(time.Millisecond))
+	})
+
+	t.Run("Should not be stalled within the update interval plus threshold", func(t *testing.T) {
+		atomic.StoreInt64(&reader.lastPoll, time.Now().Add(-1200*time.Millisecond).UnixNano())
+		require.False(t, reader.pollingStalled(time.Second))
+	})
+
+	t.Run("Should be stalled after the update interval plus threshold", func(t *testing.T) {
+		atomic.StoreInt64(&reader.lastPoll, time.Now().Add(-3*time.Second).UnixNano())
+		require.True(t, reader.pollingStalled(time.Second))
+	})
+
+	t.Run("Should allow for the longest jittered interval", func(t *testing.T) {
+		jittered := &FileReader{Cfg: &config{UpdateIntervalSeconds: 1}, PollInterval: defaultPollInterval, PollJitter: 50}
+		atomic.StoreInt64(&jittered.lastPoll, time.Now().Add(-2*time.Second).UnixNano())
+		require.False(t, jittered.pollingStalled(time.Second))
+	})
+}
+
+func TestPollingFailed(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+	})
+	fakeService = mockDashboardProvisioningService()
+	bus.AddHandler("test", mockGetDashboardQuery)
+	t.Cleanup(bus.ClearBusHandlers)
+
+	mount := filepath.Join(t.TempDir(), "mount")
+	require.NoError(t, os.Mkdir(mount, 0750))
+	reader, err := NewDashboardFileReader(&config{
+		Name:    "mounted",
+		Type:    "file",
+		OrgID:   1,
+		Options: map[string]interface{}{"path": mount},
+	}, log.New("test.logger"), nil)
+	require.NoError(t, err)
+	reader.PollInterval = 10 * time.Millisecond
+	reader.PollFailureThreshold = 3
+	require.NoError(t, reader.walkDisk(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		reader.pollChanges(ctx)
+		close(stopped)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+	require.NoError(t, reader.pollingFailed())
+
+	unmounted := mount + ".unmounted"
+	require.NoError(t, os.Rename(mount, unmounted))
+	require.Eventually(t, func() bool { return reader.pollingFailed() != nil }, 5*time.Second, 10*time.Millisecond,
+		"Polling should fail once the directory is gone for enough cycles")
+	err = reader.pollingFailed()
+	require.True(t, os.IsNotExist(errors.Unwrap(err)))
+	require.Contains(t, err.Error(), `polling dashboard provider "mounted" failed`)
+
+	require.NoError(t, os.Rename(unmounted, mount))
+	require.Eventually(t, func() bool { return reader.pollingFailed() == nil }, 5*time.Second, 10*time.Millisecond,
+		"Polling should recover once the directory is back")
+}
+
+func TestPollWindow(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+	})
+	fakeService = mockDashboardProvisioningService()
+	bus.AddHandler("test", mockGetDashboardQuery)
+	t.Cleanup(bus.ClearBusHandlers)
+
+	mount := filepath.Join(t.TempDir(), "mount")
+	require.NoError(t, os.Mkdir(mount, 0750))
+	reader, err := NewDashboardFileReader(&config{
+		Name:    "windowed",
+		Type:    "file",
+		OrgID:   1,
+		Options: map[string]interface{}{"path": mount},
+	}, log.New("test.logger"), nil)
+	require.NoError(t, err)
+	reader.PollInterval = 10 * time.Millisecond
+	reader.PollFailureThreshold = 1
+	// The window opens in an hour, so it's closed for the whole test.
+	now := time.Now()
+	start := (time.Duration(now.Hour()+1)%24)*time.Hour + time.Duration(now.Minute())*time.Minute
+	reader.PollWindow = setting.ProvisioningPollWindow{Start: start, End: (start + time.Hour) % (24 * time.Hour)}
+	require.NoError(t, reader.walkDisk(context.Background()))
+	require.NoError(t, os.Remove(mount))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		reader.pollChanges(ctx)
+		close(stopped)
+	}()
+
+	started := atomic.LoadInt64(&reader.lastPoll)
+	require.Eventually(t, func() bool { return atomic.LoadInt64(&reader.lastPoll) > started }, 5*time.Second,
+		10*time.Millisecond, "Skipped polls should still count as polling cycles")
+	require.NoError(t, reader.pollingFailed(), "The missing directory isn't walked outside of the window")
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("pollChanges should stop once the context is canceled")
+	}
+}
+
+func TestPollInterval(t *testing.T) {
+	t.Run("Provider interval takes precedence over the poll interval", func(t *testing.T) {
+		reader := &FileReader{Cfg: &config{UpdateIntervalSeconds: 3}, PollInterval: time.Minute}
+		require.Equal(t, 3*time.Second, reader.updateInterval())
+	})
+
+	t.Run("Poll interval is used for providers without an interval", func(t *testing.T) {
+		reader := &FileReader{Cfg: &config{}, PollInterval: time.Minute}
+		require.Equal(t, time.Minute, reader.updateInterval())
+	})
+
+	t.Run("Zero poll interval disables polling for all providers", func(t *testing.T) {
+		reader := &FileReader{Cfg: &config{UpdateIntervalSeconds: 3}, log: log.New("test-logger")}
+		require.Equal(t, time.Duration(0), reader.updateInterval())
+
+		done := make(chan struct{})
+		go func() {
+			reader.pollChanges(context.Background())
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("pollChanges should return right away when polling is disabled")
+		}
+		require.False(t, reader.pollingStalled(0))
+	})
+}
+
+func TestJitterInterval(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	require.Equal(t, 10*time.Second, jitterInterval(10*time.Second, 0, rnd))
+
+	seen := map[time.Duration]bool{}
+	for i := 0; i < 100; i++ {
+		interval := jitterInterval(10*time.Second, 20, rnd)
+		require.GreaterOrEqual(t, int64(interval), int64(8*time.Second))
+		require.LessOrEqual(t, int64(interval), int64(12*time.Second))
+		seen[interval] = true
+	}
+	require.Greater(t, len(seen), 1)
+}
+
+func BenchmarkProcessFiles(b *testing.B) {
+	files := map[string]os.FileInfo{}
+	for i := 0; i < 100; i++ {
+		name := fmt.Sprintf("dashboard%d.json", i)
+		files[name] = &FakeFileInfo{name: name}
+	}
+
+	// Simulates the database round trip of saving a dashboard.
+	save := func(_ context.Context, path string, fileInfo os.FileInfo) error {
+		time.Sleep(100 * time.Microsecond)
+		return nil
+	}
+
+	for _, workers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			reader := &FileReader{MaxConcurrency: workers}
+			for i := 0; i < b.N; i++ {
+				if err := reader.processFiles(context.Background(), files, save); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 type FakeFileInfo struct {
 	isDirectory bool
 	name        string
@@ -522,6 +1254,68 @@ func mockDashboardProvisioningService() *fakeDashboardProvisioningService {
 	return &mock
 }
 
+func TestMissingDashboardFilesCleanupGuard(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+	})
+	setupProvider := func(t *testing.T) *FileReader {
+		t.Helper()
+		fakeService = mockDashboardProvisioningService()
+		// The provider stays configured, but its dashboards path reads as empty, like a volume that failed to mount.
+		dir := t.TempDir()
+		fakeService.inserted = []*dashboards.SaveDashboardDTO{
+			{Dashboard: &models.Dashboard{Id: 1}},
+			{Dashboard: &models.Dashboard{Id: 2}},
+		}
+		fakeService.provisioned = map[string][]*models.DashboardProvisioning{
+			"Default": {
+				{DashboardId: 1, Name: "Default", ExternalId: filepath.Join(dir, "dashboard1.json")},
+				{DashboardId: 2, Name: "Default", ExternalId: filepath.Join(dir, "dashboard2.json")},
+			},
+		}
+
+		cfg := &config{Name: "Default", Type: "file", OrgID: 1, Options: map[string]interface{}{"path": dir}}
+		reader, err := NewDashboardFileReader(cfg, log.New("test-logger"), nil)
+		require.NoError(t, err)
+		reader.CleanupGuard.MaxPercent = 50
+		return reader
+	}
+
+	t.Run("Should keep the dashboards of a provider whose dashboards path was emptied", func(t *testing.T) {
+		reader := setupProvider(t)
+
+		require.NoError(t, reader.walkDisk(context.Background()))
+		require.Len(t, fakeService.provisioned["Default"], 2)
+		require.Len(t, fakeService.inserted, 2)
+	})
+
+	t.Run("Should keep them provisioned when deletion is disabled", func(t *testing.T) {
+		reader := setupProvider(t)
+		reader.Cfg.DisableDeletion = true
+
+		require.NoError(t, reader.walkDisk(context.Background()))
+		require.Len(t, fakeService.provisioned["Default"], 2)
+	})
+
+	t.Run("Should delete them without a percentage limit", func(t *testing.T) {
+		reader := setupProvider(t)
+		reader.CleanupGuard.MaxPercent = 100
+
+		require.NoError(t, reader.walkDisk(context.Background()))
+		require.Empty(t, fakeService.provisioned["Default"])
+	})
+
+	t.Run("Should delete them when the cleanup is forced", func(t *testing.T) {
+		reader := setupProvider(t)
+		reader.CleanupGuard.Force = true
+
+		require.NoError(t, reader.walkDisk(context.Background()))
+		require.Empty(t, fakeService.provisioned["Default"])
+		require.Empty(t, fakeService.inserted)
+	})
+}
+
 type fakeDashboardProvisioningService struct {
 	dashboards.DashboardProvisioningService
 
@@ -577,6 +1371,9 @@ func (s *fakeDashboardProvisioningService) SaveProvisionedDashboard(dto *dashboa
 }
 
 func (s *fakeDashboardProvisioningService) SaveFolderForProvisionedDashboards(dto *dashboards.SaveDashboardDTO) (*models.Dashboard, error) {
+	if dto.Dashboard.Id == 0 {
+		dto.Dashboard.Id = rand.Int63n(1000000)
+	}
 	s.inserted = append(s.inserted, dto)
 	return dto.Dashboard, nil
 }
@@ -612,7 +1409,7 @@ func (s *fakeDashboardProvisioningService) GetProvisionedDashboardDataByDashboar
 
 func mockGetDashboardQuery(cmd *models.GetDashboardQuery) error {
 	for _, d := range fakeService.getDashboard {
-		if d.Slug == cmd.Slug {
+		if cmd.Uid != "" && d.Uid == cmd.Uid || cmd.Uid == "" && d.Slug == cmd.Slug {
 			cmd.Result = d
 			return nil
 		}
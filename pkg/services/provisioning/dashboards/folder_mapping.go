@@ -0,0 +1,93 @@
+package dashboards
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// validateFolderMapping checks that every rule of a folderMapping has a valid pattern and a folder.
+func validateFolderMapping(mapping []folderMapping) error {
+	for i, rule := range mapping {
+		if rule.Pattern == "" {
+			return fmt.Errorf("folderMapping rule %d has no pattern", i+1)
+		}
+		if _, err := path.Match(rule.Pattern, ""); err != nil {
+			return fmt.Errorf("folderMapping rule %d has an invalid pattern %q: %w", i+1, rule.Pattern, err)
+		}
+		if rule.Folder == "" && rule.FolderUID == "" {
+			return fmt.Errorf("folderMapping rule %d with pattern %q has no folder or folderUid", i+1, rule.Pattern)
+		}
+	}
+	return nil
+}
+
+// mappedFolder returns the first rule of the folderMapping whose pattern matches the dashboard file at path, or nil
+// when none does. Patterns with a slash are matched against the path of the file below rootPath, like
+// team-a/*.json, the others against the file name only, so team-a-*.json matches in every directory.
+func (fr *FileReader) mappedFolder(filePath string, rootPath string) *folderMapping {
+	relPath, ok := relativePath(rootPath, filePath)
+	if !ok {
+		return nil
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	for i, rule := range fr.Cfg.FolderMapping {
+		name := path.Base(relPath)
+		if strings.Contains(rule.Pattern, "/") {
+			name = relPath
+		}
+		// The patterns are validated when the reader is created.
+		if matched, _ := path.Match(rule.Pattern, name); matched {
+			return &fr.Cfg.FolderMapping[i]
+		}
+	}
+	return nil
+}
+
+// storeDashboardsInMappedFolders saves dashboards from the filesystem on disk to the folder of the first
+// folderMapping rule they match, or to the folder from config.
+func (fr *FileReader) storeDashboardsInMappedFolders(ctx context.Context, filesFoundOnDisk map[string]os.FileInfo, localizedFiles map[string]string,
+	dashboardRefs map[string]*models.DashboardProvisioning, rootPath string, sanityChecker *provisioningSanityChecker) error {
+	folderIDs := newFolderIDCache()
+	getFolderID := func(rule *folderMapping) (int64, error) {
+		folderCfg := fr.Cfg
+		if rule != nil {
+			mappedCfg := *fr.Cfg
+			mappedCfg.Folder, mappedCfg.FolderUID = rule.Folder, rule.FolderUID
+			folderCfg = &mappedCfg
+		}
+		return folderIDs.get(folderCfg.FolderUID+"/"+folderCfg.Folder, func() (int64, error) {
+			folderID, err := getOrCreateFolderID(ctx, folderCfg, fr.dashboardProvisioningService, folderCfg.Folder)
+			if err != nil && !errors.Is(err, ErrFolderNameMissing) {
+				return 0, err
+			}
+			return folderID, nil
+		})
+	}
+
+	return fr.processFiles(ctx, filesFoundOnDisk, func(ctx context.Context, path string, fileInfo os.FileInfo) error {
+		rule := fr.mappedFolder(path, rootPath)
+		folderID, err := getFolderID(rule)
+		if err != nil {
+			if rule != nil {
+				return fmt.Errorf("can't provision folder %q of folderMapping pattern %q: %w", rule.Folder, rule.Pattern, err)
+			}
+			return err
+		}
+
+		provisioningMetadata, err := fr.saveDashboard(ctx, path, localizedFiles[path], folderID, fileInfo, dashboardRefs)
+		sanityChecker.track(provisioningMetadata)
+		if err != nil {
+			fr.log.Error("failed to save dashboard", "file", path, "error", err)
+			fr.resume.fail(path)
+		}
+		return nil
+	})
+}
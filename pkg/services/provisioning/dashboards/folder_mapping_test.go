@@ -0,0 +1,125 @@
+package dashboards
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappedFolder(t *testing.T) {
+	reader := &FileReader{Cfg: &config{FolderMapping: []folderMapping{
+		{Pattern: "team-a-critical-*.json", Folder: "Team A critical"},
+		{Pattern: "team-a-*.json", Folder: "Team A"},
+		{Pattern: "legacy/*.json", Folder: "Legacy"},
+		{Pattern: "*-overview.json", Folder: "Overviews"},
+	}}}
+
+	for _, tc := range []struct {
+		path   string
+		folder string
+	}{
+		{path: "team-a-critical-latency.json", folder: "Team A critical"},
+		{path: "team-a-latency.json", folder: "Team A"},
+		{path: "nested/team-a-critical-errors.json", folder: "Team A critical"},
+		{path: "legacy/team-a-latency.json", folder: "Team A"},
+		{path: "legacy/old.json", folder: "Legacy"},
+		{path: "legacy/nested/old.json", folder: ""},
+		{path: "team-b-overview.json", folder: "Overviews"},
+		{path: "team-b-latency.json", folder: ""},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			rule := reader.mappedFolder(filepath.Join("/dashboards", filepath.FromSlash(tc.path)), "/dashboards")
+			if tc.folder == "" {
+				assert.Nil(t, rule)
+				return
+			}
+			require.NotNil(t, rule)
+			assert.Equal(t, tc.folder, rule.Folder)
+		})
+	}
+}
+
+func TestValidateFolderMapping(t *testing.T) {
+	require.NoError(t, validateFolderMapping([]folderMapping{{Pattern: "*.json", Folder: "All"}}))
+
+	err := validateFolderMapping([]folderMapping{{Pattern: "", Folder: "All"}})
+	require.EqualError(t, err, "folderMapping rule 1 has no pattern")
+
+	err = validateFolderMapping([]folderMapping{{Pattern: "*.json", Folder: "All"}, {Pattern: "[", Folder: "All"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `folderMapping rule 2 has an invalid pattern "["`)
+
+	err = validateFolderMapping([]folderMapping{{Pattern: "*.json"}})
+	require.EqualError(t, err, `folderMapping rule 1 with pattern "*.json" has no folder or folderUid`)
+	require.NoError(t, validateFolderMapping([]folderMapping{{Pattern: "*.json", FolderUID: "all"}}))
+
+	_, err = NewDashboardFileReader(&config{
+		Name:    "mapped",
+		Options: map[string]interface{}{"path": "/dashboards", "foldersFromFilesStructure": true},
+		FolderMapping: []folderMapping{
+			{Pattern: "*.json", Folder: "All"},
+		},
+	}, log.New("test.logger"), nil)
+	require.Error(t, err, "folderMapping can't be combined with folders from the file structure")
+}
+
+func TestWalkDiskWithFolderMapping(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+	})
+	fakeService = mockDashboardProvisioningService()
+	bus.AddHandler("test", mockGetDashboardQuery)
+	t.Cleanup(bus.ClearBusHandlers)
+
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "legacy"), 0750))
+	for _, name := range []string{"team-a-critical-latency.json", "team-a-errors.json", "legacy/old.json", "other.json"} {
+		content := fmt.Sprintf(`{"title": %q}`, filepath.Base(name))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(content), 0600))
+	}
+
+	cfg := &config{
+		Name:    "test-folder-mapping",
+		Type:    "file",
+		OrgID:   1,
+		Folder:  "Provider folder",
+		Options: map[string]interface{}{"path": dir},
+		FolderMapping: []folderMapping{
+			{Pattern: "team-a-critical-*.json", Folder: "Team A critical", FolderUID: "team-a-critical"},
+			{Pattern: "team-a-*.json", Folder: "Team A"},
+			{Pattern: "legacy/*.json", Folder: "Legacy"},
+		},
+	}
+	reader, err := NewDashboardFileReader(cfg, log.New("test.logger"), nil)
+	require.NoError(t, err)
+	require.NoError(t, reader.walkDisk(context.Background()))
+
+	folders := map[string]*dashboards.SaveDashboardDTO{}
+	folderOf := map[string]int64{}
+	for _, dto := range fakeService.inserted {
+		if dto.Dashboard.IsFolder {
+			folders[dto.Dashboard.Title] = dto
+			continue
+		}
+		folderOf[dto.Dashboard.Title] = dto.Dashboard.FolderId
+	}
+	require.Len(t, folders, 4, "Every mapped folder and the provider folder should be created once")
+	assert.Equal(t, "team-a-critical", folders["Team A critical"].Dashboard.Uid)
+
+	assert.Equal(t, folders["Team A critical"].Dashboard.Id, folderOf["team-a-critical-latency.json"],
+		"The first matching rule should win")
+	assert.Equal(t, folders["Team A"].Dashboard.Id, folderOf["team-a-errors.json"])
+	assert.Equal(t, folders["Legacy"].Dashboard.Id, folderOf["old.json"])
+	assert.Equal(t, folders["Provider folder"].Dashboard.Id, folderOf["other.json"],
+		"Files without a matching rule should go to the provider folder")
+}
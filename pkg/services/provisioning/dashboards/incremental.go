@@ -0,0 +1,82 @@
+package dashboards
+
+import (
+	"os"
+	"time"
+)
+
+// fullWalkEvery is the number of polls after which an incremental poll does a full walk of the disk anyway, which
+// catches files copied with their modification time preserved and dashboards deleted from the database.
+const fullWalkEvery = 10
+
+// pollState is what an incremental poll compares the dashboard files on disk to: the files found by the last
+// successful walk of the disk and the latest modification time among them.
+type pollState struct {
+	resolvedPath string
+	watermark    time.Time
+	files        map[string]bool
+	// polls is the number of incremental polls since the last full walk.
+	polls int
+}
+
+func newPollState(resolvedPath string, filesOnDisk map[string]os.FileInfo) *pollState {
+	state := &pollState{resolvedPath: resolvedPath, files: make(map[string]bool, len(filesOnDisk))}
+	for path, fileInfo := range filesOnDisk {
+		state.files[path] = true
+		if fileInfo.ModTime().After(state.watermark) {
+			state.watermark = fileInfo.ModTime()
+		}
+	}
+	return state
+}
+
+// changes returns the files on disk that are new or were modified after the watermark, and whether files of the
+// last walk are missing. It returns false when the files can't be compared, since the root resolves to another
+// directory, and have to be walked in full.
+func (state *pollState) changes(resolvedPath string, filesOnDisk map[string]os.FileInfo) (map[string]bool, bool, bool) {
+	if state == nil || state.resolvedPath != resolvedPath || state.polls >= fullWalkEvery-1 {
+		return nil, false, false
+	}
+
+	changed := map[string]bool{}
+	found := 0
+	for path, fileInfo := range filesOnDisk {
+		if state.files[path] {
+			found++
+		}
+		if !state.files[path] || fileInfo.ModTime().After(state.watermark) {
+			changed[path] = true
+		}
+	}
+	return changed, found < len(state.files), true
+}
+
+// changedFiles returns the files to save whose dashboard file, or its locale variant, changed.
+func changedFiles(filesToSave map[string]os.FileInfo, localizedFiles map[string]string,
+	changed map[string]bool) map[string]os.FileInfo {
+	files := map[string]os.FileInfo{}
+	for path, fileInfo := range filesToSave {
+		if changed[path] || changed[localizedFiles[path]] {
+			files[path] = fileInfo
+		}
+	}
+	return files
+}
+
+// trackUnchanged tracks the dashboards of the files an incremental poll skipped with what the last walk parsed
+// from them, so the sanity checks still cover every dashboard of the provider.
+func (fr *FileReader) trackUnchanged(filesFoundOnDisk map[string]os.FileInfo, filesToSave map[string]os.FileInfo,
+	localizedFiles map[string]string, sanityChecker *provisioningSanityChecker) {
+	for path := range filesFoundOnDisk {
+		if _, ok := filesToSave[path]; ok {
+			continue
+		}
+		sourcePath := path
+		if localizedPath, ok := localizedFiles[path]; ok {
+			sourcePath = localizedPath
+		}
+		if metadata, ok := fr.parseCache.cached(sourcePath); ok {
+			sanityChecker.track(metadata)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package dashboards
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeDashboardFile(t testing.TB, dir string, uid string, title string, modTime time.Time) {
+	t.Helper()
+	path := filepath.Join(dir, uid+".json")
+	content := fmt.Sprintf(`{"uid": %q, "title": %q, "panels": []}`, uid, title)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func newIncrementalTestReader(t testing.TB, dir string) *FileReader {
+	t.Helper()
+	fakeService = mockDashboardProvisioningService()
+	cfg := &config{Name: "Default", Type: "file", OrgID: 1, Options: map[string]interface{}{"path": dir}}
+	reader, err := NewDashboardFileReader(cfg, log.New("test-logger"), nil)
+	require.NoError(t, err)
+	reader.IncrementalPolling = true
+	return reader
+}
+
+func insertedTitles() []string {
+	var titles []string
+	for _, dto := range fakeService.inserted {
+		titles = append(titles, dto.Dashboard.Title)
+	}
+	return titles
+}
+
+func TestIncrementalPolling(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+		bus.ClearBusHandlers()
+	})
+	bus.ClearBusHandlers()
+
+	past := time.Now().Add(-time.Hour)
+	setup := func(t *testing.T) (*FileReader, string) {
+		dir := t.TempDir()
+		writeDashboardFile(t, dir, "a", "A", past)
+		writeDashboardFile(t, dir, "b", "B", past)
+		reader := newIncrementalTestReader(t, dir)
+		require.NoError(t, reader.walkDisk(context.Background()))
+		require.ElementsMatch(t, []string{"A", "B"}, insertedTitles())
+		return reader, dir
+	}
+
+	t.Run("Polls without changes don't save anything", func(t *testing.T) {
+		reader, _ := setup(t)
+
+		require.NoError(t, reader.walk(context.Background(), true))
+		assert.Equal(t, 1, reader.poll.polls)
+		assert.ElementsMatch(t, []string{"A", "B"}, insertedTitles())
+	})
+
+	t.Run("Only the files modified after the watermark are saved", func(t *testing.T) {
+		reader, dir := setup(t)
+		writeDashboardFile(t, dir, "a", "A modified", past.Add(time.Minute))
+		// Modified without a newer modification time, so only a full walk notices.
+		writeDashboardFile(t, dir, "b", "B modified", past)
+
+		require.NoError(t, reader.walk(context.Background(), true))
+		assert.ElementsMatch(t, []string{"A modified", "B"}, insertedTitles())
+
+		require.NoError(t, reader.walkDisk(context.Background()))
+		assert.ElementsMatch(t, []string{"A modified", "B modified"}, insertedTitles())
+		assert.Equal(t, 0, reader.poll.polls, "A full walk starts counting the polls again")
+	})
+
+	t.Run("New files are saved whatever their modification time", func(t *testing.T) {
+		reader, dir := setup(t)
+		writeDashboardFile(t, dir, "c", "C", past.Add(-time.Hour))
+
+		require.NoError(t, reader.walk(context.Background(), true))
+		assert.ElementsMatch(t, []string{"A", "B", "C"}, insertedTitles())
+	})
+
+	t.Run("Dashboards of missing files are deleted", func(t *testing.T) {
+		reader, dir := setup(t)
+		require.NoError(t, os.Remove(filepath.Join(dir, "b.json")))
+
+		require.NoError(t, reader.walk(context.Background(), true))
+		assert.ElementsMatch(t, []string{"A"}, insertedTitles())
+		require.Len(t, reader.DeletedDashboards(), 1)
+		assert.Len(t, fakeService.provisioned["Default"], 1)
+	})
+
+	t.Run("Every few polls walk the disk in full", func(t *testing.T) {
+		reader, dir := setup(t)
+		writeDashboardFile(t, dir, "b", "B modified", past)
+
+		for i := 1; i < fullWalkEvery; i++ {
+			require.NoError(t, reader.walk(context.Background(), true))
+		}
+		assert.ElementsMatch(t, []string{"A", "B"}, insertedTitles())
+
+		require.NoError(t, reader.walk(context.Background(), true))
+		assert.ElementsMatch(t, []string{"A", "B modified"}, insertedTitles())
+	})
+}
+
+// BenchmarkPollUnchangedFiles compares polls of a large directory in which no file changed.
+func BenchmarkPollUnchangedFiles(b *testing.B) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	b.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+		bus.ClearBusHandlers()
+	})
+	bus.ClearBusHandlers()
+
+	dir := b.TempDir()
+	past := time.Now().Add(-time.Hour)
+	for i := 0; i < 1000; i++ {
+		writeDashboardFile(b, dir, fmt.Sprintf("dashboard-%d", i), fmt.Sprintf("Dashboard %d", i), past)
+	}
+
+	for _, incremental := range []bool{false, true} {
+		b.Run(fmt.Sprintf("incremental=%t", incremental), func(b *testing.B) {
+			reader := newIncrementalTestReader(b, dir)
+			require.NoError(b, reader.walkDisk(context.Background()))
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := reader.walk(context.Background(), incremental); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
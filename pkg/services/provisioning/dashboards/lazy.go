@@ -0,0 +1,168 @@
+package dashboards
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/util/errutil"
+)
+
+// deferredDashboard is a dashboard file of a lazy provider that hasn't been provisioned yet.
+type deferredDashboard struct {
+	path          string
+	localizedPath string
+	fileInfo      os.FileInfo
+}
+
+// deferDashboards indexes the files of a lazy provider that aren't provisioned yet by the uid of their dashboard,
+// and returns the files to save now: the provisioned ones, so changes reach the dashboards already in the
+// database, and the ones that can't be looked up by uid. Only the uid is decoded, the dashboards are parsed once
+// they're provisioned by provisionDeferred.
+func (fr *FileReader) deferDashboards(filesFoundOnDisk map[string]os.FileInfo, localizedFiles map[string]string,
+	provisionedDashboardRefs map[string]*models.DashboardProvisioning) map[string]os.FileInfo {
+	deferred := map[string]deferredDashboard{}
+	filesToSave := map[string]os.FileInfo{}
+	for path, fileInfo := range filesFoundOnDisk {
+		if _, ok := provisionedDashboardRefs[path]; ok {
+			filesToSave[path] = fileInfo
+			continue
+		}
+
+		sourcePath := path
+		if localizedPath, ok := localizedFiles[path]; ok {
+			sourcePath = localizedPath
+		}
+		uid, err := fr.readDashboardUID(sourcePath)
+		if err != nil || uid == "" {
+			fr.log.Debug("Provisioning dashboard without a uid of a lazy provider", "file", sourcePath, "error", err)
+			filesToSave[path] = fileInfo
+			continue
+		}
+		if other, ok := deferred[uid]; ok {
+			fr.log.Warn("Dashboards of a lazy provider have the same uid, provisioning them now", "uid", uid,
+				"file", path, "other", other.path)
+			filesToSave[path], filesToSave[other.path] = fileInfo, other.fileInfo
+			delete(deferred, uid)
+			continue
+		}
+		deferred[uid] = deferredDashboard{path: path, localizedPath: localizedFiles[path], fileInfo: fileInfo}
+	}
+
+	fr.mutex.Lock()
+	fr.deferred = deferred
+	fr.mutex.Unlock()
+	return filesToSave
+}
+
+// readDashboardUID returns the uid of the dashboard file at path without parsing the rest of the dashboard.
+func (fr *FileReader) readDashboardUID(path string) (string, error) {
+	content, _, err := fr.readDashboardFile(path)
+	if err != nil {
+		return "", err
+	}
+	var dashboard struct {
+		UID string `json:"uid"`
+	}
+	if err := json.Unmarshal(content, &dashboard); err != nil {
+		return "", err
+	}
+	return dashboard.UID, nil
+}
+
+// DeferredDashboards returns the number of dashboards the last walk of the disk of a lazy provider indexed without
+// provisioning them.
+func (fr *FileReader) DeferredDashboards() int {
+	fr.mutex.Lock()
+	defer fr.mutex.Unlock()
+	return len(fr.deferred)
+}
+
+// provisionDeferred provisions the dashboard with uid if the last walk of the disk deferred it, and reports whether
+// it did. It waits for a walk that is running to finish, so the file isn't saved twice.
+func (fr *FileReader) provisionDeferred(ctx context.Context, uid string) (bool, error) {
+	fr.walkMutex.Lock()
+	defer fr.walkMutex.Unlock()
+
+	fr.mutex.Lock()
+	dashboard, ok := fr.deferred[uid]
+	fr.mutex.Unlock()
+	if !ok {
+		return false, nil
+	}
+
+	rootPath := fr.rootPath()
+	folderID, err := fr.fileFolderID(ctx, dashboard.path, rootPath)
+	if err != nil {
+		return false, err
+	}
+	metadata, err := fr.saveDashboard(ctx, dashboard.path, dashboard.localizedPath, folderID, dashboard.fileInfo,
+		map[string]*models.DashboardProvisioning{})
+	if err != nil {
+		return false, err
+	}
+	if err := utils.CheckDanglingReferences(fr.log, fr.referenceCheck(), metadata.danglingRefs); err != nil {
+		return false, err
+	}
+	if metadata.uid == "" {
+		// saveDashboard only logs files that can't be read or parsed.
+		return false, nil
+	}
+
+	fr.mutex.Lock()
+	delete(fr.deferred, uid)
+	fr.mutex.Unlock()
+	fr.log.Debug("Provisioned deferred dashboard", "uid", uid, "file", dashboard.path)
+	return true, nil
+}
+
+// fileFolderID returns the ID of the folder the file at path is saved to, creating the folder when it's missing.
+// It resolves the folder the same way a walk of the disk does for the options of the provider.
+func (fr *FileReader) fileFolderID(ctx context.Context, path string, rootPath string) (int64, error) {
+	var folderID int64
+	var err error
+	switch {
+	case fr.FoldersFromFilesStructure || fr.FoldersFromFilesPath:
+		folderName := fr.fileFolderName(path, rootPath)
+		if fr.FoldersFromFilesPath && folderName != "" {
+			folderID, err = getOrCreatePathFolderID(ctx, fr.Cfg, fr.dashboardProvisioningService, folderName)
+		} else {
+			folderID, err = getOrCreateFolderID(ctx, fr.Cfg, fr.dashboardProvisioningService, folderName)
+		}
+	case len(fr.Cfg.FolderMapping) > 0:
+		folderCfg := fr.Cfg
+		if rule := fr.mappedFolder(path, rootPath); rule != nil {
+			mappedCfg := *fr.Cfg
+			mappedCfg.Folder, mappedCfg.FolderUID = rule.Folder, rule.FolderUID
+			folderCfg = &mappedCfg
+		}
+		folderID, err = getOrCreateFolderID(ctx, folderCfg, fr.dashboardProvisioningService, folderCfg.Folder)
+	default:
+		folderID, err = getOrCreateFolderID(ctx, fr.Cfg, fr.dashboardProvisioningService, fr.Cfg.Folder)
+	}
+	if err != nil && !errors.Is(err, ErrFolderNameMissing) {
+		return 0, err
+	}
+	return folderID, nil
+}
+
+// ProvisionDeferredDashboard provisions the dashboard with uid of org from the lazy provider that deferred it, and
+// reports whether one did. It's meant to be called when the dashboard isn't found in the database.
+func (provider *Provisioner) ProvisionDeferredDashboard(ctx context.Context, orgID int64, uid string) (bool, error) {
+	for _, reader := range provider.fileReaders {
+		if !reader.Cfg.Lazy || reader.Cfg.OrgID != orgID {
+			continue
+		}
+		provisioned, err := reader.provisionDeferred(ctx, uid)
+		if err != nil {
+			return false, errutil.Wrapf(err, "Failed to provision dashboard %s of provider %s", uid, reader.Cfg.Name)
+		}
+		if provisioned {
+			return true, nil
+		}
+	}
+	return false, nil
+}
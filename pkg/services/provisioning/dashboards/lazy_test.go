@@ -0,0 +1,81 @@
+package dashboards
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLazyProvider(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+	})
+	fakeService = mockDashboardProvisioningService()
+	t.Cleanup(bus.ClearBusHandlers)
+	bus.AddHandler("test", mockGetDashboardQuery)
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"provisioned.json": `{"title": "Provisioned", "uid": "provisioned"}`,
+		"deferred.json":    `{"title": "Deferred", "uid": "deferred"}`,
+		"other.json":       `{"title": "Other", "uid": "other"}`,
+		"no-uid.json":      `{"title": "Without uid"}`,
+	}
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+
+	cfg := &config{Name: "lazy", Type: "file", OrgID: 1, Lazy: true, Options: map[string]interface{}{"path": dir}}
+	fakeService.provisioned[cfg.Name] = []*models.DashboardProvisioning{
+		{DashboardId: 1, Name: cfg.Name, ExternalId: filepath.Join(dir, "provisioned.json"), CheckSum: "outdated"},
+	}
+	reader, err := NewDashboardFileReader(cfg, log.New("test.logger"), nil)
+	require.NoError(t, err)
+	provisioner := &Provisioner{log: log.New("test.logger"), fileReaders: []*FileReader{reader}, configs: []*config{cfg}}
+
+	require.NoError(t, provisioner.Provision(context.Background()))
+	titles := func() []string {
+		var titles []string
+		for _, dash := range fakeService.inserted {
+			titles = append(titles, dash.Dashboard.Title)
+		}
+		return titles
+	}
+	assert.ElementsMatch(t, []string{"Provisioned", "Without uid"}, titles(),
+		"Provisioned dashboards are updated and the ones without uid can't be deferred")
+	assert.Equal(t, 2, reader.DeferredDashboards())
+
+	provisioned, err := provisioner.ProvisionDeferredDashboard(context.Background(), 2, "deferred")
+	require.NoError(t, err)
+	assert.False(t, provisioned, "Dashboards are only provisioned for the org of their provider")
+
+	provisioned, err = provisioner.ProvisionDeferredDashboard(context.Background(), 1, "deferred")
+	require.NoError(t, err)
+	assert.True(t, provisioned)
+	assert.ElementsMatch(t, []string{"Provisioned", "Without uid", "Deferred"}, titles())
+	assert.Equal(t, 1, reader.DeferredDashboards())
+
+	provisioned, err = provisioner.ProvisionDeferredDashboard(context.Background(), 1, "unknown")
+	require.NoError(t, err)
+	assert.False(t, provisioned)
+
+	t.Run("Polling defers new files and keeps the ones it provisioned up to date", func(t *testing.T) {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "new.json"), []byte(`{"title": "New", "uid": "new"}`), 0600))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "deferred.json"),
+			[]byte(`{"title": "Deferred and changed", "uid": "deferred"}`), 0600))
+		fakeService.inserted = nil
+
+		require.NoError(t, reader.walkDisk(context.Background()))
+		assert.Equal(t, []string{"Deferred and changed"}, titles())
+		assert.Equal(t, 2, reader.DeferredDashboards(), "other.json and new.json are deferred")
+	})
+}
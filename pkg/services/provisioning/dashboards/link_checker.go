@@ -0,0 +1,143 @@
+package dashboards
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// BrokenLink is a link in a provisioned dashboard whose target doesn't resolve.
+type BrokenLink struct {
+	// File is the provisioning file of the dashboard holding the link.
+	File string
+	// DashboardUID is the UID of the dashboard holding the link.
+	DashboardUID string
+	// Location describes where the link is in the dashboard, like `dashboard link "Docs"`.
+	Location string
+	URL      string
+	Reason   string
+}
+
+// dashboardLink is a link found in a dashboard, before its target is resolved.
+type dashboardLink struct {
+	location string
+	url      string
+}
+
+// internalDashboardURL matches the path of links to a dashboard or a single panel of it, optionally under a
+// sub path Grafana is served from, and captures the dashboard UID.
+var internalDashboardURL = regexp.MustCompile(`(?:^|/)d(?:-solo)?/([^/?#]+)`)
+
+// findDashboardLinks returns the dashboard links, panel links and data links of a dashboard, including the ones
+// of panels nested in rows.
+func findDashboardLinks(data *simplejson.Json) []dashboardLink {
+	var links []dashboardLink
+
+	for _, link := range data.Get("links").MustArray() {
+		link := simplejson.NewFromAny(link)
+		// Links of type dashboards point to dashboards by tag, not by URL.
+		if link.Get("type").MustString() == "dashboards" {
+			continue
+		}
+		links = append(links, dashboardLink{
+			location: fmt.Sprintf("dashboard link %q", link.Get("title").MustString()),
+			url:      link.Get("url").MustString(),
+		})
+	}
+
+	var panels []interface{}
+	panels = append(panels, data.Get("panels").MustArray()...)
+	for _, row := range data.Get("rows").MustArray() {
+		panels = append(panels, simplejson.NewFromAny(row).Get("panels").MustArray()...)
+	}
+	for _, panel := range panels {
+		links = append(links, findPanelLinks(simplejson.NewFromAny(panel))...)
+	}
+
+	return links
+}
+
+func findPanelLinks(panel *simplejson.Json) []dashboardLink {
+	var links []dashboardLink
+	panelTitle := panel.Get("title").MustString()
+
+	appendLinks := func(kind string, items []interface{}) {
+		for _, item := range items {
+			link := simplejson.NewFromAny(item)
+			links = append(links, dashboardLink{
+				location: fmt.Sprintf("%s %q of panel %q", kind, link.Get("title").MustString(), panelTitle),
+				url:      link.Get("url").MustString(),
+			})
+		}
+	}
+
+	appendLinks("panel link", panel.Get("links").MustArray())
+	appendLinks("data link", panel.GetPath("fieldConfig", "defaults", "links").MustArray())
+	for _, override := range panel.GetPath("fieldConfig", "overrides").MustArray() {
+		for _, property := range simplejson.NewFromAny(override).Get("properties").MustArray() {
+			property := simplejson.NewFromAny(property)
+			if property.Get("id").MustString() == "links" {
+				appendLinks("data link", property.Get("value").MustArray())
+			}
+		}
+	}
+	// Collapsed rows keep their panels inside the row panel.
+	for _, nested := range panel.Get("panels").MustArray() {
+		links = append(links, findPanelLinks(simplejson.NewFromAny(nested))...)
+	}
+
+	return links
+}
+
+// checkLink returns why link doesn't resolve, or an empty string if it does or can't be checked. dashboardExists
+// reports whether a dashboard with the given UID exists.
+func checkLink(link dashboardLink, dashboardExists func(uid string) bool) string {
+	if strings.TrimSpace(link.url) == "" {
+		return "the link has no URL"
+	}
+
+	// Template variables are only known when the dashboard is viewed.
+	if strings.Contains(link.url, "$") {
+		return ""
+	}
+
+	target, err := url.Parse(link.url)
+	if err != nil {
+		return fmt.Sprintf("invalid URL: %v", err)
+	}
+
+	if target.IsAbs() || target.Host != "" {
+		return ""
+	}
+
+	match := internalDashboardURL.FindStringSubmatch(target.Path)
+	if match == nil {
+		return ""
+	}
+	if !dashboardExists(match[1]) {
+		return fmt.Sprintf("no dashboard with UID %q", match[1])
+	}
+	return ""
+}
+
+// dashboardExistsInOrg returns a function reporting whether a dashboard with a UID exists in an org, either
+// among the UIDs provisioned in the current pass or in the database. Lookups that fail for another reason than
+// the dashboard missing count as existing so they're not reported as broken.
+func dashboardExistsInOrg(ctx context.Context, orgID int64, provisionedUIDs map[string]uint8) func(uid string) bool {
+	return func(uid string) bool {
+		if provisionedUIDs[uid] > 0 {
+			return true
+		}
+
+		query := &models.GetDashboardQuery{Uid: uid, OrgId: orgID}
+		err := bus.DispatchCtx(ctx, query)
+		return !errors.Is(err, models.ErrDashboardNotFound)
+	}
+}
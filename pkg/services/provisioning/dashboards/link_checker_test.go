@@ -0,0 +1,102 @@
+package dashboards
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	brokenLinksDashboards = "testdata/test-dashboards/links/broken"
+	cleanLinksDashboards  = "testdata/test-dashboards/links/clean"
+)
+
+func TestBrokenLinks(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+		bus.ClearBusHandlers()
+	})
+
+	// Dashboards already in the database, outside of the provisioned files.
+	existingUIDs := map[string]bool{"services": true}
+	bus.ClearBusHandlers()
+	bus.AddHandler("test", func(query *models.GetDashboardQuery) error {
+		if query.OrgId == 1 && existingUIDs[query.Uid] {
+			query.Result = &models.Dashboard{Uid: query.Uid, OrgId: query.OrgId}
+			return nil
+		}
+		return models.ErrDashboardNotFound
+	})
+
+	walk := func(t *testing.T, path string) []BrokenLink {
+		t.Helper()
+
+		fakeService = mockDashboardProvisioningService()
+		cfg := &config{
+			Name:    "Default",
+			Type:    "file",
+			OrgID:   1,
+			Options: map[string]interface{}{"path": path},
+		}
+		reader, err := NewDashboardFileReader(cfg, log.New("test-logger"), nil)
+		require.NoError(t, err)
+
+		require.NoError(t, reader.walkDisk(context.Background()))
+		return reader.BrokenLinks()
+	}
+
+	t.Run("Links to missing dashboards are reported", func(t *testing.T) {
+		broken := walk(t, brokenLinksDashboards)
+
+		require.Len(t, broken, 2)
+		assert.ElementsMatch(t, []string{
+			`dashboard link "Overview"`,
+			`data link "Error budget" of panel "Errors"`,
+		}, []string{broken[0].Location, broken[1].Location})
+		for _, link := range broken {
+			assert.Equal(t, "services", link.DashboardUID)
+			assert.Contains(t, link.File, "services.json")
+		}
+
+		byURL := map[string]string{}
+		for _, link := range broken {
+			byURL[link.URL] = link.Reason
+		}
+		assert.Equal(t, map[string]string{
+			"/d/overview/overview":                          `no dashboard with UID "overview"`,
+			"/grafana/d-solo/error-budget/budget?panelId=2": `no dashboard with UID "error-budget"`,
+		}, byURL)
+	})
+
+	t.Run("Dashboard with resolvable links has no broken links", func(t *testing.T) {
+		assert.Empty(t, walk(t, cleanLinksDashboards))
+	})
+}
+
+func TestCheckLink(t *testing.T) {
+	exists := func(uid string) bool { return uid == "home" }
+
+	tests := []struct {
+		url    string
+		reason string
+	}{
+		{url: "/d/home/home", reason: ""},
+		{url: "/d/gone/gone", reason: `no dashboard with UID "gone"`},
+		{url: "https://grafana.example.com/d/gone", reason: ""},
+		{url: "/d/${uid}/details", reason: ""},
+		{url: "/explore?left=[]", reason: ""},
+		{url: "", reason: "the link has no URL"},
+		{url: "/d/%zz", reason: `invalid URL: parse "/d/%zz": invalid URL escape "%zz"`},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.reason, checkLink(dashboardLink{url: tt.url}, exists), tt.url)
+	}
+}
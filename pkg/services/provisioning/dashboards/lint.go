@@ -0,0 +1,56 @@
+package dashboards
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// Lint validates the dashboard provider config files in configDirectory without a database, so the checks that
+// need one, like whether orgs exist, are skipped. The dashboards of the providers aren't read. Unknown fields are
+// an error when strict is set. It returns a LintError for every invalid file.
+func Lint(configDirectory string, strict bool) ([]utils.LintError, error) {
+	cr := &configReader{path: configDirectory, log: log.New("provisioning.dashboard"), offline: true, strict: strict,
+		env: utils.EnvironmentFromContext(context.Background())}
+	files, err := utils.ProvisioningFiles(configDirectory, setting.ProvisioningFileFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	var lintErrors []utils.LintError
+	for _, file := range files {
+		filename, _ := filepath.Abs(filepath.Join(configDirectory, file.Name()))
+		if err := cr.lintFile(file); err != nil {
+			lintErrors = append(lintErrors, utils.NewLintError(filename, err))
+		}
+	}
+	return lintErrors, nil
+}
+
+func (cr *configReader) lintFile(file os.FileInfo) error {
+	configs, err := cr.parseConfigs(file)
+	if err != nil {
+		return err
+	}
+	if err := cr.applyPathOrg(configs); err != nil {
+		return err
+	}
+	if err := cr.validateConfigs(context.Background(), configs); err != nil {
+		return err
+	}
+
+	for _, cfg := range configs {
+		if cfg.Type != "file" {
+			return fmt.Errorf("provider %q: type %s is not supported", cfg.Name, cfg.Type)
+		}
+		if _, err := NewDashboardFileReader(cfg, cr.log, nil); err != nil {
+			return fmt.Errorf("provider %q: %w", cfg.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package dashboards
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const invalidProviders = "./testdata/test-configs/invalid-providers"
+
+func TestLint(t *testing.T) {
+	t.Run("Valid configs don't have lint errors and orgs aren't looked up", func(t *testing.T) {
+		for _, path := range []string{appliedDefaults, simpleDashboardConfig, oldVersion} {
+			lintErrors, err := Lint(path, false)
+			require.NoError(t, err)
+			assert.Empty(t, lintErrors, path)
+		}
+	})
+
+	t.Run("Reports every invalid file", func(t *testing.T) {
+		lintErrors, err := Lint(invalidProviders, false)
+		require.NoError(t, err)
+		require.Len(t, lintErrors, 2)
+
+		missingPath, _ := filepath.Abs(filepath.Join(invalidProviders, "missing-path.yaml"))
+		assert.Equal(t, missingPath, lintErrors[0].File)
+		assert.EqualError(t, lintErrors[0].Err,
+			"provider \"missing-path\": failed to load dashboards, path param is not a string")
+
+		unsupportedType, _ := filepath.Abs(filepath.Join(invalidProviders, "unsupported-type.yaml"))
+		assert.Equal(t, unsupportedType, lintErrors[1].File)
+		assert.EqualError(t, lintErrors[1].Err, "provider \"unsupported-type\": type git is not supported")
+	})
+}
@@ -0,0 +1,165 @@
+package dashboards
+
+import (
+	"context"
+	"sync"
+
+	"github.com/grafana/grafana/pkg/infra/metrics"
+)
+
+// parseCache keeps what was parsed from each dashboard file of a provider by content hash, so walks of the disk
+// don't parse files again that haven't changed and are up to date in the database. Entries are keyed by path and
+// only used while the content hash matches, which covers files replaced by an atomic rename too.
+type parseCache struct {
+	provider string
+
+	mutex   sync.Mutex
+	entries map[string]parseCacheEntry
+	// seen holds the paths looked up or stored during the current walk, the others are dropped by finishWalk.
+	seen map[string]bool
+}
+
+type parseCacheEntry struct {
+	checkSum string
+	folderID int64
+	metadata provisioningMetadata
+}
+
+func newParseCache(provider string) *parseCache {
+	return &parseCache{
+		provider: provider,
+		entries:  map[string]parseCacheEntry{},
+		seen:     map[string]bool{},
+	}
+}
+
+// lookup returns the metadata parsed from the file at path if its content and folder are the same as when it was
+// stored. Files that aren't up to date in the database always miss, since they have to be parsed to be saved.
+func (c *parseCache) lookup(path string, checkSum string, folderID int64, upToDate bool) (provisioningMetadata, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.seen[path] = true
+	entry, ok := c.entries[path]
+	if !upToDate || !ok || entry.checkSum != checkSum || entry.folderID != folderID {
+		metrics.MProvisioningParseCache.WithLabelValues(c.provider, "miss").Inc()
+		return provisioningMetadata{}, false
+	}
+	metrics.MProvisioningParseCache.WithLabelValues(c.provider, "hit").Inc()
+	return entry.metadata, true
+}
+
+// setProvider labels the metrics of the cache with the name of the provider, which can change across reloads.
+func (c *parseCache) setProvider(provider string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.provider = provider
+}
+
+func (c *parseCache) put(path string, checkSum string, folderID int64, metadata provisioningMetadata) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.seen[path] = true
+	c.entries[path] = parseCacheEntry{checkSum: checkSum, folderID: folderID, metadata: metadata}
+}
+
+// cached returns the metadata stored for the file at path, whatever its content is now, and marks it as found during
+// the current walk. It's for files that are known not to have changed.
+func (c *parseCache) cached(path string) (provisioningMetadata, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.seen[path] = true
+	entry, ok := c.entries[path]
+	return entry.metadata, ok
+}
+
+// keep marks the entry of the file at path as found during the current walk, for files that didn't need a lookup.
+func (c *parseCache) keep(path string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.seen[path] = true
+}
+
+// finishWalk drops the entries of files that weren't found during the walk, e.g. because they were deleted.
+func (c *parseCache) finishWalk() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for path := range c.entries {
+		if !c.seen[path] {
+			delete(c.entries, path)
+		}
+	}
+	c.seen = map[string]bool{}
+}
+
+// ParseCaches holds the parse caches of the dashboard providers, by org and path, so they outlive the provisioners
+// that are created again on every reload. The provisioning service owns one, and passes it to New with
+// WithParseCaches.
+type ParseCaches struct {
+	mutex  sync.Mutex
+	caches map[parseCacheKey]*parseCache
+}
+
+// parseCacheKey is the org of a provider and the absolute path it reads, so providers that are renamed keep their
+// cache and providers of different orgs never share one.
+type parseCacheKey struct {
+	orgID int64
+	path  string
+}
+
+// NewParseCaches returns empty parse caches.
+func NewParseCaches() *ParseCaches {
+	return &ParseCaches{caches: map[parseCacheKey]*parseCache{}}
+}
+
+// get returns the parse cache of the file reader, counted in the metrics of its provider.
+func (c *ParseCaches) get(fr *FileReader) *parseCache {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := parseCacheKey{orgID: fr.Cfg.OrgID, path: fr.rootPath()}
+	cache, ok := c.caches[key]
+	if !ok {
+		cache = newParseCache(fr.Cfg.Name)
+		c.caches[key] = cache
+	}
+	cache.setProvider(fr.Cfg.Name)
+	return cache
+}
+
+// retain drops the parse caches of the providers that are no longer configured.
+func (c *ParseCaches) retain(readers []*FileReader) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	configured := make(map[parseCacheKey]bool, len(readers))
+	for _, fr := range readers {
+		configured[parseCacheKey{orgID: fr.Cfg.OrgID, path: fr.rootPath()}] = true
+	}
+	for key := range c.caches {
+		if !configured[key] {
+			delete(c.caches, key)
+		}
+	}
+}
+
+type parseCachesKey struct{}
+
+// WithParseCaches returns a copy of ctx that makes the provisioners New creates keep their parse caches in caches.
+func WithParseCaches(ctx context.Context, caches *ParseCaches) context.Context {
+	return context.WithValue(ctx, parseCachesKey{}, caches)
+}
+
+// parseCachesFromContext returns the parse caches carried by ctx, or new ones that only the provisioner being
+// created uses.
+func parseCachesFromContext(ctx context.Context) *ParseCaches {
+	if caches, ok := ctx.Value(parseCachesKey{}).(*ParseCaches); ok && caches != nil {
+		return caches
+	}
+	return NewParseCaches()
+}
@@ -0,0 +1,159 @@
+package dashboards
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/metrics"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCache(t *testing.T) {
+	cache := newParseCache("test-parse-cache")
+	metadata := provisioningMetadata{uid: "abc"}
+
+	_, ok := cache.lookup("a.json", "sum", 1, true)
+	require.False(t, ok, "Empty cache should miss")
+
+	cache.put("a.json", "sum", 1, metadata)
+	cached, ok := cache.lookup("a.json", "sum", 1, true)
+	require.True(t, ok)
+	require.Equal(t, metadata, cached)
+
+	_, ok = cache.lookup("a.json", "other-sum", 1, true)
+	require.False(t, ok, "Changed content should miss")
+	_, ok = cache.lookup("a.json", "sum", 2, true)
+	require.False(t, ok, "Changed folder should miss")
+	_, ok = cache.lookup("a.json", "sum", 1, false)
+	require.False(t, ok, "Files to save should miss")
+
+	cache.finishWalk()
+	require.Contains(t, cache.entries, "a.json", "Files looked up during the walk should be kept")
+	cache.finishWalk()
+	require.NotContains(t, cache.entries, "a.json", "Files not found during the walk should be dropped")
+}
+
+func TestWalkDiskUsesParseCache(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+	})
+	fakeService = mockDashboardProvisioningService()
+	bus.AddHandler("test", mockGetDashboardQuery)
+	t.Cleanup(bus.ClearBusHandlers)
+
+	dir := t.TempDir()
+	dashboardPath := filepath.Join(dir, "dashboard.json")
+	writeDashboard := func(title string) {
+		// Replace the file with an atomic rename, like config map updates do.
+		tmpPath := filepath.Join(dir, ".dashboard.json.tmp")
+		require.NoError(t, ioutil.WriteFile(tmpPath, []byte(`{"title": "`+title+`"}`), 0600))
+		require.NoError(t, os.Rename(tmpPath, dashboardPath))
+	}
+	writeDashboard("First")
+
+	const provider = "test-walk-parse-cache"
+	reader, err := NewDashboardFileReader(&config{
+		Name:    provider,
+		Type:    "file",
+		OrgID:   1,
+		Options: map[string]interface{}{"path": dir},
+	}, log.New("test.logger"), nil)
+	require.NoError(t, err)
+
+	hits := metrics.MProvisioningParseCache.WithLabelValues(provider, "hit")
+	misses := metrics.MProvisioningParseCache.WithLabelValues(provider, "miss")
+
+	require.NoError(t, reader.walkDisk(context.Background()))
+	require.Len(t, fakeService.inserted, 1)
+	require.Equal(t, float64(1), testutil.ToFloat64(misses))
+
+	require.NoError(t, reader.walkDisk(context.Background()))
+	require.Equal(t, float64(1), testutil.ToFloat64(hits), "Unchanged file should not be parsed again")
+	require.Len(t, fakeService.inserted, 1)
+
+	writeDashboard("Second")
+	require.NoError(t, reader.walkDisk(context.Background()))
+	require.Equal(t, float64(2), testutil.ToFloat64(misses), "Changed file should be parsed again")
+	require.Len(t, fakeService.inserted, 1, "Changed dashboard should have replaced the first one")
+	require.Equal(t, "Second", fakeService.inserted[0].Dashboard.Title)
+
+	require.NoError(t, os.Remove(dashboardPath))
+	require.NoError(t, reader.walkDisk(context.Background()))
+	require.Empty(t, reader.parseCache.entries, "Deleted file should be dropped from the cache")
+}
+
+func TestParseCaches(t *testing.T) {
+	bus.AddHandler("test", func(query *models.GetOrgByIdQuery) error {
+		query.Result = &models.Org{Id: query.Id}
+		return nil
+	})
+	t.Cleanup(bus.ClearBusHandlers)
+	dashboardsDir := t.TempDir()
+	writeProviders := func(t *testing.T, providers string) string {
+		t.Helper()
+		dir := t.TempDir()
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "providers.yaml"),
+			[]byte("apiVersion: 1\nproviders:\n"+providers), 0600))
+		return dir
+	}
+	provider := func(name string, orgID int) string {
+		return "  - name: " + name + "\n    orgId: " + strconv.Itoa(orgID) + "\n    type: file\n    options:\n      path: " +
+			dashboardsDir + "\n"
+	}
+	readers := func(t *testing.T, ctx context.Context, configDir string) []*FileReader {
+		t.Helper()
+		provisioner, err := New(ctx, []string{configDir}, nil, setting.NewCfg())
+		require.NoError(t, err)
+		return provisioner.(*Provisioner).fileReaders
+	}
+
+	t.Run("Providers of different orgs reading the same path have their own cache", func(t *testing.T) {
+		ctx := WithParseCaches(context.Background(), NewParseCaches())
+		configDir := writeProviders(t, provider("default", 1)+provider("other", 2))
+
+		first := readers(t, ctx, configDir)
+		require.Len(t, first, 2)
+		assert.NotSame(t, first[0].parseCache, first[1].parseCache)
+
+		again := readers(t, ctx, configDir)
+		assert.Same(t, first[0].parseCache, again[0].parseCache, "The cache outlives the provisioner")
+		assert.Same(t, first[1].parseCache, again[1].parseCache)
+	})
+
+	t.Run("A renamed provider keeps its cache", func(t *testing.T) {
+		ctx := WithParseCaches(context.Background(), NewParseCaches())
+		before := readers(t, ctx, writeProviders(t, provider("default", 1)))
+		after := readers(t, ctx, writeProviders(t, provider("renamed", 1)))
+		assert.Same(t, before[0].parseCache, after[0].parseCache)
+		assert.Equal(t, "renamed", after[0].parseCache.provider, "The metrics are labeled with the new name")
+	})
+
+	t.Run("Caches of providers that are no longer configured are dropped", func(t *testing.T) {
+		caches := NewParseCaches()
+		ctx := WithParseCaches(context.Background(), caches)
+		readers(t, ctx, writeProviders(t, provider("default", 1)+provider("other", 2)))
+		require.Len(t, caches.caches, 2)
+
+		readers(t, ctx, writeProviders(t, provider("default", 1)))
+		assert.Len(t, caches.caches, 1)
+	})
+
+	t.Run("Provisioners created without caches don't share them", func(t *testing.T) {
+		configDir := writeProviders(t, provider("default", 1))
+		first := readers(t, context.Background(), configDir)
+		second := readers(t, context.Background(), configDir)
+		assert.NotSame(t, first[0].parseCache, second[0].parseCache)
+	})
+}
@@ -0,0 +1,134 @@
+package dashboards
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// resumeState lets the walk after a failed one skip the files the failed walk already applied and that haven't
+// changed since, so a retry resumes at the file that failed instead of reading every file again. A walk that saved
+// every file it could but failed to save some counts as failed too, so the next one resumes at those files. It's
+// kept in memory only and reset when the config of the provider changes.
+type resumeState struct {
+	// fingerprint identifies the provider config the files were applied with.
+	fingerprint string
+
+	mutex sync.Mutex
+	// failed is set when the last walk failed, only then are the files in applied skipped.
+	failed  bool
+	applied map[string]resumeEntry
+	// failing are the files the current walk failed to save.
+	failing map[string]bool
+}
+
+type resumeEntry struct {
+	modTime  time.Time
+	size     int64
+	folderID int64
+	metadata provisioningMetadata
+}
+
+func newResumeState(fingerprint string) *resumeState {
+	return &resumeState{fingerprint: fingerprint, applied: map[string]resumeEntry{}, failing: map[string]bool{}}
+}
+
+// lookup returns the metadata of the file at path if the failed walk before applied it, and it has the same
+// modification time, size and folder now.
+func (s *resumeState) lookup(path string, fileInfo os.FileInfo, folderID int64) (provisioningMetadata, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.failed {
+		return provisioningMetadata{}, false
+	}
+	entry, ok := s.applied[path]
+	if !ok || !entry.modTime.Equal(fileInfo.ModTime()) || entry.size != fileInfo.Size() || entry.folderID != folderID {
+		return provisioningMetadata{}, false
+	}
+	return entry.metadata, true
+}
+
+// done records that the file at path is applied.
+func (s *resumeState) done(path string, fileInfo os.FileInfo, folderID int64, metadata provisioningMetadata) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.applied[path] = resumeEntry{modTime: fileInfo.ModTime(), size: fileInfo.Size(), folderID: folderID, metadata: metadata}
+}
+
+// fail records that the current walk failed to save the file at path, so the next walk saves it again.
+func (s *resumeState) fail(path string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.failing[path] = true
+	delete(s.applied, path)
+}
+
+// failedFiles returns the files the current walk failed to save, sorted.
+func (s *resumeState) failedFiles() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	paths := make([]string, 0, len(s.failing))
+	for path := range s.failing {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// finishWalk keeps the applied files for the next walk when this one failed or failed to save some files, and
+// forgets them otherwise.
+func (s *resumeState) finishWalk(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.failed = err != nil || len(s.failing) > 0
+	s.failing = map[string]bool{}
+	if !s.failed {
+		s.applied = map[string]resumeEntry{}
+	}
+}
+
+// resumeStates holds the resume state of every dashboard provider, since the provisioner of a failed run is
+// thrown away and the retry creates a new one.
+var resumeStates = struct {
+	sync.Mutex
+	byProvider map[string]*resumeState
+}{byProvider: map[string]*resumeState{}}
+
+// providerResumeState returns the resume state of the provider, a new one when the provider config or the locale
+// changed.
+func providerResumeState(cfg *config, locale string) *resumeState {
+	fingerprint := fmt.Sprintf("%+v locale=%s", *cfg, locale)
+
+	resumeStates.Lock()
+	defer resumeStates.Unlock()
+
+	state, ok := resumeStates.byProvider[cfg.Name]
+	if !ok || state.fingerprint != fingerprint {
+		state = newResumeState(fingerprint)
+		resumeStates.byProvider[cfg.Name] = state
+	}
+	return state
+}
+
+// retainResumeStates drops the resume states of the providers that are no longer configured.
+func retainResumeStates(configs []*config) {
+	resumeStates.Lock()
+	defer resumeStates.Unlock()
+
+	configured := make(map[string]bool, len(configs))
+	for _, cfg := range configs {
+		configured[cfg.Name] = true
+	}
+	for provider := range resumeStates.byProvider {
+		if !configured[provider] {
+			delete(resumeStates.byProvider, provider)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package dashboards
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeFileInfo struct {
+	os.FileInfo
+	modTime time.Time
+	size    int64
+}
+
+func (fi fakeFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi fakeFileInfo) Size() int64        { return fi.size }
+
+func TestResumeState(t *testing.T) {
+	modTime := time.Now()
+	fileInfo := fakeFileInfo{modTime: modTime, size: 10}
+	metadata := provisioningMetadata{uid: "abc"}
+
+	state := newResumeState("")
+	state.done("a.json", fileInfo, 1, metadata)
+	_, ok := state.lookup("a.json", fileInfo, 1)
+	require.False(t, ok, "Files are only skipped after a failed walk")
+
+	state.finishWalk(errors.New("database is locked"))
+	resumed, ok := state.lookup("a.json", fileInfo, 1)
+	require.True(t, ok)
+	require.Equal(t, metadata, resumed)
+
+	_, ok = state.lookup("a.json", fakeFileInfo{modTime: modTime.Add(time.Second), size: 10}, 1)
+	require.False(t, ok, "Modified files aren't skipped")
+	_, ok = state.lookup("a.json", fakeFileInfo{modTime: modTime, size: 11}, 1)
+	require.False(t, ok, "Files of another size aren't skipped")
+	_, ok = state.lookup("a.json", fileInfo, 2)
+	require.False(t, ok, "Files of another folder aren't skipped")
+
+	state.finishWalk(nil)
+	_, ok = state.lookup("a.json", fileInfo, 1)
+	require.False(t, ok, "A successful walk forgets the applied files")
+	require.Empty(t, state.applied)
+
+	state.done("a.json", fileInfo, 1, metadata)
+	state.done("b.json", fileInfo, 1, metadata)
+	state.fail("b.json")
+	require.Equal(t, []string{"b.json"}, state.failedFiles())
+	state.finishWalk(nil)
+	_, ok = state.lookup("a.json", fileInfo, 1)
+	require.True(t, ok, "A walk that failed to save some files counts as failed")
+	_, ok = state.lookup("b.json", fileInfo, 1)
+	require.False(t, ok, "Files that failed to save aren't skipped")
+	require.Empty(t, state.failedFiles(), "Failed files are recorded for one walk")
+}
+
+func TestProviderResumeState(t *testing.T) {
+	cfg := &config{Name: "test-provider-resume-state", Type: "file", Options: map[string]interface{}{"path": "/a"}}
+	state := providerResumeState(cfg, "")
+	assert.Same(t, state, providerResumeState(cfg, ""), "The state outlives the reader")
+
+	changed := *cfg
+	changed.Options = map[string]interface{}{"path": "/b"}
+	assert.NotSame(t, state, providerResumeState(&changed, ""), "Changing the config resets the state")
+	assert.NotSame(t, providerResumeState(&changed, ""), providerResumeState(&changed, "de-DE"),
+		"Changing the locale resets the state")
+
+	retainResumeStates(nil)
+	assert.NotContains(t, resumeStates.byProvider, cfg.Name)
+}
+
+// cancelingProvisioningService cancels a walk once it saved a dashboard.
+type cancelingProvisioningService struct {
+	*fakeDashboardProvisioningService
+	cancel context.CancelFunc
+}
+
+func (s *cancelingProvisioningService) SaveProvisionedDashboard(dto *dashboards.SaveDashboardDTO,
+	provisioning *models.DashboardProvisioning) (*models.Dashboard, error) {
+	defer s.cancel()
+	return s.fakeDashboardProvisioningService.SaveProvisionedDashboard(dto, provisioning)
+}
+
+func TestWalkDiskResumesAfterFailedWalk(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+	})
+	fakeService = mockDashboardProvisioningService()
+	bus.AddHandler("test", mockGetDashboardQuery)
+	t.Cleanup(bus.ClearBusHandlers)
+
+	dir := t.TempDir()
+	for i := 1; i <= 3; i++ {
+		content := fmt.Sprintf(`{"title": "Dashboard %d", "uid": "dashboard-%d"}`, i, i)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("dashboard%d.json", i)), []byte(content), 0600))
+	}
+
+	cfg := &config{Name: "test-walk-resume", Type: "file", OrgID: 1, Options: map[string]interface{}{"path": dir}}
+	t.Cleanup(func() { retainResumeStates(nil) })
+	newReader := func() *FileReader {
+		reader, err := NewDashboardFileReader(cfg, log.New("test.logger"), nil)
+		require.NoError(t, err)
+		reader.MaxConcurrency = 1
+		reader.resume = providerResumeState(cfg, "")
+		return reader
+	}
+
+	failing := newReader()
+	ctx, cancel := context.WithCancel(context.Background())
+	failing.dashboardProvisioningService = &cancelingProvisioningService{fakeDashboardProvisioningService: fakeService, cancel: cancel}
+	require.ErrorIs(t, failing.walkDisk(ctx), context.Canceled)
+	applied := len(fakeService.inserted)
+	require.Greater(t, applied, 0)
+	require.Less(t, applied, 3)
+
+	retry := newReader()
+	require.NoError(t, retry.walkDisk(context.Background()))
+	assert.Equal(t, applied, retry.ResumedFiles(), "The files applied by the failed walk should be skipped")
+	assert.Len(t, fakeService.provisioned[cfg.Name], 3)
+	assert.Len(t, retry.ProvisionedDashboards(), 3)
+
+	require.NoError(t, retry.walkDisk(context.Background()))
+	assert.Equal(t, 0, retry.ResumedFiles(), "Walks after a successful walk don't resume")
+}
+
+// failingProvisioningService fails to save the dashboard with the given uid once.
+type failingProvisioningService struct {
+	*fakeDashboardProvisioningService
+	uid    string
+	failed bool
+}
+
+func (s *failingProvisioningService) SaveProvisionedDashboard(dto *dashboards.SaveDashboardDTO,
+	provisioning *models.DashboardProvisioning) (*models.Dashboard, error) {
+	if dto.Dashboard.Uid == s.uid && !s.failed {
+		s.failed = true
+		return nil, errors.New("database is locked")
+	}
+	return s.fakeDashboardProvisioningService.SaveProvisionedDashboard(dto, provisioning)
+}
+
+func TestWalkDiskResumesAfterFailedSave(t *testing.T) {
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+	})
+	fakeService = mockDashboardProvisioningService()
+	bus.AddHandler("test", mockGetDashboardQuery)
+	t.Cleanup(bus.ClearBusHandlers)
+
+	dir := t.TempDir()
+	for i := 1; i <= 3; i++ {
+		content := fmt.Sprintf(`{"title": "Dashboard %d", "uid": "dashboard-%d"}`, i, i)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("dashboard%d.json", i)), []byte(content), 0600))
+	}
+
+	cfg := &config{Name: "test-walk-resume-failed-save", Type: "file", OrgID: 1, Options: map[string]interface{}{"path": dir}}
+	t.Cleanup(func() { retainResumeStates(nil) })
+	reader, err := NewDashboardFileReader(cfg, log.New("test.logger"), nil)
+	require.NoError(t, err)
+	reader.MaxConcurrency = 1
+	reader.resume = providerResumeState(cfg, "")
+	reader.dashboardProvisioningService = &failingProvisioningService{fakeDashboardProvisioningService: fakeService,
+		uid: "dashboard-2"}
+
+	require.NoError(t, reader.walkDisk(context.Background()), "A failed save doesn't stop the other files")
+	require.Len(t, fakeService.provisioned[cfg.Name], 2)
+	assert.True(t, reader.resume.failed, "A walk that failed to save a file counts as failed")
+
+	require.NoError(t, reader.walkDisk(context.Background()))
+	assert.Equal(t, 2, reader.ResumedFiles(), "The walk after a failed save should resume at the failed file")
+	assert.Len(t, fakeService.provisioned[cfg.Name], 3)
+
+	require.NoError(t, reader.walkDisk(context.Background()))
+	assert.Equal(t, 0, reader.ResumedFiles(), "Walks after a walk that saved every file don't resume")
+}
@@ -14,6 +14,7 @@ type config struct {
 	Name                  string
 	Type                  string
 	OrgID                 int64
+	OrgName               string
 	Folder                string
 	FolderUID             string
 	Editable              bool
@@ -21,6 +22,19 @@ type config struct {
 	DisableDeletion       bool
 	UpdateIntervalSeconds int64
 	AllowUIUpdates        bool
+	// DisableFolderCreation makes a missing FolderUID folder an error instead of creating it.
+	DisableFolderCreation bool
+	// FolderMapping routes the dashboard files matching a pattern to another folder than Folder.
+	FolderMapping []folderMapping
+	// Lazy defers provisioning the dashboards that aren't in the database yet until they're requested.
+	Lazy bool
+}
+
+// folderMapping is a rule of the folderMapping of a provider, see mappedFolder.
+type folderMapping struct {
+	Pattern   string
+	Folder    string
+	FolderUID string
 }
 
 type configV0 struct {
@@ -41,6 +55,8 @@ type configVersion struct {
 }
 
 type configV1 struct {
+	configVersion `yaml:",inline"`
+
 	Providers []*configs `json:"providers" yaml:"providers"`
 }
 
@@ -48,6 +64,7 @@ type configs struct {
 	Name                  values.StringValue `json:"name" yaml:"name"`
 	Type                  values.StringValue `json:"type" yaml:"type"`
 	OrgID                 values.Int64Value  `json:"orgId" yaml:"orgId"`
+	OrgName               values.StringValue `json:"orgName" yaml:"orgName"`
 	Folder                values.StringValue `json:"folder" yaml:"folder"`
 	FolderUID             values.StringValue `json:"folderUid" yaml:"folderUid"`
 	Editable              values.BoolValue   `json:"editable" yaml:"editable"`
@@ -55,6 +72,15 @@ type configs struct {
 	DisableDeletion       values.BoolValue   `json:"disableDeletion" yaml:"disableDeletion"`
 	UpdateIntervalSeconds values.Int64Value  `json:"updateIntervalSeconds" yaml:"updateIntervalSeconds"`
 	AllowUIUpdates        values.BoolValue   `json:"allowUiUpdates" yaml:"allowUiUpdates"`
+	DisableFolderCreation values.BoolValue   `json:"disableFolderCreation" yaml:"disableFolderCreation"`
+	FolderMapping         []folderMappingV1  `json:"folderMapping" yaml:"folderMapping"`
+	Lazy                  values.BoolValue   `json:"lazy" yaml:"lazy"`
+}
+
+type folderMappingV1 struct {
+	Pattern   values.StringValue `json:"pattern" yaml:"pattern"`
+	Folder    values.StringValue `json:"folder" yaml:"folder"`
+	FolderUID values.StringValue `json:"folderUid" yaml:"folderUid"`
 }
 
 func createDashboardJSON(data *simplejson.Json, lastModified time.Time, cfg *config, folderID int64) (*dashboards.SaveDashboardDTO, error) {
@@ -114,6 +140,7 @@ func (dc *configV1) mapToDashboardsAsConfig() ([]*config, error) {
 			Name:                  v.Name.Value(),
 			Type:                  v.Type.Value(),
 			OrgID:                 v.OrgID.Value(),
+			OrgName:               v.OrgName.Value(),
 			Folder:                v.Folder.Value(),
 			FolderUID:             v.FolderUID.Value(),
 			Editable:              v.Editable.Value(),
@@ -121,8 +148,23 @@ func (dc *configV1) mapToDashboardsAsConfig() ([]*config, error) {
 			DisableDeletion:       v.DisableDeletion.Value(),
 			UpdateIntervalSeconds: v.UpdateIntervalSeconds.Value(),
 			AllowUIUpdates:        v.AllowUIUpdates.Value(),
+			DisableFolderCreation: v.DisableFolderCreation.Value(),
+			FolderMapping:         mapFolderMapping(v.FolderMapping),
+			Lazy:                  v.Lazy.Value(),
 		})
 	}
 
 	return r, nil
 }
+
+func mapFolderMapping(v1 []folderMappingV1) []folderMapping {
+	var mapping []folderMapping
+	for _, rule := range v1 {
+		mapping = append(mapping, folderMapping{
+			Pattern:   rule.Pattern.Value(),
+			Folder:    rule.Folder.Value(),
+			FolderUID: rule.FolderUID.Value(),
+		})
+	}
+	return mapping
+}
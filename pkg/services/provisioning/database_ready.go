@@ -0,0 +1,76 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// ErrDatabaseNotReady is returned, wrapped with the time waited, by RunInitProvisioners when the database doesn't
+// answer within the database_ready_timeout setting.
+var ErrDatabaseNotReady = errors.New("database not ready")
+
+// maxDatabaseReadyInterval caps the doubling of the wait between the pings of waitForDatabase.
+const maxDatabaseReadyInterval = 30 * time.Second
+
+// waitForDatabase pings the database until it answers, before any stage runs, so a database that went away doesn't
+// fail provisioning partway. At startup it runs in Init, after the SQL store was initialized, which already connected
+// to the database and migrated it; so it only covers a database that drops between the migrations and provisioning,
+// not one that isn't up yet. The wait between pings starts at the database_ready_interval setting and
+// doubles after every failed ping. Nothing is waited for when the database_ready_timeout setting is zero.
+func (ps *provisioningServiceImpl) waitForDatabase(ctx context.Context) error {
+	timeout := ps.Cfg.ProvisioningDatabaseReadyTimeout
+	if timeout <= 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(timeout)
+	interval := ps.Cfg.ProvisioningDatabaseReadyInterval
+	for pings := 1; ; pings++ {
+		err := ps.pingDatabase(ctx)
+		if err == nil {
+			if pings > 1 {
+				ps.log.Info("Database is ready for provisioning", "pings", pings)
+			}
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%w after %s: %v", ErrDatabaseNotReady, timeout, err)
+		}
+		wait := interval
+		if wait > remaining {
+			wait = remaining
+		}
+		ps.log.Warn("Database isn't ready for provisioning, waiting", "error", err, "retryIn", wait)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		if interval < maxDatabaseReadyInterval {
+			interval *= 2
+			if interval > maxDatabaseReadyInterval {
+				interval = maxDatabaseReadyInterval
+			}
+		}
+	}
+}
+
+// pingDatabase checks that the database answers with the GetDBHealthQuery of the SQL store, or with the ping the
+// service was given.
+func (ps *provisioningServiceImpl) pingDatabase(ctx context.Context) error {
+	if ps.databasePing != nil {
+		return ps.databasePing(ctx)
+	}
+	return bus.DispatchCtx(ctx, &models.GetDBHealthQuery{})
+}
@@ -0,0 +1,67 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForDatabase(t *testing.T) {
+	t.Cleanup(bus.ClearBusHandlers)
+	setupDatabase := func(t *testing.T, failures int) (*serviceTestStruct, *int) {
+		t.Helper()
+
+		pings := 0
+		bus.ClearBusHandlers()
+		bus.AddHandler("test", func(query *models.GetDBHealthQuery) error {
+			pings++
+			if failures < 0 || pings <= failures {
+				return errors.New("connection refused")
+			}
+			return nil
+		})
+		serviceTest := setup()
+		serviceTest.service.Cfg.ProvisioningDatabaseReadyTimeout = time.Second
+		serviceTest.service.Cfg.ProvisioningDatabaseReadyInterval = 10 * time.Millisecond
+		return serviceTest, &pings
+	}
+
+	t.Run("Waits until the database answers", func(t *testing.T) {
+		serviceTest, pings := setupDatabase(t, 2)
+
+		require.NoError(t, serviceTest.service.waitForDatabase(context.Background()))
+		assert.Equal(t, 3, *pings)
+	})
+
+	t.Run("Fails when the database doesn't answer in time", func(t *testing.T) {
+		serviceTest, _ := setupDatabase(t, -1)
+		serviceTest.service.Cfg.ProvisioningDatabaseReadyTimeout = 50 * time.Millisecond
+
+		err := serviceTest.service.RunInitProvisioners(context.Background())
+		require.True(t, errors.Is(err, ErrDatabaseNotReady))
+		assert.EqualError(t, err, "database not ready after 50ms: connection refused")
+	})
+
+	t.Run("Stops waiting when the context is canceled", func(t *testing.T) {
+		serviceTest, _ := setupDatabase(t, -1)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := serviceTest.service.waitForDatabase(ctx)
+		require.True(t, errors.Is(err, context.Canceled))
+	})
+
+	t.Run("Doesn't ping the database without a timeout", func(t *testing.T) {
+		serviceTest, pings := setupDatabase(t, -1)
+		serviceTest.service.Cfg.ProvisioningDatabaseReadyTimeout = 0
+
+		require.NoError(t, serviceTest.service.waitForDatabase(context.Background()))
+		assert.Zero(t, *pings)
+	})
+}
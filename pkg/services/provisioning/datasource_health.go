@@ -0,0 +1,48 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/plugins/adapters"
+	"github.com/grafana/grafana/pkg/plugins/backendplugin"
+	"github.com/grafana/grafana/pkg/services/provisioning/datasources"
+)
+
+// checkDatasourceHealth runs the health check of the backend plugin of a provisioned datasource, the same one the
+// datasource health API runs.
+func (ps *provisioningServiceImpl) checkDatasourceHealth(ctx context.Context, ds *models.DataSource) error {
+	if ps.PluginManager == nil || ps.BackendPluginManager == nil {
+		return datasources.ErrHealthCheckNotSupported
+	}
+
+	plugin := ps.PluginManager.GetDataSource(ds.Type)
+	if plugin == nil {
+		return fmt.Errorf("data source plugin %q not found", ds.Type)
+	}
+
+	instanceSettings, err := adapters.ModelToInstanceSettings(ds)
+	if err != nil {
+		return err
+	}
+
+	resp, err := ps.BackendPluginManager.CheckHealth(ctx, backend.PluginContext{
+		OrgID:                      ds.OrgId,
+		PluginID:                   plugin.Id,
+		DataSourceInstanceSettings: instanceSettings,
+	})
+	if errors.Is(err, backendplugin.ErrPluginNotRegistered) || errors.Is(err, backendplugin.ErrMethodNotImplemented) {
+		return datasources.ErrHealthCheckNotSupported
+	}
+	if err != nil {
+		return err
+	}
+
+	if resp.Status != backend.HealthStatusOk {
+		return fmt.Errorf("%s: %s", resp.Status, resp.Message)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package datasources
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// certFileJSONDataKeys are the jsonData fields data sources use to reference certificate files on disk,
+// such as the CA bundle of the PostgreSQL data source.
+var certFileJSONDataKeys = []string{"sslRootCertFile", "sslCertFile", "sslKeyFile"}
+
+// provisionedCertFiles tracks the certificate files referenced by the last provisioned data sources.
+var provisionedCertFiles = newCertFileTracker()
+
+// CertFilesChanged reports whether a certificate file referenced by a provisioned data source was
+// modified, created or removed since the data sources were last provisioned.
+func CertFilesChanged() bool {
+	return provisionedCertFiles.changed()
+}
+
+// certFileTracker remembers the modification times of certificate files, so data sources can be
+// provisioned again when a certificate is rotated without any change to the provisioning files.
+type certFileTracker struct {
+	mutex  sync.Mutex
+	mtimes map[string]time.Time
+}
+
+func newCertFileTracker() *certFileTracker {
+	return &certFileTracker{mtimes: map[string]time.Time{}}
+}
+
+// track replaces the tracked files with the files referenced by the given configs.
+func (t *certFileTracker) track(configs []*configs) {
+	mtimes := map[string]time.Time{}
+	for _, cfg := range configs {
+		for _, ds := range cfg.Datasources {
+			for _, path := range referencedCertFiles(ds) {
+				mtimes[path] = certFileModTime(path)
+			}
+		}
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.mtimes = mtimes
+}
+
+func (t *certFileTracker) changed() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for path, mtime := range t.mtimes {
+		if !certFileModTime(path).Equal(mtime) {
+			return true
+		}
+	}
+	return false
+}
+
+func referencedCertFiles(ds *upsertDataSourceFromConfig) []string {
+	var files []string
+	for _, key := range certFileJSONDataKeys {
+		if path, ok := ds.JSONData[key].(string); ok && path != "" {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// certFileModTime returns the zero time for missing files, so a file showing up later counts as a change.
+func certFileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,64 @@
+package datasources
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const certFilesConfig = "testdata/cert-files"
+
+func TestCertFileRotation(t *testing.T) {
+	fakeRepo = &fakeRepository{}
+	bus.ClearBusHandlers()
+	t.Cleanup(bus.ClearBusHandlers)
+	bus.AddHandler("test", mockDelete)
+	bus.AddHandler("test", mockInsert)
+	bus.AddHandler("test", mockUpdate)
+	bus.AddHandler("test", mockGet)
+	bus.AddHandler("test", mockGetOrg)
+	bus.AddHandler("test", mockSaveProvisioned)
+	bus.AddHandler("test", mockGetProvisioned)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, ioutil.WriteFile(caFile, []byte("old CA"), 0600))
+	_ = os.Setenv("CA_BUNDLE_FILE", caFile)
+	t.Cleanup(func() { _ = os.Unsetenv("CA_BUNDLE_FILE") })
+
+	dc := newDatasourceProvisioner(logger)
+	dc.certFiles = newCertFileTracker()
+
+	require.NoError(t, dc.applyChanges(context.Background(), certFilesConfig))
+	require.Len(t, fakeRepo.inserted, 1)
+	fakeRepo.loadAll = []*models.DataSource{{Name: "Postgres", OrgId: 1, Id: 1}}
+
+	t.Run("Unchanged CA file isn't reported", func(t *testing.T) {
+		assert.False(t, dc.certFiles.changed())
+	})
+
+	t.Run("Modified CA file is reported and reprovisioning updates the datasource", func(t *testing.T) {
+		require.NoError(t, ioutil.WriteFile(caFile, []byte("new CA"), 0600))
+		rotated := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(caFile, rotated, rotated))
+		require.True(t, dc.certFiles.changed())
+
+		require.NoError(t, dc.applyChanges(context.Background(), certFilesConfig))
+
+		require.Len(t, fakeRepo.updated, 1)
+		assert.Equal(t, caFile, fakeRepo.updated[0].JsonData.Get("sslRootCertFile").MustString())
+		assert.False(t, dc.certFiles.changed())
+	})
+
+	t.Run("Removed CA file is reported", func(t *testing.T) {
+		require.NoError(t, os.Remove(caFile))
+		assert.True(t, dc.certFiles.changed())
+	})
+}
@@ -1,6 +1,8 @@
 package datasources
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -10,17 +12,61 @@ import (
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/provisioning/utils"
-	"gopkg.in/yaml.v2"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 type configReader struct {
-	log log.Logger
+	log        log.Logger
+	fileFilter setting.ProvisioningFileFilter
+	// offline skips the validation that needs a database, like checking that orgs exist.
+	offline bool
+	// strict rejects unknown fields instead of ignoring them.
+	strict bool
+	// env is what the guards of the files are checked against.
+	env utils.Environment
 }
 
-func (cr *configReader) readConfig(path string) ([]*configs, error) {
+// deprecatedFields are the fields of version 1 config files that have a replacement.
+var deprecatedFields = []utils.DeprecatedField{
+	{Path: "datasources[].password", Replacement: "datasources[].secureJsonData.password"},
+	{Path: "datasources[].basicAuthPassword", Replacement: "datasources[].secureJsonData.basicAuthPassword"},
+}
+
+// readConfig reads the config files of each directory in order. Datasources of later directories override the ones
+// of earlier directories with the same name or uid in the same org. Every file is read before the files that
+// couldn't be parsed are returned as utils.FileErrors.
+func (cr *configReader) readConfig(ctx context.Context, paths ...string) ([]*configs, error) {
 	var datasources []*configs
+	var fileErrs utils.FileErrors
 
-	files, err := ioutil.ReadDir(path)
+	for _, path := range paths {
+		configs, err := cr.readDirectory(path)
+		if fileErrs, err = utils.AppendFileError(fileErrs, err); err != nil {
+			return nil, err
+		}
+
+		for _, cfg := range configs {
+			cr.overrideDatasources(datasources, cfg)
+		}
+		datasources = append(datasources, configs...)
+	}
+	if err := fileErrs.Err(); err != nil {
+		return nil, err
+	}
+
+	err := cr.validateDefaultUniqueness(ctx, datasources)
+	if err != nil {
+		return nil, err
+	}
+
+	return datasources, nil
+}
+
+func (cr *configReader) readDirectory(path string) ([]*configs, error) {
+	var datasources []*configs
+	var fileErrs utils.FileErrors
+
+	files, err := utils.ReadDir(path)
 	if err != nil {
 		cr.log.Error("can't read datasource provisioning files from directory", "path", path, "error", err)
 		return datasources, nil
@@ -28,26 +74,80 @@ func (cr *configReader) readConfig(path string) ([]*configs, error) {
 
 	for _, file := range files {
 		if strings.HasSuffix(file.Name(), ".yaml") || strings.HasSuffix(file.Name(), ".yml") {
-			datasource, err := cr.parseDatasourceConfig(path, file)
-			if err != nil {
+			if !cr.fileFilter.Includes(file.Name()) {
+				cr.log.Debug("skipping excluded datasource provisioning file", "path", path, "file.Name", file.Name())
+				continue
+			}
+
+			cfgs, err := cr.parseDatasourceConfig(path, file)
+			if fileErrs, err = utils.AppendFileError(fileErrs, err); err != nil {
 				return nil, err
 			}
 
-			if datasource != nil {
-				datasources = append(datasources, datasource)
+			if err := applyPathOrg(path, cfgs); err != nil {
+				fileErrs = append(fileErrs, utils.FileErrorsOf(err)...)
+				continue
 			}
+			datasources = append(datasources, cfgs...)
 		}
 	}
 
-	err = cr.validateDefaultUniqueness(datasources)
+	return datasources, fileErrs.Err()
+}
+
+// overrideDatasources removes the datasources of earlier configs that override replaces.
+func (cr *configReader) overrideDatasources(earlier []*configs, override *configs) {
+	for _, ds := range override.Datasources {
+		for _, cfg := range earlier {
+			kept := cfg.Datasources[:0]
+			for _, existing := range cfg.Datasources {
+				if !sameDatasource(existing, ds) {
+					kept = append(kept, existing)
+					continue
+				}
+				cr.log.Info("datasource overridden by a later provisioning path", "name", ds.Name, "orgId", ds.OrgID,
+					"overridden", cfg.Filename, "winner", override.Filename)
+			}
+			cfg.Datasources = kept
+		}
+	}
+}
+
+// sameDatasource compares datasources before their org is resolved, which defaults to 1.
+func sameDatasource(a, b *upsertDataSourceFromConfig) bool {
+	orgID := func(ds *upsertDataSourceFromConfig) int64 {
+		if ds.OrgID == 0 && ds.OrgName == "" {
+			return 1
+		}
+		return ds.OrgID
+	}
+	if orgID(a) != orgID(b) || a.OrgName != b.OrgName {
+		return false
+	}
+	return a.Name == b.Name || (a.UID != "" && a.UID == b.UID)
+}
+
+// readDocument reads a config that isn't read from a provisioning directory, a config for each of its YAML
+// documents. The datasources without an orgId are provisioned in orgID, unless it's 0. It returns no configs when
+// the guards of the documents skip them.
+func (cr *configReader) readDocument(ctx context.Context, orgID int64, yamlFile []byte) ([]*configs, error) {
+	cfgs, err := cr.parseDatasources(utils.ReaderFilename, yamlFile)
 	if err != nil {
 		return nil, err
 	}
 
-	return datasources, nil
+	for _, cfg := range cfgs {
+		if err := applyOrg(orgID, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if err := cr.validateDefaultUniqueness(ctx, cfgs); err != nil {
+		return nil, err
+	}
+	return cfgs, nil
 }
 
-func (cr *configReader) parseDatasourceConfig(path string, file os.FileInfo) (*configs, error) {
+func (cr *configReader) parseDatasourceConfig(path string, file os.FileInfo) ([]*configs, error) {
 	filename, _ := filepath.Abs(filepath.Join(path, file.Name()))
 
 	// nolint:gosec
@@ -57,53 +157,151 @@ func (cr *configReader) parseDatasourceConfig(path string, file os.FileInfo) (*c
 		return nil, err
 	}
 
-	var apiVersion *configVersion
-	err = yaml.Unmarshal(yamlFile, &apiVersion)
+	return cr.parseDatasources(filename, yamlFile)
+}
+
+// schemas read the config files by their apiVersion. Version 0 is the deprecated snake case format.
+var schemas = utils.NewSchemaRegistry(
+	utils.Schema{APIVersion: 0, New: func() interface{} { return &configsV0{} }},
+	utils.Schema{APIVersion: 1, New: func() interface{} { return &configsV1{} }, Deprecated: deprecatedFields},
+)
+
+// parseDatasources parses the content of a config file into a config for each of its YAML documents, leaving out
+// the documents whose guards skip them. The datasources have the line of the file they start at.
+func (cr *configReader) parseDatasources(filename string, yamlFile []byte) ([]*configs, error) {
+	decoder := utils.YAMLDecoder{Subsystem: "datasources", Kind: "Datasource", Strict: cr.strict, Environment: cr.env,
+		Log: cr.log}
+	docs, err := schemas.DecodeDocuments(decoder, filename, yamlFile)
 	if err != nil {
+		if errors.Is(err, utils.ErrFileSkipped) {
+			return nil, nil
+		}
 		return nil, err
 	}
 
-	if apiVersion == nil {
-		apiVersion = &configVersion{APIVersion: 0}
+	cfgs := make([]*configs, 0, len(docs))
+	for _, doc := range docs {
+		var datasources *configs
+		switch cfg := doc.Value.(type) {
+		case *configsV1:
+			cfg.log = cr.log
+			datasources = cfg.mapToDatasourceFromConfig(doc.APIVersion)
+		case *configsV0:
+			cr.log.Warn("[Deprecated] the datasource provisioning config is outdated. please upgrade", "filename", filename)
+			datasources = cfg.mapToDatasourceFromConfig(doc.APIVersion)
+		}
+		datasources.Filename = filename
+		if len(docs) > 1 {
+			datasources.Document = doc.Index
+		}
+		lines := doc.ItemLines("datasources")
+		for i, ds := range datasources.Datasources {
+			if i < len(lines) {
+				ds.Line = lines[i]
+			}
+		}
+		cfgs = append(cfgs, datasources)
 	}
+	return cfgs, nil
+}
 
-	if apiVersion.APIVersion > 0 {
-		v1 := &configsV1{log: cr.log}
-		err = yaml.Unmarshal(yamlFile, v1)
-		if err != nil {
-			return nil, err
+// applyPathOrg sets the org of the datasources of the configs of a file in a per-org directory like
+// orgs/<orgID>/datasources.
+func applyPathOrg(path string, cfgs []*configs) error {
+	for _, cfg := range cfgs {
+		if err := applyOrg(utils.OrgFromPath(path), cfg); err != nil {
+			return err
 		}
-
-		return v1.mapToDatasourceFromConfig(apiVersion.APIVersion), nil
 	}
+	return nil
+}
 
-	var v0 *configsV0
-	err = yaml.Unmarshal(yamlFile, &v0)
-	if err != nil {
-		return nil, err
+// applyOrg sets the org of the datasources without one to pathOrgID, see utils.ApplyPathOrg.
+func applyOrg(pathOrgID int64, cfg *configs) error {
+	for _, ds := range cfg.Datasources {
+		if err := utils.ApplyPathOrg(&ds.OrgID, pathOrgID); err != nil {
+			return &utils.ProvisioningFileError{Subsystem: "datasources", Path: cfg.Filename, Line: ds.Line,
+				Document: cfg.Document, Err: fmt.Errorf("data source %q: %w", ds.Name, err)}
+		}
+	}
+	for _, ds := range cfg.DeleteDatasources {
+		if err := utils.ApplyPathOrg(&ds.OrgID, pathOrgID); err != nil {
+			return &utils.ProvisioningFileError{Subsystem: "datasources", Path: cfg.Filename,
+				Document: cfg.Document, Err: fmt.Errorf("deleted data source %q: %w", ds.Name, err)}
+		}
 	}
+	return nil
+}
 
-	cr.log.Warn("[Deprecated] the datasource provisioning config is outdated. please upgrade", "filename", filename)
+func (cr *configReader) validateDefaultUniqueness(ctx context.Context, datasources []*configs) error {
+	for i := range datasources {
+		if err := cr.validateDatasources(ctx, datasources[i]); err != nil {
+			return err
+		}
+	}
 
-	return v0.mapToDatasourceFromConfig(apiVersion.APIVersion), nil
+	return validateSingleDefault(datasources)
 }
 
-func (cr *configReader) validateDefaultUniqueness(datasources []*configs) error {
-	defaultCount := map[int64]int{}
-	for i := range datasources {
-		if datasources[i].Datasources == nil {
-			continue
+// validateDatasources validates the datasources of a single config file, resolving their org and defaulting their
+// access.
+func (cr *configReader) validateDatasources(ctx context.Context, cfg *configs) error {
+	if err := validateDeletedPolicy(cfg.DeletedPolicy); err != nil {
+		return utils.InvalidConfig(err)
+	}
+
+	for _, ds := range cfg.Datasources {
+		if err := cr.resolveOrg(ctx, &ds.OrgID, ds.OrgName); err != nil {
+			return fmt.Errorf("failed to provision %q data source: %w", ds.Name, err)
 		}
 
-		for _, ds := range datasources[i].Datasources {
-			if ds.OrgID == 0 {
-				ds.OrgID = 1
-			}
+		if err := cr.validateAccessAndOrgID(ctx, ds); err != nil {
+			return fmt.Errorf("failed to provision %q data source: %w", ds.Name, err)
+		}
 
-			if err := cr.validateAccessAndOrgID(ds); err != nil {
-				return fmt.Errorf("failed to provision %q data source: %w", ds.Name, err)
-			}
+		if err := resolveEnvHeaders(ds); err != nil {
+			return utils.InvalidConfig(fmt.Errorf("failed to provision %q data source: %w", ds.Name, err))
+		}
 
+		if err := validateHealthCheckMode(ds.HealthCheck); err != nil {
+			return utils.InvalidConfig(fmt.Errorf("failed to provision %q data source: %w", ds.Name, err))
+		}
+
+		if err := validatePermissions(ds.Permissions); err != nil {
+			return utils.InvalidConfig(fmt.Errorf("failed to provision %q data source: %w", ds.Name, err))
+		}
+
+		if err := validateUpdateMode(ds.Mode); err != nil {
+			return utils.InvalidConfig(fmt.Errorf("failed to provision %q data source: %w", ds.Name, err))
+		}
+	}
+
+	for _, ds := range cfg.DeleteDatasources {
+		if err := cr.resolveOrg(ctx, &ds.OrgID, ds.OrgName); err != nil {
+			return fmt.Errorf("failed to delete %q data source: %w", ds.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// resolveOrg sets the org of a datasource from its orgName, or to the default org when it has neither. Offline, the
+// names can't be resolved and datasources without an orgId are in org 1.
+func (cr *configReader) resolveOrg(ctx context.Context, orgID *int64, orgName string) error {
+	if cr.offline {
+		if *orgID == 0 {
+			*orgID = 1
+		}
+		return nil
+	}
+	return utils.ResolveOrg(ctx, orgID, orgName)
+}
+
+// validateSingleDefault makes sure there is at most one default datasource per org across all config files.
+func validateSingleDefault(datasources []*configs) error {
+	defaultCount := map[int64]int{}
+	for i := range datasources {
+		for _, ds := range datasources[i].Datasources {
 			if ds.IsDefault {
 				defaultCount[ds.OrgID]++
 				if defaultCount[ds.OrgID] > 1 {
@@ -111,20 +309,16 @@ func (cr *configReader) validateDefaultUniqueness(datasources []*configs) error
 				}
 			}
 		}
-
-		for _, ds := range datasources[i].DeleteDatasources {
-			if ds.OrgID == 0 {
-				ds.OrgID = 1
-			}
-		}
 	}
 
 	return nil
 }
 
-func (cr *configReader) validateAccessAndOrgID(ds *upsertDataSourceFromConfig) error {
-	if err := utils.CheckOrgExists(ds.OrgID); err != nil {
-		return err
+func (cr *configReader) validateAccessAndOrgID(ctx context.Context, ds *upsertDataSourceFromConfig) error {
+	if !cr.offline {
+		if err := utils.CheckOrgExists(ctx, ds.OrgID); err != nil {
+			return err
+		}
 	}
 
 	if ds.Access == "" {
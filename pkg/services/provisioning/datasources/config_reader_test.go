@@ -1,12 +1,18 @@
 package datasources
 
 import (
+	"context"
+	"errors"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -23,6 +29,9 @@ var (
 	multipleOrgsWithDefault         = "testdata/multiple-org-default"
 	withoutDefaults                 = "testdata/appliedDefaults"
 	invalidAccess                   = "testdata/invalid-access"
+	fileFilterConfig                = "testdata/file-filter"
+	guardedConfig                   = "testdata/guarded"
+	kindHeaderConfig                = "testdata/kind-header"
 
 	fakeRepo *fakeRepository
 )
@@ -36,10 +45,12 @@ func TestDatasourceAsConfig(t *testing.T) {
 		bus.AddHandler("test", mockUpdate)
 		bus.AddHandler("test", mockGet)
 		bus.AddHandler("test", mockGetOrg)
+		bus.AddHandler("test", mockSaveProvisioned)
+		bus.AddHandler("test", mockGetProvisioned)
 
 		Convey("apply default values when missing", func() {
 			dc := newDatasourceProvisioner(logger)
-			err := dc.applyChanges(withoutDefaults)
+			err := dc.applyChanges(context.Background(), withoutDefaults)
 			if err != nil {
 				t.Fatalf("applyChanges return an error %v", err)
 			}
@@ -52,7 +63,7 @@ func TestDatasourceAsConfig(t *testing.T) {
 		Convey("One configured datasource", func() {
 			Convey("no datasource in database", func() {
 				dc := newDatasourceProvisioner(logger)
-				err := dc.applyChanges(twoDatasourcesConfig)
+				err := dc.applyChanges(context.Background(), twoDatasourcesConfig)
 				if err != nil {
 					t.Fatalf("applyChanges return an error %v", err)
 				}
@@ -69,7 +80,7 @@ func TestDatasourceAsConfig(t *testing.T) {
 
 				Convey("should update one datasource", func() {
 					dc := newDatasourceProvisioner(logger)
-					err := dc.applyChanges(twoDatasourcesConfig)
+					err := dc.applyChanges(context.Background(), twoDatasourcesConfig)
 					if err != nil {
 						t.Fatalf("applyChanges return an error %v", err)
 					}
@@ -82,7 +93,7 @@ func TestDatasourceAsConfig(t *testing.T) {
 
 			Convey("Two datasources with is_default", func() {
 				dc := newDatasourceProvisioner(logger)
-				err := dc.applyChanges(doubleDatasourcesConfig)
+				err := dc.applyChanges(context.Background(), doubleDatasourcesConfig)
 				Convey("should raise error", func() {
 					So(err, ShouldEqual, ErrInvalidConfigToManyDefault)
 				})
@@ -91,7 +102,7 @@ func TestDatasourceAsConfig(t *testing.T) {
 
 		Convey("Multiple datasources in different organizations with isDefault in each organization", func() {
 			dc := newDatasourceProvisioner(logger)
-			err := dc.applyChanges(multipleOrgsWithDefault)
+			err := dc.applyChanges(context.Background(), multipleOrgsWithDefault)
 			Convey("should not raise error", func() {
 				So(err, ShouldBeNil)
 				So(len(fakeRepo.inserted), ShouldEqual, 4)
@@ -111,7 +122,7 @@ func TestDatasourceAsConfig(t *testing.T) {
 
 				Convey("should have two new datasources", func() {
 					dc := newDatasourceProvisioner(logger)
-					err := dc.applyChanges(twoDatasourcesConfigPurgeOthers)
+					err := dc.applyChanges(context.Background(), twoDatasourcesConfigPurgeOthers)
 					if err != nil {
 						t.Fatalf("applyChanges return an error %v", err)
 					}
@@ -132,7 +143,7 @@ func TestDatasourceAsConfig(t *testing.T) {
 
 				Convey("should have two new datasources", func() {
 					dc := newDatasourceProvisioner(logger)
-					err := dc.applyChanges(twoDatasourcesConfig)
+					err := dc.applyChanges(context.Background(), twoDatasourcesConfig)
 					if err != nil {
 						t.Fatalf("applyChanges return an error %v", err)
 					}
@@ -145,21 +156,74 @@ func TestDatasourceAsConfig(t *testing.T) {
 		})
 
 		Convey("broken yaml should return error", func() {
-			reader := &configReader{}
-			_, err := reader.readConfig(brokenYaml)
+			reader := &configReader{log: logger}
+			_, err := reader.readConfig(context.Background(), brokenYaml)
 			So(err, ShouldNotBeNil)
+
+			var fileErr *utils.ProvisioningFileError
+			So(errors.As(err, &fileErr), ShouldBeTrue)
+			So(fileErr.Subsystem, ShouldEqual, "datasources")
+			So(fileErr.Path, ShouldEndWith, filepath.Join("broken-yaml", "broken.yaml"))
+			So(fileErr.Line, ShouldEqual, 2)
+		})
+
+		Convey("every broken file should be reported", func() {
+			dir := t.TempDir()
+			So(ioutil.WriteFile(filepath.Join(dir, "a.yaml"), []byte("apiVersion: 1\n  datasources: []\n"), 0600), ShouldBeNil)
+			So(ioutil.WriteFile(filepath.Join(dir, "b.yaml"), []byte("apiVersion: 1\ndatasources: {\n"), 0600), ShouldBeNil)
+
+			reader := &configReader{log: logger}
+			_, err := reader.readConfig(context.Background(), dir)
+			So(err, ShouldNotBeNil)
+
+			fileErrs := utils.FileErrorsOf(err)
+			So(len(fileErrs), ShouldEqual, 2)
+			So(fileErrs[0].Path, ShouldEndWith, "a.yaml")
+			So(fileErrs[1].Path, ShouldEndWith, "b.yaml")
+			So(utils.IsInvalidConfig(err), ShouldBeTrue)
 		})
 
 		Convey("invalid access should warn about invalid value and return 'proxy'", func() {
 			reader := &configReader{log: logger}
-			configs, err := reader.readConfig(invalidAccess)
+			configs, err := reader.readConfig(context.Background(), invalidAccess)
 			So(err, ShouldBeNil)
 			So(configs[0].Datasources[0].Access, ShouldEqual, models.DS_ACCESS_PROXY)
 		})
 
+		Convey("excluded files should be skipped", func() {
+			reader := &configReader{log: logger, fileFilter: setting.ProvisioningFileFilter{Exclude: []string{"*.tmpl.yaml"}}}
+			configs, err := reader.readConfig(context.Background(), fileFilterConfig)
+			So(err, ShouldBeNil)
+			So(len(configs), ShouldEqual, 1)
+			So(configs[0].Datasources[0].Name, ShouldEqual, "Graphite")
+		})
+
+		Convey("files whose guards aren't satisfied should be skipped", func() {
+			reader := &configReader{log: logger, strict: true, env: utils.Environment{Version: "8.1.2"}}
+			configs, err := reader.readConfig(context.Background(), guardedConfig)
+			So(err, ShouldBeNil)
+			So(len(configs), ShouldEqual, 1)
+			So(configs[0].Datasources[0].Name, ShouldEqual, "Graphite")
+
+			reader.env.FeatureToggles = map[string]bool{"tempoSearch": true}
+			configs, err = reader.readConfig(context.Background(), guardedConfig)
+			So(err, ShouldBeNil)
+			So(len(configs), ShouldEqual, 2)
+		})
+
+		Convey("files of another kind should be rejected", func() {
+			reader := &configReader{log: logger, strict: true}
+			_, err := reader.readConfig(context.Background(), kindHeaderConfig)
+			So(errors.Is(err, utils.ErrKindMismatch), ShouldBeTrue)
+
+			var fileErr *utils.ProvisioningFileError
+			So(errors.As(err, &fileErr), ShouldBeTrue)
+			So(filepath.Base(fileErr.Path), ShouldEqual, "dashboards.yaml")
+		})
+
 		Convey("skip invalid directory", func() {
 			cfgProvider := &configReader{log: log.New("test logger")}
-			cfg, err := cfgProvider.readConfig("./invalid-directory")
+			cfg, err := cfgProvider.readConfig(context.Background(), "./invalid-directory")
 			if err != nil {
 				t.Fatalf("readConfig return an error %v", err)
 			}
@@ -170,7 +234,7 @@ func TestDatasourceAsConfig(t *testing.T) {
 		Convey("can read all properties from version 1", func() {
 			_ = os.Setenv("TEST_VAR", "name")
 			cfgProvider := &configReader{log: log.New("test logger")}
-			cfg, err := cfgProvider.readConfig(allProperties)
+			cfg, err := cfgProvider.readConfig(context.Background(), allProperties)
 			_ = os.Unsetenv("TEST_VAR")
 			if err != nil {
 				t.Fatalf("readConfig return an error %v", err)
@@ -199,7 +263,7 @@ func TestDatasourceAsConfig(t *testing.T) {
 
 		Convey("can read all properties from version 0", func() {
 			cfgProvider := &configReader{log: log.New("test logger")}
-			cfg, err := cfgProvider.readConfig(versionZero)
+			cfg, err := cfgProvider.readConfig(context.Background(), versionZero)
 			if err != nil {
 				t.Fatalf("readConfig return an error %v", err)
 			}
@@ -263,7 +327,8 @@ type fakeRepository struct {
 	deleted  []*models.DeleteDataSourceCommand
 	updated  []*models.UpdateDataSourceCommand
 
-	loadAll []*models.DataSource
+	loadAll     []*models.DataSource
+	provisioned []*models.DataSource
 }
 
 func mockDelete(cmd *models.DeleteDataSourceCommand) error {
@@ -273,11 +338,14 @@ func mockDelete(cmd *models.DeleteDataSourceCommand) error {
 
 func mockUpdate(cmd *models.UpdateDataSourceCommand) error {
 	fakeRepo.updated = append(fakeRepo.updated, cmd)
+	cmd.Result = &models.DataSource{Id: cmd.Id, OrgId: cmd.OrgId, Name: cmd.Name, Type: cmd.Type, Url: cmd.Url}
 	return nil
 }
 
 func mockInsert(cmd *models.AddDataSourceCommand) error {
 	fakeRepo.inserted = append(fakeRepo.inserted, cmd)
+	cmd.Result = &models.DataSource{Id: int64(100 + len(fakeRepo.inserted)), OrgId: cmd.OrgId, Name: cmd.Name,
+		Type: cmd.Type, Url: cmd.Url}
 	return nil
 }
 
@@ -295,3 +363,31 @@ func mockGet(cmd *models.GetDataSourceQuery) error {
 func mockGetOrg(_ *models.GetOrgByIdQuery) error {
 	return nil
 }
+
+func mockSaveProvisioned(cmd *models.SaveProvisionedDatasourceCommand) error {
+	for _, ds := range fakeRepo.provisioned {
+		if ds.Id == cmd.DatasourceId {
+			return nil
+		}
+	}
+
+	for _, ds := range append(fakeRepo.loadAll, insertedDatasources()...) {
+		if ds.Id == cmd.DatasourceId {
+			fakeRepo.provisioned = append(fakeRepo.provisioned, ds)
+		}
+	}
+	return nil
+}
+
+func mockGetProvisioned(query *models.GetProvisionedDatasourcesQuery) error {
+	query.Result = fakeRepo.provisioned
+	return nil
+}
+
+func insertedDatasources() []*models.DataSource {
+	var result []*models.DataSource
+	for _, cmd := range fakeRepo.inserted {
+		result = append(result, cmd.Result)
+	}
+	return result
+}
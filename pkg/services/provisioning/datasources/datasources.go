@@ -1,26 +1,116 @@
 package datasources
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
 
 	"github.com/grafana/grafana/pkg/bus"
 
 	"github.com/grafana/grafana/pkg/infra/log"
 
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 var (
 	// ErrInvalidConfigToManyDefault indicates that multiple datasource in the provisioning files
 	// contains more than one datasource marked as default.
-	ErrInvalidConfigToManyDefault = errors.New("datasource.yaml config is invalid. Only one datasource per organization can be marked as default")
+	ErrInvalidConfigToManyDefault = utils.InvalidConfig(errors.New("datasource.yaml config is invalid. Only one datasource per organization can be marked as default"))
 )
 
-// Provision scans a directory for provisioning config files
-// and provisions the datasource in those files.
-func Provision(configDirectory string) error {
+// PruneMode controls what happens to provisioned datasources that are no longer in any config file.
+type PruneMode string
+
+const (
+	// PruneOff leaves orphaned datasources alone.
+	PruneOff PruneMode = "off"
+	// PruneReport logs orphaned datasources without deleting them.
+	PruneReport PruneMode = "report"
+	// PruneDelete deletes orphaned datasources.
+	PruneDelete PruneMode = "delete"
+)
+
+// Store is what datasources are provisioned through. Datasources are still looked up with bus queries.
+type Store interface {
+	AddDataSource(ctx context.Context, cmd *models.AddDataSourceCommand) error
+	UpdateDataSource(ctx context.Context, cmd *models.UpdateDataSourceCommand) error
+	DeleteDataSource(ctx context.Context, cmd *models.DeleteDataSourceCommand) error
+	SaveProvisionedDatasource(ctx context.Context, cmd *models.SaveProvisionedDatasourceCommand) error
+}
+
+// ProvisionOptions are what Provision provisions the datasources of the config directories with.
+type ProvisionOptions struct {
+	// Dirs are the directories of the config files, in order.
+	Dirs []string
+	// Store is what the datasources are written through, the bus commands of the SQL store when it's nil.
+	Store      Store
+	FileFilter setting.ProvisioningFileFilter
+	// Strict makes unknown fields in the files an error.
+	Strict    bool
+	PruneMode PruneMode
+	// DeletedPolicy applies to the files that don't set their own, recreate when it's empty.
+	DeletedPolicy DeletedPolicy
+	CleanupGuard  setting.ProvisioningCleanupGuard
+	HealthCheck   HealthCheckSettings
+	// Inventory records the datasources that were applied.
+	Inventory *utils.Inventory
+}
+
+// Provision scans the directories for provisioning config files in order
+// and provisions the datasource in those files. Secret references in secureJsonData are resolved
+// with the secret resolver of ctx.
+func Provision(ctx context.Context, opts ProvisionOptions) error {
 	dc := newDatasourceProvisioner(log.New("provisioning.datasources"))
-	return dc.applyChanges(configDirectory)
+	dc.cfgProvider.fileFilter = opts.FileFilter
+	dc.cfgProvider.strict = opts.Strict
+	dc.cfgProvider.env = utils.EnvironmentFromContext(ctx)
+	dc.secrets = utils.SecretResolverFromContext(ctx)
+	if opts.Store != nil {
+		dc.store = opts.Store
+	}
+	dc.pruneMode = opts.PruneMode
+	if opts.DeletedPolicy != "" {
+		dc.deletedPolicy = opts.DeletedPolicy
+	}
+	dc.cleanupGuard = opts.CleanupGuard
+	dc.healthCheck = opts.HealthCheck
+	dc.inventory = opts.Inventory
+	dc.log.Info("Provisioning datasources", "deletedPolicy", dc.deletedPolicy)
+	return dc.applyChanges(ctx, opts.Dirs...)
+}
+
+// ProvisionFromReader provisions the datasources of a config read from r, which may have several YAML documents,
+// with the validation and apply steps of Provision. Datasources without an orgId are provisioned in orgID, unless
+// it's 0. Nothing is pruned, since the datasources of the provisioning directories aren't known.
+func ProvisionFromReader(ctx context.Context, orgID int64, r io.Reader, store Store, strict bool,
+	healthCheck HealthCheckSettings, inventory *utils.Inventory) error {
+	dc := newDatasourceProvisioner(log.New("provisioning.datasources"))
+	dc.store = store
+	dc.cfgProvider.strict = strict
+	dc.cfgProvider.env = utils.EnvironmentFromContext(ctx)
+	dc.secrets = utils.SecretResolverFromContext(ctx)
+	dc.healthCheck = healthCheck
+	dc.inventory = inventory
+
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	cfgs, err := dc.cfgProvider.readDocument(ctx, orgID, content)
+	if err != nil {
+		return err
+	}
+	for _, cfg := range cfgs {
+		if err := dc.apply(ctx, cfg); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // DatasourceProvisioner is responsible for provisioning datasources based on
@@ -28,70 +118,237 @@ func Provision(configDirectory string) error {
 type DatasourceProvisioner struct {
 	log         log.Logger
 	cfgProvider *configReader
+	store       Store
+	certFiles   *certFileTracker
+	pruneMode   PruneMode
+	healthCheck HealthCheckSettings
+	inventory   *utils.Inventory
+	secrets     utils.SecretResolver
+	// deletedPolicy is the policy for the datasources deleted outside of provisioning of the files without one.
+	deletedPolicy DeletedPolicy
+	// cleanupGuard limits how many of the provisioned datasources pruning may delete in one run.
+	cleanupGuard setting.ProvisioningCleanupGuard
 }
 
 func newDatasourceProvisioner(log log.Logger) DatasourceProvisioner {
 	return DatasourceProvisioner{
 		log:         log,
 		cfgProvider: &configReader{log: log},
+		store:       utils.BusStore{},
+		certFiles:   provisionedCertFiles,
+		pruneMode:   PruneOff,
+		healthCheck: HealthCheckSettings{Mode: HealthCheckOff},
+		secrets:     utils.RegisteredSecretResolver(),
+
+		deletedPolicy: DeletedRecreate,
 	}
 }
 
-func (dc *DatasourceProvisioner) apply(cfg *configs) error {
-	if err := dc.deleteDatasources(cfg.DeleteDatasources); err != nil {
+// apply provisions the datasources of cfg. A datasource whose secret references can't be resolved isn't
+// provisioned, while the others still are, and the error of the first one is returned.
+func (dc *DatasourceProvisioner) apply(ctx context.Context, cfg *configs) error {
+	if err := dc.deleteDatasources(ctx, cfg.DeleteDatasources, cfg.Filename); err != nil {
 		return err
 	}
 
+	var secretErr error
 	for _, ds := range cfg.Datasources {
+		// Not every store handler takes the context, so a canceled run is also stopped between datasources.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		checksum, err := configChecksum(ds)
+		if err != nil {
+			return err
+		}
+		ds, err := resolveSecrets(ctx, dc.secrets, ds)
+		if err != nil {
+			err = fmt.Errorf("failed to provision %q data source: %w", ds.Name, err)
+			dc.log.Error("Failed to resolve the secrets of datasource", "name", ds.Name, "orgId", ds.OrgID,
+				"file", cfg.Filename, "document", cfg.Document, "line", ds.Line, "error", err)
+			if secretErr == nil {
+				secretErr = err
+			}
+			continue
+		}
 		cmd := &models.GetDataSourceQuery{OrgId: ds.OrgID, Name: ds.Name}
-		err := bus.Dispatch(cmd)
+		err = bus.DispatchCtx(ctx, cmd)
 		if err != nil && !errors.Is(err, models.ErrDataSourceNotFound) {
 			return err
 		}
 
 		if errors.Is(err, models.ErrDataSourceNotFound) {
-			dc.log.Info("inserting datasource from configuration ", "name", ds.Name, "uid", ds.UID)
+			skip, err := dc.skipDeleted(ctx, cfg, ds, checksum)
+			if err != nil {
+				return err
+			}
+			if skip {
+				continue
+			}
+			dc.log.Info("inserting datasource from configuration ", "name", ds.Name, "uid", ds.UID, "file", cfg.Filename,
+				"document", cfg.Document, "line", ds.Line)
 			insertCmd := createInsertCommand(ds)
-			if err := bus.Dispatch(insertCmd); err != nil {
+			if err := dc.store.AddDataSource(ctx, insertCmd); err != nil {
+				return err
+			}
+			if err := dc.markProvisioned(ctx, insertCmd.Result, checksum); err != nil {
+				return err
+			}
+			dc.recordApplied(cfg, ds, insertCmd.Result.Uid, utils.ActionCreated)
+			if err := dc.checkHealth(ctx, ds, insertCmd.Result); err != nil {
 				return err
 			}
 		} else {
-			dc.log.Debug("updating datasource from configuration", "name", ds.Name, "uid", ds.UID)
+			dc.log.Debug("updating datasource from configuration", "name", ds.Name, "uid", ds.UID, "file", cfg.Filename,
+				"document", cfg.Document, "line", ds.Line)
+			if ds.Mode == UpdateMerge {
+				ds = mergeWithExisting(cmd.Result, ds)
+			}
 			updateCmd := createUpdateCommand(ds, cmd.Result.Id)
-			if err := bus.Dispatch(updateCmd); err != nil {
+			if err := dc.store.UpdateDataSource(ctx, updateCmd); err != nil {
+				return err
+			}
+			if err := dc.markProvisioned(ctx, cmd.Result, checksum); err != nil {
+				return err
+			}
+			dc.recordApplied(cfg, ds, cmd.Result.Uid, utils.ActionUpdated)
+			if err := dc.checkHealth(ctx, ds, updateCmd.Result); err != nil {
 				return err
 			}
 		}
 	}
 
-	return nil
+	return secretErr
+}
+
+// resolveSecrets returns a copy of ds with the secret references of its secureJsonData replaced by the secrets. The
+// config isn't changed, so the secrets don't outlive the run.
+func resolveSecrets(ctx context.Context, secrets utils.SecretResolver, ds *upsertDataSourceFromConfig) (*upsertDataSourceFromConfig, error) {
+	secureJSONData, err := utils.ResolveSecrets(ctx, secrets, ds.SecureJSONData)
+	if err != nil {
+		return ds, err
+	}
+	resolved := *ds
+	resolved.SecureJSONData = secureJSONData
+	return &resolved, nil
 }
 
-func (dc *DatasourceProvisioner) applyChanges(configPath string) error {
-	configs, err := dc.cfgProvider.readConfig(configPath)
+func (dc *DatasourceProvisioner) applyChanges(ctx context.Context, configPaths ...string) error {
+	configs, err := dc.cfgProvider.readConfig(ctx, configPaths...)
 	if err != nil {
 		return err
 	}
 
 	for _, cfg := range configs {
-		if err := dc.apply(cfg); err != nil {
+		if err := dc.apply(ctx, cfg); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	dc.certFiles.track(configs)
+	return dc.pruneOrphans(ctx, configPaths, configs)
 }
 
-func (dc *DatasourceProvisioner) deleteDatasources(dsToDelete []*deleteDatasourceConfig) error {
+func (dc *DatasourceProvisioner) deleteDatasources(ctx context.Context, dsToDelete []*deleteDatasourceConfig, filename string) error {
 	for _, ds := range dsToDelete {
-		cmd := &models.DeleteDataSourceCommand{OrgID: ds.OrgID, Name: ds.Name}
-		if err := bus.Dispatch(cmd); err != nil {
+		cmd := &models.DeleteDataSourceCommand{OrgID: ds.OrgID, Name: ds.Name, Provisioned: true}
+		if err := dc.store.DeleteDataSource(ctx, cmd); err != nil {
 			return err
 		}
 
 		if cmd.DeletedDatasourcesCount > 0 {
 			dc.log.Info("deleted datasource based on configuration", "name", ds.Name)
+			dc.inventory.RecordDeleted(utils.ProvisionedObject{Kind: "datasource", Name: ds.Name, OrgID: ds.OrgID, File: filename})
+		}
+	}
+
+	return nil
+}
+
+// recordApplied adds a datasource of cfg to the inventory, with the UID it has in the database when the config has
+// none.
+func (dc *DatasourceProvisioner) recordApplied(cfg *configs, ds *upsertDataSourceFromConfig, storedUID string,
+	action string) {
+	uid := ds.UID
+	if uid == "" {
+		uid = storedUID
+	}
+	dc.inventory.Record(utils.ProvisionedObject{Kind: "datasource", Name: ds.Name, UID: uid, OrgID: ds.OrgID,
+		File: cfg.Filename, Document: cfg.Document, Line: ds.Line, Action: action})
+}
+
+// markProvisioned records that a datasource is managed by provisioning, which makes it a candidate for pruning
+// once it's removed from the config files. The checksum of its config is kept for DeletedSkipUntilFileChanges.
+func (dc *DatasourceProvisioner) markProvisioned(ctx context.Context, ds *models.DataSource, checksum string) error {
+	return dc.store.SaveProvisionedDatasource(ctx, &models.SaveProvisionedDatasourceCommand{DatasourceId: ds.Id, OrgId: ds.OrgId,
+		Name: ds.Name, CheckSum: checksum})
+}
+
+// pruneOrphans reports or deletes the datasources provisioning created or updated before that are in none of
+// the configs. Datasources created through the UI or API are never marked as provisioned, so they're left alone.
+func (dc *DatasourceProvisioner) pruneOrphans(ctx context.Context, configPaths []string, configs []*configs) error {
+	if dc.pruneMode != PruneReport && dc.pruneMode != PruneDelete {
+		return nil
+	}
+
+	// A missing directory reads as no configs. It's left out, so the other directories are still pruned, but when
+	// none can be read nothing is, since that would prune every provisioned datasource.
+	readable := 0
+	for _, configPath := range configPaths {
+		if _, err := os.Stat(configPath); err != nil {
+			dc.log.Warn("Can't read the datasource provisioning directory, pruning the other directories",
+				"path", configPath, "error", err)
+			continue
+		}
+		readable++
+	}
+	if readable == 0 {
+		dc.log.Warn("Not pruning datasources, none of the provisioning directories can be read", "paths", configPaths)
+		return nil
+	}
+
+	type datasourceKey struct {
+		orgID int64
+		name  string
+	}
+	configured := map[datasourceKey]bool{}
+	for _, cfg := range configs {
+		for _, ds := range cfg.Datasources {
+			configured[datasourceKey{orgID: ds.OrgID, name: ds.Name}] = true
+		}
+	}
+
+	query := &models.GetProvisionedDatasourcesQuery{}
+	if err := bus.DispatchCtx(ctx, query); err != nil {
+		return err
+	}
+
+	var orphans []*models.DataSource
+	for _, ds := range query.Result {
+		if !configured[datasourceKey{orgID: ds.OrgId, name: ds.Name}] {
+			orphans = append(orphans, ds)
+		}
+	}
+
+	if dc.pruneMode == PruneDelete && !dc.cleanupGuard.Allows(len(orphans), len(query.Result)) {
+		dc.log.Error("Not pruning datasources, pruning would delete more of the provisioned datasources than allowed. "+
+			"Check that the provisioning directories are complete, or set orphan_cleanup_force to prune them",
+			"orphaned", len(orphans), "provisioned", len(query.Result))
+		return nil
+	}
+
+	for _, ds := range orphans {
+		if dc.pruneMode == PruneReport {
+			dc.log.Warn("provisioned datasource is no longer in any configuration", "name", ds.Name, "orgId", ds.OrgId)
+			continue
+		}
+
+		cmd := &models.DeleteDataSourceCommand{ID: ds.Id, OrgID: ds.OrgId, Provisioned: true}
+		if err := dc.store.DeleteDataSource(ctx, cmd); err != nil {
+			return err
 		}
+		dc.log.Info("deleted datasource no longer in any configuration", "name", ds.Name, "orgId", ds.OrgId)
+		dc.inventory.RecordDeleted(utils.ProvisionedObject{Kind: "datasource", Name: ds.Name, UID: ds.Uid, OrgID: ds.OrgId})
 	}
 
 	return nil
@@ -0,0 +1,387 @@
+package datasources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneOrphanedDatasources(t *testing.T) {
+	setup := func(t *testing.T, pruneMode PruneMode) DatasourceProvisioner {
+		t.Helper()
+
+		fakeRepo = &fakeRepository{}
+		bus.ClearBusHandlers()
+		t.Cleanup(bus.ClearBusHandlers)
+		bus.AddHandler("test", mockDelete)
+		bus.AddHandler("test", mockInsert)
+		bus.AddHandler("test", mockUpdate)
+		bus.AddHandler("test", mockGet)
+		bus.AddHandler("test", mockGetOrg)
+		bus.AddHandler("test", mockSaveProvisioned)
+		bus.AddHandler("test", mockGetProvisioned)
+
+		graphite := &models.DataSource{Id: 1, OrgId: 1, Name: "Graphite"}
+		renamed := &models.DataSource{Id: 2, OrgId: 1, Name: "Old Graphite"}
+		fakeRepo.loadAll = []*models.DataSource{
+			graphite,
+			renamed,
+			{Id: 3, OrgId: 1, Name: "Created in the UI"},
+		}
+		fakeRepo.provisioned = []*models.DataSource{graphite, renamed}
+
+		dc := newDatasourceProvisioner(logger)
+		dc.pruneMode = pruneMode
+		dc.cleanupGuard = setting.ProvisioningCleanupGuard{MaxPercent: 50}
+		return dc
+	}
+
+	t.Run("Provisioned datasources are marked", func(t *testing.T) {
+		dc := setup(t, PruneOff)
+		fakeRepo.provisioned = nil
+
+		require.NoError(t, dc.applyChanges(context.Background(), twoDatasourcesConfig))
+
+		var names []string
+		for _, ds := range fakeRepo.provisioned {
+			names = append(names, ds.Name)
+		}
+		assert.ElementsMatch(t, []string{"Graphite", "Prometheus"}, names)
+	})
+
+	t.Run("Applied datasources are recorded in the inventory", func(t *testing.T) {
+		dc := setup(t, PruneOff)
+		dc.inventory = utils.NewInventory()
+
+		require.NoError(t, dc.applyChanges(context.Background(), twoDatasourcesConfig))
+
+		actions := map[string]string{}
+		for _, object := range dc.inventory.Objects() {
+			assert.Equal(t, "datasource", object.Kind)
+			assert.True(t, filepath.IsAbs(object.File))
+			actions[object.Name] = object.Action
+		}
+		assert.Equal(t, map[string]string{"Graphite": utils.ActionUpdated, "Prometheus": utils.ActionCreated}, actions)
+	})
+
+	t.Run("Orphans are kept when pruning is off", func(t *testing.T) {
+		dc := setup(t, PruneOff)
+
+		require.NoError(t, dc.applyChanges(context.Background(), twoDatasourcesConfig))
+		assert.Empty(t, fakeRepo.deleted)
+	})
+
+	t.Run("Orphans are only reported in report mode", func(t *testing.T) {
+		dc := setup(t, PruneReport)
+
+		require.NoError(t, dc.applyChanges(context.Background(), twoDatasourcesConfig))
+		assert.Empty(t, fakeRepo.deleted)
+	})
+
+	t.Run("Orphans are deleted in delete mode", func(t *testing.T) {
+		dc := setup(t, PruneDelete)
+		dc.inventory = utils.NewInventory()
+
+		require.NoError(t, dc.applyChanges(context.Background(), twoDatasourcesConfig))
+
+		require.Len(t, fakeRepo.deleted, 1)
+		assert.Equal(t, int64(2), fakeRepo.deleted[0].ID)
+		assert.Equal(t, int64(1), fakeRepo.deleted[0].OrgID)
+		require.Len(t, dc.inventory.Deleted(), 1)
+		assert.Equal(t, "Old Graphite", dc.inventory.Deleted()[0].Name)
+	})
+
+	t.Run("Nothing is deleted when the provisioning directory is missing", func(t *testing.T) {
+		dc := setup(t, PruneDelete)
+
+		require.NoError(t, dc.applyChanges(context.Background(), "testdata/does-not-exist"))
+		assert.Empty(t, fakeRepo.deleted)
+	})
+
+	t.Run("A missing directory doesn't stop the other directories from being pruned", func(t *testing.T) {
+		dc := setup(t, PruneDelete)
+
+		require.NoError(t, dc.applyChanges(context.Background(), twoDatasourcesConfig, "testdata/does-not-exist"))
+		require.Len(t, fakeRepo.deleted, 1)
+		assert.Equal(t, int64(2), fakeRepo.deleted[0].ID)
+	})
+
+	t.Run("An empty provisioning directory triggers the cleanup guard", func(t *testing.T) {
+		dc := setup(t, PruneDelete)
+
+		require.NoError(t, dc.applyChanges(context.Background(), t.TempDir()))
+		assert.Empty(t, fakeRepo.deleted)
+	})
+
+	t.Run("Forcing the cleanup prunes every orphan", func(t *testing.T) {
+		dc := setup(t, PruneDelete)
+		dc.cleanupGuard.Force = true
+
+		require.NoError(t, dc.applyChanges(context.Background(), t.TempDir()))
+		assert.Len(t, fakeRepo.deleted, 2)
+	})
+
+	t.Run("Nothing is applied when the context is canceled", func(t *testing.T) {
+		dc := setup(t, PruneDelete)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := dc.applyChanges(ctx, twoDatasourcesConfig)
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, fakeRepo.inserted)
+		assert.Empty(t, fakeRepo.updated)
+		assert.Empty(t, fakeRepo.deleted)
+	})
+}
+
+func TestLayeredProvisioningPaths(t *testing.T) {
+	fakeRepo = &fakeRepository{}
+	bus.ClearBusHandlers()
+	t.Cleanup(bus.ClearBusHandlers)
+	bus.AddHandler("test", mockGetOrg)
+
+	reader := &configReader{log: logger}
+	configs, err := reader.readConfig(context.Background(), "testdata/layered/base", "testdata/layered/overlay")
+	require.NoError(t, err, "Overridden defaults shouldn't count twice")
+
+	urls := map[string]string{}
+	for _, cfg := range configs {
+		for _, ds := range cfg.Datasources {
+			urls[ds.Name] = ds.URL
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"Graphite":        "http://graphite-team:8080",
+		"Team Prometheus": "http://team-prometheus:9090",
+		"Loki":            "http://loki:3100",
+	}, urls, "Later paths should override datasources by name and uid")
+}
+
+// memoryStore is a Store that keeps the datasources it's asked to write.
+type memoryStore struct {
+	inserted    []*models.AddDataSourceCommand
+	provisioned []*models.SaveProvisionedDatasourceCommand
+}
+
+func (s *memoryStore) AddDataSource(_ context.Context, cmd *models.AddDataSourceCommand) error {
+	s.inserted = append(s.inserted, cmd)
+	cmd.Result = &models.DataSource{Id: int64(len(s.inserted)), OrgId: cmd.OrgId, Name: cmd.Name}
+	return nil
+}
+
+func (s *memoryStore) UpdateDataSource(context.Context, *models.UpdateDataSourceCommand) error {
+	return errors.New("not implemented")
+}
+
+func (s *memoryStore) DeleteDataSource(context.Context, *models.DeleteDataSourceCommand) error {
+	return errors.New("not implemented")
+}
+
+func (s *memoryStore) SaveProvisionedDatasource(_ context.Context, cmd *models.SaveProvisionedDatasourceCommand) error {
+	s.provisioned = append(s.provisioned, cmd)
+	return nil
+}
+
+func TestProvisionThroughStore(t *testing.T) {
+	fakeRepo = &fakeRepository{}
+	bus.ClearBusHandlers()
+	t.Cleanup(bus.ClearBusHandlers)
+	// Only the queries are handled on the bus, the writes have to go through the store.
+	bus.AddHandler("test", mockGet)
+	bus.AddHandler("test", mockGetOrg)
+	store := &memoryStore{}
+
+	require.NoError(t, Provision(context.Background(), ProvisionOptions{Dirs: []string{twoDatasourcesConfig}, Store: store}))
+	require.Len(t, store.inserted, 2)
+	assert.ElementsMatch(t, []string{"Graphite", "Prometheus"},
+		[]string{store.inserted[0].Name, store.inserted[1].Name})
+	assert.Len(t, store.provisioned, 2)
+	assert.Empty(t, fakeRepo.inserted)
+}
+
+func TestProvisionFromReader(t *testing.T) {
+	setup := func(t *testing.T) {
+		t.Helper()
+
+		fakeRepo = &fakeRepository{}
+		bus.ClearBusHandlers()
+		t.Cleanup(bus.ClearBusHandlers)
+		bus.AddHandler("test", mockDelete)
+		bus.AddHandler("test", mockInsert)
+		bus.AddHandler("test", mockUpdate)
+		bus.AddHandler("test", mockGet)
+		bus.AddHandler("test", mockGetOrg)
+		bus.AddHandler("test", mockSaveProvisioned)
+	}
+	healthCheck := HealthCheckSettings{Mode: HealthCheckOff}
+
+	t.Run("Datasources of the document are inserted in the org", func(t *testing.T) {
+		setup(t)
+		inventory := utils.NewInventory()
+		doc := `apiVersion: 1
+datasources:
+  - name: Graphite
+    type: graphite
+    url: http://localhost:8080
+  - name: Prometheus
+    type: prometheus
+    orgId: 2
+`
+
+		err := ProvisionFromReader(context.Background(), 2, strings.NewReader(doc), utils.BusStore{}, false, healthCheck, inventory)
+		require.NoError(t, err)
+
+		require.Len(t, fakeRepo.inserted, 2)
+		for _, insert := range fakeRepo.inserted {
+			assert.Equal(t, int64(2), insert.OrgId)
+			assert.Equal(t, models.DsAccess(models.DS_ACCESS_PROXY), insert.Access)
+		}
+		require.Len(t, inventory.Objects(), 2)
+		assert.Equal(t, utils.ReaderFilename, inventory.Objects()[0].File)
+	})
+
+	t.Run("Datasources of every document are provisioned with their position", func(t *testing.T) {
+		setup(t)
+		inventory := utils.NewInventory()
+		doc := `apiVersion: 1
+datasources:
+  - name: Graphite
+    type: graphite
+  - name: Loki
+    type: loki
+---
+apiVersion: 1
+datasources:
+  - name: Prometheus
+    type: prometheus
+`
+
+		err := ProvisionFromReader(context.Background(), 1, strings.NewReader(doc), utils.BusStore{}, true, healthCheck, inventory)
+		require.NoError(t, err)
+
+		var positions []string
+		for _, object := range inventory.Objects() {
+			positions = append(positions, fmt.Sprintf("%s document %d line %d", object.Name, object.Document, object.Line))
+		}
+		assert.Equal(t, []string{"Graphite document 1 line 3", "Loki document 1 line 5", "Prometheus document 2 line 10"},
+			positions)
+	})
+
+	t.Run("The org of a datasource can't conflict with the given org", func(t *testing.T) {
+		setup(t)
+		doc := "apiVersion: 1\ndatasources:\n  - name: Graphite\n    orgId: 3\n"
+
+		err := ProvisionFromReader(context.Background(), 2, strings.NewReader(doc), utils.BusStore{}, false, healthCheck, utils.NewInventory())
+		require.ErrorIs(t, err, utils.ErrOrgConflict)
+		assert.Empty(t, fakeRepo.inserted)
+	})
+
+	t.Run("Documents are validated like files", func(t *testing.T) {
+		setup(t)
+		doc := `apiVersion: 1
+datasources:
+  - name: Graphite
+    isDefault: true
+  - name: Prometheus
+    isDefault: true
+`
+
+		err := ProvisionFromReader(context.Background(), 0, strings.NewReader(doc), utils.BusStore{}, false, healthCheck, utils.NewInventory())
+		require.ErrorIs(t, err, ErrInvalidConfigToManyDefault)
+
+		err = ProvisionFromReader(context.Background(), 0, strings.NewReader("apiVersion: 1\nunknown: true\n"), utils.BusStore{}, true,
+			healthCheck, utils.NewInventory())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), utils.ReaderFilename)
+		assert.Empty(t, fakeRepo.inserted)
+	})
+
+	t.Run("Secret references are resolved with the resolver of the context", func(t *testing.T) {
+		setup(t)
+		doc := `apiVersion: 1
+datasources:
+  - name: Graphite
+    secureJsonData:
+      password: vault://secret/data/graphite#password
+      basicAuthPassword: plain
+  - name: Prometheus
+    secureJsonData:
+      password: vault://secret/data/missing#password
+  - name: Loki
+`
+		var refs []string
+		ctx := utils.WithSecretResolver(context.Background(), utils.SecretResolverFunc(
+			func(_ context.Context, ref utils.SecretRef) (string, error) {
+				refs = append(refs, ref.String())
+				if ref.Path == "secret/data/missing" {
+					return "", errors.New("secret not found")
+				}
+				return "s3cr3t", nil
+			}))
+
+		err := ProvisionFromReader(ctx, 1, strings.NewReader(doc), utils.BusStore{}, false, healthCheck, utils.NewInventory())
+		require.ErrorIs(t, err, utils.ErrSecretUnresolved)
+		assert.EqualError(t, err, `failed to provision "Prometheus" data source: secret reference can't be resolved: `+
+			`password (vault://secret/data/missing#password): secret not found`)
+		assert.Equal(t, []string{"vault://secret/data/graphite#password", "vault://secret/data/missing#password"}, refs)
+
+		require.Len(t, fakeRepo.inserted, 2, "Datasources after the one that failed are still provisioned")
+		assert.Equal(t, "Graphite", fakeRepo.inserted[0].Name)
+		assert.Equal(t, map[string]string{"password": "s3cr3t", "basicAuthPassword": "plain"},
+			fakeRepo.inserted[0].SecureJsonData)
+		assert.Equal(t, "Loki", fakeRepo.inserted[1].Name)
+	})
+}
+
+func TestApplyOrderIsStable(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"b-loki.yaml":        "apiVersion: 1\ndatasources:\n  - name: Loki\n    type: loki\n    url: http://loki:3100\n",
+		"a-graphite.yaml":    "apiVersion: 1\ndatasources:\n  - name: Graphite\n    type: graphite\n    url: http://graphite-a\n",
+		"c-graphite-v2.yaml": "apiVersion: 1\ndatasources:\n  - name: Graphite\n    type: graphite\n    url: http://graphite-c\n",
+	}
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+
+	for seed := int64(0); seed < 5; seed++ {
+		random := rand.New(rand.NewSource(seed))
+		utils.ListDir = func(path string) ([]os.FileInfo, error) {
+			files, err := ioutil.ReadDir(path)
+			random.Shuffle(len(files), func(i, j int) { files[i], files[j] = files[j], files[i] })
+			return files, err
+		}
+		t.Cleanup(func() { utils.ListDir = ioutil.ReadDir })
+
+		fakeRepo = &fakeRepository{}
+		bus.ClearBusHandlers()
+		t.Cleanup(bus.ClearBusHandlers)
+		bus.AddHandler("test", mockInsert)
+		bus.AddHandler("test", mockGet)
+		bus.AddHandler("test", mockGetOrg)
+		bus.AddHandler("test", mockSaveProvisioned)
+
+		dc := newDatasourceProvisioner(logger)
+		require.NoError(t, dc.applyChanges(context.Background(), dir))
+
+		var applied []string
+		for _, cmd := range fakeRepo.inserted {
+			applied = append(applied, cmd.Name+" "+cmd.Url)
+		}
+		assert.Equal(t, []string{"Graphite http://graphite-a", "Loki http://loki:3100", "Graphite http://graphite-c"}, applied,
+			"The files should be applied sorted by name, whatever order the directory is listed in")
+	}
+}
@@ -0,0 +1,76 @@
+package datasources
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// DeletedPolicy controls what happens to a datasource of the config files that provisioning created and that was
+// then deleted other than by provisioning, like through the UI.
+type DeletedPolicy string
+
+const (
+	// DeletedRecreate provisions the datasource again.
+	DeletedRecreate DeletedPolicy = "recreate"
+	// DeletedSkipUntilFileChanges leaves the datasource deleted until its config changes.
+	DeletedSkipUntilFileChanges DeletedPolicy = "skip-until-file-changes"
+	// DeletedWarn provisions the datasource again and logs a warning.
+	DeletedWarn DeletedPolicy = "warn"
+)
+
+func validateDeletedPolicy(policy DeletedPolicy) error {
+	switch policy {
+	case "", DeletedRecreate, DeletedSkipUntilFileChanges, DeletedWarn:
+		return nil
+	}
+	return fmt.Errorf("invalid deletedPolicy %q, must be one of recreate, skip-until-file-changes or warn", policy)
+}
+
+// configChecksum is the checksum of the config of a datasource, before its secret references are resolved, so
+// the datasource is only provisioned again under DeletedSkipUntilFileChanges when its config changes.
+func configChecksum(ds *upsertDataSourceFromConfig) (string, error) {
+	content, err := json.Marshal(ds)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// skipDeleted tells whether a datasource that isn't in the database is left deleted, by the policy of its file or
+// else the one of the provisioner.
+func (dc *DatasourceProvisioner) skipDeleted(ctx context.Context, cfg *configs, ds *upsertDataSourceFromConfig, checksum string) (bool, error) {
+	policy := dc.deletedPolicy
+	if cfg.DeletedPolicy != "" {
+		policy = cfg.DeletedPolicy
+	}
+	if policy != DeletedSkipUntilFileChanges && policy != DeletedWarn {
+		return false, nil
+	}
+
+	query := &models.GetDeletedProvisionedDatasourceQuery{OrgId: ds.OrgID, Name: ds.Name}
+	if err := bus.DispatchCtx(ctx, query); err != nil {
+		return false, err
+	}
+	if query.Result == nil {
+		return false, nil
+	}
+
+	if policy == DeletedWarn {
+		dc.log.Warn("Recreating provisioned datasource that was deleted outside of provisioning", "name", ds.Name,
+			"orgId", ds.OrgID, "file", cfg.Filename)
+		return false, nil
+	}
+	if query.Result.CheckSum == checksum {
+		dc.log.Info("Not recreating provisioned datasource that was deleted outside of provisioning until its config changes",
+			"name", ds.Name, "orgId", ds.OrgID, "file", cfg.Filename)
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,153 @@
+package datasources
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const invalidDeletedPolicyConfig = "testdata/invalid-deleted-policy"
+
+func TestDeletedPolicy(t *testing.T) {
+	// marks are the provisioning marks of the datasources by name, which outlive the datasources deleted in the UI.
+	var marks map[string]*models.DatasourceProvisioning
+	var deletedLookups int
+	setup := func(t *testing.T, policy DeletedPolicy) DatasourceProvisioner {
+		t.Helper()
+
+		fakeRepo = &fakeRepository{}
+		marks = map[string]*models.DatasourceProvisioning{}
+		deletedLookups = 0
+		bus.ClearBusHandlers()
+		t.Cleanup(bus.ClearBusHandlers)
+		bus.AddHandler("test", mockDelete)
+		bus.AddHandler("test", mockInsert)
+		bus.AddHandler("test", mockUpdate)
+		bus.AddHandler("test", mockGet)
+		bus.AddHandler("test", mockGetOrg)
+		bus.AddHandler("test", mockGetProvisioned)
+		bus.AddHandler("test", func(cmd *models.SaveProvisionedDatasourceCommand) error {
+			marks[cmd.Name] = &models.DatasourceProvisioning{DatasourceId: cmd.DatasourceId, OrgId: cmd.OrgId,
+				Name: cmd.Name, CheckSum: cmd.CheckSum}
+			return nil
+		})
+		bus.AddHandler("test", func(query *models.GetDeletedProvisionedDatasourceQuery) error {
+			deletedLookups++
+			query.Result = nil
+			if mark := marks[query.Name]; mark != nil && mark.Deleted && mark.OrgId == query.OrgId {
+				query.Result = mark
+			}
+			return nil
+		})
+
+		dc := newDatasourceProvisioner(logger)
+		dc.deletedPolicy = policy
+		dc.inventory = utils.NewInventory()
+		return dc
+	}
+	writeConfig := func(t *testing.T, dir string, content string) {
+		t.Helper()
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "datasources.yaml"), []byte(content), 0600))
+	}
+	// deleteInUI deletes the datasources the way the UI does, which leaves their marks behind as deleted. The fake
+	// store never finds inserted datasources, so they read as deleted on the next run anyway.
+	deleteInUI := func() {
+		for _, mark := range marks {
+			mark.Deleted = true
+		}
+	}
+	const config = `apiVersion: 1
+datasources:
+  - name: Graphite
+    type: graphite
+    url: http://localhost:8080
+`
+	const changedConfig = `apiVersion: 1
+datasources:
+  - name: Graphite
+    type: graphite
+    url: http://localhost:8081
+`
+
+	t.Run("Deleted datasources are recreated by default", func(t *testing.T) {
+		dc := setup(t, DeletedRecreate)
+		dir := t.TempDir()
+		writeConfig(t, dir, config)
+
+		require.NoError(t, dc.applyChanges(context.Background(), dir))
+		deleteInUI()
+		require.NoError(t, dc.applyChanges(context.Background(), dir))
+
+		assert.Len(t, fakeRepo.inserted, 2)
+		assert.Zero(t, deletedLookups, "The deletion doesn't matter to recreate")
+		assert.False(t, marks["Graphite"].Deleted, "The recreated datasource is marked again")
+	})
+
+	t.Run("Deleted datasources are recreated with a warning in warn mode", func(t *testing.T) {
+		dc := setup(t, DeletedWarn)
+		dir := t.TempDir()
+		writeConfig(t, dir, config)
+
+		require.NoError(t, dc.applyChanges(context.Background(), dir))
+		deleteInUI()
+		require.NoError(t, dc.applyChanges(context.Background(), dir))
+
+		assert.Len(t, fakeRepo.inserted, 2)
+		assert.Equal(t, 2, deletedLookups)
+	})
+
+	t.Run("Deleted datasources are skipped until their config changes", func(t *testing.T) {
+		dc := setup(t, DeletedSkipUntilFileChanges)
+		dir := t.TempDir()
+		writeConfig(t, dir, config)
+
+		require.NoError(t, dc.applyChanges(context.Background(), dir))
+		deleteInUI()
+		dc.inventory = utils.NewInventory()
+		require.NoError(t, dc.applyChanges(context.Background(), dir))
+		assert.Len(t, fakeRepo.inserted, 1, "The deleted datasource isn't recreated")
+		assert.Empty(t, dc.inventory.Objects())
+
+		writeConfig(t, dir, changedConfig)
+		require.NoError(t, dc.applyChanges(context.Background(), dir))
+		require.Len(t, fakeRepo.inserted, 2, "The changed datasource is recreated")
+		assert.Equal(t, "http://localhost:8081", fakeRepo.inserted[1].Url)
+	})
+
+	t.Run("Datasources that were never provisioned aren't skipped", func(t *testing.T) {
+		dc := setup(t, DeletedSkipUntilFileChanges)
+		dir := t.TempDir()
+		writeConfig(t, dir, config)
+
+		require.NoError(t, dc.applyChanges(context.Background(), dir))
+		assert.Len(t, fakeRepo.inserted, 1)
+	})
+
+	t.Run("The policy of a file overrides the one of the provisioner", func(t *testing.T) {
+		dc := setup(t, DeletedRecreate)
+		dir := t.TempDir()
+		writeConfig(t, dir, "deletedPolicy: skip-until-file-changes\n"+config)
+
+		require.NoError(t, dc.applyChanges(context.Background(), dir))
+		deleteInUI()
+		require.NoError(t, dc.applyChanges(context.Background(), dir))
+
+		assert.Len(t, fakeRepo.inserted, 1)
+	})
+
+	t.Run("Invalid policies fail validation", func(t *testing.T) {
+		dc := setup(t, DeletedRecreate)
+
+		err := dc.applyChanges(context.Background(), invalidDeletedPolicyConfig)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid deletedPolicy "ignore", must be one of recreate, skip-until-file-changes or warn`)
+		assert.Empty(t, fakeRepo.inserted)
+	})
+}
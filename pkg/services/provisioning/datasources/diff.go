@@ -0,0 +1,150 @@
+package datasources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// Diff compares the datasources of the config files in the directories with the ones in the database, without
+// changing anything. Datasources of deleteDatasources that still exist and provisioned datasources that are in none
+// of the files are listed as removed, the latter only when every directory can be read.
+func Diff(ctx context.Context, configDirectories []string, fileFilter setting.ProvisioningFileFilter, strict bool) (*utils.Diff, error) {
+	cr := &configReader{log: log.New("provisioning.datasources"), fileFilter: fileFilter, strict: strict,
+		env: utils.EnvironmentFromContext(ctx)}
+	configs, err := cr.readConfig(ctx, configDirectories...)
+	if err != nil {
+		return nil, err
+	}
+	secrets := utils.SecretResolverFromContext(ctx)
+
+	diff := utils.NewDiff("datasources")
+	type datasourceKey struct {
+		orgID int64
+		name  string
+	}
+	configured := map[datasourceKey]bool{}
+	for _, cfg := range configs {
+		for _, ds := range cfg.DeleteDatasources {
+			existing, err := getDatasource(ctx, ds.OrgID, ds.Name)
+			if err != nil {
+				return nil, err
+			}
+			if existing != nil {
+				diff.Removed = append(diff.Removed, diffObject(existing, cfg.Filename))
+			}
+		}
+
+		for _, ds := range cfg.Datasources {
+			configured[datasourceKey{orgID: ds.OrgID, name: ds.Name}] = true
+			// The secrets are compared resolved, so a reference to an unchanged secret isn't a change.
+			ds, err := resolveSecrets(ctx, secrets, ds)
+			if err != nil {
+				return nil, fmt.Errorf("failed to diff %q data source: %w", ds.Name, err)
+			}
+
+			existing, err := getDatasource(ctx, ds.OrgID, ds.Name)
+			if err != nil {
+				return nil, err
+			}
+			if existing == nil {
+				diff.Added = append(diff.Added, utils.DiffObject{Kind: "datasource", Name: ds.Name, UID: ds.UID,
+					OrgID: ds.OrgID, File: cfg.Filename})
+				continue
+			}
+			if ds.Mode == UpdateMerge {
+				ds = mergeWithExisting(existing, ds)
+			}
+			if fields := datasourceChanges(existing, ds); len(fields) > 0 {
+				diff.Changed = append(diff.Changed, utils.ChangedObject{DiffObject: diffObject(existing, cfg.Filename),
+					Fields: fields})
+			}
+		}
+	}
+
+	for _, configDirectory := range configDirectories {
+		if _, err := os.Stat(configDirectory); err != nil {
+			return diff, nil
+		}
+	}
+	query := &models.GetProvisionedDatasourcesQuery{}
+	if err := bus.DispatchCtx(ctx, query); err != nil {
+		return nil, err
+	}
+	for _, ds := range query.Result {
+		if !configured[datasourceKey{orgID: ds.OrgId, name: ds.Name}] {
+			diff.Removed = append(diff.Removed, diffObject(ds, ""))
+		}
+	}
+
+	return diff, nil
+}
+
+// getDatasource returns the named datasource of the org, or nil when there's none.
+func getDatasource(ctx context.Context, orgID int64, name string) (*models.DataSource, error) {
+	query := &models.GetDataSourceQuery{OrgId: orgID, Name: name}
+	if err := bus.DispatchCtx(ctx, query); err != nil {
+		if errors.Is(err, models.ErrDataSourceNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return query.Result, nil
+}
+
+func diffObject(ds *models.DataSource, filename string) utils.DiffObject {
+	return utils.DiffObject{Kind: "datasource", Name: ds.Name, UID: ds.Uid, OrgID: ds.OrgId, File: filename}
+}
+
+// datasourceChanges returns the fields of the stored datasource that provisioning ds would change.
+func datasourceChanges(existing *models.DataSource, ds *upsertDataSourceFromConfig) utils.FieldChanges {
+	var fields utils.FieldChanges
+	if ds.UID != "" {
+		fields.Compare("uid", existing.Uid, ds.UID)
+	}
+	fields.Compare("type", existing.Type, ds.Type)
+	fields.Compare("access", string(existing.Access), ds.Access)
+	fields.Compare("url", existing.Url, ds.URL)
+	fields.Compare("user", existing.User, ds.User)
+	fields.Compare("database", existing.Database, ds.Database)
+	fields.Compare("basicAuth", existing.BasicAuth, ds.BasicAuth)
+	fields.Compare("basicAuthUser", existing.BasicAuthUser, ds.BasicAuthUser)
+	fields.Compare("withCredentials", existing.WithCredentials, ds.WithCredentials)
+	fields.Compare("isDefault", existing.IsDefault, ds.IsDefault)
+	fields.Compare("editable", !existing.ReadOnly, ds.Editable)
+
+	var jsonData map[string]interface{}
+	if existing.JsonData != nil {
+		jsonData = existing.JsonData.MustMap()
+	}
+	fields.CompareMap("jsonData", jsonData, ds.JSONData)
+
+	fields.CompareSecret("password", existing.Password != ds.Password)
+	fields.CompareSecret("basicAuthPassword", existing.BasicAuthPassword != ds.BasicAuthPassword)
+	// The stored secure settings are replaced as a whole, so keys that are only in the database are changes too.
+	secureJSONData := existing.DecryptedValues()
+	keys := make([]string, 0, len(secureJSONData)+len(ds.SecureJSONData))
+	for key := range secureJSONData {
+		keys = append(keys, key)
+	}
+	for key := range ds.SecureJSONData {
+		if _, ok := secureJSONData[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		current, inDatabase := secureJSONData[key]
+		desired, inFile := ds.SecureJSONData[key]
+		fields.CompareSecret("secureJsonData."+key, inDatabase != inFile || current != desired)
+	}
+	return fields
+}
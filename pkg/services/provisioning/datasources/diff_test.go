@@ -0,0 +1,58 @@
+package datasources
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	fakeRepo = &fakeRepository{}
+	t.Cleanup(bus.ClearBusHandlers)
+	bus.AddHandler("test", mockGet)
+	bus.AddHandler("test", mockGetOrg)
+	bus.AddHandler("test", mockGetProvisioned)
+
+	graphite := &models.DataSource{Id: 1, OrgId: 1, Name: "Graphite", Type: "graphite", Access: models.DS_ACCESS_PROXY,
+		Url: "http://localhost:9999", ReadOnly: true, JsonData: simplejson.NewFromAny(map[string]interface{}{"graphiteVersion": "1.1"})}
+	renamed := &models.DataSource{Id: 2, OrgId: 1, Name: "Old Graphite", Uid: "old-graphite"}
+	fakeRepo.loadAll = []*models.DataSource{graphite, renamed, {Id: 3, OrgId: 1, Name: "Created in the UI"}}
+	fakeRepo.provisioned = []*models.DataSource{graphite, renamed}
+
+	diff, err := Diff(context.Background(), []string{twoDatasourcesConfig}, setting.ProvisioningFileFilter{}, false)
+	require.NoError(t, err)
+
+	require.Len(t, diff.Added, 1)
+	assert.Equal(t, "Prometheus", diff.Added[0].Name)
+	require.Len(t, diff.Removed, 1, "Datasources created in the UI aren't removed")
+	assert.Equal(t, utils.DiffObject{Kind: "datasource", Name: "Old Graphite", UID: "old-graphite", OrgID: 1}, diff.Removed[0])
+
+	require.Len(t, diff.Changed, 1)
+	assert.Equal(t, "Graphite", diff.Changed[0].Name)
+	assert.Equal(t, utils.FieldChanges{
+		{Field: "url", Current: "http://localhost:9999", Desired: "http://localhost:8080"},
+		{Field: "jsonData.graphiteVersion", Current: "1.1"},
+	}, diff.Changed[0].Fields)
+
+	assert.Empty(t, fakeRepo.inserted, "Diffing doesn't change the database")
+	assert.Empty(t, fakeRepo.updated)
+	assert.Empty(t, fakeRepo.deleted)
+}
+
+func TestDatasourceChangesOfSecrets(t *testing.T) {
+	existing := &models.DataSource{Id: 1, OrgId: 1, Name: "Graphite", Password: "old", ReadOnly: true}
+	ds := &upsertDataSourceFromConfig{Name: "Graphite", SecureJSONData: map[string]string{"token": "secret"}}
+
+	fields := datasourceChanges(existing, ds)
+	assert.Equal(t, utils.FieldChanges{
+		{Field: "password", Secret: true},
+		{Field: "secureJsonData.token", Secret: true},
+	}, fields, "Secrets are compared without their values")
+}
@@ -0,0 +1,58 @@
+package datasources
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+)
+
+// resolveEnvHeaders adds the headers configured in envHeaders to a datasource, with each value read from its
+// environment variable. They're numbered after the static httpHeaderName<N> headers and their values go to
+// secureJsonData, so they get encrypted like any other header value.
+func resolveEnvHeaders(ds *upsertDataSourceFromConfig) error {
+	if len(ds.EnvHeaders) == 0 {
+		return nil
+	}
+
+	if ds.JSONData == nil {
+		ds.JSONData = map[string]interface{}{}
+	}
+	if ds.SecureJSONData == nil {
+		ds.SecureJSONData = map[string]string{}
+	}
+
+	staticHeaders := map[string]bool{}
+	index := 1
+	for ; ; index++ {
+		name, _ := ds.JSONData[fmt.Sprintf("httpHeaderName%d", index)].(string)
+		if name == "" {
+			break
+		}
+		staticHeaders[http.CanonicalHeaderKey(name)] = true
+	}
+
+	names := make([]string, 0, len(ds.EnvHeaders))
+	for name := range ds.EnvHeaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if staticHeaders[http.CanonicalHeaderKey(name)] {
+			return fmt.Errorf("header %q is configured both in jsonData and envHeaders", name)
+		}
+
+		envVar := ds.EnvHeaders[name]
+		value, ok := os.LookupEnv(envVar)
+		if !ok {
+			return fmt.Errorf("environment variable %q for header %q is not set", envVar, name)
+		}
+
+		ds.JSONData[fmt.Sprintf("httpHeaderName%d", index)] = name
+		ds.SecureJSONData[fmt.Sprintf("httpHeaderValue%d", index)] = value
+		index++
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package datasources
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const envHeadersConfig = "testdata/env-headers"
+
+func TestEnvHeaders(t *testing.T) {
+	fakeRepo = &fakeRepository{}
+	bus.ClearBusHandlers()
+	t.Cleanup(bus.ClearBusHandlers)
+	bus.AddHandler("test", mockDelete)
+	bus.AddHandler("test", mockInsert)
+	bus.AddHandler("test", mockUpdate)
+	bus.AddHandler("test", mockGet)
+	bus.AddHandler("test", mockGetOrg)
+	bus.AddHandler("test", mockSaveProvisioned)
+	bus.AddHandler("test", mockGetProvisioned)
+
+	_ = os.Setenv("PROXY_AUTH_TOKEN", "Bearer first-token")
+	t.Cleanup(func() { _ = os.Unsetenv("PROXY_AUTH_TOKEN") })
+
+	dc := newDatasourceProvisioner(logger)
+
+	t.Run("Header value is resolved from the environment", func(t *testing.T) {
+		require.NoError(t, dc.applyChanges(context.Background(), envHeadersConfig))
+
+		require.Len(t, fakeRepo.inserted, 1)
+		ds := fakeRepo.inserted[0]
+		assert.Equal(t, "X-Scope-OrgID", ds.JsonData.Get("httpHeaderName1").MustString())
+		assert.Equal(t, "Authorization", ds.JsonData.Get("httpHeaderName2").MustString())
+		assert.Equal(t, map[string]string{
+			"httpHeaderValue1": "tenant-1",
+			"httpHeaderValue2": "Bearer first-token",
+		}, ds.SecureJsonData)
+	})
+
+	t.Run("Reprovisioning picks up a changed environment value", func(t *testing.T) {
+		fakeRepo.loadAll = []*models.DataSource{{Name: "Prometheus", OrgId: 1, Id: 1}}
+		_ = os.Setenv("PROXY_AUTH_TOKEN", "Bearer rotated-token")
+
+		require.NoError(t, dc.applyChanges(context.Background(), envHeadersConfig))
+
+		require.Len(t, fakeRepo.updated, 1)
+		ds := fakeRepo.updated[0]
+		assert.Equal(t, "Authorization", ds.JsonData.Get("httpHeaderName2").MustString())
+		assert.Equal(t, "Bearer rotated-token", ds.SecureJsonData["httpHeaderValue2"])
+	})
+
+	t.Run("Missing environment variable fails provisioning", func(t *testing.T) {
+		_ = os.Unsetenv("PROXY_AUTH_TOKEN")
+
+		err := dc.applyChanges(context.Background(), envHeadersConfig)
+		require.EqualError(t, err, `failed to provision "Prometheus" data source: environment variable "PROXY_AUTH_TOKEN" for header "Authorization" is not set`)
+	})
+}
+
+func TestResolveEnvHeaders(t *testing.T) {
+	t.Run("Header configured in both jsonData and envHeaders is rejected", func(t *testing.T) {
+		ds := &upsertDataSourceFromConfig{
+			JSONData:   map[string]interface{}{"httpHeaderName1": "authorization"},
+			EnvHeaders: map[string]string{"Authorization": "PROXY_AUTH_TOKEN"},
+		}
+
+		err := resolveEnvHeaders(ds)
+		require.EqualError(t, err, `header "Authorization" is configured both in jsonData and envHeaders`)
+	})
+
+	t.Run("Headers are numbered in name order", func(t *testing.T) {
+		_ = os.Setenv("HEADER_A", "a")
+		_ = os.Setenv("HEADER_B", "b")
+		t.Cleanup(func() {
+			_ = os.Unsetenv("HEADER_A")
+			_ = os.Unsetenv("HEADER_B")
+		})
+		ds := &upsertDataSourceFromConfig{
+			EnvHeaders: map[string]string{"X-B": "HEADER_B", "X-A": "HEADER_A"},
+		}
+
+		require.NoError(t, resolveEnvHeaders(ds))
+		assert.Equal(t, map[string]interface{}{"httpHeaderName1": "X-A", "httpHeaderName2": "X-B"}, ds.JSONData)
+		assert.Equal(t, map[string]string{"httpHeaderValue1": "a", "httpHeaderValue2": "b"}, ds.SecureJSONData)
+	})
+}
@@ -0,0 +1,137 @@
+package datasources
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+)
+
+// exportFileName is the name of the file Export writes the datasources to.
+const exportFileName = "datasources.yaml"
+
+// exportedConfigV1 is the apiVersion 1 file Export writes, with the fields of configsV1 it fills.
+type exportedConfigV1 struct {
+	APIVersion  int64                   `yaml:"apiVersion"`
+	Datasources []*exportedDatasourceV1 `yaml:"datasources"`
+}
+
+type exportedDatasourceV1 struct {
+	OrgID             int64                  `yaml:"orgId"`
+	Name              string                 `yaml:"name"`
+	Type              string                 `yaml:"type"`
+	Access            string                 `yaml:"access,omitempty"`
+	UID               string                 `yaml:"uid,omitempty"`
+	URL               string                 `yaml:"url,omitempty"`
+	User              string                 `yaml:"user,omitempty"`
+	Database          string                 `yaml:"database,omitempty"`
+	BasicAuth         bool                   `yaml:"basicAuth,omitempty"`
+	BasicAuthUser     string                 `yaml:"basicAuthUser,omitempty"`
+	WithCredentials   bool                   `yaml:"withCredentials,omitempty"`
+	IsDefault         bool                   `yaml:"isDefault,omitempty"`
+	JSONData          map[string]interface{} `yaml:"jsonData,omitempty"`
+	Password          string                 `yaml:"password,omitempty"`
+	BasicAuthPassword string                 `yaml:"basicAuthPassword,omitempty"`
+	SecureJSONData    map[string]string      `yaml:"secureJsonData,omitempty"`
+	Editable          bool                   `yaml:"editable"`
+}
+
+// Export returns a provisioning file of the datasources in the database, of every org, or only of the ones
+// provisioning manages when provisionedOnly is set. Their secrets are ${DS_...} placeholders, so provisioning the file
+// with the variables set leaves the datasources as they are.
+func Export(ctx context.Context, provisionedOnly bool) (*utils.ExportFile, error) {
+	datasources, err := exportedDatasources(ctx, provisionedOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := utils.NewExportSecrets("DS")
+	cfg := &exportedConfigV1{APIVersion: 1, Datasources: []*exportedDatasourceV1{}}
+	for _, ds := range datasources {
+		cfg.Datasources = append(cfg.Datasources, exportDatasource(ds, secrets))
+	}
+
+	content, err := utils.MarshalExport(cfg, secrets.Variables())
+	if err != nil {
+		return nil, err
+	}
+	return &utils.ExportFile{Name: exportFileName, Content: content, Objects: len(cfg.Datasources),
+		Variables: secrets.Variables()}, nil
+}
+
+func exportedDatasources(ctx context.Context, provisionedOnly bool) ([]*models.DataSource, error) {
+	if provisionedOnly {
+		query := &models.GetProvisionedDatasourcesQuery{}
+		if err := bus.DispatchCtx(ctx, query); err != nil {
+			return nil, err
+		}
+		return query.Result, nil
+	}
+
+	orgs := &models.SearchOrgsQuery{}
+	if err := bus.DispatchCtx(ctx, orgs); err != nil {
+		return nil, err
+	}
+	var datasources []*models.DataSource
+	for _, org := range orgs.Result {
+		query := &models.GetDataSourcesQuery{OrgId: org.Id}
+		if err := bus.DispatchCtx(ctx, query); err != nil {
+			return nil, err
+		}
+		datasources = append(datasources, query.Result...)
+	}
+	return datasources, nil
+}
+
+func exportDatasource(ds *models.DataSource, secrets *utils.ExportSecrets) *exportedDatasourceV1 {
+	escape := utils.EscapeInterpolation
+	exported := &exportedDatasourceV1{
+		OrgID:           ds.OrgId,
+		Name:            escape(ds.Name),
+		Type:            escape(ds.Type),
+		Access:          escape(string(ds.Access)),
+		UID:             escape(ds.Uid),
+		URL:             escape(ds.Url),
+		User:            escape(ds.User),
+		Database:        escape(ds.Database),
+		BasicAuth:       ds.BasicAuth,
+		BasicAuthUser:   escape(ds.BasicAuthUser),
+		WithCredentials: ds.WithCredentials,
+		IsDefault:       ds.IsDefault,
+		Editable:        !ds.ReadOnly,
+	}
+	if ds.JsonData != nil {
+		if jsonData, ok := utils.ExportJSON(ds.JsonData.MustMap()).(map[string]interface{}); ok && len(jsonData) > 0 {
+			exported.JSONData = jsonData
+		}
+	}
+
+	// The variables of the datasources of other orgs than the main one are named after their org too, since names are
+	// only unique within an org.
+	name := ds.Name
+	if ds.OrgId != 1 {
+		name = "org" + strconv.FormatInt(ds.OrgId, 10) + "_" + ds.Name
+	}
+	if ds.Password != "" {
+		exported.Password = secrets.Placeholder(name, "password")
+	}
+	if ds.BasicAuthPassword != "" {
+		exported.BasicAuthPassword = secrets.Placeholder(name, "basicAuthPassword")
+	}
+	// The keys are sorted, so the suffixes of variables that are named alike don't change between exports.
+	keys := make([]string, 0, len(ds.SecureJsonData))
+	for key := range ds.SecureJsonData {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if exported.SecureJSONData == nil {
+			exported.SecureJSONData = map[string]string{}
+		}
+		exported.SecureJSONData[key] = secrets.Placeholder(name, key)
+	}
+	return exported
+}
@@ -0,0 +1,84 @@
+package datasources
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/components/securejsondata"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	fakeRepo = &fakeRepository{}
+	// The datasources of other tests with the same ids have other secrets.
+	models.ClearDSDecryptionCache()
+	t.Cleanup(bus.ClearBusHandlers)
+	bus.AddHandler("test", mockGet)
+	bus.AddHandler("test", mockGetOrg)
+	bus.AddHandler("test", mockGetProvisioned)
+	bus.AddHandler("test", func(query *models.SearchOrgsQuery) error {
+		query.Result = []*models.OrgDTO{{Id: 1, Name: "Main Org."}, {Id: 2, Name: "Second Org."}}
+		return nil
+	})
+	bus.AddHandler("test", func(query *models.GetDataSourcesQuery) error {
+		query.Result = nil
+		for _, ds := range fakeRepo.loadAll {
+			if ds.OrgId == query.OrgId {
+				query.Result = append(query.Result, ds)
+			}
+		}
+		return nil
+	})
+
+	jsonData, err := simplejson.NewJson([]byte(`{"graphiteVersion": "1.1", "timeout": 30, "query": "$__interval"}`))
+	require.NoError(t, err)
+	graphite := &models.DataSource{Id: 1, OrgId: 1, Name: "Graphite", Uid: "graphite", Type: "graphite",
+		Access: models.DS_ACCESS_PROXY, Url: "http://localhost:8080", BasicAuth: true, BasicAuthUser: "grafana",
+		ReadOnly: true, JsonData: jsonData,
+		SecureJsonData: securejsondata.GetEncryptedJsonData(map[string]string{"basicAuthPassword": "secret", "token": "t0ken"})}
+	prometheus := &models.DataSource{Id: 2, OrgId: 2, Name: "Prometheus", Uid: "prometheus", Type: "prometheus",
+		Access: models.DS_ACCESS_PROXY, Url: "http://localhost:9090", IsDefault: true, Password: "legacy"}
+	fakeRepo.loadAll = []*models.DataSource{graphite, prometheus}
+	fakeRepo.provisioned = []*models.DataSource{graphite}
+
+	t.Run("Only the provisioned datasources are exported when asked to", func(t *testing.T) {
+		file, err := Export(context.Background(), true)
+		require.NoError(t, err)
+
+		assert.Equal(t, "datasources.yaml", file.Name)
+		assert.Equal(t, 1, file.Objects)
+		assert.Equal(t, []string{"DS_GRAPHITE_BASICAUTHPASSWORD", "DS_GRAPHITE_TOKEN"}, file.Variables)
+		assert.NotContains(t, string(file.Content), "t0ken", "Secrets aren't exported")
+	})
+
+	t.Run("The exported file provisions the datasources as they are", func(t *testing.T) {
+		file, err := Export(context.Background(), false)
+		require.NoError(t, err)
+		assert.Equal(t, 2, file.Objects)
+		assert.Equal(t, []string{"DS_GRAPHITE_BASICAUTHPASSWORD", "DS_GRAPHITE_TOKEN", "DS_ORG2_PROMETHEUS_PASSWORD"},
+			file.Variables)
+
+		dir := t.TempDir()
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, file.Name), file.Content, 0600))
+		for variable, value := range map[string]string{"DS_GRAPHITE_BASICAUTHPASSWORD": "secret",
+			"DS_GRAPHITE_TOKEN": "t0ken", "DS_ORG2_PROMETHEUS_PASSWORD": "legacy"} {
+			require.NoError(t, os.Setenv(variable, value))
+			variable := variable
+			t.Cleanup(func() { _ = os.Unsetenv(variable) })
+		}
+
+		diff, err := Diff(context.Background(), []string{dir}, setting.ProvisioningFileFilter{}, true)
+		require.NoError(t, err)
+		assert.Empty(t, diff.Added)
+		assert.Empty(t, diff.Removed)
+		assert.Empty(t, diff.Changed, "Provisioning the export is a no-op")
+	})
+}
@@ -0,0 +1,90 @@
+package datasources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// HealthCheckMode controls what happens when the health check of a provisioned datasource fails.
+type HealthCheckMode string
+
+const (
+	// HealthCheckOff doesn't check the health of provisioned datasources.
+	HealthCheckOff HealthCheckMode = "off"
+	// HealthCheckWarn logs a warning for every datasource that fails its health check.
+	HealthCheckWarn HealthCheckMode = "warn"
+	// HealthCheckFail fails provisioning when a datasource fails its health check.
+	HealthCheckFail HealthCheckMode = "fail"
+)
+
+// ErrHealthCheckNotSupported is returned by a HealthChecker for datasources whose plugin has no health check.
+var ErrHealthCheckNotSupported = errors.New("data source plugin doesn't support health checks")
+
+// HealthChecker checks whether a provisioned datasource can be reached.
+type HealthChecker func(ctx context.Context, ds *models.DataSource) error
+
+// HealthCheckSettings control the health checks run after a datasource is inserted or updated. The mode can be
+// overridden by the healthCheck field of each datasource.
+type HealthCheckSettings struct {
+	Mode HealthCheckMode
+	// Timeout bounds every health check, so an unreachable datasource can't hold up provisioning.
+	Timeout time.Duration
+	Check   HealthChecker
+}
+
+func validateHealthCheckMode(mode HealthCheckMode) error {
+	switch mode {
+	case "", HealthCheckOff, HealthCheckWarn, HealthCheckFail:
+		return nil
+	}
+	return fmt.Errorf("invalid healthCheck %q, must be one of off, warn or fail", mode)
+}
+
+// checkHealth runs the health check of a datasource that was just inserted or updated. An error is only
+// returned when the check failed in fail mode.
+func (dc *DatasourceProvisioner) checkHealth(ctx context.Context, config *upsertDataSourceFromConfig, ds *models.DataSource) error {
+	mode := dc.healthCheck.Mode
+	if config.HealthCheck != "" {
+		mode = config.HealthCheck
+	}
+	if (mode != HealthCheckWarn && mode != HealthCheckFail) || dc.healthCheck.Check == nil || ds == nil {
+		return nil
+	}
+
+	err := runHealthCheck(ctx, dc.healthCheck.Check, dc.healthCheck.Timeout, ds)
+	if errors.Is(err, ErrHealthCheckNotSupported) {
+		dc.log.Debug("data source doesn't support health checks", "name", ds.Name, "type", ds.Type)
+		return nil
+	}
+	if err == nil {
+		return nil
+	}
+
+	if mode == HealthCheckFail {
+		return fmt.Errorf("health check of %q data source failed: %w", ds.Name, err)
+	}
+	dc.log.Warn("provisioned data source failed its health check", "name", ds.Name, "orgId", ds.OrgId, "error", err)
+	return nil
+}
+
+// runHealthCheck gives up on checks that don't return within timeout, even when they ignore the context.
+func runHealthCheck(ctx context.Context, check HealthChecker, timeout time.Duration, ds *models.DataSource) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- check(ctx, ds)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("health check timed out after %v", timeout)
+	}
+}
@@ -0,0 +1,104 @@
+package datasources
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	healthCheckConfig        = "testdata/health-check"
+	invalidHealthCheckConfig = "testdata/invalid-health-check"
+)
+
+func TestHealthCheck(t *testing.T) {
+	setup := func(t *testing.T, mode HealthCheckMode, check HealthChecker) DatasourceProvisioner {
+		t.Helper()
+
+		fakeRepo = &fakeRepository{}
+		bus.ClearBusHandlers()
+		t.Cleanup(bus.ClearBusHandlers)
+		bus.AddHandler("test", mockDelete)
+		bus.AddHandler("test", mockInsert)
+		bus.AddHandler("test", mockUpdate)
+		bus.AddHandler("test", mockGet)
+		bus.AddHandler("test", mockGetOrg)
+		bus.AddHandler("test", mockSaveProvisioned)
+		bus.AddHandler("test", mockGetProvisioned)
+
+		dc := newDatasourceProvisioner(logger)
+		dc.healthCheck = HealthCheckSettings{Mode: mode, Timeout: time.Second, Check: check}
+		return dc
+	}
+	failUnreachable := func(ctx context.Context, ds *models.DataSource) error {
+		if ds.Url == "http://unreachable:9090" {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	t.Run("Health checks are off by default", func(t *testing.T) {
+		var checked []string
+		dc := setup(t, HealthCheckOff, func(ctx context.Context, ds *models.DataSource) error {
+			checked = append(checked, ds.Name)
+			return nil
+		})
+
+		require.NoError(t, dc.applyChanges(context.Background(), healthCheckConfig))
+		assert.Equal(t, []string{"Unreachable"}, checked, "Only the datasource that opts in should be checked")
+	})
+
+	t.Run("Failed health checks only warn in warn mode", func(t *testing.T) {
+		dc := setup(t, HealthCheckWarn, failUnreachable)
+
+		require.NoError(t, dc.applyChanges(context.Background(), healthCheckConfig))
+		assert.Len(t, fakeRepo.inserted, 2)
+	})
+
+	t.Run("Failed health checks fail provisioning in fail mode", func(t *testing.T) {
+		dc := setup(t, HealthCheckFail, failUnreachable)
+		require.NoError(t, dc.applyChanges(context.Background(), healthCheckConfig), "The failing datasource overrides fail mode with warn mode")
+
+		dc.healthCheck.Check = func(ctx context.Context, ds *models.DataSource) error {
+			return errors.New("connection refused")
+		}
+		err := dc.applyChanges(context.Background(), healthCheckConfig)
+		require.EqualError(t, err, `health check of "Prometheus" data source failed: connection refused`)
+	})
+
+	t.Run("Datasources without a health check pass", func(t *testing.T) {
+		dc := setup(t, HealthCheckFail, func(ctx context.Context, ds *models.DataSource) error {
+			return ErrHealthCheckNotSupported
+		})
+
+		require.NoError(t, dc.applyChanges(context.Background(), healthCheckConfig))
+	})
+
+	t.Run("Hanging health checks time out", func(t *testing.T) {
+		release := make(chan struct{})
+		t.Cleanup(func() { close(release) })
+		dc := setup(t, HealthCheckFail, func(ctx context.Context, ds *models.DataSource) error {
+			// Ignores the context, like a check stuck on a dial without a deadline.
+			<-release
+			return nil
+		})
+		dc.healthCheck.Timeout = 10 * time.Millisecond
+
+		err := dc.applyChanges(context.Background(), healthCheckConfig)
+		require.EqualError(t, err, `health check of "Prometheus" data source failed: health check timed out after 10ms`)
+	})
+
+	t.Run("Invalid health check mode fails provisioning", func(t *testing.T) {
+		dc := setup(t, HealthCheckOff, nil)
+
+		err := dc.applyChanges(context.Background(), invalidHealthCheckConfig)
+		require.EqualError(t, err,
+			`failed to provision "Prometheus" data source: invalid healthCheck "always", must be one of off, warn or fail`)
+	})
+}
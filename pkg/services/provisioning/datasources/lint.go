@@ -0,0 +1,48 @@
+package datasources
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// Lint validates the datasource config files in configDirectory without a database, so the checks that need one,
+// like whether orgs exist, are skipped. Unknown fields are an error when strict is set. It returns a LintError for
+// every invalid file.
+func Lint(configDirectory string, strict bool) ([]utils.LintError, error) {
+	cr := &configReader{log: log.New("provisioning.datasources"), offline: true, strict: strict,
+		env: utils.EnvironmentFromContext(context.Background())}
+	files, err := utils.ProvisioningFiles(configDirectory, setting.ProvisioningFileFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	var lintErrors []utils.LintError
+	var valid []*configs
+	for _, file := range files {
+		filename, _ := filepath.Abs(filepath.Join(configDirectory, file.Name()))
+		cfgs, err := cr.parseDatasourceConfig(configDirectory, file)
+		if err == nil {
+			err = applyPathOrg(configDirectory, cfgs)
+		}
+		for _, cfg := range cfgs {
+			if err == nil {
+				err = cr.validateDatasources(context.Background(), cfg)
+			}
+		}
+		if err != nil {
+			lintErrors = append(lintErrors, utils.NewLintError(filename, err))
+			continue
+		}
+		valid = append(valid, cfgs...)
+	}
+
+	if err := validateSingleDefault(valid); err != nil {
+		dir, _ := filepath.Abs(configDirectory)
+		lintErrors = append(lintErrors, utils.NewLintError(dir, err))
+	}
+	return lintErrors, nil
+}
@@ -0,0 +1,67 @@
+package datasources
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	t.Run("Valid configs don't have lint errors", func(t *testing.T) {
+		lintErrors, err := Lint(twoDatasourcesConfig, false)
+		require.NoError(t, err)
+		assert.Empty(t, lintErrors)
+	})
+
+	t.Run("Reports the invalid file", func(t *testing.T) {
+		lintErrors, err := Lint(invalidHealthCheckConfig, false)
+		require.NoError(t, err)
+		require.Len(t, lintErrors, 1)
+
+		filename, _ := filepath.Abs(filepath.Join(invalidHealthCheckConfig, "invalid-health-check.yaml"))
+		assert.Equal(t, filename, lintErrors[0].File)
+		assert.Contains(t, lintErrors[0].Error(), "invalid healthCheck")
+	})
+
+	t.Run("Reports the line of broken YAML", func(t *testing.T) {
+		lintErrors, err := Lint(brokenYaml, false)
+		require.NoError(t, err)
+		require.Len(t, lintErrors, 1)
+
+		filename, _ := filepath.Abs(filepath.Join(brokenYaml, "broken.yaml"))
+		assert.Equal(t, filename, lintErrors[0].File)
+		assert.Greater(t, lintErrors[0].Line, 0)
+	})
+
+	t.Run("Reports more than one default across files", func(t *testing.T) {
+		lintErrors, err := Lint(doubleDatasourcesConfig, false)
+		require.NoError(t, err)
+		require.Len(t, lintErrors, 1)
+
+		dir, _ := filepath.Abs(doubleDatasourcesConfig)
+		assert.Equal(t, dir, lintErrors[0].File)
+		assert.Equal(t, ErrInvalidConfigToManyDefault, lintErrors[0].Err)
+	})
+
+	t.Run("Reports unknown fields in strict mode", func(t *testing.T) {
+		lintErrors, err := Lint("testdata/unknown-fields", false)
+		require.NoError(t, err)
+		assert.Empty(t, lintErrors, "Unknown fields are ignored by default")
+
+		lintErrors, err = Lint("testdata/unknown-fields", true)
+		require.NoError(t, err)
+		require.Len(t, lintErrors, 1)
+
+		filename, _ := filepath.Abs("testdata/unknown-fields/unknown-fields.yaml")
+		assert.Equal(t, filename, lintErrors[0].File)
+		assert.Equal(t, 8, lintErrors[0].Line)
+		assert.Contains(t, lintErrors[0].Err.Error(), `unknown field "isDefualt"`)
+	})
+
+	t.Run("Fails when the directory can't be read", func(t *testing.T) {
+		_, err := Lint("testdata/missing", false)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,110 @@
+package datasources
+
+import (
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// UpdateMode controls how a datasource of the config files updates the datasource with its name that already
+// exists. Datasources that don't exist yet are inserted as they're configured either way.
+type UpdateMode string
+
+const (
+	// UpdateReplace replaces the stored datasource with the config, fields the config doesn't set included.
+	UpdateReplace UpdateMode = "replace"
+	// UpdateMerge only updates the fields the config sets, and the keys of the jsonData and secureJsonData it sets.
+	UpdateMerge UpdateMode = "merge"
+)
+
+func validateUpdateMode(mode UpdateMode) error {
+	switch mode {
+	case "", UpdateReplace, UpdateMerge:
+		return nil
+	}
+	return fmt.Errorf("invalid mode %q, must be one of replace or merge", mode)
+}
+
+// mergeWithExisting returns a copy of ds with the fields its config doesn't set taken from the stored datasource.
+// The jsonData of both is merged, keys of nested objects included, and the secureJsonData keys of ds are added to
+// the stored ones.
+func mergeWithExisting(existing *models.DataSource, ds *upsertDataSourceFromConfig) *upsertDataSourceFromConfig {
+	merged := *ds
+	keep := func(field string) bool { return !ds.Fields[field] }
+
+	if keep("type") {
+		merged.Type = existing.Type
+	}
+	if keep("access") {
+		merged.Access = string(existing.Access)
+	}
+	if keep("url") {
+		merged.URL = existing.Url
+	}
+	if keep("password") {
+		merged.Password = existing.Password
+	}
+	if keep("user") {
+		merged.User = existing.User
+	}
+	if keep("database") {
+		merged.Database = existing.Database
+	}
+	if keep("basicAuth") {
+		merged.BasicAuth = existing.BasicAuth
+	}
+	if keep("basicAuthUser") {
+		merged.BasicAuthUser = existing.BasicAuthUser
+	}
+	if keep("basicAuthPassword") {
+		merged.BasicAuthPassword = existing.BasicAuthPassword
+	}
+	if keep("withCredentials") {
+		merged.WithCredentials = existing.WithCredentials
+	}
+	if keep("isDefault") {
+		merged.IsDefault = existing.IsDefault
+	}
+	if keep("editable") {
+		merged.Editable = !existing.ReadOnly
+	}
+	if keep("uid") {
+		merged.UID = existing.Uid
+	}
+
+	var jsonData map[string]interface{}
+	if existing.JsonData != nil {
+		jsonData = existing.JsonData.MustMap()
+	}
+	merged.JSONData = mergeJSONData(jsonData, ds.JSONData)
+
+	// The decrypted values are cached, so they're copied rather than changed.
+	merged.SecureJSONData = make(map[string]string, len(ds.SecureJSONData))
+	for key, value := range existing.DecryptedValues() {
+		merged.SecureJSONData[key] = value
+	}
+	for key, value := range ds.SecureJSONData {
+		merged.SecureJSONData[key] = value
+	}
+	return &merged
+}
+
+// mergeJSONData returns the keys of base with the ones of override on top. Objects in both are merged the same
+// way, other values of override replace the ones of base. Neither map is changed, since base may be the jsonData of
+// a datasource in a cache.
+func mergeJSONData(base, override map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		baseObject, baseIsObject := merged[key].(map[string]interface{})
+		overrideObject, overrideIsObject := value.(map[string]interface{})
+		if baseIsObject && overrideIsObject {
+			merged[key] = mergeJSONData(baseObject, overrideObject)
+			continue
+		}
+		merged[key] = value
+	}
+	return merged
+}
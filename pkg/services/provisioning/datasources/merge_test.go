@@ -0,0 +1,126 @@
+package datasources
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/components/securejsondata"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateMode(t *testing.T) {
+	setup := func(t *testing.T) *models.DataSource {
+		t.Helper()
+
+		fakeRepo = &fakeRepository{}
+		// The datasources of other tests with the same id have other secrets.
+		models.ClearDSDecryptionCache()
+		bus.ClearBusHandlers()
+		t.Cleanup(bus.ClearBusHandlers)
+		bus.AddHandler("test", mockUpdate)
+		bus.AddHandler("test", mockGet)
+		bus.AddHandler("test", mockGetOrg)
+		bus.AddHandler("test", mockSaveProvisioned)
+		bus.AddHandler("test", mockGetProvisioned)
+
+		existing := &models.DataSource{Id: 1, OrgId: 1, Name: "Prometheus", Uid: "prometheus", Type: "prometheus",
+			Access: models.DS_ACCESS_PROXY, Url: "http://prometheus:9090", BasicAuth: true, BasicAuthUser: "admin",
+			JsonData: simplejson.NewFromAny(map[string]interface{}{
+				"httpMethod": "POST",
+				"tls":        map[string]interface{}{"skipVerify": true, "serverName": "prometheus"},
+			}),
+			SecureJsonData: securejsondata.GetEncryptedJsonData(map[string]string{"basicAuthPassword": "secret"}),
+		}
+		fakeRepo.loadAll = []*models.DataSource{existing}
+		return existing
+	}
+	healthCheck := HealthCheckSettings{Mode: HealthCheckOff}
+	doc := func(mode string) string {
+		return `apiVersion: 1
+datasources:
+  - name: Prometheus
+    mode: ` + mode + `
+    jsonData:
+      tlsAuthWithCACert: true
+      tls:
+        skipVerify: false
+    secureJsonData:
+      tlsCACert: ca
+`
+	}
+
+	t.Run("Merge only updates the fields the config sets", func(t *testing.T) {
+		existing := setup(t)
+
+		require.NoError(t, ProvisionFromReader(context.Background(), 1, strings.NewReader(doc("merge")), utils.BusStore{}, false,
+			healthCheck, utils.NewInventory()))
+		require.Len(t, fakeRepo.updated, 1)
+		update := fakeRepo.updated[0]
+		assert.Equal(t, "prometheus", update.Uid)
+		assert.Equal(t, "prometheus", update.Type)
+		assert.Equal(t, models.DsAccess(models.DS_ACCESS_PROXY), update.Access)
+		assert.Equal(t, "http://prometheus:9090", update.Url)
+		assert.True(t, update.BasicAuth)
+		assert.Equal(t, "admin", update.BasicAuthUser)
+		assert.False(t, update.ReadOnly, "A datasource that's editable stays editable")
+		assert.Equal(t, map[string]interface{}{
+			"httpMethod":        "POST",
+			"tlsAuthWithCACert": true,
+			"tls":               map[string]interface{}{"skipVerify": false, "serverName": "prometheus"},
+		}, update.JsonData.MustMap())
+		assert.Equal(t, map[string]string{"basicAuthPassword": "secret", "tlsCACert": "ca"}, update.SecureJsonData)
+		assert.Equal(t, map[string]string{"basicAuthPassword": "secret"}, existing.DecryptedValues(),
+			"The cached secrets of the datasource aren't changed")
+		assert.Equal(t, []*models.DataSource{existing}, fakeRepo.provisioned, "Merged datasources are provisioned")
+	})
+
+	t.Run("Replace sets the fields the config doesn't set to their defaults", func(t *testing.T) {
+		existing := setup(t)
+
+		require.NoError(t, ProvisionFromReader(context.Background(), 1, strings.NewReader(doc("replace")), utils.BusStore{}, false,
+			healthCheck, utils.NewInventory()))
+		require.Len(t, fakeRepo.updated, 1)
+		update := fakeRepo.updated[0]
+		assert.Empty(t, update.Type)
+		assert.Empty(t, update.Url)
+		assert.False(t, update.BasicAuth)
+		assert.True(t, update.ReadOnly)
+		assert.Equal(t, map[string]interface{}{
+			"tlsAuthWithCACert": true,
+			"tls":               map[string]interface{}{"skipVerify": false},
+		}, update.JsonData.MustMap())
+		assert.Equal(t, map[string]string{"tlsCACert": "ca"}, update.SecureJsonData)
+		assert.Equal(t, []*models.DataSource{existing}, fakeRepo.provisioned)
+	})
+
+	t.Run("A merge that changes nothing isn't a change", func(t *testing.T) {
+		setup(t)
+		dir := t.TempDir()
+		content := "apiVersion: 1\ndatasources:\n  - name: Prometheus\n    mode: merge\n    url: http://prometheus:9090\n" +
+			"    jsonData:\n      tls:\n        serverName: prometheus\n"
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "datasources.yaml"), []byte(content), 0600))
+
+		diff, err := Diff(context.Background(), []string{dir}, setting.ProvisioningFileFilter{}, true)
+		require.NoError(t, err)
+		assert.Empty(t, diff.Changed)
+	})
+
+	t.Run("Only replace and merge are valid modes", func(t *testing.T) {
+		setup(t)
+
+		err := ProvisionFromReader(context.Background(), 1, strings.NewReader(doc("patch")), utils.BusStore{}, false, healthCheck,
+			utils.NewInventory())
+		require.ErrorIs(t, err, utils.ErrInvalidConfig)
+		assert.Contains(t, err.Error(), `invalid mode "patch", must be one of replace or merge`)
+		assert.Empty(t, fakeRepo.updated)
+	})
+}
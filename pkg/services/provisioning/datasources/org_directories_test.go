@@ -0,0 +1,45 @@
+package datasources
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrgScopedDirectories(t *testing.T) {
+	t.Run("Datasources get the org of their directory", func(t *testing.T) {
+		dirs := utils.OrgDirectories("testdata/org-scoped", "datasources")
+		require.Equal(t, []string{
+			"testdata/org-scoped/orgs/2/datasources",
+			"testdata/org-scoped/orgs/3/datasources",
+		}, dirs)
+
+		cr := &configReader{log: log.New("test logger"), offline: true}
+		cfgs, err := cr.readConfig(context.Background(), dirs...)
+		require.NoError(t, err, "Every org can have its own default")
+		require.Len(t, cfgs, 2)
+
+		require.Len(t, cfgs[0].Datasources, 1)
+		assert.Equal(t, int64(2), cfgs[0].Datasources[0].OrgID)
+		assert.Equal(t, "http://graphite-team-a:8080", cfgs[0].Datasources[0].URL)
+		require.Len(t, cfgs[0].DeleteDatasources, 1)
+		assert.Equal(t, int64(2), cfgs[0].DeleteDatasources[0].OrgID)
+
+		require.Len(t, cfgs[1].Datasources, 2)
+		for _, ds := range cfgs[1].Datasources {
+			assert.Equal(t, int64(3), ds.OrgID, ds.Name)
+		}
+	})
+
+	t.Run("An orgId of another org is an error", func(t *testing.T) {
+		cr := &configReader{log: log.New("test logger"), offline: true}
+		_, err := cr.readConfig(context.Background(), utils.OrgDirectories("testdata/org-conflict", "datasources")...)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, utils.ErrOrgConflict))
+	})
+}
@@ -0,0 +1,65 @@
+package datasources
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrgNames(t *testing.T) {
+	bus.ClearBusHandlers()
+	t.Cleanup(bus.ClearBusHandlers)
+	bus.AddHandler("test", mockGetOrg)
+	bus.AddHandler("test", func(query *models.GetOrgByNameQuery) error {
+		orgIDs := map[string]int64{"Main Org.": 1, "Staging": 2, "Dev": 3}
+		if orgIDs[query.Name] == 0 {
+			return models.ErrOrgNotFound
+		}
+		query.Result = &models.Org{Id: orgIDs[query.Name], Name: query.Name}
+		return nil
+	})
+
+	cr := &configReader{log: log.New("test logger")}
+
+	t.Run("Datasources get the org of their orgName", func(t *testing.T) {
+		cfgs, err := cr.readConfig(context.Background(), "testdata/org-names")
+		require.NoError(t, err)
+		require.Len(t, cfgs, 1)
+
+		orgIDs := map[string]int64{}
+		for _, ds := range cfgs[0].Datasources {
+			orgIDs[ds.Name] = ds.OrgID
+		}
+		assert.Equal(t, map[string]int64{"Graphite": 2, "Prometheus": 2, "Loki": 1}, orgIDs)
+		require.Len(t, cfgs[0].DeleteDatasources, 1)
+		assert.Equal(t, int64(2), cfgs[0].DeleteDatasources[0].OrgID)
+	})
+
+	t.Run("Datasources without an org get the default org", func(t *testing.T) {
+		cfgs, err := cr.readConfig(utils.WithDefaultOrg(context.Background(), "Dev"), "testdata/org-names")
+		require.NoError(t, err)
+		assert.Equal(t, "Loki", cfgs[0].Datasources[2].Name)
+		assert.Equal(t, int64(3), cfgs[0].Datasources[2].OrgID)
+	})
+
+	t.Run("An orgName of another org than the orgId is an error", func(t *testing.T) {
+		_, err := cr.readConfig(context.Background(), "testdata/org-name-conflict")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, utils.ErrOrgNameConflict))
+		assert.EqualError(t, err, `failed to provision "Graphite" data source: org name conflicts with the org ID: `+
+			`"Staging" is org 2, not 1`)
+	})
+
+	t.Run("An orgName that isn't an org is an error", func(t *testing.T) {
+		_, err := cr.readConfig(context.Background(), "testdata/org-name-missing")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrOrgNotFound))
+	})
+}
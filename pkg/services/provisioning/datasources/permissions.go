@@ -0,0 +1,127 @@
+package datasources
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/services/provisioning/values"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// permissionFromConfig grants the team with the name Team, or the built-in role Role, a permission on a datasource.
+type permissionFromConfig struct {
+	Team       string
+	Role       string
+	Permission string
+}
+
+type permissionFromConfigV1 struct {
+	Team       values.StringValue `json:"team" yaml:"team"`
+	Role       values.StringValue `json:"role" yaml:"role"`
+	Permission values.StringValue `json:"permission" yaml:"permission"`
+}
+
+// mapPermissions maps the permissions of a datasource, keeping an empty permissions block apart from a missing one.
+func mapPermissions(v1 []permissionFromConfigV1) []*permissionFromConfig {
+	if v1 == nil {
+		return nil
+	}
+	permissions := make([]*permissionFromConfig, 0, len(v1))
+	for _, p := range v1 {
+		permissions = append(permissions, &permissionFromConfig{
+			Team:       p.Team.Value(),
+			Role:       p.Role.Value(),
+			Permission: p.Permission.Value(),
+		})
+	}
+	return permissions
+}
+
+// validatePermissions checks that every permission names either a team or a built-in role, and is query or edit.
+func validatePermissions(permissions []*permissionFromConfig) error {
+	for i, p := range permissions {
+		if (p.Team == "") == (p.Role == "") {
+			return fmt.Errorf("permission %d must have either a team or a role", i+1)
+		}
+		if p.Role != "" && !models.RoleType(p.Role).IsValid() {
+			return fmt.Errorf("permission %d has an invalid role %q, must be one of Viewer, Editor or Admin", i+1, p.Role)
+		}
+		if p.Permission != accesscontrol.DatasourcePermissionQuery && p.Permission != accesscontrol.DatasourcePermissionEdit {
+			return fmt.Errorf("permission %d has an invalid permission %q, must be query or edit", i+1, p.Permission)
+		}
+	}
+	return nil
+}
+
+// ProvisionPermissions applies the permissions blocks of the datasources in the config files of the directories,
+// so it has to run after Provision created the datasources. Since the permissions reference teams, it runs after
+// the provisioners that may create them too. It does nothing but warn about the datasources with permissions when
+// the permissions service is disabled.
+func ProvisionPermissions(ctx context.Context, configDirectories []string, service accesscontrol.DatasourcePermissionsService,
+	fileFilter setting.ProvisioningFileFilter, strict bool) error {
+	logger := log.New("provisioning.datasources")
+	cr := &configReader{log: logger, fileFilter: fileFilter, strict: strict, env: utils.EnvironmentFromContext(ctx)}
+	configs, err := cr.readConfig(ctx, configDirectories...)
+	if err != nil {
+		return err
+	}
+
+	disabled := service == nil || service.IsDisabled()
+	for _, cfg := range configs {
+		for _, ds := range cfg.Datasources {
+			if ds.Permissions == nil {
+				continue
+			}
+			if disabled {
+				logger.Warn("Ignoring the permissions of datasource, datasource permissions aren't available",
+					"name", ds.Name, "orgId", ds.OrgID, "file", cfg.Filename)
+				continue
+			}
+			if err := applyPermissions(ctx, service, ds); err != nil {
+				return fmt.Errorf("failed to provision the permissions of %q data source: %w", ds.Name, err)
+			}
+			logger.Debug("Provisioned datasource permissions", "name", ds.Name, "orgId", ds.OrgID,
+				"permissions", len(ds.Permissions))
+		}
+	}
+	return nil
+}
+
+// applyPermissions replaces the permissions of a datasource with the ones of its config, resolving the teams by name.
+func applyPermissions(ctx context.Context, service accesscontrol.DatasourcePermissionsService, ds *upsertDataSourceFromConfig) error {
+	query := &models.GetDataSourceQuery{OrgId: ds.OrgID, Name: ds.Name}
+	if err := bus.DispatchCtx(ctx, query); err != nil {
+		return err
+	}
+
+	permissions := make([]accesscontrol.DatasourcePermission, 0, len(ds.Permissions))
+	for _, p := range ds.Permissions {
+		permission := accesscontrol.DatasourcePermission{BuiltInRole: p.Role, Permission: p.Permission}
+		if p.Team != "" {
+			teamID, err := teamID(ctx, ds.OrgID, p.Team)
+			if err != nil {
+				return err
+			}
+			permission.TeamID = teamID
+		}
+		permissions = append(permissions, permission)
+	}
+	return service.SetDatasourcePermissions(ctx, ds.OrgID, query.Result.Id, permissions)
+}
+
+// teamID returns the ID of the team with the name in the org.
+func teamID(ctx context.Context, orgID int64, name string) (int64, error) {
+	query := &models.SearchTeamsQuery{OrgId: orgID, Name: name, Limit: 1, Page: 1}
+	if err := bus.DispatchCtx(ctx, query); err != nil {
+		return 0, err
+	}
+	if len(query.Result.Teams) == 0 {
+		return 0, utils.InvalidConfig(fmt.Errorf("%w: %q in org %d", models.ErrTeamNotFound, name, orgID))
+	}
+	return query.Result.Teams[0].Id, nil
+}
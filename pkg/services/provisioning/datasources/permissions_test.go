@@ -0,0 +1,162 @@
+package datasources
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	permissionsConfig        = "testdata/permissions"
+	invalidPermissionsConfig = "testdata/invalid-permissions"
+)
+
+func TestProvisionPermissions(t *testing.T) {
+	setup := func(t *testing.T) *fakeDatasourcePermissions {
+		t.Helper()
+
+		fakeRepo = &fakeRepository{loadAll: []*models.DataSource{
+			{Id: 1, OrgId: 1, Name: "Prometheus"},
+			{Id: 2, OrgId: 1, Name: "Graphite"},
+			{Id: 3, OrgId: 1, Name: "Loki"},
+		}}
+		bus.ClearBusHandlers()
+		t.Cleanup(bus.ClearBusHandlers)
+		bus.AddHandler("test", mockGet)
+		bus.AddHandler("test", mockGetOrg)
+		bus.AddHandler("test", func(query *models.SearchTeamsQuery) error {
+			query.Result = models.SearchTeamQueryResult{Teams: []*models.TeamDTO{}}
+			if query.Name == "SRE" {
+				query.Result.Teams = append(query.Result.Teams, &models.TeamDTO{Id: 7, OrgId: query.OrgId, Name: "SRE"})
+			}
+			return nil
+		})
+		return &fakeDatasourcePermissions{permissions: map[int64][]accesscontrol.DatasourcePermission{}}
+	}
+	provision := func(service accesscontrol.DatasourcePermissionsService, dirs ...string) error {
+		return ProvisionPermissions(context.Background(), dirs, service, setting.ProvisioningFileFilter{}, false)
+	}
+
+	t.Run("Permissions of the config replace the ones of the datasources", func(t *testing.T) {
+		service := setup(t)
+		service.permissions[3] = []accesscontrol.DatasourcePermission{{BuiltInRole: "Viewer", Permission: "query"}}
+
+		require.NoError(t, provision(service, permissionsConfig))
+		assert.Equal(t, []accesscontrol.DatasourcePermission{
+			{TeamID: 7, Permission: accesscontrol.DatasourcePermissionQuery},
+			{BuiltInRole: "Editor", Permission: accesscontrol.DatasourcePermissionEdit},
+		}, service.permissions[1])
+		assert.Empty(t, service.permissions[2], "An empty permissions block should revoke every permission")
+		assert.Contains(t, service.permissions, int64(2))
+		assert.Len(t, service.permissions[3], 1, "Datasources without a permissions block should keep their permissions")
+	})
+
+	t.Run("Permissions removed from the config are revoked", func(t *testing.T) {
+		service := setup(t)
+		dir := t.TempDir()
+		write := func(content string) {
+			require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "datasources.yaml"), []byte(content), 0600))
+		}
+
+		write(`apiVersion: 1
+datasources:
+  - name: Prometheus
+    type: prometheus
+    permissions:
+      - team: SRE
+        permission: edit
+      - role: Viewer
+        permission: query
+`)
+		require.NoError(t, provision(service, dir))
+		require.Len(t, service.permissions[1], 2)
+
+		write(`apiVersion: 1
+datasources:
+  - name: Prometheus
+    type: prometheus
+    permissions:
+      - team: SRE
+        permission: edit
+`)
+		require.NoError(t, provision(service, dir))
+		assert.Equal(t, []accesscontrol.DatasourcePermission{{TeamID: 7, Permission: accesscontrol.DatasourcePermissionEdit}},
+			service.permissions[1])
+	})
+
+	t.Run("Permissions are ignored when datasource permissions are disabled", func(t *testing.T) {
+		service := setup(t)
+		service.disabled = true
+
+		require.NoError(t, provision(service, permissionsConfig))
+		assert.Empty(t, service.permissions)
+		require.NoError(t, provision(nil, permissionsConfig))
+	})
+
+	t.Run("Unknown teams fail provisioning the permissions", func(t *testing.T) {
+		setup(t)
+		bus.AddHandler("test", func(query *models.SearchTeamsQuery) error {
+			query.Result = models.SearchTeamQueryResult{Teams: []*models.TeamDTO{}}
+			return nil
+		})
+		service := &fakeDatasourcePermissions{permissions: map[int64][]accesscontrol.DatasourcePermission{}}
+
+		err := provision(service, permissionsConfig)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrTeamNotFound))
+		assert.True(t, utils.IsInvalidConfig(err))
+		assert.Contains(t, err.Error(), `"SRE" in org 1`)
+	})
+
+	t.Run("Permissions need either a team or a role", func(t *testing.T) {
+		service := setup(t)
+
+		err := provision(service, invalidPermissionsConfig)
+		require.EqualError(t, err, `failed to provision "Prometheus" data source: permission 1 must have either a team or a role`)
+		assert.True(t, utils.IsInvalidConfig(err))
+		assert.Empty(t, service.permissions)
+	})
+}
+
+func TestValidatePermissions(t *testing.T) {
+	require.NoError(t, validatePermissions(nil))
+	require.NoError(t, validatePermissions([]*permissionFromConfig{
+		{Team: "SRE", Permission: "query"},
+		{Role: "Admin", Permission: "edit"},
+	}))
+
+	err := validatePermissions([]*permissionFromConfig{{Permission: "query"}})
+	require.EqualError(t, err, "permission 1 must have either a team or a role")
+
+	err = validatePermissions([]*permissionFromConfig{{Role: "Owner", Permission: "query"}})
+	require.EqualError(t, err, `permission 1 has an invalid role "Owner", must be one of Viewer, Editor or Admin`)
+
+	err = validatePermissions([]*permissionFromConfig{{Team: "SRE", Permission: "admin"}})
+	require.EqualError(t, err, `permission 1 has an invalid permission "admin", must be query or edit`)
+}
+
+// fakeDatasourcePermissions keeps the permissions of the datasources by datasource ID.
+type fakeDatasourcePermissions struct {
+	disabled    bool
+	permissions map[int64][]accesscontrol.DatasourcePermission
+}
+
+func (f *fakeDatasourcePermissions) IsDisabled() bool {
+	return f.disabled
+}
+
+func (f *fakeDatasourcePermissions) SetDatasourcePermissions(_ context.Context, _ int64, datasourceID int64,
+	permissions []accesscontrol.DatasourcePermission) error {
+	f.permissions[datasourceID] = permissions
+	return nil
+}
@@ -14,18 +14,27 @@ type configVersion struct {
 
 type configs struct {
 	APIVersion int64
+	// Filename is the absolute path of the file the config was read from.
+	Filename string
+	// Document is the 1-based index of the YAML document of the file the config was read from, when the file has
+	// several documents.
+	Document int
 
 	Datasources       []*upsertDataSourceFromConfig
 	DeleteDatasources []*deleteDatasourceConfig
+	// DeletedPolicy overrides the policy for the datasources of the file that were deleted outside of provisioning.
+	DeletedPolicy DeletedPolicy
 }
 
 type deleteDatasourceConfig struct {
-	OrgID int64
-	Name  string
+	OrgID   int64
+	OrgName string
+	Name    string
 }
 
 type upsertDataSourceFromConfig struct {
 	OrgID   int64
+	OrgName string
 	Version int
 
 	Name              string
@@ -42,23 +51,37 @@ type upsertDataSourceFromConfig struct {
 	IsDefault         bool
 	JSONData          map[string]interface{}
 	SecureJSONData    map[string]string
+	EnvHeaders        map[string]string
 	Editable          bool
 	UID               string
+	HealthCheck       HealthCheckMode
+	// Permissions replace the permissions of the datasource when they're set. They're nil, which leaves the
+	// permissions alone, when the config has no permissions block.
+	Permissions []*permissionFromConfig
+	// Mode is how the datasource updates an existing one, and Fields are the fields its config sets, by their name
+	// in the file, which UpdateMerge updates. Fields is only kept for UpdateMerge. Both are left out of the checksum
+	// when they're empty, so it only changes for the configs that use them.
+	Mode   UpdateMode      `json:",omitempty"`
+	Fields map[string]bool `json:",omitempty"`
+	// Line is the line of the file the datasource starts at, 0 when it isn't known. It isn't part of the checksum,
+	// so moving a datasource in its file doesn't change it.
+	Line int `json:"-"`
 }
 
 type configsV0 struct {
-	configVersion
+	configVersion `yaml:",inline"`
 
 	Datasources       []*upsertDataSourceFromConfigV0 `json:"datasources" yaml:"datasources"`
 	DeleteDatasources []*deleteDatasourceConfigV0     `json:"delete_datasources" yaml:"delete_datasources"`
 }
 
 type configsV1 struct {
-	configVersion
-	log log.Logger
+	configVersion `yaml:",inline"`
+	log           log.Logger
 
 	Datasources       []*upsertDataSourceFromConfigV1 `json:"datasources" yaml:"datasources"`
 	DeleteDatasources []*deleteDatasourceConfigV1     `json:"deleteDatasources" yaml:"deleteDatasources"`
+	DeletedPolicy     values.StringValue              `json:"deletedPolicy" yaml:"deletedPolicy"`
 }
 
 type deleteDatasourceConfigV0 struct {
@@ -67,8 +90,9 @@ type deleteDatasourceConfigV0 struct {
 }
 
 type deleteDatasourceConfigV1 struct {
-	OrgID values.Int64Value  `json:"orgId" yaml:"orgId"`
-	Name  values.StringValue `json:"name" yaml:"name"`
+	OrgID   values.Int64Value  `json:"orgId" yaml:"orgId"`
+	OrgName values.StringValue `json:"orgName" yaml:"orgName"`
+	Name    values.StringValue `json:"name" yaml:"name"`
 }
 
 type upsertDataSourceFromConfigV0 struct {
@@ -92,24 +116,50 @@ type upsertDataSourceFromConfigV0 struct {
 }
 
 type upsertDataSourceFromConfigV1 struct {
-	OrgID             values.Int64Value     `json:"orgId" yaml:"orgId"`
-	Version           values.IntValue       `json:"version" yaml:"version"`
-	Name              values.StringValue    `json:"name" yaml:"name"`
-	Type              values.StringValue    `json:"type" yaml:"type"`
-	Access            values.StringValue    `json:"access" yaml:"access"`
-	URL               values.StringValue    `json:"url" yaml:"url"`
-	Password          values.StringValue    `json:"password" yaml:"password"`
-	User              values.StringValue    `json:"user" yaml:"user"`
-	Database          values.StringValue    `json:"database" yaml:"database"`
-	BasicAuth         values.BoolValue      `json:"basicAuth" yaml:"basicAuth"`
-	BasicAuthUser     values.StringValue    `json:"basicAuthUser" yaml:"basicAuthUser"`
-	BasicAuthPassword values.StringValue    `json:"basicAuthPassword" yaml:"basicAuthPassword"`
-	WithCredentials   values.BoolValue      `json:"withCredentials" yaml:"withCredentials"`
-	IsDefault         values.BoolValue      `json:"isDefault" yaml:"isDefault"`
-	JSONData          values.JSONValue      `json:"jsonData" yaml:"jsonData"`
-	SecureJSONData    values.StringMapValue `json:"secureJsonData" yaml:"secureJsonData"`
-	Editable          values.BoolValue      `json:"editable" yaml:"editable"`
-	UID               values.StringValue    `json:"uid" yaml:"uid"`
+	OrgID             values.Int64Value        `json:"orgId" yaml:"orgId"`
+	OrgName           values.StringValue       `json:"orgName" yaml:"orgName"`
+	Version           values.IntValue          `json:"version" yaml:"version"`
+	Name              values.StringValue       `json:"name" yaml:"name"`
+	Type              values.StringValue       `json:"type" yaml:"type"`
+	Access            values.StringValue       `json:"access" yaml:"access"`
+	URL               values.StringValue       `json:"url" yaml:"url"`
+	Password          values.StringValue       `json:"password" yaml:"password"`
+	User              values.StringValue       `json:"user" yaml:"user"`
+	Database          values.StringValue       `json:"database" yaml:"database"`
+	BasicAuth         values.BoolValue         `json:"basicAuth" yaml:"basicAuth"`
+	BasicAuthUser     values.StringValue       `json:"basicAuthUser" yaml:"basicAuthUser"`
+	BasicAuthPassword values.StringValue       `json:"basicAuthPassword" yaml:"basicAuthPassword"`
+	WithCredentials   values.BoolValue         `json:"withCredentials" yaml:"withCredentials"`
+	IsDefault         values.BoolValue         `json:"isDefault" yaml:"isDefault"`
+	JSONData          values.JSONValue         `json:"jsonData" yaml:"jsonData"`
+	SecureJSONData    values.StringMapValue    `json:"secureJsonData" yaml:"secureJsonData"`
+	EnvHeaders        map[string]string        `json:"envHeaders" yaml:"envHeaders"`
+	Editable          values.BoolValue         `json:"editable" yaml:"editable"`
+	UID               values.StringValue       `json:"uid" yaml:"uid"`
+	HealthCheck       values.StringValue       `json:"healthCheck" yaml:"healthCheck"`
+	Permissions       []permissionFromConfigV1 `json:"permissions" yaml:"permissions"`
+	Mode              values.StringValue       `json:"mode" yaml:"mode"`
+
+	// fields are the keys the config of the datasource has.
+	fields map[string]bool
+}
+
+// UnmarshalYAML decodes the datasource and records which fields it has, since the values of the fields it doesn't
+// have can't be told apart from empty ones.
+func (ds *upsertDataSourceFromConfigV1) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain upsertDataSourceFromConfigV1
+	if err := unmarshal((*plain)(ds)); err != nil {
+		return err
+	}
+	var fields map[string]interface{}
+	if err := unmarshal(&fields); err != nil {
+		return err
+	}
+	ds.fields = make(map[string]bool, len(fields))
+	for field := range fields {
+		ds.fields[field] = true
+	}
+	return nil
 }
 
 func (cfg *configsV1) mapToDatasourceFromConfig(apiVersion int64) *configs {
@@ -120,10 +170,12 @@ func (cfg *configsV1) mapToDatasourceFromConfig(apiVersion int64) *configs {
 	if cfg == nil {
 		return r
 	}
+	r.DeletedPolicy = DeletedPolicy(cfg.DeletedPolicy.Value())
 
 	for _, ds := range cfg.Datasources {
 		r.Datasources = append(r.Datasources, &upsertDataSourceFromConfig{
 			OrgID:             ds.OrgID.Value(),
+			OrgName:           ds.OrgName.Value(),
 			Name:              ds.Name.Value(),
 			Type:              ds.Type.Value(),
 			Access:            ds.Access.Value(),
@@ -138,10 +190,17 @@ func (cfg *configsV1) mapToDatasourceFromConfig(apiVersion int64) *configs {
 			IsDefault:         ds.IsDefault.Value(),
 			JSONData:          ds.JSONData.Value(),
 			SecureJSONData:    ds.SecureJSONData.Value(),
+			EnvHeaders:        ds.EnvHeaders,
 			Editable:          ds.Editable.Value(),
 			Version:           ds.Version.Value(),
 			UID:               ds.UID.Value(),
+			HealthCheck:       HealthCheckMode(ds.HealthCheck.Value()),
+			Permissions:       mapPermissions(ds.Permissions),
+			Mode:              UpdateMode(ds.Mode.Value()),
 		})
+		if UpdateMode(ds.Mode.Value()) == UpdateMerge {
+			r.Datasources[len(r.Datasources)-1].Fields = ds.fields
+		}
 
 		// Using Raw value for the warnings here so that even if it uses env interpolation and the env var is empty
 		// it will still warn
@@ -163,8 +222,9 @@ func (cfg *configsV1) mapToDatasourceFromConfig(apiVersion int64) *configs {
 
 	for _, ds := range cfg.DeleteDatasources {
 		r.DeleteDatasources = append(r.DeleteDatasources, &deleteDatasourceConfig{
-			OrgID: ds.OrgID.Value(),
-			Name:  ds.Name.Value(),
+			OrgID:   ds.OrgID.Value(),
+			OrgName: ds.OrgName.Value(),
+			Name:    ds.Name.Value(),
 		})
 	}
 
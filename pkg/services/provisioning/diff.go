@@ -0,0 +1,48 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/services/provisioning/datasources"
+	"github.com/grafana/grafana/pkg/services/provisioning/plugins"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/util/errutil"
+	"github.com/opentracing/opentracing-go"
+)
+
+// ProvisioningDiff is what provisioning the files of a kind would change in the database, as returned by
+// DiffProvisioning.
+type ProvisioningDiff = utils.Diff
+
+// ErrUnsupportedDiffKind is returned, wrapped with the kind, by DiffProvisioning for kinds it can't diff.
+var ErrUnsupportedDiffKind = errors.New("provisioning kind can't be diffed")
+
+// DiffProvisioning parses the provisioning files of kind, which is datasources, plugins or dashboards, and compares
+// them with the objects in the database without changing anything. It isn't coalesced with the Provision methods,
+// so the result can be outdated by a run that finishes at the same time.
+func (ps *provisioningServiceImpl) DiffProvisioning(ctx context.Context, kind string) (_ *ProvisioningDiff, err error) {
+	span, ctx := opentracing.StartSpanFromContext(ps.withEnvironment(ctx), "provisioning "+kind+" diff")
+	defer func() { utils.FinishSpan(span, err) }()
+
+	switch kind {
+	case "datasources":
+		diff, err := datasources.Diff(ctx, ps.orgScopedDirs("datasources"), ps.Cfg.ProvisioningFileFilters["datasources"],
+			ps.Cfg.ProvisioningStrictFields["datasources"])
+		return diff, errutil.Wrap("Failed to diff datasources", err)
+	case "plugins":
+		diff, err := plugins.Diff(ctx, ps.provisioningDirs("plugins"), ps.PluginManager, ps.Cfg.ProvisioningFileFilters["plugins"],
+			ps.Cfg.ProvisioningStrictFields["plugins"])
+		return diff, errutil.Wrap("Failed to diff plugins", err)
+	case "dashboards":
+		dashProvisioner, err := ps.newDashboardProvisioner(ctx, ps.orgScopedDirs("dashboards"), ps.provisioningStore(), ps.dashboardsCfg())
+		if err != nil {
+			return nil, errutil.Wrap("Failed to create provisioner", err)
+		}
+		diff, err := dashProvisioner.Diff(ctx)
+		return diff, errutil.Wrap("Failed to diff dashboards", err)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedDiffKind, kind)
+	}
+}
@@ -0,0 +1,54 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/grafana/grafana/pkg/services/provisioning/datasources"
+	"github.com/grafana/grafana/pkg/services/provisioning/notifiers"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/util/errutil"
+	"github.com/opentracing/opentracing-go"
+)
+
+// ErrUnsupportedExportKind is returned, wrapped with the kind, by ExportProvisioning for kinds it can't export.
+var ErrUnsupportedExportKind = errors.New("provisioning kind can't be exported")
+
+// ExportProvisioning writes the objects of kind in the database to a provisioning file in outDir, which is created
+// when it doesn't exist. The kinds are datasources, of which only the ones provisioning manages are exported when
+// provisionedOnly is set, and notifiers, which are always exported all. Secrets are written as ${VAR} placeholders
+// of environment variables, so the file doesn't hold them and provisioning it with the variables set is a no-op.
+func (ps *provisioningServiceImpl) ExportProvisioning(ctx context.Context, kind string, outDir string, provisionedOnly bool) (err error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "provisioning "+kind+" export")
+	defer func() { utils.FinishSpan(span, err) }()
+
+	var file *utils.ExportFile
+	switch kind {
+	case "datasources":
+		file, err = datasources.Export(ctx, provisionedOnly)
+	case "notifiers":
+		if provisionedOnly {
+			return fmt.Errorf("%w: %q don't record whether provisioning created them", ErrUnsupportedExportKind, kind)
+		}
+		file, err = notifiers.Export(ctx)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedExportKind, kind)
+	}
+	if err != nil {
+		return errutil.Wrapf(err, "Failed to export %s", kind)
+	}
+
+	if err := os.MkdirAll(outDir, 0750); err != nil {
+		return errutil.Wrapf(err, "Failed to export %s", kind)
+	}
+	path := filepath.Join(outDir, file.Name)
+	if err := writeFileAtomic(path, file.Content); err != nil {
+		return errutil.Wrapf(err, "Failed to export %s", kind)
+	}
+	ps.log.Info("Exported provisioning file", "kind", kind, "path", path, "objects", file.Objects,
+		"variables", file.Variables)
+	return nil
+}
@@ -0,0 +1,58 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportProvisioning(t *testing.T) {
+	t.Cleanup(bus.ClearBusHandlers)
+	bus.ClearBusHandlers()
+	bus.AddHandler("test", func(query *models.GetProvisionedDatasourcesQuery) error {
+		query.Result = []*models.DataSource{{Id: 1, OrgId: 1, Name: "Graphite", Uid: "graphite", Type: "graphite",
+			Access: models.DS_ACCESS_PROXY, Url: "http://localhost:8080", Password: "hunter2"}}
+		return nil
+	})
+
+	t.Run("Writes the file to the directory", func(t *testing.T) {
+		serviceTest := setup()
+		outDir := filepath.Join(t.TempDir(), "export")
+
+		require.NoError(t, serviceTest.service.ExportProvisioning(context.Background(), "datasources", outDir, true))
+		content, err := ioutil.ReadFile(filepath.Join(outDir, "datasources.yaml"))
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "password: ${DS_GRAPHITE_PASSWORD}")
+		assert.NotContains(t, string(content), "hunter2")
+
+		entries, err := ioutil.ReadDir(outDir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1, "The temporary file is renamed")
+	})
+
+	t.Run("Notifiers are only exported all", func(t *testing.T) {
+		serviceTest := setup()
+		outDir := t.TempDir()
+
+		err := serviceTest.service.ExportProvisioning(context.Background(), "notifiers", outDir, true)
+		require.True(t, errors.Is(err, ErrUnsupportedExportKind))
+		_, err = os.Stat(filepath.Join(outDir, "notifiers.yaml"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Fails for kinds that can't be exported", func(t *testing.T) {
+		serviceTest := setup()
+
+		err := serviceTest.service.ExportProvisioning(context.Background(), "plugins", t.TempDir(), false)
+		require.True(t, errors.Is(err, ErrUnsupportedExportKind))
+		assert.EqualError(t, err, `provisioning kind can't be exported: "plugins"`)
+	})
+}
@@ -0,0 +1,52 @@
+package provisioning
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// failureNotification is the payload sent to the failure webhook when a provisioning pass fails.
+type failureNotification struct {
+	Title     string `json:"title"`
+	Kind      string `json:"kind"`
+	Error     string `json:"error"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// notifyFailure sends err to the configured failure webhook URL in the background and returns it, so it can wrap
+// the return value of a provisioning pass. Failing to notify is only logged.
+func (ps *provisioningServiceImpl) notifyFailure(kind string, err error) error {
+	if err == nil || ps.Cfg.ProvisioningFailureWebhookURL == "" {
+		return err
+	}
+
+	body, jsonErr := json.Marshal(failureNotification{
+		Title:     "Grafana provisioning failed",
+		Kind:      kind,
+		Error:     err.Error(),
+		Timestamp: time.Now().Unix(),
+	})
+	if jsonErr != nil {
+		ps.log.Warn("Failed to create provisioning failure notification", "kind", kind, "error", jsonErr)
+		return err
+	}
+
+	cmd := &models.SendWebhookSync{
+		Url:         ps.Cfg.ProvisioningFailureWebhookURL,
+		Body:        string(body),
+		HttpMethod:  http.MethodPost,
+		ContentType: "application/json",
+	}
+	go func() {
+		if err := bus.DispatchCtx(context.Background(), cmd); err != nil {
+			ps.log.Warn("Failed to send provisioning failure notification", "kind", kind, "error", err)
+		}
+	}()
+
+	return err
+}
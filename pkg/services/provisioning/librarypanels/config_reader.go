@@ -0,0 +1,202 @@
+package librarypanels
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+type configReader struct {
+	log        log.Logger
+	fileFilter setting.ProvisioningFileFilter
+	// strict rejects unknown fields instead of ignoring them.
+	strict bool
+	// env is what the guards of the files are checked against.
+	env utils.Environment
+}
+
+// readConfig reads the config files of each directory in order. Library panels of later directories override the
+// ones of earlier directories with the same uid in the same org. Every file is read before the invalid ones are
+// returned as utils.FileErrors.
+func (cr *configReader) readConfig(paths ...string) ([]*configs, error) {
+	var panels []*configs
+	var fileErrs utils.FileErrors
+
+	for _, path := range paths {
+		configs, err := cr.readDirectory(path)
+		if fileErrs, err = utils.AppendFileError(fileErrs, err); err != nil {
+			return nil, err
+		}
+
+		for _, cfg := range configs {
+			cr.overrideLibraryPanels(panels, cfg)
+		}
+		panels = append(panels, configs...)
+	}
+	if err := fileErrs.Err(); err != nil {
+		return nil, err
+	}
+
+	cr.log.Debug("Validating library panels")
+	if err := validateRequiredField(panels); err != nil {
+		return nil, err
+	}
+
+	return panels, nil
+}
+
+func (cr *configReader) readDirectory(path string) ([]*configs, error) {
+	var panels []*configs
+	var fileErrs utils.FileErrors
+	cr.log.Debug("Looking for library panel provisioning files", "path", path)
+
+	files, err := utils.ReadDir(path)
+	if err != nil {
+		cr.log.Error("Can't read library panel provisioning files from directory", "path", path, "error", err)
+		return panels, nil
+	}
+
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".yaml") || strings.HasSuffix(file.Name(), ".yml") {
+			if !cr.fileFilter.Includes(file.Name()) {
+				cr.log.Debug("Skipping excluded library panel provisioning file", "path", path, "file.Name", file.Name())
+				continue
+			}
+
+			cr.log.Debug("Parsing library panel provisioning file", "path", path, "file.Name", file.Name())
+			cfg, err := cr.parseLibraryPanelConfig(path, file)
+			if fileErrs, err = utils.AppendFileError(fileErrs, err); err != nil {
+				return nil, err
+			}
+
+			if cfg == nil {
+				continue
+			}
+
+			if err := applyPathOrg(path, cfg); err != nil {
+				fileErrs = append(fileErrs, utils.FileErrorsOf(err)...)
+				continue
+			}
+			panels = append(panels, cfg)
+		}
+	}
+
+	return panels, fileErrs.Err()
+}
+
+func (cr *configReader) parseLibraryPanelConfig(path string, file os.FileInfo) (*configs, error) {
+	filename, err := filepath.Abs(filepath.Join(path, file.Name()))
+	if err != nil {
+		return nil, err
+	}
+
+	// nolint:gosec
+	// We can ignore the gosec G304 warning on this one because `filename` comes from ps.Cfg.ProvisioningPath
+	yamlFile, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg *configsV1
+	decoder := utils.YAMLDecoder{Subsystem: "library-panels", Kind: "LibraryPanel", Strict: cr.strict, Environment: cr.env,
+		Log: cr.log}
+	if err := decoder.Decode(filename, yamlFile, &cfg); err != nil {
+		if errors.Is(err, utils.ErrFileSkipped) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	panels := cfg.mapToLibraryPanelsFromConfig()
+	panels.Filename = filename
+	return panels, nil
+}
+
+// overrideLibraryPanels removes the library panels of earlier configs that override replaces.
+func (cr *configReader) overrideLibraryPanels(earlier []*configs, override *configs) {
+	for _, panel := range override.LibraryPanels {
+		for _, cfg := range earlier {
+			kept := cfg.LibraryPanels[:0]
+			for _, existing := range cfg.LibraryPanels {
+				if orgID(existing.OrgID) != orgID(panel.OrgID) || existing.UID != panel.UID {
+					kept = append(kept, existing)
+					continue
+				}
+				cr.log.Info("Library panel overridden by a later provisioning path", "uid", panel.UID, "orgId", panel.OrgID,
+					"overridden", cfg.Filename, "winner", override.Filename)
+			}
+			cfg.LibraryPanels = kept
+		}
+	}
+}
+
+// orgID returns the org of a library panel, which defaults to 1.
+func orgID(id int64) int64 {
+	if id == 0 {
+		return 1
+	}
+	return id
+}
+
+// applyPathOrg sets the org of the library panels of a per-org directory like orgs/<orgID>/library-panels, see
+// utils.ApplyPathOrg.
+func applyPathOrg(path string, cfg *configs) error {
+	pathOrgID := utils.OrgFromPath(path)
+	for _, panel := range cfg.LibraryPanels {
+		if err := utils.ApplyPathOrg(&panel.OrgID, pathOrgID); err != nil {
+			return &utils.ProvisioningFileError{Subsystem: "library-panels", Path: cfg.Filename,
+				Err: fmt.Errorf("library panel %q: %w", panel.UID, err)}
+		}
+	}
+	for _, panel := range cfg.DeleteLibraryPanels {
+		if err := utils.ApplyPathOrg(&panel.OrgID, pathOrgID); err != nil {
+			return &utils.ProvisioningFileError{Subsystem: "library-panels", Path: cfg.Filename,
+				Err: fmt.Errorf("deleted library panel %q: %w", panel.UID, err)}
+		}
+	}
+	return nil
+}
+
+// validateRequiredField checks that every library panel has a uid, which dashboards reference it by, a name and a
+// model.
+func validateRequiredField(panels []*configs) error {
+	var fileErrs utils.FileErrors
+	for i := range panels {
+		var errStrings []string
+		for index, panel := range panels[i].LibraryPanels {
+			if panel.UID == "" {
+				errStrings = append(errStrings,
+					fmt.Sprintf("library panel item %d in configuration doesn't contain required field uid", index+1))
+			}
+			if panel.Name == "" {
+				errStrings = append(errStrings,
+					fmt.Sprintf("library panel item %d in configuration doesn't contain required field name", index+1))
+			}
+			if len(panel.Model) == 0 {
+				errStrings = append(errStrings,
+					fmt.Sprintf("library panel item %d in configuration doesn't contain required field model", index+1))
+			}
+		}
+
+		for index, panel := range panels[i].DeleteLibraryPanels {
+			if panel.UID == "" {
+				errStrings = append(errStrings,
+					fmt.Sprintf("delete library panel item %d in configuration doesn't contain required field uid", index+1))
+			}
+		}
+
+		if len(errStrings) != 0 {
+			fileErrs = append(fileErrs, &utils.ProvisioningFileError{Subsystem: "library-panels", Path: panels[i].Filename,
+				Err: errors.New(strings.Join(errStrings, "\n"))})
+		}
+	}
+
+	return fileErrs.Err()
+}
@@ -0,0 +1,236 @@
+package librarypanels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/bus"
+	dboards "github.com/grafana/grafana/pkg/dashboards"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	libpanels "github.com/grafana/grafana/pkg/services/librarypanels"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// ErrLibraryPanelInUse is returned, wrapped with the dashboards using it, when a library panel listed in
+// deleteLibraryPanels is still used by dashboards.
+var ErrLibraryPanelInUse = errors.New("library panel is used by dashboards")
+
+// PanelStore is the part of the library panel store used to provision library panels.
+type PanelStore interface {
+	GetProvisionedLibraryPanel(ctx context.Context, orgID int64, uid string) (*libpanels.ProvisionedLibraryPanel, error)
+	UpsertProvisionedLibraryPanel(ctx context.Context, panel libpanels.ProvisionedLibraryPanel) (bool, error)
+	DeleteProvisionedLibraryPanel(ctx context.Context, orgID int64, uid string) error
+	GetLibraryPanelDashboards(ctx context.Context, orgID int64, uid string) ([]string, error)
+}
+
+// Store is what library panels are provisioned through: the dashboard store, for their folders, and the library
+// panel store.
+type Store interface {
+	dboards.Store
+	PanelStore
+}
+
+// Provision scans the directories for provisioning config files and provisions the library panels in those files.
+// The library panels that were applied are recorded in inventory.
+func Provision(ctx context.Context, configDirectories []string, store Store, fileFilter setting.ProvisioningFileFilter,
+	strict bool, inventory *utils.Inventory) error {
+	logger := log.New("provisioning.librarypanels")
+	lp := LibraryPanelProvisioner{
+		log:         logger,
+		cfgProvider: &configReader{log: logger, fileFilter: fileFilter, strict: strict, env: utils.EnvironmentFromContext(ctx)},
+		store:       store,
+		inventory:   inventory,
+	}
+	return lp.applyChanges(ctx, configDirectories...)
+}
+
+// LibraryPanelProvisioner is responsible for provisioning library panels based on
+// configuration read by the `configReader`
+type LibraryPanelProvisioner struct {
+	log         log.Logger
+	cfgProvider *configReader
+	store       Store
+	inventory   *utils.Inventory
+}
+
+func (lp *LibraryPanelProvisioner) applyChanges(ctx context.Context, configPaths ...string) error {
+	configs, err := lp.cfgProvider.readConfig(configPaths...)
+	if err != nil {
+		return err
+	}
+
+	for _, cfg := range configs {
+		if err := lp.deleteLibraryPanels(ctx, cfg); err != nil {
+			return err
+		}
+	}
+
+	for _, cfg := range configs {
+		for _, panel := range cfg.LibraryPanels {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := lp.provisionLibraryPanel(ctx, cfg.Filename, panel); err != nil {
+				return fmt.Errorf("failed to provision library panel %q of %s: %w", panel.UID, cfg.Filename, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// deleteLibraryPanels deletes the library panels listed in deleteLibraryPanels. A library panel that dashboards still
+// use isn't deleted, since the dashboards would break: its deletion fails with an error naming the dashboards.
+func (lp *LibraryPanelProvisioner) deleteLibraryPanels(ctx context.Context, cfg *configs) error {
+	for _, panel := range cfg.DeleteLibraryPanels {
+		orgID := orgID(panel.OrgID)
+		existing, err := lp.store.GetProvisionedLibraryPanel(ctx, orgID, panel.UID)
+		if errors.Is(err, libpanels.ErrProvisionedLibraryPanelNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		dependents, err := lp.store.GetLibraryPanelDashboards(ctx, orgID, panel.UID)
+		if err != nil {
+			return err
+		}
+		if len(dependents) > 0 {
+			return &utils.ProvisioningFileError{Subsystem: "library-panels", Path: cfg.Filename,
+				Err: fmt.Errorf("can't delete library panel %q of org %d: %w: %s", panel.UID, orgID, ErrLibraryPanelInUse,
+					quote(dependents))}
+		}
+
+		if err := lp.store.DeleteProvisionedLibraryPanel(ctx, orgID, panel.UID); err != nil {
+			return err
+		}
+		lp.log.Info("Deleted library panel based on configuration", "uid", panel.UID, "orgId", orgID)
+		lp.inventory.RecordDeleted(utils.ProvisionedObject{Kind: "library_panel", Name: existing.Name, UID: panel.UID,
+			OrgID: orgID, File: cfg.Filename})
+	}
+	return nil
+}
+
+func (lp *LibraryPanelProvisioner) provisionLibraryPanel(ctx context.Context, filename string, panel *libraryPanelFromConfig) error {
+	orgID := orgID(panel.OrgID)
+	if err := utils.CheckOrgExists(ctx, orgID); err != nil {
+		return err
+	}
+
+	folderID, err := lp.folderID(ctx, orgID, panel)
+	if err != nil {
+		return err
+	}
+	model, err := json.Marshal(panel.Model)
+	if err != nil {
+		return err
+	}
+	desired := libpanels.ProvisionedLibraryPanel{
+		OrgID:      orgID,
+		FolderID:   folderID,
+		UID:        panel.UID,
+		Name:       panel.Name,
+		Model:      model,
+		Provenance: libpanels.ProvenanceFile,
+	}
+
+	existing, err := lp.store.GetProvisionedLibraryPanel(ctx, orgID, panel.UID)
+	if err != nil && !errors.Is(err, libpanels.ErrProvisionedLibraryPanelNotFound) {
+		return err
+	}
+	object := utils.ProvisionedObject{Kind: "library_panel", Name: panel.Name, UID: panel.UID, OrgID: orgID, File: filename}
+	if existing != nil && upToDate(existing, desired) {
+		object.Action = utils.ActionSkipped
+		lp.inventory.Record(object)
+		return nil
+	}
+	if existing != nil && existing.Provenance == "" {
+		lp.log.Info("Library panel created in the UI is now provisioned", "uid", panel.UID, "orgId", orgID)
+	}
+
+	created, err := lp.store.UpsertProvisionedLibraryPanel(ctx, desired)
+	if err != nil {
+		return err
+	}
+	object.Action = utils.ActionUpdated
+	if created {
+		object.Action = utils.ActionCreated
+	}
+	lp.log.Debug("Provisioned library panel", "uid", panel.UID, "orgId", orgID, "action", object.Action)
+	lp.inventory.Record(object)
+	return nil
+}
+
+// folderID returns the ID of the folder of a library panel, creating the folder if it doesn't exist like the
+// dashboard providers do. The folder is looked up by uid when there is one, and by title otherwise.
+func (lp *LibraryPanelProvisioner) folderID(ctx context.Context, orgID int64, panel *libraryPanelFromConfig) (int64, error) {
+	if panel.Folder == "" && panel.FolderUID == "" {
+		return 0, nil
+	}
+
+	query := &models.GetDashboardQuery{Uid: panel.FolderUID, OrgId: orgID}
+	if panel.FolderUID == "" {
+		query.Slug = models.SlugifyTitle(panel.Folder)
+	}
+	err := bus.DispatchCtx(ctx, query)
+	if err == nil {
+		if !query.Result.IsFolder {
+			return 0, utils.InvalidConfig(errors.New("folder of the library panel is a dashboard"))
+		}
+		return query.Result.Id, nil
+	}
+	if !errors.Is(err, models.ErrDashboardNotFound) {
+		return 0, err
+	}
+	if panel.Folder == "" {
+		return 0, utils.InvalidConfig(fmt.Errorf("folder with uid %q doesn't exist, set folder to create it", panel.FolderUID))
+	}
+
+	folder := &dashboards.SaveDashboardDTO{OrgId: orgID, Overwrite: true, Dashboard: models.NewDashboardFolder(panel.Folder)}
+	folder.Dashboard.SetUid(panel.FolderUID)
+	saved, err := dashboards.NewProvisioningService(lp.store).SaveFolderForProvisionedDashboards(folder)
+	if err != nil {
+		return 0, err
+	}
+	lp.log.Info("Created folder of library panel", "folder", panel.Folder, "orgId", orgID)
+	return saved.Id, nil
+}
+
+// upToDate reports whether the stored library panel already is the provisioned one. The store adds the name and
+// other fields to the model, so only the fields of the provisioned model are compared.
+func upToDate(existing *libpanels.ProvisionedLibraryPanel, desired libpanels.ProvisionedLibraryPanel) bool {
+	if existing.Provenance != desired.Provenance || existing.FolderID != desired.FolderID || existing.Name != desired.Name {
+		return false
+	}
+
+	var existingModel, desiredModel map[string]interface{}
+	if err := json.Unmarshal(existing.Model, &existingModel); err != nil {
+		return false
+	}
+	if err := json.Unmarshal(desired.Model, &desiredModel); err != nil {
+		return false
+	}
+	desiredModel["title"] = desired.Name
+	for key, value := range desiredModel {
+		if !reflect.DeepEqual(existingModel[key], value) {
+			return false
+		}
+	}
+	return true
+}
+
+func quote(names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, fmt.Sprintf("%q", name))
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,179 @@
+package librarypanels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	dboards "github.com/grafana/grafana/pkg/dashboards"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	libpanels "github.com/grafana/grafana/pkg/services/librarypanels"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	panelsDir     = "testdata/test-configs/panels"
+	deleteDir     = "testdata/test-configs/delete"
+	missingUIDDir = "testdata/test-configs/missing-uid"
+)
+
+func TestLibraryPanelProvisioner(t *testing.T) {
+	setup := func(t *testing.T) (*LibraryPanelProvisioner, *fakeLibraryPanelStore) {
+		bus.ClearBusHandlers()
+		t.Cleanup(bus.ClearBusHandlers)
+		bus.AddHandler("test", func(query *models.GetOrgByIdQuery) error {
+			query.Result = &models.Org{Id: query.Id}
+			return nil
+		})
+		bus.AddHandler("test", func(query *models.GetDashboardQuery) error {
+			if query.Slug == "shared-panels" {
+				query.Result = &models.Dashboard{Id: 10, Title: "Shared panels", IsFolder: true}
+				return nil
+			}
+			return models.ErrDashboardNotFound
+		})
+
+		store := &fakeLibraryPanelStore{panels: map[int64]map[string]*libpanels.ProvisionedLibraryPanel{}}
+		logger := log.New("test logger")
+		return &LibraryPanelProvisioner{log: logger, cfgProvider: &configReader{log: logger}, store: store,
+			inventory: utils.NewInventory()}, store
+	}
+
+	t.Run("Provisions library panels with their folder and provenance", func(t *testing.T) {
+		lp, store := setup(t)
+
+		require.NoError(t, lp.applyChanges(context.Background(), panelsDir))
+
+		cpu := store.panels[1]["cpu-usage"]
+		require.NotNil(t, cpu)
+		assert.Equal(t, "CPU usage", cpu.Name)
+		assert.Equal(t, int64(10), cpu.FolderID)
+		assert.Equal(t, libpanels.ProvenanceFile, cpu.Provenance)
+		assert.JSONEq(t, `{"title": "CPU usage", "type": "timeseries", "datasource": {"uid": "prometheus"},
+			"targets": [{"refId": "A", "expr": "rate(node_cpu_seconds_total[5m])"}]}`, string(cpu.Model))
+
+		notice := store.panels[2]["notice"]
+		require.NotNil(t, notice)
+		assert.Equal(t, int64(0), notice.FolderID)
+
+		objects := lp.inventory.Objects()
+		require.Len(t, objects, 2)
+		assert.Equal(t, "library_panel", objects[0].Kind)
+		assert.Equal(t, utils.ActionCreated, objects[0].Action)
+	})
+
+	t.Run("Library panels that are up to date aren't written again", func(t *testing.T) {
+		lp, store := setup(t)
+		require.NoError(t, lp.applyChanges(context.Background(), panelsDir))
+		store.upserts = 0
+
+		lp.inventory = utils.NewInventory()
+		require.NoError(t, lp.applyChanges(context.Background(), panelsDir))
+		assert.Zero(t, store.upserts)
+		for _, object := range lp.inventory.Objects() {
+			assert.Equal(t, utils.ActionSkipped, object.Action)
+		}
+	})
+
+	t.Run("Library panels created in the UI are taken over", func(t *testing.T) {
+		lp, store := setup(t)
+		store.panels[1] = map[string]*libpanels.ProvisionedLibraryPanel{
+			"cpu-usage": {OrgID: 1, FolderID: 10, UID: "cpu-usage", Name: "CPU usage", Model: []byte(`{"type": "graph"}`)},
+		}
+
+		require.NoError(t, lp.applyChanges(context.Background(), panelsDir))
+		assert.Equal(t, libpanels.ProvenanceFile, store.panels[1]["cpu-usage"].Provenance)
+		assert.Equal(t, utils.ActionUpdated, lp.inventory.Objects()[0].Action)
+	})
+
+	t.Run("Deletes library panels that no dashboard uses", func(t *testing.T) {
+		lp, store := setup(t)
+		store.panels[1] = map[string]*libpanels.ProvisionedLibraryPanel{
+			"unused":    {OrgID: 1, UID: "unused", Name: "Unused"},
+			"cpu-usage": {OrgID: 1, UID: "cpu-usage", Name: "CPU usage"},
+		}
+
+		require.NoError(t, lp.applyChanges(context.Background(), deleteDir))
+		assert.Empty(t, store.panels[1])
+		assert.Len(t, lp.inventory.Deleted(), 2)
+	})
+
+	t.Run("Refuses to delete a library panel that dashboards use", func(t *testing.T) {
+		lp, store := setup(t)
+		store.panels[1] = map[string]*libpanels.ProvisionedLibraryPanel{
+			"unused":    {OrgID: 1, UID: "unused", Name: "Unused"},
+			"cpu-usage": {OrgID: 1, UID: "cpu-usage", Name: "CPU usage"},
+		}
+		store.dashboards = map[string][]string{"cpu-usage": {"Hosts", "Overview"}}
+
+		err := lp.applyChanges(context.Background(), deleteDir)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrLibraryPanelInUse))
+		var fileErr *utils.ProvisioningFileError
+		require.True(t, errors.As(err, &fileErr))
+		assert.Contains(t, err.Error(), `"Hosts", "Overview"`)
+		assert.Contains(t, store.panels[1], "cpu-usage")
+	})
+
+	t.Run("Library panels without a uid are rejected", func(t *testing.T) {
+		lp, store := setup(t)
+
+		err := lp.applyChanges(context.Background(), missingUIDDir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "doesn't contain required field uid")
+		assert.Zero(t, store.upserts)
+	})
+}
+
+// fakeLibraryPanelStore keeps the library panels by org and uid. Its dashboard store methods aren't implemented.
+type fakeLibraryPanelStore struct {
+	dboards.Store
+	panels map[int64]map[string]*libpanels.ProvisionedLibraryPanel
+	// dashboards are the titles of the dashboards using each library panel, by uid.
+	dashboards map[string][]string
+	upserts    int
+}
+
+func (s *fakeLibraryPanelStore) GetProvisionedLibraryPanel(_ context.Context, orgID int64, uid string) (*libpanels.ProvisionedLibraryPanel, error) {
+	panel, ok := s.panels[orgID][uid]
+	if !ok {
+		return nil, libpanels.ErrProvisionedLibraryPanelNotFound
+	}
+	return panel, nil
+}
+
+func (s *fakeLibraryPanelStore) UpsertProvisionedLibraryPanel(_ context.Context, panel libpanels.ProvisionedLibraryPanel) (bool, error) {
+	s.upserts++
+	if s.panels[panel.OrgID] == nil {
+		s.panels[panel.OrgID] = map[string]*libpanels.ProvisionedLibraryPanel{}
+	}
+	// Like the library panel store, the name is set as the title of the model.
+	var model map[string]interface{}
+	if err := json.Unmarshal(panel.Model, &model); err != nil {
+		return false, err
+	}
+	model["title"] = panel.Name
+	synced, err := json.Marshal(model)
+	if err != nil {
+		return false, err
+	}
+	panel.Model = synced
+
+	_, exists := s.panels[panel.OrgID][panel.UID]
+	s.panels[panel.OrgID][panel.UID] = &panel
+	return !exists, nil
+}
+
+func (s *fakeLibraryPanelStore) DeleteProvisionedLibraryPanel(_ context.Context, orgID int64, uid string) error {
+	delete(s.panels[orgID], uid)
+	return nil
+}
+
+func (s *fakeLibraryPanelStore) GetLibraryPanelDashboards(_ context.Context, _ int64, uid string) ([]string, error) {
+	return s.dashboards[uid], nil
+}
@@ -0,0 +1,79 @@
+package librarypanels
+
+import "github.com/grafana/grafana/pkg/services/provisioning/values"
+
+// configs is a normalized data object for library panels config data. Any config version should be mappable
+// to this type.
+type configs struct {
+	// Filename is the absolute path of the file the config was read from.
+	Filename            string
+	LibraryPanels       []*libraryPanelFromConfig
+	DeleteLibraryPanels []*deleteLibraryPanelConfig
+}
+
+type libraryPanelFromConfig struct {
+	OrgID int64
+	UID   string
+	Name  string
+	// Folder is the title of the folder of the panel, and FolderUID the uid of the folder. A missing folder is
+	// created with both, and a panel without either goes in the General folder.
+	Folder    string
+	FolderUID string
+	Model     map[string]interface{}
+}
+
+type deleteLibraryPanelConfig struct {
+	OrgID int64
+	UID   string
+}
+
+// configsV1 is a mapping for version 1 configs. This is mapped to its normalised version.
+type configsV1 struct {
+	APIVersion int64 `json:"apiVersion" yaml:"apiVersion"`
+
+	LibraryPanels       []*libraryPanelFromConfigV1   `json:"libraryPanels" yaml:"libraryPanels"`
+	DeleteLibraryPanels []*deleteLibraryPanelConfigV1 `json:"deleteLibraryPanels" yaml:"deleteLibraryPanels"`
+}
+
+type libraryPanelFromConfigV1 struct {
+	OrgID     values.Int64Value  `json:"orgId" yaml:"orgId"`
+	UID       values.StringValue `json:"uid" yaml:"uid"`
+	Name      values.StringValue `json:"name" yaml:"name"`
+	Folder    values.StringValue `json:"folder" yaml:"folder"`
+	FolderUID values.StringValue `json:"folderUid" yaml:"folderUid"`
+	Model     values.JSONValue   `json:"model" yaml:"model"`
+}
+
+type deleteLibraryPanelConfigV1 struct {
+	OrgID values.Int64Value  `json:"orgId" yaml:"orgId"`
+	UID   values.StringValue `json:"uid" yaml:"uid"`
+}
+
+// mapToLibraryPanelsFromConfig maps config syntax to a normalized configs object. Every version of the config
+// syntax should have this function.
+func (cfg *configsV1) mapToLibraryPanelsFromConfig() *configs {
+	r := &configs{}
+	if cfg == nil {
+		return r
+	}
+
+	for _, panel := range cfg.LibraryPanels {
+		r.LibraryPanels = append(r.LibraryPanels, &libraryPanelFromConfig{
+			OrgID:     panel.OrgID.Value(),
+			UID:       panel.UID.Value(),
+			Name:      panel.Name.Value(),
+			Folder:    panel.Folder.Value(),
+			FolderUID: panel.FolderUID.Value(),
+			Model:     panel.Model.Value(),
+		})
+	}
+
+	for _, panel := range cfg.DeleteLibraryPanels {
+		r.DeleteLibraryPanels = append(r.DeleteLibraryPanels, &deleteLibraryPanelConfig{
+			OrgID: panel.OrgID.Value(),
+			UID:   panel.UID.Value(),
+		})
+	}
+
+	return r
+}
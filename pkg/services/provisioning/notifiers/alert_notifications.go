@@ -1,49 +1,92 @@
 package notifiers
 
 import (
+	"context"
+	"io"
+	"io/ioutil"
+
 	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
-// Provision alert notifiers
-func Provision(configDirectory string) error {
+// Store is what alert notifiers are provisioned through. Notifiers are still looked up with bus queries.
+type Store interface {
+	CreateAlertNotification(ctx context.Context, cmd *models.CreateAlertNotificationCommand) error
+	UpdateAlertNotificationWithUid(ctx context.Context, cmd *models.UpdateAlertNotificationWithUidCommand) error
+	DeleteAlertNotificationWithUid(ctx context.Context, cmd *models.DeleteAlertNotificationWithUidCommand) error
+}
+
+// Provision alert notifiers from the directories in order through store. The notifiers that were applied are
+// recorded in inventory.
+func Provision(ctx context.Context, configDirectories []string, store Store, fileFilter setting.ProvisioningFileFilter, strict bool, inventory *utils.Inventory) error {
 	dc := newNotificationProvisioner(log.New("provisioning.notifiers"))
-	return dc.applyChanges(configDirectory)
+	dc.store = store
+	dc.cfgProvider.fileFilter = fileFilter
+	dc.cfgProvider.strict = strict
+	dc.cfgProvider.env = utils.EnvironmentFromContext(ctx)
+	dc.inventory = inventory
+	return dc.applyChanges(ctx, configDirectories...)
+}
+
+// ProvisionFromReader provisions the alert notifiers of a single config document read from r, with the validation
+// and apply steps of Provision. Notifiers without an orgId or orgName are provisioned in orgID, unless it's 0.
+func ProvisionFromReader(ctx context.Context, orgID int64, r io.Reader, store Store, strict bool,
+	inventory *utils.Inventory) error {
+	dc := newNotificationProvisioner(log.New("provisioning.notifiers"))
+	dc.store = store
+	dc.cfgProvider.strict = strict
+	dc.cfgProvider.env = utils.EnvironmentFromContext(ctx)
+	dc.inventory = inventory
+
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	cfg, err := dc.cfgProvider.readDocument(ctx, orgID, content)
+	if err != nil || cfg == nil {
+		return err
+	}
+	return dc.apply(ctx, cfg)
 }
 
 // NotificationProvisioner is responsible for provsioning alert notifiers
 type NotificationProvisioner struct {
 	log         log.Logger
 	cfgProvider *configReader
+	store       Store
+	inventory   *utils.Inventory
 }
 
 func newNotificationProvisioner(log log.Logger) NotificationProvisioner {
 	return NotificationProvisioner{
 		log:         log,
 		cfgProvider: &configReader{log: log},
+		store:       utils.BusStore{},
 	}
 }
 
-func (dc *NotificationProvisioner) apply(cfg *notificationsAsConfig) error {
-	if err := dc.deleteNotifications(cfg.DeleteNotifications); err != nil {
+func (dc *NotificationProvisioner) apply(ctx context.Context, cfg *notificationsAsConfig) error {
+	if err := dc.deleteNotifications(ctx, cfg.DeleteNotifications, cfg.Filename); err != nil {
 		return err
 	}
 
-	if err := dc.mergeNotifications(cfg.Notifications); err != nil {
+	if err := dc.mergeNotifications(ctx, cfg.Notifications, cfg.Filename); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (dc *NotificationProvisioner) deleteNotifications(notificationToDelete []*deleteNotificationConfig) error {
+func (dc *NotificationProvisioner) deleteNotifications(ctx context.Context, notificationToDelete []*deleteNotificationConfig, filename string) error {
 	for _, notification := range notificationToDelete {
 		dc.log.Info("Deleting alert notification", "name", notification.Name, "uid", notification.UID)
 
 		if notification.OrgID == 0 && notification.OrgName != "" {
 			getOrg := &models.GetOrgByNameQuery{Name: notification.OrgName}
-			if err := bus.Dispatch(getOrg); err != nil {
+			if err := bus.DispatchCtx(ctx, getOrg); err != nil {
 				return err
 			}
 			notification.OrgID = getOrg.Result.Id
@@ -53,26 +96,31 @@ func (dc *NotificationProvisioner) deleteNotifications(notificationToDelete []*d
 
 		getNotification := &models.GetAlertNotificationsWithUidQuery{Uid: notification.UID, OrgId: notification.OrgID}
 
-		if err := bus.Dispatch(getNotification); err != nil {
+		if err := bus.DispatchCtx(ctx, getNotification); err != nil {
 			return err
 		}
 
 		if getNotification.Result != nil {
 			cmd := &models.DeleteAlertNotificationWithUidCommand{Uid: getNotification.Result.Uid, OrgId: getNotification.OrgId}
-			if err := bus.Dispatch(cmd); err != nil {
+			if err := dc.store.DeleteAlertNotificationWithUid(ctx, cmd); err != nil {
 				return err
 			}
+			dc.inventory.RecordDeleted(utils.ProvisionedObject{Kind: "notifier", Name: getNotification.Result.Name,
+				UID: getNotification.Result.Uid, OrgID: notification.OrgID, File: filename})
 		}
 	}
 
 	return nil
 }
 
-func (dc *NotificationProvisioner) mergeNotifications(notificationToMerge []*notificationFromConfig) error {
+func (dc *NotificationProvisioner) mergeNotifications(ctx context.Context, notificationToMerge []*notificationFromConfig, filename string) error {
 	for _, notification := range notificationToMerge {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if notification.OrgID == 0 && notification.OrgName != "" {
 			getOrg := &models.GetOrgByNameQuery{Name: notification.OrgName}
-			if err := bus.Dispatch(getOrg); err != nil {
+			if err := bus.DispatchCtx(ctx, getOrg); err != nil {
 				return err
 			}
 			notification.OrgID = getOrg.Result.Id
@@ -81,12 +129,14 @@ func (dc *NotificationProvisioner) mergeNotifications(notificationToMerge []*not
 		}
 
 		cmd := &models.GetAlertNotificationsWithUidQuery{OrgId: notification.OrgID, Uid: notification.UID}
-		err := bus.Dispatch(cmd)
+		err := bus.DispatchCtx(ctx, cmd)
 		if err != nil {
 			return err
 		}
 
+		action := utils.ActionUpdated
 		if cmd.Result == nil {
+			action = utils.ActionCreated
 			dc.log.Debug("inserting alert notification from configuration", "name", notification.Name, "uid", notification.UID)
 			insertCmd := &models.CreateAlertNotificationCommand{
 				Uid:                   notification.UID,
@@ -101,7 +151,7 @@ func (dc *NotificationProvisioner) mergeNotifications(notificationToMerge []*not
 				SendReminder:          notification.SendReminder,
 			}
 
-			if err := bus.Dispatch(insertCmd); err != nil {
+			if err := dc.store.CreateAlertNotification(ctx, insertCmd); err != nil {
 				return err
 			}
 		} else {
@@ -119,23 +169,29 @@ func (dc *NotificationProvisioner) mergeNotifications(notificationToMerge []*not
 				SendReminder:          notification.SendReminder,
 			}
 
-			if err := bus.Dispatch(updateCmd); err != nil {
+			if err := dc.store.UpdateAlertNotificationWithUid(ctx, updateCmd); err != nil {
 				return err
 			}
 		}
+		dc.inventory.Record(utils.ProvisionedObject{Kind: "notifier", Name: notification.Name, UID: notification.UID,
+			OrgID: notification.OrgID, File: filename, Action: action})
 	}
 
 	return nil
 }
 
-func (dc *NotificationProvisioner) applyChanges(configPath string) error {
-	configs, err := dc.cfgProvider.readConfig(configPath)
+func (dc *NotificationProvisioner) applyChanges(ctx context.Context, configPaths ...string) error {
+	configs, err := dc.cfgProvider.readConfig(ctx, configPaths...)
+	if err != nil {
+		return err
+	}
+	configs, err = dc.expandShared(ctx, configs)
 	if err != nil {
 		return err
 	}
 
 	for _, cfg := range configs {
-		if err := dc.apply(cfg); err != nil {
+		if err := dc.apply(ctx, cfg); err != nil {
 			return err
 		}
 	}
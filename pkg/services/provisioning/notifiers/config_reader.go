@@ -1,6 +1,8 @@
 package notifiers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -12,18 +14,70 @@ import (
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/alerting"
 	"github.com/grafana/grafana/pkg/services/provisioning/utils"
-	"gopkg.in/yaml.v2"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 type configReader struct {
-	log log.Logger
+	log        log.Logger
+	fileFilter setting.ProvisioningFileFilter
+	// offline skips the validation that needs a database, like checking that orgs exist.
+	offline bool
+	// strict rejects unknown fields instead of ignoring them.
+	strict bool
+	// env is what the guards of the files are checked against.
+	env utils.Environment
 }
 
-func (cr *configReader) readConfig(path string) ([]*notificationsAsConfig, error) {
+// readConfig reads the config files of each directory in order, the shared ones of its _shared directory first.
+// Notifiers of later directories override the ones of earlier directories with the same uid in the same org, or
+// that are shared alike. Every file is read before the files that couldn't be parsed are returned as
+// utils.FileErrors.
+func (cr *configReader) readConfig(ctx context.Context, paths ...string) ([]*notificationsAsConfig, error) {
 	var notifications []*notificationsAsConfig
+	var fileErrs utils.FileErrors
+
+	for _, path := range paths {
+		shared, err := cr.readSharedDirectory(filepath.Join(path, sharedDirectory))
+		if fileErrs, err = utils.AppendFileError(fileErrs, err); err != nil {
+			return nil, err
+		}
+		configs, err := cr.readDirectory(path)
+		if fileErrs, err = utils.AppendFileError(fileErrs, err); err != nil {
+			return nil, err
+		}
+		configs = append(shared, configs...)
+
+		for _, cfg := range configs {
+			cr.overrideNotifications(notifications, cfg)
+		}
+		notifications = append(notifications, configs...)
+	}
+	if err := fileErrs.Err(); err != nil {
+		return nil, err
+	}
+
+	cr.log.Debug("Validating alert notifications")
+	if err := validateRequiredField(notifications); err != nil {
+		return nil, err
+	}
+
+	if err := cr.checkOrgIDAndOrgName(ctx, notifications); err != nil {
+		return nil, err
+	}
+
+	if err := validateNotifications(notifications); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func (cr *configReader) readDirectory(path string) ([]*notificationsAsConfig, error) {
+	var notifications []*notificationsAsConfig
+	var fileErrs utils.FileErrors
 	cr.log.Debug("Looking for alert notification provisioning files", "path", path)
 
-	files, err := ioutil.ReadDir(path)
+	files, err := utils.ReadDir(path)
 	if err != nil {
 		cr.log.Error("Can't read alert notification provisioning files from directory", "path", path, "error", err)
 		return notifications, nil
@@ -31,9 +85,14 @@ func (cr *configReader) readConfig(path string) ([]*notificationsAsConfig, error
 
 	for _, file := range files {
 		if strings.HasSuffix(file.Name(), ".yaml") || strings.HasSuffix(file.Name(), ".yml") {
+			if !cr.fileFilter.Includes(file.Name()) {
+				cr.log.Debug("Skipping excluded alert notifications provisioning file", "path", path, "file.Name", file.Name())
+				continue
+			}
+
 			cr.log.Debug("Parsing alert notifications provisioning file", "path", path, "file.Name", file.Name())
 			notifs, err := cr.parseNotificationConfig(path, file)
-			if err != nil {
+			if fileErrs, err = utils.AppendFileError(fileErrs, err); err != nil {
 				return nil, err
 			}
 
@@ -43,20 +102,82 @@ func (cr *configReader) readConfig(path string) ([]*notificationsAsConfig, error
 		}
 	}
 
-	cr.log.Debug("Validating alert notifications")
-	if err = validateRequiredField(notifications); err != nil {
+	return notifications, fileErrs.Err()
+}
+
+// readSharedDirectory reads the config files of a shared directory, which doesn't have to exist.
+func (cr *configReader) readSharedDirectory(path string) ([]*notificationsAsConfig, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	configs, err := cr.readDirectory(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, cfg := range configs {
+		if err := markShared(cfg); err != nil {
+			return nil, err
+		}
+	}
+	return configs, nil
+}
+
+// overrideNotifications removes the notifiers of earlier configs that override replaces.
+func (cr *configReader) overrideNotifications(earlier []*notificationsAsConfig, override *notificationsAsConfig) {
+	for _, notification := range override.Notifications {
+		for _, cfg := range earlier {
+			kept := cfg.Notifications[:0]
+			for _, existing := range cfg.Notifications {
+				if !sameNotification(existing, notification) {
+					kept = append(kept, existing)
+					continue
+				}
+				cr.log.Info("Alert notification overridden by a later provisioning path", "uid", notification.UID,
+					"name", notification.Name, "overridden", cfg.Filename, "winner", override.Filename)
+			}
+			cfg.Notifications = kept
+		}
+	}
+}
+
+// sameNotification compares notifiers before their org defaults to 1.
+func sameNotification(a, b *notificationFromConfig) bool {
+	if a.Shared || b.Shared {
+		return a.Shared == b.Shared && a.UID == b.UID
+	}
+	orgID := func(n *notificationFromConfig) int64 {
+		if n.OrgID < 1 && n.OrgName == "" {
+			return 1
+		}
+		return n.OrgID
+	}
+	return a.UID == b.UID && orgID(a) == orgID(b) && a.OrgName == b.OrgName
+}
+
+// readDocument reads a single config document that isn't read from a provisioning directory. The notifiers without
+// an orgId or orgName are provisioned in orgID, unless it's 0. It returns nil when the guards of the document skip it.
+func (cr *configReader) readDocument(ctx context.Context, orgID int64, yamlFile []byte) (*notificationsAsConfig, error) {
+	cfg, err := cr.parseNotifications(utils.ReaderFilename, yamlFile)
+	if err != nil || cfg == nil {
 		return nil, err
 	}
 
-	if err := checkOrgIDAndOrgName(notifications); err != nil {
+	if err := applyOrg(orgID, cfg); err != nil {
 		return nil, err
 	}
 
+	notifications := []*notificationsAsConfig{cfg}
+	cr.log.Debug("Validating alert notifications")
+	if err := validateRequiredField(notifications); err != nil {
+		return nil, err
+	}
+	if err := cr.checkOrgIDAndOrgName(ctx, notifications); err != nil {
+		return nil, err
+	}
 	if err := validateNotifications(notifications); err != nil {
 		return nil, err
 	}
-
-	return notifications, nil
+	return cfg, nil
 }
 
 func (cr *configReader) parseNotificationConfig(path string, file os.FileInfo) (*notificationsAsConfig, error) {
@@ -69,44 +190,95 @@ func (cr *configReader) parseNotificationConfig(path string, file os.FileInfo) (
 		return nil, err
 	}
 
+	return cr.parseNotifications(filename, yamlFile)
+}
+
+// parseNotifications parses the content of a config file, it returns nil when the guards of the file skip it.
+func (cr *configReader) parseNotifications(filename string, yamlFile []byte) (*notificationsAsConfig, error) {
 	var cfg *notificationsAsConfigV0
-	err = yaml.Unmarshal(yamlFile, &cfg)
-	if err != nil {
+	decoder := utils.YAMLDecoder{Subsystem: "notifiers", Kind: "Notifier", Strict: cr.strict, Environment: cr.env,
+		Log: cr.log}
+	if err := decoder.Decode(filename, yamlFile, &cfg); err != nil {
+		if errors.Is(err, utils.ErrFileSkipped) {
+			return nil, nil
+		}
 		return nil, err
 	}
 
-	return cfg.mapToNotificationFromConfig(), nil
+	notifications := cfg.mapToNotificationFromConfig()
+	notifications.Filename = filename
+	return notifications, nil
 }
 
-func checkOrgIDAndOrgName(notifications []*notificationsAsConfig) error {
+// applyOrg sets the org of the notifiers without an orgId or orgName to orgID, see utils.ApplyPathOrg.
+func applyOrg(orgID int64, cfg *notificationsAsConfig) error {
+	for _, notification := range cfg.Notifications {
+		if notification.OrgName != "" {
+			continue
+		}
+		if err := utils.ApplyPathOrg(&notification.OrgID, orgID); err != nil {
+			return &utils.ProvisioningFileError{Subsystem: "notifiers", Path: cfg.Filename,
+				Err: fmt.Errorf("alert notification %q: %w", notification.Name, err)}
+		}
+	}
+	for _, notification := range cfg.DeleteNotifications {
+		if notification.OrgName != "" {
+			continue
+		}
+		if err := utils.ApplyPathOrg(&notification.OrgID, orgID); err != nil {
+			return &utils.ProvisioningFileError{Subsystem: "notifiers", Path: cfg.Filename,
+				Err: fmt.Errorf("deleted alert notification %q: %w", notification.Name, err)}
+		}
+	}
+	return nil
+}
+
+// checkOrgIDAndOrgName resolves the org of the notifiers from their org_name, or sets it to the default org when they
+// have neither. Offline, the names aren't resolved and notifiers without either are in org 1.
+func (cr *configReader) checkOrgIDAndOrgName(ctx context.Context, notifications []*notificationsAsConfig) error {
 	for i := range notifications {
 		for _, notification := range notifications[i].Notifications {
-			if notification.OrgID < 1 {
-				if notification.OrgName == "" {
-					notification.OrgID = 1
-				} else {
-					notification.OrgID = 0
-				}
-			} else {
-				if err := utils.CheckOrgExists(notification.OrgID); err != nil {
+			if notification.Shared {
+				continue
+			}
+			checkExists := notification.OrgID > 0 && notification.OrgName == ""
+			if err := cr.resolveOrg(ctx, &notification.OrgID, notification.OrgName); err != nil {
+				return fmt.Errorf("failed to provision %q notification: %w", notification.Name, err)
+			}
+			if checkExists && !cr.offline {
+				if err := utils.CheckOrgExists(ctx, notification.OrgID); err != nil {
 					return fmt.Errorf("failed to provision %q notification: %w", notification.Name, err)
 				}
 			}
 		}
 
 		for _, notification := range notifications[i].DeleteNotifications {
-			if notification.OrgID < 1 {
-				if notification.OrgName == "" {
-					notification.OrgID = 1
-				} else {
-					notification.OrgID = 0
-				}
+			if notification.Shared {
+				continue
+			}
+			if err := cr.resolveOrg(ctx, &notification.OrgID, notification.OrgName); err != nil {
+				return fmt.Errorf("failed to delete %q notification: %w", notification.Name, err)
 			}
 		}
 	}
 	return nil
 }
 
+// resolveOrg resolves the org of a notifier with utils.ResolveOrg. Offline, notifiers with an org_name are left for
+// the provisioner to resolve and the ones with neither are in org 1.
+func (cr *configReader) resolveOrg(ctx context.Context, orgID *int64, orgName string) error {
+	if *orgID < 0 {
+		*orgID = 0
+	}
+	if cr.offline {
+		if *orgID == 0 && orgName == "" {
+			*orgID = 1
+		}
+		return nil
+	}
+	return utils.ResolveOrg(ctx, orgID, orgName)
+}
+
 func validateRequiredField(notifications []*notificationsAsConfig) error {
 	for i := range notifications {
 		var errStrings []string
@@ -143,7 +315,8 @@ func validateRequiredField(notifications []*notificationsAsConfig) error {
 		}
 
 		if len(errStrings) != 0 {
-			return fmt.Errorf(strings.Join(errStrings, "\n"))
+			return &utils.ProvisioningFileError{Subsystem: "notifiers", Path: notifications[i].Filename,
+				Err: errors.New(strings.Join(errStrings, "\n"))}
 		}
 	}
 
@@ -165,7 +338,7 @@ func validateNotifications(notifications []*notificationsAsConfig) error {
 			})
 
 			if err != nil {
-				return err
+				return utils.InvalidConfig(err)
 			}
 		}
 	}
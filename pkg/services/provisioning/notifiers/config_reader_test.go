@@ -1,14 +1,18 @@
 package notifiers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/alerting"
 	"github.com/grafana/grafana/pkg/services/alerting/notifiers"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -24,6 +28,9 @@ var (
 	emptyFile                    = "./testdata/test-configs/empty"
 	twoNotificationsConfig       = "./testdata/test-configs/two-notifications"
 	unknownNotifier              = "./testdata/test-configs/unknown-notifier"
+	overrideNotificationConfig   = "./testdata/test-configs/override-notification"
+	orgNameConflictConfig        = "./testdata/test-configs/org-name-conflict"
+	withoutOrgConfig             = "./testdata/test-configs/without-org"
 )
 
 func TestNotificationAsConfig(t *testing.T) {
@@ -53,7 +60,7 @@ func TestNotificationAsConfig(t *testing.T) {
 		Convey("Can read correct properties", func() {
 			_ = os.Setenv("TEST_VAR", "default")
 			cfgProvider := &configReader{log: log.New("test logger")}
-			cfg, err := cfgProvider.readConfig(correctProperties)
+			cfg, err := cfgProvider.readConfig(context.Background(), correctProperties)
 			_ = os.Unsetenv("TEST_VAR")
 			if err != nil {
 				t.Fatalf("readConfig return an error %v", err)
@@ -126,7 +133,7 @@ func TestNotificationAsConfig(t *testing.T) {
 		Convey("One configured notification", func() {
 			Convey("no notification in database", func() {
 				dc := newNotificationProvisioner(logger)
-				err := dc.applyChanges(twoNotificationsConfig)
+				err := dc.applyChanges(context.Background(), twoNotificationsConfig)
 				if err != nil {
 					t.Fatalf("applyChanges return an error %v", err)
 				}
@@ -137,6 +144,23 @@ func TestNotificationAsConfig(t *testing.T) {
 				So(len(notificationsQuery.Result), ShouldEqual, 2)
 			})
 
+			Convey("later provisioning path overrides notification with the same uid", func() {
+				dc := newNotificationProvisioner(logger)
+				err := dc.applyChanges(context.Background(), twoNotificationsConfig, overrideNotificationConfig)
+				So(err, ShouldBeNil)
+
+				notificationsQuery := models.GetAllAlertNotificationsQuery{OrgId: 1}
+				err = sqlstore.GetAllAlertNotifications(&notificationsQuery)
+				So(err, ShouldBeNil)
+				So(len(notificationsQuery.Result), ShouldEqual, 2)
+				for _, notification := range notificationsQuery.Result {
+					if notification.Uid == "notifier1" {
+						So(notification.Name, ShouldEqual, "team channel")
+						So(notification.Settings.Get("addresses").MustString(), ShouldEqual, "team@example.com")
+					}
+				}
+			})
+
 			Convey("One notification in database with same name and uid", func() {
 				existingNotificationCmd := models.CreateAlertNotificationCommand{
 					Name:  "channel1",
@@ -155,7 +179,7 @@ func TestNotificationAsConfig(t *testing.T) {
 
 				Convey("should update one notification", func() {
 					dc := newNotificationProvisioner(logger)
-					err = dc.applyChanges(twoNotificationsConfig)
+					err = dc.applyChanges(context.Background(), twoNotificationsConfig)
 					if err != nil {
 						t.Fatalf("applyChanges return an error %v", err)
 					}
@@ -178,7 +202,7 @@ func TestNotificationAsConfig(t *testing.T) {
 			})
 			Convey("Two notifications with is_default", func() {
 				dc := newNotificationProvisioner(logger)
-				err := dc.applyChanges(doubleNotificationsConfig)
+				err := dc.applyChanges(context.Background(), doubleNotificationsConfig)
 				Convey("should both be inserted", func() {
 					So(err, ShouldBeNil)
 					notificationsQuery := models.GetAllAlertNotificationsQuery{OrgId: 1}
@@ -220,7 +244,7 @@ func TestNotificationAsConfig(t *testing.T) {
 
 				Convey("should have two new notifications", func() {
 					dc := newNotificationProvisioner(logger)
-					err := dc.applyChanges(twoNotificationsConfig)
+					err := dc.applyChanges(context.Background(), twoNotificationsConfig)
 					if err != nil {
 						t.Fatalf("applyChanges return an error %v", err)
 					}
@@ -253,7 +277,7 @@ func TestNotificationAsConfig(t *testing.T) {
 			So(err, ShouldBeNil)
 
 			dc := newNotificationProvisioner(logger)
-			err = dc.applyChanges(correctPropertiesWithOrgName)
+			err = dc.applyChanges(context.Background(), correctPropertiesWithOrgName)
 			if err != nil {
 				t.Fatalf("applyChanges return an error %v", err)
 			}
@@ -269,9 +293,25 @@ func TestNotificationAsConfig(t *testing.T) {
 			So(nt.OrgId, ShouldEqual, existingOrg2.Result.Id)
 		})
 
+		Convey("An org_name of another org than the org_id is an error", func() {
+			reader := &configReader{log: log.New("test logger")}
+			_, err := reader.readConfig(context.Background(), orgNameConflictConfig)
+			So(errors.Is(err, utils.ErrOrgNameConflict), ShouldBeTrue)
+		})
+
+		Convey("Notifiers without an org are provisioned in the default org", func() {
+			reader := &configReader{log: log.New("test logger")}
+			cfg, err := reader.readConfig(utils.WithDefaultOrg(context.Background(), "Main Org. 3"), withoutOrgConfig)
+			So(err, ShouldBeNil)
+			So(cfg[0].Notifications[0].OrgID, ShouldEqual, 3)
+
+			_, err = reader.readConfig(utils.WithDefaultOrg(context.Background(), "Production"), withoutOrgConfig)
+			So(errors.Is(err, models.ErrOrgNotFound), ShouldBeTrue)
+		})
+
 		Convey("Config doesn't contain required field", func() {
 			dc := newNotificationProvisioner(logger)
-			err := dc.applyChanges(noRequiredFields)
+			err := dc.applyChanges(context.Background(), noRequiredFields)
 			So(err, ShouldNotBeNil)
 
 			errString := err.Error()
@@ -284,7 +324,7 @@ func TestNotificationAsConfig(t *testing.T) {
 		Convey("Empty yaml file", func() {
 			Convey("should have not changed repo", func() {
 				dc := newNotificationProvisioner(logger)
-				err := dc.applyChanges(emptyFile)
+				err := dc.applyChanges(context.Background(), emptyFile)
 				if err != nil {
 					t.Fatalf("applyChanges return an error %v", err)
 				}
@@ -297,13 +337,19 @@ func TestNotificationAsConfig(t *testing.T) {
 
 		Convey("Broken yaml should return error", func() {
 			reader := &configReader{log: log.New("test logger")}
-			_, err := reader.readConfig(brokenYaml)
+			_, err := reader.readConfig(context.Background(), brokenYaml)
 			So(err, ShouldNotBeNil)
+
+			var fileErr *utils.ProvisioningFileError
+			So(errors.As(err, &fileErr), ShouldBeTrue)
+			So(fileErr.Subsystem, ShouldEqual, "notifiers")
+			So(fileErr.Path, ShouldEndWith, "broken.yaml")
+			So(fileErr.Line, ShouldBeGreaterThan, 0)
 		})
 
 		Convey("Skip invalid directory", func() {
 			cfgProvider := &configReader{log: log.New("test logger")}
-			cfg, err := cfgProvider.readConfig(emptyFolder)
+			cfg, err := cfgProvider.readConfig(context.Background(), emptyFolder)
 			if err != nil {
 				t.Fatalf("readConfig return an error %v", err)
 			}
@@ -312,14 +358,45 @@ func TestNotificationAsConfig(t *testing.T) {
 
 		Convey("Unknown notifier should return error", func() {
 			cfgProvider := &configReader{log: log.New("test logger")}
-			_, err := cfgProvider.readConfig(unknownNotifier)
+			_, err := cfgProvider.readConfig(context.Background(), unknownNotifier)
 			So(err, ShouldNotBeNil)
 			So(err.Error(), ShouldEqual, `unsupported notification type "nonexisting"`)
 		})
 
+		Convey("Notifications can be provisioned from a reader", func() {
+			doc := `notifiers:
+  - name: team email
+    type: email
+    uid: team-email
+    settings:
+      addresses: team@example.com
+`
+			inventory := utils.NewInventory()
+			err := ProvisionFromReader(context.Background(), 2, strings.NewReader(doc), utils.BusStore{}, false, inventory)
+			So(err, ShouldBeNil)
+
+			notificationsQuery := models.GetAllAlertNotificationsQuery{OrgId: 2}
+			err = sqlstore.GetAllAlertNotifications(&notificationsQuery)
+			So(err, ShouldBeNil)
+			So(len(notificationsQuery.Result), ShouldEqual, 1)
+			So(notificationsQuery.Result[0].Uid, ShouldEqual, "team-email")
+			So(inventory.Objects()[0].File, ShouldEqual, utils.ReaderFilename)
+
+			Convey("and are validated like files", func() {
+				err := ProvisionFromReader(context.Background(), 2,
+					strings.NewReader("notifiers:\n  - name: unknown\n    type: nonexisting\n    uid: unknown\n"), utils.BusStore{}, false, inventory)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, `unsupported notification type "nonexisting"`)
+
+				err = ProvisionFromReader(context.Background(), 2,
+					strings.NewReader("notifiers:\n  - name: team email\n    type: email\n    uid: team-email\n    org_id: 3\n"), utils.BusStore{}, false, inventory)
+				So(errors.Is(err, utils.ErrOrgConflict), ShouldBeTrue)
+			})
+		})
+
 		Convey("Read incorrect properties", func() {
 			cfgProvider := &configReader{log: log.New("test logger")}
-			_, err := cfgProvider.readConfig(incorrectSettings)
+			_, err := cfgProvider.readConfig(context.Background(), incorrectSettings)
 			So(err, ShouldNotBeNil)
 			So(err.Error(), ShouldEqual, "alert validation error: token must be specified when using the Slack chat API")
 		})
@@ -0,0 +1,100 @@
+package notifiers
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+)
+
+// exportFileName is the name of the file Export writes the notifiers to.
+const exportFileName = "notifiers.yaml"
+
+// exportedConfigV0 is the file Export writes, with the fields of notificationsAsConfigV0 it fills.
+type exportedConfigV0 struct {
+	Notifications []*exportedNotificationV0 `yaml:"notifiers"`
+}
+
+type exportedNotificationV0 struct {
+	UID                   string                 `yaml:"uid"`
+	OrgID                 int64                  `yaml:"org_id"`
+	Name                  string                 `yaml:"name"`
+	Type                  string                 `yaml:"type"`
+	IsDefault             bool                   `yaml:"is_default,omitempty"`
+	SendReminder          bool                   `yaml:"send_reminder,omitempty"`
+	Frequency             string                 `yaml:"frequency,omitempty"`
+	DisableResolveMessage bool                   `yaml:"disable_resolve_message,omitempty"`
+	Settings              map[string]interface{} `yaml:"settings,omitempty"`
+	SecureSettings        map[string]string      `yaml:"secure_settings,omitempty"`
+}
+
+// Export returns a provisioning file of the alert notifiers of every org in the database. Their secure settings are
+// ${NOTIFIER_...} placeholders, so provisioning the file with the variables set leaves the notifiers as they are.
+// Notifiers don't record whether provisioning created them, so they're all exported.
+func Export(ctx context.Context) (*utils.ExportFile, error) {
+	orgs := &models.SearchOrgsQuery{}
+	if err := bus.DispatchCtx(ctx, orgs); err != nil {
+		return nil, err
+	}
+
+	secrets := utils.NewExportSecrets("NOTIFIER")
+	cfg := &exportedConfigV0{Notifications: []*exportedNotificationV0{}}
+	for _, org := range orgs.Result {
+		query := &models.GetAllAlertNotificationsQuery{OrgId: org.Id}
+		if err := bus.DispatchCtx(ctx, query); err != nil {
+			return nil, err
+		}
+		for _, notification := range query.Result {
+			cfg.Notifications = append(cfg.Notifications, exportNotification(notification, secrets))
+		}
+	}
+
+	content, err := utils.MarshalExport(cfg, secrets.Variables())
+	if err != nil {
+		return nil, err
+	}
+	return &utils.ExportFile{Name: exportFileName, Content: content, Objects: len(cfg.Notifications),
+		Variables: secrets.Variables()}, nil
+}
+
+func exportNotification(notification *models.AlertNotification, secrets *utils.ExportSecrets) *exportedNotificationV0 {
+	escape := utils.EscapeInterpolation
+	exported := &exportedNotificationV0{
+		UID:                   escape(notification.Uid),
+		OrgID:                 notification.OrgId,
+		Name:                  escape(notification.Name),
+		Type:                  escape(notification.Type),
+		IsDefault:             notification.IsDefault,
+		SendReminder:          notification.SendReminder,
+		DisableResolveMessage: notification.DisableResolveMessage,
+	}
+	if notification.Frequency > 0 {
+		exported.Frequency = notification.Frequency.String()
+	}
+	if notification.Settings != nil {
+		if settings, ok := utils.ExportJSON(notification.Settings.MustMap()).(map[string]interface{}); ok && len(settings) > 0 {
+			exported.Settings = settings
+		}
+	}
+
+	// Uids are only unique within an org, so the variables of other orgs than the main one are named after it too.
+	name := notification.Uid
+	if notification.OrgId != 1 {
+		name = "org" + strconv.FormatInt(notification.OrgId, 10) + "_" + notification.Uid
+	}
+	keys := make([]string, 0, len(notification.SecureSettings))
+	for key := range notification.SecureSettings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if exported.SecureSettings == nil {
+			exported.SecureSettings = map[string]string{}
+		}
+		exported.SecureSettings[key] = secrets.Placeholder(name, key)
+	}
+	return exported
+}
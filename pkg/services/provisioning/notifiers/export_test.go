@@ -0,0 +1,83 @@
+package notifiers
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/alerting"
+	"github.com/grafana/grafana/pkg/services/alerting/notifiers"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	alerting.RegisterNotifier(&alerting.NotifierPlugin{Type: "slack", Name: "slack", Factory: notifiers.NewSlackNotifier})
+	alerting.RegisterNotifier(&alerting.NotifierPlugin{Type: "email", Name: "email", Factory: notifiers.NewEmailNotifier})
+
+	sqlstore.InitTestDB(t)
+	for _, name := range []string{"Main Org.", "Second Org."} {
+		require.NoError(t, sqlstore.CreateOrg(&models.CreateOrgCommand{Name: name}))
+	}
+	require.NoError(t, sqlstore.CreateAlertNotificationCommand(&models.CreateAlertNotificationCommand{
+		Uid:            "ops-slack",
+		OrgId:          1,
+		Name:           "Ops",
+		Type:           "slack",
+		SendReminder:   true,
+		Frequency:      "10m",
+		Settings:       simplejson.NewFromAny(map[string]interface{}{"recipient": "#ops", "mentionUsers": "$oncall"}),
+		SecureSettings: map[string]string{"url": "https://hooks.slack.com/secret", "token": "xoxb"},
+	}))
+	require.NoError(t, sqlstore.CreateAlertNotificationCommand(&models.CreateAlertNotificationCommand{
+		Uid:                   "team-email",
+		OrgId:                 2,
+		Name:                  "Team",
+		Type:                  "email",
+		IsDefault:             true,
+		DisableResolveMessage: true,
+		Settings:              simplejson.NewFromAny(map[string]interface{}{"addresses": "team@example.com"}),
+	}))
+
+	// notifiersOf returns the notifiers of the org with their secure settings decrypted, as provisioning compares them.
+	notifiersOf := func(t *testing.T, orgID int64) []map[string]interface{} {
+		query := models.GetAllAlertNotificationsQuery{OrgId: orgID}
+		require.NoError(t, sqlstore.GetAllAlertNotifications(&query))
+		var result []map[string]interface{}
+		for _, n := range query.Result {
+			result = append(result, map[string]interface{}{"uid": n.Uid, "name": n.Name, "type": n.Type,
+				"isDefault": n.IsDefault, "sendReminder": n.SendReminder, "frequency": n.Frequency,
+				"disableResolveMessage": n.DisableResolveMessage, "settings": n.Settings.MustMap(),
+				"secureSettings": n.SecureSettings.Decrypt()})
+		}
+		return result
+	}
+	before := map[int64][]map[string]interface{}{1: notifiersOf(t, 1), 2: notifiersOf(t, 2)}
+
+	file, err := Export(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "notifiers.yaml", file.Name)
+	assert.Equal(t, 2, file.Objects)
+	assert.Equal(t, []string{"NOTIFIER_OPS_SLACK_TOKEN", "NOTIFIER_OPS_SLACK_URL"}, file.Variables)
+	assert.NotContains(t, string(file.Content), "xoxb", "Secure settings aren't exported")
+
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, file.Name), file.Content, 0600))
+	for variable, value := range map[string]string{"NOTIFIER_OPS_SLACK_TOKEN": "xoxb",
+		"NOTIFIER_OPS_SLACK_URL": "https://hooks.slack.com/secret"} {
+		require.NoError(t, os.Setenv(variable, value))
+		variable := variable
+		t.Cleanup(func() { _ = os.Unsetenv(variable) })
+	}
+
+	dc := newNotificationProvisioner(log.New("test logger"))
+	require.NoError(t, dc.applyChanges(context.Background(), dir))
+	assert.Equal(t, before, map[int64][]map[string]interface{}{1: notifiersOf(t, 1), 2: notifiersOf(t, 2)},
+		"Provisioning the export leaves the notifiers as they are")
+}
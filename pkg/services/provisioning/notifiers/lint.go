@@ -0,0 +1,51 @@
+package notifiers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// Lint validates the alert notification config files in configDirectory without a database, so the checks that
+// need one, like whether orgs exist, are skipped. Unknown fields are an error when strict is set. It returns a
+// LintError for every invalid file.
+func Lint(configDirectory string, strict bool) ([]utils.LintError, error) {
+	cr := &configReader{log: log.New("provisioning.notifiers"), offline: true, strict: strict,
+		env: utils.EnvironmentFromContext(context.Background())}
+	files, err := utils.ProvisioningFiles(configDirectory, setting.ProvisioningFileFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	var lintErrors []utils.LintError
+	for _, file := range files {
+		filename, _ := filepath.Abs(filepath.Join(configDirectory, file.Name()))
+		if err := cr.lintFile(configDirectory, file); err != nil {
+			lintErrors = append(lintErrors, utils.NewLintError(filename, err))
+		}
+	}
+	return lintErrors, nil
+}
+
+func (cr *configReader) lintFile(path string, file os.FileInfo) error {
+	cfg, err := cr.parseNotificationConfig(path, file)
+	if err != nil {
+		return err
+	}
+	if cfg == nil {
+		return nil
+	}
+
+	notifications := []*notificationsAsConfig{cfg}
+	if err := validateRequiredField(notifications); err != nil {
+		return err
+	}
+	if err := cr.checkOrgIDAndOrgName(context.Background(), notifications); err != nil {
+		return err
+	}
+	return validateNotifications(notifications)
+}
@@ -0,0 +1,48 @@
+package notifiers
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/services/alerting"
+	"github.com/grafana/grafana/pkg/services/alerting/notifiers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	alerting.RegisterNotifier(&alerting.NotifierPlugin{
+		Type:    "slack",
+		Name:    "slack",
+		Factory: notifiers.NewSlackNotifier,
+	})
+	alerting.RegisterNotifier(&alerting.NotifierPlugin{
+		Type:    "email",
+		Name:    "email",
+		Factory: notifiers.NewEmailNotifier,
+	})
+
+	t.Run("Valid configs don't have lint errors and orgs aren't looked up", func(t *testing.T) {
+		lintErrors, err := Lint(correctProperties, false)
+		require.NoError(t, err)
+		assert.Empty(t, lintErrors)
+	})
+
+	t.Run("Reports every invalid file", func(t *testing.T) {
+		for _, path := range []string{noRequiredFields, unknownNotifier, incorrectSettings} {
+			lintErrors, err := Lint(path, false)
+			require.NoError(t, err)
+			assert.Len(t, lintErrors, 1, path)
+		}
+	})
+
+	t.Run("Reports the line of broken YAML", func(t *testing.T) {
+		lintErrors, err := Lint(brokenYaml, false)
+		require.NoError(t, err)
+		require.Len(t, lintErrors, 1)
+
+		filename, _ := filepath.Abs(filepath.Join(brokenYaml, "broken.yaml"))
+		assert.Equal(t, filename, lintErrors[0].File)
+		assert.Greater(t, lintErrors[0].Line, 0)
+	})
+}
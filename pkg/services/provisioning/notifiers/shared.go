@@ -0,0 +1,122 @@
+package notifiers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+)
+
+// sharedDirectory is the directory of a notifiers provisioning directory whose notifiers are provisioned into every
+// org, like notifiers/_shared.
+const sharedDirectory = "_shared"
+
+// markShared marks the notifiers of a config read from a shared directory, which can't be given an org.
+func markShared(cfg *notificationsAsConfig) error {
+	for _, notification := range cfg.Notifications {
+		if notification.OrgID != 0 || notification.OrgName != "" {
+			return &utils.ProvisioningFileError{Subsystem: "notifiers", Path: cfg.Filename,
+				Err: fmt.Errorf("shared alert notification %q can't have an org_id or org_name", notification.Name)}
+		}
+		notification.Shared = true
+	}
+	for _, notification := range cfg.DeleteNotifications {
+		if notification.OrgID != 0 || notification.OrgName != "" {
+			return &utils.ProvisioningFileError{Subsystem: "notifiers", Path: cfg.Filename,
+				Err: fmt.Errorf("deleted shared alert notification %q can't have an org_id or org_name", notification.Name)}
+		}
+		notification.Shared = true
+	}
+	return nil
+}
+
+// expandShared replaces the shared notifiers of configs with a copy for every org. An org whose own notifiers
+// have one with the same name overrides the shared notifier: the shared one isn't provisioned into that org, and
+// its copy is deleted from it when the override has another uid. Shared notifiers that are deleted are deleted from
+// every org that doesn't override them.
+func (dc *NotificationProvisioner) expandShared(ctx context.Context, configs []*notificationsAsConfig) ([]*notificationsAsConfig, error) {
+	if !hasShared(configs) {
+		return configs, nil
+	}
+
+	query := &models.SearchOrgsQuery{}
+	if err := bus.DispatchCtx(ctx, query); err != nil {
+		return nil, fmt.Errorf("failed to list the orgs of the shared alert notifications: %w", err)
+	}
+	orgIDsByName := map[string]int64{}
+	for _, org := range query.Result {
+		orgIDsByName[org.Name] = org.Id
+	}
+
+	// overrides holds the uids of the notifiers each org has of its own, by name.
+	overrides := map[int64]map[string]string{}
+	for _, cfg := range configs {
+		for _, notification := range cfg.Notifications {
+			if notification.Shared {
+				continue
+			}
+			orgID := notification.OrgID
+			if orgID == 0 {
+				orgID = orgIDsByName[notification.OrgName]
+			}
+			if overrides[orgID] == nil {
+				overrides[orgID] = map[string]string{}
+			}
+			overrides[orgID][notification.Name] = notification.UID
+		}
+	}
+
+	expanded := make([]*notificationsAsConfig, 0, len(configs))
+	for _, cfg := range configs {
+		if !hasShared([]*notificationsAsConfig{cfg}) {
+			expanded = append(expanded, cfg)
+			continue
+		}
+
+		orgsCfg := &notificationsAsConfig{Filename: cfg.Filename}
+		for _, org := range query.Result {
+			for _, notification := range cfg.Notifications {
+				overrideUID, overridden := overrides[org.Id][notification.Name]
+				if !overridden {
+					copied := *notification
+					copied.OrgID = org.Id
+					orgsCfg.Notifications = append(orgsCfg.Notifications, &copied)
+					continue
+				}
+				dc.log.Debug("Shared alert notification overridden by a notification of the org", "name", notification.Name,
+					"orgId", org.Id, "uid", overrideUID)
+				if overrideUID != notification.UID {
+					orgsCfg.DeleteNotifications = append(orgsCfg.DeleteNotifications,
+						&deleteNotificationConfig{UID: notification.UID, Name: notification.Name, OrgID: org.Id})
+				}
+			}
+			for _, notification := range cfg.DeleteNotifications {
+				if _, overridden := overrides[org.Id][notification.Name]; overridden {
+					continue
+				}
+				orgsCfg.DeleteNotifications = append(orgsCfg.DeleteNotifications,
+					&deleteNotificationConfig{UID: notification.UID, Name: notification.Name, OrgID: org.Id})
+			}
+		}
+		expanded = append(expanded, orgsCfg)
+	}
+	return expanded, nil
+}
+
+func hasShared(configs []*notificationsAsConfig) bool {
+	for _, cfg := range configs {
+		for _, notification := range cfg.Notifications {
+			if notification.Shared {
+				return true
+			}
+		}
+		for _, notification := range cfg.DeleteNotifications {
+			if notification.Shared {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package notifiers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/alerting"
+	"github.com/grafana/grafana/pkg/services/alerting/notifiers"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	sharedNotificationsConfig = "./testdata/test-configs/shared"
+	sharedOverrideConfig      = "./testdata/test-configs/shared-override"
+	sharedDeleteConfig        = "./testdata/test-configs/shared-delete"
+	sharedWithOrgConfig       = "./testdata/test-configs/shared-with-org"
+)
+
+func TestSharedNotifications(t *testing.T) {
+	alerting.RegisterNotifier(&alerting.NotifierPlugin{Type: "slack", Name: "slack", Factory: notifiers.NewSlackNotifier})
+	alerting.RegisterNotifier(&alerting.NotifierPlugin{Type: "email", Name: "email", Factory: notifiers.NewEmailNotifier})
+
+	setup := func(t *testing.T) *NotificationProvisioner {
+		sqlstore.InitTestDB(t)
+		for i := 1; i <= 3; i++ {
+			require.NoError(t, sqlstore.CreateOrg(&models.CreateOrgCommand{Name: fmt.Sprintf("Org %d", i)}))
+		}
+		dc := newNotificationProvisioner(log.New("test logger"))
+		return &dc
+	}
+
+	// notifiers returns the uids of the notifiers of every org by name.
+	notifiersByOrg := func(t *testing.T) map[int64]map[string]string {
+		byOrg := map[int64]map[string]string{}
+		for orgID := int64(1); orgID <= 3; orgID++ {
+			query := models.GetAllAlertNotificationsQuery{OrgId: orgID}
+			require.NoError(t, sqlstore.GetAllAlertNotifications(&query))
+			byOrg[orgID] = map[string]string{}
+			for _, notification := range query.Result {
+				byOrg[orgID][notification.Name] = notification.Uid
+			}
+		}
+		return byOrg
+	}
+
+	t.Run("Shared notifiers are provisioned into every org", func(t *testing.T) {
+		dc := setup(t)
+		require.NoError(t, dc.applyChanges(context.Background(), sharedNotificationsConfig))
+
+		shared := map[string]string{"Shared email": "shared-email", "Ops": "ops-slack"}
+		assert.Equal(t, map[int64]map[string]string{1: shared, 2: shared, 3: shared}, notifiersByOrg(t))
+	})
+
+	t.Run("Notifiers of an org replace the shared notifier with the same name", func(t *testing.T) {
+		dc := setup(t)
+		require.NoError(t, dc.applyChanges(context.Background(), sharedNotificationsConfig))
+		require.NoError(t, dc.applyChanges(context.Background(), sharedNotificationsConfig, sharedOverrideConfig))
+
+		shared := map[string]string{"Shared email": "shared-email", "Ops": "ops-slack"}
+		assert.Equal(t, map[int64]map[string]string{
+			1: shared,
+			2: {"Shared email": "shared-email", "Ops": "org2-ops"},
+			3: shared,
+		}, notifiersByOrg(t))
+	})
+
+	t.Run("Deleted shared notifiers are deleted from the orgs that don't override them", func(t *testing.T) {
+		dc := setup(t)
+		require.NoError(t, dc.applyChanges(context.Background(), sharedNotificationsConfig, sharedOverrideConfig))
+		require.NoError(t, dc.applyChanges(context.Background(), sharedDeleteConfig, sharedOverrideConfig))
+
+		assert.Equal(t, map[int64]map[string]string{
+			1: {},
+			2: {"Ops": "org2-ops"},
+			3: {},
+		}, notifiersByOrg(t))
+	})
+
+	t.Run("Shared notifiers can't have an org", func(t *testing.T) {
+		dc := setup(t)
+		err := dc.applyChanges(context.Background(), sharedWithOrgConfig)
+		var fileErr *utils.ProvisioningFileError
+		require.True(t, errors.As(err, &fileErr))
+		assert.Contains(t, err.Error(), `shared alert notification "Ops" can't have an org_id or org_name`)
+	})
+}
@@ -8,6 +8,8 @@ import (
 // notificationsAsConfig is normalized data object for notifications config data. Any config version should be mappable
 // to this type.
 type notificationsAsConfig struct {
+	// Filename is the absolute path of the file the config was read from.
+	Filename            string
 	Notifications       []*notificationFromConfig
 	DeleteNotifications []*deleteNotificationConfig
 }
@@ -17,6 +19,8 @@ type deleteNotificationConfig struct {
 	Name    string
 	OrgID   int64
 	OrgName string
+	// Shared is set for the notifiers deleted from every org, see sharedDirectory.
+	Shared bool
 }
 
 type notificationFromConfig struct {
@@ -31,10 +35,14 @@ type notificationFromConfig struct {
 	IsDefault             bool
 	Settings              map[string]interface{}
 	SecureSettings        map[string]string
+	// Shared is set for the notifiers provisioned into every org, see sharedDirectory.
+	Shared bool
 }
 
 // notificationsAsConfigV0 is mapping for zero version configs. This is mapped to its normalised version.
 type notificationsAsConfigV0 struct {
+	APIVersion int64 `json:"apiVersion" yaml:"apiVersion"`
+
 	Notifications       []*notificationFromConfigV0   `json:"notifiers" yaml:"notifiers"`
 	DeleteNotifications []*deleteNotificationConfigV0 `json:"delete_notifiers" yaml:"delete_notifiers"`
 }
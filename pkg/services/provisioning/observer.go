@@ -0,0 +1,93 @@
+package provisioning
+
+import (
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/provisioning/dashboards"
+)
+
+// ProvisionResult is what a successful provisioning run of a subsystem applied.
+type ProvisionResult struct {
+	// Objects are the objects applied by the run, for dashboards the ones of every provider.
+	Objects []ProvisionedObject
+	// Deleted are the objects the run deleted, since they were listed for deletion or their files were removed.
+	Deleted []ProvisionedObject
+	// Directories are the directories the run read its files from, whether they exist or not.
+	Directories []string
+	// Duration is how long the run took.
+	Duration time.Duration
+	// ResumedFiles is the number of dashboard files the run skipped, because a failed run before it already applied
+	// them and they haven't changed since.
+	ResumedFiles int
+}
+
+// ProvisioningObserver is notified after every provisioning run of a subsystem, like datasources or dashboards.
+// Observers are called synchronously by the provisioning run, so they should return quickly.
+type ProvisioningObserver interface {
+	OnProvisioned(kind string, result ProvisionResult)
+	OnProvisioningError(kind string, err error)
+}
+
+// DashboardDrift is a provisioned dashboard whose manual changes are overwritten by provisioning.
+type DashboardDrift = dashboards.DashboardDrift
+
+// DashboardDriftObserver can be implemented by a ProvisioningObserver to also be notified before provisioning
+// overwrites the manual changes of a dashboard, for example to keep an audit trail of them.
+type DashboardDriftObserver interface {
+	OnDashboardDrift(drift DashboardDrift)
+}
+
+// RegisterObserver adds an observer that is notified of the provisioning runs from now on.
+func (ps *provisioningServiceImpl) RegisterObserver(observer ProvisioningObserver) {
+	ps.observersMutex.Lock()
+	defer ps.observersMutex.Unlock()
+
+	ps.observers = append(ps.observers, observer)
+}
+
+// notifyDashboardDrift passes drift to the observers that implement DashboardDriftObserver.
+func (ps *provisioningServiceImpl) notifyDashboardDrift(drift DashboardDrift) {
+	ps.observersMutex.Lock()
+	observers := ps.observers
+	ps.observersMutex.Unlock()
+
+	for _, observer := range observers {
+		driftObserver, ok := observer.(DashboardDriftObserver)
+		if !ok {
+			continue
+		}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					ps.log.Error("Provisioning observer panicked", "kind", "dashboards", "error", r, "stack", log.Stack(1))
+				}
+			}()
+			driftObserver.OnDashboardDrift(drift)
+		}()
+	}
+}
+
+// notifyObservers passes the outcome of a run to every observer. A panicking observer is logged and skipped, so it
+// can't fail the run or keep the other observers from being notified.
+func (ps *provisioningServiceImpl) notifyObservers(kind string, result ProvisionResult, err error) {
+	ps.observersMutex.Lock()
+	observers := ps.observers
+	ps.observersMutex.Unlock()
+
+	for _, observer := range observers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					ps.log.Error("Provisioning observer panicked", "kind", kind, "error", r, "stack", log.Stack(1))
+				}
+			}()
+
+			if err != nil {
+				observer.OnProvisioningError(kind, err)
+				return
+			}
+			observer.OnProvisioned(kind, result)
+		}()
+	}
+}
@@ -0,0 +1,130 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/services/provisioning/dashboards"
+	"github.com/grafana/grafana/pkg/services/provisioning/datasources"
+	"github.com/grafana/grafana/pkg/services/provisioning/orgs"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingObserver struct {
+	provisioned map[string]ProvisionResult
+	failed      map[string]error
+}
+
+func newRecordingObserver() *recordingObserver {
+	return &recordingObserver{provisioned: map[string]ProvisionResult{}, failed: map[string]error{}}
+}
+
+func (o *recordingObserver) OnProvisioned(kind string, result ProvisionResult) {
+	o.provisioned[kind] = result
+}
+
+func (o *recordingObserver) OnProvisioningError(kind string, err error) {
+	o.failed[kind] = err
+}
+
+type driftObserver struct {
+	*recordingObserver
+	drifts []DashboardDrift
+}
+
+func (o *driftObserver) OnDashboardDrift(drift DashboardDrift) {
+	o.drifts = append(o.drifts, drift)
+}
+
+type panickingObserver struct{}
+
+func (panickingObserver) OnProvisioned(string, ProvisionResult) {
+	panic("observer is broken")
+}
+
+func (panickingObserver) OnProvisioningError(string, error) {
+	panic("observer is broken")
+}
+
+func TestProvisioningObservers(t *testing.T) {
+	setupObserved := func(t *testing.T) (*serviceTestStruct, *recordingObserver) {
+		t.Helper()
+		serviceTest := setup()
+		serviceTest.service.provisionOrgs = func(context.Context, string, orgs.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error {
+			return errors.New("invalid org config")
+		}
+		serviceTest.service.provisionDatasources = func(_ context.Context, opts datasources.ProvisionOptions) error {
+			opts.Inventory.Record(ProvisionedObject{Kind: "datasource", Name: "Prometheus", OrgID: 1})
+			return nil
+		}
+		serviceTest.mock.GetProvisionedDashboardsFunc = func() []ProvisionedObject {
+			return []ProvisionedObject{{Kind: "dashboard", Name: "Home", OrgID: 1}}
+		}
+
+		serviceTest.mock.ResumedFilesFunc = func() int { return 2 }
+
+		observer := newRecordingObserver()
+		serviceTest.service.RegisterObserver(observer)
+		return serviceTest, observer
+	}
+
+	t.Run("Observers are notified of each run by kind", func(t *testing.T) {
+		serviceTest, observer := setupObserved(t)
+
+		require.NoError(t, serviceTest.service.ProvisionDatasources(context.Background()))
+		require.NoError(t, serviceTest.service.ProvisionDashboards(context.Background()))
+		require.Error(t, serviceTest.service.ProvisionOrgs(context.Background()))
+
+		require.Len(t, observer.provisioned, 2)
+		require.Contains(t, observer.provisioned, "datasources")
+		assert.Equal(t, "Prometheus", observer.provisioned["datasources"].Objects[0].Name)
+		require.Contains(t, observer.provisioned, "dashboards")
+		assert.Equal(t, "Home", observer.provisioned["dashboards"].Objects[0].Name)
+		assert.Equal(t, 2, observer.provisioned["dashboards"].ResumedFiles)
+
+		require.Len(t, observer.failed, 1)
+		assert.EqualError(t, observer.failed["orgs"], "Org provisioning error: invalid org config")
+	})
+
+	t.Run("Reprovisioning a provider notifies the dashboards observers", func(t *testing.T) {
+		serviceTest, observer := setupObserved(t)
+		require.NoError(t, serviceTest.service.ProvisionDashboards(context.Background()))
+		delete(observer.provisioned, "dashboards")
+
+		require.NoError(t, serviceTest.service.ReprovisionProvider(context.Background(), "default"))
+		assert.Contains(t, observer.provisioned, "dashboards")
+	})
+
+	t.Run("Observers implementing DashboardDriftObserver are notified of overwritten dashboards", func(t *testing.T) {
+		serviceTest, observer := setupObserved(t)
+		var handler dashboards.DriftHandler
+		serviceTest.mock.SetDriftHandlerFunc = func(h dashboards.DriftHandler) { handler = h }
+		withDrift := &driftObserver{recordingObserver: newRecordingObserver()}
+		serviceTest.service.RegisterObserver(withDrift)
+
+		require.NoError(t, serviceTest.service.ProvisionDashboards(context.Background()))
+		require.NotNil(t, handler)
+		handler(DashboardDrift{UID: "home", Provider: "default"})
+
+		require.Len(t, withDrift.drifts, 1)
+		assert.Equal(t, "home", withDrift.drifts[0].UID)
+		assert.Contains(t, observer.provisioned, "dashboards")
+	})
+
+	t.Run("A panicking observer doesn't fail provisioning or the other observers", func(t *testing.T) {
+		serviceTest := setup()
+		serviceTest.service.provisionDatasources = func(context.Context, datasources.ProvisionOptions) error {
+			return nil
+		}
+		observer := newRecordingObserver()
+		serviceTest.service.RegisterObserver(panickingObserver{})
+		serviceTest.service.RegisterObserver(observer)
+
+		require.NoError(t, serviceTest.service.ProvisionDatasources(context.Background()))
+		assert.Contains(t, observer.provisioned, "datasources")
+	})
+}
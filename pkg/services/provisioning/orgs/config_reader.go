@@ -0,0 +1,142 @@
+package orgs
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+type configReader struct {
+	log        log.Logger
+	fileFilter setting.ProvisioningFileFilter
+	// strict rejects unknown fields instead of ignoring them.
+	strict bool
+	// env is what the guards of the files are checked against.
+	env utils.Environment
+}
+
+func (cr *configReader) readConfig(path string) ([]*orgsAsConfig, error) {
+	var orgs []*orgsAsConfig
+	cr.log.Debug("Looking for org provisioning files", "path", path)
+
+	files, err := utils.ReadDir(path)
+	if err != nil {
+		cr.log.Error("Can't read org provisioning files from directory", "path", path, "error", err)
+		return orgs, nil
+	}
+
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".yaml") || strings.HasSuffix(file.Name(), ".yml") {
+			if !cr.fileFilter.Includes(file.Name()) {
+				cr.log.Debug("Skipping excluded org provisioning file", "path", path, "file.Name", file.Name())
+				continue
+			}
+
+			cr.log.Debug("Parsing org provisioning file", "path", path, "file.Name", file.Name())
+			org, err := cr.parseOrgConfig(path, file)
+			if err != nil {
+				return nil, err
+			}
+
+			if org != nil {
+				orgs = append(orgs, org)
+			}
+		}
+	}
+
+	cr.log.Debug("Validating orgs")
+	if err := validateRequiredField(orgs); err != nil {
+		return nil, err
+	}
+
+	if err := validateUniqueness(orgs); err != nil {
+		return nil, err
+	}
+
+	return orgs, nil
+}
+
+func (cr *configReader) parseOrgConfig(path string, file os.FileInfo) (*orgsAsConfig, error) {
+	filename, err := filepath.Abs(filepath.Join(path, file.Name()))
+	if err != nil {
+		return nil, err
+	}
+
+	// nolint:gosec
+	// We can ignore the gosec G304 warning on this one because `filename` comes from ps.Cfg.ProvisioningPath
+	yamlFile, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg *orgsAsConfigV0
+	decoder := utils.YAMLDecoder{Subsystem: "orgs", Kind: "Org", Strict: cr.strict, Environment: cr.env, Log: cr.log}
+	if err := decoder.Decode(filename, yamlFile, &cfg); err != nil {
+		if errors.Is(err, utils.ErrFileSkipped) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	orgs := cfg.mapToOrgsFromConfig()
+	orgs.Filename = filename
+	return orgs, nil
+}
+
+func validateRequiredField(orgs []*orgsAsConfig) error {
+	for i := range orgs {
+		var errStrings []string
+		for index, org := range orgs[i].Orgs {
+			if org.Name == "" {
+				errStrings = append(
+					errStrings,
+					fmt.Sprintf("org item %d in configuration doesn't contain required field name", index+1),
+				)
+			}
+		}
+
+		for index, org := range orgs[i].DeleteOrgs {
+			if org.Name == "" {
+				errStrings = append(
+					errStrings,
+					fmt.Sprintf("delete org item %d in configuration doesn't contain required field name", index+1),
+				)
+			}
+		}
+
+		if len(errStrings) != 0 {
+			return fmt.Errorf(strings.Join(errStrings, "\n"))
+		}
+	}
+
+	return nil
+}
+
+// validateUniqueness makes sure an org isn't declared twice, since the declarations would keep
+// overwriting each other.
+func validateUniqueness(orgs []*orgsAsConfig) error {
+	names := map[string]bool{}
+	externalIDs := map[string]bool{}
+	for i := range orgs {
+		for _, org := range orgs[i].Orgs {
+			if names[org.Name] {
+				return fmt.Errorf("org %q is provisioned more than once", org.Name)
+			}
+			names[org.Name] = true
+
+			if externalIDs[org.externalID()] {
+				return fmt.Errorf("external ID %q is used by more than one provisioned org", org.externalID())
+			}
+			externalIDs[org.externalID()] = true
+		}
+	}
+
+	return nil
+}
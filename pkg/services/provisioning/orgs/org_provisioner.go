@@ -0,0 +1,211 @@
+package orgs
+
+import (
+	"context"
+	"errors"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// Store is what orgs are provisioned through. Orgs are still looked up with bus queries.
+type Store interface {
+	CreateOrg(ctx context.Context, cmd *models.CreateOrgCommand) error
+	UpdateOrg(ctx context.Context, cmd *models.UpdateOrgCommand) error
+	DeleteOrg(ctx context.Context, cmd *models.DeleteOrgCommand) error
+	SaveProvisionedOrg(ctx context.Context, cmd *models.SaveProvisionedOrgCommand) error
+	SavePreferences(ctx context.Context, cmd *models.SavePreferencesCommand) error
+}
+
+// Provision scans a directory for provisioning config files
+// and provisions the orgs in those files through store. The orgs that were applied are recorded in inventory.
+func Provision(ctx context.Context, configDirectory string, store Store, fileFilter setting.ProvisioningFileFilter, strict bool, inventory *utils.Inventory) error {
+	logger := log.New("provisioning.orgs")
+	op := OrgProvisioner{
+		log:         logger,
+		cfgProvider: &configReader{log: logger, fileFilter: fileFilter, strict: strict, env: utils.EnvironmentFromContext(ctx)},
+		store:       store,
+		inventory:   inventory,
+	}
+	return op.applyChanges(ctx, configDirectory)
+}
+
+// OrgProvisioner is responsible for provisioning orgs based on
+// configuration read by the `configReader`
+type OrgProvisioner struct {
+	log         log.Logger
+	cfgProvider *configReader
+	store       Store
+	inventory   *utils.Inventory
+}
+
+func (op *OrgProvisioner) applyChanges(ctx context.Context, configPath string) error {
+	configs, err := op.cfgProvider.readConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	query := &models.GetProvisionedOrgsQuery{}
+	if err := bus.DispatchCtx(ctx, query); err != nil {
+		return err
+	}
+
+	provisioned := make(map[string]int64, len(query.Result))
+	for _, p := range query.Result {
+		provisioned[p.ExternalId] = p.OrgId
+	}
+
+	declared := map[string]bool{}
+	for _, cfg := range configs {
+		if err := op.apply(ctx, cfg, provisioned); err != nil {
+			return err
+		}
+
+		for _, org := range cfg.Orgs {
+			declared[org.externalID()] = true
+		}
+	}
+
+	op.reportRemovedOrgs(provisioned, declared)
+	return nil
+}
+
+func (op *OrgProvisioner) apply(ctx context.Context, cfg *orgsAsConfig, provisioned map[string]int64) error {
+	if err := op.deleteOrgs(ctx, cfg.DeleteOrgs, provisioned, cfg.Filename); err != nil {
+		return err
+	}
+
+	for _, org := range cfg.Orgs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		orgID, err := op.provisionOrg(ctx, org, provisioned)
+		if err != nil {
+			return err
+		}
+
+		if err := op.applyPreferences(ctx, org, orgID); err != nil {
+			return err
+		}
+		op.inventory.Record(utils.ProvisionedObject{Kind: "org", Name: org.Name, OrgID: orgID, File: cfg.Filename})
+	}
+
+	return nil
+}
+
+// provisionOrg makes sure the org exists with the configured name and returns its ID. An org previously
+// provisioned with the same external ID is renamed rather than a new one created.
+func (op *OrgProvisioner) provisionOrg(ctx context.Context, org *orgFromConfig, provisioned map[string]int64) (int64, error) {
+	externalID := org.externalID()
+
+	if orgID, exists := provisioned[externalID]; exists {
+		query := &models.GetOrgByIdQuery{Id: orgID}
+		err := bus.DispatchCtx(ctx, query)
+		if err != nil && !errors.Is(err, models.ErrOrgNotFound) {
+			return 0, err
+		}
+
+		if err == nil {
+			if query.Result.Name != org.Name {
+				op.log.Info("renaming org from configuration", "from", query.Result.Name, "to", org.Name, "externalId", externalID)
+				if err := op.store.UpdateOrg(ctx, &models.UpdateOrgCommand{OrgId: orgID, Name: org.Name}); err != nil {
+					return 0, err
+				}
+			}
+			return orgID, nil
+		}
+
+		op.log.Warn("previously provisioned org no longer exists, provisioning it again", "name", org.Name, "externalId", externalID)
+	}
+
+	query := &models.GetOrgByNameQuery{Name: org.Name}
+	err := bus.DispatchCtx(ctx, query)
+	if err != nil && !errors.Is(err, models.ErrOrgNotFound) {
+		return 0, err
+	}
+
+	var orgID int64
+	if errors.Is(err, models.ErrOrgNotFound) {
+		op.log.Info("inserting org from configuration", "name", org.Name, "externalId", externalID)
+		createCmd := &models.CreateOrgCommand{Name: org.Name}
+		if err := op.store.CreateOrg(ctx, createCmd); err != nil {
+			return 0, err
+		}
+		orgID = createCmd.Result.Id
+	} else {
+		orgID = query.Result.Id
+	}
+
+	if err := op.store.SaveProvisionedOrg(ctx, &models.SaveProvisionedOrgCommand{OrgId: orgID, ExternalId: externalID}); err != nil {
+		return 0, err
+	}
+	provisioned[externalID] = orgID
+
+	return orgID, nil
+}
+
+func (op *OrgProvisioner) applyPreferences(ctx context.Context, org *orgFromConfig, orgID int64) error {
+	if org.Theme == "" && org.Timezone == "" {
+		return nil
+	}
+
+	query := &models.GetPreferencesQuery{OrgId: orgID}
+	if err := bus.DispatchCtx(ctx, query); err != nil {
+		return err
+	}
+
+	cmd := &models.SavePreferencesCommand{
+		OrgId:           orgID,
+		HomeDashboardId: query.Result.HomeDashboardId,
+		Theme:           query.Result.Theme,
+		Timezone:        query.Result.Timezone,
+	}
+	if org.Theme != "" {
+		cmd.Theme = org.Theme
+	}
+	if org.Timezone != "" {
+		cmd.Timezone = org.Timezone
+	}
+
+	return op.store.SavePreferences(ctx, cmd)
+}
+
+func (op *OrgProvisioner) deleteOrgs(ctx context.Context, orgsToDelete []*deleteOrgConfig, provisioned map[string]int64,
+	filename string) error {
+	for _, org := range orgsToDelete {
+		query := &models.GetOrgByNameQuery{Name: org.Name}
+		if err := bus.DispatchCtx(ctx, query); err != nil {
+			if errors.Is(err, models.ErrOrgNotFound) {
+				continue
+			}
+			return err
+		}
+
+		if err := op.store.DeleteOrg(ctx, &models.DeleteOrgCommand{Id: query.Result.Id}); err != nil {
+			return err
+		}
+		op.log.Info("deleted org based on configuration", "name", org.Name)
+		op.inventory.RecordDeleted(utils.ProvisionedObject{Kind: "org", Name: org.Name, OrgID: query.Result.Id, File: filename})
+
+		for externalID, orgID := range provisioned {
+			if orgID == query.Result.Id {
+				delete(provisioned, externalID)
+			}
+		}
+	}
+
+	return nil
+}
+
+// reportRemovedOrgs logs the orgs that were provisioned before but are no longer in the config. Those are
+// kept, since deleting an org deletes everything in it; they have to be listed in `deleteOrgs` to go away.
+func (op *OrgProvisioner) reportRemovedOrgs(provisioned map[string]int64, declared map[string]bool) {
+	for externalID, orgID := range provisioned {
+		if !declared[externalID] {
+			op.log.Warn("org was removed from provisioning config but is not deleted", "orgId", orgID, "externalId", externalID)
+		}
+	}
+}
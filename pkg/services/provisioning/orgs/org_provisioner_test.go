@@ -0,0 +1,196 @@
+package orgs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	twoOrgsConfig             = "testdata/two-orgs"
+	renamedOrgConfig          = "testdata/renamed"
+	deleteOrgsConfig          = "testdata/delete-org"
+	missingNameConfig         = "testdata/missing-name"
+	duplicateExternalIDConfig = "testdata/duplicate-external-id"
+)
+
+func TestOrgProvisioner(t *testing.T) {
+	t.Run("Creates missing orgs and tracks them by external ID", func(t *testing.T) {
+		repo := setupFakeRepository(t)
+
+		require.NoError(t, newTestProvisioner().applyChanges(context.Background(), twoOrgsConfig))
+
+		require.Len(t, repo.created, 2)
+		assert.Equal(t, repo.orgByName("Customer A").Id, repo.provisioned["customer-a"])
+		assert.Equal(t, repo.orgByName("Customer B").Id, repo.provisioned["Customer B"])
+	})
+
+	t.Run("Applies preferences", func(t *testing.T) {
+		repo := setupFakeRepository(t)
+
+		require.NoError(t, newTestProvisioner().applyChanges(context.Background(), twoOrgsConfig))
+
+		require.Len(t, repo.savedPreferences, 1)
+		assert.Equal(t, repo.orgByName("Customer A").Id, repo.savedPreferences[0].OrgId)
+		assert.Equal(t, "light", repo.savedPreferences[0].Theme)
+		assert.Equal(t, "utc", repo.savedPreferences[0].Timezone)
+	})
+
+	t.Run("Adopts existing orgs with the same name", func(t *testing.T) {
+		repo := setupFakeRepository(t)
+		existing := repo.addOrg("Customer B")
+
+		require.NoError(t, newTestProvisioner().applyChanges(context.Background(), twoOrgsConfig))
+
+		require.Len(t, repo.created, 1)
+		assert.Equal(t, existing.Id, repo.provisioned["Customer B"])
+	})
+
+	t.Run("Renames orgs with a known external ID", func(t *testing.T) {
+		repo := setupFakeRepository(t)
+		require.NoError(t, newTestProvisioner().applyChanges(context.Background(), twoOrgsConfig))
+		orgID := repo.provisioned["customer-a"]
+
+		require.NoError(t, newTestProvisioner().applyChanges(context.Background(), renamedOrgConfig))
+
+		require.Len(t, repo.created, 2)
+		require.Len(t, repo.updated, 1)
+		assert.Equal(t, orgID, repo.updated[0].OrgId)
+		assert.Equal(t, "Customer A Inc.", repo.updated[0].Name)
+	})
+
+	t.Run("Keeps orgs removed from config", func(t *testing.T) {
+		repo := setupFakeRepository(t)
+		require.NoError(t, newTestProvisioner().applyChanges(context.Background(), twoOrgsConfig))
+
+		require.NoError(t, newTestProvisioner().applyChanges(context.Background(), renamedOrgConfig))
+
+		assert.Empty(t, repo.deleted)
+		assert.Contains(t, repo.provisioned, "Customer B")
+	})
+
+	t.Run("Deletes orgs listed in deleteOrgs", func(t *testing.T) {
+		repo := setupFakeRepository(t)
+		existing := repo.addOrg("Customer B")
+
+		require.NoError(t, newTestProvisioner().applyChanges(context.Background(), deleteOrgsConfig))
+
+		require.Len(t, repo.deleted, 1)
+		assert.Equal(t, existing.Id, repo.deleted[0].Id)
+	})
+
+	t.Run("Fails when an org has no name", func(t *testing.T) {
+		setupFakeRepository(t)
+
+		err := newTestProvisioner().applyChanges(context.Background(), missingNameConfig)
+		assert.EqualError(t, err, "org item 1 in configuration doesn't contain required field name")
+	})
+
+	t.Run("Fails when an external ID is used twice", func(t *testing.T) {
+		setupFakeRepository(t)
+
+		err := newTestProvisioner().applyChanges(context.Background(), duplicateExternalIDConfig)
+		assert.EqualError(t, err, `external ID "customer" is used by more than one provisioned org`)
+	})
+}
+
+func newTestProvisioner() *OrgProvisioner {
+	logger := log.New("fake.log")
+	return &OrgProvisioner{
+		log:         logger,
+		cfgProvider: &configReader{log: logger},
+		store:       utils.BusStore{},
+	}
+}
+
+type fakeRepository struct {
+	orgs             []*models.Org
+	provisioned      map[string]int64
+	created          []*models.CreateOrgCommand
+	updated          []*models.UpdateOrgCommand
+	deleted          []*models.DeleteOrgCommand
+	savedPreferences []*models.SavePreferencesCommand
+}
+
+func (repo *fakeRepository) addOrg(name string) *models.Org {
+	org := &models.Org{Id: int64(len(repo.orgs) + 1), Name: name}
+	repo.orgs = append(repo.orgs, org)
+	return org
+}
+
+func (repo *fakeRepository) orgByName(name string) *models.Org {
+	for _, org := range repo.orgs {
+		if org.Name == name {
+			return org
+		}
+	}
+	return nil
+}
+
+func setupFakeRepository(t *testing.T) *fakeRepository {
+	repo := &fakeRepository{provisioned: map[string]int64{}}
+
+	bus.ClearBusHandlers()
+	t.Cleanup(bus.ClearBusHandlers)
+
+	bus.AddHandler("test", func(cmd *models.CreateOrgCommand) error {
+		repo.created = append(repo.created, cmd)
+		cmd.Result = *repo.addOrg(cmd.Name)
+		return nil
+	})
+	bus.AddHandler("test", func(cmd *models.UpdateOrgCommand) error {
+		repo.updated = append(repo.updated, cmd)
+		for _, org := range repo.orgs {
+			if org.Id == cmd.OrgId {
+				org.Name = cmd.Name
+			}
+		}
+		return nil
+	})
+	bus.AddHandler("test", func(cmd *models.DeleteOrgCommand) error {
+		repo.deleted = append(repo.deleted, cmd)
+		return nil
+	})
+	bus.AddHandler("test", func(query *models.GetOrgByIdQuery) error {
+		for _, org := range repo.orgs {
+			if org.Id == query.Id {
+				query.Result = org
+				return nil
+			}
+		}
+		return models.ErrOrgNotFound
+	})
+	bus.AddHandler("test", func(query *models.GetOrgByNameQuery) error {
+		if org := repo.orgByName(query.Name); org != nil {
+			query.Result = org
+			return nil
+		}
+		return models.ErrOrgNotFound
+	})
+	bus.AddHandler("test", func(cmd *models.SaveProvisionedOrgCommand) error {
+		repo.provisioned[cmd.ExternalId] = cmd.OrgId
+		return nil
+	})
+	bus.AddHandler("test", func(query *models.GetProvisionedOrgsQuery) error {
+		for externalID, orgID := range repo.provisioned {
+			query.Result = append(query.Result, &models.OrgProvisioning{OrgId: orgID, ExternalId: externalID})
+		}
+		return nil
+	})
+	bus.AddHandler("test", func(query *models.GetPreferencesQuery) error {
+		query.Result = &models.Preferences{}
+		return nil
+	})
+	bus.AddHandler("test", func(cmd *models.SavePreferencesCommand) error {
+		repo.savedPreferences = append(repo.savedPreferences, cmd)
+		return nil
+	})
+
+	return repo
+}
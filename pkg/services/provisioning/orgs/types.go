@@ -0,0 +1,81 @@
+package orgs
+
+import "github.com/grafana/grafana/pkg/services/provisioning/values"
+
+// orgsAsConfig is a normalized data object for orgs config data. Any config version should be mappable
+// to this type.
+type orgsAsConfig struct {
+	// Filename is the absolute path of the file the config was read from.
+	Filename   string
+	Orgs       []*orgFromConfig
+	DeleteOrgs []*deleteOrgConfig
+}
+
+type orgFromConfig struct {
+	Name       string
+	ExternalID string
+	Theme      string
+	Timezone   string
+}
+
+// externalID returns the ID the org is tracked by between provisioning runs. Orgs without an explicit
+// external ID are tracked by their name, so they can't be renamed from config.
+func (org *orgFromConfig) externalID() string {
+	if org.ExternalID != "" {
+		return org.ExternalID
+	}
+	return org.Name
+}
+
+type deleteOrgConfig struct {
+	Name string
+}
+
+// orgsAsConfigV0 is a mapping for zero version configs. This is mapped to its normalised version.
+type orgsAsConfigV0 struct {
+	APIVersion int64 `json:"apiVersion" yaml:"apiVersion"`
+
+	Orgs       []*orgFromConfigV0   `json:"orgs" yaml:"orgs"`
+	DeleteOrgs []*deleteOrgConfigV0 `json:"deleteOrgs" yaml:"deleteOrgs"`
+}
+
+type orgFromConfigV0 struct {
+	Name        values.StringValue     `json:"name" yaml:"name"`
+	ExternalID  values.StringValue     `json:"externalId" yaml:"externalId"`
+	Preferences orgPreferencesConfigV0 `json:"preferences" yaml:"preferences"`
+}
+
+type orgPreferencesConfigV0 struct {
+	Theme    values.StringValue `json:"theme" yaml:"theme"`
+	Timezone values.StringValue `json:"timezone" yaml:"timezone"`
+}
+
+type deleteOrgConfigV0 struct {
+	Name values.StringValue `json:"name" yaml:"name"`
+}
+
+// mapToOrgsFromConfig maps config syntax to a normalized orgsAsConfig object. Every version
+// of the config syntax should have this function.
+func (cfg *orgsAsConfigV0) mapToOrgsFromConfig() *orgsAsConfig {
+	r := &orgsAsConfig{}
+	if cfg == nil {
+		return r
+	}
+
+	for _, org := range cfg.Orgs {
+		r.Orgs = append(r.Orgs, &orgFromConfig{
+			Name:       org.Name.Value(),
+			ExternalID: org.ExternalID.Value(),
+			Theme:      org.Preferences.Theme.Value(),
+			Timezone:   org.Preferences.Timezone.Value(),
+		})
+	}
+
+	for _, org := range cfg.DeleteOrgs {
+		r.DeleteOrgs = append(r.DeleteOrgs, &deleteOrgConfig{
+			Name: org.Name.Value(),
+		})
+	}
+
+	return r
+}
@@ -1,6 +1,7 @@
 package plugins
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -9,16 +10,27 @@ import (
 
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/plugins"
-	"gopkg.in/yaml.v2"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 type configReader interface {
 	readConfig(path string) ([]*pluginsAsConfig, error)
+	// validateApp returns an error when app can't be provisioned, like when its plugin isn't installed.
+	validateApp(app *appFromConfig) error
 }
 
 type configReaderImpl struct {
 	log           log.Logger
 	pluginManager plugins.Manager
+	fileFilter    setting.ProvisioningFileFilter
+	// strict rejects unknown fields instead of ignoring them.
+	strict bool
+	// env is what the guards of the files are checked against.
+	env utils.Environment
+	// failFast fails reading the config when an app isn't installed. Otherwise that's left to validateApp, so only
+	// that app fails to provision.
+	failFast bool
 }
 
 func newConfigReader(logger log.Logger, pluginManager plugins.Manager) configReader {
@@ -29,7 +41,7 @@ func (cr *configReaderImpl) readConfig(path string) ([]*pluginsAsConfig, error)
 	var apps []*pluginsAsConfig
 	cr.log.Debug("Looking for plugin provisioning files", "path", path)
 
-	files, err := ioutil.ReadDir(path)
+	files, err := utils.ReadDir(path)
 	if err != nil {
 		cr.log.Error("Failed to read plugin provisioning files from directory", "path", path, "error", err)
 		return apps, nil
@@ -37,6 +49,11 @@ func (cr *configReaderImpl) readConfig(path string) ([]*pluginsAsConfig, error)
 
 	for _, file := range files {
 		if strings.HasSuffix(file.Name(), ".yaml") || strings.HasSuffix(file.Name(), ".yml") {
+			if !cr.fileFilter.Includes(file.Name()) {
+				cr.log.Debug("Skipping excluded plugin provisioning file", "path", path, "file.Name", file.Name())
+				continue
+			}
+
 			cr.log.Debug("Parsing plugin provisioning file", "path", path, "file.Name", file.Name())
 			app, err := cr.parsePluginConfig(path, file)
 			if err != nil {
@@ -56,9 +73,10 @@ func (cr *configReaderImpl) readConfig(path string) ([]*pluginsAsConfig, error)
 
 	checkOrgIDAndOrgName(apps)
 
-	err = cr.validatePluginsConfig(apps)
-	if err != nil {
-		return nil, err
+	if cr.failFast {
+		if err := cr.validatePluginsConfig(apps); err != nil {
+			return nil, err
+		}
 	}
 
 	return apps, nil
@@ -78,12 +96,18 @@ func (cr *configReaderImpl) parsePluginConfig(path string, file os.FileInfo) (*p
 	}
 
 	var cfg *pluginsAsConfigV0
-	err = yaml.Unmarshal(yamlFile, &cfg)
-	if err != nil {
+	decoder := utils.YAMLDecoder{Subsystem: "plugins", Kind: "Plugin", Strict: cr.strict, Environment: cr.env,
+		Log: cr.log}
+	if err := decoder.Decode(filename, yamlFile, &cfg); err != nil {
+		if errors.Is(err, utils.ErrFileSkipped) {
+			return nil, nil
+		}
 		return nil, err
 	}
 
-	return cfg.mapToPluginsFromConfig(), nil
+	plugins := cfg.mapToPluginsFromConfig()
+	plugins.Filename = filename
+	return plugins, nil
 }
 
 func validateRequiredField(apps []*pluginsAsConfig) error {
@@ -113,8 +137,8 @@ func (cr *configReaderImpl) validatePluginsConfig(apps []*pluginsAsConfig) error
 		}
 
 		for _, app := range apps[i].Apps {
-			if !cr.pluginManager.IsAppInstalled(app.PluginID) {
-				return fmt.Errorf("app plugin not installed: %q", app.PluginID)
+			if err := cr.validateApp(app); err != nil {
+				return err
 			}
 		}
 	}
@@ -122,6 +146,13 @@ func (cr *configReaderImpl) validatePluginsConfig(apps []*pluginsAsConfig) error
 	return nil
 }
 
+func (cr *configReaderImpl) validateApp(app *appFromConfig) error {
+	if !cr.pluginManager.IsAppInstalled(app.PluginID) {
+		return fmt.Errorf("app plugin not installed: %q", app.PluginID)
+	}
+	return nil
+}
+
 func checkOrgIDAndOrgName(apps []*pluginsAsConfig) {
 	for i := range apps {
 		for _, app := range apps[i].Apps {
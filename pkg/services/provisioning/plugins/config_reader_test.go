@@ -1,11 +1,13 @@
 package plugins
 
 import (
+	"errors"
 	"os"
 	"testing"
 
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
 	"github.com/stretchr/testify/require"
 )
 
@@ -22,6 +24,11 @@ func TestConfigReader(t *testing.T) {
 		reader := newConfigReader(log.New("test logger"), nil)
 		_, err := reader.readConfig(brokenYaml)
 		require.Error(t, err)
+
+		var fileErr *utils.ProvisioningFileError
+		require.True(t, errors.As(err, &fileErr))
+		require.Equal(t, "plugins", fileErr.Subsystem)
+		require.Equal(t, 3, fileErr.Line)
 	})
 
 	t.Run("Skip invalid directory", func(t *testing.T) {
@@ -32,12 +39,20 @@ func TestConfigReader(t *testing.T) {
 	})
 
 	t.Run("Unknown app plugin should return error", func(t *testing.T) {
-		cfgProvider := newConfigReader(log.New("test logger"), fakePluginManager{})
+		cfgProvider := &configReaderImpl{log: log.New("test logger"), pluginManager: fakePluginManager{}, failFast: true}
 		_, err := cfgProvider.readConfig(unknownApp)
 		require.Error(t, err)
 		require.Equal(t, "app plugin not installed: \"nonexisting\"", err.Error())
 	})
 
+	t.Run("Unknown app plugin is only an error of that app unless failing fast", func(t *testing.T) {
+		cfgProvider := newConfigReader(log.New("test logger"), fakePluginManager{})
+		cfg, err := cfgProvider.readConfig(unknownApp)
+		require.NoError(t, err)
+		require.Len(t, cfg, 1)
+		require.EqualError(t, cfgProvider.validateApp(cfg[0].Apps[0]), "app plugin not installed: \"nonexisting\"")
+	})
+
 	t.Run("Read incorrect properties", func(t *testing.T) {
 		cfgProvider := newConfigReader(log.New("test logger"), nil)
 		_, err := cfgProvider.readConfig(incorrectSettings)
@@ -69,11 +84,13 @@ func TestConfigReader(t *testing.T) {
 			ExpectedOrgID    int64
 			ExpectedOrgName  string
 			ExpectedEnabled  bool
+			ExpectedPinned   bool
 		}{
-			{ExpectedPluginID: "test-plugin", ExpectedOrgID: 2, ExpectedOrgName: "", ExpectedEnabled: true},
-			{ExpectedPluginID: "test-plugin-2", ExpectedOrgID: 3, ExpectedOrgName: "", ExpectedEnabled: false},
-			{ExpectedPluginID: "test-plugin", ExpectedOrgID: 0, ExpectedOrgName: "Org 3", ExpectedEnabled: true},
-			{ExpectedPluginID: "test-plugin-2", ExpectedOrgID: 1, ExpectedOrgName: "", ExpectedEnabled: true},
+			{ExpectedPluginID: "test-plugin", ExpectedOrgID: 2, ExpectedOrgName: "", ExpectedEnabled: true, ExpectedPinned: true},
+			{ExpectedPluginID: "test-plugin-2", ExpectedOrgID: 3, ExpectedOrgName: "", ExpectedEnabled: false, ExpectedPinned: true},
+			{ExpectedPluginID: "test-plugin", ExpectedOrgID: 0, ExpectedOrgName: "Org 3", ExpectedEnabled: true, ExpectedPinned: true},
+			{ExpectedPluginID: "test-plugin-2", ExpectedOrgID: 1, ExpectedOrgName: "", ExpectedEnabled: true, ExpectedPinned: true},
+			{ExpectedPluginID: "test-plugin", ExpectedOrgID: 5, ExpectedOrgName: "", ExpectedEnabled: false, ExpectedPinned: false},
 		}
 
 		for index, tc := range testCases {
@@ -83,6 +100,7 @@ func TestConfigReader(t *testing.T) {
 			require.Equal(t, tc.ExpectedOrgID, app.OrgID)
 			require.Equal(t, tc.ExpectedOrgName, app.OrgName)
 			require.Equal(t, tc.ExpectedEnabled, app.Enabled)
+			require.Equal(t, tc.ExpectedPinned, app.Pinned)
 		}
 	})
 }
@@ -0,0 +1,116 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// Diff compares the apps of the config files in the directories with their settings in the database, without
+// changing anything. Apps provisioning configured before that are in none of the files are listed as removed, only
+// when every directory can be read.
+func Diff(ctx context.Context, configDirectories []string, pluginManager plugins.Manager, fileFilter setting.ProvisioningFileFilter,
+	strict bool) (*utils.Diff, error) {
+	logger := log.New("provisioning.plugins")
+	cr := &configReaderImpl{log: logger, pluginManager: pluginManager, fileFilter: fileFilter, strict: strict,
+		env: utils.EnvironmentFromContext(ctx), failFast: true}
+
+	diff := utils.NewDiff("plugins")
+	type appKey struct {
+		orgID    int64
+		pluginID string
+	}
+	configured := map[appKey]bool{}
+	for _, configDirectory := range configDirectories {
+		configs, err := cr.readConfig(configDirectory)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cfg := range configs {
+			for _, app := range cfg.Apps {
+				orgID, err := appOrgID(ctx, app)
+				if err != nil {
+					return nil, err
+				}
+				configured[appKey{orgID: orgID, pluginID: app.PluginID}] = true
+				object := utils.DiffObject{Kind: "plugin", Name: app.PluginID, OrgID: orgID, File: cfg.Filename}
+
+				query := &models.GetPluginSettingByIdQuery{OrgId: orgID, PluginId: app.PluginID}
+				if err := bus.DispatchCtx(ctx, query); err != nil {
+					if !errors.Is(err, models.ErrPluginSettingNotFound) {
+						return nil, err
+					}
+					diff.Added = append(diff.Added, object)
+					continue
+				}
+
+				fields := appChanges(query.Result, app)
+				if len(fields) > 0 {
+					diff.Changed = append(diff.Changed, utils.ChangedObject{DiffObject: object, Fields: fields})
+				}
+			}
+		}
+	}
+
+	for _, configDirectory := range configDirectories {
+		if _, err := os.Stat(configDirectory); err != nil {
+			return diff, nil
+		}
+	}
+	query := &models.GetProvisionedPluginSettingsQuery{}
+	if err := bus.DispatchCtx(ctx, query); err != nil {
+		return nil, err
+	}
+	for _, mark := range query.Result {
+		if !configured[appKey{orgID: mark.OrgId, pluginID: mark.PluginId}] {
+			diff.Removed = append(diff.Removed, utils.DiffObject{Kind: "plugin", Name: mark.PluginId, OrgID: mark.OrgId})
+		}
+	}
+
+	return diff, nil
+}
+
+// appChanges returns the fields of the stored settings that provisioning app would change.
+func appChanges(existing *models.PluginSetting, app *appFromConfig) utils.FieldChanges {
+	var fields utils.FieldChanges
+	fields.Compare("enabled", existing.Enabled, app.Enabled)
+	fields.Compare("pinned", existing.Pinned, app.Pinned)
+	fields.CompareMap("jsonData", existing.JsonData, app.JSONData)
+
+	// Stored secure settings that aren't in the file are kept.
+	secureJSONData := existing.DecryptedValues()
+	keys := make([]string, 0, len(app.SecureJSONData))
+	for key := range app.SecureJSONData {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		current, ok := secureJSONData[key]
+		fields.CompareSecret("secureJsonData."+key, !ok || current != app.SecureJSONData[key])
+	}
+	return fields
+}
+
+// appOrgID returns the org app is provisioned in, looking it up by name like apply does.
+func appOrgID(ctx context.Context, app *appFromConfig) (int64, error) {
+	if app.OrgID == 0 && app.OrgName != "" {
+		query := &models.GetOrgByNameQuery{Name: app.OrgName}
+		if err := bus.DispatchCtx(ctx, query); err != nil {
+			return 0, err
+		}
+		return query.Result.Id, nil
+	}
+	if app.OrgID < 0 {
+		return 1, nil
+	}
+	return app.OrgID, nil
+}
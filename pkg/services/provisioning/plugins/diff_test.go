@@ -0,0 +1,50 @@
+package plugins
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	t.Cleanup(bus.ClearBusHandlers)
+	bus.AddHandler("test", func(query *models.GetPluginSettingByIdQuery) error {
+		if query.PluginId == "test-plugin" && query.OrgId == 1 {
+			query.Result = &models.PluginSetting{OrgId: 1, PluginId: "test-plugin", Enabled: true, Pinned: true,
+				JsonData: map[string]interface{}{"url": "http://localhost:8080"}}
+			return nil
+		}
+		return models.ErrPluginSettingNotFound
+	})
+	bus.AddHandler("test", func(query *models.GetProvisionedPluginSettingsQuery) error {
+		query.Result = []*models.PluginSettingProvisioning{
+			{OrgId: 1, PluginId: "test-plugin"},
+			{OrgId: 1, PluginId: "removed-app"},
+		}
+		return nil
+	})
+	bus.AddHandler("test", func(cmd *models.UpdatePluginSettingCmd) error {
+		t.Fatal("Diffing doesn't update the settings")
+		return nil
+	})
+
+	pm := fakePluginManager{apps: map[string]*plugins.AppPlugin{"test-plugin": {}, "test-plugin-2": {}}}
+	diff, err := Diff(context.Background(), []string{"./testdata/test-configs/diff"}, pm, setting.ProvisioningFileFilter{}, false)
+	require.NoError(t, err)
+
+	require.Len(t, diff.Added, 1)
+	assert.Equal(t, "test-plugin-2", diff.Added[0].Name)
+	assert.Equal(t, []utils.DiffObject{{Kind: "plugin", Name: "removed-app", OrgID: 1}}, diff.Removed)
+	require.Len(t, diff.Changed, 1)
+	assert.Equal(t, "test-plugin", diff.Changed[0].Name)
+	assert.Equal(t, utils.FieldChanges{
+		{Field: "jsonData.url", Current: "http://localhost:8080", Desired: "http://localhost:3000"},
+	}, diff.Changed[0].Fields)
+}
@@ -0,0 +1,50 @@
+package plugins
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AppError is the error of provisioning a single app in an org.
+type AppError struct {
+	PluginID string
+	// OrgID is 0 when the org of the app, given by OrgName, couldn't be found.
+	OrgID   int64
+	OrgName string
+	// File is the absolute path of the config file the app is in.
+	File string
+	Err  error
+}
+
+func (e *AppError) Error() string {
+	if e.OrgID == 0 && e.OrgName != "" {
+		return fmt.Sprintf("app %q of org %q: %v", e.PluginID, e.OrgName, e.Err)
+	}
+	return fmt.Sprintf("app %q of org %d: %v", e.PluginID, e.OrgID, e.Err)
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// ProvisionError is returned by Provision when some apps failed to provision while the others were provisioned.
+// errors.Is and errors.As look at the error of every failed app.
+type ProvisionError struct {
+	Errors []*AppError
+}
+
+func (e *ProvisionError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d apps failed to provision: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+func (e *ProvisionError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
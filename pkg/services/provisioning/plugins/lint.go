@@ -0,0 +1,53 @@
+package plugins
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// Lint validates the plugin config files in configDirectory without a database. Whether the apps are installed is
+// only checked when pluginManager isn't nil. Unknown fields are an error when strict is set. It returns a LintError
+// for every invalid file.
+func Lint(configDirectory string, pluginManager plugins.Manager, strict bool) ([]utils.LintError, error) {
+	cr := &configReaderImpl{log: log.New("provisioning.plugins"), pluginManager: pluginManager, strict: strict,
+		env: utils.EnvironmentFromContext(context.Background())}
+	files, err := utils.ProvisioningFiles(configDirectory, setting.ProvisioningFileFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	var lintErrors []utils.LintError
+	for _, file := range files {
+		filename, _ := filepath.Abs(filepath.Join(configDirectory, file.Name()))
+		if err := cr.lintFile(configDirectory, file); err != nil {
+			lintErrors = append(lintErrors, utils.NewLintError(filename, err))
+		}
+	}
+	return lintErrors, nil
+}
+
+func (cr *configReaderImpl) lintFile(path string, file os.FileInfo) error {
+	cfg, err := cr.parsePluginConfig(path, file)
+	if err != nil {
+		return err
+	}
+	if cfg == nil {
+		return nil
+	}
+
+	apps := []*pluginsAsConfig{cfg}
+	if err := validateRequiredField(apps); err != nil {
+		return err
+	}
+	checkOrgIDAndOrgName(apps)
+	if cr.pluginManager == nil {
+		return nil
+	}
+	return cr.validatePluginsConfig(apps)
+}
@@ -0,0 +1,50 @@
+package plugins
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	err := os.Setenv("ENABLE_PLUGIN_VAR", "test-plugin")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = os.Unsetenv("ENABLE_PLUGIN_VAR")
+	})
+
+	t.Run("Valid configs don't have lint errors", func(t *testing.T) {
+		lintErrors, err := Lint(correctProperties, nil, false)
+		require.NoError(t, err)
+		assert.Empty(t, lintErrors)
+	})
+
+	t.Run("Only checks that apps are installed with a plugin manager", func(t *testing.T) {
+		lintErrors, err := Lint(unknownApp, nil, false)
+		require.NoError(t, err)
+		assert.Empty(t, lintErrors)
+
+		lintErrors, err = Lint(unknownApp, fakePluginManager{apps: map[string]*plugins.AppPlugin{}}, false)
+		require.NoError(t, err)
+		require.Len(t, lintErrors, 1)
+		assert.EqualError(t, lintErrors[0].Err, "app plugin not installed: \"nonexisting\"")
+	})
+
+	t.Run("Reports every invalid file", func(t *testing.T) {
+		lintErrors, err := Lint(incorrectSettings, nil, false)
+		require.NoError(t, err)
+		require.Len(t, lintErrors, 1)
+
+		filename, _ := filepath.Abs(filepath.Join(incorrectSettings, "incorrect-settings.yaml"))
+		assert.Equal(t, filename, lintErrors[0].File)
+
+		lintErrors, err = Lint(brokenYaml, nil, false)
+		require.NoError(t, err)
+		require.Len(t, lintErrors, 1)
+		assert.Equal(t, 3, lintErrors[0].Line)
+	})
+}
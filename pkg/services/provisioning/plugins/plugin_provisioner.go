@@ -1,83 +1,230 @@
 package plugins
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"os"
 
 	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
-// Provision scans a directory for provisioning config files
-// and provisions the app in those files.
-func Provision(configDirectory string, pluginManager plugins.Manager) error {
+// Store is what apps are provisioned through. Plugin settings are still looked up with bus queries.
+type Store interface {
+	UpdatePluginSetting(ctx context.Context, cmd *models.UpdatePluginSettingCmd) error
+	SaveProvisionedPluginSetting(ctx context.Context, cmd *models.SaveProvisionedPluginSettingCommand) error
+	DeleteProvisionedPluginSetting(ctx context.Context, cmd *models.DeleteProvisionedPluginSettingCommand) error
+}
+
+// Provision scans the directories in order for provisioning config files
+// and provisions the app in those files through store. The apps that were applied are recorded in inventory. With
+// disableRemovedApps, apps that provisioning configured before but that are no longer in any file are disabled.
+// An app that fails to provision doesn't stop the others, the errors of the failed apps are returned as a
+// *ProvisionError, unless failFast is set, which stops at the first failure.
+func Provision(ctx context.Context, configDirectories []string, store Store, pluginManager plugins.Manager, fileFilter setting.ProvisioningFileFilter,
+	strict bool, disableRemovedApps bool, failFast bool, inventory *utils.Inventory) error {
 	logger := log.New("provisioning.plugins")
 	ap := PluginProvisioner{
-		log:         logger,
-		cfgProvider: newConfigReader(logger, pluginManager),
+		log: logger,
+		cfgProvider: &configReaderImpl{log: logger, pluginManager: pluginManager, fileFilter: fileFilter,
+			strict: strict, env: utils.EnvironmentFromContext(ctx), failFast: failFast},
+		store:              store,
+		disableRemovedApps: disableRemovedApps,
+		failFast:           failFast,
+		inventory:          inventory,
 	}
-	return ap.applyChanges(configDirectory)
+	return ap.applyChanges(ctx, configDirectories...)
 }
 
 // PluginProvisioner is responsible for provisioning apps based on
 // configuration read by the `configReader`
 type PluginProvisioner struct {
-	log         log.Logger
-	cfgProvider configReader
+	log                log.Logger
+	cfgProvider        configReader
+	store              Store
+	disableRemovedApps bool
+	failFast           bool
+	inventory          *utils.Inventory
 }
 
-func (ap *PluginProvisioner) apply(cfg *pluginsAsConfig) error {
+// apply provisions the apps of cfg. It returns the apps that were provisioned and the errors of the ones that
+// failed, or only the first error with failFast. Errors of ctx are returned on their own, since no other app can be
+// provisioned with it.
+func (ap *PluginProvisioner) apply(ctx context.Context, cfg *pluginsAsConfig) ([]string, []*AppError, error) {
+	var succeeded []string
+	var failed []*AppError
 	for _, app := range cfg.Apps {
-		if app.OrgID == 0 && app.OrgName != "" {
-			getOrgQuery := &models.GetOrgByNameQuery{Name: app.OrgName}
-			if err := bus.Dispatch(getOrgQuery); err != nil {
-				return err
-			}
-			app.OrgID = getOrgQuery.Result.Id
-		} else if app.OrgID < 0 {
-			app.OrgID = 1
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
 		}
-
-		query := &models.GetPluginSettingByIdQuery{OrgId: app.OrgID, PluginId: app.PluginID}
-		err := bus.Dispatch(query)
-		if err != nil {
-			if !errors.Is(err, models.ErrPluginSettingNotFound) {
-				return err
+		if err := ap.applyApp(ctx, app); err != nil {
+			appErr := &AppError{PluginID: app.PluginID, OrgID: app.OrgID, OrgName: app.OrgName, File: cfg.Filename, Err: err}
+			ap.log.Error("Failed to provision app", "type", app.PluginID, "orgId", app.OrgID, "orgName", app.OrgName,
+				"file", cfg.Filename, "error", err)
+			if ap.failFast {
+				return nil, nil, appErr
 			}
-		} else {
-			app.PluginVersion = query.Result.PluginVersion
+			failed = append(failed, appErr)
+			continue
 		}
+		ap.inventory.Record(utils.ProvisionedObject{Kind: "plugin", Name: app.PluginID, OrgID: app.OrgID, File: cfg.Filename})
+		succeeded = append(succeeded, fmt.Sprintf("%s (org %d)", app.PluginID, app.OrgID))
+	}
+
+	return succeeded, failed, nil
+}
 
-		ap.log.Info("Updating app from configuration ", "type", app.PluginID, "enabled", app.Enabled)
-		cmd := &models.UpdatePluginSettingCmd{
-			OrgId:          app.OrgID,
-			PluginId:       app.PluginID,
-			Enabled:        app.Enabled,
-			Pinned:         app.Pinned,
-			JsonData:       app.JSONData,
-			SecureJsonData: app.SecureJSONData,
-			PluginVersion:  app.PluginVersion,
+func (ap *PluginProvisioner) applyApp(ctx context.Context, app *appFromConfig) error {
+	if err := ap.cfgProvider.validateApp(app); err != nil {
+		return err
+	}
+	if app.OrgID == 0 && app.OrgName != "" {
+		getOrgQuery := &models.GetOrgByNameQuery{Name: app.OrgName}
+		if err := bus.DispatchCtx(ctx, getOrgQuery); err != nil {
+			return err
 		}
-		if err := bus.Dispatch(cmd); err != nil {
+		app.OrgID = getOrgQuery.Result.Id
+	} else if app.OrgID < 0 {
+		app.OrgID = 1
+	}
+
+	query := &models.GetPluginSettingByIdQuery{OrgId: app.OrgID, PluginId: app.PluginID}
+	err := bus.DispatchCtx(ctx, query)
+	if err != nil {
+		if !errors.Is(err, models.ErrPluginSettingNotFound) {
 			return err
 		}
+	} else {
+		app.PluginVersion = query.Result.PluginVersion
 	}
 
-	return nil
+	ap.log.Info("Updating app from configuration ", "type", app.PluginID, "enabled", app.Enabled)
+	cmd := &models.UpdatePluginSettingCmd{
+		OrgId:          app.OrgID,
+		PluginId:       app.PluginID,
+		Enabled:        app.Enabled,
+		Pinned:         app.Pinned,
+		JsonData:       app.JSONData,
+		SecureJsonData: app.SecureJSONData,
+		PluginVersion:  app.PluginVersion,
+	}
+	if err := ap.store.UpdatePluginSetting(ctx, cmd); err != nil {
+		return err
+	}
+	return ap.store.SaveProvisionedPluginSetting(ctx, &models.SaveProvisionedPluginSettingCommand{OrgId: app.OrgID, PluginId: app.PluginID})
 }
 
-func (ap *PluginProvisioner) applyChanges(configPath string) error {
-	configs, err := ap.cfgProvider.readConfig(configPath)
-	if err != nil {
+func (ap *PluginProvisioner) applyChanges(ctx context.Context, configPaths ...string) error {
+	var configs []*pluginsAsConfig
+	for _, configPath := range configPaths {
+		dirConfigs, err := ap.cfgProvider.readConfig(configPath)
+		if err != nil {
+			return err
+		}
+		configs = append(configs, dirConfigs...)
+	}
+
+	var succeeded []string
+	var failed []*AppError
+	for _, cfg := range configs {
+		appsSucceeded, appErrs, err := ap.apply(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		succeeded = append(succeeded, appsSucceeded...)
+		failed = append(failed, appErrs...)
+	}
+
+	if err := ap.releaseRemovedApps(ctx, configPaths, configs); err != nil {
 		return err
 	}
 
+	if len(failed) == 0 {
+		return nil
+	}
+	failedApps := make([]string, 0, len(failed))
+	for _, appErr := range failed {
+		failedApps = append(failedApps, fmt.Sprintf("%s (org %d)", appErr.PluginID, appErr.OrgID))
+	}
+	ap.log.Warn("Some apps failed to provision, the others were provisioned", "succeeded", succeeded, "failed", failedApps)
+	return &ProvisionError{Errors: failed}
+}
+
+// releaseRemovedApps removes the provisioning mark of apps that are no longer in any file, disabling them first
+// with disableRemovedApps. They're never uninstalled, since dashboards may still use their panels.
+func (ap *PluginProvisioner) releaseRemovedApps(ctx context.Context, configPaths []string, configs []*pluginsAsConfig) error {
+	// A missing directory reads as no configs, which would otherwise release every app provisioned from it.
+	for _, configPath := range configPaths {
+		if _, err := os.Stat(configPath); err != nil {
+			ap.log.Debug("Not releasing removed apps, can't read the provisioning directory", "path", configPath, "error", err)
+			return nil
+		}
+	}
+
+	type appKey struct {
+		orgID    int64
+		pluginID string
+	}
+	configured := map[appKey]bool{}
 	for _, cfg := range configs {
-		if err := ap.apply(cfg); err != nil {
+		for _, app := range cfg.Apps {
+			configured[appKey{orgID: app.OrgID, pluginID: app.PluginID}] = true
+		}
+	}
+
+	query := &models.GetProvisionedPluginSettingsQuery{}
+	if err := bus.DispatchCtx(ctx, query); err != nil {
+		return err
+	}
+
+	for _, mark := range query.Result {
+		if configured[appKey{orgID: mark.OrgId, pluginID: mark.PluginId}] {
+			continue
+		}
+
+		if ap.disableRemovedApps {
+			if err := ap.disableApp(ctx, mark.OrgId, mark.PluginId); err != nil {
+				return err
+			}
+		} else {
+			ap.log.Info("App is no longer provisioned, leaving it as it is", "type", mark.PluginId, "orgId", mark.OrgId)
+		}
+
+		cmd := &models.DeleteProvisionedPluginSettingCommand{OrgId: mark.OrgId, PluginId: mark.PluginId}
+		if err := ap.store.DeleteProvisionedPluginSetting(ctx, cmd); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// disableApp disables an app in an org, keeping the rest of its settings.
+func (ap *PluginProvisioner) disableApp(ctx context.Context, orgID int64, pluginID string) error {
+	query := &models.GetPluginSettingByIdQuery{OrgId: orgID, PluginId: pluginID}
+	if err := bus.DispatchCtx(ctx, query); err != nil {
+		if errors.Is(err, models.ErrPluginSettingNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !query.Result.Enabled {
+		return nil
+	}
+
+	ap.log.Info("Disabling app that is no longer provisioned", "type", pluginID, "orgId", orgID)
+	// Secure settings are left out, which keeps the encrypted ones that are stored.
+	return ap.store.UpdatePluginSetting(ctx, &models.UpdatePluginSettingCmd{
+		OrgId:         orgID,
+		PluginId:      pluginID,
+		Enabled:       false,
+		Pinned:        false,
+		JsonData:      query.Result.JsonData,
+		PluginVersion: query.Result.PluginVersion,
+	})
+}
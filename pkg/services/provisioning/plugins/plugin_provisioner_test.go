@@ -1,12 +1,15 @@
 package plugins
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
 	"github.com/stretchr/testify/require"
 )
 
@@ -14,8 +17,8 @@ func TestPluginProvisioner(t *testing.T) {
 	t.Run("Should return error when config reader returns error", func(t *testing.T) {
 		expectedErr := errors.New("test")
 		reader := &testConfigReader{err: expectedErr}
-		ap := PluginProvisioner{log: log.New("test"), cfgProvider: reader}
-		err := ap.applyChanges("")
+		ap := PluginProvisioner{log: log.New("test"), store: utils.BusStore{}, cfgProvider: reader}
+		err := ap.applyChanges(context.Background(), "")
 		require.Equal(t, expectedErr, err)
 	})
 
@@ -45,6 +48,11 @@ func TestPluginProvisioner(t *testing.T) {
 			sentCommands = append(sentCommands, cmd)
 			return nil
 		})
+		var marked []string
+		bus.AddHandler("test", func(cmd *models.SaveProvisionedPluginSettingCommand) error {
+			marked = append(marked, fmt.Sprintf("%d/%s", cmd.OrgId, cmd.PluginId))
+			return nil
+		})
 
 		cfg := []*pluginsAsConfig{
 			{
@@ -57,10 +65,11 @@ func TestPluginProvisioner(t *testing.T) {
 			},
 		}
 		reader := &testConfigReader{result: cfg}
-		ap := PluginProvisioner{log: log.New("test"), cfgProvider: reader}
-		err := ap.applyChanges("")
+		ap := PluginProvisioner{log: log.New("test"), store: utils.BusStore{}, cfgProvider: reader}
+		err := ap.applyChanges(context.Background(), "")
 		require.NoError(t, err)
 		require.Len(t, sentCommands, 4)
+		require.Equal(t, []string{"2/test-plugin", "3/test-plugin-2", "4/test-plugin", "1/test-plugin-2"}, marked)
 
 		testCases := []struct {
 			ExpectedPluginID      string
@@ -85,11 +94,158 @@ func TestPluginProvisioner(t *testing.T) {
 	})
 }
 
+func TestReleaseRemovedApps(t *testing.T) {
+	setup := func(t *testing.T, disableRemovedApps bool) (*PluginProvisioner, *[]*models.UpdatePluginSettingCmd, *[]string) {
+		t.Cleanup(bus.ClearBusHandlers)
+
+		bus.AddHandler("test", func(query *models.GetProvisionedPluginSettingsQuery) error {
+			query.Result = []*models.PluginSettingProvisioning{
+				{OrgId: 1, PluginId: "kept-app"},
+				{OrgId: 1, PluginId: "removed-app"},
+				{OrgId: 2, PluginId: "kept-app"},
+			}
+			return nil
+		})
+		bus.AddHandler("test", func(query *models.GetPluginSettingByIdQuery) error {
+			query.Result = &models.PluginSetting{OrgId: query.OrgId, PluginId: query.PluginId, Enabled: true,
+				JsonData: map[string]interface{}{"url": "http://localhost"}, PluginVersion: "1.0.0"}
+			return nil
+		})
+		updates := []*models.UpdatePluginSettingCmd{}
+		bus.AddHandler("test", func(cmd *models.UpdatePluginSettingCmd) error {
+			updates = append(updates, cmd)
+			return nil
+		})
+		bus.AddHandler("test", func(cmd *models.SaveProvisionedPluginSettingCommand) error { return nil })
+		released := []string{}
+		bus.AddHandler("test", func(cmd *models.DeleteProvisionedPluginSettingCommand) error {
+			released = append(released, fmt.Sprintf("%d/%s", cmd.OrgId, cmd.PluginId))
+			return nil
+		})
+
+		cfg := []*pluginsAsConfig{{Apps: []*appFromConfig{{PluginID: "kept-app", OrgID: 1, Enabled: true}}}}
+		ap := &PluginProvisioner{log: log.New("test"), store: utils.BusStore{}, cfgProvider: &testConfigReader{result: cfg},
+			disableRemovedApps: disableRemovedApps}
+		return ap, &updates, &released
+	}
+
+	t.Run("Leaves apps removed from the config as they are by default", func(t *testing.T) {
+		ap, updates, released := setup(t, false)
+
+		require.NoError(t, ap.applyChanges(context.Background(), t.TempDir()))
+
+		require.Len(t, *updates, 1, "Only the configured app should be updated")
+		require.Equal(t, []string{"1/removed-app", "2/kept-app"}, *released)
+	})
+
+	t.Run("Disables apps removed from the config and keeps their settings", func(t *testing.T) {
+		ap, updates, released := setup(t, true)
+
+		require.NoError(t, ap.applyChanges(context.Background(), t.TempDir()))
+
+		require.Len(t, *updates, 3)
+		for _, cmd := range (*updates)[1:] {
+			require.False(t, cmd.Enabled)
+			require.Equal(t, map[string]interface{}{"url": "http://localhost"}, cmd.JsonData)
+			require.Equal(t, "1.0.0", cmd.PluginVersion)
+			require.Nil(t, cmd.SecureJsonData, "Stored secure settings should be kept")
+		}
+		require.Equal(t, "removed-app", (*updates)[1].PluginId)
+		require.Equal(t, int64(2), (*updates)[2].OrgId)
+		require.Equal(t, []string{"1/removed-app", "2/kept-app"}, *released)
+	})
+
+	t.Run("Doesn't release apps when a directory is missing", func(t *testing.T) {
+		ap, updates, released := setup(t, true)
+
+		require.NoError(t, ap.applyChanges(context.Background(), "testdata/missing"))
+
+		require.Len(t, *updates, 1)
+		require.Empty(t, *released)
+	})
+}
+
+func TestApplyChangesContinuesPastFailingApps(t *testing.T) {
+	setup := func(t *testing.T) (*testConfigReader, *[]string) {
+		t.Cleanup(bus.ClearBusHandlers)
+		bus.AddHandler("test", func(query *models.GetOrgByNameQuery) error {
+			return models.ErrOrgNotFound
+		})
+		bus.AddHandler("test", func(query *models.GetPluginSettingByIdQuery) error {
+			return models.ErrPluginSettingNotFound
+		})
+		var updated []string
+		bus.AddHandler("test", func(cmd *models.UpdatePluginSettingCmd) error {
+			updated = append(updated, fmt.Sprintf("%d/%s", cmd.OrgId, cmd.PluginId))
+			return nil
+		})
+		bus.AddHandler("test", func(cmd *models.SaveProvisionedPluginSettingCommand) error { return nil })
+		bus.AddHandler("test", func(query *models.GetProvisionedPluginSettingsQuery) error { return nil })
+
+		reader := &testConfigReader{
+			result: []*pluginsAsConfig{
+				{
+					Filename: "/etc/grafana/provisioning/plugins/apps.yaml",
+					Apps: []*appFromConfig{
+						{PluginID: "first-app", OrgID: 1, Enabled: true},
+						{PluginID: "missing-org-app", OrgName: "Missing org", Enabled: true},
+						{PluginID: "not-installed-app", OrgID: 1, Enabled: true},
+						{PluginID: "last-app", OrgID: 2, Enabled: true},
+					},
+				},
+			},
+			notInstalled: map[string]bool{"not-installed-app": true},
+		}
+		return reader, &updated
+	}
+
+	t.Run("Provisions the other apps and returns the errors of the failed ones", func(t *testing.T) {
+		reader, updated := setup(t)
+		inventory := utils.NewInventory()
+		ap := PluginProvisioner{log: log.New("test"), store: utils.BusStore{}, cfgProvider: reader, inventory: inventory}
+
+		err := ap.applyChanges(context.Background(), t.TempDir())
+		require.Error(t, err)
+		require.Equal(t, []string{"1/first-app", "2/last-app"}, *updated)
+		require.Equal(t, 2, inventory.Len(), "Only the provisioned apps should be recorded")
+
+		var provisionErr *ProvisionError
+		require.True(t, errors.As(err, &provisionErr))
+		require.Len(t, provisionErr.Errors, 2)
+		require.Equal(t, "missing-org-app", provisionErr.Errors[0].PluginID)
+		require.Equal(t, "Missing org", provisionErr.Errors[0].OrgName)
+		require.Equal(t, "/etc/grafana/provisioning/plugins/apps.yaml", provisionErr.Errors[0].File)
+		require.Equal(t, "not-installed-app", provisionErr.Errors[1].PluginID)
+		require.ErrorIs(t, err, models.ErrOrgNotFound, "The errors of the failed apps should be unwrapped")
+		require.Contains(t, err.Error(), `app "not-installed-app" of org 1: app plugin not installed`)
+	})
+
+	t.Run("Stops at the first failing app when failing fast", func(t *testing.T) {
+		reader, updated := setup(t)
+		ap := PluginProvisioner{log: log.New("test"), store: utils.BusStore{}, cfgProvider: reader, failFast: true}
+
+		err := ap.applyChanges(context.Background(), t.TempDir())
+		require.ErrorIs(t, err, models.ErrOrgNotFound)
+		var appErr *AppError
+		require.True(t, errors.As(err, &appErr))
+		require.Equal(t, "missing-org-app", appErr.PluginID)
+		require.Equal(t, []string{"1/first-app"}, *updated)
+	})
+}
+
 type testConfigReader struct {
-	result []*pluginsAsConfig
-	err    error
+	result       []*pluginsAsConfig
+	err          error
+	notInstalled map[string]bool
 }
 
 func (tcr *testConfigReader) readConfig(path string) ([]*pluginsAsConfig, error) {
 	return tcr.result, tcr.err
 }
+
+func (tcr *testConfigReader) validateApp(app *appFromConfig) error {
+	if tcr.notInstalled[app.PluginID] {
+		return fmt.Errorf("app plugin not installed: %q", app.PluginID)
+	}
+	return nil
+}
@@ -5,7 +5,9 @@ import "github.com/grafana/grafana/pkg/services/provisioning/values"
 // pluginsAsConfig is a normalized data object for plugins config data. Any config version should be mappable.
 // to this type.
 type pluginsAsConfig struct {
-	Apps []*appFromConfig
+	// Filename is the absolute path of the file the config was read from.
+	Filename string
+	Apps     []*appFromConfig
 }
 
 type appFromConfig struct {
@@ -20,16 +22,21 @@ type appFromConfig struct {
 }
 
 type appFromConfigV0 struct {
-	OrgID          values.Int64Value     `json:"org_id" yaml:"org_id"`
-	OrgName        values.StringValue    `json:"org_name" yaml:"org_name"`
-	Type           values.StringValue    `json:"type" yaml:"type"`
-	Disabled       values.BoolValue      `json:"disabled" yaml:"disabled"`
+	OrgID    values.Int64Value  `json:"org_id" yaml:"org_id"`
+	OrgName  values.StringValue `json:"org_name" yaml:"org_name"`
+	Type     values.StringValue `json:"type" yaml:"type"`
+	Disabled values.BoolValue   `json:"disabled" yaml:"disabled"`
+	// Enabled overrides Disabled when it's set.
+	Enabled        *values.BoolValue     `json:"enabled" yaml:"enabled"`
+	Pinned         *values.BoolValue     `json:"pinned" yaml:"pinned"`
 	JSONData       values.JSONValue      `json:"jsonData" yaml:"jsonData"`
 	SecureJSONData values.StringMapValue `json:"secureJsonData" yaml:"secureJsonData"`
 }
 
 // pluginsAsConfigV0 is a mapping for zero version configs. This is mapped to its normalised version.
 type pluginsAsConfigV0 struct {
+	APIVersion int64 `json:"apiVersion" yaml:"apiVersion"`
+
 	Apps []*appFromConfigV0 `json:"apps" yaml:"apps"`
 }
 
@@ -42,12 +49,21 @@ func (cfg *pluginsAsConfigV0) mapToPluginsFromConfig() *pluginsAsConfig {
 	}
 
 	for _, app := range cfg.Apps {
+		enabled := !app.Disabled.Value()
+		if app.Enabled != nil {
+			enabled = app.Enabled.Value()
+		}
+		pinned := true
+		if app.Pinned != nil {
+			pinned = app.Pinned.Value()
+		}
+
 		r.Apps = append(r.Apps, &appFromConfig{
 			OrgID:          app.OrgID.Value(),
 			OrgName:        app.OrgName.Value(),
 			PluginID:       app.Type.Value(),
-			Enabled:        !app.Disabled.Value(),
-			Pinned:         true,
+			Enabled:        enabled,
+			Pinned:         pinned,
 			JSONData:       app.JSONData.Value(),
 			SecureJSONData: app.SecureJSONData.Value(),
 		})
@@ -0,0 +1,145 @@
+package provisioning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// postApplyPayload is the result of a provisioning run passed to the post-apply hooks, as the body of the webhook
+// and in the GF_PROVISIONING_RESULT environment variable of the command.
+type postApplyPayload struct {
+	Kind         string              `json:"kind"`
+	Counts       ProvisionCounts     `json:"counts"`
+	Objects      []ProvisionedObject `json:"objects"`
+	Deleted      []ProvisionedObject `json:"deleted"`
+	Directories  []string            `json:"directories"`
+	DurationMs   int64               `json:"durationMs"`
+	ResumedFiles int                 `json:"resumedFiles"`
+	Timestamp    int64               `json:"timestamp"`
+}
+
+func newPostApplyPayload(kind string, result ProvisionResult) postApplyPayload {
+	payload := postApplyPayload{
+		Kind:         kind,
+		Counts:       result.Counts(),
+		Objects:      result.Objects,
+		Deleted:      result.Deleted,
+		Directories:  result.Directories,
+		DurationMs:   result.Duration.Milliseconds(),
+		ResumedFiles: result.ResumedFiles,
+		Timestamp:    time.Now().Unix(),
+	}
+	// Empty lists are sent as lists rather than null.
+	if payload.Objects == nil {
+		payload.Objects = []ProvisionedObject{}
+	}
+	if payload.Deleted == nil {
+		payload.Deleted = []ProvisionedObject{}
+	}
+	if payload.Directories == nil {
+		payload.Directories = []string{}
+	}
+	return payload
+}
+
+// runPostApplyHooks calls the configured post-apply hooks with the result of a successful run of kind, the webhook
+// first. Each hook is bounded by the hook timeout and stopped when ctx is done. Failures are logged, and the first
+// one is only returned when the hooks are set to fail provisioning.
+func (ps *provisioningServiceImpl) runPostApplyHooks(ctx context.Context, kind string, result ProvisionResult) error {
+	hooks := ps.Cfg.ProvisioningPostApplyHooks
+	if !hooks.Enabled() {
+		return nil
+	}
+
+	body, err := json.Marshal(newPostApplyPayload(kind, result))
+	if err != nil {
+		ps.log.Warn("Failed to create provisioning post-apply hook payload", "kind", kind, "error", err)
+		return ps.postApplyHookError(kind, err)
+	}
+
+	var hookErr error
+	if hooks.WebhookURL != "" {
+		if err := callPostApplyWebhook(ctx, hooks, body); err != nil {
+			ps.log.Warn("Provisioning post-apply webhook failed", "kind", kind, "url", hooks.WebhookURL, "error", err)
+			hookErr = fmt.Errorf("webhook: %w", err)
+		}
+	}
+	if len(hooks.Command) > 0 {
+		output, err := runPostApplyCommand(ctx, hooks, kind, result, body)
+		if err != nil {
+			ps.log.Warn("Provisioning post-apply command failed", "kind", kind, "command", hooks.Command[0],
+				"error", err, "output", output)
+			if hookErr == nil {
+				hookErr = fmt.Errorf("command: %w", err)
+			}
+		} else {
+			ps.log.Debug("Provisioning post-apply command succeeded", "kind", kind, "command", hooks.Command[0],
+				"output", output)
+		}
+	}
+	return ps.postApplyHookError(kind, hookErr)
+}
+
+func (ps *provisioningServiceImpl) postApplyHookError(kind string, err error) error {
+	if err == nil || !ps.Cfg.ProvisioningPostApplyHooks.FailOnError {
+		return nil
+	}
+	return ps.notifyFailure(kind, fmt.Errorf("%s post-apply hook failed: %w", kind, err))
+}
+
+func callPostApplyWebhook(ctx context.Context, hooks setting.ProvisioningPostApplyHooks, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, hooks.Timeout)
+	defer cancel()
+
+	return bus.DispatchCtx(ctx, &models.SendWebhookSync{
+		Url:         hooks.WebhookURL,
+		Body:        string(body),
+		HttpMethod:  hooks.WebhookMethod,
+		HttpHeader:  hooks.WebhookHeaders,
+		ContentType: "application/json",
+	})
+}
+
+// runPostApplyCommand runs the command of the hooks with the environment of Grafana and the result of the run in
+// GF_PROVISIONING_* variables, and returns what it printed. The command is killed once the timeout is reached.
+func runPostApplyCommand(ctx context.Context, hooks setting.ProvisioningPostApplyHooks, kind string,
+	result ProvisionResult, body []byte) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, hooks.Timeout)
+	defer cancel()
+
+	counts := result.Counts()
+	// nolint:gosec
+	// We can ignore the gosec G204 warning on this one because the command comes from the Grafana configuration
+	cmd := exec.CommandContext(ctx, hooks.Command[0], hooks.Command[1:]...)
+	cmd.Env = append(os.Environ(),
+		"GF_PROVISIONING_KIND="+kind,
+		"GF_PROVISIONING_APPLIED="+strconv.Itoa(counts.Applied),
+		"GF_PROVISIONING_CREATED="+strconv.Itoa(counts.Created),
+		"GF_PROVISIONING_UPDATED="+strconv.Itoa(counts.Updated),
+		"GF_PROVISIONING_SKIPPED="+strconv.Itoa(counts.Skipped),
+		"GF_PROVISIONING_DELETED="+strconv.Itoa(counts.Deleted),
+		"GF_PROVISIONING_DURATION_MS="+strconv.FormatInt(result.Duration.Milliseconds(), 10),
+		"GF_PROVISIONING_RESULT="+string(body),
+	)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	if err != nil && ctx.Err() != nil {
+		// The error of a killed command is only its signal.
+		err = ctx.Err()
+	}
+	return strings.TrimSpace(output.String()), err
+}
@@ -0,0 +1,157 @@
+package provisioning
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/datasources"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostApplyHooks(t *testing.T) {
+	setupHooks := func(t *testing.T, hooks setting.ProvisioningPostApplyHooks) *serviceTestStruct {
+		t.Helper()
+		bus.ClearBusHandlers()
+		t.Cleanup(bus.ClearBusHandlers)
+
+		serviceTest := setup()
+		serviceTest.service.Cfg.ProvisioningPostApplyHooks = hooks
+		serviceTest.service.provisionDatasources = func(_ context.Context, opts datasources.ProvisionOptions) error {
+			opts.Inventory.Record(ProvisionedObject{Kind: "datasource", Name: "Prometheus", OrgID: 1, Action: utils.ActionCreated})
+			return nil
+		}
+		return serviceTest
+	}
+	webhook := setting.ProvisioningPostApplyHooks{
+		WebhookURL:     "http://deploy.example.com/hook",
+		WebhookMethod:  http.MethodPut,
+		WebhookHeaders: map[string]string{"Authorization": "Bearer token"},
+		Timeout:        time.Second,
+	}
+
+	t.Run("The webhook is sent the result of a successful run", func(t *testing.T) {
+		serviceTest := setupHooks(t, webhook)
+		var sent []*models.SendWebhookSync
+		bus.AddHandlerCtx("test", func(ctx context.Context, cmd *models.SendWebhookSync) error {
+			sent = append(sent, cmd)
+			return nil
+		})
+
+		require.NoError(t, serviceTest.service.ProvisionDatasources(context.Background()))
+		require.Len(t, sent, 1, "The webhook is called once per run")
+		assert.Equal(t, "http://deploy.example.com/hook", sent[0].Url)
+		assert.Equal(t, http.MethodPut, sent[0].HttpMethod)
+		assert.Equal(t, map[string]string{"Authorization": "Bearer token"}, sent[0].HttpHeader)
+		assert.Equal(t, "application/json", sent[0].ContentType)
+
+		var payload postApplyPayload
+		require.NoError(t, json.Unmarshal([]byte(sent[0].Body), &payload))
+		assert.Equal(t, "datasources", payload.Kind)
+		assert.Equal(t, ProvisionCounts{Applied: 1, Created: 1}, payload.Counts)
+		require.Len(t, payload.Objects, 1)
+		assert.Equal(t, "Prometheus", payload.Objects[0].Name)
+		assert.Empty(t, payload.Deleted)
+	})
+
+	t.Run("Hooks aren't called when the run fails", func(t *testing.T) {
+		serviceTest := setupHooks(t, webhook)
+		serviceTest.service.provisionDatasources = func(context.Context, datasources.ProvisionOptions) error {
+			return errors.New("invalid datasource config")
+		}
+		called := false
+		bus.AddHandlerCtx("test", func(ctx context.Context, cmd *models.SendWebhookSync) error {
+			called = true
+			return nil
+		})
+
+		require.Error(t, serviceTest.service.ProvisionDatasources(context.Background()))
+		assert.False(t, called)
+	})
+
+	t.Run("Failing hooks only fail the run when set to", func(t *testing.T) {
+		serviceTest := setupHooks(t, webhook)
+		bus.AddHandlerCtx("test", func(ctx context.Context, cmd *models.SendWebhookSync) error {
+			return errors.New("connection refused")
+		})
+		observer := newRecordingObserver()
+		serviceTest.service.RegisterObserver(observer)
+
+		require.NoError(t, serviceTest.service.ProvisionDatasources(context.Background()))
+		assert.Contains(t, observer.provisioned, "datasources")
+
+		serviceTest.service.Cfg.ProvisioningPostApplyHooks.FailOnError = true
+		err := serviceTest.service.ProvisionDatasources(context.Background())
+		require.EqualError(t, err, "datasources post-apply hook failed: webhook: connection refused")
+		assert.Contains(t, observer.failed, "datasources")
+	})
+
+	t.Run("The webhook is cancelled after the timeout", func(t *testing.T) {
+		hooks := webhook
+		hooks.Timeout = 50 * time.Millisecond
+		hooks.FailOnError = true
+		serviceTest := setupHooks(t, hooks)
+		bus.AddHandlerCtx("test", func(ctx context.Context, cmd *models.SendWebhookSync) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		err := serviceTest.service.ProvisionDatasources(context.Background())
+		require.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected error %v", err)
+	})
+
+	t.Run("The command is run with the result in its environment", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("The test command is a shell script")
+		}
+		dir := t.TempDir()
+		out := filepath.Join(dir, "out")
+		script := filepath.Join(dir, "hook.sh")
+		content := "#!/bin/sh\necho \"$1 $GF_PROVISIONING_KIND $GF_PROVISIONING_APPLIED $GF_PROVISIONING_CREATED\" > " +
+			out + "\necho \"$GF_PROVISIONING_RESULT\" >> " + out + "\n"
+		require.NoError(t, ioutil.WriteFile(script, []byte(content), 0700))
+		serviceTest := setupHooks(t, setting.ProvisioningPostApplyHooks{
+			Command:     []string{script, "warm"},
+			Timeout:     5 * time.Second,
+			FailOnError: true,
+		})
+
+		require.NoError(t, serviceTest.service.ProvisionDatasources(context.Background()))
+		written, err := ioutil.ReadFile(out)
+		require.NoError(t, err)
+		lines := strings.SplitN(string(written), "\n", 2)
+		require.Len(t, lines, 2)
+		assert.Equal(t, "warm datasources 1 1", lines[0])
+		var payload postApplyPayload
+		require.NoError(t, json.Unmarshal([]byte(lines[1]), &payload))
+		assert.Equal(t, "datasources", payload.Kind)
+	})
+
+	t.Run("The command is killed after the timeout", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("The test command uses sleep")
+		}
+		serviceTest := setupHooks(t, setting.ProvisioningPostApplyHooks{
+			Command:     []string{"sleep", "10"},
+			Timeout:     50 * time.Millisecond,
+			FailOnError: true,
+		})
+
+		start := time.Now()
+		err := serviceTest.service.ProvisionDatasources(context.Background())
+		require.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected error %v", err)
+		assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+	})
+}
@@ -0,0 +1,100 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util/errutil"
+)
+
+// Provisioner provisions resources from the config files in a directory.
+type Provisioner interface {
+	Provision(ctx context.Context, dir string) error
+}
+
+// ProvisionerFactory creates the Provisioner registered for a kind.
+type ProvisionerFactory func(cfg *setting.Cfg) (Provisioner, error)
+
+type registeredProvisioner struct {
+	kind    string
+	factory ProvisionerFactory
+}
+
+// builtinProvisionerKinds are the directories under the provisioning path used by the built-in provisioners.
+var builtinProvisionerKinds = []string{"orgs", "datasources", "plugins", "notifiers", "dashboards", "alerting",
+	"library-panels"}
+
+var (
+	provisionersMutex sync.Mutex
+	provisioners      []registeredProvisioner
+)
+
+// RegisterProvisioner registers a provisioner that runs along with the built-in ones at init, reading its config
+// files from the <kind> directory under the provisioning path. It's meant to be called from an init function.
+// Registering a kind twice, or a kind used by a built-in provisioner, fails the provisioning service init.
+func RegisterProvisioner(kind string, factory ProvisionerFactory) {
+	provisionersMutex.Lock()
+	defer provisionersMutex.Unlock()
+
+	provisioners = append(provisioners, registeredProvisioner{kind: kind, factory: factory})
+}
+
+// getRegisteredProvisioners returns the registered provisioners in registration order, or an error if a kind is
+// registered more than once.
+func getRegisteredProvisioners() ([]registeredProvisioner, error) {
+	provisionersMutex.Lock()
+	defer provisionersMutex.Unlock()
+
+	kinds := map[string]bool{}
+	for _, kind := range builtinProvisionerKinds {
+		kinds[kind] = true
+	}
+
+	for _, p := range provisioners {
+		if p.kind == "" {
+			return nil, errors.New("provisioner registered without a kind")
+		}
+		if kinds[p.kind] {
+			return nil, fmt.Errorf("provisioner for kind %q is already registered", p.kind)
+		}
+		kinds[p.kind] = true
+	}
+
+	return append([]registeredProvisioner(nil), provisioners...), nil
+}
+
+// runRegisteredProvisioners runs the registered provisioners like the built-in ones, through runProvisioner, stopping
+// at the first one that fails. They're limited by the timeout setting, since <kind>_timeout is only read for the
+// built-in kinds.
+func (ps *provisioningServiceImpl) runRegisteredProvisioners(ctx context.Context) error {
+	registered, err := getRegisteredProvisioners()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range registered {
+		provisioner, err := p.factory(ps.Cfg)
+		if err != nil {
+			return ps.notifyFailure(p.kind, errutil.Wrapf(err, "Failed to create %s provisioner", p.kind))
+		}
+
+		kind := p.kind
+		dirs := ps.provisioningDirs(kind)
+		// Registered provisioners don't report the objects they applied, so they have no inventory.
+		err = ps.runProvisioner(ctx, kind, dirs, func(ctx context.Context) (*utils.Inventory, error) {
+			err := forEachDir(dirs, func(dir string) error {
+				return provisioner.Provision(ctx, dir)
+			})
+			return nil, ps.notifyFailure(kind, errutil.Wrapf(err, "%s provisioning error", kind))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
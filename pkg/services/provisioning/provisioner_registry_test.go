@@ -0,0 +1,173 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	plugifaces "github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/provisioning/datasources"
+	"github.com/grafana/grafana/pkg/services/provisioning/notifiers"
+	"github.com/grafana/grafana/pkg/services/provisioning/orgs"
+	"github.com/grafana/grafana/pkg/services/provisioning/plugins"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProvisioner struct {
+	dirs []string
+	err  error
+}
+
+func (p *fakeProvisioner) Provision(ctx context.Context, dir string) error {
+	p.dirs = append(p.dirs, dir)
+	return p.err
+}
+
+type provisionerFunc func(ctx context.Context, dir string) error
+
+func (f provisionerFunc) Provision(ctx context.Context, dir string) error {
+	return f(ctx, dir)
+}
+
+func TestRegisteredProvisioners(t *testing.T) {
+	setupService := func(t *testing.T) *provisioningServiceImpl {
+		t.Helper()
+		t.Cleanup(func() {
+			provisioners = nil
+		})
+
+		noopOrgs := func(context.Context, string, orgs.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error {
+			return nil
+		}
+		noopNotifiers := func(context.Context, []string, notifiers.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error {
+			return nil
+		}
+		service := newProvisioningServiceImpl(nil, noopOrgs, noopNotifiers, nil, nil, nil, nil, nil)
+		service.provisionDatasources = func(context.Context, datasources.ProvisionOptions) error {
+			return nil
+		}
+		service.provisionPlugins = func(context.Context, []string, plugins.Store, plugifaces.Manager, setting.ProvisioningFileFilter, bool, bool, bool, *utils.Inventory) error {
+			return nil
+		}
+		service.Cfg = setting.NewCfg()
+		service.Cfg.ProvisioningPath = "/etc/grafana/provisioning"
+		return service
+	}
+
+	t.Run("Registered provisioner runs with its kind directory", func(t *testing.T) {
+		service := setupService(t)
+		fake := &fakeProvisioner{}
+		var factoryCfg *setting.Cfg
+		RegisterProvisioner("custom-resources", func(cfg *setting.Cfg) (Provisioner, error) {
+			factoryCfg = cfg
+			return fake, nil
+		})
+
+		require.NoError(t, service.RunInitProvisioners(context.Background()))
+		assert.Equal(t, []string{filepath.Join("/etc/grafana/provisioning", "custom-resources")}, fake.dirs)
+		assert.Same(t, service.Cfg, factoryCfg)
+	})
+
+	t.Run("Registered provisioner runs with the kind directory of every provisioning path in order", func(t *testing.T) {
+		service := setupService(t)
+		service.Cfg.ProvisioningPaths = []string{"/etc/grafana/provisioning", "/etc/grafana/overlay"}
+		fake := &fakeProvisioner{}
+		RegisterProvisioner("custom-resources", func(cfg *setting.Cfg) (Provisioner, error) {
+			return fake, nil
+		})
+
+		require.NoError(t, service.RunInitProvisioners(context.Background()))
+		assert.Equal(t, []string{
+			filepath.Join("/etc/grafana/provisioning", "custom-resources"),
+			filepath.Join("/etc/grafana/overlay", "custom-resources"),
+		}, fake.dirs)
+	})
+
+	t.Run("Datasource permissions are applied after the registered provisioners", func(t *testing.T) {
+		service := setupService(t)
+		var order []string
+		service.provisionDatasources = func(context.Context, datasources.ProvisionOptions) error {
+			order = append(order, "datasources")
+			return nil
+		}
+		service.provisionDatasourcePermissions = func(context.Context, []string, accesscontrol.DatasourcePermissionsService, setting.ProvisioningFileFilter, bool) error {
+			order = append(order, "datasource permissions")
+			return nil
+		}
+		RegisterProvisioner("teams", func(*setting.Cfg) (Provisioner, error) {
+			order = append(order, "teams")
+			return &fakeProvisioner{}, nil
+		})
+
+		require.NoError(t, service.RunInitProvisioners(context.Background()))
+		assert.Equal(t, []string{"datasources", "teams", "datasource permissions"}, order)
+
+		order = nil
+		require.NoError(t, service.ProvisionDatasources(context.Background()))
+		assert.Equal(t, []string{"datasources", "datasource permissions"}, order,
+			"Datasource permissions should follow datasources provisioned after init")
+	})
+
+	t.Run("Failing registered provisioner fails init", func(t *testing.T) {
+		service := setupService(t)
+		RegisterProvisioner("custom-resources", func(*setting.Cfg) (Provisioner, error) {
+			return &fakeProvisioner{err: errors.New("invalid config")}, nil
+		})
+
+		err := service.RunInitProvisioners(context.Background())
+		require.EqualError(t, err, "custom-resources provisioning error: invalid config")
+	})
+
+	t.Run("Registered provisioner is limited by the timeout", func(t *testing.T) {
+		service := setupService(t)
+		service.Cfg.ProvisioningTimeout = 50 * time.Millisecond
+		RegisterProvisioner("custom-resources", func(*setting.Cfg) (Provisioner, error) {
+			return provisionerFunc(func(ctx context.Context, _ string) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}), nil
+		})
+
+		err := service.RunInitProvisioners(context.Background())
+		require.True(t, errors.Is(err, ErrProvisioningTimeout))
+		assert.Contains(t, err.Error(), "custom-resources provisioning timed out after 50ms")
+	})
+
+	t.Run("Failing provisioner factory fails init", func(t *testing.T) {
+		service := setupService(t)
+		RegisterProvisioner("custom-resources", func(*setting.Cfg) (Provisioner, error) {
+			return nil, errors.New("missing setting")
+		})
+
+		err := service.RunInitProvisioners(context.Background())
+		require.EqualError(t, err, "Failed to create custom-resources provisioner: missing setting")
+	})
+
+	t.Run("Duplicate kind fails init", func(t *testing.T) {
+		service := setupService(t)
+		first := &fakeProvisioner{}
+		RegisterProvisioner("custom-resources", func(*setting.Cfg) (Provisioner, error) { return first, nil })
+		RegisterProvisioner("custom-resources", func(*setting.Cfg) (Provisioner, error) { return &fakeProvisioner{}, nil })
+
+		err := service.RunInitProvisioners(context.Background())
+		require.EqualError(t, err, `provisioner for kind "custom-resources" is already registered`)
+		assert.Empty(t, first.dirs)
+	})
+
+	for _, kind := range []string{"datasources", "library-panels"} {
+		t.Run("Kind of the built-in "+kind+" provisioner fails init", func(t *testing.T) {
+			service := setupService(t)
+			RegisterProvisioner(kind, func(*setting.Cfg) (Provisioner, error) { return &fakeProvisioner{}, nil })
+
+			err := service.RunInitProvisioners(context.Background())
+			require.EqualError(t, err, fmt.Sprintf("provisioner for kind %q is already registered", kind))
+		})
+	}
+}
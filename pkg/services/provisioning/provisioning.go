@@ -2,30 +2,88 @@ package provisioning
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
 	"path/filepath"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/grafana/grafana/pkg/infra/log"
 	plugifaces "github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/plugins/backendplugin"
 	"github.com/grafana/grafana/pkg/registry"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/provisioning/alerting"
 	"github.com/grafana/grafana/pkg/services/provisioning/dashboards"
 	"github.com/grafana/grafana/pkg/services/provisioning/datasources"
+	"github.com/grafana/grafana/pkg/services/provisioning/librarypanels"
 	"github.com/grafana/grafana/pkg/services/provisioning/notifiers"
+	"github.com/grafana/grafana/pkg/services/provisioning/orgs"
 	"github.com/grafana/grafana/pkg/services/provisioning/plugins"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util/errutil"
+	"github.com/opentracing/opentracing-go"
 )
 
+// ProvisioningFileError is returned, wrapped, by the provisioning methods when a provisioning file can't be
+// parsed. Use errors.As to get the file and location of the error.
+type ProvisioningFileError = utils.ProvisioningFileError
+
+// ProvisionedObject is an object that provisioning applied from a file, as listed by GetProvisionedInventory.
+type ProvisionedObject = utils.ProvisionedObject
+
+// ProvisioningInfo tells which dashboard provider and file a provisioned dashboard comes from.
+type ProvisioningInfo = dashboards.ProvisioningInfo
+
+// ErrProviderNotFound is returned, wrapped, by ReprovisionProvider when there is no dashboard provider with the
+// requested name.
+var ErrProviderNotFound = dashboards.ErrProviderNotFound
+
+// ErrMissingProvisioningDir is returned, wrapped with the path, by a Provision method when fail_on_missing_dir is
+// set and a directory the method reads doesn't exist.
+var ErrMissingProvisioningDir = errors.New("provisioning directory doesn't exist")
+
+// ProvisioningService provisions Grafana from the files in the provisioning directories. Calls to the same
+// Provision method, or to Reload, never overlap: a call made while one is running waits for a single extra run,
+// shared by every call made in the meantime, and returns its result. Canceling the context of a Provision method
+// stops provisioning before the next database call; the shared extra run uses the context of the first call that
+// queued it. A Provision method that doesn't finish within the timeout of its subsystem returns
+// ErrProvisioningTimeout.
 type ProvisioningService interface {
 	registry.BackgroundService
-	RunInitProvisioners() error
-	ProvisionDatasources() error
-	ProvisionPlugins() error
-	ProvisionNotifications() error
-	ProvisionDashboards() error
+	RunInitProvisioners(ctx context.Context) error
+	ProvisionNow(ctx context.Context) error
+	ProvisionOrgs(ctx context.Context) error
+	ProvisionDatasources(ctx context.Context) error
+	ProvisionPlugins(ctx context.Context) error
+	ProvisionNotifications(ctx context.Context) error
+	ProvisionDatasourcesFromReader(ctx context.Context, orgID int64, r io.Reader) (*ProvisionResult, error)
+	ProvisionNotificationsFromReader(ctx context.Context, orgID int64, r io.Reader) (*ProvisionResult, error)
+	ProvisionLibraryPanels(ctx context.Context) error
+	ProvisionDashboards(ctx context.Context) error
+	ReprovisionProvider(ctx context.Context, name string) error
+	ProvisionDeferredDashboard(ctx context.Context, orgID int64, uid string) (bool, error)
+	ProvisionAlertRules(ctx context.Context) error
+	ProvisionAlertNotifications(ctx context.Context) error
 	GetDashboardProvisionerResolvedPath(name string) string
 	GetAllowUIUpdatesFromConfig(name string) bool
+	GetAllowUIUpdatesMap() map[string]bool
+	GetProvisioningInfo(dashboardUID string) (*ProvisioningInfo, bool)
+	GetProvisionedInventory() []ProvisionedObject
+	Health() error
+	SelfTest(ctx context.Context) error
+	ExportProvisioningState(ctx context.Context) ([]byte, error)
+	ImportProvisioningState(ctx context.Context, data []byte) error
+	DiffProvisioning(ctx context.Context, kind string) (*ProvisioningDiff, error)
+	ReloadProvisioning(ctx context.Context, kind string) (*ProvisionResult, error)
+	ExportProvisioning(ctx context.Context, kind string, outDir string, provisionedOnly bool) error
+	RegisterObserver(observer ProvisioningObserver)
 }
 
 func init() {
@@ -39,83 +97,257 @@ func init() {
 // Add a public constructor for overriding service to be able to instantiate OSS as fallback
 func NewProvisioningServiceImpl() *provisioningServiceImpl {
 	return &provisioningServiceImpl{
-		log:                     log.New("provisioning"),
-		newDashboardProvisioner: dashboards.New,
-		provisionNotifiers:      notifiers.Provision,
-		provisionDatasources:    datasources.Provision,
-		provisionPlugins:        plugins.Provision,
+		log:                         log.New("provisioning"),
+		newDashboardProvisioner:     dashboards.New,
+		provisionOrgs:               orgs.Provision,
+		provisionNotifiers:          notifiers.Provision,
+		provisionDatasources:        datasources.Provision,
+		provisionPlugins:            plugins.Provision,
+		provisionAlertRules:         alerting.ProvisionRules,
+		provisionAlertNotifications: alerting.ProvisionNotifications,
+		provisionLibraryPanels:      librarypanels.Provision,
+		certFilesChanged:            datasources.CertFilesChanged,
+		secretResolver:              utils.RegisteredSecretResolver(),
+		dashboardParseCaches:        dashboards.NewParseCaches(),
+		// It applies the permissions with the injected DatasourcePermissions service.
+		provisionDatasourcePermissions: datasources.ProvisionPermissions,
 	}
 }
 
 // Used for testing purposes
 func newProvisioningServiceImpl(
 	newDashboardProvisioner dashboards.DashboardProvisionerFactory,
-	provisionNotifiers func(string) error,
-	provisionDatasources func(string) error,
-	provisionPlugins func(string, plugifaces.Manager) error,
+	provisionOrgs func(context.Context, string, orgs.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error,
+	provisionNotifiers func(context.Context, []string, notifiers.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error,
+	provisionDatasources func(context.Context, datasources.ProvisionOptions) error,
+	provisionPlugins func(context.Context, []string, plugins.Store, plugifaces.Manager, setting.ProvisioningFileFilter, bool, bool, bool, *utils.Inventory) error,
+	provisionAlertRules func(context.Context, string, alerting.RuleStore, setting.ProvisioningFileFilter, bool, *utils.Inventory) error,
+	provisionAlertNotifications func(context.Context, string, alerting.NotificationStore, setting.ProvisioningFileFilter, bool, setting.ProvisioningCleanupGuard, *utils.Inventory) error,
+	provisionLibraryPanels func(context.Context, []string, librarypanels.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error,
 ) *provisioningServiceImpl {
 	return &provisioningServiceImpl{
-		log:                     log.New("provisioning"),
-		newDashboardProvisioner: newDashboardProvisioner,
-		provisionNotifiers:      provisionNotifiers,
-		provisionDatasources:    provisionDatasources,
-		provisionPlugins:        provisionPlugins,
+		log:                         log.New("provisioning"),
+		newDashboardProvisioner:     newDashboardProvisioner,
+		provisionOrgs:               provisionOrgs,
+		provisionNotifiers:          provisionNotifiers,
+		provisionDatasources:        provisionDatasources,
+		provisionPlugins:            provisionPlugins,
+		provisionAlertRules:         provisionAlertRules,
+		provisionAlertNotifications: provisionAlertNotifications,
+		provisionLibraryPanels:      provisionLibraryPanels,
+		certFilesChanged:            datasources.CertFilesChanged,
+		secretResolver:              utils.RegisteredSecretResolver(),
+		dashboardParseCaches:        dashboards.NewParseCaches(),
+		// It applies the permissions with the injected DatasourcePermissions service.
+		provisionDatasourcePermissions: datasources.ProvisionPermissions,
 	}
 }
 
 type provisioningServiceImpl struct {
-	Cfg                     *setting.Cfg       `inject:""`
-	SQLStore                *sqlstore.SQLStore `inject:""`
-	PluginManager           plugifaces.Manager `inject:""`
-	log                     log.Logger
-	pollingCtxCancel        context.CancelFunc
-	newDashboardProvisioner dashboards.DashboardProvisionerFactory
-	dashboardProvisioner    dashboards.DashboardProvisioner
-	provisionNotifiers      func(string) error
-	provisionDatasources    func(string) error
-	provisionPlugins        func(string, plugifaces.Manager) error
-	mutex                   sync.Mutex
+	Cfg                         *setting.Cfg          `inject:""`
+	SQLStore                    *sqlstore.SQLStore    `inject:""`
+	PluginManager               plugifaces.Manager    `inject:""`
+	BackendPluginManager        backendplugin.Manager `inject:""`
+	log                         log.Logger
+	pollingCtxCancel            context.CancelFunc
+	newDashboardProvisioner     dashboards.DashboardProvisionerFactory
+	dashboardProvisioner        dashboards.DashboardProvisioner
+	provisionOrgs               func(context.Context, string, orgs.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error
+	provisionNotifiers          func(context.Context, []string, notifiers.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error
+	provisionDatasources        func(context.Context, datasources.ProvisionOptions) error
+	provisionPlugins            func(context.Context, []string, plugins.Store, plugifaces.Manager, setting.ProvisioningFileFilter, bool, bool, bool, *utils.Inventory) error
+	provisionAlertRules         func(context.Context, string, alerting.RuleStore, setting.ProvisioningFileFilter, bool, *utils.Inventory) error
+	provisionAlertNotifications func(context.Context, string, alerting.NotificationStore, setting.ProvisioningFileFilter, bool, setting.ProvisioningCleanupGuard, *utils.Inventory) error
+	provisionLibraryPanels      func(context.Context, []string, librarypanels.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error
+	certFilesChanged            func() bool
+	// secretResolver resolves the secret references of the provisioning files.
+	secretResolver SecretResolver
+	// DatasourcePermissions applies the permissions blocks of the provisioned datasources with
+	// provisionDatasourcePermissions.
+	DatasourcePermissions          accesscontrol.DatasourcePermissionsService `inject:""`
+	provisionDatasourcePermissions func(context.Context, []string, accesscontrol.DatasourcePermissionsService, setting.ProvisioningFileFilter, bool) error
+	// dashboardParseCaches keep what was parsed from the dashboard files across the dashboard provisioners, which
+	// are created again on every reload.
+	dashboardParseCaches *dashboards.ParseCaches
+	// databasePing replaces the GetDBHealthQuery of the SQL store when it's set, for the self-test that runs on its
+	// own.
+	databasePing func(context.Context) error
+	// store is what the provisioners write through, built from SQLStore on first use unless it's set.
+	store ProvisioningStore
+	// pollSettings are the dashboard poll settings as of the last Reload, nil until then.
+	pollSettings *setting.ProvisioningPollSettings
+	// inventory holds the objects applied by the last run of each provisioner but the dashboards one, by kind of
+	// provisioner.
+	inventory map[string][]ProvisionedObject
+	mutex     sync.Mutex
+	// cycleMutex keeps full provisioning cycles, the ones of Init and the first pass of Run and ProvisionNow, from
+	// overlapping. It's held around calls that take mutex, so it's always taken first.
+	cycleMutex sync.Mutex
+	// runs counts the runs of the provisioners of inventory, and inventoryRuns holds the run whose objects inventory
+	// holds, by kind of provisioner.
+	runs          map[string]uint64
+	inventoryRuns map[string]uint64
+	// dashboardsRun is the run of ProvisionDashboards whose provisioner is dashboardProvisioner, so an abandoned run
+	// doesn't replace the provisioner of a later one. dashboardRuns counts the runs that haven't returned, and
+	// dashboardRunsDone is closed once they all have. The Run loop waits for it before it polls again, so the old
+	// provisioner doesn't poll while a new one provisions.
+	dashboardsRun     uint64
+	dashboardRuns     int
+	dashboardRunsDone chan struct{}
+	// initProvisioned and dashboardsProvisioned are set to 1 once the init provisioners and the first dashboard
+	// provisioning in Run have succeeded.
+	initProvisioned       int32
+	dashboardsProvisioned int32
+	// polling holds the pollingProvisioner whose changes Run polls for, so Health doesn't wait for the mutex
+	// that provisioning holds.
+	polling atomic.Value
+	// coalescers keep the runs of each provisioner from overlapping, by provisioner name.
+	coalescers map[string]*coalescer
+	// abandonedRuns are closed once the run the timeout abandoned returns, by subsystem. They have their own mutex
+	// since a run may be abandoned while provisioning holds the other one.
+	abandonedMutex sync.Mutex
+	abandonedRuns  map[string]chan struct{}
+	// observers have their own mutex since they're notified while provisioning holds the other one.
+	observersMutex sync.Mutex
+	observers      []ProvisioningObserver
+	// report holds the last run of each subsystem for the provisioning report, by subsystem, and results the last
+	// successful run of each provisioner for ReloadProvisioning, by provisioner name.
+	reportMutex sync.Mutex
+	report      map[string]subsystemReport
+	results     map[string]ProvisionResult
+	// ctx is the context of Run, which the reloads run with so they don't depend on the context of their caller,
+	// nil until Run started.
+	ctx context.Context
 }
 
 func (ps *provisioningServiceImpl) Init() error {
-	return ps.RunInitProvisioners()
+	ps.cycleMutex.Lock()
+	defer ps.cycleMutex.Unlock()
+
+	// Services are initialized before the server context exists, and the server doesn't start before Init returns.
+	return ps.RunInitProvisioners(context.Background())
+}
+
+// ProvisionNow runs the init provisioners, then provisions the library panels, dashboards and alert rules once, and
+// returns when all of them are done. Unlike Run, it doesn't poll for changes afterwards. It waits for the cycles of
+// Init and the first pass of Run, and can be called while Run polls, which then polls the dashboards it provisioned.
+func (ps *provisioningServiceImpl) ProvisionNow(ctx context.Context) error {
+	ps.cycleMutex.Lock()
+	defer ps.cycleMutex.Unlock()
+
+	if err := ps.RunInitProvisioners(ctx); err != nil {
+		return err
+	}
+	return ps.runStages(ctx)
 }
 
-func (ps *provisioningServiceImpl) RunInitProvisioners() error {
-	err := ps.ProvisionDatasources()
+func (ps *provisioningServiceImpl) RunInitProvisioners(ctx context.Context) (err error) {
+	// The provisioners run in child spans of this one, so a startup trace shows them as a single run.
+	span, ctx := opentracing.StartSpanFromContext(ctx, "provisioning init")
+	defer func() {
+		utils.FinishSpan(span, err)
+		ps.writeReport(reportStageInit, err)
+	}()
+
+	// The database is waited for once up front, rather than by every stage, so a database that comes and goes
+	// while starting doesn't leave provisioning half applied.
+	err = ps.waitForDatabase(ctx)
 	if err != nil {
 		return err
 	}
 
-	err = ps.ProvisionPlugins()
+	// Orgs go first since everything provisioned after them is org scoped and may reference them.
+	err = ps.ProvisionOrgs(ctx)
 	if err != nil {
 		return err
 	}
 
-	err = ps.ProvisionNotifications()
+	stages := map[string]func(context.Context) error{
+		"datasources":         ps.ProvisionDatasources,
+		"plugins":             ps.ProvisionPlugins,
+		"notifiers":           ps.ProvisionNotifications,
+		"alert_notifications": ps.ProvisionAlertNotifications,
+	}
+	for _, stage := range ps.initOrder() {
+		provision, ok := stages[stage]
+		if !ok {
+			return fmt.Errorf("unknown provisioning stage %q", stage)
+		}
+		if err := provision(ctx); err != nil {
+			return err
+		}
+	}
+
+	err = ps.runRegisteredProvisioners(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Datasource permissions go last since they reference teams, which registered provisioners may create.
+	err = ps.applyDatasourcePermissions(ctx)
 	if err != nil {
 		return err
 	}
 
+	atomic.StoreInt32(&ps.initProvisioned, 1)
 	return nil
 }
 
+// initOrder returns the order of the stages run after the orgs, the default one when the settings don't have any.
+func (ps *provisioningServiceImpl) initOrder() []string {
+	if len(ps.Cfg.ProvisioningOrder) == 0 {
+		return setting.ProvisioningInitStages
+	}
+	return ps.Cfg.ProvisioningOrder
+}
+
 func (ps *provisioningServiceImpl) Run(ctx context.Context) error {
-	err := ps.ProvisionDashboards()
+	ps.mutex.Lock()
+	ps.ctx = ctx
+	ps.mutex.Unlock()
+
+	ps.cycleMutex.Lock()
+	err := ps.runStages(ctx)
+	ps.cycleMutex.Unlock()
 	if err != nil {
-		ps.log.Error("Failed to provision dashboard", "error", err)
 		return err
 	}
 
+	if ps.Cfg.ProvisioningApplyOnce {
+		ps.log.Info("Provisioning was applied once, stopping Grafana")
+		return fmt.Errorf("%w: provisioning was applied once", registry.ErrStopServer)
+	}
+
+	if ps.Cfg.ProvisioningPollingWatchdogTimeout > 0 {
+		go ps.watchPolling(ctx, ps.Cfg.ProvisioningPollingWatchdogTimeout)
+	}
+
+	if ps.Cfg.ProvisioningDatasourcesCertCheckInterval > 0 {
+		go ps.watchCertFiles(ctx, ps.Cfg.ProvisioningDatasourcesCertCheckInterval)
+	}
+
 	for {
-		// Wait for unlock. This is tied to new dashboardProvisioner to be instantiated before we start polling.
 		ps.mutex.Lock()
+		if done := ps.dashboardRunsDone; done != nil {
+			// Wait for the dashboards to be provisioned, so polling starts with the new dashboardProvisioner.
+			ps.mutex.Unlock()
+			select {
+			case <-done:
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
 		// Using background here because otherwise if root context was canceled the select later on would
 		// non-deterministically take one of the route possibly going into one polling loop before exiting.
 		pollingContext, cancelFun := context.WithCancel(context.Background())
 		ps.pollingCtxCancel = cancelFun
-		ps.dashboardProvisioner.PollChanges(pollingContext)
+		dashboardProvisioner := ps.dashboardProvisioner
 		ps.mutex.Unlock()
+		ps.polling.Store(pollingProvisioner{dashboardProvisioner})
+
+		// Not holding the mutex while starting to poll so the watchdog can still cancel a hanging PollChanges.
+		dashboardProvisioner.PollChanges(pollingContext)
 
 		select {
 		case <-pollingContext.Done():
@@ -123,61 +355,629 @@ func (ps *provisioningServiceImpl) Run(ctx context.Context) error {
 			continue
 		case <-ctx.Done():
 			// Root server context was cancelled so cancel polling and leave.
+			ps.mutex.Lock()
 			ps.cancelPolling()
+			ps.mutex.Unlock()
 			return ctx.Err()
 		}
 	}
 }
 
-func (ps *provisioningServiceImpl) ProvisionDatasources() error {
-	datasourcePath := filepath.Join(ps.Cfg.ProvisioningPath, "datasources")
-	err := ps.provisionDatasources(datasourcePath)
-	return errutil.Wrap("Datasource provisioning error", err)
+// runStages provisions the library panels, the dashboards and the alert rules, the stages Run goes through before it
+// starts polling.
+func (ps *provisioningServiceImpl) runStages(ctx context.Context) error {
+	// Library panels go before the dashboards, so the library panels the dashboards reference exist.
+	err := ps.ProvisionLibraryPanels(ctx)
+	if err != nil {
+		ps.log.Error("Failed to provision library panels", "error", err)
+		ps.writeReport(reportStageRun, err)
+		return err
+	}
+
+	err = ps.ProvisionDashboards(ctx)
+	if err != nil {
+		ps.log.Error("Failed to provision dashboard", "error", err)
+		ps.writeReport(reportStageRun, err)
+		return err
+	}
+	atomic.StoreInt32(&ps.dashboardsProvisioned, 1)
+
+	// Alert rules are provisioned after the dashboards, since they live in folders that may be provisioned
+	// along with them.
+	err = ps.ProvisionAlertRules(ctx)
+	ps.writeReport(reportStageRun, err)
+	if err != nil {
+		ps.log.Error("Failed to provision alert rules", "error", err)
+		return err
+	}
+	return nil
 }
 
-func (ps *provisioningServiceImpl) ProvisionPlugins() error {
-	appPath := filepath.Join(ps.Cfg.ProvisioningPath, "plugins")
-	err := ps.provisionPlugins(appPath, ps.PluginManager)
-	return errutil.Wrap("app provisioning error", err)
+// pollingProvisioner wraps the dashboard provisioner that is polling, since an atomic.Value can't hold interface
+// values of different types.
+type pollingProvisioner struct {
+	dashboards.DashboardProvisioner
 }
 
-func (ps *provisioningServiceImpl) ProvisionNotifications() error {
-	alertNotificationsPath := filepath.Join(ps.Cfg.ProvisioningPath, "notifiers")
-	err := ps.provisionNotifiers(alertNotificationsPath)
-	return errutil.Wrap("Alert notification provisioning error", err)
+// Health returns nil once the init provisioners and the first dashboard provisioning have succeeded, so readiness
+// probes don't route traffic to an instance that is still being provisioned. It fails again while polling for
+// dashboard changes keeps failing, and recovers with it.
+func (ps *provisioningServiceImpl) Health() error {
+	if atomic.LoadInt32(&ps.initProvisioned) == 0 {
+		return errors.New("initial provisioning hasn't completed")
+	}
+	if atomic.LoadInt32(&ps.dashboardsProvisioned) == 0 {
+		return errors.New("dashboards haven't been provisioned yet")
+	}
+	if polling, ok := ps.polling.Load().(pollingProvisioner); ok {
+		return polling.PollingFailed()
+	}
+	return nil
 }
 
-func (ps *provisioningServiceImpl) ProvisionDashboards() error {
-	dashboardPath := filepath.Join(ps.Cfg.ProvisioningPath, "dashboards")
-	dashProvisioner, err := ps.newDashboardProvisioner(dashboardPath, ps.SQLStore)
+// watchPolling restarts dashboard polling with a fresh provisioner whenever the current one hasn't finished a
+// polling cycle within timeout.
+func (ps *provisioningServiceImpl) watchPolling(ctx context.Context, timeout time.Duration) {
+	ticker := time.NewTicker(timeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ps.mutex.Lock()
+			stalled := ps.dashboardProvisioner.PollingStalled(timeout)
+			ps.mutex.Unlock()
+
+			if stalled {
+				ps.log.Warn("Dashboard polling is stuck, restarting it", "timeout", timeout)
+				ps.restartPolling(ctx)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// restartPolling cancels the current polling context and swaps in a fresh dashboard provisioner. The new
+// provisioner isn't provisioned upfront since that is what may hang, its polling loop picks up changes instead.
+func (ps *provisioningServiceImpl) restartPolling(ctx context.Context) {
+	dashProvisioner, err := ps.newDashboardProvisioner(ps.withEnvironment(ctx), ps.orgScopedDirs("dashboards"), ps.provisioningStore(), ps.dashboardsCfg())
+
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
 	if err != nil {
-		return errutil.Wrap("Failed to create provisioner", err)
+		ps.log.Error("Failed to create provisioner, restarting polling with the current one", "error", err)
+	} else {
+		dashProvisioner.SetDriftHandler(ps.notifyDashboardDrift)
+		ps.dashboardProvisioner = dashProvisioner
 	}
+	ps.cancelPolling()
+}
+
+// Reload reads the dashboard poll settings again. When they changed, polling is restarted with a fresh dashboard
+// provisioner, so the Run loop picks up the new settings on its next iteration.
+func (ps *provisioningServiceImpl) Reload() error {
+	return ps.coalesce("reload", func() (err error) {
+		span, ctx := opentracing.StartSpanFromContext(context.Background(), "provisioning reload")
+		defer func() { utils.FinishSpan(span, err) }()
+
+		pollSettings, err := ps.Cfg.ReadProvisioningPollSettings()
+		if err != nil {
+			return errutil.Wrap("Failed to read dashboard poll settings", err)
+		}
 
+		ps.mutex.Lock()
+		changed := pollSettings != ps.currentPollSettings()
+		ps.pollSettings = &pollSettings
+		polling := ps.dashboardProvisioner != nil
+		ps.mutex.Unlock()
+
+		span.SetTag("restarted", changed && polling)
+		if changed && polling {
+			ps.log.Info("Dashboard poll settings changed, restarting polling", "interval", pollSettings.Interval,
+				"jitter", pollSettings.Jitter)
+			ps.restartPolling(ctx)
+		}
+		return nil
+	})
+}
+
+// currentPollSettings must be called with the mutex held.
+func (ps *provisioningServiceImpl) currentPollSettings() setting.ProvisioningPollSettings {
+	if ps.pollSettings == nil {
+		return ps.Cfg.ProvisioningDashboardsPoll
+	}
+	return *ps.pollSettings
+}
+
+// dashboardsCfg returns the settings for a new dashboard provisioner, with the reloaded poll settings if any.
+func (ps *provisioningServiceImpl) dashboardsCfg() *setting.Cfg {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	if ps.pollSettings == nil {
+		return ps.Cfg
+	}
+	cfg := *ps.Cfg
+	cfg.ProvisioningDashboardsPoll = *ps.pollSettings
+	return &cfg
+}
+
+// withEnvironment returns a copy of ctx carrying the Grafana version and feature toggles that the guards of the
+// provisioning files are checked against, the resolver of their secret references, the default org and the parse
+// caches of the dashboards.
+func (ps *provisioningServiceImpl) withEnvironment(ctx context.Context) context.Context {
+	env := utils.Environment{Version: ps.Cfg.BuildVersion, FeatureToggles: ps.Cfg.FeatureToggles}
+	if env.Version == "" {
+		env.Version = setting.BuildVersion
+	}
+	if ps.secretResolver != nil {
+		ctx = utils.WithSecretResolver(ctx, ps.secretResolver)
+	}
+	if ps.Cfg.ProvisioningDanglingReferences != "" {
+		ctx = utils.WithReferenceCheck(ctx, utils.ReferenceCheckMode(ps.Cfg.ProvisioningDanglingReferences))
+	}
+	if ps.Cfg.ProvisioningDefaultOrgName != "" {
+		ctx = utils.WithDefaultOrg(ctx, ps.Cfg.ProvisioningDefaultOrgName)
+	}
+	if ps.dashboardParseCaches != nil {
+		ctx = dashboards.WithParseCaches(ctx, ps.dashboardParseCaches)
+	}
+	return utils.WithEnvironment(ctx, env)
+}
+
+// provisioningDirs returns the directory at elem under every provisioning path, in the order they're merged.
+func (ps *provisioningServiceImpl) provisioningDirs(elem ...string) []string {
+	paths := ps.Cfg.ProvisioningPaths
+	if len(paths) == 0 {
+		paths = []string{ps.Cfg.ProvisioningPath}
+	}
+
+	dirs := make([]string, 0, len(paths))
+	for _, path := range paths {
+		dirs = append(dirs, filepath.Join(append([]string{path}, elem...)...))
+	}
+	return dirs
+}
+
+// orgScopedDirs returns the directories of a subsystem in every provisioning path, each followed by the per-org
+// directories of the path like orgs/<orgID>/datasources. The objects of per-org directories belong to their org.
+func (ps *provisioningServiceImpl) orgScopedDirs(subsystem string) []string {
+	var dirs []string
+	for _, dir := range ps.provisioningDirs() {
+		dirs = append(dirs, filepath.Join(dir, subsystem))
+		dirs = append(dirs, utils.OrgDirectories(dir, subsystem)...)
+	}
+	return dirs
+}
+
+// requireDirs returns an ErrMissingProvisioningDir error for the first of dirs that doesn't exist, when
+// provisioning.fail_on_missing_dir is set. An empty directory is fine.
+func (ps *provisioningServiceImpl) requireDirs(dirs []string) error {
+	if !ps.Cfg.ProvisioningFailOnMissingDir {
+		return nil
+	}
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrMissingProvisioningDir, dir)
+		}
+	}
+	return nil
+}
+
+// forEachDir provisions the directories one after the other, so later ones override what earlier ones applied.
+// It stops at the first directory that fails.
+func forEachDir(dirs []string, provision func(dir string) error) error {
+	for _, dir := range dirs {
+		if err := provision(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ps *provisioningServiceImpl) ProvisionOrgs(ctx context.Context) error {
+	return ps.runProvisioner(ctx, "orgs", ps.provisioningDirs("orgs"), func(ctx context.Context) (*utils.Inventory, error) {
+		if err := ps.requireDirs(ps.provisioningDirs("orgs")); err != nil {
+			return nil, ps.notifyFailure("orgs", errutil.Wrap("Org provisioning error", err))
+		}
+		inventory := utils.NewInventory()
+		store := ps.provisioningStore()
+		err := forEachDir(ps.provisioningDirs("orgs"), func(orgPath string) error {
+			return ps.provisionOrgs(ctx, orgPath, store, ps.Cfg.ProvisioningFileFilters["orgs"],
+				ps.Cfg.ProvisioningStrictFields["orgs"], inventory)
+		})
+		return inventory, ps.notifyFailure("orgs", errutil.Wrap("Org provisioning error", err))
+	})
+}
+
+// watchCertFiles provisions the datasources again whenever a certificate file they reference changes on disk,
+// so rotated certificates are picked up without touching the provisioning files.
+func (ps *provisioningServiceImpl) watchCertFiles(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !ps.certFilesChanged() {
+				continue
+			}
+
+			ps.log.Info("Datasource certificate files changed, provisioning datasources again")
+			if err := ps.ProvisionDatasources(ctx); err != nil {
+				ps.log.Error("Failed to provision datasources", "error", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (ps *provisioningServiceImpl) ProvisionDatasources(ctx context.Context) error {
+	err := ps.provisionDatasourcesOnly(ctx)
+	if err != nil || atomic.LoadInt32(&ps.initProvisioned) == 0 {
+		// At init, the permissions are applied after the other init provisioners.
+		return err
+	}
+	return ps.applyDatasourcePermissions(ctx)
+}
+
+func (ps *provisioningServiceImpl) provisionDatasourcesOnly(ctx context.Context) error {
+	return ps.runProvisioner(ctx, "datasources", ps.orgScopedDirs("datasources"), func(ctx context.Context) (*utils.Inventory, error) {
+		if err := ps.requireDirs(ps.provisioningDirs("datasources")); err != nil {
+			return nil, ps.notifyFailure("datasources", errutil.Wrap("Datasource provisioning error", err))
+		}
+		inventory := utils.NewInventory()
+		err := ps.provisionDatasources(ctx, datasources.ProvisionOptions{
+			Dirs:          ps.orgScopedDirs("datasources"),
+			Store:         ps.provisioningStore(),
+			FileFilter:    ps.Cfg.ProvisioningFileFilters["datasources"],
+			Strict:        ps.Cfg.ProvisioningStrictFields["datasources"],
+			PruneMode:     datasources.PruneMode(ps.Cfg.ProvisioningDatasourcesPruneOrphans),
+			DeletedPolicy: datasources.DeletedPolicy(ps.Cfg.ProvisioningDatasourcesDeletedPolicy),
+			CleanupGuard:  ps.Cfg.ProvisioningCleanupGuard,
+			HealthCheck: datasources.HealthCheckSettings{
+				Mode:    datasources.HealthCheckMode(ps.Cfg.ProvisioningDatasourcesHealthCheck),
+				Timeout: ps.Cfg.ProvisioningDatasourcesHealthTimeout,
+				Check:   ps.checkDatasourceHealth,
+			},
+			Inventory: inventory,
+		})
+		return inventory, ps.notifyFailure("datasources", errutil.Wrap("Datasource provisioning error", err))
+	})
+}
+
+// applyDatasourcePermissions applies the permissions blocks of the provisioned datasources, which is only logged
+// when the datasource permissions service is disabled.
+func (ps *provisioningServiceImpl) applyDatasourcePermissions(ctx context.Context) error {
+	err := ps.provisionDatasourcePermissions(ctx, ps.orgScopedDirs("datasources"), ps.DatasourcePermissions,
+		ps.Cfg.ProvisioningFileFilters["datasources"], ps.Cfg.ProvisioningStrictFields["datasources"])
+	return ps.notifyFailure("datasources", errutil.Wrap("Datasource permissions provisioning error", err))
+}
+
+func (ps *provisioningServiceImpl) ProvisionPlugins(ctx context.Context) error {
+	return ps.runProvisioner(ctx, "plugins", ps.provisioningDirs("plugins"), func(ctx context.Context) (*utils.Inventory, error) {
+		if err := ps.requireDirs(ps.provisioningDirs("plugins")); err != nil {
+			return nil, ps.notifyFailure("plugins", errutil.Wrap("app provisioning error", err))
+		}
+		inventory := utils.NewInventory()
+		err := ps.provisionPlugins(ctx, ps.provisioningDirs("plugins"), ps.provisioningStore(), ps.PluginManager,
+			ps.Cfg.ProvisioningFileFilters["plugins"], ps.Cfg.ProvisioningStrictFields["plugins"], ps.Cfg.ProvisioningPluginsDisableRemovedApps, ps.Cfg.ProvisioningPluginsFailFast, inventory)
+		return inventory, ps.notifyFailure("plugins", errutil.Wrap("app provisioning error", err))
+	})
+}
+
+func (ps *provisioningServiceImpl) ProvisionNotifications(ctx context.Context) error {
+	return ps.runProvisioner(ctx, "notifiers", ps.provisioningDirs("notifiers"), func(ctx context.Context) (*utils.Inventory, error) {
+		if err := ps.requireDirs(ps.provisioningDirs("notifiers")); err != nil {
+			return nil, ps.notifyFailure("notifiers", errutil.Wrap("Alert notification provisioning error", err))
+		}
+		inventory := utils.NewInventory()
+		err := ps.provisionNotifiers(ctx, ps.provisioningDirs("notifiers"), ps.provisioningStore(),
+			ps.Cfg.ProvisioningFileFilters["notifiers"], ps.Cfg.ProvisioningStrictFields["notifiers"], inventory)
+		return inventory, ps.notifyFailure("notifiers", errutil.Wrap("Alert notification provisioning error", err))
+	})
+}
+
+// ProvisionLibraryPanels provisions the library panels of the panel library, which does nothing unless the
+// panelLibrary feature toggle is enabled.
+func (ps *provisioningServiceImpl) ProvisionLibraryPanels(ctx context.Context) error {
+	if !ps.Cfg.IsPanelLibraryEnabled() {
+		return nil
+	}
+
+	return ps.runProvisioner(ctx, "library panels", ps.orgScopedDirs("library-panels"), func(ctx context.Context) (*utils.Inventory, error) {
+		if err := ps.requireDirs(ps.provisioningDirs("library-panels")); err != nil {
+			return nil, ps.notifyFailure("library panels", errutil.Wrap("Library panel provisioning error", err))
+		}
+		inventory := utils.NewInventory()
+		err := ps.provisionLibraryPanels(ctx, ps.orgScopedDirs("library-panels"), ps.provisioningStore(),
+			ps.Cfg.ProvisioningFileFilters["library_panels"], ps.Cfg.ProvisioningStrictFields["library_panels"], inventory)
+		return inventory, ps.notifyFailure("library panels", errutil.Wrap("Library panel provisioning error", err))
+	})
+}
+
+func (ps *provisioningServiceImpl) ProvisionDashboards(ctx context.Context) error {
+	return ps.runProvisionerWithResult(ctx, "dashboards", func(ctx context.Context) (ProvisionResult, error) {
+		dirs := ps.orgScopedDirs("dashboards")
+		if err := ps.requireDirs(ps.provisioningDirs("dashboards")); err != nil {
+			return ProvisionResult{Directories: dirs}, ps.notifyFailure("dashboards", errutil.Wrap("Failed to provision dashboards", err))
+		}
+		dashProvisioner, err := ps.newDashboardProvisioner(ctx, dirs, ps.provisioningStore(), ps.dashboardsCfg())
+		if err != nil {
+			return ProvisionResult{Directories: dirs}, ps.notifyFailure("dashboards", errutil.Wrap("Failed to create provisioner", err))
+		}
+
+		// The mutex isn't held while the dashboards are provisioned, so a run the timeout abandoned doesn't keep
+		// the getters and the other provisioners waiting. Polling stays stopped until the run returns.
+		run := ps.startDashboardRun()
+		defer ps.finishDashboardRun()
+
+		dashProvisioner.SetDriftHandler(ps.notifyDashboardDrift)
+		dashProvisioner.CleanUpOrphanedDashboards(ctx)
+
+		err = dashProvisioner.Provision(ctx)
+		if err != nil {
+			// If we fail to provision with the new provisioner, the polling will restart with the old provisioner as we
+			// did not switch them yet.
+			return ProvisionResult{Directories: dirs}, ps.notifyFailure("dashboards", errutil.Wrap("Failed to provision dashboards", err))
+		}
+		ps.setDashboardProvisioner(run, dashProvisioner)
+		return ProvisionResult{
+			Objects:      dashProvisioner.GetProvisionedDashboards(),
+			Deleted:      dashProvisioner.GetDeletedDashboards(),
+			Directories:  dirs,
+			ResumedFiles: dashProvisioner.ResumedFiles(),
+		}, nil
+	})
+}
+
+// startDashboardRun stops polling until the run of ProvisionDashboards it starts has returned, and returns the
+// number of the run.
+func (ps *provisioningServiceImpl) startDashboardRun() uint64 {
 	ps.mutex.Lock()
 	defer ps.mutex.Unlock()
 
 	ps.cancelPolling()
-	dashProvisioner.CleanUpOrphanedDashboards()
+	if ps.dashboardRuns == 0 {
+		ps.dashboardRunsDone = make(chan struct{})
+	}
+	ps.dashboardRuns++
+	return ps.startRunLocked("dashboards")
+}
 
-	err = dashProvisioner.Provision()
-	if err != nil {
-		// If we fail to provision with the new provisioner, the mutex will unlock and the polling will restart with the
-		// old provisioner as we did not switch them yet.
-		return errutil.Wrap("Failed to provision dashboards", err)
+// finishDashboardRun lets polling start again once every run of ProvisionDashboards has returned.
+func (ps *provisioningServiceImpl) finishDashboardRun() {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	ps.dashboardRuns--
+	if ps.dashboardRuns == 0 {
+		close(ps.dashboardRunsDone)
+		ps.dashboardRunsDone = nil
+	}
+}
+
+// setDashboardProvisioner makes the provisioner of run the one that polls and answers the getters, unless a later
+// run already replaced it.
+func (ps *provisioningServiceImpl) setDashboardProvisioner(run uint64, dashProvisioner dashboards.DashboardProvisioner) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	if run < ps.dashboardsRun {
+		ps.log.Debug("Discarding the dashboard provisioner of an abandoned provisioning run", "run", run)
+		return
 	}
 	ps.dashboardProvisioner = dashProvisioner
-	return nil
+	ps.dashboardsRun = run
+}
+
+// ReprovisionProvider provisions the dashboards of a single provider again, leaving the other providers and the
+// polling as they are. ErrProviderNotFound is returned, wrapped, until the dashboards have been provisioned.
+func (ps *provisioningServiceImpl) ReprovisionProvider(ctx context.Context, name string) error {
+	ps.mutex.Lock()
+	dashboardProvisioner := ps.dashboardProvisioner
+	ps.mutex.Unlock()
+
+	if dashboardProvisioner == nil {
+		return fmt.Errorf("%w: %q", ErrProviderNotFound, name)
+	}
+
+	return ps.coalesce("dashboards provider "+name, func() (err error) {
+		span, ctx := opentracing.StartSpanFromContext(ctx, "provisioning dashboards provider")
+		span.SetTag("provider", name)
+		defer func() { utils.FinishSpan(span, err) }()
+
+		start := time.Now()
+		_, err = ps.provisionWithTimeout(ctx, "dashboards", func(ctx context.Context) (ProvisionResult, error) {
+			return ProvisionResult{}, dashboardProvisioner.ProvisionProvider(ctx, name)
+		})
+		if errors.Is(err, ErrProviderNotFound) {
+			return err
+		}
+		err = ps.notifyFailure("dashboards", errutil.Wrapf(err, "Failed to provision dashboards of provider %v", name))
+		result := ProvisionResult{
+			Objects:      dashboardProvisioner.GetProvisionedDashboards(),
+			Duration:     time.Since(start),
+			ResumedFiles: dashboardProvisioner.ResumedFiles(),
+		}
+		if err == nil {
+			err = ps.runPostApplyHooks(ctx, "dashboards", result)
+		}
+		ps.notifyObservers("dashboards", result, err)
+		return err
+	})
+}
+
+func (ps *provisioningServiceImpl) ProvisionAlertRules(ctx context.Context) error {
+	if !ps.Cfg.IsNgAlertEnabled() {
+		return nil
+	}
+
+	return ps.runProvisioner(ctx, "alert rules", ps.provisioningDirs("alerting", "rules"), func(ctx context.Context) (*utils.Inventory, error) {
+		ruleStore := ps.provisioningStore()
+		if err := ps.requireDirs(ps.provisioningDirs("alerting", "rules")); err != nil {
+			return nil, ps.notifyFailure("alert rules", errutil.Wrap("Alert rule provisioning error", err))
+		}
+		inventory := utils.NewInventory()
+		err := forEachDir(ps.provisioningDirs("alerting", "rules"), func(rulesPath string) error {
+			return ps.provisionAlertRules(ctx, rulesPath, ruleStore, ps.Cfg.ProvisioningFileFilters["alert_rules"],
+				ps.Cfg.ProvisioningStrictFields["alert_rules"], inventory)
+		})
+		return inventory, ps.notifyFailure("alert rules", errutil.Wrap("Alert rule provisioning error", err))
+	})
+}
+
+// ProvisionAlertNotifications provisions the contact points and notification policies of unified alerting.
+func (ps *provisioningServiceImpl) ProvisionAlertNotifications(ctx context.Context) error {
+	if !ps.Cfg.IsNgAlertEnabled() {
+		return nil
+	}
+
+	return ps.runProvisioner(ctx, "alert notifications", ps.provisioningDirs("alerting", "notifications"), func(ctx context.Context) (*utils.Inventory, error) {
+		if err := ps.requireDirs(ps.provisioningDirs("alerting", "notifications")); err != nil {
+			return nil, ps.notifyFailure("alert notifications", errutil.Wrap("Alert notification provisioning error", err))
+		}
+		notificationStore := ps.provisioningStore()
+		inventory := utils.NewInventory()
+		err := forEachDir(ps.provisioningDirs("alerting", "notifications"), func(notificationsPath string) error {
+			return ps.provisionAlertNotifications(ctx, notificationsPath, notificationStore,
+				ps.Cfg.ProvisioningFileFilters["alert_notifications"], ps.Cfg.ProvisioningStrictFields["alert_notifications"],
+				ps.Cfg.ProvisioningCleanupGuard, inventory)
+		})
+		return inventory, ps.notifyFailure("alert notifications", errutil.Wrap("Alert notification provisioning error", err))
+	})
+}
+
+// startRun returns the number of a new run of a provisioner.
+func (ps *provisioningServiceImpl) startRun(provisioner string) uint64 {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	return ps.startRunLocked(provisioner)
 }
 
+// startRunLocked is startRun for callers that hold the mutex.
+func (ps *provisioningServiceImpl) startRunLocked(provisioner string) uint64 {
+	if ps.runs == nil {
+		ps.runs = map[string]uint64{}
+	}
+	ps.runs[provisioner]++
+	return ps.runs[provisioner]
+}
+
+// setInventory replaces the objects listed for a provisioner with the ones applied by run, once it's done. A run
+// that failed halfway replaces them too, since the objects it didn't get to may not be in the database. A run that
+// timed out is abandoned while it's still applying objects, so it doesn't replace the objects of a later run when
+// it returns.
+func (ps *provisioningServiceImpl) setInventory(provisioner string, run uint64, inventory *utils.Inventory) {
+	objects := inventory.Objects()
+
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	if ps.inventory == nil {
+		ps.inventory = map[string][]ProvisionedObject{}
+		ps.inventoryRuns = map[string]uint64{}
+	}
+	if run < ps.inventoryRuns[provisioner] {
+		ps.log.Debug("Discarding the objects of an abandoned provisioning run", "provisioner", provisioner, "run", run)
+		return
+	}
+	ps.inventory[provisioner] = objects
+	ps.inventoryRuns[provisioner] = run
+}
+
+// GetProvisionedInventory returns the objects applied by the last run of every provisioner, sorted by kind, org
+// and name. Dashboards are listed once they have been provisioned.
+func (ps *provisioningServiceImpl) GetProvisionedInventory() []ProvisionedObject {
+	ps.mutex.Lock()
+	var objects []ProvisionedObject
+	for _, provisioned := range ps.inventory {
+		objects = append(objects, provisioned...)
+	}
+	if ps.dashboardProvisioner != nil {
+		objects = append(objects, ps.dashboardProvisioner.GetProvisionedDashboards()...)
+	}
+	ps.mutex.Unlock()
+
+	sort.SliceStable(objects, func(i, j int) bool {
+		a, b := objects[i], objects[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.OrgID != b.OrgID {
+			return a.OrgID < b.OrgID
+		}
+		return a.Name < b.Name
+	})
+	return objects
+}
+
+// ProvisionDeferredDashboard provisions the dashboard with uid of org if a lazy dashboard provider deferred it, and
+// reports whether it was provisioned. Dashboards are only deferred once the dashboards have been provisioned.
+func (ps *provisioningServiceImpl) ProvisionDeferredDashboard(ctx context.Context, orgID int64, uid string) (bool, error) {
+	ps.mutex.Lock()
+	dashboardProvisioner := ps.dashboardProvisioner
+	ps.mutex.Unlock()
+
+	if dashboardProvisioner == nil {
+		return false, nil
+	}
+	// The mutex isn't held while the dashboard is saved, since that waits for a walk of the disk that may be running.
+	return dashboardProvisioner.ProvisionDeferredDashboard(ps.withEnvironment(ctx), orgID, uid)
+}
+
+// GetDashboardProvisionerResolvedPath returns the root path of the named dashboard provider, which is absolute but
+// doesn't resolve symlinks, like the external ids of its dashboards. The name predates that. It returns an empty path
+// until the dashboards have been provisioned.
 func (ps *provisioningServiceImpl) GetDashboardProvisionerResolvedPath(name string) string {
-	return ps.dashboardProvisioner.GetProvisionerResolvedPath(name)
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	if ps.dashboardProvisioner == nil {
+		return ""
+	}
+	return ps.dashboardProvisioner.GetProvisionerRootPath(name)
 }
 
+// GetAllowUIUpdatesFromConfig returns false until the dashboards have been provisioned, and for unknown providers.
 func (ps *provisioningServiceImpl) GetAllowUIUpdatesFromConfig(name string) bool {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	if ps.dashboardProvisioner == nil {
+		return false
+	}
 	return ps.dashboardProvisioner.GetAllowUIUpdatesFromConfig(name)
 }
 
+// GetProvisioningInfo returns where the dashboard with the uid was provisioned from. It returns false until the
+// dashboards have been provisioned, and for dashboards no provider provisioned.
+func (ps *provisioningServiceImpl) GetProvisioningInfo(dashboardUID string) (*ProvisioningInfo, bool) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	if ps.dashboardProvisioner == nil {
+		return nil, false
+	}
+	return ps.dashboardProvisioner.GetProvisioningInfo(dashboardUID)
+}
+
+// GetAllowUIUpdatesMap returns whether each dashboard provisioner allows updates from the UI, by provisioner name.
+// The map is empty until the dashboards have been provisioned.
+func (ps *provisioningServiceImpl) GetAllowUIUpdatesMap() map[string]bool {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	if ps.dashboardProvisioner == nil {
+		return map[string]bool{}
+	}
+	return ps.dashboardProvisioner.GetAllowUIUpdatesMap()
+}
+
 func (ps *provisioningServiceImpl) cancelPolling() {
 	if ps.pollingCtxCancel != nil {
 		ps.log.Debug("Stop polling for dashboard changes")
@@ -1,27 +1,74 @@
 package provisioning
 
-import "context"
+import (
+	"context"
+	"io"
+)
 
 type Calls struct {
 	RunInitProvisioners                 []interface{}
+	ProvisionNow                        []interface{}
+	ProvisionOrgs                       []interface{}
 	ProvisionDatasources                []interface{}
 	ProvisionPlugins                    []interface{}
 	ProvisionNotifications              []interface{}
+	ProvisionDatasourcesFromReader      []interface{}
+	ProvisionNotificationsFromReader    []interface{}
+	ProvisionLibraryPanels              []interface{}
 	ProvisionDashboards                 []interface{}
+	ReprovisionProvider                 []interface{}
+	ProvisionAlertRules                 []interface{}
+	ProvisionAlertNotifications         []interface{}
 	GetDashboardProvisionerResolvedPath []interface{}
 	GetAllowUIUpdatesFromConfig         []interface{}
+	GetAllowUIUpdatesMap                []interface{}
+	GetProvisioningInfo                 []interface{}
+	GetProvisionedInventory             []interface{}
+	Health                              []interface{}
+	SelfTest                            []interface{}
+	ExportProvisioningState             []interface{}
+	ImportProvisioningState             []interface{}
+	DiffProvisioning                    []interface{}
+	ReloadProvisioning                  []interface{}
+	ExportProvisioning                  []interface{}
+	ProvisionDeferredDashboard          []interface{}
+	RegisterObserver                    []interface{}
 	Run                                 []interface{}
 }
 
+var _ ProvisioningService = (*ProvisioningServiceMock)(nil)
+
+// ProvisioningServiceMock is a ProvisioningService whose methods call the matching Func field when it's set, and
+// record their calls in Calls. It isn't safe for concurrent use; provisioningtest.FakeProvisioningService is.
 type ProvisioningServiceMock struct {
 	Calls                                   *Calls
-	RunInitProvisionersFunc                 func() error
-	ProvisionDatasourcesFunc                func() error
-	ProvisionPluginsFunc                    func() error
-	ProvisionNotificationsFunc              func() error
-	ProvisionDashboardsFunc                 func() error
+	RunInitProvisionersFunc                 func(ctx context.Context) error
+	ProvisionNowFunc                        func(ctx context.Context) error
+	ProvisionOrgsFunc                       func(ctx context.Context) error
+	ProvisionDatasourcesFunc                func(ctx context.Context) error
+	ProvisionPluginsFunc                    func(ctx context.Context) error
+	ProvisionNotificationsFunc              func(ctx context.Context) error
+	ProvisionDatasourcesFromReaderFunc      func(ctx context.Context, orgID int64, r io.Reader) (*ProvisionResult, error)
+	ProvisionNotificationsFromReaderFunc    func(ctx context.Context, orgID int64, r io.Reader) (*ProvisionResult, error)
+	ProvisionLibraryPanelsFunc              func(ctx context.Context) error
+	ProvisionDashboardsFunc                 func(ctx context.Context) error
+	ReprovisionProviderFunc                 func(ctx context.Context, name string) error
+	ProvisionAlertRulesFunc                 func(ctx context.Context) error
+	ProvisionAlertNotificationsFunc         func(ctx context.Context) error
 	GetDashboardProvisionerResolvedPathFunc func(name string) string
 	GetAllowUIUpdatesFromConfigFunc         func(name string) bool
+	GetAllowUIUpdatesMapFunc                func() map[string]bool
+	GetProvisioningInfoFunc                 func(dashboardUID string) (*ProvisioningInfo, bool)
+	GetProvisionedInventoryFunc             func() []ProvisionedObject
+	HealthFunc                              func() error
+	SelfTestFunc                            func(ctx context.Context) error
+	ExportProvisioningStateFunc             func(ctx context.Context) ([]byte, error)
+	ImportProvisioningStateFunc             func(ctx context.Context, data []byte) error
+	DiffProvisioningFunc                    func(ctx context.Context, kind string) (*ProvisioningDiff, error)
+	ReloadProvisioningFunc                  func(ctx context.Context, kind string) (*ProvisionResult, error)
+	ExportProvisioningFunc                  func(ctx context.Context, kind string, outDir string, provisionedOnly bool) error
+	ProvisionDeferredDashboardFunc          func(ctx context.Context, orgID int64, uid string) (bool, error)
+	RegisterObserverFunc                    func(observer ProvisioningObserver)
 	RunFunc                                 func(ctx context.Context) error
 }
 
@@ -31,42 +78,106 @@ func NewProvisioningServiceMock() *ProvisioningServiceMock {
 	}
 }
 
-func (mock *ProvisioningServiceMock) RunInitProvisioners() error {
+func (mock *ProvisioningServiceMock) RunInitProvisioners(ctx context.Context) error {
 	mock.Calls.RunInitProvisioners = append(mock.Calls.RunInitProvisioners, nil)
 	if mock.RunInitProvisionersFunc != nil {
-		return mock.RunInitProvisionersFunc()
+		return mock.RunInitProvisionersFunc(ctx)
+	}
+	return nil
+}
+
+func (mock *ProvisioningServiceMock) ProvisionNow(ctx context.Context) error {
+	mock.Calls.ProvisionNow = append(mock.Calls.ProvisionNow, nil)
+	if mock.ProvisionNowFunc != nil {
+		return mock.ProvisionNowFunc(ctx)
+	}
+	return nil
+}
+
+func (mock *ProvisioningServiceMock) ProvisionOrgs(ctx context.Context) error {
+	mock.Calls.ProvisionOrgs = append(mock.Calls.ProvisionOrgs, nil)
+	if mock.ProvisionOrgsFunc != nil {
+		return mock.ProvisionOrgsFunc(ctx)
 	}
 	return nil
 }
 
-func (mock *ProvisioningServiceMock) ProvisionDatasources() error {
+func (mock *ProvisioningServiceMock) ProvisionDatasources(ctx context.Context) error {
 	mock.Calls.ProvisionDatasources = append(mock.Calls.ProvisionDatasources, nil)
 	if mock.ProvisionDatasourcesFunc != nil {
-		return mock.ProvisionDatasourcesFunc()
+		return mock.ProvisionDatasourcesFunc(ctx)
 	}
 	return nil
 }
 
-func (mock *ProvisioningServiceMock) ProvisionPlugins() error {
+func (mock *ProvisioningServiceMock) ProvisionPlugins(ctx context.Context) error {
 	mock.Calls.ProvisionPlugins = append(mock.Calls.ProvisionPlugins, nil)
 	if mock.ProvisionPluginsFunc != nil {
-		return mock.ProvisionPluginsFunc()
+		return mock.ProvisionPluginsFunc(ctx)
 	}
 	return nil
 }
 
-func (mock *ProvisioningServiceMock) ProvisionNotifications() error {
+func (mock *ProvisioningServiceMock) ProvisionNotifications(ctx context.Context) error {
 	mock.Calls.ProvisionNotifications = append(mock.Calls.ProvisionNotifications, nil)
 	if mock.ProvisionNotificationsFunc != nil {
-		return mock.ProvisionNotificationsFunc()
+		return mock.ProvisionNotificationsFunc(ctx)
+	}
+	return nil
+}
+
+func (mock *ProvisioningServiceMock) ProvisionDatasourcesFromReader(ctx context.Context, orgID int64, r io.Reader) (*ProvisionResult, error) {
+	mock.Calls.ProvisionDatasourcesFromReader = append(mock.Calls.ProvisionDatasourcesFromReader, orgID)
+	if mock.ProvisionDatasourcesFromReaderFunc != nil {
+		return mock.ProvisionDatasourcesFromReaderFunc(ctx, orgID, r)
+	}
+	return &ProvisionResult{}, nil
+}
+
+func (mock *ProvisioningServiceMock) ProvisionNotificationsFromReader(ctx context.Context, orgID int64, r io.Reader) (*ProvisionResult, error) {
+	mock.Calls.ProvisionNotificationsFromReader = append(mock.Calls.ProvisionNotificationsFromReader, orgID)
+	if mock.ProvisionNotificationsFromReaderFunc != nil {
+		return mock.ProvisionNotificationsFromReaderFunc(ctx, orgID, r)
+	}
+	return &ProvisionResult{}, nil
+}
+
+func (mock *ProvisioningServiceMock) ProvisionLibraryPanels(ctx context.Context) error {
+	mock.Calls.ProvisionLibraryPanels = append(mock.Calls.ProvisionLibraryPanels, nil)
+	if mock.ProvisionLibraryPanelsFunc != nil {
+		return mock.ProvisionLibraryPanelsFunc(ctx)
 	}
 	return nil
 }
 
-func (mock *ProvisioningServiceMock) ProvisionDashboards() error {
+func (mock *ProvisioningServiceMock) ProvisionDashboards(ctx context.Context) error {
 	mock.Calls.ProvisionDashboards = append(mock.Calls.ProvisionDashboards, nil)
 	if mock.ProvisionDashboardsFunc != nil {
-		return mock.ProvisionDashboardsFunc()
+		return mock.ProvisionDashboardsFunc(ctx)
+	}
+	return nil
+}
+
+func (mock *ProvisioningServiceMock) ReprovisionProvider(ctx context.Context, name string) error {
+	mock.Calls.ReprovisionProvider = append(mock.Calls.ReprovisionProvider, name)
+	if mock.ReprovisionProviderFunc != nil {
+		return mock.ReprovisionProviderFunc(ctx, name)
+	}
+	return nil
+}
+
+func (mock *ProvisioningServiceMock) ProvisionAlertRules(ctx context.Context) error {
+	mock.Calls.ProvisionAlertRules = append(mock.Calls.ProvisionAlertRules, nil)
+	if mock.ProvisionAlertRulesFunc != nil {
+		return mock.ProvisionAlertRulesFunc(ctx)
+	}
+	return nil
+}
+
+func (mock *ProvisioningServiceMock) ProvisionAlertNotifications(ctx context.Context) error {
+	mock.Calls.ProvisionAlertNotifications = append(mock.Calls.ProvisionAlertNotifications, nil)
+	if mock.ProvisionAlertNotificationsFunc != nil {
+		return mock.ProvisionAlertNotificationsFunc(ctx)
 	}
 	return nil
 }
@@ -87,6 +198,101 @@ func (mock *ProvisioningServiceMock) GetAllowUIUpdatesFromConfig(name string) bo
 	return false
 }
 
+func (mock *ProvisioningServiceMock) GetAllowUIUpdatesMap() map[string]bool {
+	mock.Calls.GetAllowUIUpdatesMap = append(mock.Calls.GetAllowUIUpdatesMap, nil)
+	if mock.GetAllowUIUpdatesMapFunc != nil {
+		return mock.GetAllowUIUpdatesMapFunc()
+	}
+	return map[string]bool{}
+}
+
+func (mock *ProvisioningServiceMock) GetProvisioningInfo(dashboardUID string) (*ProvisioningInfo, bool) {
+	mock.Calls.GetProvisioningInfo = append(mock.Calls.GetProvisioningInfo, dashboardUID)
+	if mock.GetProvisioningInfoFunc != nil {
+		return mock.GetProvisioningInfoFunc(dashboardUID)
+	}
+	return nil, false
+}
+
+func (mock *ProvisioningServiceMock) GetProvisionedInventory() []ProvisionedObject {
+	mock.Calls.GetProvisionedInventory = append(mock.Calls.GetProvisionedInventory, nil)
+	if mock.GetProvisionedInventoryFunc != nil {
+		return mock.GetProvisionedInventoryFunc()
+	}
+	return nil
+}
+
+func (mock *ProvisioningServiceMock) Health() error {
+	mock.Calls.Health = append(mock.Calls.Health, nil)
+	if mock.HealthFunc != nil {
+		return mock.HealthFunc()
+	}
+	return nil
+}
+
+func (mock *ProvisioningServiceMock) SelfTest(ctx context.Context) error {
+	mock.Calls.SelfTest = append(mock.Calls.SelfTest, nil)
+	if mock.SelfTestFunc != nil {
+		return mock.SelfTestFunc(ctx)
+	}
+	return nil
+}
+
+func (mock *ProvisioningServiceMock) ExportProvisioningState(ctx context.Context) ([]byte, error) {
+	mock.Calls.ExportProvisioningState = append(mock.Calls.ExportProvisioningState, ctx)
+	if mock.ExportProvisioningStateFunc != nil {
+		return mock.ExportProvisioningStateFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (mock *ProvisioningServiceMock) ImportProvisioningState(ctx context.Context, data []byte) error {
+	mock.Calls.ImportProvisioningState = append(mock.Calls.ImportProvisioningState, data)
+	if mock.ImportProvisioningStateFunc != nil {
+		return mock.ImportProvisioningStateFunc(ctx, data)
+	}
+	return nil
+}
+
+func (mock *ProvisioningServiceMock) DiffProvisioning(ctx context.Context, kind string) (*ProvisioningDiff, error) {
+	mock.Calls.DiffProvisioning = append(mock.Calls.DiffProvisioning, kind)
+	if mock.DiffProvisioningFunc != nil {
+		return mock.DiffProvisioningFunc(ctx, kind)
+	}
+	return nil, nil
+}
+
+func (mock *ProvisioningServiceMock) ReloadProvisioning(ctx context.Context, kind string) (*ProvisionResult, error) {
+	mock.Calls.ReloadProvisioning = append(mock.Calls.ReloadProvisioning, kind)
+	if mock.ReloadProvisioningFunc != nil {
+		return mock.ReloadProvisioningFunc(ctx, kind)
+	}
+	return &ProvisionResult{}, nil
+}
+
+func (mock *ProvisioningServiceMock) ExportProvisioning(ctx context.Context, kind string, outDir string, provisionedOnly bool) error {
+	mock.Calls.ExportProvisioning = append(mock.Calls.ExportProvisioning, kind)
+	if mock.ExportProvisioningFunc != nil {
+		return mock.ExportProvisioningFunc(ctx, kind, outDir, provisionedOnly)
+	}
+	return nil
+}
+
+func (mock *ProvisioningServiceMock) ProvisionDeferredDashboard(ctx context.Context, orgID int64, uid string) (bool, error) {
+	mock.Calls.ProvisionDeferredDashboard = append(mock.Calls.ProvisionDeferredDashboard, uid)
+	if mock.ProvisionDeferredDashboardFunc != nil {
+		return mock.ProvisionDeferredDashboardFunc(ctx, orgID, uid)
+	}
+	return false, nil
+}
+
+func (mock *ProvisioningServiceMock) RegisterObserver(observer ProvisioningObserver) {
+	mock.Calls.RegisterObserver = append(mock.Calls.RegisterObserver, observer)
+	if mock.RegisterObserverFunc != nil {
+		mock.RegisterObserverFunc(observer)
+	}
+}
+
 func (mock *ProvisioningServiceMock) Run(ctx context.Context) error {
 	mock.Calls.Run = append(mock.Calls.Run, nil)
 	if mock.RunFunc != nil {
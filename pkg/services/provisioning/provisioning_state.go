@@ -0,0 +1,113 @@
+package provisioning
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// provisioningStateVersion is the version of the exported provisioning state format. Bump it whenever the format
+// changes in a way older versions can't import.
+const provisioningStateVersion = 1
+
+// ErrIncompatibleProvisioningStateVersion is returned when importing provisioning state exported with a
+// different format version.
+var ErrIncompatibleProvisioningStateVersion = errors.New("incompatible provisioning state version")
+
+type provisioningStateDocument struct {
+	Version    int                        `json:"version"`
+	Orgs       []orgProvisioningState     `json:"orgs"`
+	Dashboards []dashboardProvisioningRef `json:"dashboards"`
+}
+
+type orgProvisioningState struct {
+	OrgID      int64  `json:"orgId"`
+	ExternalID string `json:"externalId"`
+	Updated    int64  `json:"updated"`
+}
+
+type dashboardProvisioningRef struct {
+	OrgID        int64  `json:"orgId"`
+	DashboardUID string `json:"dashboardUid"`
+	Name         string `json:"name"`
+	ExternalID   string `json:"externalId"`
+	CheckSum     string `json:"checkSum"`
+	Updated      int64  `json:"updated"`
+}
+
+// ExportProvisioningState serializes the state provisioning keeps about the orgs and dashboards it provisioned,
+// so it can be restored with ImportProvisioningState as a backup or on another instance.
+func (ps *provisioningServiceImpl) ExportProvisioningState(ctx context.Context) ([]byte, error) {
+	query := &models.GetProvisioningStateQuery{}
+	if err := bus.DispatchCtx(ctx, query); err != nil {
+		return nil, fmt.Errorf("failed to get provisioning state: %w", err)
+	}
+
+	doc := provisioningStateDocument{
+		Version:    provisioningStateVersion,
+		Orgs:       make([]orgProvisioningState, 0, len(query.Result.Orgs)),
+		Dashboards: make([]dashboardProvisioningRef, 0, len(query.Result.Dashboards)),
+	}
+	for _, org := range query.Result.Orgs {
+		doc.Orgs = append(doc.Orgs, orgProvisioningState{
+			OrgID:      org.OrgId,
+			ExternalID: org.ExternalId,
+			Updated:    org.Updated,
+		})
+	}
+	for _, dash := range query.Result.Dashboards {
+		doc.Dashboards = append(doc.Dashboards, dashboardProvisioningRef{
+			OrgID:        dash.OrgId,
+			DashboardUID: dash.DashboardUid,
+			Name:         dash.Name,
+			ExternalID:   dash.ExternalId,
+			CheckSum:     dash.CheckSum,
+			Updated:      dash.Updated,
+		})
+	}
+
+	return json.Marshal(doc)
+}
+
+// ImportProvisioningState restores provisioning state exported with ExportProvisioningState. The dashboards it references must
+// already exist, and nothing is restored if one of them doesn't.
+func (ps *provisioningServiceImpl) ImportProvisioningState(ctx context.Context, data []byte) error {
+	var doc provisioningStateDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse provisioning state: %w", err)
+	}
+
+	if doc.Version != provisioningStateVersion {
+		return fmt.Errorf("%w: got %d, expected %d", ErrIncompatibleProvisioningStateVersion, doc.Version, provisioningStateVersion)
+	}
+
+	state := &models.ProvisioningState{}
+	for _, org := range doc.Orgs {
+		state.Orgs = append(state.Orgs, &models.OrgProvisioning{
+			OrgId:      org.OrgID,
+			ExternalId: org.ExternalID,
+			Updated:    org.Updated,
+		})
+	}
+	for _, dash := range doc.Dashboards {
+		state.Dashboards = append(state.Dashboards, &models.DashboardProvisioningState{
+			OrgId:        dash.OrgID,
+			DashboardUid: dash.DashboardUID,
+			Name:         dash.Name,
+			ExternalId:   dash.ExternalID,
+			CheckSum:     dash.CheckSum,
+			Updated:      dash.Updated,
+		})
+	}
+
+	if err := bus.DispatchCtx(ctx, &models.RestoreProvisioningStateCommand{State: state}); err != nil {
+		return fmt.Errorf("failed to restore provisioning state: %w", err)
+	}
+
+	ps.log.Info("Imported provisioning state", "orgs", len(state.Orgs), "dashboards", len(state.Dashboards))
+	return nil
+}
@@ -0,0 +1,97 @@
+package provisioning
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvisioningState(t *testing.T) {
+	state := &models.ProvisioningState{
+		Orgs: []*models.OrgProvisioning{
+			{Id: 3, OrgId: 2, ExternalId: "customer-1", Updated: 1000},
+		},
+		Dashboards: []*models.DashboardProvisioningState{
+			{
+				OrgId:        2,
+				DashboardUid: "home",
+				Name:         "default",
+				ExternalId:   "/var/lib/grafana/dashboards/home.json",
+				CheckSum:     "abc",
+				Updated:      2000,
+			},
+		},
+	}
+
+	setupBus := func(t *testing.T) *[]*models.ProvisioningState {
+		t.Helper()
+		t.Cleanup(bus.ClearBusHandlers)
+
+		var restored []*models.ProvisioningState
+		bus.AddHandlerCtx("test", func(ctx context.Context, query *models.GetProvisioningStateQuery) error {
+			query.Result = state
+			return nil
+		})
+		bus.AddHandlerCtx("test", func(ctx context.Context, cmd *models.RestoreProvisioningStateCommand) error {
+			restored = append(restored, cmd.State)
+			return nil
+		})
+		return &restored
+	}
+
+	t.Run("Exported state can be imported", func(t *testing.T) {
+		restored := setupBus(t)
+		service := setup().service
+
+		data, err := service.ExportProvisioningState(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, service.ImportProvisioningState(context.Background(), data))
+
+		require.Len(t, *restored, 1)
+		assert.Equal(t, []*models.OrgProvisioning{{OrgId: 2, ExternalId: "customer-1", Updated: 1000}}, (*restored)[0].Orgs)
+		assert.Equal(t, state.Dashboards, (*restored)[0].Dashboards)
+	})
+
+	t.Run("Exported state carries the format version", func(t *testing.T) {
+		setupBus(t)
+
+		data, err := setup().service.ExportProvisioningState(context.Background())
+		require.NoError(t, err)
+
+		var doc map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &doc))
+		assert.EqualValues(t, provisioningStateVersion, doc["version"])
+	})
+
+	t.Run("State with another version is rejected", func(t *testing.T) {
+		restored := setupBus(t)
+
+		err := setup().service.ImportProvisioningState(context.Background(), []byte(`{"version": 2, "orgs": []}`))
+		require.True(t, errors.Is(err, ErrIncompatibleProvisioningStateVersion))
+		assert.EqualError(t, err, "incompatible provisioning state version: got 2, expected 1")
+		assert.Empty(t, *restored)
+	})
+
+	t.Run("State without a version is rejected", func(t *testing.T) {
+		setupBus(t)
+
+		err := setup().service.ImportProvisioningState(context.Background(), []byte(`{"orgs": []}`))
+		require.True(t, errors.Is(err, ErrIncompatibleProvisioningStateVersion))
+	})
+
+	t.Run("Failing restore is returned", func(t *testing.T) {
+		t.Cleanup(bus.ClearBusHandlers)
+		bus.AddHandlerCtx("test", func(ctx context.Context, cmd *models.RestoreProvisioningStateCommand) error {
+			return models.ErrDashboardNotFound
+		})
+
+		err := setup().service.ImportProvisioningState(context.Background(), []byte(`{"version": 1}`))
+		require.True(t, errors.Is(err, models.ErrDashboardNotFound))
+	})
+}
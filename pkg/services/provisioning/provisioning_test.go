@@ -2,27 +2,50 @@ package provisioning
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/grafana/grafana/pkg/bus"
 	dboards "github.com/grafana/grafana/pkg/dashboards"
+	"github.com/grafana/grafana/pkg/models"
+	plugifaces "github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/registry"
+	"github.com/grafana/grafana/pkg/services/provisioning/alerting"
 	"github.com/grafana/grafana/pkg/services/provisioning/dashboards"
+	"github.com/grafana/grafana/pkg/services/provisioning/datasources"
+	"github.com/grafana/grafana/pkg/services/provisioning/librarypanels"
+	"github.com/grafana/grafana/pkg/services/provisioning/notifiers"
+	"github.com/grafana/grafana/pkg/services/provisioning/orgs"
+	"github.com/grafana/grafana/pkg/services/provisioning/plugins"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
 	"github.com/grafana/grafana/pkg/setting"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestProvisioningServiceImpl(t *testing.T) {
 	t.Run("Restart dashboard provisioning and stop service", func(t *testing.T) {
 		serviceTest := setup()
-		err := serviceTest.service.ProvisionDashboards()
+		err := serviceTest.service.ProvisionDashboards(context.Background())
 		assert.Nil(t, err)
 		serviceTest.startService()
 		serviceTest.waitForPollChanges()
 
 		assert.Equal(t, 1, len(serviceTest.mock.Calls.PollChanges), "PollChanges should have been called")
 
-		err = serviceTest.service.ProvisionDashboards()
+		err = serviceTest.service.ProvisionDashboards(context.Background())
 		assert.Nil(t, err)
 
 		serviceTest.waitForPollChanges()
@@ -40,18 +63,157 @@ func TestProvisioningServiceImpl(t *testing.T) {
 		assert.Equal(t, context.Canceled, serviceTest.serviceError, "Service should have returned canceled error")
 	})
 
+	t.Run("Health reports ready once init and the first dashboard provisioning succeeded", func(t *testing.T) {
+		serviceTest := setup()
+		serviceTest.service.provisionOrgs = func(context.Context, string, orgs.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error {
+			return nil
+		}
+		serviceTest.service.provisionNotifiers = func(context.Context, []string, notifiers.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error {
+			return nil
+		}
+		serviceTest.service.provisionDatasources = func(context.Context, datasources.ProvisionOptions) error {
+			return nil
+		}
+		serviceTest.service.provisionPlugins = func(context.Context, []string, plugins.Store, plugifaces.Manager, setting.ProvisioningFileFilter, bool, bool, bool, *utils.Inventory) error {
+			return nil
+		}
+
+		require.EqualError(t, serviceTest.service.Health(), "initial provisioning hasn't completed")
+		require.NoError(t, serviceTest.service.RunInitProvisioners(context.Background()))
+		require.EqualError(t, serviceTest.service.Health(), "dashboards haven't been provisioned yet")
+
+		serviceTest.startService()
+		serviceTest.waitForPollChanges()
+		require.NoError(t, serviceTest.service.Health())
+
+		serviceTest.cancel()
+		serviceTest.waitForStop()
+	})
+
+	t.Run("Init stages run in the configured order", func(t *testing.T) {
+		serviceTest := setup()
+		var order []string
+		serviceTest.service.provisionOrgs = func(context.Context, string, orgs.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error {
+			order = append(order, "orgs")
+			return nil
+		}
+		serviceTest.service.provisionNotifiers = func(context.Context, []string, notifiers.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error {
+			order = append(order, "notifiers")
+			return nil
+		}
+		serviceTest.service.provisionDatasources = func(context.Context, datasources.ProvisionOptions) error {
+			order = append(order, "datasources")
+			return nil
+		}
+		serviceTest.service.provisionPlugins = func(context.Context, []string, plugins.Store, plugifaces.Manager, setting.ProvisioningFileFilter, bool, bool, bool, *utils.Inventory) error {
+			order = append(order, "plugins")
+			return nil
+		}
+
+		require.NoError(t, serviceTest.service.RunInitProvisioners(context.Background()))
+		assert.Equal(t, []string{"orgs", "datasources", "plugins", "notifiers"}, order, "The default order should be used without settings")
+
+		order = nil
+		serviceTest.service.Cfg.ProvisioningOrder = []string{"notifiers", "datasources", "alert_notifications", "plugins"}
+		require.NoError(t, serviceTest.service.RunInitProvisioners(context.Background()))
+		assert.Equal(t, []string{"orgs", "notifiers", "datasources", "plugins"}, order)
+	})
+
+	t.Run("Init provisioning is traced as a single run", func(t *testing.T) {
+		tracer := useMockTracer(t)
+		serviceTest := setup()
+		serviceTest.service.provisionOrgs = func(context.Context, string, orgs.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error {
+			return nil
+		}
+		serviceTest.service.provisionNotifiers = func(context.Context, []string, notifiers.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error {
+			return nil
+		}
+		serviceTest.service.provisionDatasources = func(_ context.Context, opts datasources.ProvisionOptions) error {
+			opts.Inventory.Record(ProvisionedObject{Kind: "datasource", Name: "Prometheus", OrgID: 1})
+			return nil
+		}
+		serviceTest.service.provisionPlugins = func(context.Context, []string, plugins.Store, plugifaces.Manager, setting.ProvisioningFileFilter, bool, bool, bool, *utils.Inventory) error {
+			return errors.New("invalid plugin config")
+		}
+
+		require.Error(t, serviceTest.service.RunInitProvisioners(context.Background()))
+
+		spans := map[string]*mocktracer.MockSpan{}
+		for _, span := range tracer.FinishedSpans() {
+			spans[span.OperationName] = span
+		}
+		run := spans["provisioning init"]
+		require.NotNil(t, run)
+		assert.Equal(t, true, run.Tag("error"))
+		for _, name := range []string{"provisioning orgs", "provisioning datasources", "provisioning plugins"} {
+			require.Contains(t, spans, name)
+			assert.Equal(t, run.SpanContext.SpanID, spans[name].ParentID, "%s should be a child of the run", name)
+		}
+		assert.NotContains(t, spans, "provisioning notifiers", "Stages after the failed one don't run")
+		assert.Equal(t, 1, spans["provisioning datasources"].Tag("objects"))
+		assert.Nil(t, spans["provisioning datasources"].Tag("error"))
+		assert.Equal(t, true, spans["provisioning plugins"].Tag("error"))
+	})
+
+	t.Run("Health stays failing when init provisioning failed", func(t *testing.T) {
+		serviceTest := setup()
+		serviceTest.service.provisionOrgs = func(context.Context, string, orgs.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error {
+			return errors.New("invalid org config")
+		}
+
+		require.Error(t, serviceTest.service.RunInitProvisioners(context.Background()))
+		require.EqualError(t, serviceTest.service.Health(), "initial provisioning hasn't completed")
+	})
+
+	t.Run("Health fails while dashboard polling keeps failing and recovers with it", func(t *testing.T) {
+		serviceTest := setup()
+		serviceTest.service.provisionOrgs = func(context.Context, string, orgs.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error {
+			return nil
+		}
+		serviceTest.service.provisionNotifiers = func(context.Context, []string, notifiers.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error {
+			return nil
+		}
+		serviceTest.service.provisionDatasources = func(context.Context, datasources.ProvisionOptions) error {
+			return nil
+		}
+		serviceTest.service.provisionPlugins = func(context.Context, []string, plugins.Store, plugifaces.Manager, setting.ProvisioningFileFilter, bool, bool, bool, *utils.Inventory) error {
+			return nil
+		}
+		var failing int32
+		serviceTest.mock.PollingFailedFunc = func() error {
+			if atomic.LoadInt32(&failing) == 1 {
+				return errors.New(`polling dashboard provider "default" failed 5 times in a row`)
+			}
+			return nil
+		}
+
+		require.NoError(t, serviceTest.service.RunInitProvisioners(context.Background()))
+		serviceTest.startService()
+		serviceTest.waitForPollChanges()
+		require.NoError(t, serviceTest.service.Health())
+
+		atomic.StoreInt32(&failing, 1)
+		require.EqualError(t, serviceTest.service.Health(), `polling dashboard provider "default" failed 5 times in a row`)
+
+		atomic.StoreInt32(&failing, 0)
+		require.NoError(t, serviceTest.service.Health())
+
+		serviceTest.cancel()
+		serviceTest.waitForStop()
+	})
+
 	t.Run("Failed reloading does not stop polling with old provisioned", func(t *testing.T) {
 		serviceTest := setup()
-		err := serviceTest.service.ProvisionDashboards()
+		err := serviceTest.service.ProvisionDashboards(context.Background())
 		assert.Nil(t, err)
 		serviceTest.startService()
 		serviceTest.waitForPollChanges()
 		assert.Equal(t, 1, len(serviceTest.mock.Calls.PollChanges), "PollChanges should have been called")
 
-		serviceTest.mock.ProvisionFunc = func() error {
+		serviceTest.mock.ProvisionFunc = func(context.Context) error {
 			return errors.New("Test error")
 		}
-		err = serviceTest.service.ProvisionDashboards()
+		err = serviceTest.service.ProvisionDashboards(context.Background())
 		assert.NotNil(t, err)
 		serviceTest.waitForPollChanges()
 
@@ -62,6 +224,620 @@ func TestProvisioningServiceImpl(t *testing.T) {
 		// Cancelling the root context and stopping the service
 		serviceTest.cancel()
 	})
+
+	t.Run("Watchdog restarts stuck polling with a fresh provisioner", func(t *testing.T) {
+		serviceTest := setup()
+		serviceTest.service.Cfg.ProvisioningPollingWatchdogTimeout = 10 * time.Millisecond
+
+		var stalled int32 = 1
+		var provisionersCreated int32
+		serviceTest.service.newDashboardProvisioner = func(context.Context, []string, dboards.Store, *setting.Cfg) (dashboards.DashboardProvisioner, error) {
+			if atomic.AddInt32(&provisionersCreated, 1) > 1 {
+				// The fresh provisioner polls fine.
+				atomic.StoreInt32(&stalled, 0)
+			}
+			return serviceTest.mock, nil
+		}
+
+		polls := make(chan context.Context, 2)
+		serviceTest.mock.PollChangesFunc = func(ctx context.Context) {
+			polls <- ctx
+			if len(serviceTest.mock.Calls.PollChanges) == 1 {
+				// Simulate a hung poll.
+				<-ctx.Done()
+			}
+		}
+		serviceTest.mock.PollingStalledFunc = func(time.Duration) bool {
+			return atomic.LoadInt32(&stalled) == 1
+		}
+
+		serviceTest.startService()
+
+		var pollingContexts []context.Context
+		for i := 0; i < 2; i++ {
+			select {
+			case ctx := <-polls:
+				pollingContexts = append(pollingContexts, ctx)
+			case <-time.After(serviceTest.waitTimeout):
+				t.Fatal("Polling was not restarted")
+			}
+		}
+
+		assert.Equal(t, context.Canceled, pollingContexts[0].Err(), "Stuck polling context should have been cancelled")
+		assert.Nil(t, pollingContexts[1].Err(), "Restarted polling should still be running")
+		assert.Equal(t, int32(2), atomic.LoadInt32(&provisionersCreated), "A fresh provisioner should have been created")
+
+		serviceTest.cancel()
+		serviceTest.waitForStop()
+		assert.Equal(t, context.Canceled, serviceTest.serviceError, "Service should have returned canceled error")
+	})
+
+	t.Run("Reloading changed poll settings restarts polling with them", func(t *testing.T) {
+		configFile := filepath.Join(t.TempDir(), "custom.ini")
+		require.NoError(t, ioutil.WriteFile(configFile, []byte("[provisioning]\ndashboards_poll_interval = 30s\n"), 0600))
+
+		serviceTest := setup()
+		require.NoError(t, serviceTest.service.Cfg.Load(&setting.CommandLineArgs{HomePath: "../../../", Config: configFile}))
+
+		pollSettings := make(chan setting.ProvisioningPollSettings, 2)
+		serviceTest.service.newDashboardProvisioner = func(_ context.Context, _ []string, _ dboards.Store, cfg *setting.Cfg) (dashboards.DashboardProvisioner, error) {
+			pollSettings <- cfg.ProvisioningDashboardsPoll
+			return serviceTest.mock, nil
+		}
+
+		serviceTest.startService()
+		assert.Equal(t, setting.ProvisioningPollSettings{Interval: 30 * time.Second}, <-pollSettings)
+		serviceTest.waitForPollChanges()
+
+		// Unchanged settings keep polling as is.
+		require.NoError(t, serviceTest.service.Reload())
+		assert.Len(t, pollSettings, 0)
+
+		require.NoError(t, ioutil.WriteFile(configFile, []byte("[provisioning]\ndashboards_poll_interval = 0\ndashboards_poll_jitter = 10\n"), 0600))
+		require.NoError(t, serviceTest.service.Reload())
+		assert.Equal(t, setting.ProvisioningPollSettings{Jitter: 10}, <-pollSettings)
+
+		serviceTest.waitForPollChanges()
+		require.Len(t, serviceTest.mock.Calls.PollChanges, 2, "Polling should have been restarted")
+		pollingCtx := serviceTest.mock.Calls.PollChanges[0].(context.Context)
+		assert.Equal(t, context.Canceled, pollingCtx.Err(), "Polling with the old settings should have been cancelled")
+
+		serviceTest.cancel()
+		serviceTest.waitForStop()
+	})
+
+	t.Run("Reprovisioning a single provider leaves polling running", func(t *testing.T) {
+		serviceTest := setup()
+		err := serviceTest.service.ReprovisionProvider(context.Background(), "default")
+		require.True(t, errors.Is(err, ErrProviderNotFound), "Providers should be unknown before provisioning")
+
+		serviceTest.startService()
+		serviceTest.waitForPollChanges()
+
+		require.NoError(t, serviceTest.service.ReprovisionProvider(context.Background(), "default"))
+		assert.Equal(t, []interface{}{"default"}, serviceTest.mock.Calls.ProvisionProvider)
+		assert.Len(t, serviceTest.mock.Calls.Provision, 1, "Only the provider should have been provisioned again")
+		pollingCtx := serviceTest.mock.Calls.PollChanges[0].(context.Context)
+		assert.Nil(t, pollingCtx.Err(), "Polling should not have been restarted")
+
+		serviceTest.mock.ProvisionProviderFunc = func(_ context.Context, name string) error {
+			return fmt.Errorf("%w: %q", dashboards.ErrProviderNotFound, name)
+		}
+		err = serviceTest.service.ReprovisionProvider(context.Background(), "unknown")
+		assert.True(t, errors.Is(err, ErrProviderNotFound))
+
+		serviceTest.cancel()
+		serviceTest.waitForStop()
+	})
+
+	t.Run("Allow UI updates lookups before dashboards are provisioned", func(t *testing.T) {
+		serviceTest := setup()
+
+		assert.False(t, serviceTest.service.GetAllowUIUpdatesFromConfig("default"))
+		assert.Empty(t, serviceTest.service.GetAllowUIUpdatesMap())
+		_, ok := serviceTest.service.GetProvisioningInfo("home")
+		assert.False(t, ok)
+	})
+
+	t.Run("Provisioning info lookups after dashboards are provisioned", func(t *testing.T) {
+		serviceTest := setup()
+		info := &ProvisioningInfo{Provider: "default", File: "/var/lib/grafana/dashboards/home.json"}
+		serviceTest.mock.GetProvisioningInfoFunc = func(dashboardUID string) (*ProvisioningInfo, bool) {
+			return info, dashboardUID == "home"
+		}
+		require.NoError(t, serviceTest.service.ProvisionDashboards(context.Background()))
+
+		got, ok := serviceTest.service.GetProvisioningInfo("home")
+		require.True(t, ok)
+		assert.Equal(t, info, got)
+		_, ok = serviceTest.service.GetProvisioningInfo("other")
+		assert.False(t, ok)
+	})
+
+	t.Run("Allow UI updates lookups after dashboards are provisioned", func(t *testing.T) {
+		serviceTest := setup()
+		serviceTest.mock.GetAllowUIUpdatesFromConfigFunc = func(name string) bool {
+			return name == "editable"
+		}
+		serviceTest.mock.GetAllowUIUpdatesMapFunc = func() map[string]bool {
+			return map[string]bool{"editable": true, "default": false}
+		}
+		err := serviceTest.service.ProvisionDashboards(context.Background())
+		assert.Nil(t, err)
+
+		assert.True(t, serviceTest.service.GetAllowUIUpdatesFromConfig("editable"))
+		assert.Equal(t, map[string]bool{"editable": true, "default": false}, serviceTest.service.GetAllowUIUpdatesMap())
+	})
+
+	t.Run("Provisioning dashboards while looking up provisioner settings doesn't race", func(t *testing.T) {
+		serviceTest := setup()
+		serviceTest.mock.GetProvisionerRootPathFunc = func(name string) string {
+			return "/var/lib/grafana/dashboards/" + name
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				assert.Nil(t, serviceTest.service.ProvisionDashboards(context.Background()))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				path := serviceTest.service.GetDashboardProvisionerResolvedPath("default")
+				assert.Contains(t, []string{"", "/var/lib/grafana/dashboards/default"}, path)
+				serviceTest.service.GetAllowUIUpdatesFromConfig("default")
+				serviceTest.service.GetAllowUIUpdatesMap()
+				serviceTest.service.GetProvisioningInfo("home")
+			}
+		}()
+		wg.Wait()
+
+		assert.Equal(t, "/var/lib/grafana/dashboards/default", serviceTest.service.GetDashboardProvisionerResolvedPath("default"))
+	})
+
+	t.Run("Changed certificate files reprovision datasources", func(t *testing.T) {
+		serviceTest := setup()
+		serviceTest.service.Cfg.ProvisioningDatasourcesCertCheckInterval = 10 * time.Millisecond
+		serviceTest.mock.PollChangesFunc = func(ctx context.Context) {
+			<-ctx.Done()
+		}
+
+		var changed int32 = 1
+		serviceTest.service.certFilesChanged = func() bool {
+			return atomic.LoadInt32(&changed) == 1
+		}
+
+		reprovisioned := make(chan []string, 1)
+		serviceTest.service.provisionDatasources = func(_ context.Context, opts datasources.ProvisionOptions) error {
+			// Provisioning records the new state of the files.
+			atomic.StoreInt32(&changed, 0)
+			reprovisioned <- opts.Dirs
+			return nil
+		}
+
+		serviceTest.startService()
+
+		select {
+		case paths := <-reprovisioned:
+			assert.Equal(t, []string{filepath.Join(serviceTest.service.Cfg.ProvisioningPath, "datasources")}, paths)
+		case <-time.After(serviceTest.waitTimeout):
+			t.Fatal("Datasources were not provisioned again")
+		}
+
+		serviceTest.cancel()
+		serviceTest.waitForStop()
+	})
+
+	t.Run("Provisioning file errors can be extracted from a failed pass", func(t *testing.T) {
+		serviceTest := setup()
+		serviceTest.service.provisionDatasources = func(_ context.Context, opts datasources.ProvisionOptions) error {
+			return fmt.Errorf("failed to read datasources: %w",
+				utils.NewYAMLFileError("datasources", filepath.Join(opts.Dirs[0], "ds.yaml"), errors.New("yaml: line 4: did not find expected key")))
+		}
+
+		err := serviceTest.service.ProvisionDatasources(context.Background())
+
+		var fileErr *ProvisioningFileError
+		require.True(t, errors.As(err, &fileErr))
+		assert.Equal(t, filepath.Join(serviceTest.service.Cfg.ProvisioningPath, "datasources", "ds.yaml"), fileErr.Path)
+		assert.Equal(t, 4, fileErr.Line)
+	})
+
+	t.Run("The secret resolver of the service is passed to the provisioners", func(t *testing.T) {
+		serviceTest := setup()
+		serviceTest.service.secretResolver = SecretResolverFunc(func(context.Context, SecretRef) (string, error) {
+			return "from the service", nil
+		})
+		var secret string
+		serviceTest.service.provisionDatasources = func(ctx context.Context, _ datasources.ProvisionOptions) error {
+			var err error
+			secret, err = utils.SecretResolverFromContext(ctx).ResolveSecret(ctx, SecretRef{Scheme: "vault", Path: "grafana"})
+			return err
+		}
+
+		require.NoError(t, serviceTest.service.ProvisionDatasources(context.Background()))
+		assert.Equal(t, "from the service", secret)
+	})
+
+	t.Run("The default org is passed to the provisioners", func(t *testing.T) {
+		serviceTest := setup()
+		serviceTest.service.Cfg.ProvisioningDefaultOrgName = "Staging"
+		var defaultOrg string
+		serviceTest.service.provisionDatasources = func(ctx context.Context, _ datasources.ProvisionOptions) error {
+			defaultOrg = utils.DefaultOrgFromContext(ctx)
+			return nil
+		}
+
+		require.NoError(t, serviceTest.service.ProvisionDatasources(context.Background()))
+		assert.Equal(t, "Staging", defaultOrg)
+	})
+
+	t.Run("The context of the caller is passed to the provisioners", func(t *testing.T) {
+		serviceTest := setup()
+		type ctxKey struct{}
+		ctx := context.WithValue(context.Background(), ctxKey{}, "reload")
+
+		var datasourcesCtx, dashboardsCtx context.Context
+		serviceTest.service.provisionDatasources = func(ctx context.Context, _ datasources.ProvisionOptions) error {
+			datasourcesCtx = ctx
+			return nil
+		}
+		serviceTest.mock.ProvisionFunc = func(ctx context.Context) error {
+			dashboardsCtx = ctx
+			return nil
+		}
+
+		require.NoError(t, serviceTest.service.ProvisionDatasources(ctx))
+		require.NoError(t, serviceTest.service.ProvisionDashboards(ctx))
+		require.NotNil(t, datasourcesCtx)
+		require.NotNil(t, dashboardsCtx)
+		assert.Equal(t, "reload", datasourcesCtx.Value(ctxKey{}))
+		assert.Equal(t, "reload", dashboardsCtx.Value(ctxKey{}))
+	})
+
+	t.Run("Inventory lists the objects applied by each provisioner", func(t *testing.T) {
+		serviceTest := setup()
+		serviceTest.mock.GetProvisionedDashboardsFunc = func() []ProvisionedObject {
+			return []ProvisionedObject{{Kind: "dashboard", Name: "Home", UID: "home", OrgID: 1, File: "/dashboards/home.json"}}
+		}
+		serviceTest.service.provisionDatasources = func(_ context.Context, opts datasources.ProvisionOptions) error {
+			opts.Inventory.Record(ProvisionedObject{Kind: "datasource", Name: "Prometheus", OrgID: 2, File: "/datasources/ds.yaml"})
+			opts.Inventory.Record(ProvisionedObject{Kind: "datasource", Name: "Loki", OrgID: 1, File: "/datasources/ds.yaml"})
+			return nil
+		}
+
+		require.NoError(t, serviceTest.service.ProvisionDatasources(context.Background()))
+		assert.Len(t, serviceTest.service.GetProvisionedInventory(), 2, "Dashboards are listed once they are provisioned")
+		require.NoError(t, serviceTest.service.ProvisionDashboards(context.Background()))
+
+		inventory := serviceTest.service.GetProvisionedInventory()
+		require.Len(t, inventory, 3)
+		assert.Equal(t, "Home", inventory[0].Name)
+		assert.Equal(t, "Loki", inventory[1].Name)
+		assert.Equal(t, "Prometheus", inventory[2].Name)
+		assert.False(t, inventory[1].AppliedAt.IsZero())
+	})
+
+	t.Run("Inventory only lists the objects applied before provisioning failed", func(t *testing.T) {
+		serviceTest := setup()
+		fail := false
+		serviceTest.service.provisionDatasources = func(_ context.Context, opts datasources.ProvisionOptions) error {
+			opts.Inventory.Record(ProvisionedObject{Kind: "datasource", Name: "Loki", OrgID: 1})
+			if fail {
+				return errors.New("invalid datasource config")
+			}
+			opts.Inventory.Record(ProvisionedObject{Kind: "datasource", Name: "Prometheus", OrgID: 1})
+			return nil
+		}
+
+		require.NoError(t, serviceTest.service.ProvisionDatasources(context.Background()))
+		require.Len(t, serviceTest.service.GetProvisionedInventory(), 2)
+
+		fail = true
+		require.Error(t, serviceTest.service.ProvisionDatasources(context.Background()))
+		inventory := serviceTest.service.GetProvisionedInventory()
+		require.Len(t, inventory, 1)
+		assert.Equal(t, "Loki", inventory[0].Name)
+	})
+
+	t.Run("Datasources provisioned from a reader are returned but not added to the inventory", func(t *testing.T) {
+		serviceTest := setup()
+		bus.ClearBusHandlers()
+		t.Cleanup(bus.ClearBusHandlers)
+		bus.AddHandler("test", func(query *models.GetOrgByIdQuery) error { return nil })
+		bus.AddHandler("test", func(query *models.GetDataSourceQuery) error { return models.ErrDataSourceNotFound })
+		bus.AddHandler("test", func(cmd *models.AddDataSourceCommand) error {
+			cmd.Result = &models.DataSource{Id: 1, OrgId: cmd.OrgId, Name: cmd.Name, Uid: "generated"}
+			return nil
+		})
+		bus.AddHandler("test", func(cmd *models.SaveProvisionedDatasourceCommand) error { return nil })
+
+		doc := "apiVersion: 1\ndatasources:\n  - name: Loki\n    type: loki\n"
+		result, err := serviceTest.service.ProvisionDatasourcesFromReader(context.Background(), 2, strings.NewReader(doc))
+		require.NoError(t, err)
+		require.Len(t, result.Objects, 1)
+		assert.Equal(t, "Loki", result.Objects[0].Name)
+		assert.Equal(t, "generated", result.Objects[0].UID)
+		assert.Equal(t, int64(2), result.Objects[0].OrgID)
+		assert.Empty(t, serviceTest.service.GetProvisionedInventory())
+
+		_, err = serviceTest.service.ProvisionDatasourcesFromReader(context.Background(), 2, strings.NewReader("datasources: ["))
+		require.Error(t, err)
+		assert.True(t, strings.HasPrefix(err.Error(), "Datasource provisioning error"))
+	})
+
+	t.Run("Failed provisioning notifies the failure webhook", func(t *testing.T) {
+		t.Cleanup(bus.ClearBusHandlers)
+
+		serviceTest := setup()
+		serviceTest.service.Cfg.ProvisioningFailureWebhookURL = "http://alerts.example.com/hook"
+		serviceTest.service.provisionDatasources = func(context.Context, datasources.ProvisionOptions) error {
+			return errors.New("invalid datasource config")
+		}
+
+		sent := make(chan *models.SendWebhookSync, 1)
+		bus.AddHandlerCtx("test", func(ctx context.Context, cmd *models.SendWebhookSync) error {
+			sent <- cmd
+			return nil
+		})
+
+		err := serviceTest.service.ProvisionDatasources(context.Background())
+		require.EqualError(t, err, "Datasource provisioning error: invalid datasource config")
+
+		select {
+		case cmd := <-sent:
+			assert.Equal(t, "http://alerts.example.com/hook", cmd.Url)
+			assert.Equal(t, http.MethodPost, cmd.HttpMethod)
+			assert.Equal(t, "application/json", cmd.ContentType)
+
+			var notification failureNotification
+			require.NoError(t, json.Unmarshal([]byte(cmd.Body), &notification))
+			assert.Equal(t, "datasources", notification.Kind)
+			assert.Equal(t, "Datasource provisioning error: invalid datasource config", notification.Error)
+		case <-time.After(serviceTest.waitTimeout):
+			t.Fatal("Failure notification was not sent")
+		}
+	})
+
+	t.Run("Failing to send the failure notification keeps the provisioning error", func(t *testing.T) {
+		t.Cleanup(bus.ClearBusHandlers)
+
+		serviceTest := setup()
+		serviceTest.service.Cfg.ProvisioningFailureWebhookURL = "http://alerts.example.com/hook"
+		serviceTest.mock.ProvisionFunc = func(context.Context) error {
+			return errors.New("dashboard folder missing")
+		}
+
+		sent := make(chan string, 1)
+		bus.AddHandlerCtx("test", func(ctx context.Context, cmd *models.SendWebhookSync) error {
+			sent <- cmd.Body
+			return errors.New("connection refused")
+		})
+
+		err := serviceTest.service.ProvisionDashboards(context.Background())
+		require.EqualError(t, err, "Failed to provision dashboards: dashboard folder missing")
+
+		select {
+		case body := <-sent:
+			assert.Contains(t, body, `"kind":"dashboards"`)
+		case <-time.After(serviceTest.waitTimeout):
+			t.Fatal("Failure notification was not sent")
+		}
+	})
+
+	t.Run("Successful provisioning sends no failure notification", func(t *testing.T) {
+		t.Cleanup(bus.ClearBusHandlers)
+
+		serviceTest := setup()
+		serviceTest.service.Cfg.ProvisioningFailureWebhookURL = "http://alerts.example.com/hook"
+		serviceTest.service.provisionDatasources = func(context.Context, datasources.ProvisionOptions) error {
+			return nil
+		}
+
+		var sent int32
+		bus.AddHandlerCtx("test", func(ctx context.Context, cmd *models.SendWebhookSync) error {
+			atomic.AddInt32(&sent, 1)
+			return nil
+		})
+
+		require.NoError(t, serviceTest.service.ProvisionDatasources(context.Background()))
+		assert.Equal(t, int32(0), atomic.LoadInt32(&sent))
+	})
+
+	t.Run("Provisions the per-org directories after the directory of each provisioning path", func(t *testing.T) {
+		base := t.TempDir()
+		for _, dir := range []string{"datasources", "orgs/2/datasources", "orgs/2/dashboards", "orgs/3/datasources",
+			"orgs/team/datasources", "orgs/4"} {
+			require.NoError(t, os.MkdirAll(filepath.Join(base, dir), 0750))
+		}
+
+		serviceTest := setup()
+		serviceTest.service.Cfg.ProvisioningPaths = []string{base}
+		var datasourceDirs, dashboardDirs []string
+		serviceTest.service.provisionDatasources = func(_ context.Context, opts datasources.ProvisionOptions) error {
+			datasourceDirs = opts.Dirs
+			return nil
+		}
+		serviceTest.service.newDashboardProvisioner = func(_ context.Context, dirs []string, _ dboards.Store, _ *setting.Cfg) (dashboards.DashboardProvisioner, error) {
+			dashboardDirs = dirs
+			return serviceTest.mock, nil
+		}
+
+		require.NoError(t, serviceTest.service.ProvisionDatasources(context.Background()))
+		require.NoError(t, serviceTest.service.ProvisionDashboards(context.Background()))
+
+		assert.Equal(t, []string{
+			filepath.Join(base, "datasources"),
+			filepath.Join(base, "orgs", "2", "datasources"),
+			filepath.Join(base, "orgs", "3", "datasources"),
+		}, datasourceDirs)
+		assert.Equal(t, []string{
+			filepath.Join(base, "dashboards"),
+			filepath.Join(base, "orgs", "2", "dashboards"),
+		}, dashboardDirs)
+	})
+
+	t.Run("Missing directories fail provisioning when fail_on_missing_dir is set", func(t *testing.T) {
+		base := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(base, "datasources"), 0750))
+
+		serviceTest := setup()
+		serviceTest.service.Cfg.ProvisioningPaths = []string{base}
+		serviceTest.service.Cfg.ProvisioningFailOnMissingDir = true
+		serviceTest.service.provisionDatasources = func(context.Context, datasources.ProvisionOptions) error {
+			return nil
+		}
+		serviceTest.service.provisionPlugins = func(context.Context, []string, plugins.Store, plugifaces.Manager, setting.ProvisioningFileFilter, bool, bool, bool, *utils.Inventory) error {
+			t.Fatal("plugins are provisioned without a plugins directory")
+			return nil
+		}
+
+		require.NoError(t, serviceTest.service.ProvisionDatasources(context.Background()), "An empty directory is fine")
+
+		err := serviceTest.service.ProvisionPlugins(context.Background())
+		require.True(t, errors.Is(err, ErrMissingProvisioningDir))
+		assert.Contains(t, err.Error(), filepath.Join(base, "plugins"))
+
+		serviceTest.service.Cfg.ProvisioningFailOnMissingDir = false
+		serviceTest.service.provisionPlugins = func(context.Context, []string, plugins.Store, plugifaces.Manager, setting.ProvisioningFileFilter, bool, bool, bool, *utils.Inventory) error {
+			return nil
+		}
+		require.NoError(t, serviceTest.service.ProvisionPlugins(context.Background()))
+	})
+
+	t.Run("Diffing dashboards doesn't provision them", func(t *testing.T) {
+		serviceTest := setup()
+		expected := utils.NewDiff("dashboards")
+		expected.Added = append(expected.Added, utils.DiffObject{Kind: "dashboard", Name: "New", OrgID: 1})
+		serviceTest.mock.DiffFunc = func(context.Context) (*utils.Diff, error) {
+			return expected, nil
+		}
+
+		diff, err := serviceTest.service.DiffProvisioning(context.Background(), "dashboards")
+		require.NoError(t, err)
+		assert.Equal(t, expected, diff)
+		assert.Len(t, serviceTest.mock.Calls.Diff, 1)
+		assert.Empty(t, serviceTest.mock.Calls.Provision)
+	})
+
+	t.Run("Provisioners write through the provisioning store", func(t *testing.T) {
+		serviceTest := setup()
+		store := &fakeProvisioningStore{}
+		serviceTest.service.store = store
+		serviceTest.service.Cfg.ProvisioningPath = t.TempDir()
+		serviceTest.service.Cfg.FeatureToggles = map[string]bool{"ngalert": true}
+		var dashboardStore dboards.Store
+		serviceTest.service.newDashboardProvisioner = func(_ context.Context, _ []string, store dboards.Store, _ *setting.Cfg) (dashboards.DashboardProvisioner, error) {
+			dashboardStore = store
+			return serviceTest.mock, nil
+		}
+		var ruleStore alerting.RuleStore
+		serviceTest.service.provisionAlertRules = func(_ context.Context, _ string, store alerting.RuleStore, _ setting.ProvisioningFileFilter, _ bool, _ *utils.Inventory) error {
+			ruleStore = store
+			return nil
+		}
+
+		var orgStore orgs.Store
+		serviceTest.service.provisionOrgs = func(_ context.Context, _ string, store orgs.Store, _ setting.ProvisioningFileFilter, _ bool, _ *utils.Inventory) error {
+			orgStore = store
+			return nil
+		}
+		var datasourceStore datasources.Store
+		serviceTest.service.provisionDatasources = func(_ context.Context, opts datasources.ProvisionOptions) error {
+			datasourceStore = opts.Store
+			return nil
+		}
+		var pluginStore plugins.Store
+		serviceTest.service.provisionPlugins = func(_ context.Context, _ []string, store plugins.Store, _ plugifaces.Manager, _ setting.ProvisioningFileFilter, _ bool, _ bool, _ bool, _ *utils.Inventory) error {
+			pluginStore = store
+			return nil
+		}
+		var notifierStore notifiers.Store
+		serviceTest.service.provisionNotifiers = func(_ context.Context, _ []string, store notifiers.Store, _ setting.ProvisioningFileFilter, _ bool, _ *utils.Inventory) error {
+			notifierStore = store
+			return nil
+		}
+
+		require.NoError(t, serviceTest.service.ProvisionDashboards(context.Background()))
+		require.NoError(t, serviceTest.service.ProvisionAlertRules(context.Background()))
+		require.NoError(t, serviceTest.service.ProvisionOrgs(context.Background()))
+		require.NoError(t, serviceTest.service.ProvisionDatasources(context.Background()))
+		require.NoError(t, serviceTest.service.ProvisionPlugins(context.Background()))
+		require.NoError(t, serviceTest.service.ProvisionNotifications(context.Background()))
+		assert.Same(t, store, dashboardStore)
+		assert.Same(t, store, ruleStore)
+		assert.Same(t, store, orgStore)
+		assert.Same(t, store, datasourceStore)
+		assert.Same(t, store, pluginStore)
+		assert.Same(t, store, notifierStore)
+	})
+
+	t.Run("Diffing an unsupported kind fails", func(t *testing.T) {
+		serviceTest := setup()
+		_, err := serviceTest.service.DiffProvisioning(context.Background(), "notifiers")
+		require.True(t, errors.Is(err, ErrUnsupportedDiffKind))
+	})
+
+	t.Run("Reloading returns what the run applied", func(t *testing.T) {
+		serviceTest := setup()
+		fail := false
+		serviceTest.service.provisionDatasources = func(_ context.Context, opts datasources.ProvisionOptions) error {
+			if fail {
+				return &ProvisioningFileError{Subsystem: "datasources", Path: "/ds.yaml", Err: errors.New("invalid")}
+			}
+			opts.Inventory.Record(ProvisionedObject{Kind: "datasource", Name: "Prometheus", OrgID: 1, Action: utils.ActionCreated})
+			return nil
+		}
+
+		result, err := serviceTest.service.ReloadProvisioning(context.Background(), "datasources")
+		require.NoError(t, err)
+		require.Len(t, result.Objects, 1)
+		assert.Equal(t, ProvisionCounts{Applied: 1, Created: 1}, result.Counts())
+
+		fail = true
+		_, err = serviceTest.service.ReloadProvisioning(context.Background(), "datasources")
+		require.Error(t, err)
+		assert.True(t, IsInvalidConfig(err))
+		require.Len(t, FileErrorsOf(err), 1)
+		assert.Equal(t, "/ds.yaml", FileErrorsOf(err)[0].Path)
+
+		_, err = serviceTest.service.ReloadProvisioning(context.Background(), "orgs")
+		require.True(t, errors.Is(err, ErrUnsupportedReloadKind))
+	})
+
+	t.Run("Reloading doesn't cancel the run when the caller goes away", func(t *testing.T) {
+		serviceTest := setup()
+		started := make(chan struct{})
+		release := make(chan struct{})
+		finished := make(chan error, 1)
+		serviceTest.service.provisionDatasources = func(ctx context.Context, _ datasources.ProvisionOptions) error {
+			close(started)
+			<-release
+			finished <- ctx.Err()
+			return nil
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		reloaded := make(chan error, 1)
+		go func() {
+			_, err := serviceTest.service.ReloadProvisioning(ctx, "datasources")
+			reloaded <- err
+		}()
+		<-started
+		cancel()
+		require.True(t, errors.Is(<-reloaded, context.Canceled), "The caller stops waiting once its context is done")
+
+		close(release)
+		require.NoError(t, <-finished, "The run shouldn't see the context of the caller")
+	})
+}
+
+// fakeProvisioningStore stands in for the SQL store. It implements none of the methods, so calls that reach it panic.
+type fakeProvisioningStore struct {
+	ProvisioningStore
 }
 
 type serviceTestStruct struct {
@@ -79,6 +855,224 @@ type serviceTestStruct struct {
 	service *provisioningServiceImpl
 }
 
+// useMockTracer makes the global tracer record the spans of the test.
+func useMockTracer(t *testing.T) *mocktracer.MockTracer {
+	t.Helper()
+	tracer := mocktracer.New()
+	previous := opentracing.GlobalTracer()
+	opentracing.SetGlobalTracer(tracer)
+	t.Cleanup(func() { opentracing.SetGlobalTracer(previous) })
+	return tracer
+}
+
+func TestInventoryDuringReload(t *testing.T) {
+	serviceTest := setup()
+	const objects = 20
+	record := func(kind string, inventory *utils.Inventory) {
+		for i := 0; i < objects; i++ {
+			inventory.Record(ProvisionedObject{Kind: kind, Name: fmt.Sprintf("%s %d", kind, i), OrgID: 1})
+			time.Sleep(time.Microsecond)
+		}
+	}
+	serviceTest.service.provisionDatasources = func(_ context.Context, opts datasources.ProvisionOptions) error {
+		record("datasource", opts.Inventory)
+		return nil
+	}
+	serviceTest.service.provisionNotifiers = func(_ context.Context, _ []string, _ notifiers.Store, _ setting.ProvisioningFileFilter, _ bool, inventory *utils.Inventory) error {
+		record("notifier", inventory)
+		return nil
+	}
+
+	var reloads sync.WaitGroup
+	for _, kind := range []string{"datasources", "notifiers"} {
+		for i := 0; i < 3; i++ {
+			reloads.Add(1)
+			go func(kind string) {
+				defer reloads.Done()
+				for j := 0; j < 5; j++ {
+					_, err := serviceTest.service.ReloadProvisioning(context.Background(), kind)
+					assert.NoError(t, err)
+				}
+			}(kind)
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		reloads.Wait()
+		close(done)
+	}()
+
+	for reading := true; reading; {
+		select {
+		case <-done:
+			reading = false
+		default:
+		}
+		byKind := map[string]int{}
+		for _, object := range serviceTest.service.GetProvisionedInventory() {
+			byKind[object.Kind]++
+		}
+		for kind, count := range byKind {
+			require.Equal(t, objects, count, "The %s objects of a reload are listed once it's done", kind)
+		}
+	}
+}
+
+func TestApplyOnce(t *testing.T) {
+	t.Run("Run stops the server without polling once provisioning was applied", func(t *testing.T) {
+		serviceTest := setup()
+		serviceTest.service.Cfg.ProvisioningApplyOnce = true
+
+		err := serviceTest.service.Run(context.Background())
+		require.True(t, errors.Is(err, registry.ErrStopServer))
+		assert.Len(t, serviceTest.mock.Calls.Provision, 1)
+		assert.Empty(t, serviceTest.mock.Calls.PollChanges)
+	})
+
+	t.Run("Run fails the server when the dashboards fail", func(t *testing.T) {
+		serviceTest := setup()
+		serviceTest.service.Cfg.ProvisioningApplyOnce = true
+		serviceTest.mock.ProvisionFunc = func(context.Context) error {
+			return errors.New("invalid dashboard")
+		}
+
+		err := serviceTest.service.Run(context.Background())
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, registry.ErrStopServer))
+		assert.Empty(t, serviceTest.mock.Calls.PollChanges)
+	})
+}
+
+func TestProvisionNow(t *testing.T) {
+	setupProvisionNow := func() (*serviceTestStruct, *int32) {
+		serviceTest := setup()
+		var initRuns int32
+		serviceTest.service.provisionOrgs = func(context.Context, string, orgs.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error {
+			atomic.AddInt32(&initRuns, 1)
+			return nil
+		}
+		serviceTest.service.provisionNotifiers = func(context.Context, []string, notifiers.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error {
+			return nil
+		}
+		serviceTest.service.provisionDatasources = func(context.Context, datasources.ProvisionOptions) error {
+			return nil
+		}
+		serviceTest.service.provisionPlugins = func(context.Context, []string, plugins.Store, plugifaces.Manager, setting.ProvisioningFileFilter, bool, bool, bool, *utils.Inventory) error {
+			return nil
+		}
+		return serviceTest, &initRuns
+	}
+
+	t.Run("Runs a full cycle without polling", func(t *testing.T) {
+		serviceTest, initRuns := setupProvisionNow()
+
+		require.NoError(t, serviceTest.service.ProvisionNow(context.Background()))
+		assert.Equal(t, int32(1), *initRuns)
+		assert.Len(t, serviceTest.mock.Calls.Provision, 1)
+		assert.Empty(t, serviceTest.mock.Calls.PollChanges)
+		require.NoError(t, serviceTest.service.Health())
+	})
+
+	t.Run("Returns the error of the dashboards", func(t *testing.T) {
+		serviceTest, _ := setupProvisionNow()
+		serviceTest.mock.ProvisionFunc = func(context.Context) error {
+			return errors.New("invalid dashboard")
+		}
+
+		err := serviceTest.service.ProvisionNow(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid dashboard")
+	})
+
+	t.Run("Doesn't interleave with the first pass of a running service", func(t *testing.T) {
+		serviceTest, _ := setupProvisionNow()
+		type cycleKey struct{}
+		var mutex sync.Mutex
+		var events []string
+		record := func(ctx context.Context, event string) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if cycle, ok := ctx.Value(cycleKey{}).(string); ok {
+				event = cycle + " " + event
+			} else {
+				event = "run " + event
+			}
+			events = append(events, event)
+		}
+		serviceTest.service.provisionOrgs = func(ctx context.Context, _ string, _ orgs.Store, _ setting.ProvisioningFileFilter, _ bool, _ *utils.Inventory) error {
+			record(ctx, "orgs")
+			// Gives the service the time to start its first pass, if it could.
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		}
+		serviceTest.mock.ProvisionFunc = func(ctx context.Context) error {
+			record(ctx, "dashboards")
+			return nil
+		}
+
+		serviceTest.startService()
+		require.NoError(t, serviceTest.service.ProvisionNow(context.WithValue(context.Background(), cycleKey{}, "now")))
+		serviceTest.waitForPollChanges()
+		serviceTest.cancel()
+		serviceTest.waitForStop()
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		assert.Contains(t, [][]string{
+			{"now orgs", "now dashboards", "run dashboards"},
+			{"run dashboards", "now orgs", "now dashboards"},
+		}, events)
+	})
+}
+
+func TestProvisionLibraryPanels(t *testing.T) {
+	setupLibraryPanels := func(t *testing.T, provision func() error) (*serviceTestStruct, *[]string) {
+		serviceTest := setup()
+		serviceTest.service.Cfg.FeatureToggles = map[string]bool{"panelLibrary": true}
+		serviceTest.service.Cfg.ProvisioningApplyOnce = true
+		serviceTest.service.Cfg.ProvisioningPath = t.TempDir()
+		serviceTest.service.store = &fakeProvisioningStore{}
+		var order []string
+		serviceTest.service.provisionLibraryPanels = func(_ context.Context, dirs []string, store librarypanels.Store,
+			_ setting.ProvisioningFileFilter, _ bool, _ *utils.Inventory) error {
+			assert.Equal(t, []string{filepath.Join(serviceTest.service.Cfg.ProvisioningPath, "library-panels")}, dirs)
+			assert.Same(t, serviceTest.service.store, store)
+			order = append(order, "library panels")
+			return provision()
+		}
+		serviceTest.mock.ProvisionFunc = func(context.Context) error {
+			order = append(order, "dashboards")
+			return nil
+		}
+		return serviceTest, &order
+	}
+
+	t.Run("Run provisions the library panels before the dashboards", func(t *testing.T) {
+		serviceTest, order := setupLibraryPanels(t, func() error { return nil })
+
+		err := serviceTest.service.Run(context.Background())
+		require.True(t, errors.Is(err, registry.ErrStopServer))
+		assert.Equal(t, []string{"library panels", "dashboards"}, *order)
+	})
+
+	t.Run("Dashboards aren't provisioned when the library panels fail", func(t *testing.T) {
+		serviceTest, order := setupLibraryPanels(t, func() error { return errors.New("invalid library panel") })
+
+		err := serviceTest.service.Run(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid library panel")
+		assert.Equal(t, []string{"library panels"}, *order)
+	})
+
+	t.Run("Library panels aren't provisioned without the panel library", func(t *testing.T) {
+		serviceTest, order := setupLibraryPanels(t, func() error { return nil })
+		serviceTest.service.Cfg.FeatureToggles = nil
+
+		require.NoError(t, serviceTest.service.ProvisionLibraryPanels(context.Background()))
+		assert.Empty(t, *order)
+	})
+}
+
 func setup() *serviceTestStruct {
 	serviceTest := &serviceTestStruct{}
 	serviceTest.waitTimeout = time.Second
@@ -92,12 +1086,16 @@ func setup() *serviceTestStruct {
 	}
 
 	serviceTest.service = newProvisioningServiceImpl(
-		func(string, dboards.Store) (dashboards.DashboardProvisioner, error) {
+		func(context.Context, []string, dboards.Store, *setting.Cfg) (dashboards.DashboardProvisioner, error) {
 			return serviceTest.mock, nil
 		},
 		nil,
 		nil,
 		nil,
+		nil,
+		nil,
+		nil,
+		nil,
 	)
 	serviceTest.service.Cfg = setting.NewCfg()
 
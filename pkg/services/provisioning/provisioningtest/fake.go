@@ -0,0 +1,304 @@
+// Package provisioningtest provides a fake of the provisioning service for the tests of services that depend on it.
+package provisioningtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/grafana/grafana/pkg/services/provisioning"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+)
+
+var _ provisioning.ProvisioningService = (*FakeProvisioningService)(nil)
+
+// FakeProvisioningService is a provisioning.ProvisioningService that doesn't touch the disk or the database. Its
+// methods return the values of the matching fields, so the zero value succeeds without provisioning anything, and
+// count how often they're called. The fields must be set before the fake is used; it's safe for concurrent use
+// after that.
+type FakeProvisioningService struct {
+	RunInitProvisionersError         error
+	ProvisionNowError                error
+	ProvisionOrgsError               error
+	ProvisionDatasourcesError        error
+	ProvisionPluginsError            error
+	ProvisionNotificationsError      error
+	ProvisionLibraryPanelsError      error
+	ProvisionDashboardsError         error
+	ProvisionAlertRulesError         error
+	ProvisionAlertNotificationsError error
+	// ProvisionFromReaderError is returned by ProvisionDatasourcesFromReader and ProvisionNotificationsFromReader,
+	// which read r to the end first.
+	ProvisionFromReaderError error
+	// ReprovisionProviderError is returned by ReprovisionProvider, unless ProviderErrors has an error for the
+	// provider.
+	ReprovisionProviderError error
+	ProviderErrors           map[string]error
+	HealthError              error
+	SelfTestError            error
+	RunError                 error
+
+	// ResolvedPaths and AllowUIUpdates are the paths and allowUiUpdates of the dashboard providers, by name.
+	ResolvedPaths  map[string]string
+	AllowUIUpdates map[string]bool
+	Inventory      []provisioning.ProvisionedObject
+	// ProvisioningInfo is the provisioning info of the dashboards, by uid.
+	ProvisioningInfo map[string]*provisioning.ProvisioningInfo
+
+	ExportedState                []byte
+	ExportProvisioningStateError error
+	ImportProvisioningStateError error
+
+	// Diffs are returned by DiffProvisioning by kind, an empty diff for kinds without one.
+	Diffs                 map[string]*provisioning.ProvisioningDiff
+	DiffProvisioningError error
+
+	// Results are returned by ReloadProvisioning by kind, an empty result for kinds without one. ReloadProvisioning
+	// fails with the error of the Provision method of the kind.
+	Results map[string]*provisioning.ProvisionResult
+
+	// ExportProvisioningError is returned by ExportProvisioning, which doesn't write anything.
+	ExportProvisioningError error
+
+	// DeferredDashboards are the uids of the dashboards ProvisionDeferredDashboard provisions, by org.
+	DeferredDashboards              map[int64][]string
+	ProvisionDeferredDashboardError error
+
+	mutex     sync.Mutex
+	calls     map[string]int
+	providers []string
+	observers []provisioning.ProvisioningObserver
+}
+
+// CallCount returns how often the method with the given name, like "ProvisionDatasources", was called.
+func (f *FakeProvisioningService) CallCount(method string) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.calls[method]
+}
+
+// ReprovisionedProviders returns the names ReprovisionProvider was called with, in order.
+func (f *FakeProvisioningService) ReprovisionedProviders() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string{}, f.providers...)
+}
+
+// Observers returns the observers that were registered, in order.
+func (f *FakeProvisioningService) Observers() []provisioning.ProvisioningObserver {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]provisioning.ProvisioningObserver{}, f.observers...)
+}
+
+func (f *FakeProvisioningService) record(method string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.calls == nil {
+		f.calls = map[string]int{}
+	}
+	f.calls[method]++
+}
+
+func (f *FakeProvisioningService) Run(context.Context) error {
+	f.record("Run")
+	return f.RunError
+}
+
+func (f *FakeProvisioningService) RunInitProvisioners(context.Context) error {
+	f.record("RunInitProvisioners")
+	return f.RunInitProvisionersError
+}
+
+func (f *FakeProvisioningService) ProvisionNow(context.Context) error {
+	f.record("ProvisionNow")
+	return f.ProvisionNowError
+}
+
+func (f *FakeProvisioningService) ProvisionOrgs(context.Context) error {
+	f.record("ProvisionOrgs")
+	return f.ProvisionOrgsError
+}
+
+func (f *FakeProvisioningService) ProvisionDatasources(context.Context) error {
+	f.record("ProvisionDatasources")
+	return f.ProvisionDatasourcesError
+}
+
+func (f *FakeProvisioningService) ProvisionPlugins(context.Context) error {
+	f.record("ProvisionPlugins")
+	return f.ProvisionPluginsError
+}
+
+func (f *FakeProvisioningService) ProvisionNotifications(context.Context) error {
+	f.record("ProvisionNotifications")
+	return f.ProvisionNotificationsError
+}
+
+func (f *FakeProvisioningService) ProvisionDatasourcesFromReader(_ context.Context, _ int64, r io.Reader) (*provisioning.ProvisionResult, error) {
+	f.record("ProvisionDatasourcesFromReader")
+	return f.provisionFromReader(r)
+}
+
+func (f *FakeProvisioningService) ProvisionNotificationsFromReader(_ context.Context, _ int64, r io.Reader) (*provisioning.ProvisionResult, error) {
+	f.record("ProvisionNotificationsFromReader")
+	return f.provisionFromReader(r)
+}
+
+func (f *FakeProvisioningService) provisionFromReader(r io.Reader) (*provisioning.ProvisionResult, error) {
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return nil, err
+	}
+	if f.ProvisionFromReaderError != nil {
+		return nil, f.ProvisionFromReaderError
+	}
+	return &provisioning.ProvisionResult{}, nil
+}
+
+func (f *FakeProvisioningService) ProvisionLibraryPanels(context.Context) error {
+	f.record("ProvisionLibraryPanels")
+	return f.ProvisionLibraryPanelsError
+}
+
+func (f *FakeProvisioningService) ProvisionDashboards(context.Context) error {
+	f.record("ProvisionDashboards")
+	return f.ProvisionDashboardsError
+}
+
+func (f *FakeProvisioningService) ReprovisionProvider(_ context.Context, name string) error {
+	f.record("ReprovisionProvider")
+	f.mutex.Lock()
+	f.providers = append(f.providers, name)
+	f.mutex.Unlock()
+
+	if err, ok := f.ProviderErrors[name]; ok {
+		return err
+	}
+	return f.ReprovisionProviderError
+}
+
+func (f *FakeProvisioningService) ProvisionAlertRules(context.Context) error {
+	f.record("ProvisionAlertRules")
+	return f.ProvisionAlertRulesError
+}
+
+func (f *FakeProvisioningService) ProvisionAlertNotifications(context.Context) error {
+	f.record("ProvisionAlertNotifications")
+	return f.ProvisionAlertNotificationsError
+}
+
+func (f *FakeProvisioningService) GetDashboardProvisionerResolvedPath(name string) string {
+	f.record("GetDashboardProvisionerResolvedPath")
+	return f.ResolvedPaths[name]
+}
+
+func (f *FakeProvisioningService) GetAllowUIUpdatesFromConfig(name string) bool {
+	f.record("GetAllowUIUpdatesFromConfig")
+	return f.AllowUIUpdates[name]
+}
+
+func (f *FakeProvisioningService) GetAllowUIUpdatesMap() map[string]bool {
+	f.record("GetAllowUIUpdatesMap")
+	allowUIUpdates := make(map[string]bool, len(f.AllowUIUpdates))
+	for name, allowed := range f.AllowUIUpdates {
+		allowUIUpdates[name] = allowed
+	}
+	return allowUIUpdates
+}
+
+func (f *FakeProvisioningService) GetProvisioningInfo(dashboardUID string) (*provisioning.ProvisioningInfo, bool) {
+	f.record("GetProvisioningInfo")
+	info, ok := f.ProvisioningInfo[dashboardUID]
+	if !ok {
+		return nil, false
+	}
+	copied := *info
+	return &copied, true
+}
+
+func (f *FakeProvisioningService) GetProvisionedInventory() []provisioning.ProvisionedObject {
+	f.record("GetProvisionedInventory")
+	return append([]provisioning.ProvisionedObject{}, f.Inventory...)
+}
+
+func (f *FakeProvisioningService) Health() error {
+	f.record("Health")
+	return f.HealthError
+}
+
+func (f *FakeProvisioningService) SelfTest(context.Context) error {
+	f.record("SelfTest")
+	return f.SelfTestError
+}
+
+func (f *FakeProvisioningService) ExportProvisioningState(context.Context) ([]byte, error) {
+	f.record("ExportProvisioningState")
+	return f.ExportedState, f.ExportProvisioningStateError
+}
+
+func (f *FakeProvisioningService) ImportProvisioningState(context.Context, []byte) error {
+	f.record("ImportProvisioningState")
+	return f.ImportProvisioningStateError
+}
+
+func (f *FakeProvisioningService) DiffProvisioning(_ context.Context, kind string) (*provisioning.ProvisioningDiff, error) {
+	f.record("DiffProvisioning")
+	if f.DiffProvisioningError != nil {
+		return nil, f.DiffProvisioningError
+	}
+	if diff, ok := f.Diffs[kind]; ok {
+		return diff, nil
+	}
+	return utils.NewDiff(kind), nil
+}
+
+func (f *FakeProvisioningService) ReloadProvisioning(_ context.Context, kind string) (*provisioning.ProvisionResult, error) {
+	f.record("ReloadProvisioning")
+	var err error
+	switch kind {
+	case "datasources":
+		err = f.ProvisionDatasourcesError
+	case "plugins":
+		err = f.ProvisionPluginsError
+	case "notifiers":
+		err = f.ProvisionNotificationsError
+	case "dashboards":
+		err = f.ProvisionDashboardsError
+	default:
+		return nil, fmt.Errorf("%w: %q", provisioning.ErrUnsupportedReloadKind, kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if result, ok := f.Results[kind]; ok {
+		return result, nil
+	}
+	return &provisioning.ProvisionResult{}, nil
+}
+
+func (f *FakeProvisioningService) ExportProvisioning(_ context.Context, _ string, _ string, _ bool) error {
+	f.record("ExportProvisioning")
+	return f.ExportProvisioningError
+}
+
+func (f *FakeProvisioningService) ProvisionDeferredDashboard(_ context.Context, orgID int64, uid string) (bool, error) {
+	f.record("ProvisionDeferredDashboard")
+	if f.ProvisionDeferredDashboardError != nil {
+		return false, f.ProvisionDeferredDashboardError
+	}
+	for _, deferredUID := range f.DeferredDashboards[orgID] {
+		if deferredUID == uid {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *FakeProvisioningService) RegisterObserver(observer provisioning.ProvisioningObserver) {
+	f.record("RegisterObserver")
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.observers = append(f.observers, observer)
+}
@@ -0,0 +1,57 @@
+package provisioning
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/provisioning/datasources"
+	"github.com/grafana/grafana/pkg/services/provisioning/notifiers"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/util/errutil"
+	"github.com/opentracing/opentracing-go"
+)
+
+// ProvisionDatasourcesFromReader provisions the datasources of a single config document read from r, like a file of
+// the datasources provisioning directory. Datasources without an orgId are provisioned in orgID, unless it's 0.
+func (ps *provisioningServiceImpl) ProvisionDatasourcesFromReader(ctx context.Context, orgID int64, r io.Reader) (*ProvisionResult, error) {
+	return ps.provisionFromReader(ctx, "datasources", func(ctx context.Context, inventory *utils.Inventory) error {
+		healthCheck := datasources.HealthCheckSettings{
+			Mode:    datasources.HealthCheckMode(ps.Cfg.ProvisioningDatasourcesHealthCheck),
+			Timeout: ps.Cfg.ProvisioningDatasourcesHealthTimeout,
+			Check:   ps.checkDatasourceHealth,
+		}
+		err := datasources.ProvisionFromReader(ctx, orgID, r, ps.provisioningStore(),
+			ps.Cfg.ProvisioningStrictFields["datasources"], healthCheck, inventory)
+		return errutil.Wrap("Datasource provisioning error", err)
+	})
+}
+
+// ProvisionNotificationsFromReader provisions the alert notifiers of a single config document read from r, like a
+// file of the notifiers provisioning directory. Notifiers without an orgId or orgName are provisioned in orgID,
+// unless it's 0.
+func (ps *provisioningServiceImpl) ProvisionNotificationsFromReader(ctx context.Context, orgID int64, r io.Reader) (*ProvisionResult, error) {
+	return ps.provisionFromReader(ctx, "notifiers", func(ctx context.Context, inventory *utils.Inventory) error {
+		err := notifiers.ProvisionFromReader(ctx, orgID, r, ps.provisioningStore(), ps.Cfg.ProvisioningStrictFields["notifiers"],
+			inventory)
+		return errutil.Wrap("Alert notification provisioning error", err)
+	})
+}
+
+// provisionFromReader traces a provisioning run of an in-memory config document. These runs aren't coalesced with
+// the runs of the provisioning directories and neither notify the observers nor replace the inventory, since the
+// next run of the directories doesn't include the document.
+func (ps *provisioningServiceImpl) provisionFromReader(ctx context.Context, name string,
+	provision func(ctx context.Context, inventory *utils.Inventory) error) (*ProvisionResult, error) {
+	span, ctx := opentracing.StartSpanFromContext(ps.withEnvironment(ctx), "provisioning "+name+" from reader")
+	start := time.Now()
+	inventory := utils.NewInventory()
+	err := provision(ctx, inventory)
+	span.SetTag("objects", len(inventory.Objects()))
+	utils.FinishSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProvisionResult{Objects: inventory.Objects(), Duration: time.Since(start)}, nil
+}
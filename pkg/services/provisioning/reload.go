@@ -0,0 +1,126 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/opentracing/opentracing-go"
+)
+
+// ErrUnsupportedReloadKind is returned, wrapped with the kind, by ReloadProvisioning for kinds it can't reload.
+var ErrUnsupportedReloadKind = errors.New("provisioning kind can't be reloaded")
+
+// ErrInvalidConfig is matched with errors.Is by the errors of the Provision methods that were caused by invalid
+// provisioning files, rather than by failing to apply valid ones.
+var ErrInvalidConfig = utils.ErrInvalidConfig
+
+// FileErrors are the ProvisioningFileErrors of every invalid file of a provisioning run.
+type FileErrors = utils.FileErrors
+
+// IsInvalidConfig reports whether err was caused by invalid provisioning files. Such errors are fixed by changing
+// the files, while retrying the same files may fix the others.
+func IsInvalidConfig(err error) bool {
+	return utils.IsInvalidConfig(err)
+}
+
+// FileErrorsOf returns the errors of the invalid files err was caused by, or nil when it doesn't name any file.
+func FileErrorsOf(err error) []*ProvisioningFileError {
+	return utils.FileErrorsOf(err)
+}
+
+// ProvisionCounts are the objects a provisioning run applied, by what it did with them.
+type ProvisionCounts struct {
+	// Applied counts every object the run applied or kept, Created, Updated and Skipped the ones whose provisioner
+	// tells what it did with them.
+	Applied int `json:"applied"`
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+	Deleted int `json:"deleted"`
+}
+
+// Counts counts the objects of the result.
+func (r ProvisionResult) Counts() ProvisionCounts {
+	counts := ProvisionCounts{Applied: len(r.Objects), Deleted: len(r.Deleted)}
+	for _, object := range r.Objects {
+		switch object.Action {
+		case utils.ActionCreated:
+			counts.Created++
+		case utils.ActionUpdated:
+			counts.Updated++
+		case utils.ActionSkipped:
+			counts.Skipped++
+		}
+	}
+	return counts
+}
+
+// ReloadProvisioning provisions the files of a kind again, like its Provision method, and returns what the run
+// applied. The kinds are datasources, plugins, notifiers and dashboards. The run is detached from ctx, which only
+// bounds the wait for it, so a caller that goes away, like an HTTP client that disconnects, doesn't cancel a run
+// that coalesced triggers share. The run is canceled with the context of Run, or at the timeout of its subsystem.
+func (ps *provisioningServiceImpl) ReloadProvisioning(ctx context.Context, kind string) (*ProvisionResult, error) {
+	var provision func(context.Context) error
+	switch kind {
+	case "datasources":
+		provision = ps.ProvisionDatasources
+	case "plugins":
+		provision = ps.ProvisionPlugins
+	case "notifiers":
+		provision = ps.ProvisionNotifications
+	case "dashboards":
+		provision = ps.ProvisionDashboards
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedReloadKind, kind)
+	}
+
+	runCtx := ps.serviceContext()
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		runCtx = opentracing.ContextWithSpan(runCtx, span)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- provision(runCtx)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, err
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	result := ps.lastResult(kind)
+	return &result, nil
+}
+
+// recordResult keeps the result of the last successful run of a provisioner for ReloadProvisioning.
+func (ps *provisioningServiceImpl) recordResult(name string, result ProvisionResult) {
+	ps.reportMutex.Lock()
+	defer ps.reportMutex.Unlock()
+	if ps.results == nil {
+		ps.results = map[string]ProvisionResult{}
+	}
+	ps.results[name] = result
+}
+
+// lastResult returns the result of the last successful run of a provisioner. Since triggers that are coalesced share
+// a run, it's the result of a run that started after a Provision call that succeeded.
+func (ps *provisioningServiceImpl) lastResult(name string) ProvisionResult {
+	ps.reportMutex.Lock()
+	defer ps.reportMutex.Unlock()
+	return ps.results[name]
+}
+
+// serviceContext returns the context of Run, or the background context until Run started.
+func (ps *provisioningServiceImpl) serviceContext() context.Context {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	if ps.ctx == nil {
+		return context.Background()
+	}
+	return ps.ctx
+}
@@ -0,0 +1,138 @@
+package provisioning
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// The stages a provisioning report is written at. The init report covers the provisioners run by Init, the run
+// report also covers the dashboards and alert rules provisioned once Run starts.
+const (
+	reportStageInit = "init"
+	reportStageRun  = "run"
+)
+
+// provisioningReport is the report written to provisioning.report_path, so a deployment can check what was
+// provisioned at startup without parsing the logs.
+type provisioningReport struct {
+	Stage       string    `json:"stage"`
+	GeneratedAt time.Time `json:"generatedAt"`
+	// Error is the error that stopped the stage, empty when it succeeded.
+	Error      string            `json:"error,omitempty"`
+	Subsystems []subsystemReport `json:"subsystems"`
+}
+
+// subsystemReport is the last run of a subsystem. Applied counts every object the run applied or kept, Created,
+// Updated and Skipped the ones whose provisioner tells what it did with them.
+type subsystemReport struct {
+	Name        string              `json:"name"`
+	Directories []string            `json:"directories"`
+	StartedAt   time.Time           `json:"startedAt"`
+	FinishedAt  time.Time           `json:"finishedAt"`
+	Error       string              `json:"error,omitempty"`
+	Applied     int                 `json:"applied"`
+	Created     int                 `json:"created"`
+	Updated     int                 `json:"updated"`
+	Skipped     int                 `json:"skipped"`
+	Deleted     int                 `json:"deleted"`
+	Objects     []ProvisionedObject `json:"objects"`
+}
+
+// recordReport keeps the outcome of a run of a subsystem for the next report, replacing the one of its previous run.
+// Nothing is kept when there is no report path.
+func (ps *provisioningServiceImpl) recordReport(name string, start time.Time, result ProvisionResult, err error) {
+	if ps.Cfg.ProvisioningReportPath == "" {
+		return
+	}
+
+	counts := result.Counts()
+	subsystem := subsystemReport{
+		Name:        name,
+		Directories: result.Directories,
+		StartedAt:   start,
+		FinishedAt:  start.Add(result.Duration),
+		Applied:     counts.Applied,
+		Created:     counts.Created,
+		Updated:     counts.Updated,
+		Skipped:     counts.Skipped,
+		Deleted:     counts.Deleted,
+		Objects:     append(append([]ProvisionedObject{}, result.Objects...), result.Deleted...),
+	}
+	if err != nil {
+		subsystem.Error = err.Error()
+	}
+
+	// The report has its own mutex since a run that timed out may still hold the other one.
+	ps.reportMutex.Lock()
+	defer ps.reportMutex.Unlock()
+	if ps.report == nil {
+		ps.report = map[string]subsystemReport{}
+	}
+	ps.report[name] = subsystem
+}
+
+// writeReport writes the report of stage to provisioning.report_path, with the last run of every subsystem in the
+// order they started. A report that can't be written is logged rather than failing provisioning.
+func (ps *provisioningServiceImpl) writeReport(stage string, stageErr error) {
+	path := ps.Cfg.ProvisioningReportPath
+	if path == "" {
+		return
+	}
+
+	report := provisioningReport{Stage: stage, GeneratedAt: time.Now(), Subsystems: []subsystemReport{}}
+	if stageErr != nil {
+		report.Error = stageErr.Error()
+	}
+	ps.reportMutex.Lock()
+	for _, subsystem := range ps.report {
+		report.Subsystems = append(report.Subsystems, subsystem)
+	}
+	ps.reportMutex.Unlock()
+	sort.Slice(report.Subsystems, func(i, j int) bool {
+		return report.Subsystems[i].StartedAt.Before(report.Subsystems[j].StartedAt)
+	})
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(path, data)
+	}
+	if err != nil {
+		ps.log.Error("Failed to write provisioning report", "path", path, "error", err)
+		return
+	}
+	ps.log.Info("Wrote provisioning report", "path", path, "stage", stage)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it to path, so readers see either the
+// previous content or all of data.
+func writeFileAtomic(path string, data []byte) (err error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	// The report is read by deployment tooling, which doesn't necessarily run as the Grafana user.
+	if err = tmp.Chmod(0644); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,124 @@
+package provisioning
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	plugifaces "github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/services/provisioning/datasources"
+	"github.com/grafana/grafana/pkg/services/provisioning/notifiers"
+	"github.com/grafana/grafana/pkg/services/provisioning/orgs"
+	"github.com/grafana/grafana/pkg/services/provisioning/plugins"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvisioningReport(t *testing.T) {
+	setupReport := func(t *testing.T) (*serviceTestStruct, string) {
+		serviceTest := setup()
+		serviceTest.service.Cfg.ProvisioningPaths = []string{"/etc/grafana/provisioning"}
+		serviceTest.service.Cfg.ProvisioningReportPath = filepath.Join(t.TempDir(), "report.json")
+		serviceTest.service.provisionOrgs = func(context.Context, string, orgs.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error {
+			return nil
+		}
+		serviceTest.service.provisionNotifiers = func(context.Context, []string, notifiers.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error {
+			return nil
+		}
+		serviceTest.service.provisionPlugins = func(context.Context, []string, plugins.Store, plugifaces.Manager, setting.ProvisioningFileFilter, bool, bool, bool, *utils.Inventory) error {
+			return nil
+		}
+		serviceTest.service.provisionDatasources = func(_ context.Context, opts datasources.ProvisionOptions) error {
+			opts.Inventory.Record(ProvisionedObject{Kind: "datasource", Name: "Prometheus", OrgID: 1, Action: utils.ActionCreated})
+			opts.Inventory.Record(ProvisionedObject{Kind: "datasource", Name: "Loki", OrgID: 1, Action: utils.ActionUpdated})
+			opts.Inventory.RecordDeleted(ProvisionedObject{Kind: "datasource", Name: "Graphite", OrgID: 1})
+			return nil
+		}
+		return serviceTest, serviceTest.service.Cfg.ProvisioningReportPath
+	}
+
+	readReport := func(t *testing.T, path string) provisioningReport {
+		data, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		var report provisioningReport
+		require.NoError(t, json.Unmarshal(data, &report))
+		return report
+	}
+
+	t.Run("Init writes the report of every init provisioner", func(t *testing.T) {
+		serviceTest, path := setupReport(t)
+
+		require.NoError(t, serviceTest.service.RunInitProvisioners(context.Background()))
+		report := readReport(t, path)
+		assert.Equal(t, reportStageInit, report.Stage)
+		assert.Empty(t, report.Error)
+
+		var names []string
+		for _, subsystem := range report.Subsystems {
+			names = append(names, subsystem.Name)
+		}
+		assert.Equal(t, []string{"orgs", "datasources", "plugins", "notifiers"}, names)
+
+		ds := report.Subsystems[1]
+		assert.Equal(t, []string{filepath.Join("/etc/grafana/provisioning", "datasources")}, ds.Directories)
+		assert.Equal(t, 2, ds.Applied)
+		assert.Equal(t, 1, ds.Created)
+		assert.Equal(t, 1, ds.Updated)
+		assert.Equal(t, 1, ds.Deleted)
+		require.Len(t, ds.Objects, 3)
+		assert.Equal(t, utils.ActionDeleted, ds.Objects[2].Action)
+		assert.False(t, ds.FinishedAt.Before(ds.StartedAt))
+
+		matches, err := filepath.Glob(filepath.Join(filepath.Dir(path), "*"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{path}, matches, "The temporary file is renamed to the report")
+	})
+
+	t.Run("A failed init is reported", func(t *testing.T) {
+		serviceTest, path := setupReport(t)
+		serviceTest.service.provisionNotifiers = func(context.Context, []string, notifiers.Store, setting.ProvisioningFileFilter, bool, *utils.Inventory) error {
+			return errors.New("invalid notifier config")
+		}
+
+		require.Error(t, serviceTest.service.RunInitProvisioners(context.Background()))
+		report := readReport(t, path)
+		assert.Contains(t, report.Error, "invalid notifier config")
+		require.Len(t, report.Subsystems, 4)
+		assert.Contains(t, report.Subsystems[3].Error, "invalid notifier config")
+	})
+
+	t.Run("Run writes the report with the dashboards", func(t *testing.T) {
+		serviceTest, path := setupReport(t)
+		serviceTest.mock.GetProvisionedDashboardsFunc = func() []ProvisionedObject {
+			return []ProvisionedObject{{Kind: "dashboard", Name: "Home", OrgID: 1, Action: utils.ActionSkipped}}
+		}
+		require.NoError(t, serviceTest.service.RunInitProvisioners(context.Background()))
+
+		serviceTest.startService()
+		serviceTest.waitForPollChanges()
+		serviceTest.cancel()
+		serviceTest.waitForStop()
+
+		report := readReport(t, path)
+		assert.Equal(t, reportStageRun, report.Stage)
+		require.Len(t, report.Subsystems, 5)
+		dashboards := report.Subsystems[4]
+		assert.Equal(t, "dashboards", dashboards.Name)
+		assert.Equal(t, 1, dashboards.Skipped)
+	})
+
+	t.Run("No report is written without a path", func(t *testing.T) {
+		serviceTest, path := setupReport(t)
+		serviceTest.service.Cfg.ProvisioningReportPath = ""
+
+		require.NoError(t, serviceTest.service.RunInitProvisioners(context.Background()))
+		matches, err := filepath.Glob(filepath.Join(filepath.Dir(path), "*"))
+		require.NoError(t, err)
+		assert.Empty(t, matches)
+	})
+}
@@ -0,0 +1,25 @@
+package provisioning
+
+import (
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+)
+
+// SecretResolver resolves the secret references in the secureJsonData of provisioned datasources, like
+// vault://secret/data/grafana#password, so the secrets don't have to be in the provisioning files.
+type SecretResolver = utils.SecretResolver
+
+// SecretResolverFunc is a function that implements SecretResolver.
+type SecretResolverFunc = utils.SecretResolverFunc
+
+// SecretRef is a secret reference, <scheme>://<path> with an optional #<field>.
+type SecretRef = utils.SecretRef
+
+// ErrSecretUnresolved is returned, wrapped with the datasource, the key and the reference, when a secret reference
+// can't be resolved. The datasource isn't provisioned then.
+var ErrSecretUnresolved = utils.ErrSecretUnresolved
+
+// RegisterSecretResolver registers the resolver of the secret references with scheme, like a Vault client for vault
+// references. It's meant to be called from an init function. The env and file schemes are built in.
+func RegisterSecretResolver(scheme string, resolver SecretResolver) {
+	utils.RegisterSecretResolver(scheme, resolver)
+}
@@ -0,0 +1,205 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+	"gopkg.in/yaml.v2"
+)
+
+// ErrSelfTestFailed is returned, wrapped with the failed subsystems, by SelfTest when a check failed.
+var ErrSelfTestFailed = errors.New("provisioning self-test failed")
+
+// SelfTestResult is the outcome of the self-test of a provisioning subsystem, or of the database.
+type SelfTestResult struct {
+	Subsystem string
+	// Directories are the directories of the subsystem that exist.
+	Directories []string
+	// File is the file that was parsed, empty when the directories have no file.
+	File string
+	// Err is why the subsystem failed, nil when it passed.
+	Err error
+}
+
+// SelfTestResults are the results of a self-test, the database first and then the subsystems in the order they're
+// provisioned.
+type SelfTestResults []SelfTestResult
+
+// Err returns an ErrSelfTestFailed error naming the failed subsystems, or nil when every subsystem passed.
+func (r SelfTestResults) Err() error {
+	var failed []string
+	for _, result := range r {
+		if result.Err != nil {
+			failed = append(failed, result.Subsystem)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrSelfTestFailed, strings.Join(failed, ", "))
+}
+
+// String returns a line per subsystem saying whether it passed, followed by the overall result.
+func (r SelfTestResults) String() string {
+	var b strings.Builder
+	for _, result := range r {
+		if result.Err != nil {
+			fmt.Fprintf(&b, "FAIL  %s: %v\n", result.Subsystem, result.Err)
+			continue
+		}
+		directories := fmt.Sprintf("%d directories", len(result.Directories))
+		if len(result.Directories) == 1 {
+			directories = "1 directory"
+		}
+		switch {
+		case result.File != "":
+			fmt.Fprintf(&b, "PASS  %s: %s, parsed %s\n", result.Subsystem, directories, result.File)
+		case result.Subsystem == selfTestDatabase:
+			fmt.Fprintf(&b, "PASS  %s: reachable\n", result.Subsystem)
+		default:
+			fmt.Fprintf(&b, "PASS  %s: %s, no files\n", result.Subsystem, directories)
+		}
+	}
+	if err := r.Err(); err != nil {
+		fmt.Fprintf(&b, "%v\n", err)
+	} else {
+		b.WriteString("Provisioning self-test passed\n")
+	}
+	return b.String()
+}
+
+// selfTestDatabase is the subsystem of the database check in the self-test results.
+const selfTestDatabase = "database"
+
+// SelfTest checks that the provisioning of this configuration would be able to run, without applying anything:
+// the database answers, the directories of every subsystem can be read, and the first file of each subsystem is
+// valid YAML. It's lighter than validating the files, and meant to smoke-test the mounts and the database of a
+// deployment. A missing directory only fails when fail_on_missing_dir is set, like for provisioning.
+func (ps *provisioningServiceImpl) SelfTest(ctx context.Context) error {
+	return ps.selfTest(ctx).Err()
+}
+
+func (ps *provisioningServiceImpl) selfTest(ctx context.Context) SelfTestResults {
+	results := SelfTestResults{ps.selfTestDatabase(ctx)}
+
+	type subsystem struct {
+		name, filterKind string
+		// dirs are the directories read by the subsystem, and required the ones fail_on_missing_dir applies to.
+		dirs, required []string
+	}
+	subsystems := []subsystem{
+		{"orgs", "orgs", ps.provisioningDirs("orgs"), ps.provisioningDirs("orgs")},
+		{"datasources", "datasources", ps.orgScopedDirs("datasources"), ps.provisioningDirs("datasources")},
+		{"plugins", "plugins", ps.provisioningDirs("plugins"), ps.provisioningDirs("plugins")},
+		{"notifiers", "notifiers", ps.provisioningDirs("notifiers"), ps.provisioningDirs("notifiers")},
+		{"library panels", "library_panels", ps.orgScopedDirs("library-panels"), ps.provisioningDirs("library-panels")},
+		{"dashboards", "dashboards", ps.orgScopedDirs("dashboards"), ps.provisioningDirs("dashboards")},
+	}
+	if ps.Cfg.IsNgAlertEnabled() {
+		subsystems = append(subsystems,
+			subsystem{"alert rules", "alert_rules", ps.provisioningDirs("alerting", "rules"),
+				ps.provisioningDirs("alerting", "rules")},
+			subsystem{"alert notifications", "alert_notifications", ps.provisioningDirs("alerting", "notifications"),
+				ps.provisioningDirs("alerting", "notifications")})
+	}
+	registered, err := getRegisteredProvisioners()
+	if err != nil {
+		results = append(results, SelfTestResult{Subsystem: "registered provisioners", Err: err})
+	}
+	for _, p := range registered {
+		// Registered provisioners read their files with their own format, so only their directories are checked.
+		subsystems = append(subsystems, subsystem{name: p.kind, dirs: ps.provisioningDirs(p.kind),
+			required: ps.provisioningDirs(p.kind)})
+	}
+
+	for _, s := range subsystems {
+		if ctx.Err() != nil {
+			results = append(results, SelfTestResult{Subsystem: s.name, Err: ctx.Err()})
+			continue
+		}
+		result := ps.selfTestDirs(s.name, s.dirs, s.required, s.filterKind != "",
+			ps.Cfg.ProvisioningFileFilters[s.filterKind])
+		if result.Err != nil {
+			ps.log.Error("Provisioning self-test failed", "subsystem", s.name, "error", result.Err)
+		} else {
+			ps.log.Info("Provisioning self-test passed", "subsystem", s.name, "directories", len(result.Directories),
+				"file", result.File)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// selfTestDatabase pings the database, after waiting for it like provisioning does. The ping only reads.
+func (ps *provisioningServiceImpl) selfTestDatabase(ctx context.Context) SelfTestResult {
+	result := SelfTestResult{Subsystem: selfTestDatabase}
+	result.Err = ps.waitForDatabase(ctx)
+	if result.Err == nil {
+		result.Err = ps.pingDatabase(ctx)
+	}
+	if result.Err != nil {
+		ps.log.Error("Provisioning self-test failed", "subsystem", selfTestDatabase, "error", result.Err)
+	}
+	return result
+}
+
+// selfTestDirs lists the directories of a subsystem and, when parse is set, parses the first YAML file the filter
+// includes.
+func (ps *provisioningServiceImpl) selfTestDirs(subsystem string, dirs, required []string, parse bool,
+	filter setting.ProvisioningFileFilter) SelfTestResult {
+	result := SelfTestResult{Subsystem: subsystem}
+	if err := ps.requireDirs(required); err != nil {
+		result.Err = err
+		return result
+	}
+
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+		files, err := utils.ProvisioningFiles(dir, filter)
+		if err != nil {
+			result.Err = fmt.Errorf("can't read directory %s: %w", dir, err)
+			return result
+		}
+		result.Directories = append(result.Directories, dir)
+
+		if !parse || result.File != "" || len(files) == 0 {
+			continue
+		}
+		result.File = filepath.Join(dir, files[0].Name())
+		if err := parseSelfTestFile(subsystem, result.File); err != nil {
+			result.Err = err
+			return result
+		}
+	}
+	return result
+}
+
+// parseSelfTestFile parses the YAML of a provisioning file, without decoding it into the config of its subsystem.
+func parseSelfTestFile(subsystem, path string) error {
+	// nolint:gosec
+	// We can ignore the gosec G304 warning on this one because `path` comes from ps.Cfg.ProvisioningPath
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("can't read file %s: %w", path, err)
+	}
+	var parsed interface{}
+	return utils.NewYAMLFileError(subsystem, path, yaml.Unmarshal(data, &parsed))
+}
+
+// RunSelfTest runs the self-test of cfg on its own, for the grafana-server provisioning self-test command. It only
+// needs the settings: no service is started, the migrations aren't run, and the database is only checked with ping,
+// which mustn't write to it.
+func RunSelfTest(ctx context.Context, cfg *setting.Cfg, ping func(context.Context) error) SelfTestResults {
+	ps := &provisioningServiceImpl{Cfg: cfg, log: log.New("provisioning"), databasePing: ping}
+	return ps.selfTest(ctx)
+}
@@ -0,0 +1,112 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/datasources"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelfTest(t *testing.T) {
+	setupSelfTest := func(t *testing.T, dbErr error) (*serviceTestStruct, string) {
+		t.Helper()
+		bus.ClearBusHandlers()
+		t.Cleanup(bus.ClearBusHandlers)
+		bus.AddHandler("test", func(query *models.GetDBHealthQuery) error {
+			return dbErr
+		})
+
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "datasources"), 0750))
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "dashboards"), 0750))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "datasources", "prometheus.yaml"),
+			[]byte("apiVersion: 1\ndatasources:\n  - name: Prometheus\n"), 0600))
+
+		serviceTest := setup()
+		serviceTest.service.Cfg.ProvisioningPath = dir
+		serviceTest.service.provisionDatasources = func(context.Context, datasources.ProvisionOptions) error {
+			t.Error("The self-test provisioned datasources")
+			return nil
+		}
+		return serviceTest, dir
+	}
+
+	t.Run("Every subsystem passes without anything being provisioned", func(t *testing.T) {
+		serviceTest, dir := setupSelfTest(t, nil)
+
+		require.NoError(t, serviceTest.service.SelfTest(context.Background()))
+		results := serviceTest.service.selfTest(context.Background())
+		require.Len(t, results, 7)
+		assert.Equal(t, SelfTestResult{Subsystem: "database"}, results[0])
+		assert.Equal(t, SelfTestResult{
+			Subsystem:   "datasources",
+			Directories: []string{filepath.Join(dir, "datasources")},
+			File:        filepath.Join(dir, "datasources", "prometheus.yaml"),
+		}, results[2])
+		assert.Equal(t, SelfTestResult{Subsystem: "dashboards", Directories: []string{filepath.Join(dir, "dashboards")}},
+			results[6])
+		assert.Equal(t, SelfTestResult{Subsystem: "orgs"}, results[1], "Missing directories pass")
+		assert.Contains(t, results.String(), "PASS  datasources: 1 directory, parsed "+results[2].File+"\n")
+		assert.Contains(t, results.String(), "Provisioning self-test passed\n")
+		assert.Empty(t, serviceTest.mock.Calls.Provision)
+	})
+
+	t.Run("Invalid YAML fails its subsystem", func(t *testing.T) {
+		serviceTest, dir := setupSelfTest(t, nil)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "dashboards", "providers.yaml"),
+			[]byte("providers:\n  - name: [\n"), 0600))
+
+		err := serviceTest.service.SelfTest(context.Background())
+		require.True(t, errors.Is(err, ErrSelfTestFailed))
+		assert.EqualError(t, err, "provisioning self-test failed: dashboards")
+
+		results := serviceTest.service.selfTest(context.Background())
+		var fileErr *ProvisioningFileError
+		require.True(t, errors.As(results[6].Err, &fileErr))
+		assert.Equal(t, filepath.Join(dir, "dashboards", "providers.yaml"), fileErr.Path)
+		assert.Contains(t, results.String(), "FAIL  dashboards: ")
+	})
+
+	t.Run("An unreachable database fails the self-test", func(t *testing.T) {
+		serviceTest, _ := setupSelfTest(t, errors.New("connection refused"))
+
+		err := serviceTest.service.SelfTest(context.Background())
+		assert.EqualError(t, err, "provisioning self-test failed: database")
+	})
+
+	t.Run("Missing directories fail with fail_on_missing_dir", func(t *testing.T) {
+		serviceTest, _ := setupSelfTest(t, nil)
+		serviceTest.service.Cfg.ProvisioningFailOnMissingDir = true
+
+		results := serviceTest.service.selfTest(context.Background())
+		require.True(t, errors.Is(results[1].Err, ErrMissingProvisioningDir))
+		assert.NoError(t, results[2].Err)
+	})
+
+	t.Run("The standalone self-test only needs the settings and the ping", func(t *testing.T) {
+		serviceTest, dir := setupSelfTest(t, errors.New("the SQL store isn't initialized"))
+		pings := 0
+		ping := func(context.Context) error {
+			pings++
+			return nil
+		}
+
+		results := RunSelfTest(context.Background(), serviceTest.service.Cfg, ping)
+		require.NoError(t, results.Err())
+		assert.Equal(t, 1, pings, "The ping replaces the GetDBHealthQuery of the SQL store")
+		assert.Equal(t, filepath.Join(dir, "datasources", "prometheus.yaml"), results[2].File)
+
+		results = RunSelfTest(context.Background(), serviceTest.service.Cfg, func(context.Context) error {
+			return errors.New("connection refused")
+		})
+		assert.EqualError(t, results.Err(), "provisioning self-test failed: database")
+	})
+}
@@ -0,0 +1,64 @@
+package provisioning
+
+import (
+	"time"
+
+	dboards "github.com/grafana/grafana/pkg/dashboards"
+	"github.com/grafana/grafana/pkg/services/librarypanels"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	ngstore "github.com/grafana/grafana/pkg/services/ngalert/store"
+	"github.com/grafana/grafana/pkg/services/provisioning/alerting"
+	"github.com/grafana/grafana/pkg/services/provisioning/datasources"
+	lpprovisioning "github.com/grafana/grafana/pkg/services/provisioning/librarypanels"
+	"github.com/grafana/grafana/pkg/services/provisioning/notifiers"
+	"github.com/grafana/grafana/pkg/services/provisioning/orgs"
+	"github.com/grafana/grafana/pkg/services/provisioning/plugins"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+// ProvisioningStore is everything the provisioners write through: the dashboard tables for dashboards, the alert
+// rule and alertmanager configuration tables for unified alerting, the library panel tables, and the commands that
+// write orgs, datasources, plugin settings and alert notifiers. The provisioners still read through bus queries.
+type ProvisioningStore interface {
+	dboards.Store
+	alerting.RuleStore
+	alerting.NotificationStore
+	lpprovisioning.PanelStore
+	orgs.Store
+	datasources.Store
+	plugins.Store
+	notifiers.Store
+}
+
+// sqlProvisioningStore only exposes the methods of ProvisioningStore, so the rest of the SQL store can't be reached
+// through it. The orgs, datasources, plugin settings and alert notifiers are written with the commands the SQL store
+// handles on the bus.
+type sqlProvisioningStore struct {
+	dboards.Store
+	alerting.RuleStore
+	alerting.NotificationStore
+	lpprovisioning.PanelStore
+	utils.BusStore
+}
+
+func newSQLProvisioningStore(sqlStore *sqlstore.SQLStore) ProvisioningStore {
+	alertingStore := ngstore.DBstore{
+		BaseInterval:           ngmodels.BaseIntervalSeconds * time.Second,
+		DefaultIntervalSeconds: ngmodels.DefaultIntervalSeconds,
+		SQLStore:               sqlStore,
+	}
+	return sqlProvisioningStore{Store: sqlStore, RuleStore: alertingStore, NotificationStore: alertingStore,
+		PanelStore: librarypanels.ProvisioningStore{SQLStore: sqlStore}}
+}
+
+// provisioningStore returns the store the provisioners write through, the SQL store unless another one was set.
+func (ps *provisioningServiceImpl) provisioningStore() ProvisioningStore {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	if ps.store == nil {
+		ps.store = newSQLProvisioningStore(ps.SQLStore)
+	}
+	return ps.store
+}
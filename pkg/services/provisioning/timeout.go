@@ -0,0 +1,111 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrProvisioningTimeout is returned, wrapped with the subsystem and the timeout, by a Provision method that didn't
+// finish within the timeout of its subsystem.
+var ErrProvisioningTimeout = errors.New("provisioning timed out")
+
+// provisionWithTimeout runs provision with a context that's canceled after the <subsystem>_timeout setting of the
+// named provisioner, or the timeout setting for the provisioners without one. When provision hasn't returned by then,
+// e.g. because it's stuck in a call that doesn't take a context, the timeout is returned without waiting for it, and
+// sent to the failure webhook since the abandoned run can't report it. The abandoned run stops at its next context
+// check, and its outcome is logged once it returns. Until then, the next run waits for it within its own timeout, so
+// the runs of a provisioner never overlap.
+func (ps *provisioningServiceImpl) provisionWithTimeout(ctx context.Context, name string,
+	provision func(ctx context.Context) (ProvisionResult, error)) (ProvisionResult, error) {
+	timeout, ok := ps.Cfg.ProvisioningTimeouts[strings.ReplaceAll(name, " ", "_")]
+	if !ok {
+		timeout = ps.Cfg.ProvisioningTimeout
+	}
+	if timeout <= 0 {
+		if err := ps.waitForAbandonedRun(ctx, name); err != nil {
+			return ProvisionResult{}, err
+		}
+		return provision(ctx)
+	}
+
+	type outcome struct {
+		result ProvisionResult
+		err    error
+	}
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	if err := ps.waitForAbandonedRun(ctx, name); err != nil {
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) {
+			return ProvisionResult{}, ps.notifyFailure(name, fmt.Errorf("%s %w after %s waiting for the abandoned run",
+				name, ErrProvisioningTimeout, timeout))
+		}
+		return ProvisionResult{}, err
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := provision(ctx)
+		done <- outcome{result: result, err: err}
+	}()
+
+	select {
+	case o := <-done:
+		cancel()
+		if o.err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return o.result, fmt.Errorf("%s %w after %s: %v", name, ErrProvisioningTimeout, timeout, o.err)
+		}
+		return o.result, o.err
+	case <-ctx.Done():
+	}
+
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		// Canceled by the caller, which provision handles on its own.
+		o := <-done
+		cancel()
+		return o.result, o.err
+	}
+
+	ps.log.Error("Provisioning timed out, abandoning the run", "provisioner", name, "timeout", timeout)
+	abandoned := make(chan struct{})
+	ps.abandonedMutex.Lock()
+	if ps.abandonedRuns == nil {
+		ps.abandonedRuns = map[string]chan struct{}{}
+	}
+	ps.abandonedRuns[name] = abandoned
+	ps.abandonedMutex.Unlock()
+	go func() {
+		o := <-done
+		cancel()
+		ps.abandonedMutex.Lock()
+		if ps.abandonedRuns[name] == abandoned {
+			delete(ps.abandonedRuns, name)
+		}
+		ps.abandonedMutex.Unlock()
+		close(abandoned)
+		ps.log.Warn("Abandoned provisioning run returned", "provisioner", name, "duration", time.Since(start),
+			"error", o.err)
+	}()
+	return ProvisionResult{}, ps.notifyFailure(name, fmt.Errorf("%s %w after %s", name, ErrProvisioningTimeout, timeout))
+}
+
+// waitForAbandonedRun waits until the last run of the named provisioner that the timeout abandoned has returned,
+// or ctx is done.
+func (ps *provisioningServiceImpl) waitForAbandonedRun(ctx context.Context, name string) error {
+	ps.abandonedMutex.Lock()
+	abandoned := ps.abandonedRuns[name]
+	ps.abandonedMutex.Unlock()
+	if abandoned == nil {
+		return nil
+	}
+
+	ps.log.Warn("Waiting for the abandoned provisioning run to return", "provisioner", name)
+	select {
+	case <-abandoned:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,178 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/bus"
+	dboards "github.com/grafana/grafana/pkg/dashboards"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/dashboards"
+	"github.com/grafana/grafana/pkg/services/provisioning/datasources"
+	"github.com/grafana/grafana/pkg/services/provisioning/notifiers"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvisioningTimeouts(t *testing.T) {
+	t.Run("A stage that ignores its context is abandoned at the timeout", func(t *testing.T) {
+		serviceTest := setup()
+		serviceTest.service.Cfg.ProvisioningTimeouts = map[string]time.Duration{"datasources": 50 * time.Millisecond}
+		release := make(chan struct{})
+		defer close(release)
+		serviceTest.service.provisionDatasources = func(context.Context, datasources.ProvisionOptions) error {
+			<-release
+			return nil
+		}
+
+		err := serviceTest.service.ProvisionDatasources(context.Background())
+		require.True(t, errors.Is(err, ErrProvisioningTimeout))
+		assert.EqualError(t, err, "datasources provisioning timed out after 50ms")
+	})
+
+	t.Run("An abandoned stage notifies the failure webhook", func(t *testing.T) {
+		t.Cleanup(bus.ClearBusHandlers)
+		serviceTest := setup()
+		serviceTest.service.Cfg.ProvisioningFailureWebhookURL = "http://alerts.example.com/hook"
+		serviceTest.service.Cfg.ProvisioningTimeouts = map[string]time.Duration{"datasources": 50 * time.Millisecond}
+		release := make(chan struct{})
+		defer close(release)
+		serviceTest.service.provisionDatasources = func(context.Context, datasources.ProvisionOptions) error {
+			<-release
+			return nil
+		}
+		sent := make(chan string, 1)
+		bus.AddHandlerCtx("test", func(_ context.Context, cmd *models.SendWebhookSync) error {
+			sent <- cmd.Body
+			return nil
+		})
+
+		require.True(t, errors.Is(serviceTest.service.ProvisionDatasources(context.Background()), ErrProvisioningTimeout))
+		select {
+		case body := <-sent:
+			assert.Contains(t, body, `"error":"datasources provisioning timed out after 50ms"`)
+		case <-time.After(serviceTest.waitTimeout):
+			t.Fatal("Failure notification was not sent")
+		}
+	})
+
+	t.Run("A stage canceled by the timeout reports the timeout", func(t *testing.T) {
+		serviceTest := setup()
+		serviceTest.service.Cfg.ProvisioningTimeouts = map[string]time.Duration{"notifiers": 50 * time.Millisecond}
+		serviceTest.service.provisionNotifiers = func(ctx context.Context, _ []string, _ notifiers.Store, _ setting.ProvisioningFileFilter, _ bool, _ *utils.Inventory) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+
+		err := serviceTest.service.ProvisionNotifications(context.Background())
+		require.True(t, errors.Is(err, ErrProvisioningTimeout))
+		assert.Contains(t, err.Error(), "notifiers provisioning timed out after 50ms")
+	})
+
+	t.Run("The next run waits for an abandoned stage, which doesn't replace its inventory", func(t *testing.T) {
+		serviceTest := setup()
+		serviceTest.service.Cfg.ProvisioningTimeouts = map[string]time.Duration{"datasources": 50 * time.Millisecond}
+		release := make(chan struct{})
+		returned := make(chan struct{})
+		var runs int32
+		serviceTest.service.provisionDatasources = func(_ context.Context, opts datasources.ProvisionOptions) error {
+			if atomic.AddInt32(&runs, 1) == 1 {
+				defer close(returned)
+				opts.Inventory.Record(ProvisionedObject{Kind: "datasource", Name: "Stale", OrgID: 1})
+				<-release
+				return nil
+			}
+			opts.Inventory.Record(ProvisionedObject{Kind: "datasource", Name: "Current", OrgID: 1})
+			return nil
+		}
+
+		require.True(t, errors.Is(serviceTest.service.ProvisionDatasources(context.Background()), ErrProvisioningTimeout))
+		err := serviceTest.service.ProvisionDatasources(context.Background())
+		require.True(t, errors.Is(err, ErrProvisioningTimeout))
+		assert.EqualError(t, err, "datasources provisioning timed out after 50ms waiting for the abandoned run")
+		assert.Equal(t, int32(1), atomic.LoadInt32(&runs), "The next run shouldn't start before the abandoned one returned")
+
+		close(release)
+		<-returned
+		require.NoError(t, serviceTest.service.ProvisionDatasources(context.Background()))
+
+		inventory := serviceTest.service.GetProvisionedInventory()
+		require.Len(t, inventory, 1)
+		assert.Equal(t, "Current", inventory[0].Name)
+	})
+
+	t.Run("An abandoned dashboards run doesn't hold up the getters, only the next run", func(t *testing.T) {
+		serviceTest := setup()
+		serviceTest.service.Cfg.ProvisioningTimeouts = map[string]time.Duration{"dashboards": 500 * time.Millisecond}
+		release := make(chan struct{})
+		stuck := dashboards.NewDashboardProvisionerMock()
+		stuck.ProvisionFunc = func(context.Context) error {
+			<-release
+			return nil
+		}
+		stuck.GetProvisionerRootPathFunc = func(string) string { return "/stale" }
+		current := serviceTest.mock
+		current.GetProvisionerRootPathFunc = func(string) string { return "/current" }
+		var created int32
+		serviceTest.service.newDashboardProvisioner = func(context.Context, []string, dboards.Store, *setting.Cfg) (dashboards.DashboardProvisioner, error) {
+			if atomic.AddInt32(&created, 1) == 1 {
+				return stuck, nil
+			}
+			return current, nil
+		}
+
+		require.True(t, errors.Is(serviceTest.service.ProvisionDashboards(context.Background()), ErrProvisioningTimeout))
+
+		// The abandoned run is still provisioning, so these would block if it held the mutex.
+		getters := make(chan struct{})
+		go func() {
+			defer close(getters)
+			assert.Empty(t, serviceTest.service.GetDashboardProvisionerResolvedPath("default"))
+			assert.False(t, serviceTest.service.GetAllowUIUpdatesFromConfig("default"))
+		}()
+		select {
+		case <-getters:
+		case <-time.After(time.Second):
+			t.Fatal("The getters waited for the abandoned run")
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- serviceTest.service.ProvisionDashboards(context.Background())
+		}()
+		select {
+		case err := <-done:
+			t.Fatalf("The second run didn't wait for the abandoned one: %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+		assert.Equal(t, int32(1), atomic.LoadInt32(&created), "The second run shouldn't start while the first one runs")
+
+		close(release)
+		require.NoError(t, <-done)
+		assert.Equal(t, "/current", serviceTest.service.GetDashboardProvisionerResolvedPath("default"))
+		require.Eventually(t, func() bool {
+			serviceTest.service.mutex.Lock()
+			defer serviceTest.service.mutex.Unlock()
+			return serviceTest.service.dashboardRuns == 0
+		}, time.Second, 10*time.Millisecond)
+		assert.Equal(t, "/current", serviceTest.service.GetDashboardProvisionerResolvedPath("default"),
+			"The abandoned run doesn't replace the provisioner of the later one")
+	})
+
+	t.Run("Stages without a timeout aren't limited", func(t *testing.T) {
+		serviceTest := setup()
+		serviceTest.service.Cfg.ProvisioningTimeouts = map[string]time.Duration{"datasources": 0}
+		serviceTest.service.provisionDatasources = func(ctx context.Context, _ datasources.ProvisionOptions) error {
+			_, hasDeadline := ctx.Deadline()
+			assert.False(t, hasDeadline)
+			return nil
+		}
+
+		require.NoError(t, serviceTest.service.ProvisionDatasources(context.Background()))
+	})
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// BusStore writes the orgs, datasources, plugin settings and alert notifiers of provisioning with the commands the
+// SQL store handles on the bus. It implements the stores of the orgs, datasources, plugins and notifiers
+// provisioners, which only write through them.
+type BusStore struct{}
+
+func (BusStore) CreateOrg(ctx context.Context, cmd *models.CreateOrgCommand) error {
+	return bus.DispatchCtx(ctx, cmd)
+}
+
+func (BusStore) UpdateOrg(ctx context.Context, cmd *models.UpdateOrgCommand) error {
+	return bus.DispatchCtx(ctx, cmd)
+}
+
+func (BusStore) DeleteOrg(ctx context.Context, cmd *models.DeleteOrgCommand) error {
+	return bus.DispatchCtx(ctx, cmd)
+}
+
+func (BusStore) SaveProvisionedOrg(ctx context.Context, cmd *models.SaveProvisionedOrgCommand) error {
+	return bus.DispatchCtx(ctx, cmd)
+}
+
+func (BusStore) SavePreferences(ctx context.Context, cmd *models.SavePreferencesCommand) error {
+	return bus.DispatchCtx(ctx, cmd)
+}
+
+func (BusStore) AddDataSource(ctx context.Context, cmd *models.AddDataSourceCommand) error {
+	return bus.DispatchCtx(ctx, cmd)
+}
+
+func (BusStore) UpdateDataSource(ctx context.Context, cmd *models.UpdateDataSourceCommand) error {
+	return bus.DispatchCtx(ctx, cmd)
+}
+
+func (BusStore) DeleteDataSource(ctx context.Context, cmd *models.DeleteDataSourceCommand) error {
+	return bus.DispatchCtx(ctx, cmd)
+}
+
+func (BusStore) SaveProvisionedDatasource(ctx context.Context, cmd *models.SaveProvisionedDatasourceCommand) error {
+	return bus.DispatchCtx(ctx, cmd)
+}
+
+func (BusStore) UpdatePluginSetting(ctx context.Context, cmd *models.UpdatePluginSettingCmd) error {
+	return bus.DispatchCtx(ctx, cmd)
+}
+
+func (BusStore) SaveProvisionedPluginSetting(ctx context.Context, cmd *models.SaveProvisionedPluginSettingCommand) error {
+	return bus.DispatchCtx(ctx, cmd)
+}
+
+func (BusStore) DeleteProvisionedPluginSetting(ctx context.Context,
+	cmd *models.DeleteProvisionedPluginSettingCommand) error {
+	return bus.DispatchCtx(ctx, cmd)
+}
+
+func (BusStore) CreateAlertNotification(ctx context.Context, cmd *models.CreateAlertNotificationCommand) error {
+	return bus.DispatchCtx(ctx, cmd)
+}
+
+func (BusStore) UpdateAlertNotificationWithUid(ctx context.Context,
+	cmd *models.UpdateAlertNotificationWithUidCommand) error {
+	return bus.DispatchCtx(ctx, cmd)
+}
+
+func (BusStore) DeleteAlertNotificationWithUid(ctx context.Context,
+	cmd *models.DeleteAlertNotificationWithUidCommand) error {
+	return bus.DispatchCtx(ctx, cmd)
+}
@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"gopkg.in/yaml.v2"
+)
+
+// DeprecatedField is a field of provisioning files that is still read, but has a replacement.
+type DeprecatedField struct {
+	// Path is the dot separated path of the field, with [] for the items of a list, like datasources[].password.
+	Path        string
+	Replacement string
+}
+
+// YAMLDecoder decodes the provisioning files of a subsystem.
+type YAMLDecoder struct {
+	Subsystem string
+	// Kind is the kind of the files of the directory, which the kind header of a file must match when it has one.
+	// Files aren't checked when it's empty.
+	Kind string
+	// Strict rejects the fields the decoded type doesn't have, instead of ignoring them.
+	Strict     bool
+	Deprecated []DeprecatedField
+	Log        log.Logger
+	// Environment is what the guards of the files are checked against.
+	Environment Environment
+}
+
+// yaml.v2 reports unknown fields as "line N: field <name> not found in type <type>", and the type is of no use to
+// whoever has to fix the file.
+var yamlUnknownField = regexp.MustCompile(`field (\S+) not found in type .*`)
+
+// Decode decodes the content of the YAML file at filename into out, and logs a warning for every deprecated field
+// the file uses. Files whose guards the environment doesn't satisfy are logged and not decoded, and an
+// ErrFileSkipped error is returned for them. Other errors are ProvisioningFileErrors, which wrap ErrKindMismatch for
+// files of another kind.
+func (d YAMLDecoder) Decode(filename string, data []byte, out interface{}) error {
+	if d.Kind != "" {
+		if err := checkKind(data, d.Subsystem, d.Kind); err != nil {
+			return NewYAMLFileError(d.Subsystem, filename, err)
+		}
+	}
+
+	if err := checkGuards(data, d.Environment); err != nil {
+		if errors.Is(err, ErrFileSkipped) {
+			if d.Log != nil {
+				d.Log.Info("Skipping provisioning file", "file", filename, "reason", err)
+			}
+			return err
+		}
+		return NewYAMLFileError(d.Subsystem, filename, err)
+	}
+
+	unmarshal := yaml.Unmarshal
+	if d.Strict {
+		unmarshal = yaml.UnmarshalStrict
+	}
+
+	if err := unmarshal(data, out); err != nil {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) && yamlUnknownField.MatchString(err.Error()) {
+			// The guards and the header are known fields of every file, even though the decoded types don't have them.
+			topLevelType := reflect.TypeOf(out)
+			for topLevelType.Kind() == reflect.Ptr {
+				topLevelType = topLevelType.Elem()
+			}
+			messages := make([]string, 0, len(typeErr.Errors))
+			for _, message := range typeErr.Errors {
+				if isGuardField(message, topLevelType.String()) || isHeaderField(message, topLevelType.String()) {
+					continue
+				}
+				messages = append(messages, yamlUnknownField.ReplaceAllString(message, `unknown field "$1"`))
+			}
+			if len(messages) == 0 {
+				d.warnDeprecated(filename, data)
+				return nil
+			}
+			err = fmt.Errorf("%s", strings.Join(messages, "; "))
+		}
+		return NewYAMLFileError(d.Subsystem, filename, err)
+	}
+
+	d.warnDeprecated(filename, data)
+	return nil
+}
+
+// warnDeprecated logs a warning for every deprecated field the file uses.
+func (d YAMLDecoder) warnDeprecated(filename string, data []byte) {
+	if len(d.Deprecated) > 0 && d.Log != nil {
+		for _, field := range DeprecatedFieldsIn(data, d.Deprecated) {
+			d.Log.Warn("Provisioning file uses a deprecated field", "file", filename, "field", field.Path,
+				"replacement", field.Replacement)
+		}
+	}
+}
+
+// DeprecatedFieldsIn returns the deprecated fields that are set in the YAML content data.
+func DeprecatedFieldsIn(data []byte, deprecated []DeprecatedField) []DeprecatedField {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil
+	}
+
+	paths := map[string]bool{}
+	collectFieldPaths(doc, "", paths)
+
+	var found []DeprecatedField
+	for _, field := range deprecated {
+		if paths[field.Path] {
+			found = append(found, field)
+		}
+	}
+	return found
+}
+
+func collectFieldPaths(node interface{}, prefix string, paths map[string]bool) {
+	switch node := node.(type) {
+	case map[interface{}]interface{}:
+		for key, value := range node {
+			path := fmt.Sprint(key)
+			if prefix != "" {
+				path = prefix + "." + path
+			}
+			paths[path] = true
+			collectFieldPaths(value, path, paths)
+		}
+	case []interface{}:
+		for _, item := range node {
+			collectFieldPaths(item, prefix+"[]", paths)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const decodeTestYAML = `apiVersion: 1
+datasources:
+  - name: Graphite
+    acess: proxy
+    password: secret
+`
+
+type decodeTestConfig struct {
+	APIVersion  int64 `yaml:"apiVersion"`
+	Datasources []struct {
+		Name     string `yaml:"name"`
+		Password string `yaml:"password"`
+	} `yaml:"datasources"`
+}
+
+func TestYAMLDecoder(t *testing.T) {
+	t.Run("Ignores unknown fields by default", func(t *testing.T) {
+		var cfg decodeTestConfig
+		require.NoError(t, YAMLDecoder{Subsystem: "datasources"}.Decode("/etc/ds.yaml", []byte(decodeTestYAML), &cfg))
+		require.Len(t, cfg.Datasources, 1)
+		assert.Equal(t, "Graphite", cfg.Datasources[0].Name)
+	})
+
+	t.Run("Rejects unknown fields in strict mode", func(t *testing.T) {
+		var cfg decodeTestConfig
+		err := YAMLDecoder{Subsystem: "datasources", Strict: true}.Decode("/etc/ds.yaml", []byte(decodeTestYAML), &cfg)
+		require.Error(t, err)
+
+		var fileErr *ProvisioningFileError
+		require.True(t, errors.As(err, &fileErr))
+		assert.Equal(t, "/etc/ds.yaml", fileErr.Path)
+		assert.Equal(t, 4, fileErr.Line)
+		assert.EqualError(t, err, `file /etc/ds.yaml line 4: line 4: unknown field "acess"`)
+	})
+}
+
+func TestDeprecatedFieldsIn(t *testing.T) {
+	deprecated := []DeprecatedField{
+		{Path: "datasources[].password", Replacement: "datasources[].secureJsonData.password"},
+		{Path: "datasources[].basicAuthPassword", Replacement: "datasources[].secureJsonData.basicAuthPassword"},
+	}
+
+	found := DeprecatedFieldsIn([]byte(decodeTestYAML), deprecated)
+	assert.Equal(t, deprecated[:1], found)
+	assert.Empty(t, DeprecatedFieldsIn([]byte("{{"), deprecated), "Broken YAML has no deprecated fields")
+}
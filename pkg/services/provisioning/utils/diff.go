@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// Diff is what provisioning the files of a kind would change in the database, computed without changing anything.
+type Diff struct {
+	Kind string `json:"kind"`
+	// Added are the objects of the files that aren't in the database.
+	Added []DiffObject `json:"added"`
+	// Removed are the objects in the database provisioning created before that are in none of the files.
+	Removed []DiffObject `json:"removed"`
+	// Changed are the objects of the files that differ from the database.
+	Changed []ChangedObject `json:"changed"`
+}
+
+// NewDiff returns an empty diff of kind.
+func NewDiff(kind string) *Diff {
+	return &Diff{Kind: kind, Added: []DiffObject{}, Removed: []DiffObject{}, Changed: []ChangedObject{}}
+}
+
+// DiffObject is an object of a Diff.
+type DiffObject struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	UID  string `json:"uid,omitempty"`
+	// OrgID is 0 for objects that don't belong to an org.
+	OrgID int64 `json:"orgId"`
+	// File is the absolute path of the file of the object, empty for removed objects when it isn't known.
+	File string `json:"file,omitempty"`
+}
+
+// ChangedObject is an object that differs between the files and the database.
+type ChangedObject struct {
+	DiffObject
+	// Fields are the fields that differ. It's empty when the change can't be broken down by field.
+	Fields FieldChanges `json:"fields"`
+}
+
+// FieldChange is a field whose value in the database differs from the one in the file.
+type FieldChange struct {
+	Field   string      `json:"field"`
+	Current interface{} `json:"current,omitempty"`
+	Desired interface{} `json:"desired,omitempty"`
+	// Secret is set for encrypted fields, whose values are left out.
+	Secret bool `json:"secret,omitempty"`
+}
+
+// FieldChanges collects the fields that differ between the database and a file.
+type FieldChanges []FieldChange
+
+// Compare adds field when current and desired differ. Values are compared by their JSON encoding, so numbers
+// decoded from YAML and from JSON compare equal.
+func (c *FieldChanges) Compare(field string, current interface{}, desired interface{}) {
+	if !equalJSON(current, desired) {
+		*c = append(*c, FieldChange{Field: field, Current: current, Desired: desired})
+	}
+}
+
+// CompareMap adds a field prefix.key, or key without a prefix, for every key whose value differs between current and
+// desired, including keys that are only in one of them.
+func (c *FieldChanges) CompareMap(prefix string, current map[string]interface{}, desired map[string]interface{}) {
+	keys := make([]string, 0, len(current)+len(desired))
+	for key := range current {
+		keys = append(keys, key)
+	}
+	for key := range desired {
+		if _, ok := current[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		field := key
+		if prefix != "" {
+			field = prefix + "." + key
+		}
+		c.Compare(field, current[key], desired[key])
+	}
+}
+
+// CompareSecret adds the encrypted field, without its values, when changed is set.
+func (c *FieldChanges) CompareSecret(field string, changed bool) {
+	if changed {
+		*c = append(*c, FieldChange{Field: field, Secret: true})
+	}
+}
+
+func equalJSON(a interface{}, b interface{}) bool {
+	normalize := func(v interface{}) (interface{}, bool) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, false
+		}
+		var normalized interface{}
+		if err := json.Unmarshal(data, &normalized); err != nil {
+			return nil, false
+		}
+		return normalized, true
+	}
+
+	normalizedA, okA := normalize(a)
+	normalizedB, okB := normalize(b)
+	if !okA || !okB {
+		return reflect.DeepEqual(a, b)
+	}
+	return reflect.DeepEqual(normalizedA, normalizedB)
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// Document is one of the YAML documents of a provisioning file, which are separated by --- lines.
+type Document struct {
+	// Index is the 1-based position of the document in the file, not counting the empty documents.
+	Index int
+	// Line is the 1-based line of the file the document starts at.
+	Line int
+	// Data is the document, after an empty line for each line of the file before it, so the lines the decoder
+	// reports are the lines of the file.
+	Data []byte
+
+	// node is the document parsed by the streaming decoder, which knows the lines of its nodes. It's nil when the
+	// file couldn't be parsed, which decoding Data reports.
+	node *yaml3.Node
+}
+
+// ItemLines returns the lines of the items of the list at the top level key of the document, like the datasources of
+// a datasource config, or nil when it has no such list.
+func (d Document) ItemLines(key string) []int {
+	if d.node == nil || len(d.node.Content) == 0 {
+		return nil
+	}
+	mapping := d.node.Content[0]
+	if mapping.Kind != yaml3.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key || mapping.Content[i+1].Kind != yaml3.SequenceNode {
+			continue
+		}
+		lines := make([]int, 0, len(mapping.Content[i+1].Content))
+		for _, item := range mapping.Content[i+1].Content {
+			lines = append(lines, item.Line)
+		}
+		return lines
+	}
+	return nil
+}
+
+// SplitDocuments returns the YAML documents of data in order, skipping the empty ones. yaml.v2 only decodes the
+// first document of its input, so the documents are found with the streaming decoder of yaml.v3, and each one is
+// decoded on its own. Data is returned as a single document when it has at most one, and when its first document
+// can't be parsed, so decoding it reports the error like before. Errors parsing a later document are returned.
+func SplitDocuments(data []byte) ([]Document, error) {
+	var docs []Document
+	decoder := yaml3.NewDecoder(bytes.NewReader(data))
+	for {
+		node := &yaml3.Node{}
+		err := decoder.Decode(node)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if len(docs) == 0 {
+				return []Document{{Index: 1, Line: 1, Data: data}}, nil
+			}
+			return nil, err
+		}
+		if len(node.Content) == 0 || node.Content[0].Tag == "!!null" {
+			continue
+		}
+		line := node.Line
+		if len(docs) == 0 {
+			// Whatever comes before the first document, like comments, is part of it.
+			line = 1
+		}
+		docs = append(docs, Document{Index: len(docs) + 1, Line: line, node: node})
+	}
+
+	if len(docs) <= 1 {
+		doc := Document{Index: 1, Line: 1, Data: data}
+		if len(docs) == 1 {
+			doc.node = docs[0].node
+		}
+		return []Document{doc}, nil
+	}
+
+	// The documents start at their --- line and end where the next one starts.
+	lineStarts := []int{0}
+	for i, b := range data {
+		if b == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	offset := func(line int) int {
+		if line-1 < len(lineStarts) {
+			return lineStarts[line-1]
+		}
+		return len(data)
+	}
+	for i := range docs {
+		end := len(data)
+		if i+1 < len(docs) {
+			end = offset(docs[i+1].Line)
+		}
+		docs[i].Data = data[offset(docs[i].Line):end]
+		if docs[i].Line > 1 {
+			docs[i].Data = append(bytes.Repeat([]byte("\n"), docs[i].Line-1), docs[i].Data...)
+		}
+	}
+	return docs, nil
+}
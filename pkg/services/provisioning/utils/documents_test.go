@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitDocuments(t *testing.T) {
+	t.Run("Documents start at their separator", func(t *testing.T) {
+		data := "# first\napiVersion: 1\n---\n# second\nproviders:\n  - name: a\n  - name: b\n---\n---\nproviders: []\n---\n"
+		docs, err := SplitDocuments([]byte(data))
+		require.NoError(t, err)
+		require.Len(t, docs, 3, "Empty documents are left out")
+
+		assert.Equal(t, 1, docs[0].Index)
+		assert.Equal(t, 1, docs[0].Line)
+		assert.Equal(t, "# first\napiVersion: 1\n", string(docs[0].Data))
+		assert.Equal(t, 2, docs[1].Index)
+		assert.Equal(t, 3, docs[1].Line)
+		assert.Equal(t, "\n\n---\n# second\nproviders:\n  - name: a\n  - name: b\n---\n", string(docs[1].Data))
+		assert.Equal(t, []int{6, 7}, docs[1].ItemLines("providers"))
+		assert.Equal(t, 3, docs[2].Index)
+		assert.Equal(t, 9, docs[2].Line)
+		assert.Empty(t, docs[2].ItemLines("providers"))
+		assert.Nil(t, docs[2].ItemLines("datasources"))
+	})
+
+	t.Run("A file of a single document is kept as it is", func(t *testing.T) {
+		data := "---\nproviders:\n  - name: a\n"
+		docs, err := SplitDocuments([]byte(data))
+		require.NoError(t, err)
+		require.Len(t, docs, 1)
+		assert.Equal(t, Document{Index: 1, Line: 1, Data: []byte(data), node: docs[0].node}, docs[0])
+		assert.Equal(t, []int{3}, docs[0].ItemLines("providers"))
+
+		docs, err = SplitDocuments([]byte("# nothing yet\n"))
+		require.NoError(t, err)
+		require.Len(t, docs, 1)
+		assert.Equal(t, "# nothing yet\n", string(docs[0].Data))
+	})
+
+	t.Run("A first document that can't be parsed is left for the decoder", func(t *testing.T) {
+		data := "apiVersion: [\n---\nproviders: []\n"
+		docs, err := SplitDocuments([]byte(data))
+		require.NoError(t, err)
+		require.Len(t, docs, 1)
+		assert.Equal(t, data, string(docs[0].Data))
+		assert.Nil(t, docs[0].ItemLines("providers"))
+	})
+
+	t.Run("Later documents that can't be parsed are an error", func(t *testing.T) {
+		_, err := SplitDocuments([]byte("providers: []\n---\nproviders: [\n"))
+		require.Error(t, err)
+	})
+}
+
+func TestSchemaRegistryDecodeDocuments(t *testing.T) {
+	registry := NewSchemaRegistry(
+		Schema{APIVersion: 1, New: func() interface{} { return &schemaTestConfigV1{} }},
+		Schema{APIVersion: 0, New: func() interface{} { return &schemaTestConfigV0{} }},
+	)
+	decoder := YAMLDecoder{Subsystem: "dashboards", Strict: true, Environment: Environment{Version: "8.0.0"}}
+
+	t.Run("Every document is decoded with the schema of its version", func(t *testing.T) {
+		docs, err := registry.DecodeDocuments(decoder, "/etc/dashboards.yaml",
+			[]byte("- name: old\n---\napiVersion: 1\nproviders:\n  - name: new\n"))
+		require.NoError(t, err)
+		require.Len(t, docs, 2)
+		assert.Equal(t, int64(0), docs[0].APIVersion)
+		assert.Equal(t, "old", (*docs[0].Value.(*schemaTestConfigV0))[0].Name)
+		assert.Equal(t, int64(1), docs[1].APIVersion)
+		assert.Equal(t, "new", docs[1].Value.(*schemaTestConfigV1).Providers[0].Name)
+		assert.Equal(t, []int{5}, docs[1].ItemLines("providers"))
+	})
+
+	t.Run("Documents are skipped by their own guards", func(t *testing.T) {
+		data := []byte("apiVersion: 1\nminVersion: 9.0.0\nproviders: []\n---\napiVersion: 1\nproviders: []\n")
+		docs, err := registry.DecodeDocuments(decoder, "/etc/dashboards.yaml", data)
+		require.NoError(t, err)
+		require.Len(t, docs, 1)
+		assert.Equal(t, 2, docs[0].Index)
+
+		_, err = registry.DecodeDocuments(decoder, "/etc/dashboards.yaml",
+			[]byte("apiVersion: 1\nminVersion: 9.0.0\n---\napiVersion: 1\nminVersion: 9.0.0\n"))
+		require.True(t, errors.Is(err, ErrFileSkipped), "A file whose documents are all skipped is skipped")
+	})
+
+	t.Run("Errors have the document and the line in the file", func(t *testing.T) {
+		_, err := registry.DecodeDocuments(decoder, "/etc/dashboards.yaml",
+			[]byte("apiVersion: 1\nproviders: []\n---\napiVersion: 1\nproviders:\n  - nmae: typo\n"))
+		var fileErr *ProvisioningFileError
+		require.True(t, errors.As(err, &fileErr))
+		assert.Equal(t, 2, fileErr.Document)
+		assert.Equal(t, 6, fileErr.Line)
+		assert.EqualError(t, err, `file /etc/dashboards.yaml document 2 line 6: line 6: unknown field "nmae"`)
+
+		_, err = registry.DecodeDocuments(decoder, "/etc/dashboards.yaml", []byte("apiVersion: 1\nproviders: [\n"))
+		require.True(t, errors.As(err, &fileErr))
+		assert.Equal(t, 0, fileErr.Document, "Errors of files of a single document don't have the document")
+	})
+}
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ExportFile is a provisioning file written from the objects in the database.
+type ExportFile struct {
+	// Name is the name of the file in the export directory.
+	Name    string
+	Content []byte
+	// Objects counts the objects of the file.
+	Objects int
+	// Variables are the environment variables the placeholders of the secrets of the file read, in order.
+	Variables []string
+}
+
+// ExportSecrets replaces the secrets of exported objects with environment variable placeholders, so an export
+// never holds them in plaintext.
+type ExportSecrets struct {
+	prefix    string
+	variables []string
+	used      map[string]bool
+}
+
+// NewExportSecrets returns the placeholders of an export whose variables start with prefix.
+func NewExportSecrets(prefix string) *ExportSecrets {
+	return &ExportSecrets{prefix: prefix, used: map[string]bool{}}
+}
+
+// Placeholder returns the ${VAR} placeholder of a secret. The variable is named after the parts, upper cased with
+// anything but letters and digits replaced by underscores, and gets a suffix when another secret has the same name.
+func (s *ExportSecrets) Placeholder(parts ...string) string {
+	name := s.prefix
+	for _, part := range parts {
+		name += "_" + strings.Map(func(r rune) rune {
+			if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				return unicode.ToUpper(r)
+			}
+			return '_'
+		}, part)
+	}
+	variable := name
+	for i := 2; s.used[variable]; i++ {
+		variable = fmt.Sprintf("%s_%d", name, i)
+	}
+	s.used[variable] = true
+	s.variables = append(s.variables, variable)
+	return "${" + variable + "}"
+}
+
+// Variables returns the variables of the placeholders returned so far, in order.
+func (s *ExportSecrets) Variables() []string {
+	return s.variables
+}
+
+// EscapeInterpolation escapes the $ of value, so reading the exported file gives value back rather than
+// interpolating it.
+func EscapeInterpolation(value string) string {
+	return strings.ReplaceAll(value, "$", "$$")
+}
+
+// ExportJSON returns a JSON value of the database as it's written to an export file. Its strings are escaped like
+// EscapeInterpolation and its json.Numbers, which YAML would quote as strings, are turned into numbers. It returns
+// a copy of the maps and slices of value.
+func ExportJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return EscapeInterpolation(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case map[string]interface{}:
+		exported := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			exported[key] = ExportJSON(item)
+		}
+		return exported
+	case []interface{}:
+		exported := make([]interface{}, len(v))
+		for i, item := range v {
+			exported[i] = ExportJSON(item)
+		}
+		return exported
+	default:
+		return value
+	}
+}
+
+// MarshalExport encodes cfg as the YAML of an export file, with a header listing the variables its secrets read.
+func MarshalExport(cfg interface{}, variables []string) ([]byte, error) {
+	content, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString("# Exported from the Grafana database.\n")
+	if len(variables) > 0 {
+		buf.WriteString("# The secrets are read from these environment variables:\n")
+		for _, variable := range variables {
+			buf.WriteString("#   " + variable + "\n")
+		}
+	}
+	buf.Write(content)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// ProvisioningFileError is returned when a provisioning file can't be parsed or is invalid. It survives the wrapping
+// done by the provisioning service, so callers can get the file and location of a failure with errors.As. Errors
+// applying valid files, like database errors, aren't ProvisioningFileErrors.
+type ProvisioningFileError struct {
+	// Subsystem is the kind of provisioning the file belongs to, like datasources or dashboards.
+	Subsystem string
+	// Path is the absolute path of the file.
+	Path string
+	// Line and Column are the 1-based location of the error in the file, or 0 when the decoder doesn't report it.
+	Line   int
+	Column int
+	Err    error
+	// Document is the 1-based index of the YAML document of the error in a file of several documents, see
+	// SplitDocuments, and 0 for the other files.
+	Document int
+}
+
+func (e *ProvisioningFileError) Error() string {
+	file := "file " + e.Path
+	if e.Document > 0 {
+		file += fmt.Sprintf(" document %d", e.Document)
+	}
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		return fmt.Sprintf("%s line %d column %d: %v", file, e.Line, e.Column, e.Err)
+	case e.Line > 0:
+		return fmt.Sprintf("%s line %d: %v", file, e.Line, e.Err)
+	default:
+		return fmt.Sprintf("%s: %v", file, e.Err)
+	}
+}
+
+func (e *ProvisioningFileError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes every ProvisioningFileError match ErrInvalidConfig.
+func (e *ProvisioningFileError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
+
+// ErrInvalidConfig is matched with errors.Is by the errors caused by invalid provisioning files, as opposed to the
+// errors applying valid files, like database errors.
+var ErrInvalidConfig = errors.New("invalid provisioning config")
+
+// invalidConfigError marks an error as caused by an invalid provisioning file, keeping its message.
+type invalidConfigError struct {
+	err error
+}
+
+// InvalidConfig marks err as caused by an invalid provisioning file without changing its message, for the
+// validation errors that don't belong to a single file or whose message is already part of the API. It returns nil
+// if err is nil.
+func InvalidConfig(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &invalidConfigError{err: err}
+}
+
+func (e *invalidConfigError) Error() string {
+	return e.err.Error()
+}
+
+func (e *invalidConfigError) Unwrap() error {
+	return e.err
+}
+
+func (e *invalidConfigError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
+
+// IsInvalidConfig reports whether err was caused by invalid provisioning files rather than by applying them.
+// Besides ErrInvalidConfig, it matches the orgs that provisioning files reference but don't exist.
+func IsInvalidConfig(err error) bool {
+	return errors.Is(err, ErrInvalidConfig) || errors.Is(err, models.ErrOrgNotFound)
+}
+
+// FileErrors are the errors of every invalid file of a provisioning run, so that they can be fixed at once. It
+// unwraps to its first error, which errors.As finds like a single ProvisioningFileError.
+type FileErrors []*ProvisioningFileError
+
+func (e FileErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e FileErrors) Unwrap() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e[0]
+}
+
+// Err returns nil when there are no errors and the error itself when there is only one.
+func (e FileErrors) Err() error {
+	switch len(e) {
+	case 0:
+		return nil
+	case 1:
+		return e[0]
+	default:
+		return e
+	}
+}
+
+// AppendFileError appends the file errors of err to errs, so that the other files are still read. Any other error is
+// returned, as reading can't go on.
+func AppendFileError(errs FileErrors, err error) (FileErrors, error) {
+	fileErrs := FileErrorsOf(err)
+	if fileErrs == nil {
+		return errs, err
+	}
+	return append(errs, fileErrs...), nil
+}
+
+// FileErrorsOf returns the errors of the invalid files err was caused by, or nil when err isn't a validation error
+// but, for example, a database error.
+func FileErrorsOf(err error) []*ProvisioningFileError {
+	var fileErrs FileErrors
+	if errors.As(err, &fileErrs) {
+		return fileErrs
+	}
+	var fileErr *ProvisioningFileError
+	if errors.As(err, &fileErr) {
+		return []*ProvisioningFileError{fileErr}
+	}
+	return nil
+}
+
+// yaml.v2 only reports the line, either as "yaml: line N: ..." or as "line N: ..." for each unmarshal error.
+var yamlErrorLine = regexp.MustCompile(`line (\d+):`)
+
+// NewYAMLFileError wraps an error returned while decoding the YAML file at path. The line is taken from the
+// first location the decoder reports. It returns nil if err is nil.
+func NewYAMLFileError(subsystem, path string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	fileErr := &ProvisioningFileError{Subsystem: subsystem, Path: path, Err: err}
+	if match := yamlErrorLine.FindStringSubmatch(err.Error()); match != nil {
+		fileErr.Line, _ = strconv.Atoi(match[1])
+	}
+	return fileErr
+}
+
+// NewJSONFileError wraps an error returned while decoding data, the content of the JSON file at path. The line
+// and column are derived from the byte offset of syntax and type errors. It returns nil if err is nil.
+func NewJSONFileError(subsystem, path string, data []byte, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	fileErr := &ProvisioningFileError{Subsystem: subsystem, Path: path, Err: err}
+
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	}
+
+	if offset > 0 && offset <= int64(len(data)) {
+		// The offset points just past the byte that caused the error.
+		before := data[:offset-1]
+		fileErr.Line = bytes.Count(before, []byte("\n")) + 1
+		fileErr.Column = len(before) - bytes.LastIndexByte(before, '\n')
+	}
+	return fileErr
+}
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/util/errutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestProvisioningFileError(t *testing.T) {
+	t.Run("YAML syntax error carries the line", func(t *testing.T) {
+		var cfg map[string]interface{}
+		yamlErr := yaml.Unmarshal([]byte("apiVersion: 1\n  datasources: []\n"), &cfg)
+		require.Error(t, yamlErr)
+
+		err := NewYAMLFileError("datasources", "/etc/grafana/provisioning/datasources/ds.yaml", yamlErr)
+
+		var fileErr *ProvisioningFileError
+		require.True(t, errors.As(err, &fileErr))
+		assert.Equal(t, "datasources", fileErr.Subsystem)
+		assert.Equal(t, "/etc/grafana/provisioning/datasources/ds.yaml", fileErr.Path)
+		assert.Equal(t, 2, fileErr.Line)
+		assert.Equal(t, 0, fileErr.Column)
+		assert.Equal(t, "file /etc/grafana/provisioning/datasources/ds.yaml line 2: "+yamlErr.Error(), err.Error())
+	})
+
+	t.Run("YAML type error carries the line of the first failure", func(t *testing.T) {
+		var cfg struct {
+			APIVersion int64 `yaml:"apiVersion"`
+		}
+		yamlErr := yaml.Unmarshal([]byte("# comment\napiVersion: one\n"), &cfg)
+		require.Error(t, yamlErr)
+
+		var fileErr *ProvisioningFileError
+		require.True(t, errors.As(NewYAMLFileError("plugins", "/plugins.yaml", yamlErr), &fileErr))
+		assert.Equal(t, 2, fileErr.Line)
+
+		var typeErr *yaml.TypeError
+		assert.True(t, errors.As(fileErr, &typeErr), "the decoder error should still be reachable")
+	})
+
+	t.Run("JSON syntax error carries the line and column", func(t *testing.T) {
+		data := []byte("{\n  \"title\": \"Home\",\n  \"uid\" \"home\"\n}")
+		var dash map[string]interface{}
+		jsonErr := json.Unmarshal(data, &dash)
+		require.Error(t, jsonErr)
+
+		var fileErr *ProvisioningFileError
+		require.True(t, errors.As(NewJSONFileError("dashboards", "/home.json", data, jsonErr), &fileErr))
+		assert.Equal(t, 3, fileErr.Line)
+		assert.Equal(t, 9, fileErr.Column)
+		assert.Equal(t, "file /home.json line 3 column 9: "+jsonErr.Error(), fileErr.Error())
+	})
+
+	t.Run("Error without a location only names the file", func(t *testing.T) {
+		err := NewJSONFileError("dashboards", "/home.json", nil, errors.New("unexpected EOF"))
+		assert.Equal(t, "file /home.json: unexpected EOF", err.Error())
+	})
+
+	t.Run("Nil error stays nil", func(t *testing.T) {
+		assert.Nil(t, NewYAMLFileError("orgs", "/orgs.yaml", nil))
+		assert.Nil(t, NewJSONFileError("dashboards", "/home.json", nil, nil))
+	})
+
+	t.Run("errors.Is and errors.As work through wrapping", func(t *testing.T) {
+		cause := errors.New("cause")
+		err := errutil.Wrap("Datasource provisioning error", fmt.Errorf("reading config: %w", NewYAMLFileError("datasources", "/ds.yaml", cause)))
+
+		var fileErr *ProvisioningFileError
+		require.True(t, errors.As(err, &fileErr))
+		assert.Equal(t, "/ds.yaml", fileErr.Path)
+		assert.True(t, errors.Is(err, cause))
+	})
+
+	t.Run("Errors of several files are all reported", func(t *testing.T) {
+		var errs FileErrors
+		errs, err := AppendFileError(errs, &ProvisioningFileError{Path: "/a.yaml", Err: errors.New("invalid")})
+		require.NoError(t, err)
+		errs, err = AppendFileError(errs, fmt.Errorf("reading: %w", &ProvisioningFileError{Path: "/b.yaml", Line: 3, Err: errors.New("invalid")}))
+		require.NoError(t, err)
+		errs, err = AppendFileError(errs, nil)
+		require.NoError(t, err)
+
+		wrapped := errutil.Wrap("Datasource provisioning error", errs.Err())
+		assert.Equal(t, "Datasource provisioning error: file /a.yaml: invalid; file /b.yaml line 3: invalid", wrapped.Error())
+		fileErrs := FileErrorsOf(wrapped)
+		require.Len(t, fileErrs, 2)
+		assert.Equal(t, "/b.yaml", fileErrs[1].Path)
+
+		var fileErr *ProvisioningFileError
+		require.True(t, errors.As(wrapped, &fileErr))
+		assert.Equal(t, "/a.yaml", fileErr.Path)
+	})
+
+	t.Run("Other errors aren't file errors", func(t *testing.T) {
+		dbErr := errors.New("database is locked")
+		errs, err := AppendFileError(nil, dbErr)
+		assert.Equal(t, dbErr, err)
+		assert.Nil(t, errs.Err())
+		assert.Nil(t, FileErrorsOf(errutil.Wrap("Datasource provisioning error", dbErr)))
+
+		single := &ProvisioningFileError{Path: "/a.yaml", Err: errors.New("invalid")}
+		assert.Equal(t, []*ProvisioningFileError{single}, FileErrorsOf(errutil.Wrap("Datasource provisioning error", single)))
+	})
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/hashicorp/go-version"
+	"gopkg.in/yaml.v2"
+)
+
+// ErrFileSkipped is returned, wrapped, for provisioning files whose guards the running Grafana doesn't satisfy.
+var ErrFileSkipped = errors.New("provisioning file skipped")
+
+// guardFields are the top level fields of the guards, which every provisioning file may have on top of its own.
+var guardFields = []string{"minVersion", "maxVersion", "requiresFeatureFlags"}
+
+// FileGuards are the optional top level fields of a provisioning file that limit the Grafana instances it applies to,
+// so the same files can be used by instances of different versions.
+type FileGuards struct {
+	// MinVersion and MaxVersion are the inclusive range of Grafana versions the file applies to.
+	MinVersion string `yaml:"minVersion"`
+	MaxVersion string `yaml:"maxVersion"`
+	// RequiresFeatureFlags are the feature toggles that must all be enabled for the file to apply.
+	RequiresFeatureFlags []string `yaml:"requiresFeatureFlags"`
+}
+
+// Environment is the running Grafana the guards of provisioning files are checked against.
+type Environment struct {
+	// Version is the Grafana build version, like 8.0.0 or 8.1.0-beta1.
+	Version        string
+	FeatureToggles map[string]bool
+}
+
+type environmentKey struct{}
+
+// WithEnvironment returns a copy of ctx that carries env to the config readers of the provisioners.
+func WithEnvironment(ctx context.Context, env Environment) context.Context {
+	return context.WithValue(ctx, environmentKey{}, env)
+}
+
+// EnvironmentFromContext returns the environment carried by ctx. Without one, it's the build version without any
+// feature toggles, like for linting provisioning files.
+func EnvironmentFromContext(ctx context.Context) Environment {
+	if env, ok := ctx.Value(environmentKey{}).(Environment); ok {
+		return env
+	}
+	return Environment{Version: setting.BuildVersion}
+}
+
+// unsatisfied returns why the guards don't apply to env, or an empty string when they do.
+func (g FileGuards) unsatisfied(env Environment) (string, error) {
+	if g.MinVersion != "" || g.MaxVersion != "" {
+		current, err := version.NewVersion(env.Version)
+		if err != nil {
+			return "", fmt.Errorf("can't check the version guards of the file, invalid Grafana version %q", env.Version)
+		}
+		// Pre-releases count as the release they precede, so a beta of 8.1 gets the files of 8.1.
+		current = current.Core()
+
+		if g.MinVersion != "" {
+			min, err := version.NewVersion(g.MinVersion)
+			if err != nil {
+				return "", fmt.Errorf("invalid minVersion %q", g.MinVersion)
+			}
+			if current.LessThan(min) {
+				return fmt.Sprintf("Grafana %s is older than minVersion %s", env.Version, g.MinVersion), nil
+			}
+		}
+		if g.MaxVersion != "" {
+			max, err := version.NewVersion(g.MaxVersion)
+			if err != nil {
+				return "", fmt.Errorf("invalid maxVersion %q", g.MaxVersion)
+			}
+			if current.GreaterThan(max) {
+				return fmt.Sprintf("Grafana %s is newer than maxVersion %s", env.Version, g.MaxVersion), nil
+			}
+		}
+	}
+
+	var disabled []string
+	for _, flag := range g.RequiresFeatureFlags {
+		if !env.FeatureToggles[flag] {
+			disabled = append(disabled, flag)
+		}
+	}
+	if len(disabled) > 0 {
+		return fmt.Sprintf("required feature flags aren't enabled: %s", strings.Join(disabled, ", ")), nil
+	}
+	return "", nil
+}
+
+// checkGuards returns an ErrFileSkipped error when the guards of the YAML content data don't apply to env. Content
+// that isn't a mapping, like a list of version 0 dashboard providers, has no guards.
+func checkGuards(data []byte, env Environment) error {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil
+	}
+	if _, ok := doc.(map[interface{}]interface{}); !ok {
+		return nil
+	}
+
+	var guards FileGuards
+	if err := yaml.Unmarshal(data, &guards); err != nil {
+		return err
+	}
+
+	reason, err := guards.unsatisfied(env)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		return fmt.Errorf("%w: %s", ErrFileSkipped, reason)
+	}
+	return nil
+}
+
+// isGuardField returns whether a "field <name> not found in type <type>" error of yaml.v2 is about a guard field at
+// the top level of a file decoded into a value of type topLevelType.
+func isGuardField(message string, topLevelType string) bool {
+	for _, field := range guardFields {
+		if strings.HasSuffix(message, fmt.Sprintf("field %s not found in type %s", field, topLevelType)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileGuards(t *testing.T) {
+	env := Environment{Version: "8.1.0-beta1", FeatureToggles: map[string]bool{"ngalert": true}}
+
+	tcs := []struct {
+		desc   string
+		guards FileGuards
+		reason string
+	}{
+		{desc: "No guards", guards: FileGuards{}},
+		{desc: "Inclusive version range", guards: FileGuards{MinVersion: "8.1.0", MaxVersion: "8.1.0"}},
+		{desc: "Older than minVersion", guards: FileGuards{MinVersion: "8.2.0"},
+			reason: "Grafana 8.1.0-beta1 is older than minVersion 8.2.0"},
+		{desc: "Newer than maxVersion", guards: FileGuards{MaxVersion: "8.0.7"},
+			reason: "Grafana 8.1.0-beta1 is newer than maxVersion 8.0.7"},
+		{desc: "Enabled feature flags", guards: FileGuards{RequiresFeatureFlags: []string{"ngalert"}}},
+		{desc: "Disabled feature flags", guards: FileGuards{RequiresFeatureFlags: []string{"ngalert", "live", "tempoSearch"}},
+			reason: "required feature flags aren't enabled: live, tempoSearch"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.desc, func(t *testing.T) {
+			reason, err := tc.guards.unsatisfied(env)
+			require.NoError(t, err)
+			assert.Equal(t, tc.reason, reason)
+		})
+	}
+
+	t.Run("Invalid versions are an error", func(t *testing.T) {
+		_, err := FileGuards{MinVersion: "eight"}.unsatisfied(env)
+		assert.EqualError(t, err, `invalid minVersion "eight"`)
+
+		_, err = FileGuards{MaxVersion: "8.0.0"}.unsatisfied(Environment{Version: "dev"})
+		assert.EqualError(t, err, `can't check the version guards of the file, invalid Grafana version "dev"`)
+	})
+
+	t.Run("Feature flags don't need a valid Grafana version", func(t *testing.T) {
+		reason, err := FileGuards{RequiresFeatureFlags: []string{"live"}}.unsatisfied(Environment{Version: "dev"})
+		require.NoError(t, err)
+		assert.NotEmpty(t, reason)
+	})
+}
+
+func TestYAMLDecoderGuards(t *testing.T) {
+	const guarded = `apiVersion: 1
+minVersion: 8.0.0
+requiresFeatureFlags: [ngalert]
+datasources:
+  - name: Graphite
+`
+
+	t.Run("Skips files whose guards aren't satisfied", func(t *testing.T) {
+		var cfg decodeTestConfig
+		decoder := YAMLDecoder{Subsystem: "datasources", Environment: Environment{Version: "8.0.0"}}
+		err := decoder.Decode("/etc/ds.yaml", []byte(guarded), &cfg)
+		require.True(t, errors.Is(err, ErrFileSkipped))
+		assert.EqualError(t, err, "provisioning file skipped: required feature flags aren't enabled: ngalert")
+		assert.Empty(t, cfg.Datasources)
+	})
+
+	t.Run("Guard fields aren't unknown fields in strict mode", func(t *testing.T) {
+		var cfg decodeTestConfig
+		decoder := YAMLDecoder{Subsystem: "datasources", Strict: true,
+			Environment: Environment{Version: "8.0.0", FeatureToggles: map[string]bool{"ngalert": true}}}
+		require.NoError(t, decoder.Decode("/etc/ds.yaml", []byte(guarded), &cfg))
+		require.Len(t, cfg.Datasources, 1)
+		assert.Equal(t, "Graphite", cfg.Datasources[0].Name)
+	})
+
+	t.Run("Invalid guards are a file error", func(t *testing.T) {
+		var cfg decodeTestConfig
+		decoder := YAMLDecoder{Subsystem: "datasources", Environment: Environment{Version: "8.0.0"}}
+		err := decoder.Decode("/etc/ds.yaml", []byte("apiVersion: 1\nmaxVersion: latest\n"), &cfg)
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, ErrFileSkipped))
+
+		var fileErr *ProvisioningFileError
+		require.True(t, errors.As(err, &fileErr))
+		assert.Equal(t, "/etc/ds.yaml", fileErr.Path)
+	})
+
+	t.Run("Lists have no guards", func(t *testing.T) {
+		var providers []struct {
+			Name string `yaml:"name"`
+		}
+		decoder := YAMLDecoder{Subsystem: "dashboards", Strict: true}
+		require.NoError(t, decoder.Decode("/etc/dashboards.yaml", []byte("- name: default\n"), &providers))
+		require.Len(t, providers, 1)
+	})
+}
+
+func TestEnvironmentFromContext(t *testing.T) {
+	env := Environment{Version: "8.1.0", FeatureToggles: map[string]bool{"live": true}}
+	assert.Equal(t, env, EnvironmentFromContext(WithEnvironment(context.Background(), env)))
+	assert.Nil(t, EnvironmentFromContext(context.Background()).FeatureToggles)
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// The actions provisioning takes on an object.
+const (
+	ActionCreated = "created"
+	ActionUpdated = "updated"
+	// ActionSkipped is for objects that were already up to date.
+	ActionSkipped = "skipped"
+	ActionDeleted = "deleted"
+)
+
+// ProvisionedObject is an object that provisioning applied from a file.
+type ProvisionedObject struct {
+	// Kind is the kind of object, like datasource or dashboard.
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	UID  string `json:"uid,omitempty"`
+	// OrgID is 0 for objects that don't belong to an org.
+	OrgID int64 `json:"orgId"`
+	// File is the absolute path of the file the object was provisioned from.
+	File      string    `json:"file"`
+	AppliedAt time.Time `json:"appliedAt"`
+	// Action is what the run that applied the object did, empty when the provisioner doesn't tell.
+	Action string `json:"action,omitempty"`
+	// Document is the 1-based index of the YAML document the object is in, for files of several documents, and Line
+	// the line of the file it starts at when the provisioner knows it. Both are 0 for objects that are a file of
+	// their own, like dashboards.
+	Document int `json:"document,omitempty"`
+	Line     int `json:"line,omitempty"`
+}
+
+// Inventory records the objects a provisioner applied during a run, so a run that fails halfway only lists what
+// was actually applied. The methods of a nil Inventory do nothing.
+type Inventory struct {
+	mutex   sync.Mutex
+	objects []ProvisionedObject
+	deleted []ProvisionedObject
+}
+
+// NewInventory returns an empty inventory.
+func NewInventory() *Inventory {
+	return &Inventory{}
+}
+
+// Record adds an applied object, with the current time unless AppliedAt is set.
+func (i *Inventory) Record(object ProvisionedObject) {
+	if i == nil {
+		return
+	}
+	if object.AppliedAt.IsZero() {
+		object.AppliedAt = time.Now()
+	}
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.objects = append(i.objects, object)
+}
+
+// Objects returns the recorded objects in the order they were applied.
+func (i *Inventory) Objects() []ProvisionedObject {
+	if i == nil {
+		return nil
+	}
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return append([]ProvisionedObject{}, i.objects...)
+}
+
+// Len returns the number of recorded objects.
+func (i *Inventory) Len() int {
+	if i == nil {
+		return 0
+	}
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return len(i.objects)
+}
+
+// RecordDeleted adds an object the run deleted, with the current time unless AppliedAt is set. Deleted objects
+// aren't listed by Objects.
+func (i *Inventory) RecordDeleted(object ProvisionedObject) {
+	if i == nil {
+		return
+	}
+	if object.AppliedAt.IsZero() {
+		object.AppliedAt = time.Now()
+	}
+	object.Action = ActionDeleted
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.deleted = append(i.deleted, object)
+}
+
+// Deleted returns the recorded deleted objects in the order they were deleted.
+func (i *Inventory) Deleted() []ProvisionedObject {
+	if i == nil {
+		return nil
+	}
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return append([]ProvisionedObject{}, i.deleted...)
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// LintError is a problem found in a provisioning file by the Lint function of a provisioner, which validates the
+// files without a database.
+type LintError struct {
+	// File is the absolute path of the invalid file, or of its directory for problems that span several files.
+	File string
+	// Line is the 1-based line of the problem, or 0 when it isn't known.
+	Line int
+	Err  error
+}
+
+// NewLintError returns the LintError of a file. The location of a ProvisioningFileError is kept.
+func NewLintError(file string, err error) LintError {
+	var fileErr *ProvisioningFileError
+	if errors.As(err, &fileErr) {
+		return LintError{File: fileErr.Path, Line: fileErr.Line, Err: fileErr.Err}
+	}
+	return LintError{File: file, Err: err}
+}
+
+func (e LintError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.File, e.Err)
+}
+
+// ProvisioningFiles returns the YAML files of a provisioning directory that the filter includes, sorted by name.
+func ProvisioningFiles(path string, fileFilter setting.ProvisioningFileFilter) ([]os.FileInfo, error) {
+	files, err := ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var yamlFiles []os.FileInfo
+	for _, file := range files {
+		if (strings.HasSuffix(file.Name(), ".yaml") || strings.HasSuffix(file.Name(), ".yml")) &&
+			fileFilter.Includes(file.Name()) {
+			yamlFiles = append(yamlFiles, file)
+		}
+	}
+	return yamlFiles, nil
+}
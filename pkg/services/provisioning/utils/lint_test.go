@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLintError(t *testing.T) {
+	t.Run("Keeps the location of file errors", func(t *testing.T) {
+		inner := errors.New("did not find expected key")
+		err := fmt.Errorf("failed to read: %w", &ProvisioningFileError{Subsystem: "datasources", Path: "/etc/ds.yaml", Line: 4, Err: inner})
+
+		lintErr := NewLintError("/etc/other.yaml", err)
+		assert.Equal(t, LintError{File: "/etc/ds.yaml", Line: 4, Err: inner}, lintErr)
+		assert.Equal(t, "/etc/ds.yaml:4: did not find expected key", lintErr.Error())
+	})
+
+	t.Run("Uses the given file for other errors", func(t *testing.T) {
+		lintErr := NewLintError("/etc/ds.yaml", errors.New("invalid"))
+		assert.Equal(t, "/etc/ds.yaml: invalid", lintErr.Error())
+	})
+}
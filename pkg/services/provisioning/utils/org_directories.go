@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// orgsDirectory is the directory of a provisioning path that holds the per-org directories, like
+// orgs/2/datasources. It's shared with the org config files, which are read from the same directory.
+const orgsDirectory = "orgs"
+
+// ErrOrgConflict is returned for objects of a per-org directory whose orgId is another org.
+var ErrOrgConflict = InvalidConfig(errors.New("orgId conflicts with the org of the directory"))
+
+// OrgDirectories returns the per-org directories of a subsystem in a provisioning path, like
+// <path>/orgs/<orgID>/datasources, ordered by org ID. Org directories whose name isn't a positive org ID and orgs
+// without a directory for the subsystem are skipped.
+func OrgDirectories(path, subsystem string) []string {
+	entries, err := ReadDir(filepath.Join(path, orgsDirectory))
+	if err != nil {
+		return nil
+	}
+
+	var orgIDs []int64
+	for _, entry := range entries {
+		orgID, err := strconv.ParseInt(entry.Name(), 10, 64)
+		if err != nil || orgID < 1 || !entry.IsDir() {
+			continue
+		}
+		orgIDs = append(orgIDs, orgID)
+	}
+	sort.Slice(orgIDs, func(i, j int) bool { return orgIDs[i] < orgIDs[j] })
+
+	var dirs []string
+	for _, orgID := range orgIDs {
+		dir := filepath.Join(path, orgsDirectory, strconv.FormatInt(orgID, 10), subsystem)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// OrgFromPath returns the org ID of a per-org directory like <path>/orgs/<orgID>/datasources, or 0 for other
+// directories.
+func OrgFromPath(dir string) int64 {
+	orgDir := filepath.Dir(filepath.Clean(dir))
+	if filepath.Base(filepath.Dir(orgDir)) != orgsDirectory {
+		return 0
+	}
+
+	orgID, err := strconv.ParseInt(filepath.Base(orgDir), 10, 64)
+	if err != nil || orgID < 1 {
+		return 0
+	}
+	return orgID
+}
+
+// ApplyPathOrg sets the org of an object read from a per-org directory. An object without an orgId gets the org of
+// the directory, and one with another orgId is an ErrOrgConflict. Objects of other directories are left alone.
+func ApplyPathOrg(orgID *int64, pathOrgID int64) error {
+	if pathOrgID == 0 {
+		return nil
+	}
+	if *orgID == 0 {
+		*orgID = pathOrgID
+		return nil
+	}
+	if *orgID != pathOrgID {
+		return fmt.Errorf("%w: orgId %d in the directory of org %d", ErrOrgConflict, *orgID, pathOrgID)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrgFromPath(t *testing.T) {
+	assert.Equal(t, int64(2), OrgFromPath("/etc/grafana/provisioning/orgs/2/datasources"))
+	assert.Equal(t, int64(12), OrgFromPath("provisioning/orgs/12/dashboards/"))
+	assert.Equal(t, int64(0), OrgFromPath("/etc/grafana/provisioning/datasources"))
+	assert.Equal(t, int64(0), OrgFromPath("/etc/grafana/provisioning/orgs/team/datasources"))
+	assert.Equal(t, int64(0), OrgFromPath("/etc/grafana/provisioning/orgs/0/datasources"))
+	assert.Equal(t, int64(0), OrgFromPath("/etc/grafana/provisioning/teams/2/datasources"))
+}
+
+func TestApplyPathOrg(t *testing.T) {
+	orgID := int64(0)
+	require.NoError(t, ApplyPathOrg(&orgID, 2))
+	assert.Equal(t, int64(2), orgID, "Objects without an orgId get the org of the directory")
+
+	require.NoError(t, ApplyPathOrg(&orgID, 2), "A matching orgId is allowed")
+
+	err := ApplyPathOrg(&orgID, 3)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrOrgConflict))
+
+	orgID = 0
+	require.NoError(t, ApplyPathOrg(&orgID, 0))
+	assert.Equal(t, int64(0), orgID, "Objects of other directories are left alone")
+}
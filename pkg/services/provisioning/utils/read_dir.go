@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// ListDir lists the entries of a directory in whatever order the filesystem returns them. It's a variable so that
+// tests can list the entries in another order, the provisioners read their directories through ReadDir.
+var ListDir = ioutil.ReadDir
+
+// ReadDir returns the entries of a provisioning directory sorted by name, so that the files of a directory are
+// applied in the same order on every machine and OS, and a later file wins over an earlier one with the same name
+// or uid alike.
+func ReadDir(path string) ([]os.FileInfo, error) {
+	files, err := ListDir(path)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+	return files, nil
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// shuffleListDir makes ListDir return the entries of a directory in a random order until the test ends.
+func shuffleListDir(t *testing.T, seed int64) {
+	t.Helper()
+	random := rand.New(rand.NewSource(seed))
+	ListDir = func(path string) ([]os.FileInfo, error) {
+		files, err := ioutil.ReadDir(path)
+		random.Shuffle(len(files), func(i, j int) { files[i], files[j] = files[j], files[i] })
+		return files, err
+	}
+	t.Cleanup(func() { ListDir = ioutil.ReadDir })
+}
+
+func TestReadDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.yaml", "a.yaml", "c.yml", "a-first.yaml", "B.yaml"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), nil, 0600))
+	}
+
+	for seed := int64(0); seed < 5; seed++ {
+		shuffleListDir(t, seed)
+
+		files, err := ReadDir(dir)
+		require.NoError(t, err)
+		names := make([]string, 0, len(files))
+		for _, file := range files {
+			names = append(names, file.Name())
+		}
+		assert.Equal(t, []string{"B.yaml", "a-first.yaml", "a.yaml", "b.yaml", "c.yml"}, names)
+	}
+
+	_, err := ReadDir(filepath.Join(dir, "missing"))
+	assert.True(t, os.IsNotExist(err))
+}
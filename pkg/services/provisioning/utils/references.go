@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// ReferenceCheckMode controls what happens when a provisioned object references a datasource that doesn't exist.
+type ReferenceCheckMode string
+
+const (
+	// ReferenceCheckOff doesn't check the datasource references of provisioned objects.
+	ReferenceCheckOff ReferenceCheckMode = "off"
+	// ReferenceCheckWarn logs a warning for every dangling reference.
+	ReferenceCheckWarn ReferenceCheckMode = "warn"
+	// ReferenceCheckFail skips the objects with dangling references and fails provisioning.
+	ReferenceCheckFail ReferenceCheckMode = "fail"
+)
+
+// ErrDanglingReferences is returned, as a DanglingReferencesError, when provisioned objects reference datasources
+// that don't exist and the reference check fails on them.
+var ErrDanglingReferences = errors.New("provisioned objects reference datasources that don't exist")
+
+// DanglingReference is a reference of a provisioned object to a datasource uid that doesn't exist.
+type DanglingReference struct {
+	// File is the provisioning file of the object.
+	File string
+	// Kind and UID are the kind and the uid of the object, like dashboard and its uid.
+	Kind string
+	UID  string
+	// Location is where in the object the datasource is referenced, like panel "CPU".
+	Location      string
+	DatasourceUID string
+}
+
+func (r DanglingReference) String() string {
+	return fmt.Sprintf("%s %q in %s references datasource %q in %s", r.Kind, r.UID, r.File, r.DatasourceUID, r.Location)
+}
+
+// DanglingReferencesError lists the dangling references that failed provisioning.
+type DanglingReferencesError struct {
+	References []DanglingReference
+}
+
+func (e *DanglingReferencesError) Error() string {
+	refs := make([]string, 0, len(e.References))
+	for _, ref := range e.References {
+		refs = append(refs, ref.String())
+	}
+	return fmt.Sprintf("%v: %s", ErrDanglingReferences, strings.Join(refs, "; "))
+}
+
+func (e *DanglingReferencesError) Unwrap() error {
+	return ErrDanglingReferences
+}
+
+type referenceCheckKey struct{}
+
+// WithReferenceCheck returns a copy of ctx that carries mode to the provisioners.
+func WithReferenceCheck(ctx context.Context, mode ReferenceCheckMode) context.Context {
+	return context.WithValue(ctx, referenceCheckKey{}, mode)
+}
+
+// ReferenceCheckFromContext returns the reference check mode carried by ctx, or warn without one.
+func ReferenceCheckFromContext(ctx context.Context) ReferenceCheckMode {
+	if mode, ok := ctx.Value(referenceCheckKey{}).(ReferenceCheckMode); ok && mode != "" {
+		return mode
+	}
+	return ReferenceCheckWarn
+}
+
+// builtInDatasourceUIDs are the uids of datasources that exist without being stored, like the one of expressions.
+var builtInDatasourceUIDs = map[string]bool{
+	"grafana":         true,
+	"-- Grafana --":   true,
+	"-- Mixed --":     true,
+	"-- Dashboard --": true,
+	"__expr__":        true,
+	"-100":            true,
+}
+
+// IsDatasourceReference reports whether uid has to be the uid of a stored datasource. Template variables are only
+// known when the dashboard is viewed and built-in datasources aren't stored.
+func IsDatasourceReference(uid string) bool {
+	return uid != "" && !strings.Contains(uid, "$") && !builtInDatasourceUIDs[uid]
+}
+
+// DatasourceExists returns a function reporting whether a datasource with a uid exists in an org. The lookups are
+// cached, and datasources that can't be looked up count as existing so a failing lookup doesn't fail provisioning.
+func DatasourceExists(ctx context.Context) func(orgID int64, uid string) bool {
+	type key struct {
+		orgID int64
+		uid   string
+	}
+	found := map[key]bool{}
+	return func(orgID int64, uid string) bool {
+		if exists, ok := found[key{orgID, uid}]; ok {
+			return exists
+		}
+		query := &models.GetDataSourceQuery{OrgId: orgID, Uid: uid}
+		exists := !errors.Is(bus.DispatchCtx(ctx, query), models.ErrDataSourceNotFound)
+		found[key{orgID, uid}] = exists
+		return exists
+	}
+}
+
+// CheckDanglingReferences logs the dangling references unless mode is off, and returns them as a
+// DanglingReferencesError when mode is fail.
+func CheckDanglingReferences(logger log.Logger, mode ReferenceCheckMode, refs []DanglingReference) error {
+	if mode == ReferenceCheckOff || len(refs) == 0 {
+		return nil
+	}
+	for _, ref := range refs {
+		logger.Warn("provisioned object references a datasource that doesn't exist", "file", ref.File, "kind", ref.Kind,
+			"uid", ref.UID, "location", ref.Location, "datasourceUid", ref.DatasourceUID)
+	}
+	if mode == ReferenceCheckFail {
+		return &DanglingReferencesError{References: refs}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	// ErrKindMismatch is returned, wrapped, for provisioning files whose kind header isn't the kind of the files of
+	// the directory they're in.
+	ErrKindMismatch = errors.New("provisioning file kind doesn't match its directory")
+	// ErrUnsupportedAPIVersion is returned, wrapped, for provisioning files of an API version no schema reads.
+	ErrUnsupportedAPIVersion = errors.New("unsupported provisioning file apiVersion")
+)
+
+// headerFields are the top level fields of the header, which every provisioning file may have on top of its own.
+// The apiVersion is left out since the decoded types have it.
+var headerFields = []string{"kind"}
+
+// FileHeader is the optional header at the top of a provisioning file that tells which schema it's written in.
+type FileHeader struct {
+	// APIVersion is the version of the schema, 0 for the files without one.
+	APIVersion int64 `yaml:"apiVersion"`
+	// Kind is the kind of objects the file provisions, like Datasource, empty for files without one.
+	Kind string `yaml:"kind"`
+}
+
+// ReadFileHeader returns the header of the YAML content data. Content that isn't a mapping, like a list of
+// version 0 dashboard providers, has an empty header.
+func ReadFileHeader(data []byte) (FileHeader, error) {
+	var header FileHeader
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return header, err
+	}
+	if _, ok := doc.(map[interface{}]interface{}); !ok {
+		return header, nil
+	}
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return header, fmt.Errorf("invalid file header: %w", err)
+	}
+	return header, nil
+}
+
+// checkKind returns an ErrKindMismatch error when the kind header of the YAML content data is set and isn't kind.
+// Kinds are compared ignoring case, and the plural of kind is accepted too.
+func checkKind(data []byte, subsystem string, kind string) error {
+	header, err := ReadFileHeader(data)
+	if err != nil || header.Kind == "" {
+		// Content that can't be parsed is reported by decoding it.
+		return nil
+	}
+	if strings.EqualFold(header.Kind, kind) || strings.EqualFold(header.Kind, kind+"s") {
+		return nil
+	}
+	return fmt.Errorf("%w: kind %q can't be provisioned from the %s directory, which has %s files", ErrKindMismatch,
+		header.Kind, subsystem, kind)
+}
+
+// isHeaderField returns whether a "field <name> not found in type <type>" error of yaml.v2 is about a header field
+// at the top level of a file decoded into a value of type topLevelType.
+func isHeaderField(message string, topLevelType string) bool {
+	for _, field := range headerFields {
+		if strings.HasSuffix(message, fmt.Sprintf("field %s not found in type %s", field, topLevelType)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Schema is a version of the format of the provisioning files of a directory.
+type Schema struct {
+	APIVersion int64
+	// New returns a pointer to the value a file of the schema is decoded into.
+	New func() interface{}
+	// Deprecated are the fields of the schema that have a replacement.
+	Deprecated []DeprecatedField
+}
+
+// SchemaRegistry dispatches the provisioning files of a directory to the schema of their API version, so formats
+// can evolve without breaking the files written for older ones. Files without an apiVersion are of version 0, and
+// files of a version newer than every schema are read with the latest one, like they were before the schema of their
+// version existed.
+type SchemaRegistry struct {
+	// schemas are sorted by API version.
+	schemas []Schema
+}
+
+// NewSchemaRegistry returns a registry of the schemas.
+func NewSchemaRegistry(schemas ...Schema) *SchemaRegistry {
+	r := &SchemaRegistry{}
+	for _, schema := range schemas {
+		r.Register(schema)
+	}
+	return r
+}
+
+// Register adds a schema to the registry, replacing the schema of the same API version. It isn't safe to call
+// while the registry is used.
+func (r *SchemaRegistry) Register(schema Schema) {
+	for i, registered := range r.schemas {
+		if registered.APIVersion == schema.APIVersion {
+			r.schemas[i] = schema
+			return
+		}
+	}
+	r.schemas = append(r.schemas, schema)
+	sort.Slice(r.schemas, func(i, j int) bool { return r.schemas[i].APIVersion < r.schemas[j].APIVersion })
+}
+
+// Schema returns the schema that reads the files of apiVersion, the latest one that isn't newer.
+func (r *SchemaRegistry) Schema(apiVersion int64) (Schema, error) {
+	for i := len(r.schemas) - 1; i >= 0; i-- {
+		if r.schemas[i].APIVersion <= apiVersion {
+			return r.schemas[i], nil
+		}
+	}
+	return Schema{}, fmt.Errorf("%w: %d", ErrUnsupportedAPIVersion, apiVersion)
+}
+
+// Decode decodes the YAML file at filename with d, into the value of the schema of its apiVersion, which it returns
+// with the version. The errors are the ones of YAMLDecoder.Decode.
+func (r *SchemaRegistry) Decode(d YAMLDecoder, filename string, data []byte) (interface{}, int64, error) {
+	header, err := ReadFileHeader(data)
+	if err != nil {
+		return nil, 0, NewYAMLFileError(d.Subsystem, filename, err)
+	}
+	schema, err := r.Schema(header.APIVersion)
+	if err != nil {
+		return nil, 0, NewYAMLFileError(d.Subsystem, filename, err)
+	}
+
+	d.Deprecated = schema.Deprecated
+	out := schema.New()
+	if err := d.Decode(filename, data, out); err != nil {
+		return nil, 0, err
+	}
+	return out, header.APIVersion, nil
+}
+
+// DecodedDocument is a YAML document of a provisioning file, decoded into the value of the schema of its apiVersion.
+type DecodedDocument struct {
+	Document
+	Value      interface{}
+	APIVersion int64
+}
+
+// DecodeDocuments decodes every YAML document of the file at filename like Decode, so the documents of a file can be
+// of different versions and have their own guards. The documents whose guards skip them are left out, and an
+// ErrFileSkipped error is only returned when every document is skipped. The errors of a file of several documents
+// have the document.
+func (r *SchemaRegistry) DecodeDocuments(d YAMLDecoder, filename string, data []byte) ([]DecodedDocument, error) {
+	docs, err := SplitDocuments(data)
+	if err != nil {
+		return nil, NewYAMLFileError(d.Subsystem, filename, err)
+	}
+
+	var decoded []DecodedDocument
+	var skipErr error
+	for _, doc := range docs {
+		value, apiVersion, err := r.Decode(d, filename, doc.Data)
+		if errors.Is(err, ErrFileSkipped) {
+			skipErr = err
+			continue
+		}
+		if err != nil {
+			var fileErr *ProvisioningFileError
+			if len(docs) > 1 && errors.As(err, &fileErr) {
+				fileErr.Document = doc.Index
+			}
+			return nil, err
+		}
+		decoded = append(decoded, DecodedDocument{Document: doc, Value: value, APIVersion: apiVersion})
+	}
+	if len(decoded) == 0 && skipErr != nil {
+		return nil, skipErr
+	}
+	return decoded, nil
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type schemaTestConfigV0 []struct {
+	Name string `yaml:"name"`
+}
+
+type schemaTestConfigV1 struct {
+	APIVersion int64 `yaml:"apiVersion"`
+	Providers  []struct {
+		Name string `yaml:"name"`
+	} `yaml:"providers"`
+}
+
+func TestReadFileHeader(t *testing.T) {
+	header, err := ReadFileHeader([]byte("apiVersion: 2\nkind: Datasource\n"))
+	require.NoError(t, err)
+	assert.Equal(t, FileHeader{APIVersion: 2, Kind: "Datasource"}, header)
+
+	header, err = ReadFileHeader([]byte("- name: default\n"))
+	require.NoError(t, err)
+	assert.Equal(t, FileHeader{}, header, "Lists have no header")
+
+	_, err = ReadFileHeader([]byte("apiVersion: one\n"))
+	require.Error(t, err)
+}
+
+func TestSchemaRegistry(t *testing.T) {
+	registry := NewSchemaRegistry(
+		Schema{APIVersion: 1, New: func() interface{} { return &schemaTestConfigV1{} }},
+		Schema{APIVersion: 0, New: func() interface{} { return &schemaTestConfigV0{} }},
+	)
+	decoder := YAMLDecoder{Subsystem: "dashboards", Kind: "Dashboard", Strict: true}
+
+	t.Run("Files without a header are of version 0", func(t *testing.T) {
+		cfg, apiVersion, err := registry.Decode(decoder, "/etc/dashboards.yaml", []byte("- name: default\n"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), apiVersion)
+		require.IsType(t, &schemaTestConfigV0{}, cfg)
+		assert.Equal(t, "default", (*cfg.(*schemaTestConfigV0))[0].Name)
+	})
+
+	t.Run("Files are decoded with the schema of their version", func(t *testing.T) {
+		cfg, apiVersion, err := registry.Decode(decoder, "/etc/dashboards.yaml",
+			[]byte("apiVersion: 1\nkind: Dashboard\nproviders:\n  - name: default\n"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), apiVersion)
+		require.IsType(t, &schemaTestConfigV1{}, cfg)
+		assert.Equal(t, "default", cfg.(*schemaTestConfigV1).Providers[0].Name)
+	})
+
+	t.Run("Files of a newer version are decoded with the latest schema", func(t *testing.T) {
+		cfg, apiVersion, err := registry.Decode(decoder, "/etc/dashboards.yaml", []byte("apiVersion: 3\nproviders: []\n"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), apiVersion)
+		assert.IsType(t, &schemaTestConfigV1{}, cfg)
+	})
+
+	t.Run("Files of a version older than every schema are rejected", func(t *testing.T) {
+		_, _, err := registry.Decode(decoder, "/etc/dashboards.yaml", []byte("apiVersion: -1\n"))
+		require.True(t, errors.Is(err, ErrUnsupportedAPIVersion))
+		var fileErr *ProvisioningFileError
+		assert.True(t, errors.As(err, &fileErr))
+	})
+
+	t.Run("Registering a version replaces its schema", func(t *testing.T) {
+		registry := NewSchemaRegistry(Schema{APIVersion: 1, New: func() interface{} { return &schemaTestConfigV0{} }})
+		registry.Register(Schema{APIVersion: 1, New: func() interface{} { return &schemaTestConfigV1{} }})
+		schema, err := registry.Schema(1)
+		require.NoError(t, err)
+		assert.IsType(t, &schemaTestConfigV1{}, schema.New())
+	})
+}
+
+func TestYAMLDecoderKind(t *testing.T) {
+	decoder := YAMLDecoder{Subsystem: "datasources", Kind: "Datasource", Strict: true}
+
+	for _, kind := range []string{"Datasource", "datasources"} {
+		var cfg decodeTestConfig
+		err := decoder.Decode("/etc/ds.yaml", []byte("apiVersion: 1\nkind: "+kind+"\ndatasources: []\n"), &cfg)
+		require.NoError(t, err, "The kind header is a known field of %s files", kind)
+	}
+
+	var cfg decodeTestConfig
+	err := decoder.Decode("/etc/ds.yaml", []byte("apiVersion: 1\nkind: Dashboard\ndatasources: []\n"), &cfg)
+	require.True(t, errors.Is(err, ErrKindMismatch))
+	assert.EqualError(t, err, `file /etc/ds.yaml: provisioning file kind doesn't match its directory: kind "Dashboard" can't be provisioned from the datasources directory, which has Datasource files`)
+
+	require.NoError(t, YAMLDecoder{Subsystem: "datasources"}.Decode("/etc/ds.yaml",
+		[]byte("apiVersion: 1\nkind: Dashboard\ndatasources: []\n"), &cfg), "Decoders without a kind don't check it")
+}
@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrSecretUnresolved is returned, wrapped with the key and the reference, when a secret reference in a provisioning
+// file can't be resolved.
+var ErrSecretUnresolved = errors.New("secret reference can't be resolved")
+
+// SecretRef is a reference to a secret kept outside of the provisioning files, written as <scheme>://<path>#<field>
+// with an optional field, like vault://secret/data/grafana#password, env://PROMETHEUS_PASSWORD or
+// file:///run/secrets/prometheus-password.
+type SecretRef struct {
+	Scheme string
+	Path   string
+	Field  string
+}
+
+func (r SecretRef) String() string {
+	if r.Field == "" {
+		return r.Scheme + "://" + r.Path
+	}
+	return r.Scheme + "://" + r.Path + "#" + r.Field
+}
+
+// SecretResolver returns the secrets that references point at. The errors of a resolver must not contain the secret.
+type SecretResolver interface {
+	ResolveSecret(ctx context.Context, ref SecretRef) (string, error)
+}
+
+// SecretResolverFunc is a function that implements SecretResolver.
+type SecretResolverFunc func(ctx context.Context, ref SecretRef) (string, error)
+
+func (f SecretResolverFunc) ResolveSecret(ctx context.Context, ref SecretRef) (string, error) {
+	return f(ctx, ref)
+}
+
+// reservedSecretSchemes are the schemes of secret managers that have no built-in resolver. Values using them are
+// references even when no resolver is registered, so they fail instead of being stored as the secret.
+var reservedSecretSchemes = map[string]bool{"vault": true}
+
+var (
+	secretResolversMutex sync.Mutex
+	secretResolvers      = map[string]SecretResolver{
+		"env":  SecretResolverFunc(resolveEnvSecret),
+		"file": SecretResolverFunc(resolveFileSecret),
+	}
+)
+
+// RegisterSecretResolver registers the resolver of the references with scheme, replacing the one registered before.
+// It's meant to be called from an init function.
+func RegisterSecretResolver(scheme string, resolver SecretResolver) {
+	secretResolversMutex.Lock()
+	defer secretResolversMutex.Unlock()
+
+	secretResolvers[scheme] = resolver
+}
+
+func registeredSecretResolver(scheme string) (SecretResolver, bool) {
+	secretResolversMutex.Lock()
+	defer secretResolversMutex.Unlock()
+
+	resolver, ok := secretResolvers[scheme]
+	return resolver, ok
+}
+
+var secretRefPattern = regexp.MustCompile(`^([a-z][a-z0-9+.-]*)://([^#]+)(?:#(.+))?$`)
+
+// ParseSecretRef returns the secret reference of value, and false when value isn't one. A value is a reference when
+// its scheme has a registered resolver or is the scheme of a known secret manager, so other URLs are left alone.
+func ParseSecretRef(value string) (SecretRef, bool) {
+	match := secretRefPattern.FindStringSubmatch(value)
+	if match == nil {
+		return SecretRef{}, false
+	}
+	if _, registered := registeredSecretResolver(match[1]); !registered && !reservedSecretSchemes[match[1]] {
+		return SecretRef{}, false
+	}
+	return SecretRef{Scheme: match[1], Path: match[2], Field: match[3]}, true
+}
+
+// RegisteredSecretResolver resolves references with the resolver registered for their scheme.
+func RegisteredSecretResolver() SecretResolver {
+	return SecretResolverFunc(func(ctx context.Context, ref SecretRef) (string, error) {
+		resolver, ok := registeredSecretResolver(ref.Scheme)
+		if !ok {
+			return "", fmt.Errorf("no secret resolver is registered for %s references", ref.Scheme)
+		}
+		return resolver.ResolveSecret(ctx, ref)
+	})
+}
+
+// ResolveSecrets returns a copy of values with the secret references resolved by resolver. The errors name the key and
+// the reference, never a secret.
+func ResolveSecrets(ctx context.Context, resolver SecretResolver, values map[string]string) (map[string]string, error) {
+	if values == nil {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	resolved := make(map[string]string, len(values))
+	for _, key := range keys {
+		ref, ok := ParseSecretRef(values[key])
+		if !ok {
+			resolved[key] = values[key]
+			continue
+		}
+		secret, err := resolver.ResolveSecret(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s (%s): %v", ErrSecretUnresolved, key, ref, err)
+		}
+		resolved[key] = secret
+	}
+	return resolved, nil
+}
+
+type secretResolverKey struct{}
+
+// WithSecretResolver returns a copy of ctx that carries resolver to the provisioners.
+func WithSecretResolver(ctx context.Context, resolver SecretResolver) context.Context {
+	return context.WithValue(ctx, secretResolverKey{}, resolver)
+}
+
+// SecretResolverFromContext returns the secret resolver carried by ctx, or the registered resolvers without one.
+func SecretResolverFromContext(ctx context.Context) SecretResolver {
+	if resolver, ok := ctx.Value(secretResolverKey{}).(SecretResolver); ok {
+		return resolver
+	}
+	return RegisteredSecretResolver()
+}
+
+// resolveEnvSecret resolves env://<variable> to the value of the environment variable.
+func resolveEnvSecret(_ context.Context, ref SecretRef) (string, error) {
+	if ref.Field != "" {
+		return "", errors.New("env references don't have fields")
+	}
+	value, ok := os.LookupEnv(ref.Path)
+	if !ok {
+		return "", fmt.Errorf("environment variable %q is not set", ref.Path)
+	}
+	return value, nil
+}
+
+// resolveFileSecret resolves file://<path> to the content of the file, without the trailing line break, which is
+// how secrets mounted by Docker and Kubernetes are read.
+func resolveFileSecret(_ context.Context, ref SecretRef) (string, error) {
+	if ref.Field != "" {
+		return "", errors.New("file references don't have fields")
+	}
+	// nolint:gosec
+	// We can ignore the gosec G304 warning on this one because the path comes from the provisioning files
+	content, err := ioutil.ReadFile(ref.Path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	tlog "github.com/opentracing/opentracing-go/log"
+)
+
+// StartChildSpan starts a span as a child of the span in ctx. Without a span in ctx a no-op span is returned, so
+// work that runs outside of a traced provisioning run, like dashboard polling, doesn't start traces of its own.
+func StartChildSpan(ctx context.Context, operationName string) (opentracing.Span, context.Context) {
+	if opentracing.SpanFromContext(ctx) == nil {
+		return opentracing.NoopTracer{}.StartSpan(operationName), ctx
+	}
+	return opentracing.StartSpanFromContext(ctx, operationName)
+}
+
+// FinishSpan marks span as failed when err is set and finishes it.
+func FinishSpan(span opentracing.Span, err error) {
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(tlog.Error(err))
+	}
+	span.Finish()
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -8,9 +9,16 @@ import (
 	"github.com/grafana/grafana/pkg/models"
 )
 
-func CheckOrgExists(orgID int64) error {
+// ErrOrgNameConflict is returned for objects whose org name is another org than their org ID.
+var ErrOrgNameConflict = InvalidConfig(errors.New("org name conflicts with the org ID"))
+
+// ReaderFilename stands in for the file name of config documents provisioned from memory instead of a provisioning
+// directory, in errors and in the inventory.
+const ReaderFilename = "<reader>"
+
+func CheckOrgExists(ctx context.Context, orgID int64) error {
 	query := models.GetOrgByIdQuery{Id: orgID}
-	if err := bus.Dispatch(&query); err != nil {
+	if err := bus.DispatchCtx(ctx, &query); err != nil {
 		if errors.Is(err, models.ErrOrgNotFound) {
 			return err
 		}
@@ -18,3 +26,44 @@ func CheckOrgExists(orgID int64) error {
 	}
 	return nil
 }
+
+type defaultOrgKey struct{}
+
+// WithDefaultOrg returns a copy of ctx that carries the name of the org that objects without an org are
+// provisioned in.
+func WithDefaultOrg(ctx context.Context, orgName string) context.Context {
+	return context.WithValue(ctx, defaultOrgKey{}, orgName)
+}
+
+// DefaultOrgFromContext returns the name of the default org carried by ctx, or an empty name for the org with ID 1.
+func DefaultOrgFromContext(ctx context.Context) string {
+	orgName, _ := ctx.Value(defaultOrgKey{}).(string)
+	return orgName
+}
+
+// ResolveOrg sets the org ID of an object from its org name. An object with both must name the org of its ID, and
+// one with neither gets the default org of ctx. A name that isn't an org is an error wrapping models.ErrOrgNotFound.
+func ResolveOrg(ctx context.Context, orgID *int64, orgName string) error {
+	if orgName == "" {
+		if *orgID != 0 {
+			return nil
+		}
+		if orgName = DefaultOrgFromContext(ctx); orgName == "" {
+			*orgID = 1
+			return nil
+		}
+	}
+
+	query := models.GetOrgByNameQuery{Name: orgName}
+	if err := bus.DispatchCtx(ctx, &query); err != nil {
+		if errors.Is(err, models.ErrOrgNotFound) {
+			return fmt.Errorf("%w: %q", models.ErrOrgNotFound, orgName)
+		}
+		return fmt.Errorf("failed to look up org %q: %w", orgName, err)
+	}
+	if *orgID != 0 && *orgID != query.Result.Id {
+		return fmt.Errorf("%w: %q is org %d, not %d", ErrOrgNameConflict, orgName, query.Result.Id, *orgID)
+	}
+	*orgID = query.Result.Id
+	return nil
+}
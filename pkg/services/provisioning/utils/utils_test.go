@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/grafana/grafana/pkg/models"
@@ -17,13 +19,60 @@ func TestCheckOrgExists(t *testing.T) {
 		So(err, ShouldBeNil)
 
 		Convey("default org exists", func() {
-			err := CheckOrgExists(defaultOrg.Result.Id)
+			err := CheckOrgExists(context.Background(), defaultOrg.Result.Id)
 			So(err, ShouldBeNil)
 		})
 
 		Convey("other org doesn't exist", func() {
-			err := CheckOrgExists(defaultOrg.Result.Id + 1)
+			err := CheckOrgExists(context.Background(), defaultOrg.Result.Id+1)
 			So(err, ShouldEqual, models.ErrOrgNotFound)
 		})
 	})
 }
+
+func TestResolveOrg(t *testing.T) {
+	Convey("with two orgs in database", t, func() {
+		sqlstore.InitTestDB(t)
+
+		mainOrg := models.CreateOrgCommand{Name: "Main Org."}
+		So(sqlstore.CreateOrg(&mainOrg), ShouldBeNil)
+		staging := models.CreateOrgCommand{Name: "Staging"}
+		So(sqlstore.CreateOrg(&staging), ShouldBeNil)
+
+		Convey("the org name is resolved to its ID", func() {
+			var orgID int64
+			So(ResolveOrg(context.Background(), &orgID, "Staging"), ShouldBeNil)
+			So(orgID, ShouldEqual, staging.Result.Id)
+		})
+
+		Convey("an org name that isn't an org fails", func() {
+			var orgID int64
+			err := ResolveOrg(context.Background(), &orgID, "Production")
+			So(errors.Is(err, models.ErrOrgNotFound), ShouldBeTrue)
+			So(err.Error(), ShouldEqual, `organization not found: "Production"`)
+		})
+
+		Convey("an org name of another org than the ID fails", func() {
+			orgID := mainOrg.Result.Id
+			err := ResolveOrg(context.Background(), &orgID, "Staging")
+			So(errors.Is(err, ErrOrgNameConflict), ShouldBeTrue)
+		})
+
+		Convey("an org name of the org of the ID is fine", func() {
+			orgID := staging.Result.Id
+			So(ResolveOrg(context.Background(), &orgID, "Staging"), ShouldBeNil)
+		})
+
+		Convey("objects without an org are provisioned in the default org", func() {
+			var orgID int64
+			So(ResolveOrg(WithDefaultOrg(context.Background(), "Staging"), &orgID, ""), ShouldBeNil)
+			So(orgID, ShouldEqual, staging.Result.Id)
+		})
+
+		Convey("objects without an org are provisioned in org 1 without a default org", func() {
+			var orgID int64
+			So(ResolveOrg(context.Background(), &orgID, ""), ShouldBeNil)
+			So(orgID, ShouldEqual, 1)
+		})
+	})
+}
@@ -3,6 +3,7 @@ package sqlstore
 import (
 	"context"
 	"errors"
+	"strings"
 
 	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/models"
@@ -11,6 +12,7 @@ import (
 func init() {
 	bus.AddHandler("sql", UnprovisionDashboard)
 	bus.AddHandler("sql", DeleteOrphanedProvisionedDashboards)
+	bus.AddHandler("sql", CountOrphanedProvisionedDashboards)
 }
 
 type DashboardExtras struct {
@@ -42,6 +44,11 @@ func (ss *SQLStore) SaveProvisionedDashboard(cmd models.SaveDashboardCommand,
 		if provisioning.Updated == 0 {
 			provisioning.Updated = cmd.Result.Updated.Unix()
 		}
+		checkSum, err := models.DashboardCheckSum(cmd.Result.Data)
+		if err != nil {
+			return err
+		}
+		provisioning.DashboardCheckSum = checkSum
 
 		return saveProvisionedData(sess, provisioning, cmd.Result)
 	})
@@ -87,6 +94,26 @@ func UnprovisionDashboard(cmd *models.UnprovisionDashboardCommand) error {
 	return nil
 }
 
+func CountOrphanedProvisionedDashboards(query *models.CountOrphanedProvisionedDashboardsQuery) error {
+	convertedReaderNames := make([]interface{}, len(query.ReaderNames))
+	for index, readerName := range query.ReaderNames {
+		convertedReaderNames[index] = readerName
+	}
+
+	orphaned := "1"
+	if len(convertedReaderNames) > 0 {
+		orphaned = "CASE WHEN dashboard_provisioning.name IN (?" + strings.Repeat(",?", len(convertedReaderNames)-1) +
+			") THEN 0 ELSE 1 END"
+	}
+	rawSQL := `SELECT dashboard.org_id AS org_id, COUNT(*) AS provisioned, SUM(` + orphaned + `) AS orphaned
+		FROM dashboard_provisioning
+		INNER JOIN dashboard ON dashboard.id = dashboard_provisioning.dashboard_id
+		GROUP BY dashboard.org_id`
+
+	query.Result = make([]*models.OrphanedProvisionedDashboardsCount, 0)
+	return x.SQL(rawSQL, convertedReaderNames...).Find(&query.Result)
+}
+
 func DeleteOrphanedProvisionedDashboards(cmd *models.DeleteOrphanedProvisionedDashboardsCommand) error {
 	var result []*models.DashboardProvisioning
 
@@ -95,13 +122,16 @@ func DeleteOrphanedProvisionedDashboards(cmd *models.DeleteOrphanedProvisionedDa
 		convertedReaderNames[index] = readerName
 	}
 
-	err := x.NotIn("name", convertedReaderNames...).Find(&result)
-	if err != nil {
+	sess := x.NotIn("name", convertedReaderNames...)
+	if cmd.OrgId != 0 {
+		sess = sess.Where("dashboard_id IN (SELECT id FROM dashboard WHERE org_id = ?)", cmd.OrgId)
+	}
+	if err := sess.Find(&result); err != nil {
 		return err
 	}
 
 	for _, deleteDashCommand := range result {
-		err := DeleteDashboard(&models.DeleteDashboardCommand{Id: deleteDashCommand.DashboardId})
+		err := DeleteDashboard(&models.DeleteDashboardCommand{Id: deleteDashCommand.DashboardId, OrgId: cmd.OrgId})
 		if err != nil && !errors.Is(err, models.ErrDashboardNotFound) {
 			return err
 		}
@@ -77,6 +77,24 @@ func TestDashboardProvisioningTest(t *testing.T) {
 				So(err, ShouldBeNil)
 				So(query.Result, ShouldNotBeNil)
 
+				countQuery := &models.CountOrphanedProvisionedDashboardsQuery{ReaderNames: []string{"default"}}
+				So(CountOrphanedProvisionedDashboards(countQuery), ShouldBeNil)
+				So(countQuery.Result, ShouldHaveLength, 1)
+				So(countQuery.Result[0].OrgId, ShouldEqual, 1)
+				So(countQuery.Result[0].Orphaned, ShouldEqual, 1)
+				So(countQuery.Result[0].Provisioned, ShouldEqual, 2)
+
+				countQuery = &models.CountOrphanedProvisionedDashboardsQuery{}
+				So(CountOrphanedProvisionedDashboards(countQuery), ShouldBeNil)
+				So(countQuery.Result, ShouldHaveLength, 1)
+				So(countQuery.Result[0].Orphaned, ShouldEqual, 2)
+
+				So(DeleteOrphanedProvisionedDashboards(&models.DeleteOrphanedProvisionedDashboardsCommand{
+					ReaderNames: []string{"default"}, OrgId: 2}), ShouldBeNil)
+				query = &models.GetDashboardsQuery{DashboardIds: []int64{anotherDash.Id}}
+				So(GetDashboards(query), ShouldBeNil)
+				So(query.Result, ShouldHaveLength, 1)
+
 				deleteCmd := &models.DeleteOrphanedProvisionedDashboardsCommand{ReaderNames: []string{"default"}}
 				So(DeleteOrphanedProvisionedDashboards(deleteCmd), ShouldBeNil)
 
@@ -95,6 +113,10 @@ func TestDashboardProvisioningTest(t *testing.T) {
 				So(len(rslt), ShouldEqual, 1)
 				So(rslt[0].DashboardId, ShouldEqual, dashId)
 				So(rslt[0].Updated, ShouldEqual, now.Unix())
+
+				saved, err := models.DashboardCheckSum(dash.Data)
+				So(err, ShouldBeNil)
+				So(rslt[0].DashboardCheckSum, ShouldEqual, saved)
 			})
 
 			Convey("Can query for one provisioned dashboard", func() {
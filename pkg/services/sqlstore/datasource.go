@@ -144,7 +144,19 @@ func DeleteDataSource(cmd *models.DeleteDataSourceCommand) error {
 
 	return inTransaction(func(sess *DBSession) error {
 		result, err := sess.Exec(params...)
+		if err != nil {
+			return err
+		}
 		cmd.DeletedDatasourcesCount, _ = result.RowsAffected()
+
+		if cmd.Provisioned {
+			_, err = sess.Exec("DELETE FROM datasource_provisioning WHERE org_id = ? AND datasource_id NOT IN (SELECT id FROM data_source WHERE org_id = ?)",
+				cmd.OrgID, cmd.OrgID)
+			return err
+		}
+		// The marks of the deleted datasources are kept, so provisioning can tell they were deleted outside of it.
+		_, err = sess.Exec("UPDATE datasource_provisioning SET deleted = ? WHERE org_id = ? AND datasource_id NOT IN (SELECT id FROM data_source WHERE org_id = ?)",
+			true, cmd.OrgID, cmd.OrgID)
 		return err
 	})
 }
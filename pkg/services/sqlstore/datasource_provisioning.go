@@ -0,0 +1,63 @@
+package sqlstore
+
+import (
+	"time"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+func init() {
+	bus.AddHandler("sql", SaveProvisionedDatasource)
+	bus.AddHandler("sql", GetProvisionedDatasources)
+	bus.AddHandler("sql", GetDeletedProvisionedDatasource)
+}
+
+// SaveProvisionedDatasource marks a datasource as managed by provisioning, replacing the mark of a datasource with
+// the same name that was deleted.
+func SaveProvisionedDatasource(cmd *models.SaveProvisionedDatasourceCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		if _, err := sess.Exec("DELETE FROM datasource_provisioning WHERE datasource_id = ?", cmd.DatasourceId); err != nil {
+			return err
+		}
+		if cmd.Name != "" {
+			if _, err := sess.Exec("DELETE FROM datasource_provisioning WHERE org_id = ? AND name = ? AND deleted = ?",
+				cmd.OrgId, cmd.Name, true); err != nil {
+				return err
+			}
+		}
+
+		_, err := sess.Insert(&models.DatasourceProvisioning{
+			DatasourceId: cmd.DatasourceId,
+			OrgId:        cmd.OrgId,
+			Updated:      time.Now().Unix(),
+			Name:         cmd.Name,
+			CheckSum:     cmd.CheckSum,
+		})
+		return err
+	})
+}
+
+func GetProvisionedDatasources(query *models.GetProvisionedDatasourcesQuery) error {
+	query.Result = make([]*models.DataSource, 0)
+	return x.Table("data_source").
+		Join("INNER", "datasource_provisioning", "datasource_provisioning.datasource_id = data_source.id").
+		Where("datasource_provisioning.deleted = ?", false).
+		Cols("data_source.*").
+		Asc("data_source.id").
+		Find(&query.Result)
+}
+
+// GetDeletedProvisionedDatasource returns the latest mark of a provisioned datasource of the name that was deleted.
+func GetDeletedProvisionedDatasource(query *models.GetDeletedProvisionedDatasourceQuery) error {
+	var marks []*models.DatasourceProvisioning
+	if err := x.Where("org_id = ? AND name = ? AND deleted = ?", query.OrgId, query.Name, true).
+		Desc("updated").Limit(1).Find(&marks); err != nil {
+		return err
+	}
+	query.Result = nil
+	if len(marks) > 0 {
+		query.Result = marks[0]
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// +build integration
+
+package sqlstore
+
+import (
+	"testing"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatasourceProvisioning(t *testing.T) {
+	InitTestDB(t)
+
+	addDatasource := func(name string) int64 {
+		cmd := models.AddDataSourceCommand{OrgId: 10, Name: name, Type: models.DS_GRAPHITE, Access: models.DS_ACCESS_PROXY}
+		require.NoError(t, AddDataSource(&cmd))
+		return cmd.Result.Id
+	}
+
+	getProvisioned := func() []string {
+		query := models.GetProvisionedDatasourcesQuery{}
+		require.NoError(t, GetProvisionedDatasources(&query))
+
+		var names []string
+		for _, ds := range query.Result {
+			names = append(names, ds.Name)
+		}
+		return names
+	}
+
+	provisioned := addDatasource("provisioned")
+	addDatasource("created in the UI")
+
+	t.Run("Only marked datasources are returned", func(t *testing.T) {
+		require.NoError(t, SaveProvisionedDatasource(&models.SaveProvisionedDatasourceCommand{DatasourceId: provisioned, OrgId: 10}))
+		require.NoError(t, SaveProvisionedDatasource(&models.SaveProvisionedDatasourceCommand{DatasourceId: provisioned, OrgId: 10}))
+
+		require.Equal(t, []string{"provisioned"}, getProvisioned())
+	})
+
+	t.Run("Deleting a datasource from the UI marks it as deleted", func(t *testing.T) {
+		uiDeleted := addDatasource("deleted in the UI")
+		require.NoError(t, SaveProvisionedDatasource(&models.SaveProvisionedDatasourceCommand{DatasourceId: uiDeleted, OrgId: 10,
+			Name: "deleted in the UI", CheckSum: "abc"}))
+		require.NoError(t, DeleteDataSource(&models.DeleteDataSourceCommand{ID: uiDeleted, OrgID: 10}))
+
+		require.Equal(t, []string{"provisioned"}, getProvisioned())
+		query := models.GetDeletedProvisionedDatasourceQuery{OrgId: 10, Name: "deleted in the UI"}
+		require.NoError(t, GetDeletedProvisionedDatasource(&query))
+		require.NotNil(t, query.Result)
+		require.Equal(t, "abc", query.Result.CheckSum)
+
+		recreated := addDatasource("deleted in the UI")
+		require.NoError(t, SaveProvisionedDatasource(&models.SaveProvisionedDatasourceCommand{DatasourceId: recreated, OrgId: 10,
+			Name: "deleted in the UI", CheckSum: "abc"}))
+		require.NoError(t, GetDeletedProvisionedDatasource(&query))
+		require.Nil(t, query.Result, "Provisioning the datasource again replaces the mark")
+		require.NoError(t, DeleteDataSource(&models.DeleteDataSourceCommand{ID: recreated, OrgID: 10, Provisioned: true}))
+	})
+
+	t.Run("Deleting a datasource by provisioning removes its mark", func(t *testing.T) {
+		require.NoError(t, DeleteDataSource(&models.DeleteDataSourceCommand{ID: provisioned, OrgID: 10, Provisioned: true}))
+
+		require.Empty(t, getProvisioned())
+
+		var marks []*models.DatasourceProvisioning
+		require.NoError(t, x.Find(&marks))
+		require.Empty(t, marks)
+	})
+}
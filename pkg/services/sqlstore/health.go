@@ -1,8 +1,16 @@
 package sqlstore
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
 	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 func init() {
@@ -13,3 +21,34 @@ func GetDBHealthQuery(query *models.GetDBHealthQuery) error {
 	_, err := x.Exec("SELECT 1")
 	return err
 }
+
+// Ping connects to the database of cfg and runs the query of GetDBHealthQuery, for the commands that only check
+// that the database answers. Unlike Init, it doesn't run the migrations or create the main org, and a SQLite
+// database file that doesn't exist isn't created but fails the ping, so nothing is written.
+func Ping(ctx context.Context, cfg *setting.Cfg) error {
+	ss := &SQLStore{Cfg: cfg, log: log.New("sqlstore")}
+	ss.readConfig()
+
+	if ss.dbCfg.Type == migrator.SQLite && ss.dbCfg.ConnectionString == "" {
+		if !filepath.IsAbs(ss.dbCfg.Path) {
+			ss.dbCfg.Path = filepath.Join(cfg.DataPath, ss.dbCfg.Path)
+		}
+		if _, err := os.Stat(ss.dbCfg.Path); err != nil {
+			return fmt.Errorf("can't open SQLite database file: %w", err)
+		}
+		ss.dbCfg.ConnectionString = fmt.Sprintf("file:%s?cache=%s&mode=ro", ss.dbCfg.Path, ss.dbCfg.CacheMode) +
+			ss.buildExtraConnectionString('&')
+	}
+
+	if err := ss.initEngine(); err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() {
+		if err := ss.engine.Close(); err != nil {
+			ss.log.Warn("Failed to close database connection", "error", err)
+		}
+	}()
+
+	_, err := ss.engine.DB().ExecContext(ctx, "SELECT 1")
+	return err
+}
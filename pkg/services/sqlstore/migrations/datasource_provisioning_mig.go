@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func addDatasourceProvisioningMigrations(mg *Migrator) {
+	datasourceProvisioningV1 := Table{
+		Name: "datasource_provisioning",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "datasource_id", Type: DB_BigInt, Nullable: false},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "updated", Type: DB_Int, Default: "0", Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"datasource_id"}, Type: UniqueIndex},
+			{Cols: []string{"org_id"}},
+		},
+	}
+
+	mg.AddMigration("create datasource_provisioning table v1", NewAddTableMigration(datasourceProvisioningV1))
+
+	mg.AddMigration("add unique index datasource_provisioning.datasource_id", NewAddIndexMigration(datasourceProvisioningV1, datasourceProvisioningV1.Indices[0]))
+	mg.AddMigration("add index datasource_provisioning.org_id", NewAddIndexMigration(datasourceProvisioningV1, datasourceProvisioningV1.Indices[1]))
+
+	mg.AddMigration("add column name to datasource_provisioning", NewAddColumnMigration(datasourceProvisioningV1, &Column{
+		Name: "name", Type: DB_NVarchar, Length: 190, Nullable: true,
+	}))
+	mg.AddMigration("add column check_sum to datasource_provisioning", NewAddColumnMigration(datasourceProvisioningV1, &Column{
+		Name: "check_sum", Type: DB_NVarchar, Length: 100, Nullable: true,
+	}))
+	mg.AddMigration("add column deleted to datasource_provisioning", NewAddColumnMigration(datasourceProvisioningV1, &Column{
+		Name: "deleted", Type: DB_Bool, Nullable: false, Default: "0",
+	}))
+}
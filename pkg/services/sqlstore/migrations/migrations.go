@@ -35,6 +35,9 @@ func AddMigrations(mg *Migrator) {
 	addUserAuthTokenMigrations(mg)
 	addCacheMigration(mg)
 	addShortURLMigrations(mg)
+	addOrgProvisioningMigrations(mg)
+	addDatasourceProvisioningMigrations(mg)
+	addPluginSettingProvisioningMigrations(mg)
 }
 
 func addMigrationLogMigrations(mg *Migrator) {
@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func addOrgProvisioningMigrations(mg *Migrator) {
+	orgProvisioningV1 := Table{
+		Name: "org_provisioning",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "external_id", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "updated", Type: DB_Int, Default: "0", Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"external_id"}, Type: UniqueIndex},
+			{Cols: []string{"org_id"}},
+		},
+	}
+
+	mg.AddMigration("create org_provisioning table v1", NewAddTableMigration(orgProvisioningV1))
+
+	mg.AddMigration("add unique index org_provisioning.external_id", NewAddIndexMigration(orgProvisioningV1, orgProvisioningV1.Indices[0]))
+	mg.AddMigration("add index org_provisioning.org_id", NewAddIndexMigration(orgProvisioningV1, orgProvisioningV1.Indices[1]))
+}
@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func addPluginSettingProvisioningMigrations(mg *Migrator) {
+	pluginSettingProvisioningV1 := Table{
+		Name: "plugin_setting_provisioning",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "plugin_id", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "updated", Type: DB_Int, Default: "0", Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "plugin_id"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create plugin_setting_provisioning table v1", NewAddTableMigration(pluginSettingProvisioningV1))
+
+	mg.AddMigration("add unique index plugin_setting_provisioning.org_id_plugin_id", NewAddIndexMigration(pluginSettingProvisioningV1, pluginSettingProvisioningV1.Indices[0]))
+}
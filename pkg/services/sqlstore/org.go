@@ -241,6 +241,9 @@ func DeleteOrg(cmd *models.DeleteOrgCommand) error {
 			"DELETE FROM org_user WHERE org_id = ?",
 			"DELETE FROM org WHERE id = ?",
 			"DELETE FROM temp_user WHERE org_id = ?",
+			"DELETE FROM org_provisioning WHERE org_id = ?",
+			"DELETE FROM datasource_provisioning WHERE org_id = ?",
+			"DELETE FROM plugin_setting_provisioning WHERE org_id = ?",
 		}
 
 		for _, sql := range deletes {
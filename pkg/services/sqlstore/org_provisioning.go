@@ -0,0 +1,34 @@
+package sqlstore
+
+import (
+	"time"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+func init() {
+	bus.AddHandler("sql", SaveProvisionedOrg)
+	bus.AddHandler("sql", GetProvisionedOrgs)
+}
+
+// SaveProvisionedOrg links an org to an external ID, replacing any previous link of either of them.
+func SaveProvisionedOrg(cmd *models.SaveProvisionedOrgCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		if _, err := sess.Exec("DELETE FROM org_provisioning WHERE org_id = ? OR external_id = ?", cmd.OrgId, cmd.ExternalId); err != nil {
+			return err
+		}
+
+		_, err := sess.Insert(&models.OrgProvisioning{
+			OrgId:      cmd.OrgId,
+			ExternalId: cmd.ExternalId,
+			Updated:    time.Now().Unix(),
+		})
+		return err
+	})
+}
+
+func GetProvisionedOrgs(query *models.GetProvisionedOrgsQuery) error {
+	query.Result = make([]*models.OrgProvisioning, 0)
+	return x.Find(&query.Result)
+}
@@ -0,0 +1,60 @@
+// +build integration
+
+package sqlstore
+
+import (
+	"testing"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrgProvisioning(t *testing.T) {
+	InitTestDB(t)
+
+	createOrg := func(name string) int64 {
+		cmd := models.CreateOrgCommand{Name: name}
+		require.NoError(t, CreateOrg(&cmd))
+		return cmd.Result.Id
+	}
+
+	getProvisioned := func() []*models.OrgProvisioning {
+		query := models.GetProvisionedOrgsQuery{}
+		require.NoError(t, GetProvisionedOrgs(&query))
+		return query.Result
+	}
+
+	first := createOrg("first")
+	second := createOrg("second")
+
+	t.Run("Saving links an org to an external ID", func(t *testing.T) {
+		require.NoError(t, SaveProvisionedOrg(&models.SaveProvisionedOrgCommand{OrgId: first, ExternalId: "customer-1"}))
+
+		provisioned := getProvisioned()
+		require.Len(t, provisioned, 1)
+		require.Equal(t, first, provisioned[0].OrgId)
+		require.Equal(t, "customer-1", provisioned[0].ExternalId)
+		require.NotZero(t, provisioned[0].Updated)
+	})
+
+	t.Run("Saving an external ID again moves it to the new org", func(t *testing.T) {
+		require.NoError(t, SaveProvisionedOrg(&models.SaveProvisionedOrgCommand{OrgId: second, ExternalId: "customer-1"}))
+
+		provisioned := getProvisioned()
+		require.Len(t, provisioned, 1)
+		require.Equal(t, second, provisioned[0].OrgId)
+	})
+
+	t.Run("Saving a new external ID for an org replaces the old one", func(t *testing.T) {
+		require.NoError(t, SaveProvisionedOrg(&models.SaveProvisionedOrgCommand{OrgId: second, ExternalId: "customer-2"}))
+
+		provisioned := getProvisioned()
+		require.Len(t, provisioned, 1)
+		require.Equal(t, "customer-2", provisioned[0].ExternalId)
+	})
+
+	t.Run("Deleting an org removes its link", func(t *testing.T) {
+		require.NoError(t, DeleteOrg(&models.DeleteOrgCommand{Id: second}))
+		require.Empty(t, getProvisioned())
+	})
+}
@@ -0,0 +1,44 @@
+package sqlstore
+
+import (
+	"time"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+func init() {
+	bus.AddHandler("sql", SaveProvisionedPluginSetting)
+	bus.AddHandler("sql", DeleteProvisionedPluginSetting)
+	bus.AddHandler("sql", GetProvisionedPluginSettings)
+}
+
+// SaveProvisionedPluginSetting marks the settings of an app in an org as managed by provisioning.
+func SaveProvisionedPluginSetting(cmd *models.SaveProvisionedPluginSettingCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		if _, err := sess.Exec("DELETE FROM plugin_setting_provisioning WHERE org_id = ? AND plugin_id = ?", cmd.OrgId,
+			cmd.PluginId); err != nil {
+			return err
+		}
+
+		_, err := sess.Insert(&models.PluginSettingProvisioning{
+			OrgId:    cmd.OrgId,
+			PluginId: cmd.PluginId,
+			Updated:  time.Now().Unix(),
+		})
+		return err
+	})
+}
+
+func DeleteProvisionedPluginSetting(cmd *models.DeleteProvisionedPluginSettingCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		_, err := sess.Exec("DELETE FROM plugin_setting_provisioning WHERE org_id = ? AND plugin_id = ?", cmd.OrgId,
+			cmd.PluginId)
+		return err
+	})
+}
+
+func GetProvisionedPluginSettings(query *models.GetProvisionedPluginSettingsQuery) error {
+	query.Result = make([]*models.PluginSettingProvisioning, 0)
+	return x.Table("plugin_setting_provisioning").Asc("org_id", "plugin_id").Find(&query.Result)
+}
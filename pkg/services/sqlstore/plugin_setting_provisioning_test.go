@@ -0,0 +1,39 @@
+// +build integration
+
+package sqlstore
+
+import (
+	"testing"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPluginSettingProvisioning(t *testing.T) {
+	InitTestDB(t)
+
+	getProvisioned := func() []string {
+		query := models.GetProvisionedPluginSettingsQuery{}
+		require.NoError(t, GetProvisionedPluginSettings(&query))
+
+		var ids []string
+		for _, mark := range query.Result {
+			ids = append(ids, mark.PluginId)
+		}
+		return ids
+	}
+
+	t.Run("Marks are saved once per org and app", func(t *testing.T) {
+		require.NoError(t, SaveProvisionedPluginSetting(&models.SaveProvisionedPluginSettingCommand{OrgId: 10, PluginId: "app-a"}))
+		require.NoError(t, SaveProvisionedPluginSetting(&models.SaveProvisionedPluginSettingCommand{OrgId: 10, PluginId: "app-a"}))
+		require.NoError(t, SaveProvisionedPluginSetting(&models.SaveProvisionedPluginSettingCommand{OrgId: 10, PluginId: "app-b"}))
+
+		require.Equal(t, []string{"app-a", "app-b"}, getProvisioned())
+	})
+
+	t.Run("Deleting a mark keeps the others", func(t *testing.T) {
+		require.NoError(t, DeleteProvisionedPluginSetting(&models.DeleteProvisionedPluginSettingCommand{OrgId: 10, PluginId: "app-a"}))
+
+		require.Equal(t, []string{"app-b"}, getProvisioned())
+	})
+}
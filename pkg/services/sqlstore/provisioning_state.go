@@ -0,0 +1,83 @@
+package sqlstore
+
+import (
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+func init() {
+	bus.AddHandler("sql", GetProvisioningState)
+	bus.AddHandler("sql", RestoreProvisioningState)
+}
+
+func GetProvisioningState(query *models.GetProvisioningStateQuery) error {
+	state := &models.ProvisioningState{
+		Orgs:       make([]*models.OrgProvisioning, 0),
+		Dashboards: make([]*models.DashboardProvisioningState, 0),
+	}
+
+	if err := x.Asc("id").Find(&state.Orgs); err != nil {
+		return err
+	}
+
+	err := x.SQL(`SELECT dashboard.org_id, dashboard.uid AS dashboard_uid, dashboard_provisioning.name,
+		dashboard_provisioning.external_id, dashboard_provisioning.check_sum, dashboard_provisioning.updated
+		FROM dashboard_provisioning
+		INNER JOIN dashboard ON dashboard.id = dashboard_provisioning.dashboard_id
+		ORDER BY dashboard_provisioning.id`).Find(&state.Dashboards)
+	if err != nil {
+		return err
+	}
+
+	query.Result = state
+	return nil
+}
+
+// RestoreProvisioningState saves the provisioning records in the command, replacing the existing records of the
+// same orgs and dashboards. Every dashboard must already exist.
+func RestoreProvisioningState(cmd *models.RestoreProvisioningStateCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		for _, org := range cmd.State.Orgs {
+			if _, err := sess.Exec("DELETE FROM org_provisioning WHERE org_id = ? OR external_id = ?", org.OrgId, org.ExternalId); err != nil {
+				return err
+			}
+
+			if _, err := sess.Insert(&models.OrgProvisioning{
+				OrgId:      org.OrgId,
+				ExternalId: org.ExternalId,
+				Updated:    org.Updated,
+			}); err != nil {
+				return err
+			}
+		}
+
+		for _, dash := range cmd.State.Dashboards {
+			dashboard := models.Dashboard{OrgId: dash.OrgId, Uid: dash.DashboardUid}
+			exists, err := sess.Get(&dashboard)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return fmt.Errorf("dashboard with UID %q in org %d: %w", dash.DashboardUid, dash.OrgId, models.ErrDashboardNotFound)
+			}
+
+			if _, err := sess.Exec("DELETE FROM dashboard_provisioning WHERE dashboard_id = ?", dashboard.Id); err != nil {
+				return err
+			}
+
+			if _, err := sess.Insert(&models.DashboardProvisioning{
+				DashboardId: dashboard.Id,
+				Name:        dash.Name,
+				ExternalId:  dash.ExternalId,
+				CheckSum:    dash.CheckSum,
+				Updated:     dash.Updated,
+			}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,77 @@
+// +build integration
+
+package sqlstore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvisioningState(t *testing.T) {
+	sqlStore := InitTestDB(t)
+
+	orgCmd := models.CreateOrgCommand{Name: "customer"}
+	require.NoError(t, CreateOrg(&orgCmd))
+	orgID := orgCmd.Result.Id
+	require.NoError(t, SaveProvisionedOrg(&models.SaveProvisionedOrgCommand{OrgId: orgID, ExternalId: "customer-1"}))
+
+	dash, err := sqlStore.SaveProvisionedDashboard(models.SaveDashboardCommand{
+		OrgId: orgID,
+		Dashboard: simplejson.NewFromAny(map[string]interface{}{
+			"uid":   "home",
+			"title": "Home",
+		}),
+	}, &models.DashboardProvisioning{
+		Name:       "default",
+		ExternalId: "/var/lib/grafana/dashboards/home.json",
+		CheckSum:   "abc",
+		Updated:    1000,
+	})
+	require.NoError(t, err)
+
+	getState := func() *models.ProvisioningState {
+		query := models.GetProvisioningStateQuery{}
+		require.NoError(t, GetProvisioningState(&query))
+		return query.Result
+	}
+
+	state := getState()
+
+	t.Run("Getting the state references dashboards by UID", func(t *testing.T) {
+		require.Len(t, state.Orgs, 1)
+		require.Equal(t, "customer-1", state.Orgs[0].ExternalId)
+		require.Equal(t, []*models.DashboardProvisioningState{{
+			OrgId:        orgID,
+			DashboardUid: "home",
+			Name:         "default",
+			ExternalId:   "/var/lib/grafana/dashboards/home.json",
+			CheckSum:     "abc",
+			Updated:      1000,
+		}}, state.Dashboards)
+	})
+
+	t.Run("Restoring the state replaces the current records", func(t *testing.T) {
+		require.NoError(t, UnprovisionDashboard(&models.UnprovisionDashboardCommand{Id: dash.Id}))
+		require.NoError(t, SaveProvisionedOrg(&models.SaveProvisionedOrgCommand{OrgId: orgID, ExternalId: "renamed"}))
+
+		require.NoError(t, RestoreProvisioningState(&models.RestoreProvisioningStateCommand{State: state}))
+
+		restored := getState()
+		require.Len(t, restored.Orgs, 1)
+		require.Equal(t, orgID, restored.Orgs[0].OrgId)
+		require.Equal(t, "customer-1", restored.Orgs[0].ExternalId)
+		require.Equal(t, state.Orgs[0].Updated, restored.Orgs[0].Updated)
+		require.Equal(t, state.Dashboards, restored.Dashboards)
+	})
+
+	t.Run("Restoring the record of a missing dashboard fails", func(t *testing.T) {
+		err := RestoreProvisioningState(&models.RestoreProvisioningStateCommand{State: &models.ProvisioningState{
+			Dashboards: []*models.DashboardProvisioningState{{OrgId: orgID, DashboardUid: "missing", Name: "default"}},
+		}})
+		require.True(t, errors.Is(err, models.ErrDashboardNotFound))
+	})
+}
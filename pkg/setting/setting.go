@@ -188,6 +188,9 @@ type Cfg struct {
 	Raw    *ini.File
 	Logger log.Logger
 
+	// commandLineArgs are the arguments the settings were loaded with, used to read them again when reloading.
+	commandLineArgs *CommandLineArgs
+
 	// HTTP Server Settings
 	CertFile         string
 	KeyFile          string
@@ -217,7 +220,10 @@ type Cfg struct {
 	Packaging string
 
 	// Paths
-	ProvisioningPath   string
+	// ProvisioningPath is the first of the ProvisioningPaths.
+	ProvisioningPath string
+	// ProvisioningPaths are the provisioning directories, merged in order so later ones override earlier ones.
+	ProvisioningPaths  []string
 	DataPath           string
 	LogsPath           string
 	PluginsPath        string
@@ -269,6 +275,53 @@ type Cfg struct {
 	// Dashboards
 	DefaultHomeDashboardPath string
 
+	// Provisioning
+	ProvisioningLocale                   string
+	ProvisioningAPIHealthCheck           bool
+	ProvisioningDashboardsMaxConcurrency int
+	ProvisioningPollingWatchdogTimeout   time.Duration
+	// ProvisioningPollFailureThreshold is the number of dashboard polling cycles in a row that have to fail for
+	// provisioning to become unhealthy. Zero keeps it healthy.
+	ProvisioningPollFailureThreshold         int
+	ProvisioningDatasourcesCertCheckInterval time.Duration
+	ProvisioningFailureWebhookURL            string
+	ProvisioningDatasourcesPruneOrphans      string
+	ProvisioningDatasourcesHealthCheck       string
+	ProvisioningDatasourcesHealthTimeout     time.Duration
+	ProvisioningPluginsDisableRemovedApps    bool
+	ProvisioningPluginsFailFast              bool
+	ProvisioningFileFilters                  map[string]ProvisioningFileFilter
+	ProvisioningStrictFields                 map[string]bool
+	ProvisioningOrder                        []string
+	ProvisioningFailOnMissingDir             bool
+	ProvisioningDashboardsPoll               ProvisioningPollSettings
+	// ProvisioningDatasourcesDeletedPolicy is what provisioning does with the datasources it created that were deleted
+	// other than by provisioning: recreate, skip-until-file-changes or warn.
+	ProvisioningDatasourcesDeletedPolicy string
+	// ProvisioningDefaultOrgName is the name of the org that datasources, dashboard providers and notifiers without an
+	// org are provisioned in, empty for the org with ID 1.
+	ProvisioningDefaultOrgName string
+	// ProvisioningCleanupGuard limits how many provisioned dashboards and datasources the cleanups of orphans
+	// may delete in one run.
+	ProvisioningCleanupGuard ProvisioningCleanupGuard
+	// ProvisioningTimeouts limit how long each provisioning subsystem may run, by subsystem. Zero is no limit.
+	// ProvisioningTimeout is the limit of the subsystems it has no entry for, like registered provisioners.
+	ProvisioningTimeouts map[string]time.Duration
+	ProvisioningTimeout  time.Duration
+	// ProvisioningReportPath is the file the provisioning report is written to, empty for no report.
+	ProvisioningReportPath string
+	// ProvisioningDanglingReferences is what provisioning does with references to datasources that don't exist:
+	// off, warn or fail.
+	ProvisioningDanglingReferences string
+	// ProvisioningApplyOnce stops the server once the dashboards and alert rules were provisioned, instead of polling.
+	ProvisioningApplyOnce bool
+	// ProvisioningDatabaseReadyTimeout is how long provisioning waits for the database to answer before running its
+	// stages, zero to not wait. ProvisioningDatabaseReadyInterval is the first wait between pings.
+	ProvisioningDatabaseReadyTimeout  time.Duration
+	ProvisioningDatabaseReadyInterval time.Duration
+	// ProvisioningPostApplyHooks are called after every successful provisioning run of a subsystem.
+	ProvisioningPostApplyHooks ProvisioningPostApplyHooks
+
 	// Auth
 	LoginCookieName              string
 	LoginMaxInactiveLifetime     time.Duration
@@ -702,6 +755,36 @@ func (cfg *Cfg) loadConfiguration(args *CommandLineArgs) (*ini.File, error) {
 	return parsedFile, err
 }
 
+// reloadConfiguration parses the config files again and applies the environment and command line overrides,
+// like loadConfiguration but without exiting on errors or touching the logging and the logged config sources.
+func reloadConfiguration(args *CommandLineArgs) (*ini.File, error) {
+	prevConfigFiles, prevCommandLineProperties, prevEnvOverrides := configFiles, appliedCommandLineProperties, appliedEnvOverrides
+	defer func() {
+		configFiles, appliedCommandLineProperties, appliedEnvOverrides = prevConfigFiles, prevCommandLineProperties, prevEnvOverrides
+	}()
+
+	parsedFile, err := ini.Load(path.Join(HomePath, "conf/defaults.ini"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse defaults.ini: %w", err)
+	}
+	parsedFile.BlockMode = false
+
+	commandLineProps := getCommandLineProperties(args.Args)
+	applyCommandLineDefaultProperties(commandLineProps, parsedFile)
+	if err := loadSpecifiedConfigFile(args.Config, parsedFile); err != nil {
+		return nil, err
+	}
+	if err := applyEnvVariableOverrides(parsedFile); err != nil {
+		return nil, err
+	}
+	applyCommandLineProperties(commandLineProps, parsedFile)
+	if err := expandConfig(parsedFile); err != nil {
+		return nil, err
+	}
+
+	return parsedFile, nil
+}
+
 func pathExists(path string) bool {
 	_, err := os.Stat(path)
 	if err == nil {
@@ -773,6 +856,7 @@ func (cfg *Cfg) validateStaticRootPath() error {
 
 func (cfg *Cfg) Load(args *CommandLineArgs) error {
 	setHomePath(args)
+	cfg.commandLineArgs = args
 
 	// Fix for missing IANA db on Windows
 	_, zoneInfoSet := os.LookupEnv(zoneInfo)
@@ -810,7 +894,16 @@ func (cfg *Cfg) Load(args *CommandLineArgs) error {
 	cfg.PluginsPath = makeAbsolute(plugins, HomePath)
 	cfg.BundledPluginsPath = makeAbsolute("plugins-bundled", HomePath)
 	provisioning := valueAsString(iniFile.Section("paths"), "provisioning", "")
-	cfg.ProvisioningPath = makeAbsolute(provisioning, HomePath)
+	cfg.ProvisioningPaths = nil
+	for _, path := range strings.Split(provisioning, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			cfg.ProvisioningPaths = append(cfg.ProvisioningPaths, makeAbsolute(path, HomePath))
+		}
+	}
+	if len(cfg.ProvisioningPaths) == 0 {
+		cfg.ProvisioningPaths = []string{makeAbsolute(provisioning, HomePath)}
+	}
+	cfg.ProvisioningPath = cfg.ProvisioningPaths[0]
 
 	if err := cfg.readServerSettings(iniFile); err != nil {
 		return err
@@ -914,6 +1007,9 @@ func (cfg *Cfg) Load(args *CommandLineArgs) error {
 	}
 
 	cfg.readDataSourcesSettings()
+	if err := cfg.readProvisioningSettings(); err != nil {
+		return err
+	}
 
 	if VerifyEmailEnabled && !cfg.Smtp.Enabled {
 		log.Warnf("require_email_validation is enabled but smtp is disabled")
@@ -1037,7 +1133,7 @@ func (cfg *Cfg) LogConfigSources() {
 	cfg.Logger.Info("Path Data", "path", cfg.DataPath)
 	cfg.Logger.Info("Path Logs", "path", cfg.LogsPath)
 	cfg.Logger.Info("Path Plugins", "path", cfg.PluginsPath)
-	cfg.Logger.Info("Path Provisioning", "path", cfg.ProvisioningPath)
+	cfg.Logger.Info("Path Provisioning", "path", strings.Join(cfg.ProvisioningPaths, ","))
 	cfg.Logger.Info("App mode " + cfg.Env)
 }
 
@@ -0,0 +1,415 @@
+package setting
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/util"
+	"gopkg.in/ini.v1"
+)
+
+// ProvisioningFileFilterKinds are the provisioning subsystems whose files can be filtered with the
+// <kind>_include and <kind>_exclude settings.
+var ProvisioningFileFilterKinds = []string{"orgs", "datasources", "plugins", "notifiers", "library_panels", "dashboards",
+	"alert_rules", "alert_notifications"}
+
+// ProvisioningInitStages are the provisioning stages run at startup after the orgs, in their default order. The
+// order can be changed with the order setting.
+var ProvisioningInitStages = []string{"datasources", "plugins", "notifiers", "alert_notifications"}
+
+// ProvisioningFileFilter selects the files a provisioner reads with glob patterns, matched against the path of
+// the file relative to the directory it's read from.
+type ProvisioningFileFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// Includes returns true if relPath matches one of the include patterns, or there are none, and none of the
+// exclude patterns.
+func (f ProvisioningFileFilter) Includes(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	return (len(f.Include) == 0 || matchesAnyPattern(f.Include, relPath)) && !matchesAnyPattern(f.Exclude, relPath)
+}
+
+func isProvisioningFileFilterKind(kind string) bool {
+	for _, k := range ProvisioningFileFilterKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func isProvisioningInitStage(stage string) bool {
+	for _, s := range ProvisioningInitStages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAnyPattern(patterns []string, relPath string) bool {
+	for _, pattern := range patterns {
+		// The patterns are validated when reading the settings.
+		if matched, _ := path.Match(pattern, relPath); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ProvisioningCleanupGuard limits how many provisioned objects a cleanup of orphans may delete in one run, so a
+// provisioning directory that reads as empty, like a ConfigMap that failed to mount, doesn't delete everything. A
+// MaxPercent below 100 is what keeps a cleanup from deleting all of them.
+type ProvisioningCleanupGuard struct {
+	// MaxPercent is the percentage of the provisioned objects a cleanup may delete. 100 doesn't limit it.
+	MaxPercent int
+	// MaxCount is the number of objects a cleanup may delete. Zero doesn't limit it.
+	MaxCount int
+	// Force lets cleanups delete any number of objects.
+	Force bool
+}
+
+// Allows returns whether a cleanup may delete deleting of the provisioned objects.
+func (g ProvisioningCleanupGuard) Allows(deleting int, provisioned int) bool {
+	if g.Force || deleting == 0 {
+		return true
+	}
+	if g.MaxCount > 0 && deleting > g.MaxCount {
+		return false
+	}
+	return g.MaxPercent >= 100 || deleting*100 <= g.MaxPercent*provisioned
+}
+
+// ProvisioningPostApplyHooks are called with the result of every successful provisioning run of a subsystem, for
+// example to warm caches or notify a deploy pipeline.
+type ProvisioningPostApplyHooks struct {
+	// WebhookURL is sent the result as JSON, with WebhookMethod, POST or PUT, and WebhookHeaders.
+	WebhookURL     string
+	WebhookMethod  string
+	WebhookHeaders map[string]string
+	// Command is the program and the arguments run with the result in environment variables. It isn't run in a
+	// shell.
+	Command []string
+	// Timeout bounds how long each hook may run.
+	Timeout time.Duration
+	// FailOnError fails the run when a hook fails, instead of only logging the failure.
+	FailOnError bool
+}
+
+// Enabled returns true if any hook is configured.
+func (h ProvisioningPostApplyHooks) Enabled() bool {
+	return h.WebhookURL != "" || len(h.Command) > 0
+}
+
+// ProvisioningPollSettings control how often the dashboard providers check their files for changes. They can be
+// reloaded without restarting Grafana.
+type ProvisioningPollSettings struct {
+	// Interval is used by the providers without an updateIntervalSeconds of their own. Zero disables polling
+	// for all providers.
+	Interval time.Duration
+	// Jitter randomizes every interval by up to plus or minus this percentage.
+	Jitter int
+	// Incremental makes polls only save the dashboard files modified since the last walk of the disk.
+	Incremental bool
+	// Window limits polling to a time of the day, polls outside of it are skipped.
+	Window ProvisioningPollWindow
+}
+
+// ProvisioningPollWindow is the daily time window the dashboard providers poll in, in the local time of the
+// server. The zero value is always open.
+type ProvisioningPollWindow struct {
+	// Start and End are the times of the day the window opens and closes at, as offsets from midnight. A window that
+	// ends before it starts closes the day after, and one that ends when it starts is open all day.
+	Start time.Duration
+	End   time.Duration
+	// Days are the weekdays the window opens on, indexed by time.Weekday. It opens every day when none is set.
+	Days [7]bool
+}
+
+// Contains returns true if the window is open at t.
+func (w ProvisioningPollWindow) Contains(t time.Time) bool {
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+	day := t.Weekday()
+	switch {
+	case w.Start == w.End:
+		return w.opensOn(day)
+	case w.Start < w.End:
+		return w.opensOn(day) && offset >= w.Start && offset < w.End
+	case offset >= w.Start:
+		return w.opensOn(day)
+	case offset < w.End:
+		// The window opened the day before.
+		return w.opensOn((day + 6) % 7)
+	}
+	return false
+}
+
+func (w ProvisioningPollWindow) opensOn(day time.Weekday) bool {
+	if w.Days == [7]bool{} {
+		return true
+	}
+	return w.Days[day]
+}
+
+var weekdays = map[string]time.Weekday{"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday,
+	"wed": time.Wednesday, "thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday}
+
+// parseProvisioningPollWindow parses a window like 08:00-18:00 and a list of weekdays like mon,tue. Both can be
+// empty, an empty window is open all day.
+func parseProvisioningPollWindow(window string, days string) (ProvisioningPollWindow, error) {
+	var w ProvisioningPollWindow
+	if window != "" {
+		parts := strings.Split(window, "-")
+		if len(parts) != 2 {
+			return w, fmt.Errorf("invalid provisioning dashboards_poll_window %q, must be like 08:00-18:00", window)
+		}
+		for i, part := range parts {
+			clock, err := time.Parse("15:04", strings.TrimSpace(part))
+			if err != nil {
+				return w, fmt.Errorf("invalid provisioning dashboards_poll_window %q, must be like 08:00-18:00", window)
+			}
+			offset := time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute
+			if i == 0 {
+				w.Start = offset
+			} else {
+				w.End = offset
+			}
+		}
+	}
+	for _, name := range util.SplitString(days) {
+		day, ok := weekdays[strings.ToLower(name)]
+		if !ok {
+			return w, fmt.Errorf("invalid day %q in provisioning dashboards_poll_window_days, must be one of mon, tue, wed, thu, fri, sat or sun", name)
+		}
+		w.Days[day] = true
+	}
+	return w, nil
+}
+
+// ReadProvisioningPollSettings reads the dashboard poll settings again from the config files, environment
+// variables and command line the settings were loaded from.
+func (cfg *Cfg) ReadProvisioningPollSettings() (ProvisioningPollSettings, error) {
+	if cfg.commandLineArgs == nil {
+		return ProvisioningPollSettings{}, errors.New("settings weren't loaded from config files")
+	}
+
+	raw, err := reloadConfiguration(cfg.commandLineArgs)
+	if err != nil {
+		return ProvisioningPollSettings{}, err
+	}
+	return readProvisioningPollSettings(raw.Section("provisioning"))
+}
+
+func readProvisioningPollSettings(provisioning *ini.Section) (ProvisioningPollSettings, error) {
+	settings := ProvisioningPollSettings{
+		Interval: provisioning.Key("dashboards_poll_interval").MustDuration(10 * time.Second),
+		Jitter:   provisioning.Key("dashboards_poll_jitter").MustInt(0),
+
+		Incremental: provisioning.Key("dashboards_poll_incremental").MustBool(false),
+	}
+	if settings.Interval < 0 {
+		return ProvisioningPollSettings{}, errors.New("provisioning dashboards_poll_interval can't be negative")
+	}
+	if settings.Jitter < 0 || settings.Jitter > 100 {
+		return ProvisioningPollSettings{}, errors.New("provisioning dashboards_poll_jitter must be a percentage between 0 and 100")
+	}
+	window, err := parseProvisioningPollWindow(valueAsString(provisioning, "dashboards_poll_window", ""),
+		valueAsString(provisioning, "dashboards_poll_window_days", ""))
+	if err != nil {
+		return ProvisioningPollSettings{}, err
+	}
+	settings.Window = window
+	return settings, nil
+}
+
+func readProvisioningPostApplyHooks(provisioning *ini.Section) (ProvisioningPostApplyHooks, error) {
+	hooks := ProvisioningPostApplyHooks{
+		WebhookURL:    valueAsString(provisioning, "post_apply_webhook_url", ""),
+		WebhookMethod: strings.ToUpper(valueAsString(provisioning, "post_apply_webhook_method", "POST")),
+		Timeout:       provisioning.Key("post_apply_hook_timeout").MustDuration(10 * time.Second),
+		FailOnError:   provisioning.Key("fail_on_post_apply_hook_error").MustBool(false),
+	}
+	if command := strings.Fields(valueAsString(provisioning, "post_apply_command", "")); len(command) > 0 {
+		hooks.Command = command
+	}
+	switch hooks.WebhookMethod {
+	case "POST", "PUT":
+	default:
+		return ProvisioningPostApplyHooks{}, fmt.Errorf(
+			"invalid provisioning post_apply_webhook_method %q, must be one of POST or PUT", hooks.WebhookMethod)
+	}
+	if hooks.Timeout <= 0 {
+		return ProvisioningPostApplyHooks{}, errors.New("provisioning post_apply_hook_timeout must be positive")
+	}
+
+	// Headers are only separated by commas, values like Bearer tokens have spaces.
+	for _, header := range strings.Split(valueAsString(provisioning, "post_apply_webhook_headers", ""), ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		split := strings.SplitN(header, ":", 2)
+		if len(split) != 2 || strings.TrimSpace(split[0]) == "" {
+			return ProvisioningPostApplyHooks{}, fmt.Errorf(
+				"invalid provisioning post_apply_webhook_headers header %q, must be Name:value", header)
+		}
+		if hooks.WebhookHeaders == nil {
+			hooks.WebhookHeaders = map[string]string{}
+		}
+		hooks.WebhookHeaders[strings.TrimSpace(split[0])] = strings.TrimSpace(split[1])
+	}
+	return hooks, nil
+}
+
+func (cfg *Cfg) readProvisioningSettings() error {
+	provisioning := cfg.Raw.Section("provisioning")
+	cfg.ProvisioningLocale = valueAsString(provisioning, "locale", "")
+	cfg.ProvisioningDefaultOrgName = valueAsString(provisioning, "default_org_name", "")
+	cfg.ProvisioningAPIHealthCheck = provisioning.Key("api_health_check").MustBool(false)
+	cfg.ProvisioningDashboardsMaxConcurrency = provisioning.Key("dashboards_max_concurrency").MustInt(1)
+	cfg.ProvisioningPollingWatchdogTimeout = provisioning.Key("polling_watchdog_timeout").MustDuration(0)
+	cfg.ProvisioningPollFailureThreshold = provisioning.Key("dashboards_poll_failure_threshold").MustInt(0)
+	if cfg.ProvisioningPollFailureThreshold < 0 {
+		return errors.New("provisioning dashboards_poll_failure_threshold can't be negative")
+	}
+	cfg.ProvisioningDatasourcesCertCheckInterval = provisioning.Key("datasources_cert_check_interval").MustDuration(time.Minute)
+	cfg.ProvisioningFailureWebhookURL = valueAsString(provisioning, "failure_webhook_url", "")
+	cfg.ProvisioningDatasourcesPruneOrphans = valueAsString(provisioning, "datasources_prune_orphans", "off")
+	cfg.ProvisioningDatasourcesHealthCheck = valueAsString(provisioning, "datasources_health_check", "off")
+	switch cfg.ProvisioningDatasourcesHealthCheck {
+	case "off", "warn", "fail":
+	default:
+		return fmt.Errorf("invalid provisioning datasources_health_check %q, must be one of off, warn or fail",
+			cfg.ProvisioningDatasourcesHealthCheck)
+	}
+	cfg.ProvisioningDatasourcesHealthTimeout = provisioning.Key("datasources_health_check_timeout").MustDuration(10 * time.Second)
+	if cfg.ProvisioningDatasourcesHealthTimeout <= 0 {
+		return errors.New("provisioning datasources_health_check_timeout must be positive")
+	}
+	cfg.ProvisioningDatasourcesDeletedPolicy = valueAsString(provisioning, "datasources_deleted_policy", "recreate")
+	switch cfg.ProvisioningDatasourcesDeletedPolicy {
+	case "recreate", "skip-until-file-changes", "warn":
+	default:
+		return fmt.Errorf("invalid provisioning datasources_deleted_policy %q, must be one of recreate, skip-until-file-changes or warn",
+			cfg.ProvisioningDatasourcesDeletedPolicy)
+	}
+
+	cfg.ProvisioningPluginsDisableRemovedApps = provisioning.Key("plugins_disable_removed_apps").MustBool(false)
+	cfg.ProvisioningPluginsFailFast = provisioning.Key("plugins_fail_fast").MustBool(false)
+	cfg.ProvisioningFailOnMissingDir = provisioning.Key("fail_on_missing_dir").MustBool(false)
+	cfg.ProvisioningApplyOnce = provisioning.Key("apply_once").MustBool(false)
+	cfg.ProvisioningDatabaseReadyTimeout = provisioning.Key("database_ready_timeout").MustDuration(0)
+	if cfg.ProvisioningDatabaseReadyTimeout < 0 {
+		return errors.New("provisioning database_ready_timeout can't be negative")
+	}
+	cfg.ProvisioningDatabaseReadyInterval = provisioning.Key("database_ready_interval").MustDuration(time.Second)
+	if cfg.ProvisioningDatabaseReadyInterval <= 0 {
+		return errors.New("provisioning database_ready_interval must be positive")
+	}
+	if reportPath := valueAsString(provisioning, "report_path", ""); reportPath != "" {
+		cfg.ProvisioningReportPath = makeAbsolute(reportPath, cfg.DataPath)
+	}
+	cfg.ProvisioningDanglingReferences = valueAsString(provisioning, "dangling_references", "warn")
+	switch cfg.ProvisioningDanglingReferences {
+	case "off", "warn", "fail":
+	default:
+		return fmt.Errorf("invalid provisioning dangling_references %q, must be one of off, warn or fail",
+			cfg.ProvisioningDanglingReferences)
+	}
+
+	cfg.ProvisioningCleanupGuard = ProvisioningCleanupGuard{
+		MaxPercent: provisioning.Key("orphan_cleanup_max_percent").MustInt(100),
+		MaxCount:   provisioning.Key("orphan_cleanup_max_count").MustInt(0),
+		Force:      provisioning.Key("orphan_cleanup_force").MustBool(false),
+	}
+	if cfg.ProvisioningCleanupGuard.MaxPercent < 0 || cfg.ProvisioningCleanupGuard.MaxPercent > 100 {
+		return errors.New("provisioning orphan_cleanup_max_percent must be a percentage between 0 and 100")
+	}
+	if cfg.ProvisioningCleanupGuard.MaxCount < 0 {
+		return errors.New("provisioning orphan_cleanup_max_count can't be negative")
+	}
+
+	hooks, err := readProvisioningPostApplyHooks(provisioning)
+	if err != nil {
+		return err
+	}
+	cfg.ProvisioningPostApplyHooks = hooks
+
+	pollSettings, err := readProvisioningPollSettings(provisioning)
+	if err != nil {
+		return err
+	}
+	cfg.ProvisioningDashboardsPoll = pollSettings
+
+	cfg.ProvisioningFileFilters = make(map[string]ProvisioningFileFilter, len(ProvisioningFileFilterKinds))
+	for _, kind := range ProvisioningFileFilterKinds {
+		filter := ProvisioningFileFilter{
+			Include: util.SplitString(valueAsString(provisioning, kind+"_include", "")),
+			Exclude: util.SplitString(valueAsString(provisioning, kind+"_exclude", "")),
+		}
+		for _, pattern := range append(filter.Include, filter.Exclude...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q in provisioning %s file filter: %w", pattern, kind, err)
+			}
+		}
+		cfg.ProvisioningFileFilters[kind] = filter
+	}
+
+	defaultTimeout := provisioning.Key("timeout").MustDuration(0)
+	if defaultTimeout < 0 {
+		return errors.New("provisioning timeout can't be negative")
+	}
+	cfg.ProvisioningTimeout = defaultTimeout
+	cfg.ProvisioningTimeouts = make(map[string]time.Duration, len(ProvisioningFileFilterKinds))
+	for _, kind := range ProvisioningFileFilterKinds {
+		timeout := provisioning.Key(kind + "_timeout").MustDuration(defaultTimeout)
+		if timeout < 0 {
+			return fmt.Errorf("provisioning %s_timeout can't be negative", kind)
+		}
+		cfg.ProvisioningTimeouts[kind] = timeout
+	}
+
+	cfg.ProvisioningStrictFields = map[string]bool{}
+	for _, kind := range util.SplitString(valueAsString(provisioning, "strict_fields", "")) {
+		if !isProvisioningFileFilterKind(kind) {
+			return fmt.Errorf("invalid provisioning strict_fields subsystem %q, must be one of %s", kind,
+				strings.Join(ProvisioningFileFilterKinds, ", "))
+		}
+		cfg.ProvisioningStrictFields[kind] = true
+	}
+
+	cfg.ProvisioningOrder = util.SplitString(valueAsString(provisioning, "order", ""))
+	if len(cfg.ProvisioningOrder) == 0 {
+		cfg.ProvisioningOrder = append([]string{}, ProvisioningInitStages...)
+	}
+	return validateProvisioningOrder(cfg.ProvisioningOrder)
+}
+
+// validateProvisioningOrder makes sure every init stage is in the order exactly once.
+func validateProvisioningOrder(order []string) error {
+	listed := map[string]bool{}
+	for _, stage := range order {
+		if !isProvisioningInitStage(stage) {
+			return fmt.Errorf("invalid provisioning order stage %q, must be one of %s", stage,
+				strings.Join(ProvisioningInitStages, ", "))
+		}
+		if listed[stage] {
+			return fmt.Errorf("invalid provisioning order, the %s stage is listed more than once", stage)
+		}
+		listed[stage] = true
+	}
+
+	for _, stage := range ProvisioningInitStages {
+		if !listed[stage] {
+			return fmt.Errorf("invalid provisioning order, the %s stage is missing", stage)
+		}
+	}
+	return nil
+}
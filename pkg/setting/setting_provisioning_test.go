@@ -0,0 +1,564 @@
+package setting
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvisioningFileFilterSettings(t *testing.T) {
+	t.Run("Filters are read per subsystem", func(t *testing.T) {
+		cfg := NewCfg()
+		sec, err := cfg.Raw.NewSection("provisioning")
+		require.NoError(t, err)
+		_, err = sec.NewKey("datasources_include", "*.yaml, prod-*.yml")
+		require.NoError(t, err)
+		_, err = sec.NewKey("datasources_exclude", "*.tmpl.yaml")
+		require.NoError(t, err)
+
+		require.NoError(t, cfg.readProvisioningSettings())
+
+		assert.Equal(t, ProvisioningFileFilter{
+			Include: []string{"*.yaml", "prod-*.yml"},
+			Exclude: []string{"*.tmpl.yaml"},
+		}, cfg.ProvisioningFileFilters["datasources"])
+		assert.Equal(t, ProvisioningFileFilter{Include: []string{}, Exclude: []string{}}, cfg.ProvisioningFileFilters["dashboards"])
+	})
+
+	t.Run("Invalid pattern fails reading the settings", func(t *testing.T) {
+		cfg := NewCfg()
+		sec, err := cfg.Raw.NewSection("provisioning")
+		require.NoError(t, err)
+		_, err = sec.NewKey("plugins_exclude", "[README")
+		require.NoError(t, err)
+
+		err = cfg.readProvisioningSettings()
+		require.EqualError(t, err, `invalid pattern "[README" in provisioning plugins file filter: syntax error in pattern`)
+	})
+}
+
+func TestProvisioningStrictFieldsSettings(t *testing.T) {
+	t.Run("Strict fields are off by default", func(t *testing.T) {
+		cfg := NewCfg()
+		require.NoError(t, cfg.readProvisioningSettings())
+		assert.Empty(t, cfg.ProvisioningStrictFields)
+	})
+
+	t.Run("Strict fields are read per subsystem", func(t *testing.T) {
+		cfg := NewCfg()
+		sec, err := cfg.Raw.NewSection("provisioning")
+		require.NoError(t, err)
+		_, err = sec.NewKey("strict_fields", "datasources, dashboards")
+		require.NoError(t, err)
+
+		require.NoError(t, cfg.readProvisioningSettings())
+		assert.Equal(t, map[string]bool{"datasources": true, "dashboards": true}, cfg.ProvisioningStrictFields)
+	})
+
+	t.Run("Unknown subsystem fails reading the settings", func(t *testing.T) {
+		cfg := NewCfg()
+		sec, err := cfg.Raw.NewSection("provisioning")
+		require.NoError(t, err)
+		_, err = sec.NewKey("strict_fields", "datasource")
+		require.NoError(t, err)
+
+		err = cfg.readProvisioningSettings()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid provisioning strict_fields subsystem "datasource"`)
+	})
+}
+
+func TestProvisioningOrderSettings(t *testing.T) {
+	readOrder := func(t *testing.T, order string) (*Cfg, error) {
+		t.Helper()
+		cfg := NewCfg()
+		sec, err := cfg.Raw.NewSection("provisioning")
+		require.NoError(t, err)
+		_, err = sec.NewKey("order", order)
+		require.NoError(t, err)
+		return cfg, cfg.readProvisioningSettings()
+	}
+
+	t.Run("Order defaults to the built-in order", func(t *testing.T) {
+		cfg := NewCfg()
+		require.NoError(t, cfg.readProvisioningSettings())
+		assert.Equal(t, []string{"datasources", "plugins", "notifiers", "alert_notifications"}, cfg.ProvisioningOrder)
+	})
+
+	t.Run("Order is read in the configured order", func(t *testing.T) {
+		cfg, err := readOrder(t, "notifiers, datasources alert_notifications,plugins")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"notifiers", "datasources", "alert_notifications", "plugins"}, cfg.ProvisioningOrder)
+	})
+
+	t.Run("Unknown stage fails reading the settings", func(t *testing.T) {
+		_, err := readOrder(t, "datasources plugins notifiers alert_notifications dashboards")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid provisioning order stage "dashboards"`)
+	})
+
+	t.Run("Duplicate stage fails reading the settings", func(t *testing.T) {
+		_, err := readOrder(t, "datasources plugins notifiers datasources alert_notifications")
+		require.EqualError(t, err, "invalid provisioning order, the datasources stage is listed more than once")
+	})
+
+	t.Run("Missing stage fails reading the settings", func(t *testing.T) {
+		_, err := readOrder(t, "notifiers datasources plugins")
+		require.EqualError(t, err, "invalid provisioning order, the alert_notifications stage is missing")
+	})
+}
+
+func TestProvisioningFileFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   ProvisioningFileFilter
+		path     string
+		included bool
+	}{
+		{name: "empty filter includes everything", path: "datasources.yaml", included: true},
+		{name: "matching include", filter: ProvisioningFileFilter{Include: []string{"prod-*"}}, path: "prod-db.yaml", included: true},
+		{name: "no matching include", filter: ProvisioningFileFilter{Include: []string{"prod-*"}}, path: "dev-db.yaml", included: false},
+		{name: "matching exclude", filter: ProvisioningFileFilter{Exclude: []string{"*.tmpl.yaml"}}, path: "db.tmpl.yaml", included: false},
+		{name: "exclude wins over include", filter: ProvisioningFileFilter{Include: []string{"*.yaml"}, Exclude: []string{"README*"}}, path: "README.yaml", included: false},
+		{name: "relative path with a folder", filter: ProvisioningFileFilter{Exclude: []string{"templates/*"}}, path: "templates/db.yaml", included: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.included, tt.filter.Includes(tt.path))
+		})
+	}
+}
+
+func TestProvisioningPollSettings(t *testing.T) {
+	t.Run("Defaults to polling every 10 seconds without jitter", func(t *testing.T) {
+		cfg := NewCfg()
+		require.NoError(t, cfg.readProvisioningSettings())
+		assert.Equal(t, ProvisioningPollSettings{Interval: 10 * time.Second}, cfg.ProvisioningDashboardsPoll)
+	})
+
+	t.Run("Zero interval is allowed to disable polling", func(t *testing.T) {
+		cfg := NewCfg()
+		sec, err := cfg.Raw.NewSection("provisioning")
+		require.NoError(t, err)
+		_, err = sec.NewKey("dashboards_poll_interval", "0")
+		require.NoError(t, err)
+
+		require.NoError(t, cfg.readProvisioningSettings())
+		assert.Equal(t, time.Duration(0), cfg.ProvisioningDashboardsPoll.Interval)
+	})
+
+	t.Run("Jitter above 100 percent fails reading the settings", func(t *testing.T) {
+		cfg := NewCfg()
+		sec, err := cfg.Raw.NewSection("provisioning")
+		require.NoError(t, err)
+		_, err = sec.NewKey("dashboards_poll_jitter", "150")
+		require.NoError(t, err)
+
+		err = cfg.readProvisioningSettings()
+		require.EqualError(t, err, "provisioning dashboards_poll_jitter must be a percentage between 0 and 100")
+	})
+
+	t.Run("Poll window is read with its days", func(t *testing.T) {
+		cfg := NewCfg()
+		sec, err := cfg.Raw.NewSection("provisioning")
+		require.NoError(t, err)
+		_, err = sec.NewKey("dashboards_poll_window", "08:30-18:00")
+		require.NoError(t, err)
+		_, err = sec.NewKey("dashboards_poll_window_days", "mon, Fri")
+		require.NoError(t, err)
+
+		require.NoError(t, cfg.readProvisioningSettings())
+		window := ProvisioningPollWindow{Start: 8*time.Hour + 30*time.Minute, End: 18 * time.Hour}
+		window.Days[time.Monday] = true
+		window.Days[time.Friday] = true
+		assert.Equal(t, window, cfg.ProvisioningDashboardsPoll.Window)
+	})
+
+	t.Run("Invalid poll windows fail reading the settings", func(t *testing.T) {
+		for key, value := range map[string]string{
+			"dashboards_poll_window":      "8-18",
+			"dashboards_poll_window_days": "weekdays",
+		} {
+			cfg := NewCfg()
+			sec, err := cfg.Raw.NewSection("provisioning")
+			require.NoError(t, err)
+			_, err = sec.NewKey(key, value)
+			require.NoError(t, err)
+
+			require.Error(t, cfg.readProvisioningSettings(), key)
+		}
+	})
+
+	t.Run("Settings are read again from the config file", func(t *testing.T) {
+		skipStaticRootValidation = true
+		configFile := filepath.Join(t.TempDir(), "custom.ini")
+		writeConfig := func(content string) {
+			require.NoError(t, ioutil.WriteFile(configFile, []byte(content), 0600))
+		}
+		writeConfig("[provisioning]\ndashboards_poll_interval = 30s\n")
+
+		cfg := NewCfg()
+		require.NoError(t, cfg.Load(&CommandLineArgs{HomePath: "../../", Config: configFile}))
+		require.Equal(t, ProvisioningPollSettings{Interval: 30 * time.Second}, cfg.ProvisioningDashboardsPoll)
+
+		writeConfig("[provisioning]\ndashboards_poll_interval = 1m\ndashboards_poll_jitter = 20\ndashboards_poll_incremental = true\n")
+		loadedConfigFiles := append([]string{}, configFiles...)
+		pollSettings, err := cfg.ReadProvisioningPollSettings()
+		require.NoError(t, err)
+		assert.Equal(t, ProvisioningPollSettings{Interval: time.Minute, Jitter: 20, Incremental: true}, pollSettings)
+		// Reloading doesn't touch the loaded settings, the caller applies the new ones.
+		assert.Equal(t, ProvisioningPollSettings{Interval: 30 * time.Second}, cfg.ProvisioningDashboardsPoll)
+		assert.Equal(t, loadedConfigFiles, configFiles)
+	})
+
+	t.Run("Settings can't be read again without config files", func(t *testing.T) {
+		_, err := NewCfg().ReadProvisioningPollSettings()
+		require.EqualError(t, err, "settings weren't loaded from config files")
+	})
+}
+
+func TestProvisioningPollWindow(t *testing.T) {
+	// 2021-06-07 is a Monday.
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2021, time.June, day, hour, minute, 0, 0, time.Local)
+	}
+
+	t.Run("The zero window is always open", func(t *testing.T) {
+		assert.True(t, ProvisioningPollWindow{}.Contains(at(7, 3, 0)))
+	})
+
+	t.Run("Window is open from its start until its end", func(t *testing.T) {
+		window := ProvisioningPollWindow{Start: 8 * time.Hour, End: 18 * time.Hour}
+		assert.False(t, window.Contains(at(7, 7, 59)))
+		assert.True(t, window.Contains(at(7, 8, 0)))
+		assert.True(t, window.Contains(at(7, 17, 59)))
+		assert.False(t, window.Contains(at(7, 18, 0)))
+	})
+
+	t.Run("Window that ends before it starts closes the day after", func(t *testing.T) {
+		window := ProvisioningPollWindow{Start: 22 * time.Hour, End: 6 * time.Hour}
+		window.Days[time.Monday] = true
+		assert.True(t, window.Contains(at(7, 23, 0)))
+		assert.True(t, window.Contains(at(8, 5, 0)), "The window opened on Monday")
+		assert.False(t, window.Contains(at(8, 23, 0)), "The window doesn't open on Tuesday")
+		assert.False(t, window.Contains(at(7, 5, 0)), "The window didn't open on Sunday")
+	})
+
+	t.Run("Window is only open on its days", func(t *testing.T) {
+		var window ProvisioningPollWindow
+		window.Days[time.Saturday] = true
+		assert.True(t, window.Contains(at(12, 12, 0)))
+		assert.False(t, window.Contains(at(11, 12, 0)))
+	})
+}
+
+func TestProvisioningDatasourcesHealthCheckSettings(t *testing.T) {
+	t.Run("Health checks are off by default", func(t *testing.T) {
+		cfg := NewCfg()
+		require.NoError(t, cfg.readProvisioningSettings())
+		assert.Equal(t, "off", cfg.ProvisioningDatasourcesHealthCheck)
+		assert.Equal(t, 10*time.Second, cfg.ProvisioningDatasourcesHealthTimeout)
+	})
+
+	t.Run("Invalid mode fails reading the settings", func(t *testing.T) {
+		cfg := NewCfg()
+		sec, err := cfg.Raw.NewSection("provisioning")
+		require.NoError(t, err)
+		_, err = sec.NewKey("datasources_health_check", "always")
+		require.NoError(t, err)
+
+		err = cfg.readProvisioningSettings()
+		require.EqualError(t, err, `invalid provisioning datasources_health_check "always", must be one of off, warn or fail`)
+	})
+
+	t.Run("Zero timeout fails reading the settings", func(t *testing.T) {
+		cfg := NewCfg()
+		sec, err := cfg.Raw.NewSection("provisioning")
+		require.NoError(t, err)
+		_, err = sec.NewKey("datasources_health_check_timeout", "0")
+		require.NoError(t, err)
+
+		err = cfg.readProvisioningSettings()
+		require.EqualError(t, err, "provisioning datasources_health_check_timeout must be positive")
+	})
+}
+
+func TestProvisioningDatasourcesDeletedPolicySetting(t *testing.T) {
+	t.Run("Deleted datasources are recreated by default", func(t *testing.T) {
+		cfg := NewCfg()
+		require.NoError(t, cfg.readProvisioningSettings())
+		assert.Equal(t, "recreate", cfg.ProvisioningDatasourcesDeletedPolicy)
+	})
+
+	t.Run("Invalid policy fails reading the settings", func(t *testing.T) {
+		cfg := NewCfg()
+		sec, err := cfg.Raw.NewSection("provisioning")
+		require.NoError(t, err)
+		_, err = sec.NewKey("datasources_deleted_policy", "ignore")
+		require.NoError(t, err)
+
+		err = cfg.readProvisioningSettings()
+		require.EqualError(t, err, `invalid provisioning datasources_deleted_policy "ignore", must be one of recreate, skip-until-file-changes or warn`)
+	})
+}
+
+func TestProvisioningDefaultOrgSetting(t *testing.T) {
+	cfg := NewCfg()
+	require.NoError(t, cfg.readProvisioningSettings())
+	assert.Empty(t, cfg.ProvisioningDefaultOrgName, "Objects without an org are in org 1 by default")
+
+	sec, err := cfg.Raw.NewSection("provisioning")
+	require.NoError(t, err)
+	_, err = sec.NewKey("default_org_name", "Staging")
+	require.NoError(t, err)
+	require.NoError(t, cfg.readProvisioningSettings())
+	assert.Equal(t, "Staging", cfg.ProvisioningDefaultOrgName)
+}
+
+func TestProvisioningCleanupGuardSettings(t *testing.T) {
+	t.Run("Cleanups aren't limited to a percentage of the provisioned objects by default", func(t *testing.T) {
+		cfg := NewCfg()
+		require.NoError(t, cfg.readProvisioningSettings())
+		assert.Equal(t, ProvisioningCleanupGuard{MaxPercent: 100}, cfg.ProvisioningCleanupGuard)
+	})
+
+	t.Run("Invalid percentage fails reading the settings", func(t *testing.T) {
+		cfg := NewCfg()
+		sec, err := cfg.Raw.NewSection("provisioning")
+		require.NoError(t, err)
+		_, err = sec.NewKey("orphan_cleanup_max_percent", "150")
+		require.NoError(t, err)
+
+		err = cfg.readProvisioningSettings()
+		require.EqualError(t, err, "provisioning orphan_cleanup_max_percent must be a percentage between 0 and 100")
+	})
+}
+
+func TestProvisioningPostApplyHooksSettings(t *testing.T) {
+	t.Run("No hooks are configured by default", func(t *testing.T) {
+		cfg := NewCfg()
+		require.NoError(t, cfg.readProvisioningSettings())
+		assert.Equal(t, ProvisioningPostApplyHooks{WebhookMethod: "POST", Timeout: 10 * time.Second},
+			cfg.ProvisioningPostApplyHooks)
+		assert.False(t, cfg.ProvisioningPostApplyHooks.Enabled())
+	})
+
+	t.Run("Hooks are read from the settings", func(t *testing.T) {
+		cfg := NewCfg()
+		sec, err := cfg.Raw.NewSection("provisioning")
+		require.NoError(t, err)
+		for key, value := range map[string]string{
+			"post_apply_webhook_url":        "http://deploy/hooks/grafana",
+			"post_apply_webhook_method":     "put",
+			"post_apply_webhook_headers":    "Authorization:Bearer token, X-Source: grafana",
+			"post_apply_command":            "/usr/local/bin/warm-cache --all",
+			"post_apply_hook_timeout":       "30s",
+			"fail_on_post_apply_hook_error": "true",
+		} {
+			_, err = sec.NewKey(key, value)
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, cfg.readProvisioningSettings())
+		assert.Equal(t, ProvisioningPostApplyHooks{
+			WebhookURL:     "http://deploy/hooks/grafana",
+			WebhookMethod:  "PUT",
+			WebhookHeaders: map[string]string{"Authorization": "Bearer token", "X-Source": "grafana"},
+			Command:        []string{"/usr/local/bin/warm-cache", "--all"},
+			Timeout:        30 * time.Second,
+			FailOnError:    true,
+		}, cfg.ProvisioningPostApplyHooks)
+		assert.True(t, cfg.ProvisioningPostApplyHooks.Enabled())
+	})
+
+	t.Run("Invalid hook settings fail reading the settings", func(t *testing.T) {
+		tests := map[string]struct {
+			key, value, err string
+		}{
+			"method": {key: "post_apply_webhook_method", value: "GET",
+				err: `invalid provisioning post_apply_webhook_method "GET", must be one of POST or PUT`},
+			"timeout": {key: "post_apply_hook_timeout", value: "0s",
+				err: "provisioning post_apply_hook_timeout must be positive"},
+			"header": {key: "post_apply_webhook_headers", value: "Authorization",
+				err: `invalid provisioning post_apply_webhook_headers header "Authorization", must be Name:value`},
+		}
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				cfg := NewCfg()
+				sec, err := cfg.Raw.NewSection("provisioning")
+				require.NoError(t, err)
+				_, err = sec.NewKey(tt.key, tt.value)
+				require.NoError(t, err)
+
+				require.EqualError(t, cfg.readProvisioningSettings(), tt.err)
+			})
+		}
+	})
+}
+
+func TestProvisioningCleanupGuard(t *testing.T) {
+	tests := []struct {
+		name        string
+		guard       ProvisioningCleanupGuard
+		deleting    int
+		provisioned int
+		allowed     bool
+	}{
+		{name: "nothing to delete", guard: ProvisioningCleanupGuard{}, deleting: 0, provisioned: 0, allowed: true},
+		{name: "up to the percentage", guard: ProvisioningCleanupGuard{MaxPercent: 50}, deleting: 2, provisioned: 4, allowed: true},
+		{name: "over the percentage", guard: ProvisioningCleanupGuard{MaxPercent: 50}, deleting: 3, provisioned: 4},
+		{name: "over the percentage of one", guard: ProvisioningCleanupGuard{MaxPercent: 50}, deleting: 1, provisioned: 1},
+		{name: "no percentage limit", guard: ProvisioningCleanupGuard{MaxPercent: 100}, deleting: 3, provisioned: 4, allowed: true},
+		{name: "everything", guard: ProvisioningCleanupGuard{MaxPercent: 100}, deleting: 4, provisioned: 4, allowed: true},
+		{name: "everything over the percentage", guard: ProvisioningCleanupGuard{MaxPercent: 99}, deleting: 4, provisioned: 4},
+		{name: "the only one", guard: ProvisioningCleanupGuard{MaxPercent: 100}, deleting: 1, provisioned: 1, allowed: true},
+		{name: "over the count", guard: ProvisioningCleanupGuard{MaxPercent: 100, MaxCount: 3}, deleting: 4, provisioned: 10},
+		{name: "forced", guard: ProvisioningCleanupGuard{MaxCount: 1, Force: true}, deleting: 4, provisioned: 4, allowed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.allowed, tt.guard.Allows(tt.deleting, tt.provisioned))
+		})
+	}
+}
+
+func TestProvisioningPollFailureThresholdSetting(t *testing.T) {
+	t.Run("Polling never fails by default", func(t *testing.T) {
+		cfg := NewCfg()
+		require.NoError(t, cfg.readProvisioningSettings())
+		assert.Equal(t, 0, cfg.ProvisioningPollFailureThreshold)
+	})
+
+	t.Run("Negative threshold fails reading the settings", func(t *testing.T) {
+		cfg := NewCfg()
+		sec, err := cfg.Raw.NewSection("provisioning")
+		require.NoError(t, err)
+		_, err = sec.NewKey("dashboards_poll_failure_threshold", "-1")
+		require.NoError(t, err)
+
+		err = cfg.readProvisioningSettings()
+		require.EqualError(t, err, "provisioning dashboards_poll_failure_threshold can't be negative")
+	})
+}
+
+func TestProvisioningDatabaseReadySettings(t *testing.T) {
+	t.Run("Provisioning doesn't wait for the database by default", func(t *testing.T) {
+		cfg := NewCfg()
+		require.NoError(t, cfg.readProvisioningSettings())
+		assert.Zero(t, cfg.ProvisioningDatabaseReadyTimeout)
+		assert.Equal(t, time.Second, cfg.ProvisioningDatabaseReadyInterval)
+	})
+
+	t.Run("Zero interval fails reading the settings", func(t *testing.T) {
+		cfg := NewCfg()
+		sec, err := cfg.Raw.NewSection("provisioning")
+		require.NoError(t, err)
+		_, err = sec.NewKey("database_ready_timeout", "2m")
+		require.NoError(t, err)
+		_, err = sec.NewKey("database_ready_interval", "0")
+		require.NoError(t, err)
+
+		err = cfg.readProvisioningSettings()
+		require.EqualError(t, err, "provisioning database_ready_interval must be positive")
+	})
+}
+
+func TestProvisioningTimeoutSettings(t *testing.T) {
+	t.Run("No subsystem has a timeout by default", func(t *testing.T) {
+		cfg := NewCfg()
+		require.NoError(t, cfg.readProvisioningSettings())
+		assert.Len(t, cfg.ProvisioningTimeouts, len(ProvisioningFileFilterKinds))
+		assert.Equal(t, time.Duration(0), cfg.ProvisioningTimeouts["alert_rules"])
+	})
+
+	t.Run("Subsystem timeouts override the timeout", func(t *testing.T) {
+		cfg := NewCfg()
+		sec, err := cfg.Raw.NewSection("provisioning")
+		require.NoError(t, err)
+		_, err = sec.NewKey("timeout", "1m")
+		require.NoError(t, err)
+		_, err = sec.NewKey("dashboards_timeout", "0")
+		require.NoError(t, err)
+
+		require.NoError(t, cfg.readProvisioningSettings())
+		assert.Equal(t, time.Minute, cfg.ProvisioningTimeouts["datasources"])
+		assert.Equal(t, time.Duration(0), cfg.ProvisioningTimeouts["dashboards"])
+	})
+
+	t.Run("Negative timeout fails reading the settings", func(t *testing.T) {
+		cfg := NewCfg()
+		sec, err := cfg.Raw.NewSection("provisioning")
+		require.NoError(t, err)
+		_, err = sec.NewKey("plugins_timeout", "-1s")
+		require.NoError(t, err)
+
+		err = cfg.readProvisioningSettings()
+		require.EqualError(t, err, "provisioning plugins_timeout can't be negative")
+	})
+}
+
+func TestProvisioningReportPathSettings(t *testing.T) {
+	t.Run("No report is written by default", func(t *testing.T) {
+		cfg := NewCfg()
+		require.NoError(t, cfg.readProvisioningSettings())
+		assert.Empty(t, cfg.ProvisioningReportPath)
+	})
+
+	t.Run("Relative paths are relative to the data path", func(t *testing.T) {
+		cfg := NewCfg()
+		cfg.DataPath = "/var/lib/grafana"
+		sec, err := cfg.Raw.NewSection("provisioning")
+		require.NoError(t, err)
+		_, err = sec.NewKey("report_path", "provisioning/report.json")
+		require.NoError(t, err)
+
+		require.NoError(t, cfg.readProvisioningSettings())
+		assert.Equal(t, filepath.Join("/var/lib/grafana", "provisioning", "report.json"), cfg.ProvisioningReportPath)
+	})
+}
+
+func TestProvisioningDanglingReferencesSettings(t *testing.T) {
+	t.Run("Dangling references are logged by default", func(t *testing.T) {
+		cfg := NewCfg()
+		require.NoError(t, cfg.readProvisioningSettings())
+		assert.Equal(t, "warn", cfg.ProvisioningDanglingReferences)
+	})
+
+	t.Run("Unknown modes are invalid", func(t *testing.T) {
+		cfg := NewCfg()
+		sec, err := cfg.Raw.NewSection("provisioning")
+		require.NoError(t, err)
+		_, err = sec.NewKey("dangling_references", "error")
+		require.NoError(t, err)
+
+		require.EqualError(t, cfg.readProvisioningSettings(),
+			`invalid provisioning dangling_references "error", must be one of off, warn or fail`)
+	})
+}
+
+func TestProvisioningPaths(t *testing.T) {
+	t.Run("A single path is also the provisioning path", func(t *testing.T) {
+		cfg := NewCfg()
+		require.NoError(t, cfg.Load(&CommandLineArgs{HomePath: "../../"}))
+
+		expected := filepath.Join(HomePath, "conf", "provisioning")
+		assert.Equal(t, []string{expected}, cfg.ProvisioningPaths)
+		assert.Equal(t, expected, cfg.ProvisioningPath)
+	})
+
+	t.Run("Comma separated paths are read in order", func(t *testing.T) {
+		cfg := NewCfg()
+		require.NoError(t, cfg.Load(&CommandLineArgs{
+			HomePath: "../../",
+			Args:     []string{"cfg:paths.provisioning=/etc/grafana/base, overlays/team-a,"},
+		}))
+
+		assert.Equal(t, []string{"/etc/grafana/base", filepath.Join(HomePath, "overlays", "team-a")}, cfg.ProvisioningPaths)
+		assert.Equal(t, "/etc/grafana/base", cfg.ProvisioningPath)
+	})
+}